@@ -7,23 +7,29 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/api/rest"
 	"github.com/davidmoltin/intelligent-workflows/internal/api/rest/handlers"
 	"github.com/davidmoltin/intelligent-workflows/internal/engine"
+	"github.com/davidmoltin/intelligent-workflows/internal/ingestion"
 	"github.com/davidmoltin/intelligent-workflows/internal/repository/postgres"
 	"github.com/davidmoltin/intelligent-workflows/internal/services"
 	"github.com/davidmoltin/intelligent-workflows/internal/websocket"
 	"github.com/davidmoltin/intelligent-workflows/internal/workers"
+	"github.com/davidmoltin/intelligent-workflows/pkg/archive"
 	"github.com/davidmoltin/intelligent-workflows/pkg/auth"
 	"github.com/davidmoltin/intelligent-workflows/pkg/config"
 	"github.com/davidmoltin/intelligent-workflows/pkg/database"
+	"github.com/davidmoltin/intelligent-workflows/pkg/egress"
+	"github.com/davidmoltin/intelligent-workflows/pkg/health"
 	"github.com/davidmoltin/intelligent-workflows/pkg/llm"
 	"github.com/davidmoltin/intelligent-workflows/pkg/llm/providers/anthropic"
+	"github.com/davidmoltin/intelligent-workflows/pkg/llm/providers/ollama"
 	"github.com/davidmoltin/intelligent-workflows/pkg/llm/providers/openai"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
 	"github.com/davidmoltin/intelligent-workflows/pkg/metrics"
+	"github.com/davidmoltin/intelligent-workflows/pkg/tracing"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -55,9 +61,15 @@ func run() error {
 	)
 
 	// Initialize Prometheus metrics
-	metricsRegistry := metrics.New()
+	metricsRegistry := metrics.New(metrics.Options{OrganizationLabel: cfg.Metrics.OrganizationLabel})
 	log.Info("Metrics registry initialized")
 
+	// Initialize OpenTelemetry tracing; a no-op without an exporter endpoint
+	shutdownTracing, err := tracing.Setup(context.Background(), &cfg.Tracing, log)
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+
 	// Initialize PostgreSQL
 	db, err := database.NewPostgresDB(cfg, log, metricsRegistry)
 	if err != nil {
@@ -85,6 +97,9 @@ func run() error {
 	scheduleRepo := postgres.NewScheduleRepository(db.DB)
 	auditRepo := postgres.NewAuditRepository(db.DB)
 	ruleRepo := postgres.NewRuleRepository(db.DB)
+	webhookSecretRepo := postgres.NewWebhookSecretRepository(db.DB)
+	deadLetterRepo := postgres.NewDeadLetterRepository(db.DB)
+	retentionRepo := postgres.NewRetentionRepository(db.DB)
 
 	// Initialize WebSocket hub
 	wsHub := websocket.NewHub(redis.Client, log.Logger)
@@ -97,23 +112,57 @@ func run() error {
 	// Initialize workflow engine components
 	evaluator := engine.NewEvaluator()
 	executor := engine.NewWorkflowExecutor(redis.Client, executionRepo, workflowRepo, wsHub, log, metricsRegistry, &cfg.ContextEnrichment)
+	executor.SetEnrichmentCircuitBreaker(engine.NewEnrichmentCircuitBreaker(&cfg.CircuitBreaker, log, metricsRegistry))
+	executor.SetWebhookConfig(&cfg.Webhook)
+	egressPolicy, err := egress.NewPolicy(&cfg.Egress)
+	if err != nil {
+		return fmt.Errorf("failed to load egress policy: %w", err)
+	}
+	egressPolicy.SetMetrics(metricsRegistry)
+	executor.SetEgressPolicy(egressPolicy)
+	executor.SetConcurrencyLimiter(engine.NewConcurrencyLimiter(redis.Client, &cfg.Concurrency, log))
+	executor.SetVersionRepository(workflowRepo)
+	executor.SetRateLimiter(engine.NewRateLimiter(redis.Client, &cfg.WorkflowRateLimit, log, metricsRegistry))
+	if len(cfg.Query.Datasources) > 0 {
+		datasources, err := database.OpenDatasources(&cfg.Query, log)
+		if err != nil {
+			return fmt.Errorf("failed to open query datasources: %w", err)
+		}
+		defer database.CloseDatasources(datasources)
+		executor.SetQueryRunner(engine.NewQueryRunner(datasources, &cfg.Query, log))
+	}
 
 	// Initialize rule service and connect to executor
 	ruleService := services.NewRuleService(ruleRepo, evaluator, redis, log)
 	executor.SetRuleService(ruleService)
 	eventRouter := engine.NewEventRouter(workflowRepo, eventRepo, executor, log)
+	eventRouter.SetVersionRepository(workflowRepo)
+	eventRouter.SetDeadLetterRepository(deadLetterRepo)
+	var workflowIndex *engine.WorkflowIndex
+	if cfg.Workers.WorkflowIndexRefreshInterval > 0 {
+		workflowIndex = engine.NewWorkflowIndex(workflowRepo, log, cfg.Workers.WorkflowIndexRefreshInterval)
+		eventRouter.SetWorkflowIndex(workflowIndex)
+	}
 
 	// Initialize notification service
 	notificationService, err := services.NewNotificationService(&cfg.Notification, log)
 	if err != nil {
 		return fmt.Errorf("failed to initialize notification service: %w", err)
 	}
+	notificationService.SetOrganizationRepository(organizationRepo)
+	notificationService.SetEgressPolicy(egressPolicy)
+	executor.SetSlackService(notificationService)
+	executor.SetEmailService(notificationService)
 
 	// Initialize audit service
 	auditService := services.NewAuditService(auditRepo, log)
 
 	// Initialize workflow resumer
 	workflowResumer := services.NewWorkflowResumer(log, executionRepo, executor, auditService)
+	workflowResumer.SetApprovalLookup(approvalRepo)
+	workflowResumer.SetWorkflowLookup(workflowRepo)
+	workflowResumer.SetMaxPausedDuration(cfg.Workers.MaxPausedDuration)
+	workflowResumer.SetExecutionBroadcaster(executor)
 
 	// Initialize JWT manager
 	jwtSecret := os.Getenv("JWT_SECRET")
@@ -128,9 +177,9 @@ func run() error {
 	}
 	jwtManager := auth.NewJWTManager(jwtSecret)
 
-	// Initialize LLM client (if configured)
+	// Initialize LLM client (if configured). Ollama runs locally, so it needs no API key
 	var aiService *services.AIService
-	if cfg.LLM.APIKey != "" {
+	if cfg.LLM.APIKey != "" || llm.Provider(cfg.LLM.Provider) == llm.ProviderOllama {
 		llmConfig := &llm.Config{
 			Provider:     llm.Provider(cfg.LLM.Provider),
 			APIKey:       cfg.LLM.APIKey,
@@ -147,6 +196,10 @@ func run() error {
 			llmClient, err = anthropic.NewClient(llmConfig)
 		case llm.ProviderOpenAI:
 			llmClient, err = openai.NewClient(llmConfig)
+		case llm.ProviderAzureOpenAI:
+			llmClient, err = openai.NewAzureClient(llmConfig)
+		case llm.ProviderOllama:
+			llmClient, err = ollama.NewClient(llmConfig)
 		default:
 			log.Warn("Unknown LLM provider, AI features will be disabled",
 				logger.String("provider", cfg.LLM.Provider))
@@ -165,6 +218,10 @@ func run() error {
 				log.Info("AI service initialized",
 					logger.String("provider", string(llmClient.GetProvider())))
 				defer aiService.Close()
+				aiService.SetMetrics(metricsRegistry)
+				aiService.SetResponseCache(redis, &cfg.LLM)
+				aiService.SetCircuitBreaker(services.NewLLMCircuitBreaker(&cfg.CircuitBreaker, log, metricsRegistry))
+				executor.SetAIService(aiService)
 			}
 		}
 	} else {
@@ -173,8 +230,21 @@ func run() error {
 
 	// Initialize services
 	approvalService := services.NewApprovalService(approvalRepo, log, notificationService, workflowResumer, auditService, cfg.App.DefaultApproverEmail)
+	approvalService.SetUserDirectory(userRepo)
 	authService := services.NewAuthService(userRepo, apiKeyRepo, refreshTokenRepo, organizationRepo, jwtManager, log)
+	passwordPolicy, err := auth.NewPasswordPolicy(&cfg.PasswordPolicy)
+	if err != nil {
+		return fmt.Errorf("failed to load password policy: %w", err)
+	}
+	authService.SetPasswordPolicy(passwordPolicy)
 	scheduleService := services.NewScheduleService(scheduleRepo, log)
+	roleService := services.NewRoleService(
+		postgres.NewRoleRepository(db.DB),
+		postgres.NewPermissionRepository(db.DB),
+		userRepo,
+		organizationRepo,
+		log,
+	)
 
 	// Connect approval service to workflow executor (for create_approval_request actions)
 	executor.SetApprovalService(approvalService)
@@ -194,9 +264,65 @@ func run() error {
 	timeoutEnforcerWorker.Start(workerCtx)
 
 	// Initialize and start scheduler worker
-	schedulerWorker := workers.NewSchedulerWorker(scheduleService, eventRouter, log, 1*time.Minute)
+	schedulerWorker := workers.NewSchedulerWorker(scheduleService, eventRouter, log, cfg.Workers.SchedulerCheckInterval, cfg.Workers.SchedulerMaxCatchUpRuns)
 	schedulerWorker.Start(workerCtx)
 
+	// Initialize and start delay worker
+	delayWorker := workers.NewDelayWorker(executionRepo, executor, log, cfg.Workers.DelayCheckInterval)
+	delayWorker.Start(workerCtx)
+
+	// Initialize and start retention worker, exporting purged executions if an archive is configured
+	var retentionWorker *workers.RetentionWorker
+	if cfg.Retention.Enabled {
+		var archiveStore archive.Store
+		if cfg.Retention.ArchiveURL != "" {
+			archiveStore, err = archive.New(cfg.Retention.ArchiveURL, cfg.Retention.ArchiveToken)
+			if err != nil {
+				return fmt.Errorf("failed to initialize execution archive: %w", err)
+			}
+		}
+		retentionWorker = workers.NewRetentionWorker(executionRepo, archiveStore, log, &cfg.Retention)
+		retentionWorker.Start(workerCtx)
+	}
+
+	// Start refreshing the workflow index
+	if workflowIndex != nil {
+		workflowIndex.Start(workerCtx)
+	}
+
+	// Poll dependency health in the background, so the health endpoint answers from cache
+	healthDependencies := []health.Dependency{
+		{Name: "database", Checker: db, Critical: true},
+		{Name: "redis", Checker: redis, Critical: true},
+	}
+	if aiService != nil {
+		llmURL := cfg.LLM.BaseURL
+		if llmURL == "" {
+			llmURL = llm.DefaultBaseURL(llm.Provider(cfg.LLM.Provider))
+		}
+		healthDependencies = append(healthDependencies, health.Dependency{Name: "llm", Checker: health.NewHTTPChecker(llmURL)})
+	}
+	if cfg.ContextEnrichment.Enabled && cfg.ContextEnrichment.BaseURL != "" {
+		healthDependencies = append(healthDependencies, health.Dependency{Name: "enrichment", Checker: health.NewHTTPChecker(cfg.ContextEnrichment.BaseURL)})
+	}
+	healthPoller := health.NewPoller(healthDependencies, cfg.Health.PollInterval, cfg.Health.CheckTimeout, log)
+	healthPoller.Start(workerCtx)
+
+	// Consume events from a broker alongside HTTP, if one is configured
+	var eventConsumer *ingestion.Consumer
+	if cfg.Ingestion.Broker == "nats" {
+		var organizationID uuid.UUID
+		if cfg.Ingestion.OrganizationID != "" {
+			organizationID, err = uuid.Parse(cfg.Ingestion.OrganizationID)
+			if err != nil {
+				return fmt.Errorf("invalid INGESTION_ORGANIZATION_ID: %w", err)
+			}
+		}
+		source := ingestion.NewNATSSource(&cfg.Ingestion.NATS, cfg.Ingestion.BatchSize, cfg.Ingestion.FetchWait, log)
+		eventConsumer = ingestion.NewConsumer(source, eventRouter, organizationID, log)
+		eventConsumer.Start(workerCtx)
+	}
+
 	// Initialize handlers
 	h := handlers.NewHandlers(
 		log,
@@ -213,6 +339,7 @@ func run() error {
 		wsHub,
 		auditService,
 		ruleService,
+		roleService,
 		&handlers.HealthCheckers{
 			DB:    db,
 			Redis: redis,
@@ -220,8 +347,13 @@ func run() error {
 		cfg.App.Version,
 	)
 
+	h.Event.SetWebhookSecrets(webhookSecretRepo, metricsRegistry)
+	h.Health.SetPoller(healthPoller)
+	h.Retention = handlers.NewRetentionHandler(log, retentionRepo, &cfg.Retention)
+
 	// Initialize router
 	router := rest.NewRouter(log, h, authService, metricsRegistry)
+	router.SetRateLimiter(redis, &cfg.RateLimit)
 	router.SetupRoutes()
 
 	// Create HTTP server
@@ -251,18 +383,54 @@ func run() error {
 	case sig := <-shutdown:
 		log.Info("Shutdown signal received", logger.String("signal", sig.String()))
 
-		// Stop background workers first
+		// Stop taking events from the broker, then stop background workers
+		if eventConsumer != nil {
+			eventConsumer.Stop()
+		}
 		expirationWorker.Stop()
 		schedulerWorker.Stop()
+		delayWorker.Stop()
+		if retentionWorker != nil {
+			retentionWorker.Stop()
+		}
+		if workflowIndex != nil {
+			workflowIndex.Stop()
+		}
+		healthPoller.Stop()
 
 		// Give outstanding requests a deadline for completion
 		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 		defer cancel()
 
 		// Gracefully shutdown the server
-		if err := server.Shutdown(ctx); err != nil {
+		shutdownErr := server.Shutdown(ctx)
+		if shutdownErr != nil {
 			server.Close()
-			return fmt.Errorf("graceful shutdown failed: %w", err)
+		}
+
+		// Let workflow executions started by requests and workers finish before exiting
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.Server.DrainTimeout)
+		defer cancelDrain()
+		if interrupted := executor.Drain(drainCtx); interrupted > 0 {
+			log.Warn("Interrupted workflow executions still running at shutdown", logger.Int("count", interrupted))
+		}
+
+		// Dead-letter the routed events whose executions were turned away while draining
+		routedCtx, cancelRouted := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancelRouted()
+		if !eventRouter.Drain(routedCtx) {
+			log.Warn("Routed events still being handled at shutdown")
+		}
+
+		// Export the spans of drained executions
+		tracingCtx, cancelTracing := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancelTracing()
+		if err := shutdownTracing(tracingCtx); err != nil {
+			log.Warnf("Failed to flush traces: %v", err)
+		}
+
+		if shutdownErr != nil {
+			return fmt.Errorf("graceful shutdown failed: %w", shutdownErr)
 		}
 
 		log.Info("Server stopped gracefully")
@@ -12,6 +12,7 @@ import (
 
 	_ "github.com/lib/pq"
 	"github.com/davidmoltin/intelligent-workflows/internal/seeds"
+	"github.com/davidmoltin/intelligent-workflows/pkg/auth"
 )
 
 var (
@@ -21,6 +22,16 @@ var (
 	dbPassword = getEnv("DB_PASSWORD", "postgres")
 	dbName     = getEnv("DB_NAME", "workflows")
 	dbSSLMode  = getEnv("DB_SSL_MODE", "disable")
+
+	environment = getEnv("APP_ENV", "development")
+)
+
+// Development defaults for the admin user, used for credentials missing from the environment
+// when APP_ENV is development. The password is refused without --allow-insecure-default
+const (
+	defaultAdminUsername = "admin"
+	defaultAdminEmail    = "admin@example.com"
+	defaultAdminPassword = "admin123"
 )
 
 func main() {
@@ -29,7 +40,9 @@ func main() {
 		seedUsers   = flag.Bool("users", false, "Seed default users (admin user)")
 		verifyOnly  = flag.Bool("verify", false, "Only verify existing RBAC data, don't seed")
 		statsOnly   = flag.Bool("stats", false, "Only show RBAC statistics")
-		force       = flag.Bool("force", false, "Force re-seeding (updates existing data)")
+		force       = flag.Bool("force", false, "Revoke permissions the default roles are granted but should not be")
+
+		allowInsecureDefault = flag.Bool("allow-insecure-default", false, "Allow seeding the admin user with a weak or default password")
 	)
 	flag.Parse()
 
@@ -37,6 +50,16 @@ func main() {
 	log.SetFlags(log.LstdFlags)
 	log.SetPrefix("[RBAC Seed] ")
 
+	// Read the admin credentials before connecting, so missing ones fail fast
+	var users []seeds.DefaultUser
+	if *seedUsers && !*statsOnly && !*verifyOnly {
+		admin, err := adminCredentials(*allowInsecureDefault)
+		if err != nil {
+			log.Fatalf("Invalid admin credentials: %v", err)
+		}
+		users = seeds.GetDefaultUsers(admin)
+	}
+
 	// Connect to database
 	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		dbHost, dbPort, dbUser, dbPassword, dbName, dbSSLMode)
@@ -84,10 +107,10 @@ func main() {
 		// Seed mode (default)
 		log.Println("\n=== Seeding Mode ===")
 		if !*force {
-			log.Println("Note: Existing data will be preserved (use --force to update)")
+			log.Println("Note: Permissions removed from the default roles stay granted (use --force to revoke them)")
 		}
 
-		if err := seeder.SeedAll(ctx, *seedUsers); err != nil {
+		if err := seeder.SeedAll(ctx, users, *force); err != nil {
 			log.Fatalf("Seeding failed: %v", err)
 		}
 
@@ -109,17 +132,58 @@ func main() {
 		log.Println("✓✓✓ RBAC seeding completed successfully! ✓✓✓")
 		fmt.Println(strings.Repeat("=", 60))
 
-		if *seedUsers {
-			fmt.Println("\n⚠️  DEFAULT ADMIN CREDENTIALS:")
-			fmt.Println("   Username: admin")
-			fmt.Println("   Email:    admin@example.com")
-			fmt.Println("   Password: admin123")
-			fmt.Println("\n⚠️  IMPORTANT: Change the default password after first login!")
+		for _, user := range users {
+			fmt.Println("\nAdmin user:")
+			fmt.Println("   Username:", user.Username)
+			fmt.Println("   Email:   ", user.Email)
 			fmt.Println(strings.Repeat("=", 60))
 		}
 	}
 }
 
+// adminCredentials reads the admin user's credentials from SEED_ADMIN_USERNAME,
+// SEED_ADMIN_EMAIL and SEED_ADMIN_PASSWORD. Outside development all three are required; in
+// development missing ones fall back to the defaults. Passwords that fail the default password
+// policy, including the default one, are refused unless allowInsecure is set
+func adminCredentials(allowInsecure bool) (seeds.AdminCredentials, error) {
+	admin := seeds.AdminCredentials{
+		Username: os.Getenv("SEED_ADMIN_USERNAME"),
+		Email:    os.Getenv("SEED_ADMIN_EMAIL"),
+		Password: os.Getenv("SEED_ADMIN_PASSWORD"),
+	}
+
+	if environment != "development" {
+		var missing []string
+		for _, name := range []string{"SEED_ADMIN_USERNAME", "SEED_ADMIN_EMAIL", "SEED_ADMIN_PASSWORD"} {
+			if os.Getenv(name) == "" {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			return admin, fmt.Errorf("%s must be set to seed users in the %s environment", strings.Join(missing, ", "), environment)
+		}
+	}
+
+	if admin.Username == "" {
+		admin.Username = defaultAdminUsername
+	}
+	if admin.Email == "" {
+		admin.Email = defaultAdminEmail
+	}
+	if admin.Password == "" {
+		admin.Password = defaultAdminPassword
+	}
+
+	if violations := auth.DefaultPasswordPolicy().Check(admin.Password); len(violations) > 0 {
+		if !allowInsecure {
+			return admin, fmt.Errorf("admin password %s; set a stronger SEED_ADMIN_PASSWORD or pass --allow-insecure-default", strings.Join(violations, ", "))
+		}
+		log.Println("⚠️  Seeding the admin user with a weak password (--allow-insecure-default). Change it after first login!")
+	}
+
+	return admin, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
+	"github.com/davidmoltin/intelligent-workflows/internal/api/rest/middleware"
 	"github.com/davidmoltin/intelligent-workflows/internal/services"
 	"github.com/davidmoltin/intelligent-workflows/pkg/llm"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -132,7 +135,7 @@ func (h *AIHandler) Chat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Handle regular chat
-	resp, err := h.aiService.Chat(r.Context(), chatReq)
+	resp, err := h.aiService.Chat(usageContext(r), chatReq)
 	if err != nil {
 		h.logger.Error("chat request failed", zap.Error(err))
 		respondLLMError(w, err)
@@ -187,7 +190,7 @@ func (h *AIHandler) handleStreamingChat(w http.ResponseWriter, r *http.Request,
 		return nil
 	}
 
-	if err := h.aiService.StreamChat(r.Context(), req, handler); err != nil {
+	if err := h.aiService.StreamChat(usageContext(r), req, handler); err != nil {
 		h.logger.Error("streaming chat failed", zap.Error(err))
 		// Can't send error response after streaming started
 		return
@@ -241,7 +244,7 @@ func (h *AIHandler) InterpretWorkflow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	workflow, err := h.aiService.InterpretWorkflow(r.Context(), req.Description)
+	workflow, err := h.aiService.InterpretWorkflow(usageContext(r), req.Description)
 	if err != nil {
 		h.logger.Error("workflow interpretation failed", zap.Error(err))
 		respondLLMError(w, err)
@@ -257,6 +260,15 @@ func (h *AIHandler) InterpretWorkflow(w http.ResponseWriter, r *http.Request) {
 
 // Helper functions
 
+// usageContext attributes the LLM usage of a request to the caller's organization
+func usageContext(r *http.Request) context.Context {
+	scope := &llm.UsageScope{}
+	if organizationID := middleware.GetOrganizationID(r.Context()); organizationID != uuid.Nil {
+		scope.OrganizationID = organizationID.String()
+	}
+	return llm.WithUsageScope(r.Context(), scope)
+}
+
 // respondJSON writes a JSON response
 func respondJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
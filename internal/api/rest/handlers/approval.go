@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -157,8 +158,7 @@ func (h *ApprovalHandler) ApproveRequest(w http.ResponseWriter, r *http.Request)
 	approval, err := h.approvalService.ApproveRequest(r.Context(), organizationID, id, userID, req.Reason)
 	if err != nil {
 		h.logger.Errorf("Failed to approve request: %v", err)
-		// Don't leak internal error details
-		RespondError(w, http.StatusBadRequest, "Failed to approve request")
+		h.respondDecisionError(w, err, "Failed to approve request")
 		return
 	}
 
@@ -201,11 +201,119 @@ func (h *ApprovalHandler) RejectRequest(w http.ResponseWriter, r *http.Request)
 	approval, err := h.approvalService.RejectRequest(r.Context(), organizationID, id, userID, req.Reason)
 	if err != nil {
 		h.logger.Errorf("Failed to reject request: %v", err)
-		// Don't leak internal error details
-		RespondError(w, http.StatusBadRequest, "Failed to reject request")
+		h.respondDecisionError(w, err, "Failed to reject request")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(approval)
 }
+
+// respondDecisionError responds to a failed approve or reject with the status of a known error,
+// or a generic message so internal error details are not leaked
+func (h *ApprovalHandler) respondDecisionError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrDuplicateApprovalDecision):
+		RespondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrNotDesignatedApprover):
+		RespondError(w, http.StatusForbidden, err.Error())
+	default:
+		RespondError(w, http.StatusBadRequest, message)
+	}
+}
+
+// CreateDelegation handles POST /api/v1/approvals/delegations
+func (h *ApprovalHandler) CreateDelegation(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	var req models.CreateApprovalDelegationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	delegation, err := h.approvalService.CreateDelegation(r.Context(), organizationID, userID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDelegation) {
+			RespondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Errorf("Failed to create delegation: %v", err)
+		RespondError(w, http.StatusInternalServerError, "Failed to create delegation")
+		return
+	}
+
+	RespondJSON(w, http.StatusCreated, delegation)
+}
+
+// ListDelegations handles GET /api/v1/approvals/delegations
+func (h *ApprovalHandler) ListDelegations(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	delegations, err := h.approvalService.ListDelegations(r.Context(), organizationID, userID)
+	if err != nil {
+		h.logger.Errorf("Failed to list delegations: %v", err)
+		RespondError(w, http.StatusInternalServerError, "Failed to retrieve delegations")
+		return
+	}
+	if delegations == nil {
+		delegations = []models.ApprovalDelegation{}
+	}
+
+	RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"delegations": delegations,
+	})
+}
+
+// RevokeDelegation handles DELETE /api/v1/approvals/delegations/:id
+func (h *ApprovalHandler) RevokeDelegation(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid delegation ID")
+		return
+	}
+
+	if err := h.approvalService.RevokeDelegation(r.Context(), organizationID, id, userID); err != nil {
+		if errors.Is(err, services.ErrDelegationNotFound) {
+			RespondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.logger.Errorf("Failed to revoke delegation: %v", err)
+		RespondError(w, http.StatusInternalServerError, "Failed to revoke delegation")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -44,6 +45,9 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.authService.Register(r.Context(), &req)
 	if err != nil {
+		if h.respondPasswordPolicyError(w, err) {
+			return
+		}
 		h.logger.Errorf("Failed to register user", logger.Err(err))
 		// Don't leak internal error details
 		h.respondError(w, http.StatusBadRequest, "Failed to register user")
@@ -95,6 +99,10 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	tokenPair, err := h.authService.RefreshToken(r.Context(), req.RefreshToken)
+	if errors.Is(err, services.ErrRefreshTokenReused) {
+		h.respondError(w, http.StatusUnauthorized, "Refresh token was already used; all sessions from this login were signed out")
+		return
+	}
 	if err != nil {
 		h.logger.Errorf("Failed to refresh token", logger.Err(err))
 		h.respondError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
@@ -147,6 +155,20 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// respondPasswordPolicyError responds with the rules a rejected password breaks, per field. It
+// reports whether err was a password policy error
+func (h *AuthHandler) respondPasswordPolicyError(w http.ResponseWriter, err error) bool {
+	var policyErr *services.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	h.respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"error":  "Password does not meet the password policy",
+		"errors": policyErr.Fields,
+	})
+	return true
+}
+
 // ChangePassword handles password change
 func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
@@ -169,6 +191,9 @@ func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.authService.ChangePassword(r.Context(), claims.UserID, &req); err != nil {
+		if h.respondPasswordPolicyError(w, err) {
+			return
+		}
 		h.logger.Errorf("Failed to change password", logger.Err(err))
 		// Don't leak internal error details
 		h.respondError(w, http.StatusBadRequest, "Failed to change password")
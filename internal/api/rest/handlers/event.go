@@ -1,21 +1,44 @@
 package handlers
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/api/rest/middleware"
 	"github.com/davidmoltin/intelligent-workflows/internal/engine"
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/metrics"
 	"github.com/davidmoltin/intelligent-workflows/pkg/validator"
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// SignatureHeader carries the hex-encoded HMAC-SHA256 of an event's request body, optionally
+// prefixed with "sha256="
+const SignatureHeader = "X-Signature"
+
+// WebhookSecretStore looks up and manages the secrets that sign inbound events
+type WebhookSecretStore interface {
+	GetSecret(ctx context.Context, organizationID uuid.UUID, source string) (string, error)
+	SetSecret(ctx context.Context, organizationID uuid.UUID, source, secret string) error
+	DeleteSecret(ctx context.Context, organizationID uuid.UUID, source string) error
+}
+
 // EventHandler handles event-related HTTP requests
 type EventHandler struct {
-	logger      *logger.Logger
-	eventRouter *engine.EventRouter
+	logger         *logger.Logger
+	eventRouter    *engine.EventRouter
+	webhookSecrets WebhookSecretStore
+	metrics        *metrics.Metrics
 }
 
 // NewEventHandler creates a new event handler
@@ -26,6 +49,13 @@ func NewEventHandler(log *logger.Logger, eventRouter *engine.EventRouter) *Event
 	}
 }
 
+// SetWebhookSecrets enables signature verification of events from sources with a secret.
+// Rejected events are counted in m, which may be nil
+func (h *EventHandler) SetWebhookSecrets(store WebhookSecretStore, m *metrics.Metrics) {
+	h.webhookSecrets = store
+	h.metrics = m
+}
+
 // CreateEvent handles POST /api/v1/events
 func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	// Get organization ID from context
@@ -35,17 +65,18 @@ func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req models.CreateEventRequest
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Errorf("Failed to decode request: %v", err)
+	// The raw body is kept to verify its signature
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.logger.Errorf("Failed to read request: %v", err)
 		RespondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	// Validate request
-	if err := validator.Validate(&req); err != nil {
-		RespondError(w, http.StatusBadRequest, err.Error())
+	var req models.CreateEventRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		h.logger.Errorf("Failed to decode request: %v", err)
+		RespondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
@@ -54,6 +85,16 @@ func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		req.Source = "api"
 	}
 
+	if !h.verifySignature(w, r, organizationID, req.Source, body) {
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Route event to workflows with organization context
 	event, err := h.eventRouter.RouteEvent(r.Context(), organizationID, req.EventType, req.Source, req.Payload)
 	if err != nil {
@@ -65,3 +106,314 @@ func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	// Return event
 	RespondJSON(w, http.StatusCreated, event)
 }
+
+// verifySignature checks the signature of an event from a source with a secret, responding
+// with an error and returning false if it does not match. Events from sources without a
+// secret are accepted unsigned
+func (h *EventHandler) verifySignature(w http.ResponseWriter, r *http.Request, organizationID uuid.UUID, source string, body []byte) bool {
+	if h.webhookSecrets == nil {
+		return true
+	}
+
+	secret, err := h.webhookSecrets.GetSecret(r.Context(), organizationID, source)
+	if err != nil {
+		h.logger.Errorf("Failed to get webhook secret: %v", err)
+		RespondError(w, http.StatusInternalServerError, "Failed to process event")
+		return false
+	}
+	if secret == "" {
+		return true
+	}
+
+	signature := r.Header.Get(SignatureHeader)
+	reason := ""
+	switch {
+	case signature == "":
+		reason = "missing"
+	case !validSignature(secret, body, signature):
+		reason = "invalid"
+	default:
+		return true
+	}
+
+	h.logger.Warnf("Rejected event from source %q for organization %s: %s signature", source, organizationID, reason)
+	if h.metrics != nil {
+		h.metrics.WebhookSignatureFailures.WithLabelValues(reason).Inc()
+	}
+	RespondError(w, http.StatusUnauthorized, "Invalid event signature")
+	return false
+}
+
+// validSignature reports whether signature is the HMAC-SHA256 of body under secret, comparing
+// in constant time
+func validSignature(secret string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// SetWebhookSecret handles PUT /api/v1/events/sources/{source}/secret
+func (h *EventHandler) SetWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	if h.webhookSecrets == nil {
+		RespondError(w, http.StatusServiceUnavailable, "Webhook signatures are not enabled")
+		return
+	}
+
+	var req models.SetWebhookSecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	source := chi.URLParam(r, "source")
+	if err := h.webhookSecrets.SetSecret(r.Context(), organizationID, source, req.Secret); err != nil {
+		h.logger.Errorf("Failed to set webhook secret: %v", err)
+		RespondError(w, http.StatusInternalServerError, "Failed to set webhook secret")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, map[string]string{"message": "Webhook secret set successfully"})
+}
+
+// DeleteWebhookSecret handles DELETE /api/v1/events/sources/{source}/secret
+func (h *EventHandler) DeleteWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	if h.webhookSecrets == nil {
+		RespondError(w, http.StatusServiceUnavailable, "Webhook signatures are not enabled")
+		return
+	}
+
+	if err := h.webhookSecrets.DeleteSecret(r.Context(), organizationID, chi.URLParam(r, "source")); err != nil {
+		h.logger.Errorf("Failed to delete webhook secret: %v", err)
+		RespondError(w, http.StatusInternalServerError, "Failed to delete webhook secret")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, map[string]string{"message": "Webhook secret deleted successfully"})
+}
+
+// ListDeadLetterEvents handles GET /api/v1/events/dead-letter
+func (h *EventHandler) ListDeadLetterEvents(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	var replayed *bool
+	if replayedStr := r.URL.Query().Get("replayed"); replayedStr != "" {
+		value, err := strconv.ParseBool(replayedStr)
+		if err != nil {
+			RespondError(w, http.StatusBadRequest, "Invalid replayed filter")
+			return
+		}
+		replayed = &value
+	}
+
+	// Parse pagination
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	events, total, err := h.eventRouter.ListDeadLetterEvents(r.Context(), organizationID, replayed, limit, offset)
+	if err != nil {
+		h.logger.Errorf("Failed to list dead-letter events: %v", err)
+		if errors.Is(err, engine.ErrDeadLetterUnavailable) {
+			RespondError(w, http.StatusServiceUnavailable, "Dead-letter queue is not available")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Failed to list dead-letter events")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, models.DeadLetterEventListResponse{
+		Events:   events,
+		Total:    total,
+		Page:     offset / limit,
+		PageSize: limit,
+	})
+}
+
+// ReplayDeadLetterEvent handles POST /api/v1/events/dead-letter/{id}/replay
+// Events that failed in a workflow are re-run through it asynchronously; events that failed
+// before they were routed are routed again
+func (h *EventHandler) ReplayDeadLetterEvent(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid dead-letter event ID")
+		return
+	}
+
+	event, err := h.eventRouter.ReplayDeadLetterEvent(r.Context(), organizationID, id)
+	if err != nil {
+		h.logger.Errorf("Failed to replay dead-letter event: %v", err)
+		switch {
+		case errors.Is(err, engine.ErrDeadLetterUnavailable):
+			RespondError(w, http.StatusServiceUnavailable, "Dead-letter queue is not available")
+		case errors.Is(err, engine.ErrDeadLetterEventNotFound):
+			RespondError(w, http.StatusNotFound, "Dead-letter event not found")
+		case errors.Is(err, engine.ErrDeadLetterAlreadyReplayed):
+			RespondError(w, http.StatusConflict, "Dead-letter event has already been replayed")
+		case errors.Is(err, engine.ErrWorkflowNotFound):
+			RespondError(w, http.StatusNotFound, "Workflow not found")
+		case errors.Is(err, engine.ErrWorkflowDisabled):
+			RespondError(w, http.StatusConflict, "Workflow is disabled")
+		default:
+			RespondError(w, http.StatusInternalServerError, "Failed to replay dead-letter event")
+		}
+		return
+	}
+
+	RespondJSON(w, http.StatusAccepted, event)
+}
+
+// DryRunWorkflow handles POST /api/v1/workflows/{id}/dry-run
+func (h *EventHandler) DryRunWorkflow(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	workflowID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid workflow ID")
+		return
+	}
+
+	var req models.DryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Errorf("Failed to decode request: %v", err)
+		RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	execution, err := h.eventRouter.DryRunWorkflow(r.Context(), organizationID, workflowID, req.EventType, req.Payload)
+	if err != nil {
+		h.logger.Errorf("Failed to dry-run workflow: %v", err)
+		if errors.Is(err, engine.ErrWorkflowNotFound) {
+			RespondError(w, http.StatusNotFound, "Workflow not found")
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "Failed to dry-run workflow")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, execution)
+}
+
+// TriggerErrorResponse is returned when a synchronously triggered workflow fails after it
+// started, so the caller can look up the failed execution's trace
+type TriggerErrorResponse struct {
+	ErrorResponse
+	ExecutionID string `json:"execution_id"`
+}
+
+// TriggerWorkflow handles POST /api/v1/workflows/{id}/trigger
+// The workflow runs synchronously. If it ends with a response action, that action's
+// status code and body are returned; otherwise the execution itself is returned.
+func (h *EventHandler) TriggerWorkflow(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	workflowID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid workflow ID")
+		return
+	}
+
+	// The payload is optional, so an empty body is accepted
+	var req models.TriggerWorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Errorf("Failed to decode request: %v", err)
+		RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Version < 0 {
+		RespondError(w, http.StatusBadRequest, "Invalid workflow version")
+		return
+	}
+
+	if req.Payload == nil {
+		req.Payload = make(map[string]interface{})
+	}
+
+	execution, err := h.eventRouter.TriggerWorkflowManually(r.Context(), organizationID, workflowID, req.Version, req.Payload)
+	if err != nil {
+		h.logger.Errorf("Failed to trigger workflow: %v", err)
+		switch {
+		case errors.Is(err, engine.ErrWorkflowNotFound):
+			RespondError(w, http.StatusNotFound, "Workflow not found")
+		case errors.Is(err, engine.ErrWorkflowVersionNotFound):
+			RespondError(w, http.StatusNotFound, "Workflow version not found")
+		case errors.Is(err, engine.ErrWorkflowDisabled):
+			RespondError(w, http.StatusConflict, "Workflow is disabled")
+		case errors.Is(err, engine.ErrConcurrencyLimitReached):
+			RespondError(w, http.StatusConflict, "Workflow concurrency limit reached")
+		case errors.Is(err, engine.ErrRateLimited):
+			RespondError(w, http.StatusTooManyRequests, "Workflow execution rate limit exceeded")
+		case execution != nil:
+			w.Header().Set("X-Execution-ID", execution.ID.String())
+			RespondJSON(w, http.StatusInternalServerError, TriggerErrorResponse{
+				ErrorResponse: ErrorResponse{
+					Error:   http.StatusText(http.StatusInternalServerError),
+					Message: "Workflow execution failed",
+				},
+				ExecutionID: execution.ID.String(),
+			})
+		default:
+			RespondError(w, http.StatusInternalServerError, "Failed to execute workflow")
+		}
+		return
+	}
+
+	w.Header().Set("X-Execution-ID", execution.ID.String())
+
+	if resp := execution.Response(); resp != nil {
+		RespondJSON(w, resp.StatusCode, resp.Body)
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, execution)
+}
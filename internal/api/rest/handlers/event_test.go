@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/engine"
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Mock engine.WorkflowRepository for event handler tests
+type mockEventWorkflowRepo struct {
+	workflow *models.Workflow
+}
+
+func (m *mockEventWorkflowRepo) GetWorkflowByID(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error) {
+	if m.workflow != nil && m.workflow.ID == id {
+		return m.workflow, nil
+	}
+	return nil, errors.New("workflow not found")
+}
+
+func (m *mockEventWorkflowRepo) GetByWorkflowID(ctx context.Context, organizationID uuid.UUID, workflowID string) (*models.Workflow, error) {
+	return nil, errors.New("workflow not found")
+}
+
+func (m *mockEventWorkflowRepo) ListWorkflows(ctx context.Context, organizationID uuid.UUID, enabled *bool, limit, offset int) ([]models.Workflow, int64, error) {
+	return []models.Workflow{}, 0, nil
+}
+
+// Mock engine.EventRepository for event handler tests
+type mockEventRepoHandler struct{}
+
+func (m *mockEventRepoHandler) CreateEvent(ctx context.Context, event *models.Event) error {
+	return nil
+}
+
+func (m *mockEventRepoHandler) UpdateEvent(ctx context.Context, organizationID uuid.UUID, event *models.Event) error {
+	return nil
+}
+
+func (m *mockEventRepoHandler) GetEventByID(ctx context.Context, organizationID, id uuid.UUID) (*models.Event, error) {
+	return nil, errors.New("not found")
+}
+
+// Mock engine.ExecutionRepository for event handler tests
+type mockEventExecutionRepo struct {
+	createErr error
+}
+
+func (m *mockEventExecutionRepo) CreateExecution(ctx context.Context, execution *models.WorkflowExecution) error {
+	return m.createErr
+}
+
+func (m *mockEventExecutionRepo) UpdateExecution(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
+	return nil
+}
+
+func (m *mockEventExecutionRepo) GetExecutionByID(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowExecution, error) {
+	return nil, errors.New("not found")
+}
+
+func (m *mockEventExecutionRepo) CreateStepExecution(ctx context.Context, step *models.StepExecution) error {
+	return nil
+}
+
+func (m *mockEventExecutionRepo) UpdateStepExecution(ctx context.Context, organizationID uuid.UUID, step *models.StepExecution) error {
+	return nil
+}
+
+func (m *mockEventExecutionRepo) GetTimedOutExecutions(ctx context.Context, organizationID uuid.UUID, limit int) ([]*models.WorkflowExecution, error) {
+	return nil, nil
+}
+
+func (m *mockEventExecutionRepo) GetExecutionByIdempotencyKey(ctx context.Context, organizationID, workflowID uuid.UUID, key string) (*models.WorkflowExecution, error) {
+	return nil, errors.New("not found")
+}
+
+func (m *mockEventExecutionRepo) MarkExecutionRerun(ctx context.Context, organizationID, id uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+func (m *mockEventExecutionRepo) InterruptExecution(ctx context.Context, organizationID, id uuid.UUID, reason string) (bool, error) {
+	return true, nil
+}
+
+// newTestEventHandler wires an EventHandler to a real event router backed by mock repositories
+func newTestEventHandler(workflow *models.Workflow, executionRepo *mockEventExecutionRepo) *EventHandler {
+	log := logger.NewForTesting()
+	workflowRepo := &mockEventWorkflowRepo{workflow: workflow}
+
+	// Enrichment is disabled, so Redis is never contacted
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := engine.NewWorkflowExecutor(redisClient, executionRepo, workflowRepo, nil, log, nil, &config.ContextEnrichmentConfig{
+		Enabled:  false,
+		Timeout:  10 * time.Second,
+		CacheTTL: 5 * time.Minute,
+	})
+
+	return NewEventHandler(log, engine.NewEventRouter(workflowRepo, &mockEventRepoHandler{}, executor, log))
+}
+
+// newWorkflowRequest builds a request for a workflow-scoped route within an organization
+func newWorkflowRequest(workflowID string, body interface{}) *http.Request {
+	bodyBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/api/v1/workflows/"+workflowID, bytes.NewReader(bodyBytes))
+	ctx := context.WithValue(createChiContext(workflowID), "organization_id", uuid.New())
+	return req.WithContext(ctx)
+}
+
+// TestDryRunWorkflow_Handler tests the DryRunWorkflow HTTP handler
+func TestDryRunWorkflow_Handler(t *testing.T) {
+	workflow := &models.Workflow{
+		ID:         uuid.New(),
+		WorkflowID: "dry-run-wf",
+		Name:       "Dry Run Workflow",
+		Definition: models.WorkflowDefinition{
+			Trigger: models.TriggerDefinition{Type: "event", Event: "order.created"},
+			Steps: []models.Step{
+				{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}},
+			},
+		},
+	}
+	body := map[string]interface{}{"payload": map[string]interface{}{"order": map[string]interface{}{"id": "A1"}}}
+
+	t.Run("returns the dry-run execution", func(t *testing.T) {
+		handler := newTestEventHandler(workflow, &mockEventExecutionRepo{})
+
+		w := httptest.NewRecorder()
+		handler.DryRunWorkflow(w, newWorkflowRequest(workflow.ID.String(), body))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var response models.WorkflowExecution
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response.Metadata["dry_run"] != true {
+			t.Errorf("Expected dry_run metadata, got %v", response.Metadata)
+		}
+	})
+
+	t.Run("returns 404 for unknown workflow", func(t *testing.T) {
+		handler := newTestEventHandler(workflow, &mockEventExecutionRepo{})
+
+		w := httptest.NewRecorder()
+		handler.DryRunWorkflow(w, newWorkflowRequest(uuid.New().String(), body))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("returns 500 when the execution cannot be recorded", func(t *testing.T) {
+		handler := newTestEventHandler(workflow, &mockEventExecutionRepo{createErr: errors.New("connection refused")})
+
+		w := httptest.NewRecorder()
+		handler.DryRunWorkflow(w, newWorkflowRequest(workflow.ID.String(), body))
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status 500, got %d", w.Code)
+		}
+	})
+}
+
+// TestTriggerWorkflow_Handler tests the TriggerWorkflow HTTP handler
+func TestTriggerWorkflow_Handler(t *testing.T) {
+	newWorkflow := func(enabled bool, steps ...models.Step) *models.Workflow {
+		return &models.Workflow{
+			ID:         uuid.New(),
+			WorkflowID: "trigger-wf",
+			Name:       "Trigger Workflow",
+			Enabled:    enabled,
+			Definition: models.WorkflowDefinition{
+				Trigger: models.TriggerDefinition{Type: "manual"},
+				Steps:   steps,
+			},
+		}
+	}
+	body := map[string]interface{}{"payload": map[string]interface{}{"order": map[string]interface{}{"id": "ord-1"}}}
+
+	t.Run("returns the response action's status and body", func(t *testing.T) {
+		workflow := newWorkflow(true, models.Step{
+			ID:   "respond",
+			Type: "action",
+			Action: &models.Action{
+				Type:       "response",
+				StatusCode: http.StatusAccepted,
+				Data:       map[string]interface{}{"order_id": "${order.id}"},
+			},
+		})
+		handler := newTestEventHandler(workflow, &mockEventExecutionRepo{})
+
+		w := httptest.NewRecorder()
+		handler.TriggerWorkflow(w, newWorkflowRequest(workflow.ID.String(), body))
+
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+		}
+		if w.Header().Get("X-Execution-ID") == "" {
+			t.Error("Expected X-Execution-ID header")
+		}
+
+		var response map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response["order_id"] != "ord-1" {
+			t.Errorf("Expected response body from the workflow, got %v", response)
+		}
+	})
+
+	t.Run("returns the execution when no response action ran", func(t *testing.T) {
+		workflow := newWorkflow(true, models.Step{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}})
+		handler := newTestEventHandler(workflow, &mockEventExecutionRepo{})
+
+		w := httptest.NewRecorder()
+		handler.TriggerWorkflow(w, newWorkflowRequest(workflow.ID.String(), body))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var response models.WorkflowExecution
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response.Result == nil || *response.Result != models.ExecutionResultAllowed {
+			t.Errorf("Expected allowed execution, got %v", response.Result)
+		}
+	})
+
+	t.Run("returns 404 for unknown workflow", func(t *testing.T) {
+		handler := newTestEventHandler(newWorkflow(true), &mockEventExecutionRepo{})
+
+		w := httptest.NewRecorder()
+		handler.TriggerWorkflow(w, newWorkflowRequest(uuid.New().String(), body))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("returns 409 for disabled workflow", func(t *testing.T) {
+		workflow := newWorkflow(false)
+		handler := newTestEventHandler(workflow, &mockEventExecutionRepo{})
+
+		w := httptest.NewRecorder()
+		handler.TriggerWorkflow(w, newWorkflowRequest(workflow.ID.String(), body))
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("Expected status 409, got %d", w.Code)
+		}
+	})
+
+	t.Run("returns 404 for unknown version", func(t *testing.T) {
+		workflow := newWorkflow(true)
+		handler := newTestEventHandler(workflow, &mockEventExecutionRepo{})
+
+		w := httptest.NewRecorder()
+		handler.TriggerWorkflow(w, newWorkflowRequest(workflow.ID.String(), map[string]interface{}{"version": 3}))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("returns 400 for negative version", func(t *testing.T) {
+		workflow := newWorkflow(true)
+		handler := newTestEventHandler(workflow, &mockEventExecutionRepo{})
+
+		w := httptest.NewRecorder()
+		handler.TriggerWorkflow(w, newWorkflowRequest(workflow.ID.String(), map[string]interface{}{"version": -1}))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("returns the execution ID when the workflow fails", func(t *testing.T) {
+		workflow := newWorkflow(true, models.Step{ID: "broken", Type: "condition"})
+		handler := newTestEventHandler(workflow, &mockEventExecutionRepo{})
+
+		w := httptest.NewRecorder()
+		handler.TriggerWorkflow(w, newWorkflowRequest(workflow.ID.String(), body))
+
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("Expected status 500, got %d", w.Code)
+		}
+
+		var response TriggerErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response.ExecutionID == "" || response.ExecutionID != w.Header().Get("X-Execution-ID") {
+			t.Errorf("Expected execution ID in body and header, got %q and %q", response.ExecutionID, w.Header().Get("X-Execution-ID"))
+		}
+	})
+}
+
+// Mock WebhookSecretStore with one secret per source
+type mockWebhookSecrets map[string]string
+
+func (m mockWebhookSecrets) GetSecret(ctx context.Context, organizationID uuid.UUID, source string) (string, error) {
+	return m[source], nil
+}
+
+func (m mockWebhookSecrets) SetSecret(ctx context.Context, organizationID uuid.UUID, source, secret string) error {
+	m[source] = secret
+	return nil
+}
+
+func (m mockWebhookSecrets) DeleteSecret(ctx context.Context, organizationID uuid.UUID, source string) error {
+	delete(m, source)
+	return nil
+}
+
+// TestCreateEvent_Signature tests signature verification of inbound events
+func TestCreateEvent_Signature(t *testing.T) {
+	secret := "shopify-signing-secret"
+	body := []byte(`{"event_type":"order.created","source":"shopify","payload":{"order":{"id":"A1"}}}`)
+
+	sign := func(secret string, body []byte) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name      string
+		secrets   mockWebhookSecrets
+		body      []byte
+		signature string
+		expected  int
+	}{
+		{name: "accepts a valid signature", secrets: mockWebhookSecrets{"shopify": secret}, body: body, signature: sign(secret, body), expected: http.StatusCreated},
+		{name: "accepts a prefixed signature", secrets: mockWebhookSecrets{"shopify": secret}, body: body, signature: "sha256=" + sign(secret, body), expected: http.StatusCreated},
+		{name: "rejects a missing signature", secrets: mockWebhookSecrets{"shopify": secret}, body: body, expected: http.StatusUnauthorized},
+		{name: "rejects a signature made with another secret", secrets: mockWebhookSecrets{"shopify": secret}, body: body, signature: sign("another-signing-secret", body), expected: http.StatusUnauthorized},
+		{name: "rejects a signature of another body", secrets: mockWebhookSecrets{"shopify": secret}, body: body, signature: sign(secret, []byte(`{}`)), expected: http.StatusUnauthorized},
+		{name: "rejects a malformed signature", secrets: mockWebhookSecrets{"shopify": secret}, body: body, signature: "not-hex", expected: http.StatusUnauthorized},
+		{name: "accepts unsigned events from sources without a secret", secrets: mockWebhookSecrets{"stripe": secret}, body: body, expected: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestEventHandler(nil, &mockEventExecutionRepo{})
+			handler.SetWebhookSecrets(tt.secrets, nil)
+
+			req := httptest.NewRequest("POST", "/api/v1/events", bytes.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), "organization_id", uuid.New()))
+			if tt.signature != "" {
+				req.Header.Set(SignatureHeader, tt.signature)
+			}
+
+			w := httptest.NewRecorder()
+			handler.CreateEvent(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
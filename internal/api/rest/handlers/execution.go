@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/api/rest/middleware"
+	"github.com/davidmoltin/intelligent-workflows/internal/engine"
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
 	"github.com/davidmoltin/intelligent-workflows/internal/repository/postgres"
 	"github.com/davidmoltin/intelligent-workflows/internal/services"
@@ -15,19 +18,24 @@ import (
 	"github.com/google/uuid"
 )
 
+// maxRerunBatchSize caps how many executions a single bulk re-run request may re-queue
+const maxRerunBatchSize = 100
+
 // ExecutionHandler handles execution-related HTTP requests
 type ExecutionHandler struct {
 	logger          *logger.Logger
 	executionRepo   *postgres.ExecutionRepository
 	workflowResumer *services.WorkflowResumerImpl
+	eventRouter     *engine.EventRouter
 }
 
 // NewExecutionHandler creates a new execution handler
-func NewExecutionHandler(log *logger.Logger, executionRepo *postgres.ExecutionRepository, workflowResumer *services.WorkflowResumerImpl) *ExecutionHandler {
+func NewExecutionHandler(log *logger.Logger, executionRepo *postgres.ExecutionRepository, workflowResumer *services.WorkflowResumerImpl, eventRouter *engine.EventRouter) *ExecutionHandler {
 	return &ExecutionHandler{
 		logger:          log,
 		executionRepo:   executionRepo,
 		workflowResumer: workflowResumer,
+		eventRouter:     eventRouter,
 	}
 }
 
@@ -41,27 +49,13 @@ func (h *ExecutionHandler) ListExecutions(w http.ResponseWriter, r *http.Request
 	}
 
 	// Parse query parameters
-	workflowIDStr := r.URL.Query().Get("workflow_id")
-	statusStr := r.URL.Query().Get("status")
 	limitStr := r.URL.Query().Get("limit")
 	offsetStr := r.URL.Query().Get("offset")
 
-	// Parse workflow_id
-	var workflowID *uuid.UUID
-	if workflowIDStr != "" {
-		id, err := uuid.Parse(workflowIDStr)
-		if err != nil {
-			http.Error(w, "Invalid workflow_id", http.StatusBadRequest)
-			return
-		}
-		workflowID = &id
-	}
-
-	// Parse status
-	var status *models.ExecutionStatus
-	if statusStr != "" {
-		s := models.ExecutionStatus(statusStr)
-		status = &s
+	filters, err := parseExecutionListFilters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	// Parse pagination
@@ -72,6 +66,12 @@ func (h *ExecutionHandler) ListExecutions(w http.ResponseWriter, r *http.Request
 		}
 	}
 
+	// A cursor parameter, even an empty one for the first page, selects keyset pagination
+	if r.URL.Query().Has("cursor") {
+		h.listExecutionsByCursor(w, r, organizationID, filters, r.URL.Query().Get("cursor"), limit)
+		return
+	}
+
 	offset := 0
 	if offsetStr != "" {
 		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
@@ -80,7 +80,7 @@ func (h *ExecutionHandler) ListExecutions(w http.ResponseWriter, r *http.Request
 	}
 
 	// Get executions
-	executions, total, err := h.executionRepo.ListExecutions(r.Context(), organizationID, workflowID, status, limit, offset)
+	executions, total, err := h.executionRepo.ListExecutions(r.Context(), organizationID, filters, limit, offset)
 	if err != nil {
 		h.logger.Errorf("Failed to list executions: %v", err)
 		http.Error(w, "Failed to retrieve executions", http.StatusInternalServerError)
@@ -101,6 +101,89 @@ func (h *ExecutionHandler) ListExecutions(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(response)
 }
 
+// listExecutionsByCursor responds with a page of executions after the given cursor and the cursor for the next page
+func (h *ExecutionHandler) listExecutionsByCursor(
+	w http.ResponseWriter,
+	r *http.Request,
+	organizationID uuid.UUID,
+	filters *postgres.ExecutionListFilters,
+	cursor string,
+	limit int,
+) {
+	executions, total, nextCursor, err := h.executionRepo.ListExecutionsByCursor(r.Context(), organizationID, filters, cursor, limit)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		h.logger.Errorf("Failed to list executions: %v", err)
+		http.Error(w, "Failed to retrieve executions", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.ExecutionListResponse{
+		Executions: executions,
+		Total:      total,
+		PageSize:   limit,
+		NextCursor: nextCursor,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseExecutionListFilters reads the list filters from the query string.
+// Times are RFC3339; started_after is inclusive and started_before exclusive
+func parseExecutionListFilters(r *http.Request) (*postgres.ExecutionListFilters, error) {
+	query := r.URL.Query()
+	filters := &postgres.ExecutionListFilters{}
+
+	if workflowIDStr := query.Get("workflow_id"); workflowIDStr != "" {
+		id, err := uuid.Parse(workflowIDStr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid workflow_id")
+		}
+		filters.WorkflowID = &id
+	}
+
+	if statusStr := query.Get("status"); statusStr != "" {
+		status := models.ExecutionStatus(statusStr)
+		filters.Status = &status
+	}
+
+	if triggerEvent := query.Get("trigger_event"); triggerEvent != "" {
+		filters.TriggerEvent = &triggerEvent
+	}
+
+	if correlationID := query.Get("correlation_id"); correlationID != "" {
+		filters.CorrelationID = &correlationID
+	}
+
+	if startedAfterStr := query.Get("started_after"); startedAfterStr != "" {
+		startedAfter, err := time.Parse(time.RFC3339, startedAfterStr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid started_after format (use RFC3339)")
+		}
+		startedAfter = startedAfter.UTC()
+		filters.StartedAfter = &startedAfter
+	}
+
+	if startedBeforeStr := query.Get("started_before"); startedBeforeStr != "" {
+		startedBefore, err := time.Parse(time.RFC3339, startedBeforeStr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid started_before format (use RFC3339)")
+		}
+		startedBefore = startedBefore.UTC()
+		filters.StartedBefore = &startedBefore
+	}
+
+	if filters.StartedAfter != nil && filters.StartedBefore != nil && !filters.StartedAfter.Before(*filters.StartedBefore) {
+		return nil, fmt.Errorf("started_after must be before started_before")
+	}
+
+	return filters, nil
+}
+
 // GetExecution handles GET /api/v1/executions/:id
 func (h *ExecutionHandler) GetExecution(w http.ResponseWriter, r *http.Request) {
 	// Get organization ID from context
@@ -146,10 +229,15 @@ func (h *ExecutionHandler) GetExecutionTrace(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// Executions in other organizations are reported as not found
 	trace, err := h.executionRepo.GetExecutionTrace(r.Context(), organizationID, id)
 	if err != nil {
 		h.logger.Errorf("Failed to get execution trace: %v", err)
-		http.Error(w, "Execution not found", http.StatusNotFound)
+		if errors.Is(err, postgres.ErrExecutionNotFound) {
+			http.Error(w, "Execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve execution trace", http.StatusInternalServerError)
 		return
 	}
 
@@ -157,6 +245,225 @@ func (h *ExecutionHandler) GetExecutionTrace(w http.ResponseWriter, r *http.Requ
 	json.NewEncoder(w).Encode(trace)
 }
 
+// ListStepExecutions handles GET /api/v1/executions/:id/steps
+func (h *ExecutionHandler) ListStepExecutions(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid execution ID")
+		return
+	}
+
+	filters, err := parseStepExecutionListFilters(r)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	// Executions in other organizations are reported as not found rather than as having no steps
+	if _, err := h.executionRepo.GetExecutionByID(r.Context(), organizationID, id); err != nil {
+		if errors.Is(err, postgres.ErrExecutionNotFound) {
+			RespondError(w, http.StatusNotFound, "Execution not found")
+			return
+		}
+		h.logger.Errorf("Failed to get execution %s: %v", id, err)
+		RespondError(w, http.StatusInternalServerError, "Failed to retrieve execution")
+		return
+	}
+
+	steps, total, err := h.executionRepo.ListStepExecutions(r.Context(), organizationID, id, filters, limit, offset)
+	if err != nil {
+		h.logger.Errorf("Failed to list step executions of %s: %v", id, err)
+		RespondError(w, http.StatusInternalServerError, "Failed to retrieve step executions")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, models.StepExecutionListResponse{
+		Steps:    steps,
+		Total:    total,
+		Page:     offset / limit,
+		PageSize: limit,
+	})
+}
+
+// parseStepExecutionListFilters reads the step execution list filters from the query string
+func parseStepExecutionListFilters(r *http.Request) (*postgres.StepExecutionListFilters, error) {
+	filters := &postgres.StepExecutionListFilters{}
+
+	if statusStr := r.URL.Query().Get("status"); statusStr != "" {
+		status := models.StepExecutionStatus(statusStr)
+		if !status.IsValid() {
+			return nil, fmt.Errorf("Invalid status")
+		}
+		filters.Status = &status
+	}
+
+	return filters, nil
+}
+
+// CancelExecution handles POST /api/v1/executions/:id/cancel
+func (h *ExecutionHandler) CancelExecution(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid execution ID")
+		return
+	}
+
+	if _, err := h.executionRepo.GetExecutionByID(r.Context(), organizationID, id); err != nil {
+		if errors.Is(err, postgres.ErrExecutionNotFound) {
+			RespondError(w, http.StatusNotFound, "Execution not found")
+			return
+		}
+		h.logger.Errorf("Failed to get execution %s: %v", id, err)
+		RespondError(w, http.StatusInternalServerError, "Failed to retrieve execution")
+		return
+	}
+
+	// Record the cancellation first, then stop the live run; the run also records itself as cancelled
+	if err := h.executionRepo.CancelExecution(r.Context(), organizationID, id); err != nil {
+		if errors.Is(err, postgres.ErrExecutionNotCancellable) {
+			RespondError(w, http.StatusConflict, "Execution is not running or waiting")
+			return
+		}
+		h.logger.Errorf("Failed to cancel execution %s: %v", id, err)
+		RespondError(w, http.StatusInternalServerError, "Failed to cancel execution")
+		return
+	}
+
+	if h.eventRouter.CancelExecution(id) {
+		h.logger.Infof("Interrupted running execution %s", id)
+	}
+
+	execution, err := h.executionRepo.GetExecutionByID(r.Context(), organizationID, id)
+	if err != nil {
+		h.logger.Errorf("Failed to get execution after cancel: %v", err)
+		RespondError(w, http.StatusInternalServerError, "Execution cancelled but failed to retrieve updated state")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, execution)
+}
+
+// RerunExecutions handles POST /api/v1/executions/rerun
+func (h *ExecutionHandler) RerunExecutions(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	var req models.RerunExecutionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if (len(req.ExecutionIDs) == 0) == (req.Filter == nil) {
+		RespondError(w, http.StatusBadRequest, "Provide either execution_ids or filter")
+		return
+	}
+	if len(req.ExecutionIDs) > maxRerunBatchSize {
+		RespondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d executions can be re-run per request", maxRerunBatchSize))
+		return
+	}
+
+	response := models.RerunExecutionsResponse{
+		Requeued: []models.RerunResult{},
+		Skipped:  []models.RerunResult{},
+	}
+
+	var executions []models.WorkflowExecution
+	if req.Filter != nil {
+		failed := models.ExecutionStatusFailed
+		filters := &postgres.ExecutionListFilters{
+			WorkflowID:    req.Filter.WorkflowID,
+			Status:        &failed,
+			TriggerEvent:  req.Filter.TriggerEvent,
+			StartedAfter:  req.Filter.StartedAfter,
+			StartedBefore: req.Filter.StartedBefore,
+			ExcludeRerun:  true,
+		}
+
+		var total int64
+		var err error
+		executions, total, err = h.executionRepo.ListExecutions(r.Context(), organizationID, filters, maxRerunBatchSize, 0)
+		if err != nil {
+			h.logger.Errorf("Failed to list executions to re-run: %v", err)
+			RespondError(w, http.StatusInternalServerError, "Failed to retrieve executions")
+			return
+		}
+		response.Remaining = total - int64(len(executions))
+	} else {
+		seen := make(map[uuid.UUID]bool, len(req.ExecutionIDs))
+		for _, id := range req.ExecutionIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			execution, err := h.executionRepo.GetExecutionByID(r.Context(), organizationID, id)
+			if err != nil {
+				if !errors.Is(err, postgres.ErrExecutionNotFound) {
+					h.logger.Errorf("Failed to get execution %s for re-run: %v", id, err)
+				}
+				response.Skipped = append(response.Skipped, models.RerunResult{ExecutionID: id, Reason: "execution not found"})
+				continue
+			}
+			executions = append(executions, *execution)
+		}
+	}
+
+	for i := range executions {
+		execution := &executions[i]
+		if err := h.eventRouter.RerunExecution(r.Context(), organizationID, execution); err != nil {
+			response.Skipped = append(response.Skipped, models.RerunResult{ExecutionID: execution.ID, Reason: rerunSkipReason(err)})
+			if !errors.Is(err, engine.ErrExecutionNotRerunnable) && !errors.Is(err, engine.ErrExecutionAlreadyRerun) {
+				h.logger.Errorf("Failed to re-run execution %s: %v", execution.ID, err)
+			}
+			continue
+		}
+		response.Requeued = append(response.Requeued, models.RerunResult{ExecutionID: execution.ID})
+	}
+
+	RespondJSON(w, http.StatusAccepted, response)
+}
+
+// rerunSkipReason maps a re-run error to the reason reported to clients without leaking internal details
+func rerunSkipReason(err error) string {
+	switch {
+	case errors.Is(err, engine.ErrExecutionNotRerunnable):
+		return "execution is not in a failed state"
+	case errors.Is(err, engine.ErrExecutionAlreadyRerun):
+		return "execution has already been re-run"
+	case errors.Is(err, engine.ErrWorkflowNotFound):
+		return "workflow not found"
+	case errors.Is(err, engine.ErrWorkflowDisabled):
+		return "workflow is disabled"
+	default:
+		return "failed to re-queue execution"
+	}
+}
+
 // PauseExecution handles POST /api/v1/executions/:id/pause
 func (h *ExecutionHandler) PauseExecution(w http.ResponseWriter, r *http.Request) {
 	// Get organization ID from context
@@ -176,7 +483,8 @@ func (h *ExecutionHandler) PauseExecution(w http.ResponseWriter, r *http.Request
 
 	// Parse request body
 	var req struct {
-		Reason string `json:"reason"`
+		Reason     string     `json:"reason"`
+		ApprovalID *uuid.UUID `json:"approval_id,omitempty"` // Resume automatically once this approval is decided
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		RespondError(w, http.StatusBadRequest, "Invalid request body")
@@ -188,8 +496,17 @@ func (h *ExecutionHandler) PauseExecution(w http.ResponseWriter, r *http.Request
 	}
 
 	// Pause the execution
-	if err := h.workflowResumer.PauseExecution(r.Context(), id, req.Reason, nil); err != nil {
+	if req.ApprovalID != nil {
+		err = h.workflowResumer.PauseForApproval(r.Context(), id, *req.ApprovalID, req.Reason, nil)
+	} else {
+		err = h.workflowResumer.PauseExecution(r.Context(), id, req.Reason, nil)
+	}
+	if err != nil {
 		h.logger.Errorf("Failed to pause execution %s: %v", id, err)
+		if errors.Is(err, services.ErrApprovalMismatch) {
+			RespondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		RespondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to pause execution: %v", err))
 		return
 	}
@@ -237,6 +554,23 @@ func (h *ExecutionHandler) ResumeExecution(w http.ResponseWriter, r *http.Reques
 	if len(req.ResumeData) > 0 {
 		if err := h.workflowResumer.ResumeExecution(r.Context(), id, req.ResumeData); err != nil {
 			h.logger.Errorf("Failed to resume execution %s: %v", id, err)
+			var dataErr *services.ResumeDataError
+			if errors.As(err, &dataErr) {
+				RespondJSON(w, http.StatusBadRequest, map[string]interface{}{
+					"error":   "Invalid resume data",
+					"step_id": dataErr.StepID,
+					"errors":  dataErr.Fields,
+				})
+				return
+			}
+			if errors.Is(err, engine.ErrConcurrencyLimitReached) {
+				RespondError(w, http.StatusConflict, "Workflow concurrency limit reached")
+				return
+			}
+			if errors.Is(err, engine.ErrRateLimited) {
+				RespondError(w, http.StatusTooManyRequests, "Workflow execution rate limit exceeded")
+				return
+			}
 			RespondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to resume execution: %v", err))
 			return
 		}
@@ -244,6 +578,14 @@ func (h *ExecutionHandler) ResumeExecution(w http.ResponseWriter, r *http.Reques
 		// Use backward-compatible ResumeWorkflow with approved=true as default
 		if err := h.workflowResumer.ResumeWorkflow(r.Context(), id, true); err != nil {
 			h.logger.Errorf("Failed to resume execution %s: %v", id, err)
+			if errors.Is(err, engine.ErrConcurrencyLimitReached) {
+				RespondError(w, http.StatusConflict, "Workflow concurrency limit reached")
+				return
+			}
+			if errors.Is(err, engine.ErrRateLimited) {
+				RespondError(w, http.StatusTooManyRequests, "Workflow execution rate limit exceeded")
+				return
+			}
 			RespondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to resume execution: %v", err))
 			return
 		}
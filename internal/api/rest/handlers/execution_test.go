@@ -12,7 +12,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/davidmoltin/intelligent-workflows/internal/api/rest/middleware"
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/internal/repository/postgres"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -22,7 +24,7 @@ import (
 type mockExecutionRepoHandler struct {
 	getByIDFunc func(ctx context.Context, id uuid.UUID) (*models.WorkflowExecution, error)
 	listFunc    func(ctx context.Context, workflowID *uuid.UUID, status *models.ExecutionStatus, limit, offset int) ([]models.WorkflowExecution, int64, error)
-	getTraceFunc func(ctx context.Context, id uuid.UUID) (*models.ExecutionTraceResponse, error)
+	getTraceFunc func(ctx context.Context, organizationID, id uuid.UUID) (*models.ExecutionTraceResponse, error)
 }
 
 func (m *mockExecutionRepoHandler) GetExecutionByID(ctx context.Context, id uuid.UUID) (*models.WorkflowExecution, error) {
@@ -39,11 +41,11 @@ func (m *mockExecutionRepoHandler) ListExecutions(ctx context.Context, workflowI
 	return []models.WorkflowExecution{}, 0, nil
 }
 
-func (m *mockExecutionRepoHandler) GetExecutionTrace(ctx context.Context, id uuid.UUID) (*models.ExecutionTraceResponse, error) {
+func (m *mockExecutionRepoHandler) GetExecutionTrace(ctx context.Context, organizationID, id uuid.UUID) (*models.ExecutionTraceResponse, error) {
 	if m.getTraceFunc != nil {
-		return m.getTraceFunc(ctx, id)
+		return m.getTraceFunc(ctx, organizationID, id)
 	}
-	return nil, errors.New("not found")
+	return nil, postgres.ErrExecutionNotFound
 }
 
 func (m *mockExecutionRepoHandler) CreateExecution(ctx context.Context, execution *models.WorkflowExecution) error {
@@ -110,7 +112,7 @@ func (m *mockWorkflowResumerHandler) CanResume(execution *models.WorkflowExecuti
 type executionRepository interface {
 	GetExecutionByID(ctx context.Context, id uuid.UUID) (*models.WorkflowExecution, error)
 	ListExecutions(ctx context.Context, workflowID *uuid.UUID, status *models.ExecutionStatus, limit, offset int) ([]models.WorkflowExecution, int64, error)
-	GetExecutionTrace(ctx context.Context, id uuid.UUID) (*models.ExecutionTraceResponse, error)
+	GetExecutionTrace(ctx context.Context, organizationID, id uuid.UUID) (*models.ExecutionTraceResponse, error)
 }
 
 type workflowResumer interface {
@@ -127,6 +129,37 @@ type testExecutionHandler struct {
 	workflowResumer workflowResumer
 }
 
+// GetExecutionTrace handles GET /api/v1/executions/:id/trace
+func (h *testExecutionHandler) GetExecutionTrace(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		http.Error(w, "Organization context required", http.StatusUnauthorized)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid execution ID", http.StatusBadRequest)
+		return
+	}
+
+	trace, err := h.executionRepo.GetExecutionTrace(r.Context(), organizationID, id)
+	if err != nil {
+		h.logger.Errorf("Failed to get execution trace: %v", err)
+		if errors.Is(err, postgres.ErrExecutionNotFound) {
+			http.Error(w, "Execution not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve execution trace", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trace)
+}
+
 // PauseExecution handles POST /api/v1/executions/:id/pause
 func (h *testExecutionHandler) PauseExecution(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
@@ -256,6 +289,312 @@ func createChiContext(id string) context.Context {
 	return context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
 }
 
+// TestParseExecutionListFilters tests parsing of the execution list query parameters
+func TestParseExecutionListFilters(t *testing.T) {
+	t.Run("combines status, event and time window", func(t *testing.T) {
+		workflowID := uuid.New()
+		req := httptest.NewRequest("GET", "/api/v1/executions?workflow_id="+workflowID.String()+
+			"&status=failed&trigger_event=order.created&correlation_id=req-42"+
+			"&started_after=2025-03-01T00:00:00Z&started_before=2025-03-02T02:00:00%2B02:00", nil)
+
+		filters, err := parseExecutionListFilters(req)
+		if err != nil {
+			t.Fatalf("parseExecutionListFilters failed: %v", err)
+		}
+
+		if filters.WorkflowID == nil || *filters.WorkflowID != workflowID {
+			t.Errorf("Expected workflow %s, got %v", workflowID, filters.WorkflowID)
+		}
+		if filters.Status == nil || *filters.Status != models.ExecutionStatusFailed {
+			t.Errorf("Expected status failed, got %v", filters.Status)
+		}
+		if filters.TriggerEvent == nil || *filters.TriggerEvent != "order.created" {
+			t.Errorf("Expected trigger event order.created, got %v", filters.TriggerEvent)
+		}
+		if filters.CorrelationID == nil || *filters.CorrelationID != "req-42" {
+			t.Errorf("Expected correlation ID req-42, got %v", filters.CorrelationID)
+		}
+		if filters.StartedAfter == nil || !filters.StartedAfter.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Unexpected started_after %v", filters.StartedAfter)
+		}
+		// Offsets are normalized to UTC
+		if filters.StartedBefore == nil || filters.StartedBefore.Location() != time.UTC || filters.StartedBefore.Hour() != 0 {
+			t.Errorf("Expected started_before 2025-03-02T00:00:00Z, got %v", filters.StartedBefore)
+		}
+	})
+
+	t.Run("leaves absent filters unset", func(t *testing.T) {
+		filters, err := parseExecutionListFilters(httptest.NewRequest("GET", "/api/v1/executions", nil))
+		if err != nil {
+			t.Fatalf("parseExecutionListFilters failed: %v", err)
+		}
+		if filters.WorkflowID != nil || filters.Status != nil || filters.TriggerEvent != nil ||
+			filters.StartedAfter != nil || filters.StartedBefore != nil {
+			t.Errorf("Expected no filters, got %+v", filters)
+		}
+	})
+
+	invalid := map[string]string{
+		"invalid workflow_id":    "workflow_id=abc",
+		"invalid started_after":  "started_after=yesterday",
+		"invalid started_before": "started_before=2025-03-01",
+		"empty time window":      "started_after=2025-03-02T00:00:00Z&started_before=2025-03-01T00:00:00Z",
+	}
+	for name, query := range invalid {
+		t.Run("rejects "+name, func(t *testing.T) {
+			if _, err := parseExecutionListFilters(httptest.NewRequest("GET", "/api/v1/executions?"+query, nil)); err == nil {
+				t.Errorf("Expected error for %q", query)
+			}
+		})
+	}
+}
+
+// TestParseStepExecutionListFilters tests parsing of the step execution list query parameters
+func TestParseStepExecutionListFilters(t *testing.T) {
+	path := "/api/v1/executions/" + uuid.New().String() + "/steps"
+
+	filters, err := parseStepExecutionListFilters(httptest.NewRequest("GET", path+"?status=failed&limit=20", nil))
+	if err != nil {
+		t.Fatalf("parseStepExecutionListFilters failed: %v", err)
+	}
+	if filters.Status == nil || *filters.Status != models.StepStatusFailed {
+		t.Errorf("Expected status failed, got %v", filters.Status)
+	}
+
+	filters, err = parseStepExecutionListFilters(httptest.NewRequest("GET", path, nil))
+	if err != nil {
+		t.Fatalf("parseStepExecutionListFilters failed: %v", err)
+	}
+	if filters.Status != nil {
+		t.Errorf("Expected no status filter, got %v", *filters.Status)
+	}
+
+	if _, err := parseStepExecutionListFilters(httptest.NewRequest("GET", path+"?status=broken", nil)); err == nil {
+		t.Error("Expected an unknown status to be rejected")
+	}
+}
+
+// TestRerunExecutions_Handler tests request validation of the bulk re-run handler
+func TestRerunExecutions_Handler(t *testing.T) {
+	// Invalid requests are rejected before any repository or router access
+	handler := NewExecutionHandler(logger.NewForTesting(), nil, nil, nil)
+
+	tooMany := make([]string, maxRerunBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.New().String()
+	}
+
+	tests := []struct {
+		name       string
+		body       interface{}
+		noOrg      bool
+		wantStatus int
+	}{
+		{name: "requires organization context", body: map[string]interface{}{"execution_ids": []string{uuid.New().String()}}, noOrg: true, wantStatus: http.StatusUnauthorized},
+		{name: "rejects invalid body", body: "not an object", wantStatus: http.StatusBadRequest},
+		{name: "rejects empty selection", body: map[string]interface{}{}, wantStatus: http.StatusBadRequest},
+		{name: "rejects ids and filter together", body: map[string]interface{}{
+			"execution_ids": []string{uuid.New().String()},
+			"filter":        map[string]interface{}{"trigger_event": "order.created"},
+		}, wantStatus: http.StatusBadRequest},
+		{name: "rejects batches over the maximum", body: map[string]interface{}{"execution_ids": tooMany}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodyBytes, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest("POST", "/api/v1/executions/rerun", bytes.NewReader(bodyBytes))
+			if !tt.noOrg {
+				req = req.WithContext(context.WithValue(req.Context(), "organization_id", uuid.New()))
+			}
+			w := httptest.NewRecorder()
+
+			handler.RerunExecutions(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+// TestGetExecutionTrace_Handler tests the GetExecutionTrace HTTP handler
+func TestGetExecutionTrace_Handler(t *testing.T) {
+	log := logger.NewForTesting()
+
+	newTraceRequest := func(id string, organizationID uuid.UUID) *http.Request {
+		req := httptest.NewRequest("GET", "/api/v1/executions/"+id+"/trace", nil)
+		ctx := createChiContext(id)
+		if organizationID != uuid.Nil {
+			ctx = context.WithValue(ctx, "organization_id", organizationID)
+		}
+		return req.WithContext(ctx)
+	}
+
+	t.Run("returns execution with ordered steps", func(t *testing.T) {
+		organizationID := uuid.New()
+		executionID := uuid.New()
+		startedAt := time.Now().Add(-time.Minute)
+		firstDuration := 120
+		secondDuration := 45
+		stepError := "webhook returned 502"
+
+		repo := &mockExecutionRepoHandler{
+			getTraceFunc: func(ctx context.Context, orgID, id uuid.UUID) (*models.ExecutionTraceResponse, error) {
+				if orgID != organizationID || id != executionID {
+					return nil, postgres.ErrExecutionNotFound
+				}
+				return &models.ExecutionTraceResponse{
+					Execution: &models.WorkflowExecution{
+						ID:             executionID,
+						OrganizationID: organizationID,
+						Status:         models.ExecutionStatusFailed,
+					},
+					Steps: []models.StepExecution{
+						{
+							ID:         uuid.New(),
+							StepID:     "check_total",
+							StepType:   "condition",
+							Status:     models.StepStatusCompleted,
+							Input:      models.JSONB{"order": map[string]interface{}{"total": 1500.0}},
+							Output:     models.JSONB{"result": true},
+							StartedAt:  startedAt,
+							DurationMs: &firstDuration,
+						},
+						{
+							ID:           uuid.New(),
+							StepID:       "notify",
+							StepType:     "action",
+							Status:       models.StepStatusFailed,
+							StartedAt:    startedAt.Add(time.Second),
+							DurationMs:   &secondDuration,
+							ErrorMessage: &stepError,
+						},
+					},
+				}, nil
+			},
+		}
+
+		handler := &testExecutionHandler{
+			logger:          log,
+			executionRepo:   repo,
+			workflowResumer: &mockWorkflowResumerHandler{},
+		}
+
+		w := httptest.NewRecorder()
+		handler.GetExecutionTrace(w, newTraceRequest(executionID.String(), organizationID))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+
+		var response models.ExecutionTraceResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+
+		if response.Execution == nil || response.Execution.ID != executionID {
+			t.Fatalf("Expected execution %s, got %+v", executionID, response.Execution)
+		}
+		if len(response.Steps) != 2 {
+			t.Fatalf("Expected 2 steps, got %d", len(response.Steps))
+		}
+		if response.Steps[0].StepID != "check_total" || response.Steps[1].StepID != "notify" {
+			t.Errorf("Expected steps in start order, got %s, %s", response.Steps[0].StepID, response.Steps[1].StepID)
+		}
+		if response.Steps[0].Output["result"] != true {
+			t.Errorf("Expected step output to be included, got %v", response.Steps[0].Output)
+		}
+		if response.Steps[0].Input["order"] == nil {
+			t.Errorf("Expected step input to be included, got %v", response.Steps[0].Input)
+		}
+		if response.Steps[0].DurationMs == nil || *response.Steps[0].DurationMs != firstDuration {
+			t.Errorf("Expected duration %d, got %v", firstDuration, response.Steps[0].DurationMs)
+		}
+		if response.Steps[1].ErrorMessage == nil || *response.Steps[1].ErrorMessage != stepError {
+			t.Errorf("Expected error message %q, got %v", stepError, response.Steps[1].ErrorMessage)
+		}
+	})
+
+	t.Run("returns 404 for execution in another organization", func(t *testing.T) {
+		executionID := uuid.New()
+		ownerOrganizationID := uuid.New()
+
+		repo := &mockExecutionRepoHandler{
+			getTraceFunc: func(ctx context.Context, orgID, id uuid.UUID) (*models.ExecutionTraceResponse, error) {
+				if orgID != ownerOrganizationID {
+					return nil, postgres.ErrExecutionNotFound
+				}
+				return &models.ExecutionTraceResponse{Execution: &models.WorkflowExecution{ID: id}}, nil
+			},
+		}
+
+		handler := &testExecutionHandler{
+			logger:          log,
+			executionRepo:   repo,
+			workflowResumer: &mockWorkflowResumerHandler{},
+		}
+
+		w := httptest.NewRecorder()
+		handler.GetExecutionTrace(w, newTraceRequest(executionID.String(), uuid.New()))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("returns 401 without organization context", func(t *testing.T) {
+		handler := &testExecutionHandler{
+			logger:          log,
+			executionRepo:   &mockExecutionRepoHandler{},
+			workflowResumer: &mockWorkflowResumerHandler{},
+		}
+
+		w := httptest.NewRecorder()
+		handler.GetExecutionTrace(w, newTraceRequest(uuid.New().String(), uuid.Nil))
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", w.Code)
+		}
+	})
+
+	t.Run("returns 400 for invalid execution ID", func(t *testing.T) {
+		handler := &testExecutionHandler{
+			logger:          log,
+			executionRepo:   &mockExecutionRepoHandler{},
+			workflowResumer: &mockWorkflowResumerHandler{},
+		}
+
+		w := httptest.NewRecorder()
+		handler.GetExecutionTrace(w, newTraceRequest("invalid-id", uuid.New()))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("returns 500 when the trace cannot be loaded", func(t *testing.T) {
+		repo := &mockExecutionRepoHandler{
+			getTraceFunc: func(ctx context.Context, orgID, id uuid.UUID) (*models.ExecutionTraceResponse, error) {
+				return nil, errors.New("connection reset")
+			},
+		}
+
+		handler := &testExecutionHandler{
+			logger:          log,
+			executionRepo:   repo,
+			workflowResumer: &mockWorkflowResumerHandler{},
+		}
+
+		w := httptest.NewRecorder()
+		handler.GetExecutionTrace(w, newTraceRequest(uuid.New().String(), uuid.New()))
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status 500, got %d", w.Code)
+		}
+	})
+}
+
 // TestPauseExecution_Handler tests the PauseExecution HTTP handler
 func TestPauseExecution_Handler(t *testing.T) {
 	log := logger.NewForTesting()
@@ -28,6 +28,8 @@ type Handlers struct {
 	Audit        *AuditHandler
 	WebSocket    *websocket.Handler
 	Rule         *RuleHandler
+	Role         *RoleHandler
+	Retention    *RetentionHandler
 }
 
 // HealthCheckers holds all health check dependencies
@@ -52,6 +54,7 @@ func NewHandlers(
 	wsHub *websocket.Hub,
 	auditService *services.AuditService,
 	ruleService *services.RuleService,
+	roleService *services.RoleService,
 	healthCheckers *HealthCheckers,
 	version string,
 ) *Handlers {
@@ -67,11 +70,16 @@ func NewHandlers(
 		auditHandler = NewAuditHandler(log, auditService)
 	}
 
+	workflowHandler := NewWorkflowHandler(log, workflowRepo, auditService)
+	if eventRouter != nil {
+		workflowHandler.SetWorkflowInvalidator(eventRouter)
+	}
+
 	return &Handlers{
 		Health:       NewHealthHandler(log, healthCheckers.DB, healthCheckers.Redis, version),
-		Workflow:     NewWorkflowHandler(log, workflowRepo, auditService),
+		Workflow:     workflowHandler,
 		Event:        NewEventHandler(log, eventRouter),
-		Execution:    NewExecutionHandler(log, executionRepo, workflowResumer),
+		Execution:    NewExecutionHandler(log, executionRepo, workflowResumer, eventRouter),
 		Approval:     NewApprovalHandler(log, approvalService),
 		Auth:         NewAuthHandler(log, authService),
 		Organization: NewOrganizationHandler(log, organizationRepo),
@@ -82,6 +90,7 @@ func NewHandlers(
 		Audit:        auditHandler,
 		WebSocket:    websocket.NewHandler(wsHub, log.Logger),
 		Rule:         NewRuleHandler(log, ruleService),
+		Role:         NewRoleHandler(log, roleService),
 	}
 }
 
@@ -6,83 +6,134 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/davidmoltin/intelligent-workflows/pkg/health"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
 )
 
+// defaultReadinessTimeout bounds each dependency ping made by a readiness check
+const defaultReadinessTimeout = 2 * time.Second
+
 // HealthChecker defines the interface for health checking
-type HealthChecker interface {
-	HealthCheck(ctx context.Context) error
-}
+type HealthChecker = health.Checker
 
 // HealthHandler handles health check endpoints
 type HealthHandler struct {
-	logger  *logger.Logger
-	db      HealthChecker
-	redis   HealthChecker
-	version string
+	logger       *logger.Logger
+	dependencies []health.Dependency // Pinged by readiness checks
+	timeout      time.Duration       // Bound on each dependency ping
+	poller       *health.Poller
+	version      string
 }
 
 // NewHealthHandler creates a new health handler
 func NewHealthHandler(log *logger.Logger, db, redis HealthChecker, version string) *HealthHandler {
 	return &HealthHandler{
-		logger:  log,
-		db:      db,
-		redis:   redis,
+		logger: log,
+		dependencies: []health.Dependency{
+			{Name: "database", Checker: db, Critical: true},
+			{Name: "redis", Checker: redis, Critical: true},
+		},
+		timeout: defaultReadinessTimeout,
 		version: version,
 	}
 }
 
+// SetPoller sets the poller whose cached dependency health Health reports (optional dependency)
+func (h *HealthHandler) SetPoller(poller *health.Poller) {
+	h.poller = poller
+}
+
 // HealthResponse represents the health check response
 type HealthResponse struct {
-	Status  string            `json:"status"`
-	Version string            `json:"version"`
-	Checks  map[string]string `json:"checks,omitempty"`
+	Status       string                             `json:"status"`
+	Version      string                             `json:"version"`
+	Checks       map[string]string                  `json:"checks,omitempty"`
+	Dependencies map[string]health.DependencyHealth `json:"dependencies,omitempty"`
+}
+
+// ReadinessResponse represents the readiness check response
+type ReadinessResponse struct {
+	Status       string                             `json:"status"`
+	Version      string                             `json:"version"`
+	Dependencies map[string]health.DependencyHealth `json:"dependencies"`
 }
 
-// Health is a simple health check endpoint
+// Health is a health check endpoint. With a poller set, it reports the health of each dependency
+// as last polled, without pinging anything, and its status is "degraded" while any is down. It
+// answers 200 either way; readiness is reported by Readyz
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	response := HealthResponse{
 		Status:  "ok",
 		Version: h.version,
 	}
 
+	if h.poller != nil {
+		response.Dependencies = h.poller.Results()
+		for _, dependency := range response.Dependencies {
+			if dependency.Status == health.StatusDown {
+				response.Status = "degraded"
+			}
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
-// Ready checks if the service is ready to accept traffic
-func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
-	defer cancel()
-
-	checks := make(map[string]string)
-	allHealthy := true
-
-	// Check database
-	if err := h.db.HealthCheck(ctx); err != nil {
-		// Log detailed error internally
-		h.logger.Errorf("Database health check failed: %v", err)
-		checks["database"] = "unhealthy"
-		allHealthy = false
-	} else {
-		checks["database"] = "healthy"
+// Live reports that the process is up and serving requests. It checks no dependencies, so an
+// outage elsewhere never gets the process restarted
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	response := HealthResponse{
+		Status:  "alive",
+		Version: h.version,
 	}
 
-	// Check Redis
-	if err := h.redis.HealthCheck(ctx); err != nil {
-		// Log detailed error internally
-		h.logger.Errorf("Redis health check failed: %v", err)
-		checks["redis"] = "unhealthy"
-		allHealthy = false
-	} else {
-		checks["redis"] = "healthy"
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Readyz reports whether the service can take traffic, with the status of each dependency. It
+// answers 503 while a critical dependency is down
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	statuses := h.checkDependencies(r.Context())
+
+	status := "ready"
+	statusCode := http.StatusOK
+	if !ready(statuses) {
+		status = "not ready"
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	response := ReadinessResponse{
+		Status:       status,
+		Version:      h.version,
+		Dependencies: statuses,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Ready checks if the service is ready to accept traffic. Kept for clients of /ready; Readyz
+// reports the same checks in more detail
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	statuses := h.checkDependencies(r.Context())
+
+	checks := make(map[string]string, len(statuses))
+	for name, dependency := range statuses {
+		checks[name] = "healthy"
+		if dependency.Status != health.StatusUp {
+			checks[name] = "unhealthy"
+		}
 	}
 
 	status := "ready"
 	statusCode := http.StatusOK
 
-	if !allHealthy {
+	if !ready(statuses) {
 		status = "not ready"
 		statusCode = http.StatusServiceUnavailable
 	}
@@ -97,3 +148,26 @@ func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(response)
 }
+
+// checkDependencies pings every dependency, each within the handler's timeout, and returns their
+// health by name
+func (h *HealthHandler) checkDependencies(ctx context.Context) map[string]health.DependencyHealth {
+	results := health.Check(ctx, h.dependencies, h.timeout)
+	for name, result := range results {
+		if result.Err != nil {
+			// Log detailed error internally
+			h.logger.Errorf("%s health check failed: %v", name, result.Err)
+		}
+	}
+	return results
+}
+
+// ready reports whether every critical dependency is up
+func ready(results map[string]health.DependencyHealth) bool {
+	for _, result := range results {
+		if result.Critical && result.Status != health.StatusUp {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/health"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+)
+
+// fakeHealthChecker fails its health check with err, or waits for the check to time out when
+// block is set
+type fakeHealthChecker struct {
+	err   error
+	block bool
+}
+
+func (f *fakeHealthChecker) HealthCheck(ctx context.Context) error {
+	if f.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return f.err
+}
+
+func TestHealthHandler_Health(t *testing.T) {
+	up := &fakeHealthChecker{}
+	down := &fakeHealthChecker{err: errors.New("connection refused")}
+
+	serve := func(h *HealthHandler) (int, HealthResponse) {
+		rec := httptest.NewRecorder()
+		h.Health(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		var response HealthResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return rec.Code, response
+	}
+
+	t.Run("without a poller", func(t *testing.T) {
+		status, response := serve(NewHealthHandler(logger.NewForTesting(), down, down, "1.2.3"))
+		if status != http.StatusOK || response.Status != "ok" || response.Dependencies != nil {
+			t.Errorf("status = %d, response = %+v", status, response)
+		}
+	})
+
+	t.Run("reports the polled dependencies", func(t *testing.T) {
+		h := NewHealthHandler(logger.NewForTesting(), up, up, "1.2.3")
+		poller := health.NewPoller([]health.Dependency{
+			{Name: "database", Checker: up, Critical: true},
+			{Name: "llm", Checker: down},
+		}, time.Minute, time.Second, logger.NewForTesting())
+		poller.Poll(context.Background())
+		h.SetPoller(poller)
+
+		status, response := serve(h)
+		if status != http.StatusOK {
+			t.Fatalf("status = %d, want %d while degraded", status, http.StatusOK)
+		}
+		if response.Status != "degraded" {
+			t.Errorf("status = %q, want degraded", response.Status)
+		}
+		database, llm := response.Dependencies["database"], response.Dependencies["llm"]
+		if database.Status != health.StatusUp || !database.Critical || database.LastChecked == nil {
+			t.Errorf("database = %+v", database)
+		}
+		if llm.Status != health.StatusDown || llm.Critical {
+			t.Errorf("llm = %+v", llm)
+		}
+	})
+}
+
+func TestHealthHandler_Live(t *testing.T) {
+	down := &fakeHealthChecker{err: errors.New("connection refused")}
+	h := NewHealthHandler(logger.NewForTesting(), down, down, "1.2.3")
+
+	rec := httptest.NewRecorder()
+	h.Live(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d even with dependencies down", rec.Code, http.StatusOK)
+	}
+	var response HealthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Status != "alive" || response.Version != "1.2.3" {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestHealthHandler_Readyz(t *testing.T) {
+	up := &fakeHealthChecker{}
+	down := &fakeHealthChecker{err: errors.New("connection refused")}
+
+	tests := []struct {
+		name       string
+		db, redis  HealthChecker
+		want       int
+		wantStatus map[string]string
+	}{
+		{"all dependencies up", up, up, http.StatusOK, map[string]string{"database": "up", "redis": "up"}},
+		{"database down", down, up, http.StatusServiceUnavailable, map[string]string{"database": "down", "redis": "up"}},
+		{"redis down", up, down, http.StatusServiceUnavailable, map[string]string{"database": "up", "redis": "down"}},
+		{"redis times out", up, &fakeHealthChecker{block: true}, http.StatusServiceUnavailable, map[string]string{"database": "up", "redis": "down"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandler(logger.NewForTesting(), tt.db, tt.redis, "1.2.3")
+			h.timeout = 10 * time.Millisecond
+
+			rec := httptest.NewRecorder()
+			h.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			var response ReadinessResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Dependencies) != len(tt.wantStatus) {
+				t.Fatalf("dependencies = %+v, want %v", response.Dependencies, tt.wantStatus)
+			}
+			for name, want := range tt.wantStatus {
+				dependency := response.Dependencies[name]
+				if dependency.Status != want || !dependency.Critical {
+					t.Errorf("%s = %+v, want critical and %s", name, dependency, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthHandler_Ready(t *testing.T) {
+	h := NewHealthHandler(logger.NewForTesting(), &fakeHealthChecker{}, &fakeHealthChecker{err: errors.New("connection refused")}, "1.2.3")
+
+	rec := httptest.NewRecorder()
+	h.Ready(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	var response HealthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Checks["database"] != "healthy" || response.Checks["redis"] != "unhealthy" {
+		t.Errorf("checks = %v", response.Checks)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/api/rest/middleware"
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/internal/repository/postgres"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/validator"
+	"github.com/google/uuid"
+)
+
+// RetentionPolicyStore reads and manages organizations' own retention windows
+type RetentionPolicyStore interface {
+	GetPolicy(ctx context.Context, organizationID uuid.UUID) (*models.RetentionPolicy, error)
+	SetPolicy(ctx context.Context, organizationID uuid.UUID, retentionDays int) (*models.RetentionPolicy, error)
+	DeletePolicy(ctx context.Context, organizationID uuid.UUID) error
+}
+
+// RetentionHandler handles execution retention HTTP requests
+type RetentionHandler struct {
+	logger   *logger.Logger
+	policies RetentionPolicyStore
+	cfg      *config.RetentionConfig
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(log *logger.Logger, policies RetentionPolicyStore, cfg *config.RetentionConfig) *RetentionHandler {
+	return &RetentionHandler{
+		logger:   log,
+		policies: policies,
+		cfg:      cfg,
+	}
+}
+
+// GetRetention handles GET /api/v1/retention
+func (h *RetentionHandler) GetRetention(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	h.respondSettings(w, r, organizationID)
+}
+
+// SetRetention handles PUT /api/v1/retention
+func (h *RetentionHandler) SetRetention(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	var req models.SetRetentionPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := h.policies.SetPolicy(r.Context(), organizationID, *req.RetentionDays); err != nil {
+		h.logger.Errorf("Failed to set retention policy: %v", err)
+		RespondError(w, http.StatusInternalServerError, "Failed to set retention policy")
+		return
+	}
+
+	h.respondSettings(w, r, organizationID)
+}
+
+// DeleteRetention handles DELETE /api/v1/retention, returning the organization to the default window
+func (h *RetentionHandler) DeleteRetention(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	if err := h.policies.DeletePolicy(r.Context(), organizationID); err != nil && !errors.Is(err, postgres.ErrRetentionPolicyNotFound) {
+		h.logger.Errorf("Failed to delete retention policy: %v", err)
+		RespondError(w, http.StatusInternalServerError, "Failed to delete retention policy")
+		return
+	}
+
+	h.respondSettings(w, r, organizationID)
+}
+
+// respondSettings responds with the retention settings in effect for the organization
+func (h *RetentionHandler) respondSettings(w http.ResponseWriter, r *http.Request, organizationID uuid.UUID) {
+	settings := models.RetentionSettings{
+		Enabled:              h.cfg.Enabled,
+		RetentionDays:        h.cfg.Days,
+		DefaultRetentionDays: h.cfg.Days,
+		PurgeAfter:           h.cfg.PurgeAfter.String(),
+		ArchiveEnabled:       h.cfg.ArchiveURL != "",
+	}
+
+	policy, err := h.policies.GetPolicy(r.Context(), organizationID)
+	switch {
+	case err == nil:
+		settings.RetentionDays = policy.RetentionDays
+		settings.Overridden = true
+	case !errors.Is(err, postgres.ErrRetentionPolicyNotFound):
+		h.logger.Errorf("Failed to get retention policy: %v", err)
+		RespondError(w, http.StatusInternalServerError, "Failed to retrieve retention policy")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, settings)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/internal/repository/postgres"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Mock RetentionPolicyStore with one retention window per organization
+type mockRetentionPolicies map[uuid.UUID]int
+
+func (m mockRetentionPolicies) GetPolicy(ctx context.Context, organizationID uuid.UUID) (*models.RetentionPolicy, error) {
+	days, ok := m[organizationID]
+	if !ok {
+		return nil, postgres.ErrRetentionPolicyNotFound
+	}
+	return &models.RetentionPolicy{OrganizationID: organizationID, RetentionDays: days}, nil
+}
+
+func (m mockRetentionPolicies) SetPolicy(ctx context.Context, organizationID uuid.UUID, retentionDays int) (*models.RetentionPolicy, error) {
+	m[organizationID] = retentionDays
+	return &models.RetentionPolicy{OrganizationID: organizationID, RetentionDays: retentionDays}, nil
+}
+
+func (m mockRetentionPolicies) DeletePolicy(ctx context.Context, organizationID uuid.UUID) error {
+	if _, ok := m[organizationID]; !ok {
+		return postgres.ErrRetentionPolicyNotFound
+	}
+	delete(m, organizationID)
+	return nil
+}
+
+// TestRetention_Handler tests reading and overriding an organization's retention window
+func TestRetention_Handler(t *testing.T) {
+	organizationID := uuid.New()
+	policies := mockRetentionPolicies{}
+	handler := NewRetentionHandler(logger.NewForTesting(), policies, &config.RetentionConfig{
+		Enabled:    true,
+		Days:       90,
+		PurgeAfter: 7 * 24 * time.Hour,
+		ArchiveURL: "file:///var/archive",
+	})
+
+	call := func(method, body string, handle http.HandlerFunc) (*httptest.ResponseRecorder, models.RetentionSettings) {
+		req := httptest.NewRequest(method, "/api/v1/retention", bytes.NewBufferString(body))
+		req = req.WithContext(context.WithValue(req.Context(), "organization_id", organizationID))
+		w := httptest.NewRecorder()
+		handle(w, req)
+
+		var settings models.RetentionSettings
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&settings); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return w, settings
+	}
+
+	w, settings := call("GET", "", handler.GetRetention)
+	if w.Code != http.StatusOK || settings.RetentionDays != 90 || settings.Overridden || !settings.ArchiveEnabled || settings.PurgeAfter != "168h0m0s" {
+		t.Errorf("Expected the default settings, got %d %+v", w.Code, settings)
+	}
+
+	// A window of 0 keeps executions forever
+	w, settings = call("PUT", `{"retention_days":0}`, handler.SetRetention)
+	if w.Code != http.StatusOK || settings.RetentionDays != 0 || !settings.Overridden || settings.DefaultRetentionDays != 90 {
+		t.Errorf("Expected the organization's window to apply, got %d %+v", w.Code, settings)
+	}
+	if days, ok := policies[organizationID]; !ok || days != 0 {
+		t.Errorf("Expected the window to be stored, got %v", policies)
+	}
+
+	for _, body := range []string{`{}`, `{"retention_days":-1}`, `not json`} {
+		if w, _ := call("PUT", body, handler.SetRetention); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+
+	w, settings = call("DELETE", "", handler.DeleteRetention)
+	if w.Code != http.StatusOK || settings.RetentionDays != 90 || settings.Overridden {
+		t.Errorf("Expected the default window to apply again, got %d %+v", w.Code, settings)
+	}
+
+	// Deleting again is not an error
+	if w, _ := call("DELETE", "", handler.DeleteRetention); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
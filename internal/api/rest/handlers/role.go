@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/api/rest/middleware"
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/internal/repository/postgres"
+	"github.com/davidmoltin/intelligent-workflows/internal/services"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/validator"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// RoleHandler handles role management HTTP requests
+type RoleHandler struct {
+	logger      *logger.Logger
+	roleService *services.RoleService
+}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler(log *logger.Logger, roleService *services.RoleService) *RoleHandler {
+	return &RoleHandler{
+		logger:      log,
+		roleService: roleService,
+	}
+}
+
+// ListPermissions handles GET /api/v1/permissions
+func (h *RoleHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, err := h.roleService.ListPermissions(r.Context())
+	if err != nil {
+		h.logger.Errorf("Failed to list permissions: %v", err)
+		RespondError(w, http.StatusInternalServerError, "Failed to retrieve permissions")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"permissions": permissions,
+	})
+}
+
+// ListRoles handles GET /api/v1/roles
+func (h *RoleHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	roles, err := h.roleService.ListRoles(r.Context(), organizationID)
+	if err != nil {
+		h.logger.Errorf("Failed to list roles: %v", err)
+		RespondError(w, http.StatusInternalServerError, "Failed to retrieve roles")
+		return
+	}
+	if roles == nil {
+		roles = []*models.Role{}
+	}
+
+	RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"roles": roles,
+	})
+}
+
+// GetRole handles GET /api/v1/roles/{id}
+func (h *RoleHandler) GetRole(w http.ResponseWriter, r *http.Request) {
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	roleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	role, err := h.roleService.GetRole(r.Context(), organizationID, roleID)
+	if err != nil {
+		h.respondRoleError(w, err, "Failed to retrieve role")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, role)
+}
+
+// CreateRole handles POST /api/v1/roles
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	caller := middleware.GetClaims(r.Context())
+	if caller == nil || caller.OrganizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	var req models.CreateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, err := h.roleService.CreateRole(r.Context(), caller, &req)
+	if err != nil {
+		h.respondRoleError(w, err, "Failed to create role")
+		return
+	}
+
+	RespondJSON(w, http.StatusCreated, role)
+}
+
+// AttachPermissions handles POST /api/v1/roles/{id}/permissions
+func (h *RoleHandler) AttachPermissions(w http.ResponseWriter, r *http.Request) {
+	caller := middleware.GetClaims(r.Context())
+	if caller == nil || caller.OrganizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	roleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	var req models.RolePermissionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, err := h.roleService.AttachPermissions(r.Context(), caller, roleID, req.Permissions)
+	if err != nil {
+		h.respondRoleError(w, err, "Failed to attach permissions")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, role)
+}
+
+// DetachPermission handles DELETE /api/v1/roles/{id}/permissions/{permission}
+func (h *RoleHandler) DetachPermission(w http.ResponseWriter, r *http.Request) {
+	caller := middleware.GetClaims(r.Context())
+	if caller == nil || caller.OrganizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	roleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	role, err := h.roleService.DetachPermission(r.Context(), caller, roleID, chi.URLParam(r, "permission"))
+	if err != nil {
+		h.respondRoleError(w, err, "Failed to detach permission")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, role)
+}
+
+// AssignRole handles POST /api/v1/users/{user_id}/roles
+func (h *RoleHandler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	caller := middleware.GetClaims(r.Context())
+	if caller == nil || caller.OrganizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "user_id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.AssignRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.roleService.AssignRole(r.Context(), caller, userID, req.RoleID); err != nil {
+		h.respondRoleError(w, err, "Failed to assign role")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnassignRole handles DELETE /api/v1/users/{user_id}/roles/{role_id}
+func (h *RoleHandler) UnassignRole(w http.ResponseWriter, r *http.Request) {
+	caller := middleware.GetClaims(r.Context())
+	if caller == nil || caller.OrganizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "user_id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	roleID, err := uuid.Parse(chi.URLParam(r, "role_id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	if err := h.roleService.UnassignRole(r.Context(), caller, userID, roleID); err != nil {
+		h.respondRoleError(w, err, "Failed to unassign role")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondRoleError maps role service errors to responses, answering unexpected errors with
+// 500 and message
+func (h *RoleHandler) respondRoleError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRole):
+		RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrPermissionEscalation):
+		RespondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrBuiltInRole):
+		RespondError(w, http.StatusForbidden, "Built-in roles cannot be modified")
+	case errors.Is(err, services.ErrRoleExists):
+		RespondError(w, http.StatusConflict, "A role with this name already exists")
+	case errors.Is(err, services.ErrMembershipRole):
+		RespondError(w, http.StatusConflict, "The membership role is changed on the organization membership")
+	case errors.Is(err, postgres.ErrRoleNotFound):
+		RespondError(w, http.StatusNotFound, "Role not found")
+	case errors.Is(err, postgres.ErrPermissionNotGranted):
+		RespondError(w, http.StatusNotFound, "Role was not granted the permission")
+	case errors.Is(err, postgres.ErrRoleAssignmentNotFound):
+		RespondError(w, http.StatusNotFound, "User was not assigned the role")
+	case errors.Is(err, services.ErrNotOrganizationMember):
+		RespondError(w, http.StatusNotFound, "User not found in organization")
+	default:
+		h.logger.Errorf("%s: %v", message, err)
+		RespondError(w, http.StatusInternalServerError, message)
+	}
+}
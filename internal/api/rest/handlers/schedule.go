@@ -3,12 +3,14 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/api/rest/middleware"
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/internal/services"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
 	"github.com/davidmoltin/intelligent-workflows/pkg/validator"
 	"github.com/go-chi/chi/v5"
@@ -23,6 +25,7 @@ type ScheduleService interface {
 	UpdateSchedule(ctx context.Context, organizationID, id uuid.UUID, req *models.UpdateScheduleRequest) (*models.WorkflowSchedule, error)
 	DeleteSchedule(ctx context.Context, organizationID, id uuid.UUID) error
 	GetNextRuns(ctx context.Context, organizationID, id uuid.UUID, count int) ([]time.Time, error)
+	PreviewSchedule(schedule *models.WorkflowSchedule, count int) (*models.SchedulePreviewResponse, error)
 	ListSchedules(ctx context.Context, organizationID uuid.UUID, limit, offset int) ([]*models.WorkflowSchedule, int64, error)
 }
 
@@ -71,6 +74,10 @@ func (h *ScheduleHandler) CreateSchedule(w http.ResponseWriter, r *http.Request)
 	schedule, err := h.scheduleService.CreateSchedule(r.Context(), organizationID, workflowID, &req)
 	if err != nil {
 		h.logger.Errorf("Failed to create schedule: %v", err)
+		if errors.Is(err, services.ErrInvalidSchedule) {
+			RespondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		RespondError(w, http.StatusInternalServerError, "Failed to create schedule: "+err.Error())
 		return
 	}
@@ -164,6 +171,10 @@ func (h *ScheduleHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request)
 	schedule, err := h.scheduleService.UpdateSchedule(r.Context(), organizationID, id, &req)
 	if err != nil {
 		h.logger.Errorf("Failed to update schedule: %v", err)
+		if errors.Is(err, services.ErrInvalidSchedule) {
+			RespondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		RespondError(w, http.StatusInternalServerError, "Failed to update schedule: "+err.Error())
 		return
 	}
@@ -240,6 +251,54 @@ func (h *ScheduleHandler) GetNextRuns(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// PreviewSchedule handles GET /api/v1/workflows/:id/schedules/:sid/preview
+func (h *ScheduleHandler) PreviewSchedule(w http.ResponseWriter, r *http.Request) {
+	// Get organization ID from context
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		RespondError(w, http.StatusUnauthorized, "Organization context required")
+		return
+	}
+
+	workflowID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid workflow ID")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "sid"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "Invalid schedule ID")
+		return
+	}
+
+	// Parse count parameter, capped at the most runs a schedule lists
+	count := 10 // default
+	if countStr := r.URL.Query().Get("count"); countStr != "" {
+		c, err := strconv.Atoi(countStr)
+		if err != nil || c <= 0 {
+			RespondError(w, http.StatusBadRequest, "Invalid count")
+			return
+		}
+		count = min(c, services.MaxNextRuns)
+	}
+
+	schedule, err := h.scheduleService.GetSchedule(r.Context(), organizationID, id)
+	if err != nil || schedule.WorkflowID != workflowID {
+		RespondError(w, http.StatusNotFound, "Schedule not found")
+		return
+	}
+
+	preview, err := h.scheduleService.PreviewSchedule(schedule, count)
+	if err != nil {
+		h.logger.Errorf("Failed to preview schedule: %v", err)
+		RespondError(w, http.StatusInternalServerError, "Failed to preview schedule")
+		return
+	}
+
+	RespondJSON(w, http.StatusOK, preview)
+}
+
 // ListSchedules handles GET /api/v1/schedules
 func (h *ScheduleHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
 	// Get organization ID from context
@@ -3,12 +3,15 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/api/rest/middleware"
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
 	"github.com/davidmoltin/intelligent-workflows/internal/repository/postgres"
+	"github.com/davidmoltin/intelligent-workflows/internal/validators"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
 	"github.com/davidmoltin/intelligent-workflows/pkg/validator"
 	"github.com/go-chi/chi/v5"
@@ -20,6 +23,13 @@ type WorkflowHandler struct {
 	logger       *logger.Logger
 	repo         *postgres.WorkflowRepository
 	auditService AuditService
+	invalidator  WorkflowInvalidator
+}
+
+// WorkflowInvalidator is told when an organization's workflows change, so workflows cached for
+// event routing are reloaded
+type WorkflowInvalidator interface {
+	InvalidateWorkflows(organizationID uuid.UUID)
 }
 
 // AuditService defines interface for audit logging
@@ -38,6 +48,11 @@ func NewWorkflowHandler(log *logger.Logger, repo *postgres.WorkflowRepository, a
 	}
 }
 
+// SetWorkflowInvalidator sets what is told when workflows change (optional dependency)
+func (h *WorkflowHandler) SetWorkflowInvalidator(invalidator WorkflowInvalidator) {
+	h.invalidator = invalidator
+}
+
 // Create creates a new workflow
 func (h *WorkflowHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateWorkflowRequest
@@ -52,6 +67,11 @@ func (h *WorkflowHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if result := validators.ValidateDefinition(&req.Definition); !result.Valid {
+		h.respondInvalidDefinition(w, result)
+		return
+	}
+
 	// Get organization ID from context
 	organizationID := middleware.GetOrganizationID(r.Context())
 	if organizationID == uuid.Nil {
@@ -59,12 +79,13 @@ func (h *WorkflowHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	workflow, err := h.repo.Create(r.Context(), organizationID, &req, nil)
+	workflow, err := h.repo.Create(r.Context(), organizationID, &req, h.getAuthor(r))
 	if err != nil {
 		h.logger.Errorf("Failed to create workflow", logger.Err(err))
 		h.respondError(w, http.StatusInternalServerError, "Failed to create workflow")
 		return
 	}
+	h.invalidateWorkflows(organizationID)
 
 	// Log audit event
 	if h.auditService != nil {
@@ -82,6 +103,18 @@ func (h *WorkflowHandler) Create(w http.ResponseWriter, r *http.Request) {
 	h.respondJSON(w, http.StatusCreated, workflow)
 }
 
+// Validate checks a workflow definition without saving it. The body is a workflow create
+// request, or any object with a "definition" field
+func (h *WorkflowHandler) Validate(w http.ResponseWriter, r *http.Request) {
+	var req models.ValidateWorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, validators.ValidateDefinition(&req.Definition))
+}
+
 // Get retrieves a workflow by ID
 func (h *WorkflowHandler) Get(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
@@ -189,12 +222,20 @@ func (h *WorkflowHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	workflow, err := h.repo.Update(r.Context(), organizationID, id, &req)
+	if req.Definition != nil {
+		if result := validators.ValidateDefinition(req.Definition); !result.Valid {
+			h.respondInvalidDefinition(w, result)
+			return
+		}
+	}
+
+	workflow, err := h.repo.Update(r.Context(), organizationID, id, &req, h.getAuthor(r))
 	if err != nil {
 		h.logger.Errorf("Failed to update workflow", logger.Err(err))
 		h.respondError(w, http.StatusInternalServerError, "Failed to update workflow")
 		return
 	}
+	h.invalidateWorkflows(organizationID)
 
 	// Log audit event
 	if h.auditService != nil {
@@ -208,6 +249,7 @@ func (h *WorkflowHandler) Update(w http.ResponseWriter, r *http.Request) {
 		}
 		if req.Definition != nil {
 			changes["definition"] = "updated"
+			changes["definition_version"] = workflow.DefinitionVersion
 		}
 		if err := h.auditService.LogWorkflowUpdated(r.Context(), id, actorID, actorType, changes); err != nil {
 			h.logger.Errorf("Failed to log audit event: %v", err)
@@ -217,6 +259,103 @@ func (h *WorkflowHandler) Update(w http.ResponseWriter, r *http.Request) {
 	h.respondJSON(w, http.StatusOK, workflow)
 }
 
+// ListVersions lists a workflow's definition history, newest first
+func (h *WorkflowHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	organizationID, id, ok := h.parseWorkflowRequest(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.repo.ListVersions(r.Context(), organizationID, id)
+	if err != nil {
+		h.logger.Errorf("Failed to list workflow versions", logger.Err(err))
+		h.respondError(w, http.StatusInternalServerError, "Failed to list workflow versions")
+		return
+	}
+
+	if len(versions) == 0 {
+		// Every workflow has at least its first version, so none means no such workflow
+		h.respondError(w, http.StatusNotFound, "Workflow not found")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"versions": versions,
+		"total":    len(versions),
+	})
+}
+
+// GetVersion retrieves one version of a workflow's definition
+func (h *WorkflowHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	organizationID, id, ok := h.parseWorkflowRequest(w, r)
+	if !ok {
+		return
+	}
+
+	number, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil || number < 1 {
+		h.respondError(w, http.StatusBadRequest, "Invalid version number")
+		return
+	}
+
+	version, ok := h.getVersion(w, r, organizationID, id, number)
+	if !ok {
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, version)
+}
+
+// DiffVersions reports the steps added, removed and changed between two versions of a
+// workflow's definition, given as the from and to query parameters. to defaults to the
+// current version and from to the one before it
+func (h *WorkflowHandler) DiffVersions(w http.ResponseWriter, r *http.Request) {
+	organizationID, id, ok := h.parseWorkflowRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var to int
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		var err error
+		if to, err = strconv.Atoi(toStr); err != nil || to < 1 {
+			h.respondError(w, http.StatusBadRequest, "Invalid to version")
+			return
+		}
+	} else {
+		workflow, err := h.repo.GetByID(r.Context(), organizationID, id)
+		if err != nil {
+			h.respondError(w, http.StatusNotFound, "Workflow not found")
+			return
+		}
+		to = workflow.DefinitionVersion
+	}
+
+	from := to - 1
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		var err error
+		if from, err = strconv.Atoi(fromStr); err != nil || from < 1 {
+			h.respondError(w, http.StatusBadRequest, "Invalid from version")
+			return
+		}
+	}
+	if from < 1 {
+		h.respondError(w, http.StatusBadRequest, "Workflow has no earlier version to compare with")
+		return
+	}
+
+	fromVersion, ok := h.getVersion(w, r, organizationID, id, from)
+	if !ok {
+		return
+	}
+	toVersion, ok := h.getVersion(w, r, organizationID, id, to)
+	if !ok {
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, models.DiffDefinitions(fromVersion, toVersion))
+}
+
 // Delete deletes a workflow
 func (h *WorkflowHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
@@ -238,6 +377,7 @@ func (h *WorkflowHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		h.respondError(w, http.StatusInternalServerError, "Failed to delete workflow")
 		return
 	}
+	h.invalidateWorkflows(organizationID)
 
 	// Log audit event
 	if h.auditService != nil {
@@ -271,6 +411,7 @@ func (h *WorkflowHandler) Enable(w http.ResponseWriter, r *http.Request) {
 		h.respondError(w, http.StatusInternalServerError, "Failed to enable workflow")
 		return
 	}
+	h.invalidateWorkflows(organizationID)
 
 	h.respondJSON(w, http.StatusOK, map[string]string{"message": "Workflow enabled"})
 }
@@ -296,12 +437,19 @@ func (h *WorkflowHandler) Disable(w http.ResponseWriter, r *http.Request) {
 		h.respondError(w, http.StatusInternalServerError, "Failed to disable workflow")
 		return
 	}
+	h.invalidateWorkflows(organizationID)
 
 	h.respondJSON(w, http.StatusOK, map[string]string{"message": "Workflow disabled"})
 }
 
 // Helper methods
 
+func (h *WorkflowHandler) invalidateWorkflows(organizationID uuid.UUID) {
+	if h.invalidator != nil {
+		h.invalidator.InvalidateWorkflows(organizationID)
+	}
+}
+
 func (h *WorkflowHandler) respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -312,6 +460,56 @@ func (h *WorkflowHandler) respondError(w http.ResponseWriter, status int, messag
 	h.respondJSON(w, status, map[string]string{"error": message})
 }
 
+// parseWorkflowRequest reads the organization and the workflow ID from the URL, responding
+// with an error when either is missing or invalid
+func (h *WorkflowHandler) parseWorkflowRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid workflow ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	organizationID := middleware.GetOrganizationID(r.Context())
+	if organizationID == uuid.Nil {
+		h.respondError(w, http.StatusUnauthorized, "Organization context required")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return organizationID, id, true
+}
+
+// getVersion loads one version of a workflow's definition, responding with an error if it can't
+func (h *WorkflowHandler) getVersion(w http.ResponseWriter, r *http.Request, organizationID, id uuid.UUID, number int) (*models.WorkflowVersion, bool) {
+	version, err := h.repo.GetVersion(r.Context(), organizationID, id, number)
+	if errors.Is(err, postgres.ErrWorkflowVersionNotFound) {
+		h.respondError(w, http.StatusNotFound, fmt.Sprintf("Workflow version %d not found", number))
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Errorf("Failed to get workflow version", logger.Err(err))
+		h.respondError(w, http.StatusInternalServerError, "Failed to get workflow version")
+		return nil, false
+	}
+	return version, true
+}
+
+// getAuthor returns the authenticated user making the request, or nil for API keys and system calls
+func (h *WorkflowHandler) getAuthor(r *http.Request) *uuid.UUID {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		return nil
+	}
+	return &userID
+}
+
+// respondInvalidDefinition rejects a workflow whose definition failed validation, listing the errors
+func (h *WorkflowHandler) respondInvalidDefinition(w http.ResponseWriter, result *validators.ValidationResult) {
+	h.respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"error":  "Invalid workflow definition",
+		"errors": result.Errors,
+	})
+}
+
 // getActorFromContext extracts actor ID and type from request context
 func (h *WorkflowHandler) getActorFromContext(r *http.Request) (uuid.UUID, string) {
 	// Try to get user ID from context
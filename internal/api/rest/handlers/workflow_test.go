@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/validators"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const invalidWorkflowBody = `{
+	"workflow_id": "order_check",
+	"version": "1.0.0",
+	"name": "Order check",
+	"definition": {
+		"trigger": {"type": "event", "event": "order.created"},
+		"steps": [
+			{"id": "check", "type": "condition", "condition": {"field": "order.total", "operator": "gt", "value": 100}, "on_true": "missing"}
+		]
+	}
+}`
+
+func TestValidateWorkflow_Handler(t *testing.T) {
+	handler := NewWorkflowHandler(logger.NewForTesting(), nil, nil)
+
+	t.Run("reports errors", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/workflows/validate", bytes.NewBufferString(invalidWorkflowBody))
+		w := httptest.NewRecorder()
+
+		handler.Validate(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var result validators.ValidationResult
+		if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if result.Valid {
+			t.Error("Expected the definition to be invalid")
+		}
+		if len(result.Errors) != 1 || result.Errors[0].StepID != "check" {
+			t.Errorf("Expected one error for step check, got %v", result.Errors)
+		}
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/workflows/validate", bytes.NewBufferString("{"))
+		w := httptest.NewRecorder()
+
+		handler.Validate(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestCreateWorkflow_RejectsInvalidDefinition(t *testing.T) {
+	// The repository is never reached, so none is needed
+	handler := NewWorkflowHandler(logger.NewForTesting(), nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/workflows", bytes.NewBufferString(invalidWorkflowBody))
+	w := httptest.NewRecorder()
+
+	handler.Create(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		Error  string                       `json:"error"`
+		Errors []validators.ValidationIssue `json:"errors"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body.Errors) != 1 || body.Errors[0].StepID != "check" {
+		t.Errorf("Expected one error for step check, got %v", body.Errors)
+	}
+}
+
+func TestWorkflowVersions_InvalidParameters(t *testing.T) {
+	// Every case is rejected before the repository is reached
+	handler := NewWorkflowHandler(logger.NewForTesting(), nil, nil)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		id      string
+		version string
+		query   string
+	}{
+		{name: "get version with invalid workflow ID", handler: handler.GetVersion, id: "not-a-uuid", version: "1"},
+		{name: "get version with invalid number", handler: handler.GetVersion, id: uuid.New().String(), version: "latest"},
+		{name: "get version zero", handler: handler.GetVersion, id: uuid.New().String(), version: "0"},
+		{name: "diff with invalid to", handler: handler.DiffVersions, id: uuid.New().String(), query: "?to=abc"},
+		{name: "diff with invalid from", handler: handler.DiffVersions, id: uuid.New().String(), query: "?to=2&from=0"},
+		{name: "diff of the first version", handler: handler.DiffVersions, id: uuid.New().String(), query: "?to=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.id)
+			rctx.URLParams.Add("version", tt.version)
+			ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+			ctx = context.WithValue(ctx, "organization_id", uuid.New())
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/workflows/"+tt.id+"/versions"+tt.query, nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			tt.handler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -3,9 +3,11 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
 	"github.com/davidmoltin/intelligent-workflows/internal/services"
 	"github.com/davidmoltin/intelligent-workflows/pkg/auth"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
@@ -64,29 +66,19 @@ func APIKeyAuth(authService *services.AuthService, log *logger.Logger) func(next
 			}
 
 			// Validate API key
-			user, organizationID, scopes, err := authService.ValidateAPIKey(r.Context(), apiKey)
+			user, key, err := authService.ValidateAPIKey(r.Context(), apiKey)
 			if err != nil {
 				log.Warn("Invalid API key", zap.Error(err))
 				respondError(w, http.StatusUnauthorized, "Invalid API key")
 				return
 			}
 
-			// Create claims-like structure for API key
-			apiClaims := &auth.JWTClaims{
-				UserID:         user.ID,
-				OrganizationID: organizationID,
-				Username:       user.Username,
-				Email:          user.Email,
+			ctx, err := apiKeyContext(r.Context(), authService, user, key)
+			if err != nil {
+				respondAPIKeyAccessError(w, err, log)
+				return
 			}
 
-			// Add to context
-			ctx := context.WithValue(r.Context(), "claims", apiClaims)
-			ctx = context.WithValue(ctx, "user_id", user.ID)
-			ctx = context.WithValue(ctx, "organization_id", organizationID)
-			ctx = context.WithValue(ctx, "username", user.Username)
-			ctx = context.WithValue(ctx, "scopes", scopes)
-			ctx = context.WithValue(ctx, "auth_type", "api_key")
-
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -117,20 +109,13 @@ func OptionalAuth(authService *services.AuthService, log *logger.Logger) func(ne
 			// Try API key
 			apiKey := r.Header.Get("X-API-Key")
 			if apiKey != "" {
-				user, organizationID, scopes, err := authService.ValidateAPIKey(r.Context(), apiKey)
+				user, key, err := authService.ValidateAPIKey(r.Context(), apiKey)
 				if err == nil {
-					apiClaims := &auth.JWTClaims{
-						UserID:         user.ID,
-						OrganizationID: organizationID,
-						Username:       user.Username,
-						Email:          user.Email,
+					ctx, err := apiKeyContext(r.Context(), authService, user, key)
+					if err != nil {
+						respondAPIKeyAccessError(w, err, log)
+						return
 					}
-					ctx := context.WithValue(r.Context(), "claims", apiClaims)
-					ctx = context.WithValue(ctx, "user_id", user.ID)
-					ctx = context.WithValue(ctx, "organization_id", organizationID)
-					ctx = context.WithValue(ctx, "username", user.Username)
-					ctx = context.WithValue(ctx, "scopes", scopes)
-					ctx = context.WithValue(ctx, "auth_type", "api_key")
 					next.ServeHTTP(w, r.WithContext(ctx))
 					return
 				}
@@ -142,6 +127,47 @@ func OptionalAuth(authService *services.AuthService, log *logger.Logger) func(ne
 	}
 }
 
+// apiKeyContext adds an API key's identity to the context. The claims carry the owner's RBAC
+// roles and permissions in the key's organization, so a key can never do more than its owner;
+// the key's scopes further restrict it to the permissions it was granted (see RequirePermission)
+func apiKeyContext(ctx context.Context, authService *services.AuthService, user *models.User, key *models.APIKey) (context.Context, error) {
+	roles, permissions, err := authService.GetUserAccess(ctx, user.ID, key.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create claims-like structure for API key
+	apiClaims := &auth.JWTClaims{
+		UserID:         user.ID,
+		OrganizationID: key.OrganizationID,
+		Username:       user.Username,
+		Email:          user.Email,
+		Roles:          roles,
+		Permissions:    permissions,
+	}
+
+	ctx = context.WithValue(ctx, "claims", apiClaims)
+	ctx = context.WithValue(ctx, "user_id", user.ID)
+	ctx = context.WithValue(ctx, "organization_id", key.OrganizationID)
+	ctx = context.WithValue(ctx, "username", user.Username)
+	ctx = context.WithValue(ctx, "scopes", []string(key.Scopes))
+	ctx = context.WithValue(ctx, "api_key_id", key.ID)
+	ctx = context.WithValue(ctx, "auth_type", "api_key")
+	return ctx, nil
+}
+
+// respondAPIKeyAccessError responds to a failure to load an API key owner's access. Owners who
+// have left the key's organization can no longer use it
+func respondAPIKeyAccessError(w http.ResponseWriter, err error, log *logger.Logger) {
+	if errors.Is(err, services.ErrNotOrganizationMember) {
+		log.Warn("API key owner is not a member of the key's organization", zap.Error(err))
+		respondError(w, http.StatusForbidden, "API key owner is not a member of its organization")
+		return
+	}
+	log.Error("Failed to load API key owner permissions", zap.Error(err))
+	respondError(w, http.StatusInternalServerError, "Failed to authenticate API key")
+}
+
 // respondError sends an error response with proper JSON encoding
 func respondError(w http.ResponseWriter, status int, message string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -177,3 +203,27 @@ func GetClaims(ctx context.Context) *auth.JWTClaims {
 	}
 	return nil
 }
+
+// IsAPIKeyRequest reports whether the request was authenticated with an API key
+func IsAPIKeyRequest(ctx context.Context) bool {
+	authType, _ := ctx.Value("auth_type").(string)
+	return authType == "api_key"
+}
+
+// GetAPIKeyID extracts the ID of the API key that authenticated the request from request context
+// Returns uuid.Nil for requests not authenticated with an API key
+func GetAPIKeyID(ctx context.Context) uuid.UUID {
+	if keyID, ok := ctx.Value("api_key_id").(uuid.UUID); ok {
+		return keyID
+	}
+	return uuid.Nil
+}
+
+// GetScopes extracts the scopes granted to the request's API key from request context
+// Returns nil for requests not authenticated with an API key
+func GetScopes(ctx context.Context) []string {
+	if scopes, ok := ctx.Value("scopes").([]string); ok {
+		return scopes
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/correlation"
+)
+
+// CorrelationID takes the request's correlation ID from its X-Correlation-ID header, or generates
+// one when the header is missing or unusable, and carries it through the request context. The ID
+// is echoed in the response so callers can find the request's events, executions and logs
+func CorrelationID() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(correlation.Header)
+			if !correlation.Valid(id) {
+				id = correlation.NewID()
+			}
+
+			w.Header().Set(correlation.Header, id)
+			next.ServeHTTP(w, r.WithContext(correlation.WithID(r.Context(), id)))
+		})
+	}
+}
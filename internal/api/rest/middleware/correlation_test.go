@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/correlation"
+)
+
+func TestCorrelationID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected string // empty when a new ID must be generated
+	}{
+		{name: "caller's ID is kept", header: "req-42", expected: "req-42"},
+		{name: "missing ID is generated"},
+		{name: "ID with spaces is replaced", header: "req 42"},
+		{name: "overlong ID is replaced", header: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := CorrelationID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = correlation.FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest("POST", "/api/v1/events", nil)
+			if tt.header != "" {
+				req.Header.Set(correlation.Header, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if seen == "" || (tt.expected != "" && seen != tt.expected) || (tt.expected == "" && seen == tt.header) {
+				t.Errorf("Expected correlation ID %q, got %q", tt.expected, seen)
+			}
+			if got := rec.Header().Get(correlation.Header); got != seen {
+				t.Errorf("Expected the response to echo %q, got %q", seen, got)
+			}
+		})
+	}
+}
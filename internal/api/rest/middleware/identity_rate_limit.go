@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/database"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// identityRateLimitKeyPrefix namespaces the request counters of the identity rate limiter
+const identityRateLimitKeyPrefix = "ratelimit:"
+
+// identityRateLimitScript counts a request in the principal's current window and returns the
+// count and the milliseconds until the window resets. The window starts with the first request
+// counted in it
+var identityRateLimitScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// IdentityRateLimiter limits requests per authenticated principal: the API key for requests
+// authenticated with one, otherwise the user. Unauthenticated requests are limited per client
+// IP. Requests are counted in Redis so the limit holds across API instances
+type IdentityRateLimiter struct {
+	redis  *database.RedisClient
+	config *config.RateLimitConfig
+	logger *logger.Logger
+}
+
+// NewIdentityRateLimiter creates a rate limiter counting requests in redisClient
+func NewIdentityRateLimiter(redisClient *database.RedisClient, cfg *config.RateLimitConfig, log *logger.Logger) *IdentityRateLimiter {
+	return &IdentityRateLimiter{
+		redis:  redisClient,
+		config: cfg,
+		logger: log,
+	}
+}
+
+// Middleware returns the rate limiting middleware. It must run after authentication to limit
+// per principal. Every response reports the limit in X-RateLimit-Limit, X-RateLimit-Remaining
+// and X-RateLimit-Reset (Unix time the window resets); a request over the limit gets 429 with
+// Retry-After. If Redis is unavailable, requests are let through
+func (l *IdentityRateLimiter) Middleware() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, limit := l.identify(r)
+
+			count, resetIn, err := l.count(r.Context(), identity)
+			if err != nil {
+				l.logger.Warn("Rate limit check failed, allowing request",
+					zap.String("identity", identity),
+					zap.Error(err),
+				)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			remaining := limit - count
+			if remaining < 0 {
+				remaining = 0
+			}
+			retryAfter := int((resetIn + time.Second - 1) / time.Second)
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(resetIn).Unix(), 10))
+
+			if count > limit {
+				l.logger.Warn("Rate limit exceeded",
+					zap.String("identity", identity),
+					zap.Int("limit", limit),
+					zap.String("path", r.URL.Path),
+				)
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				respondError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// identify returns the principal a request is counted against and its limit per window
+func (l *IdentityRateLimiter) identify(r *http.Request) (string, int) {
+	ctx := r.Context()
+	claims := GetClaims(ctx)
+	if claims == nil {
+		return "ip:" + clientIP(r), l.config.AnonymousLimit
+	}
+
+	identity := "user:" + claims.UserID.String()
+	if keyID := GetAPIKeyID(ctx); keyID != uuid.Nil {
+		identity = "api_key:" + keyID.String()
+	}
+	return identity, l.limitFor(claims.Roles)
+}
+
+// limitFor returns the highest limit of roles, or the default limit if none of them has one
+func (l *IdentityRateLimiter) limitFor(roles []string) int {
+	limit, found := 0, false
+	for _, role := range roles {
+		if roleLimit, ok := l.config.RoleLimits[role]; ok && (!found || roleLimit > limit) {
+			limit, found = roleLimit, true
+		}
+	}
+	if !found {
+		return l.config.DefaultLimit
+	}
+	return limit
+}
+
+// count counts a request against identity and returns the requests counted in the current
+// window, including this one, and the time until the window resets
+func (l *IdentityRateLimiter) count(ctx context.Context, identity string) (int, time.Duration, error) {
+	result, err := identityRateLimitScript.Run(ctx, l.redis.Client,
+		[]string{identityRateLimitKeyPrefix + identity},
+		l.config.Window.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(result) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rate limit script result: %v", result)
+	}
+	return int(result[0]), time.Duration(result[1]) * time.Millisecond, nil
+}
+
+// clientIP returns the IP of the client, without the port. Behind a proxy, the RealIP
+// middleware has already replaced RemoteAddr with the forwarded client IP
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
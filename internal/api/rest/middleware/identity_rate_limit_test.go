@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/auth"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/database"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRateLimitRedis serves the rate limit script from memory instead of a server. Windows
+// never expire, so the reset time is always the full window
+type fakeRateLimitRedis struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func newFakeRateLimitRedis() (*database.RedisClient, *fakeRateLimitRedis) {
+	fake := &fakeRateLimitRedis{counts: make(map[string]int64)}
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	client.AddHook(fake)
+	return &database.RedisClient{Client: client}, fake
+}
+
+func (f *fakeRateLimitRedis) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (f *fakeRateLimitRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (f *fakeRateLimitRedis) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		// EVALSHA sha 1 key window
+		args := cmd.Args()
+		key := args[3].(string)
+		f.counts[key]++
+		cmd.(*redis.Cmd).SetVal([]interface{}{f.counts[key], args[4].(int64)})
+		return nil
+	}
+}
+
+func TestIdentityRateLimiter(t *testing.T) {
+	cfg := &config.RateLimitConfig{
+		Window:         time.Minute,
+		DefaultLimit:   3,
+		AnonymousLimit: 1,
+		RoleLimits:     map[string]int{"agent": 5, "workflow_viewer": 2},
+	}
+
+	user := func(roles ...string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/workflows", nil)
+		claims := &auth.JWTClaims{UserID: uuid.New(), Roles: roles}
+		return req.WithContext(context.WithValue(req.Context(), "claims", claims))
+	}
+	apiKey := func(userReq *http.Request) *http.Request {
+		return userReq.WithContext(context.WithValue(userReq.Context(), "api_key_id", uuid.New()))
+	}
+	anonymous := func(remoteAddr string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+		req.RemoteAddr = remoteAddr
+		return req
+	}
+
+	// allowed sends requests until one is rate limited and returns how many were let through
+	allowed := func(t *testing.T, mw http.Handler, req *http.Request) int {
+		for i := 0; i < 20; i++ {
+			rec := httptest.NewRecorder()
+			mw.ServeHTTP(rec, req)
+			if rec.Code == http.StatusTooManyRequests {
+				if rec.Header().Get("Retry-After") != "60" {
+					t.Errorf("Retry-After = %q, want 60", rec.Header().Get("Retry-After"))
+				}
+				return i
+			}
+		}
+		return 20
+	}
+
+	newMiddleware := func() http.Handler {
+		redisClient, _ := newFakeRateLimitRedis()
+		limiter := NewIdentityRateLimiter(redisClient, cfg, logger.NewForTesting())
+		return limiter.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+
+	t.Run("limits depend on the principal's roles", func(t *testing.T) {
+		mw := newMiddleware()
+		tests := []struct {
+			name string
+			req  *http.Request
+			want int
+		}{
+			{"user without a role limit", user("approver"), 3},
+			{"user with a role limit", user("workflow_viewer"), 2},
+			{"highest limit of several roles", user("workflow_viewer", "agent"), 5},
+			{"agent api key", apiKey(user("agent")), 5},
+			{"anonymous", anonymous("203.0.113.7:4711"), 1},
+		}
+		for _, tt := range tests {
+			if got := allowed(t, mw, tt.req); got != tt.want {
+				t.Errorf("%s: allowed %d requests, want %d", tt.name, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("principals are counted separately", func(t *testing.T) {
+		mw := newMiddleware()
+		owner := user()
+
+		if got := allowed(t, mw, owner); got != 3 {
+			t.Fatalf("allowed %d requests, want 3", got)
+		}
+		if got := allowed(t, mw, apiKey(owner)); got != 3 {
+			t.Errorf("an API key has its own limit, allowed %d requests, want 3", got)
+		}
+		if got := allowed(t, mw, anonymous("203.0.113.7:4711")); got != 1 {
+			t.Errorf("allowed %d anonymous requests, want 1", got)
+		}
+		if got := allowed(t, mw, anonymous("203.0.113.7:4712")); got != 0 {
+			t.Errorf("anonymous requests are counted per IP, not per connection, allowed %d, want 0", got)
+		}
+	})
+
+	t.Run("responses report the limit", func(t *testing.T) {
+		mw := newMiddleware()
+		req := user()
+
+		for _, remaining := range []string{"2", "1", "0", "0"} {
+			rec := httptest.NewRecorder()
+			mw.ServeHTTP(rec, req)
+			if got := rec.Header().Get("X-RateLimit-Remaining"); got != remaining {
+				t.Errorf("X-RateLimit-Remaining = %q, want %q", got, remaining)
+			}
+			if got := rec.Header().Get("X-RateLimit-Limit"); got != "3" {
+				t.Errorf("X-RateLimit-Limit = %q, want 3", got)
+			}
+			reset, err := strconv.ParseInt(rec.Header().Get("X-RateLimit-Reset"), 10, 64)
+			if err != nil || time.Until(time.Unix(reset, 0)) > time.Minute+time.Second || time.Until(time.Unix(reset, 0)) < 58*time.Second {
+				t.Errorf("X-RateLimit-Reset = %q, want the Unix time a minute from now", rec.Header().Get("X-RateLimit-Reset"))
+			}
+		}
+	})
+
+	t.Run("requests are allowed when redis is unavailable", func(t *testing.T) {
+		client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+		limiter := NewIdentityRateLimiter(&database.RedisClient{Client: client}, cfg, logger.NewForTesting())
+		mw := limiter.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, anonymous("203.0.113.7:4711"))
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+	})
+}
@@ -18,7 +18,7 @@ func Logger(log *logger.Logger) func(next http.Handler) http.Handler {
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
 			defer func() {
-				log.Info("HTTP request",
+				log.WithContext(r.Context()).Info("HTTP request",
 					zap.String("method", r.Method),
 					zap.String("path", r.URL.Path),
 					zap.String("remote_addr", r.RemoteAddr),
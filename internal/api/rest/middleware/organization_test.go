@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/internal/services"
+	"github.com/davidmoltin/intelligent-workflows/pkg/auth"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// orgRoles serves the roles a user holds in each organization, with global roles under
+// uuid.Nil. Each role grants the permission "<role>:access"
+type orgRoles struct {
+	services.UserRepository
+	services.OrganizationLookup
+
+	roles map[uuid.UUID][]string
+}
+
+func (o *orgRoles) GetUserRoles(ctx context.Context, userID, organizationID uuid.UUID) ([]string, error) {
+	return append(append([]string{}, o.roles[organizationID]...), o.roles[uuid.Nil]...), nil
+}
+
+func (o *orgRoles) GetUserPermissions(ctx context.Context, userID, organizationID uuid.UUID) ([]string, error) {
+	roles, _ := o.GetUserRoles(ctx, userID, organizationID)
+	permissions := make([]string, len(roles))
+	for i, role := range roles {
+		permissions[i] = role + ":access"
+	}
+	return permissions, nil
+}
+
+func (o *orgRoles) CheckUserAccess(ctx context.Context, orgID, userID uuid.UUID) (bool, error) {
+	return len(o.roles[orgID]) > 0, nil
+}
+
+func (o *orgRoles) GetUserOrganizations(ctx context.Context, userID uuid.UUID) ([]models.Organization, error) {
+	return nil, nil
+}
+
+func TestResolveOrganization(t *testing.T) {
+	home, member, other := uuid.New(), uuid.New(), uuid.New()
+	log := logger.NewForTesting()
+
+	newMiddleware := func(roles map[uuid.UUID][]string) func(http.Handler) http.Handler {
+		repo := &orgRoles{roles: roles}
+		authService := services.NewAuthService(repo, nil, nil, repo, auth.NewJWTManager("test-secret"), log)
+		return ResolveOrganization(authService, OrganizationFromHeader, log)
+	}
+	roles := map[uuid.UUID][]string{home: {"admin"}, member: {"approver"}}
+
+	request := func(authType string, organizationID string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/approvals", nil)
+		if organizationID != "" {
+			req.Header.Set(OrganizationHeader, organizationID)
+		}
+		claims := &auth.JWTClaims{UserID: uuid.New(), OrganizationID: home, Roles: []string{"admin"}, Permissions: []string{"admin:access"}}
+		ctx := context.WithValue(req.Context(), "claims", claims)
+		ctx = context.WithValue(ctx, "organization_id", home)
+		ctx = context.WithValue(ctx, "auth_type", authType)
+		return req.WithContext(ctx)
+	}
+
+	// resolve serves req and returns the status and the claims seen by the next handler
+	resolve := func(mw func(http.Handler) http.Handler, req *http.Request) (int, *auth.JWTClaims) {
+		var seen *auth.JWTClaims
+		rec := httptest.NewRecorder()
+		mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = GetClaims(r.Context())
+			if GetOrganizationID(r.Context()) != seen.OrganizationID {
+				t.Errorf("organization_id = %s, claims organization = %s", GetOrganizationID(r.Context()), seen.OrganizationID)
+			}
+			w.WriteHeader(http.StatusOK)
+		})).ServeHTTP(rec, req)
+		return rec.Code, seen
+	}
+
+	tests := []struct {
+		name            string
+		roles           map[uuid.UUID][]string
+		req             *http.Request
+		want            int
+		wantOrg         uuid.UUID
+		wantPermissions []string
+	}{
+		{"no organization requested", roles, request("jwt", ""), http.StatusOK, home, []string{"admin:access"}},
+		{"token organization", roles, request("jwt", home.String()), http.StatusOK, home, []string{"admin:access"}},
+		{"other organization the user belongs to", roles, request("jwt", member.String()), http.StatusOK, member, []string{"approver:access"}},
+		{"organization the user does not belong to", roles, request("jwt", other.String()), http.StatusForbidden, uuid.Nil, nil},
+		{"super admin in any organization", map[uuid.UUID][]string{home: {"admin"}, uuid.Nil: {services.SuperAdminRole}}, request("jwt", other.String()), http.StatusOK, other, []string{"super_admin:access"}},
+		{"api key in its own organization", roles, request("api_key", home.String()), http.StatusOK, home, []string{"admin:access"}},
+		{"api key in another organization", roles, request("api_key", member.String()), http.StatusForbidden, uuid.Nil, nil},
+		{"invalid organization ID", roles, request("jwt", "acme"), http.StatusBadRequest, uuid.Nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, claims := resolve(newMiddleware(tt.roles), tt.req)
+			if status != tt.want {
+				t.Fatalf("status = %d, want %d", status, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			if claims.OrganizationID != tt.wantOrg {
+				t.Errorf("organization = %s, want %s", claims.OrganizationID, tt.wantOrg)
+			}
+			if !reflect.DeepEqual(claims.Permissions, tt.wantPermissions) {
+				t.Errorf("permissions = %v, want %v", claims.Permissions, tt.wantPermissions)
+			}
+		})
+	}
+
+	t.Run("claims of the authenticated organization are left unchanged", func(t *testing.T) {
+		req := request("jwt", member.String())
+		original := GetClaims(req.Context())
+
+		resolve(newMiddleware(roles), req)
+		if original.OrganizationID != home || !reflect.DeepEqual(original.Permissions, []string{"admin:access"}) {
+			t.Errorf("original claims were modified: %+v", original)
+		}
+	})
+
+	t.Run("unauthenticated requests pass through", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/approvals", nil)
+		req.Header.Set(OrganizationHeader, member.String())
+		if got := serve(newMiddleware(roles), req); got != http.StatusOK {
+			t.Errorf("status = %d, want %d", got, http.StatusOK)
+		}
+	})
+}
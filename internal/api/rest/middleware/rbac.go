@@ -20,6 +20,17 @@ func RequirePermission(permission string, log *logger.Logger) func(next http.Han
 				return
 			}
 
+			// API keys may only use the permissions they were granted as scopes
+			if !hasScope(r, permission) {
+				log.Warn("API key scope denied",
+					zap.String("user_id", claims.UserID.String()),
+					zap.String("username", claims.Username),
+					zap.String("required_scope", permission),
+				)
+				respondError(w, http.StatusForbidden, "API key lacks required scope: "+permission)
+				return
+			}
+
 			// Check if user has the required permission
 			if !hasPermission(claims.Permissions, permission) {
 				log.Warn("Permission denied",
@@ -51,7 +62,7 @@ func RequireAnyPermission(permissions []string, log *logger.Logger) func(next ht
 			// Check if user has any of the required permissions
 			hasAny := false
 			for _, perm := range permissions {
-				if hasPermission(claims.Permissions, perm) {
+				if hasScope(r, perm) && hasPermission(claims.Permissions, perm) {
 					hasAny = true
 					break
 				}
@@ -86,7 +97,7 @@ func RequireAllPermissions(permissions []string, log *logger.Logger) func(next h
 
 			// Check if user has all required permissions
 			for _, perm := range permissions {
-				if !hasPermission(claims.Permissions, perm) {
+				if !hasScope(r, perm) || !hasPermission(claims.Permissions, perm) {
 					log.Warn("Permission denied - missing permission",
 						zap.String("user_id", claims.UserID.String()),
 						zap.String("username", claims.Username),
@@ -177,6 +188,15 @@ func hasPermission(userPermissions []string, required string) bool {
 	return false
 }
 
+// hasScope reports whether an API key request was granted permission as a scope. Requests
+// authenticated with a JWT are not scoped, so only their RBAC permissions apply
+func hasScope(r *http.Request, permission string) bool {
+	if !IsAPIKeyRequest(r.Context()) {
+		return true
+	}
+	return hasPermission(GetScopes(r.Context()), permission)
+}
+
 func hasRole(userRoles []string, required string) bool {
 	for _, role := range userRoles {
 		if role == required {
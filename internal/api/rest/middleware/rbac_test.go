@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/auth"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// withJWT returns a request authenticated with a JWT carrying permissions
+func withJWT(permissions ...string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/workflows", nil)
+	ctx := context.WithValue(req.Context(), "claims", &auth.JWTClaims{UserID: uuid.New(), Permissions: permissions})
+	ctx = context.WithValue(ctx, "auth_type", "jwt")
+	return req.WithContext(ctx)
+}
+
+// withAPIKey returns a request authenticated with an API key granted scopes, owned by a user
+// with permissions
+func withAPIKey(scopes []string, permissions ...string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/workflows", nil)
+	ctx := context.WithValue(req.Context(), "claims", &auth.JWTClaims{UserID: uuid.New(), Permissions: permissions})
+	ctx = context.WithValue(ctx, "scopes", scopes)
+	ctx = context.WithValue(ctx, "auth_type", "api_key")
+	return req.WithContext(ctx)
+}
+
+func serve(mw func(http.Handler) http.Handler, req *http.Request) int {
+	rec := httptest.NewRecorder()
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRequirePermission_APIKeyScopes(t *testing.T) {
+	log := logger.NewForTesting()
+	readOnly := RequirePermission("workflow:read", log)
+	create := RequirePermission("workflow:create", log)
+
+	tests := []struct {
+		name string
+		mw   func(http.Handler) http.Handler
+		req  *http.Request
+		want int
+	}{
+		{"jwt with permission", readOnly, withJWT("workflow:read"), http.StatusOK},
+		{"jwt without permission", create, withJWT("workflow:read"), http.StatusForbidden},
+		{"jwt is not scoped", readOnly, withJWT("workflow:read", "workflow:create"), http.StatusOK},
+		{"api key with scope", readOnly, withAPIKey([]string{"workflow:read"}, "workflow:read", "workflow:create"), http.StatusOK},
+		{"api key without scope", create, withAPIKey([]string{"workflow:read"}, "workflow:read", "workflow:create"), http.StatusForbidden},
+		{"api key with no scopes", readOnly, withAPIKey(nil, "workflow:read"), http.StatusForbidden},
+		{"api key scope beyond owner permissions", create, withAPIKey([]string{"workflow:create"}, "workflow:read"), http.StatusForbidden},
+		{"unauthenticated", readOnly, httptest.NewRequest(http.MethodGet, "/api/v1/workflows", nil), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(tt.mw, tt.req); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireAnyAndAllPermissions_APIKeyScopes(t *testing.T) {
+	log := logger.NewForTesting()
+	permissions := []string{"workflow:read", "execution:read"}
+	owner := []string{"workflow:read", "execution:read"}
+
+	tests := []struct {
+		name string
+		mw   func(http.Handler) http.Handler
+		req  *http.Request
+		want int
+	}{
+		{"any with one scope", RequireAnyPermission(permissions, log), withAPIKey([]string{"execution:read"}, owner...), http.StatusOK},
+		{"any without scopes", RequireAnyPermission(permissions, log), withAPIKey([]string{"event:create"}, owner...), http.StatusForbidden},
+		{"any with jwt", RequireAnyPermission(permissions, log), withJWT("workflow:read"), http.StatusOK},
+		{"all with every scope", RequireAllPermissions(permissions, log), withAPIKey(permissions, owner...), http.StatusOK},
+		{"all missing a scope", RequireAllPermissions(permissions, log), withAPIKey([]string{"workflow:read"}, owner...), http.StatusForbidden},
+		{"all with jwt", RequireAllPermissions(permissions, log), withJWT(owner...), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(tt.mw, tt.req); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/davidmoltin/intelligent-workflows/internal/api/rest/handlers"
 	customMiddleware "github.com/davidmoltin/intelligent-workflows/internal/api/rest/middleware"
 	"github.com/davidmoltin/intelligent-workflows/internal/services"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/correlation"
+	"github.com/davidmoltin/intelligent-workflows/pkg/database"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
 	"github.com/davidmoltin/intelligent-workflows/pkg/metrics"
 	"github.com/go-chi/chi/v5"
@@ -23,6 +26,7 @@ type Router struct {
 	handlers    *handlers.Handlers
 	authService *services.AuthService
 	metrics     *metrics.Metrics
+	rateLimiter *customMiddleware.IdentityRateLimiter
 }
 
 // NewRouter creates a new HTTP router
@@ -31,6 +35,7 @@ func NewRouter(log *logger.Logger, h *handlers.Handlers, authService *services.A
 
 	// Middleware
 	r.Use(middleware.RequestID)
+	r.Use(customMiddleware.CorrelationID())
 	r.Use(middleware.RealIP)
 	r.Use(customMiddleware.Logger(log))
 	r.Use(middleware.Recoverer)
@@ -39,6 +44,9 @@ func NewRouter(log *logger.Logger, h *handlers.Handlers, authService *services.A
 	// Metrics middleware
 	r.Use(customMiddleware.Metrics(m))
 
+	// Tracing middleware
+	r.Use(customMiddleware.TraceContext())
+
 	// Security middleware
 	r.Use(customMiddleware.SecurityHeaders())
 	r.Use(customMiddleware.RequestSizeLimit(customMiddleware.GetMaxRequestSize()))
@@ -66,8 +74,8 @@ func NewRouter(log *logger.Logger, h *handlers.Handlers, authService *services.A
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   allowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
-		ExposedHeaders:   []string{"Link", "X-Request-ID"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID", correlation.Header, customMiddleware.OrganizationHeader},
+		ExposedHeaders:   []string{"Link", "X-Request-ID", correlation.Header, "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After"},
 		AllowCredentials: allowCredentials,
 		MaxAge:           300,
 	}))
@@ -81,6 +89,13 @@ func NewRouter(log *logger.Logger, h *handlers.Handlers, authService *services.A
 	}
 }
 
+// SetRateLimiter limits requests per user, API key or client IP, counted in Redis (optional
+// dependency). Must be called before SetupRoutes; without it, authenticated requests are limited
+// per instance
+func (r *Router) SetRateLimiter(redisClient *database.RedisClient, cfg *config.RateLimitConfig) {
+	r.rateLimiter = customMiddleware.NewIdentityRateLimiter(redisClient, cfg, r.logger)
+}
+
 // SetupRoutes configures all API routes
 func (r *Router) SetupRoutes() {
 	// Prometheus metrics endpoint (no auth required)
@@ -89,6 +104,8 @@ func (r *Router) SetupRoutes() {
 	// Health endpoints (no auth required)
 	r.router.Get("/health", r.handlers.Health.Health)
 	r.router.Get("/ready", r.handlers.Health.Ready)
+	r.router.Get("/livez", r.handlers.Health.Live)
+	r.router.Get("/readyz", r.handlers.Health.Readyz)
 
 	// WebSocket endpoint (requires authentication)
 	r.router.Group(func(router chi.Router) {
@@ -107,6 +124,11 @@ func (r *Router) SetupRoutes() {
 
 		// Auth endpoints (public)
 		router.Route("/auth", func(router chi.Router) {
+			// Limit unauthenticated requests, e.g. login attempts, per client IP
+			if r.rateLimiter != nil {
+				router.Use(r.rateLimiter.Middleware())
+			}
+
 			router.Post("/register", r.handlers.Auth.Register)
 			router.Post("/login", r.handlers.Auth.Login)
 			router.Post("/refresh", r.handlers.Auth.RefreshToken)
@@ -127,30 +149,48 @@ func (r *Router) SetupRoutes() {
 			// Apply optional auth (JWT or API key)
 			router.Use(customMiddleware.OptionalAuth(r.authService, r.logger))
 
-			// Apply rate limiting (100 requests per minute per user)
-			router.Use(customMiddleware.RateLimitWithConfig(100, 200, r.logger))
+			// Act in the organization selected by the X-Organization-ID header, if any
+			router.Use(customMiddleware.ResolveOrganization(r.authService, customMiddleware.OrganizationFromHeader, r.logger))
+
+			// Apply rate limiting per user or API key, with per-role limits
+			if r.rateLimiter != nil {
+				router.Use(r.rateLimiter.Middleware())
+			} else {
+				router.Use(customMiddleware.RateLimitWithConfig(100, 200, r.logger))
+			}
 
 			// Workflows
 			router.Route("/workflows", func(router chi.Router) {
 				// Read operations
 				router.With(customMiddleware.RequirePermission("workflow:read", r.logger)).Get("/", r.handlers.Workflow.List)
 				router.With(customMiddleware.RequirePermission("workflow:read", r.logger)).Get("/{id}", r.handlers.Workflow.Get)
+				router.With(customMiddleware.RequirePermission("workflow:read", r.logger)).Get("/{id}/versions", r.handlers.Workflow.ListVersions)
+				router.With(customMiddleware.RequirePermission("workflow:read", r.logger)).Get("/{id}/versions/{version}", r.handlers.Workflow.GetVersion)
+				router.With(customMiddleware.RequirePermission("workflow:read", r.logger)).Get("/{id}/diff", r.handlers.Workflow.DiffVersions)
 
 				// Write operations
 				router.With(customMiddleware.RequirePermission("workflow:create", r.logger)).Post("/", r.handlers.Workflow.Create)
+				router.With(customMiddleware.RequirePermission("workflow:read", r.logger)).Post("/validate", r.handlers.Workflow.Validate)
 				router.With(customMiddleware.RequirePermission("workflow:update", r.logger)).Put("/{id}", r.handlers.Workflow.Update)
 				router.With(customMiddleware.RequirePermission("workflow:delete", r.logger)).Delete("/{id}", r.handlers.Workflow.Delete)
 				router.With(customMiddleware.RequirePermission("workflow:update", r.logger)).Post("/{id}/enable", r.handlers.Workflow.Enable)
 				router.With(customMiddleware.RequirePermission("workflow:update", r.logger)).Post("/{id}/disable", r.handlers.Workflow.Disable)
+				router.With(customMiddleware.RequirePermission("workflow:execute", r.logger)).Post("/{id}/dry-run", r.handlers.Event.DryRunWorkflow)
+				router.With(customMiddleware.RequirePermission("workflow:execute", r.logger)).Post("/{id}/trigger", r.handlers.Event.TriggerWorkflow)
 
 				// Schedule operations
 				router.With(customMiddleware.RequirePermission("workflow:read", r.logger)).Get("/{id}/schedules", r.handlers.Schedule.GetWorkflowSchedules)
 				router.With(customMiddleware.RequirePermission("workflow:update", r.logger)).Post("/{id}/schedules", r.handlers.Schedule.CreateSchedule)
+				router.With(customMiddleware.RequirePermission("workflow:read", r.logger)).Get("/{id}/schedules/{sid}/preview", r.handlers.Schedule.PreviewSchedule)
 			})
 
 			// Events
 			router.Route("/events", func(router chi.Router) {
 				router.With(customMiddleware.RequirePermission("event:create", r.logger)).Post("/", r.handlers.Event.CreateEvent)
+				router.With(customMiddleware.RequirePermission("organization:update", r.logger)).Put("/sources/{source}/secret", r.handlers.Event.SetWebhookSecret)
+				router.With(customMiddleware.RequirePermission("organization:update", r.logger)).Delete("/sources/{source}/secret", r.handlers.Event.DeleteWebhookSecret)
+				router.With(customMiddleware.RequirePermission("event:read", r.logger)).Get("/dead-letter", r.handlers.Event.ListDeadLetterEvents)
+				router.With(customMiddleware.RequirePermission("event:create", r.logger)).Post("/dead-letter/{id}/replay", r.handlers.Event.ReplayDeadLetterEvent)
 			})
 
 			// Executions
@@ -160,15 +200,23 @@ func (r *Router) SetupRoutes() {
 				router.With(customMiddleware.RequirePermission("execution:read", r.logger)).Get("/paused", r.handlers.Execution.ListPausedExecutions)
 				router.With(customMiddleware.RequirePermission("execution:read", r.logger)).Get("/{id}", r.handlers.Execution.GetExecution)
 				router.With(customMiddleware.RequirePermission("execution:read", r.logger)).Get("/{id}/trace", r.handlers.Execution.GetExecutionTrace)
+				router.With(customMiddleware.RequirePermission("execution:read", r.logger)).Get("/{id}/steps", r.handlers.Execution.ListStepExecutions)
 
 				// Control operations
 				router.With(customMiddleware.RequirePermission("execution:cancel", r.logger)).Post("/{id}/pause", r.handlers.Execution.PauseExecution)
 				router.With(customMiddleware.RequirePermission("execution:cancel", r.logger)).Post("/{id}/resume", r.handlers.Execution.ResumeExecution)
+				router.With(customMiddleware.RequirePermission("execution:cancel", r.logger)).Post("/{id}/cancel", r.handlers.Execution.CancelExecution)
+
+				// Re-running replays trigger payloads through their workflows
+				router.With(customMiddleware.RequirePermission("workflow:execute", r.logger)).Post("/rerun", r.handlers.Execution.RerunExecutions)
 			})
 
 			// Approvals
 			router.Route("/approvals", func(router chi.Router) {
 				router.With(customMiddleware.RequirePermission("approval:read", r.logger)).Get("/", r.handlers.Approval.ListApprovals)
+				router.With(customMiddleware.RequirePermission("approval:read", r.logger)).Get("/delegations", r.handlers.Approval.ListDelegations)
+				router.With(customMiddleware.RequirePermission("approval:approve", r.logger)).Post("/delegations", r.handlers.Approval.CreateDelegation)
+				router.With(customMiddleware.RequirePermission("approval:approve", r.logger)).Delete("/delegations/{id}", r.handlers.Approval.RevokeDelegation)
 				router.With(customMiddleware.RequirePermission("approval:read", r.logger)).Get("/{id}", r.handlers.Approval.GetApproval)
 				router.With(customMiddleware.RequirePermission("approval:approve", r.logger)).Post("/{id}/approve", r.handlers.Approval.ApproveRequest)
 				router.With(customMiddleware.RequirePermission("approval:reject", r.logger)).Post("/{id}/reject", r.handlers.Approval.RejectRequest)
@@ -203,6 +251,33 @@ func (r *Router) SetupRoutes() {
 				})
 			}
 
+			// Execution retention (only if retention handler is configured)
+			if r.handlers.Retention != nil {
+				router.Route("/retention", func(router chi.Router) {
+					router.With(customMiddleware.RequirePermission("organization:read", r.logger)).Get("/", r.handlers.Retention.GetRetention)
+					router.With(customMiddleware.RequirePermission("organization:update", r.logger)).Put("/", r.handlers.Retention.SetRetention)
+					router.With(customMiddleware.RequirePermission("organization:update", r.logger)).Delete("/", r.handlers.Retention.DeleteRetention)
+				})
+			}
+
+			// Permissions that can be granted to roles
+			router.With(customMiddleware.RequirePermission("role:read", r.logger)).Get("/permissions", r.handlers.Role.ListPermissions)
+
+			// Roles
+			router.Route("/roles", func(router chi.Router) {
+				router.With(customMiddleware.RequirePermission("role:read", r.logger)).Get("/", r.handlers.Role.ListRoles)
+				router.With(customMiddleware.RequirePermission("role:create", r.logger)).Post("/", r.handlers.Role.CreateRole)
+				router.With(customMiddleware.RequirePermission("role:read", r.logger)).Get("/{id}", r.handlers.Role.GetRole)
+				router.With(customMiddleware.RequirePermission("role:update", r.logger)).Post("/{id}/permissions", r.handlers.Role.AttachPermissions)
+				router.With(customMiddleware.RequirePermission("role:update", r.logger)).Delete("/{id}/permissions/{permission}", r.handlers.Role.DetachPermission)
+			})
+
+			// Role assignments within the organization
+			router.Route("/users/{user_id}/roles", func(router chi.Router) {
+				router.With(customMiddleware.RequirePermission("role:assign", r.logger)).Post("/", r.handlers.Role.AssignRole)
+				router.With(customMiddleware.RequirePermission("role:assign", r.logger)).Delete("/{role_id}", r.handlers.Role.UnassignRole)
+			})
+
 			// Organizations
 			router.Route("/organizations", func(router chi.Router) {
 				// List user's organizations and create
@@ -214,18 +289,23 @@ func (r *Router) SetupRoutes() {
 
 				// Operations on specific organizations
 				router.Route("/{id}", func(router chi.Router) {
-					router.With(customMiddleware.RequirePermission("organization:read", r.logger)).Get("/", r.handlers.Organization.Get)
-					router.With(customMiddleware.RequirePermission("organization:update", r.logger)).Put("/", r.handlers.Organization.Update)
-					router.With(customMiddleware.RequirePermission("organization:delete", r.logger)).Delete("/", r.handlers.Organization.Delete)
+					// Access check, answered for organizations the user does not belong to
+					router.With(customMiddleware.RequirePermission("organization:read", r.logger)).Get("/access", r.handlers.Organization.CheckUserAccess)
 
-					// User management within organization
-					router.With(customMiddleware.RequirePermission("organization:read", r.logger)).Get("/users", r.handlers.Organization.ListUsers)
-					router.With(customMiddleware.RequirePermission("organization:manage_users", r.logger)).Post("/users", r.handlers.Organization.AddUser)
-					router.With(customMiddleware.RequirePermission("organization:manage_users", r.logger)).Delete("/users/{user_id}", r.handlers.Organization.RemoveUser)
-					router.With(customMiddleware.RequirePermission("organization:manage_users", r.logger)).Put("/users/{user_id}/role", r.handlers.Organization.UpdateUserRole)
+					// Permissions are checked against the user's roles in the organization
+					router.Group(func(router chi.Router) {
+						router.Use(customMiddleware.ResolveOrganization(r.authService, customMiddleware.OrganizationFromURLParam("id"), r.logger))
 
-					// Access check
-					router.With(customMiddleware.RequirePermission("organization:read", r.logger)).Get("/access", r.handlers.Organization.CheckUserAccess)
+						router.With(customMiddleware.RequirePermission("organization:read", r.logger)).Get("/", r.handlers.Organization.Get)
+						router.With(customMiddleware.RequirePermission("organization:update", r.logger)).Put("/", r.handlers.Organization.Update)
+						router.With(customMiddleware.RequirePermission("organization:delete", r.logger)).Delete("/", r.handlers.Organization.Delete)
+
+						// User management within organization
+						router.With(customMiddleware.RequirePermission("organization:read", r.logger)).Get("/users", r.handlers.Organization.ListUsers)
+						router.With(customMiddleware.RequirePermission("organization:manage_users", r.logger)).Post("/users", r.handlers.Organization.AddUser)
+						router.With(customMiddleware.RequirePermission("organization:manage_users", r.logger)).Delete("/users/{user_id}", r.handlers.Organization.RemoveUser)
+						router.With(customMiddleware.RequirePermission("organization:manage_users", r.logger)).Put("/users/{user_id}/role", r.handlers.Organization.UpdateUserRole)
+					})
 				})
 			})
 		})
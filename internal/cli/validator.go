@@ -100,11 +100,15 @@ func validateDefinition(def *models.WorkflowDefinition) error {
 
 		validStepTypes := map[string]bool{
 			"condition": true,
+			"switch":    true,
 			"action":    true,
 			"approval":  true,
 			"parallel":  true,
 			"webhook":   true,
 			"delay":     true,
+			"call":      true,
+			"query":     true,
+			"set":       true,
 		}
 
 		if !validStepTypes[step.Type] {
@@ -119,6 +123,26 @@ func validateDefinition(def *models.WorkflowDefinition) error {
 		if step.Type == "condition" && step.Condition == nil {
 			return fmt.Errorf("step[%d].condition is required for condition type", i)
 		}
+
+		if step.Type == "switch" && step.Switch == nil {
+			return fmt.Errorf("step[%d].switch is required for switch type", i)
+		}
+
+		if step.Type == "call" && (step.Call == nil || step.Call.WorkflowID == "") {
+			return fmt.Errorf("step[%d].call.workflow_id is required for call type", i)
+		}
+
+		if step.Type == "query" && (step.Query == nil || step.Query.Datasource == "" || step.Query.SQL == "") {
+			return fmt.Errorf("step[%d].query.datasource and query.sql are required for query type", i)
+		}
+
+		if step.Type == "delay" && (step.Delay == nil || step.Delay.Duration == "") {
+			return fmt.Errorf("step[%d].delay.duration is required for delay type", i)
+		}
+
+		if step.Type == "set" && len(step.Set) == 0 {
+			return fmt.Errorf("step[%d].set is required for set type", i)
+		}
 	}
 
 	return nil
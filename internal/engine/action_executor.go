@@ -3,7 +3,11 @@ package engine
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,7 +15,11 @@ import (
 	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/correlation"
+	"github.com/davidmoltin/intelligent-workflows/pkg/egress"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/slack"
 	"github.com/google/uuid"
 )
 
@@ -35,24 +43,68 @@ type ApprovalService interface {
 		approverRole string,
 		reason string,
 		expiresIn *time.Duration,
+		escalation models.ApprovalEscalationChain,
+		requiredApprovers []uuid.UUID,
+		quorum int,
 	) (*models.ApprovalRequest, error)
 }
 
+// AIService interface for the LLM-backed ai_* actions
+type AIService interface {
+	Classify(ctx context.Context, input string, labels []string, instructions string) (string, error)
+	Extract(ctx context.Context, input string, extract *models.ExtractAction) (map[string]interface{}, error)
+}
+
+// SlackService interface for posting slack action messages with an organization's credentials
+type SlackService interface {
+	PostSlackMessage(ctx context.Context, organizationID uuid.UUID, message slack.Message) (*slack.PostResult, error)
+}
+
+// EmailService interface for sending email actions through the notification transport
+type EmailService interface {
+	SendEmail(ctx context.Context, email models.EmailMessage) (string, error)
+}
+
+const (
+	// defaultWebhookTimeout bounds each webhook delivery attempt unless configured otherwise
+	defaultWebhookTimeout = 30 * time.Second
+	// maxWebhookResponseBytes limits how much of a webhook response is captured
+	maxWebhookResponseBytes = 1 << 20
+	// webhookSignatureHeader carries the HMAC-SHA256 of a signed webhook's body, in the format
+	// inbound events are verified with
+	webhookSignatureHeader = "X-Signature"
+)
+
+// allowedWebhookMethods are the HTTP methods webhook actions may use
+var allowedWebhookMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
 // ActionExecutor handles executing workflow actions
 type ActionExecutor struct {
 	logger          *logger.Logger
 	httpClient      *http.Client
 	approvalService ApprovalService
-	executionID     uuid.UUID // Current execution ID for context
+	aiService       AIService
+	slackService    SlackService
+	emailService    EmailService
+	executionID     uuid.UUID // Fallback execution ID when ctx carries none
+	webhookTimeout  time.Duration
+	signingSecrets  map[string]string // Webhook signing secrets by name
+	egress          *egress.Policy    // Destinations webhooks may reach; nil allows any
 }
 
 // NewActionExecutor creates a new action executor
 func NewActionExecutor(log *logger.Logger) *ActionExecutor {
 	return &ActionExecutor{
 		logger: log,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		// Webhook requests are bounded per attempt instead
+		httpClient:     &http.Client{},
+		webhookTimeout: defaultWebhookTimeout,
 	}
 }
 
@@ -61,7 +113,38 @@ func (ae *ActionExecutor) SetApprovalService(service ApprovalService) {
 	ae.approvalService = service
 }
 
-// SetExecutionContext sets the current execution context
+// SetAIService sets the AI service used by ai_* actions (optional dependency)
+func (ae *ActionExecutor) SetAIService(service AIService) {
+	ae.aiService = service
+}
+
+// SetSlackService sets the service slack actions post through (optional dependency)
+func (ae *ActionExecutor) SetSlackService(service SlackService) {
+	ae.slackService = service
+}
+
+// SetEmailService sets the service email actions are sent through (optional dependency)
+func (ae *ActionExecutor) SetEmailService(service EmailService) {
+	ae.emailService = service
+}
+
+// SetWebhookConfig sets the default timeout of webhook deliveries and the secrets webhooks
+// are signed with (optional dependency)
+func (ae *ActionExecutor) SetWebhookConfig(cfg *config.WebhookConfig) {
+	if cfg.Timeout > 0 {
+		ae.webhookTimeout = cfg.Timeout
+	}
+	ae.signingSecrets = cfg.SigningSecrets
+}
+
+// SetEgressPolicy sets the policy webhook destinations are checked against (optional dependency)
+func (ae *ActionExecutor) SetEgressPolicy(policy *egress.Policy) {
+	ae.egress = policy
+	ae.httpClient = policy.Client("webhook", 0)
+}
+
+// SetExecutionContext sets the execution ID used when an action runs outside an
+// executor-managed context. Executions started by WorkflowExecutor carry their ID in ctx.
 func (ae *ActionExecutor) SetExecutionContext(executionID uuid.UUID) {
 	ae.executionID = executionID
 }
@@ -85,27 +168,60 @@ func (ae *ActionExecutor) ExecuteAction(
 	switch step.Action.Type {
 	case "allow":
 		result.Reason = "Action allowed by workflow"
-		ae.logger.Infof("Action allowed: %s", step.ID)
+		loggerFor(ctx, ae.logger).Infof("Action allowed: %s", step.ID)
 
 	case "block":
 		result.Reason = step.Action.Reason
 		if result.Reason == "" {
 			result.Reason = "Action blocked by workflow"
 		}
-		ae.logger.Infof("Action blocked: %s - %s", step.ID, result.Reason)
+		loggerFor(ctx, ae.logger).Infof("Action blocked: %s - %s", step.ID, result.Reason)
 
 	case "execute":
 		// Execute additional actions defined in the Execute field
 		if len(step.Execute) > 0 {
 			executeResults, err := ae.executeActions(ctx, step.Execute, execContext)
+			result.Data["execute_results"] = executeResults
 			if err != nil {
 				result.Success = false
 				result.Error = err.Error()
 				return result, err
 			}
-			result.Data["execute_results"] = executeResults
 		}
 
+	case "response":
+		statusCode := step.Action.StatusCode
+		if statusCode == 0 {
+			statusCode = 200
+		}
+		if statusCode < 100 || statusCode > 599 {
+			return nil, fmt.Errorf("invalid response status code: %d", statusCode)
+		}
+		result.Reason = "Response set by workflow"
+		result.Data["status_code"] = statusCode
+		result.Data["body"] = renderTemplateMap(step.Action.Data, execContext, pathResolverFromContext(ctx))
+		loggerFor(ctx, ae.logger).Infof("Response action: %s - status %d", step.ID, statusCode)
+
+	case "ai_classify":
+		label, err := ae.executeClassify(ctx, step, execContext)
+		if err != nil {
+			result.Success = false
+			result.Error = err.Error()
+			return result, err
+		}
+		result.Reason = "Classified as " + label
+		result.Data["label"] = label
+
+	case "ai_extract":
+		extracted, err := ae.executeExtract(ctx, step, execContext)
+		if err != nil {
+			result.Success = false
+			result.Error = err.Error()
+			return result, err
+		}
+		result.Reason = "Extracted structured data"
+		result.Data["extracted"] = extracted
+
 	default:
 		return nil, fmt.Errorf("unsupported action type: %s", step.Action.Type)
 	}
@@ -113,6 +229,93 @@ func (ae *ActionExecutor) ExecuteAction(
 	return result, nil
 }
 
+// executeClassify classifies the step's rendered input and stores the label in the context
+func (ae *ActionExecutor) executeClassify(
+	ctx context.Context,
+	step *models.Step,
+	execContext map[string]interface{},
+) (string, error) {
+	classify := step.Action.Classify
+	if classify == nil || len(classify.Labels) == 0 {
+		return "", fmt.Errorf("ai_classify action requires classify configuration with labels")
+	}
+	if ae.aiService == nil {
+		return "", fmt.Errorf("AI service not configured")
+	}
+
+	input := strings.TrimSpace(renderTemplateString(classify.Input, execContext, pathResolverFromContext(ctx)))
+	if input == "" {
+		return "", fmt.Errorf("ai_classify input %q is empty", classify.Input)
+	}
+
+	label, err := ae.aiService.Classify(ctx, input, classify.Labels, classify.Instructions)
+	if err != nil {
+		return "", fmt.Errorf("classification failed: %w", err)
+	}
+
+	// The AI service is trusted to answer with a label, but a bad answer must not reach the context
+	valid := false
+	for _, candidate := range classify.Labels {
+		if label == candidate {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", fmt.Errorf("classification returned %q, which is not one of the labels", label)
+	}
+
+	outputVar := classify.OutputVar
+	if outputVar == "" {
+		outputVar = step.ID
+	}
+	execContext[outputVar] = label
+
+	loggerFor(ctx, ae.logger).Infof("Classified step %s input as %s", step.ID, label)
+	return label, nil
+}
+
+// executeExtract extracts an object matching the step's schema from its rendered input and
+// stores it in the context
+func (ae *ActionExecutor) executeExtract(
+	ctx context.Context,
+	step *models.Step,
+	execContext map[string]interface{},
+) (map[string]interface{}, error) {
+	extract := step.Action.Extract
+	if extract == nil || len(extract.Schema) == 0 {
+		return nil, fmt.Errorf("ai_extract action requires extract configuration with a schema")
+	}
+	if ae.aiService == nil {
+		return nil, fmt.Errorf("AI service not configured")
+	}
+
+	input := strings.TrimSpace(renderTemplateString(extract.Input, execContext, pathResolverFromContext(ctx)))
+	if input == "" {
+		return nil, fmt.Errorf("ai_extract input %q is empty", extract.Input)
+	}
+
+	extracted, err := ae.aiService.Extract(ctx, input, extract)
+	if err != nil {
+		return nil, fmt.Errorf("extraction failed: %w", err)
+	}
+
+	outputVar := extract.OutputVar
+	if outputVar == "" {
+		outputVar = step.ID
+	}
+	execContext[outputVar] = extracted
+
+	loggerFor(ctx, ae.logger).Infof("Extracted %d fields for step %s", len(extracted), step.ID)
+	return extracted, nil
+}
+
+// isAIAction reports whether an action runs on the AI service. AI actions store their result in
+// the context, so unlike other actions their step continues to its next step
+func isAIAction(action *models.Action) bool {
+	return action != nil && strings.HasPrefix(action.Type, "ai_")
+}
+
 // executeActions executes a list of execute actions
 func (ae *ActionExecutor) executeActions(
 	ctx context.Context,
@@ -124,19 +327,50 @@ func (ae *ActionExecutor) executeActions(
 	for _, action := range actions {
 		result, err := ae.executeSingleAction(ctx, action, execContext)
 		if err != nil {
-			ae.logger.Errorf("Failed to execute action %s: %v", action.Type, err)
-			result = map[string]interface{}{
-				"type":    action.Type,
-				"success": false,
-				"error":   err.Error(),
+			loggerFor(ctx, ae.logger).Errorf("Failed to execute action %s: %v", action.Type, err)
+			// Keep what the action reported, such as a webhook's final response
+			if result == nil {
+				result = map[string]interface{}{"type": action.Type}
 			}
+			result["success"] = false
+			result["error"] = err.Error()
 		}
 		results = append(results, result)
+
+		// A delivery that never arrived fails the step, so its retry and on_error apply
+		if err != nil && deliveryActions[action.Type] && !action.ContinueOnError {
+			return results, &actionError{actionType: action.Type, result: result, err: err}
+		}
 	}
 
 	return results, nil
 }
 
+// deliveryActions are the execute actions whose failures, after their own retries, fail the
+// step. Failures of other actions are recorded in the step's results
+var deliveryActions = map[string]bool{
+	"webhook":      true,
+	"http_request": true,
+	"slack":        true,
+	"email":        true,
+}
+
+// actionError is returned by executeActions when a delivery action fails, wrapping what the
+// action reported, such as a webhook's final response
+type actionError struct {
+	actionType string
+	result     map[string]interface{}
+	err        error
+}
+
+func (e *actionError) Error() string {
+	return fmt.Sprintf("%s action failed: %v", e.actionType, e.err)
+}
+
+func (e *actionError) Unwrap() error {
+	return e.err
+}
+
 // executeSingleAction executes a single execute action
 func (ae *ActionExecutor) executeSingleAction(
 	ctx context.Context,
@@ -147,6 +381,12 @@ func (ae *ActionExecutor) executeSingleAction(
 	case "notify":
 		return ae.executeNotify(ctx, action, execContext)
 
+	case "email":
+		return ae.executeEmail(ctx, action, execContext)
+
+	case "slack":
+		return ae.executeSlack(ctx, action, execContext)
+
 	case "webhook", "http_request":
 		return ae.executeWebhook(ctx, action, execContext)
 
@@ -173,7 +413,8 @@ func (ae *ActionExecutor) executeNotify(
 	action models.ExecuteAction,
 	execContext map[string]interface{},
 ) (map[string]interface{}, error) {
-	ae.logger.Infof("Executing notify action to: %v", action.Recipients)
+	message := renderTemplateString(action.Message, execContext, pathResolverFromContext(ctx))
+	loggerFor(ctx, ae.logger).Infof("Executing notify action to: %v", action.Recipients)
 
 	// In a real implementation, this would integrate with:
 	// - Email service (SendGrid, AWS SES, etc.)
@@ -185,17 +426,169 @@ func (ae *ActionExecutor) executeNotify(
 		"type":       "notify",
 		"success":    true,
 		"recipients": action.Recipients,
-		"message":    action.Message,
+		"message":    message,
 		"sent_at":    time.Now().Unix(),
 	}
 
 	// Placeholder: Log the notification
-	ae.logger.Infof("Notification sent to %v: %s", action.Recipients, action.Message)
+	loggerFor(ctx, ae.logger).Infof("Notification sent to %v: %s", action.Recipients, message)
 
 	return result, nil
 }
 
-// executeWebhook calls an external webhook
+// executeEmail sends an email, rendering its recipients, subject and bodies from the context
+func (ae *ActionExecutor) executeEmail(
+	ctx context.Context,
+	action models.ExecuteAction,
+	execContext map[string]interface{},
+) (map[string]interface{}, error) {
+	if ae.emailService == nil {
+		return nil, fmt.Errorf("email service not configured")
+	}
+
+	paths := pathResolverFromContext(ctx)
+	email := models.EmailMessage{
+		To:      renderRecipients(action.To, execContext, paths),
+		CC:      renderRecipients(action.CC, execContext, paths),
+		BCC:     renderRecipients(action.BCC, execContext, paths),
+		Subject: renderTemplateString(action.Subject, execContext, paths),
+		Text:    renderTemplateString(action.Message, execContext, paths),
+		HTML:    renderTemplateHTML(action.HTML, execContext, paths),
+	}
+
+	result := map[string]interface{}{
+		"type":    "email",
+		"success": false,
+		"to":      email.To,
+		"cc":      email.CC,
+		"bcc":     email.BCC,
+		"subject": email.Subject,
+	}
+
+	loggerFor(ctx, ae.logger).Infof("Sending email to %v", email.To)
+	messageID, err := ae.emailService.SendEmail(ctx, email)
+	if err != nil {
+		return result, err
+	}
+
+	result["success"] = true
+	result["message_id"] = messageID
+	result["sent_at"] = time.Now().Unix()
+	return result, nil
+}
+
+// renderRecipients renders recipient fields; a token resolving to a list adds each of its
+// entries, and unresolved tokens add nothing
+func renderRecipients(values []string, execContext map[string]interface{}, paths *PathResolver) []string {
+	var recipients []string
+	for _, value := range values {
+		switch rendered := renderString(value, execContext, paths).(type) {
+		case []interface{}:
+			for _, item := range rendered {
+				if item != nil {
+					recipients = append(recipients, formatTemplateValue(item))
+				}
+			}
+		case nil:
+		default:
+			if recipient := formatTemplateValue(rendered); recipient != "" {
+				recipients = append(recipients, recipient)
+			}
+		}
+	}
+	return recipients
+}
+
+// executeSlack posts a message to Slack, rendering its text and blocks from the context and
+// posting it again per the action's retry configuration after transient failures
+func (ae *ActionExecutor) executeSlack(
+	ctx context.Context,
+	action models.ExecuteAction,
+	execContext map[string]interface{},
+) (map[string]interface{}, error) {
+	if ae.slackService == nil {
+		return nil, fmt.Errorf("slack service not configured")
+	}
+
+	paths := pathResolverFromContext(ctx)
+	message := slack.Message{
+		Channel:  renderTemplateString(action.Channel, execContext, paths),
+		Text:     renderTemplateString(action.Message, execContext, paths),
+		ThreadTS: renderTemplateString(action.ThreadTS, execContext, paths),
+	}
+	if action.Blocks != nil {
+		message.Blocks = renderTemplate(action.Blocks, execContext, paths).([]interface{})
+	}
+
+	timeout := ae.webhookTimeout
+	if action.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(action.Timeout); err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid slack timeout: %s", action.Timeout)
+		}
+	}
+
+	maxAttempts := 1
+	if action.Retry != nil && action.Retry.MaxAttempts > 1 {
+		maxAttempts = action.Retry.MaxAttempts
+	}
+
+	organizationID := organizationIDFromContext(ctx)
+	result := map[string]interface{}{
+		"type":      "slack",
+		"success":   false,
+		"message":   message.Text,
+		"thread_ts": message.ThreadTS,
+	}
+
+	for attempt := 1; ; attempt++ {
+		loggerFor(ctx, ae.logger).Infof("Posting Slack message to %q (attempt %d/%d)", message.Channel, attempt, maxAttempts)
+
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		posted, err := ae.slackService.PostSlackMessage(attemptCtx, organizationID, message)
+		cancel()
+
+		result["attempts"] = attempt
+		result["posted_at"] = time.Now().Unix()
+		if err == nil {
+			// Webhooks don't report where the message went, so keep the requested channel
+			result["success"] = true
+			result["channel"] = message.Channel
+			if posted.Channel != "" {
+				result["channel"] = posted.Channel
+			}
+			result["ts"] = posted.TS
+			loggerFor(ctx, ae.logger).Infof("Slack message posted to %v (ts %s)", result["channel"], posted.TS)
+			return result, nil
+		}
+
+		var slackErr *slack.Error
+		if errors.As(err, &slackErr) && slackErr.Code != "" {
+			result["error_code"] = slackErr.Code
+		}
+		if !slack.IsRetryable(err) || attempt >= maxAttempts || ctx.Err() != nil {
+			return result, err
+		}
+
+		// Wait at least as long as a rate-limited response asked
+		backoff := webhookBackoff(attempt, action.Retry)
+		if slackErr != nil && slackErr.RetryAfter > backoff {
+			backoff = slackErr.RetryAfter
+		}
+		loggerFor(ctx, ae.logger).Warnf("Posting Slack message failed, retrying in %v: %v", backoff, err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return result, fmt.Errorf("slack message aborted during retry backoff: %w", err)
+		}
+	}
+}
+
+// executeWebhook delivers a request to an external webhook, redelivering it per the action's
+// retry configuration. The result describes the final attempt, including when it failed
 func (ae *ActionExecutor) executeWebhook(
 	ctx context.Context,
 	action models.ExecuteAction,
@@ -205,70 +598,202 @@ func (ae *ActionExecutor) executeWebhook(
 		return nil, fmt.Errorf("webhook URL is required")
 	}
 
-	method := action.Method
+	method := strings.ToUpper(action.Method)
 	if method == "" {
-		method = "POST"
+		method = http.MethodPost
 	}
+	if !allowedWebhookMethods[method] {
+		return nil, fmt.Errorf("webhook method %s is not allowed", action.Method)
+	}
+
+	paths := pathResolverFromContext(ctx)
 
 	// Prepare request body
 	var bodyBytes []byte
 	var err error
 
 	if action.Body != nil {
-		// Interpolate context variables into body
-		interpolatedBody := ae.interpolateVariables(action.Body, execContext)
-		bodyBytes, err = json.Marshal(interpolatedBody)
+		// Render context variables into body
+		renderedBody := renderTemplateMap(action.Body, execContext, paths)
+		bodyBytes, err = json.Marshal(renderedBody)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
 	}
 
+	headers := make(map[string]string, len(action.Headers))
+	for key, value := range action.Headers {
+		headers[key] = renderTemplateString(value, execContext, paths)
+	}
+
+	var signature string
+	if action.SigningSecret != "" {
+		secret, ok := ae.signingSecrets[action.SigningSecret]
+		if !ok {
+			return nil, fmt.Errorf("webhook signing secret %q is not configured", action.SigningSecret)
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(bodyBytes)
+		signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	timeout := ae.webhookTimeout
+	if action.Timeout != "" {
+		if timeout, err = time.ParseDuration(action.Timeout); err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid webhook timeout: %s", action.Timeout)
+		}
+	}
+
+	// A blocked destination stays blocked, so it is reported before any attempt. Other failures
+	// to check it, such as DNS errors, are left to the delivery attempts
+	if err := ae.egress.CheckURL(ctx, "webhook", action.URL); errors.Is(err, egress.ErrBlockedDestination) {
+		return nil, err
+	}
+
+	maxAttempts := 1
+	if action.Retry != nil && action.Retry.MaxAttempts > 1 {
+		maxAttempts = action.Retry.MaxAttempts
+	}
+
+	// Every attempt carries the same request ID, so receivers can tell redeliveries apart
+	deliveryID := uuid.New().String()
+
+	for attempt := 1; ; attempt++ {
+		request := webhookRequest{
+			method:     method,
+			url:        action.URL,
+			body:       bodyBytes,
+			headers:    headers,
+			signature:  signature,
+			deliveryID: deliveryID,
+			timeout:    timeout,
+		}
+
+		loggerFor(ctx, ae.logger).Infof("Calling webhook: %s %s (attempt %d/%d)", method, action.URL, attempt, maxAttempts)
+		result, retryable, err := ae.deliverWebhook(ctx, request)
+		result["attempts"] = attempt
+		result["delivery_id"] = deliveryID
+
+		if err == nil {
+			loggerFor(ctx, ae.logger).Infof("Webhook call successful: %s - Status %d", action.URL, result["status_code"])
+			return result, nil
+		}
+		if !retryable || attempt >= maxAttempts || ctx.Err() != nil {
+			return result, err
+		}
+
+		backoff := webhookBackoff(attempt, action.Retry)
+		loggerFor(ctx, ae.logger).Warnf("Webhook delivery to %s failed, retrying in %v: %v", action.URL, backoff, err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return result, fmt.Errorf("webhook delivery aborted during retry backoff: %w", err)
+		}
+	}
+}
+
+// webhookRequest is a single webhook delivery attempt
+type webhookRequest struct {
+	method     string
+	url        string
+	body       []byte
+	headers    map[string]string
+	signature  string
+	deliveryID string
+	timeout    time.Duration
+}
+
+// deliverWebhook makes one delivery attempt, reporting whether a failure is worth retrying:
+// network errors, timeouts and 408, 429 or 5xx responses are
+func (ae *ActionExecutor) deliverWebhook(ctx context.Context, request webhookRequest) (map[string]interface{}, bool, error) {
+	result := map[string]interface{}{
+		"type":      "webhook",
+		"success":   false,
+		"url":       request.url,
+		"method":    request.method,
+		"called_at": time.Now().Unix(),
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, request.timeout)
+	defer cancel()
+
+	var body io.Reader
+	if request.body != nil {
+		body = bytes.NewReader(request.body)
+	}
+
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, method, action.URL, bytes.NewReader(bodyBytes))
+	req, err := http.NewRequestWithContext(attemptCtx, request.method, request.url, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return result, false, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "IntelligentWorkflows/1.0")
-	req.Header.Set("X-Request-ID", uuid.New().String())
+	req.Header.Set("X-Request-ID", request.deliveryID)
+	if id := correlation.FromContext(ctx); id != "" {
+		req.Header.Set(correlation.Header, id)
+	}
 
-	for key, value := range action.Headers {
+	for key, value := range request.headers {
 		req.Header.Set(key, value)
 	}
 
-	// Execute request
-	ae.logger.Infof("Calling webhook: %s %s", method, action.URL)
+	// Set last, so a configured header can't replace the signature
+	if request.signature != "" {
+		req.Header.Set(webhookSignatureHeader, request.signature)
+	}
+
+	start := time.Now()
 	resp, err := ae.httpClient.Do(req)
+	result["duration_ms"] = time.Since(start).Milliseconds()
 	if err != nil {
-		return nil, fmt.Errorf("webhook request failed: %w", err)
+		// Redirects and DNS answers can lead to blocked destinations too
+		retryable := !errors.Is(err, egress.ErrBlockedDestination)
+		return result, retryable, fmt.Errorf("webhook request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Read response
-	respBody, err := io.ReadAll(resp.Body)
+	// Read response, capturing up to maxWebhookResponseBytes of it
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBytes))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return result, true, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	result := map[string]interface{}{
-		"type":        "webhook",
-		"success":     resp.StatusCode >= 200 && resp.StatusCode < 300,
-		"url":         action.URL,
-		"method":      method,
-		"status_code": resp.StatusCode,
-		"response":    string(respBody),
-		"called_at":   time.Now().Unix(),
+	result["success"] = resp.StatusCode >= 200 && resp.StatusCode < 300
+	result["status_code"] = resp.StatusCode
+	result["response"] = string(respBody)
+
+	var decoded interface{}
+	if json.Unmarshal(respBody, &decoded) == nil {
+		result["response_json"] = decoded
 	}
 
 	if resp.StatusCode >= 400 {
-		return result, fmt.Errorf("webhook returned error status: %d", resp.StatusCode)
+		retryable := resp.StatusCode >= 500 ||
+			resp.StatusCode == http.StatusRequestTimeout ||
+			resp.StatusCode == http.StatusTooManyRequests
+		return result, retryable, fmt.Errorf("webhook returned error status: %d", resp.StatusCode)
 	}
 
-	ae.logger.Infof("Webhook call successful: %s - Status %d", action.URL, resp.StatusCode)
+	return result, false, nil
+}
 
-	return result, nil
+// webhookBackoff returns the delay before redelivering a webhook after attempt failed: the
+// initial delay, growing linearly or doubling with each attempt, capped and spread by jitter
+func webhookBackoff(attempt int, retry *models.WebhookRetryConfig) time.Duration {
+	initial := time.Second
+	if delay, err := time.ParseDuration(retry.InitialDelay); err == nil && delay > 0 {
+		initial = delay
+	}
+	maxBackoff, _ := time.ParseDuration(retry.MaxBackoff)
+
+	backoff, _ := jitteredRetryBackoff(attempt, retry.Backoff, initial, maxBackoff, retry.Jitter)
+	return backoff
 }
 
 // executeCreateRecord creates a record (placeholder for microservice integration)
@@ -277,7 +802,7 @@ func (ae *ActionExecutor) executeCreateRecord(
 	action models.ExecuteAction,
 	execContext map[string]interface{},
 ) (map[string]interface{}, error) {
-	ae.logger.Infof("Executing create_record: entity=%s", action.Entity)
+	loggerFor(ctx, ae.logger).Infof("Executing create_record: entity=%s", action.Entity)
 
 	// In a real implementation, this would call the appropriate microservice
 	// to create a record (e.g., create order, product, customer, etc.)
@@ -289,11 +814,11 @@ func (ae *ActionExecutor) executeCreateRecord(
 		"success":    true,
 		"entity":     action.Entity,
 		"record_id":  recordID,
-		"data":       action.Data,
+		"data":       renderTemplateMap(action.Data, execContext, pathResolverFromContext(ctx)),
 		"created_at": time.Now().Unix(),
 	}
 
-	ae.logger.Infof("Record created: %s/%s", action.Entity, recordID)
+	loggerFor(ctx, ae.logger).Infof("Record created: %s/%s", action.Entity, recordID)
 
 	return result, nil
 }
@@ -304,7 +829,9 @@ func (ae *ActionExecutor) executeUpdateRecord(
 	action models.ExecuteAction,
 	execContext map[string]interface{},
 ) (map[string]interface{}, error) {
-	ae.logger.Infof("Executing update_record: entity=%s, id=%s", action.Entity, action.EntityID)
+	paths := pathResolverFromContext(ctx)
+	entityID := renderTemplateString(action.EntityID, execContext, paths)
+	loggerFor(ctx, ae.logger).Infof("Executing update_record: entity=%s, id=%s", action.Entity, entityID)
 
 	// In a real implementation, this would call the appropriate microservice
 	// to update a record
@@ -313,12 +840,12 @@ func (ae *ActionExecutor) executeUpdateRecord(
 		"type":       "update_record",
 		"success":    true,
 		"entity":     action.Entity,
-		"entity_id":  action.EntityID,
-		"data":       action.Data,
+		"entity_id":  entityID,
+		"data":       renderTemplateMap(action.Data, execContext, paths),
 		"updated_at": time.Now().Unix(),
 	}
 
-	ae.logger.Infof("Record updated: %s/%s", action.Entity, action.EntityID)
+	loggerFor(ctx, ae.logger).Infof("Record updated: %s/%s", action.Entity, entityID)
 
 	return result, nil
 }
@@ -329,12 +856,12 @@ func (ae *ActionExecutor) executeLog(
 	action models.ExecuteAction,
 	execContext map[string]interface{},
 ) (map[string]interface{}, error) {
-	message := action.Message
+	message := renderTemplateString(action.Message, execContext, pathResolverFromContext(ctx))
 	if message == "" {
 		message = fmt.Sprintf("Log action executed: %v", action.Data)
 	}
 
-	ae.logger.Infof("Workflow log: %s", message)
+	loggerFor(ctx, ae.logger).Infof("Workflow log: %s", message)
 
 	result := map[string]interface{}{
 		"type":      "log",
@@ -346,60 +873,6 @@ func (ae *ActionExecutor) executeLog(
 	return result, nil
 }
 
-// interpolateVariables replaces variables in data with values from context
-// Example: "${order.id}" becomes the actual order ID from context
-func (ae *ActionExecutor) interpolateVariables(
-	data map[string]interface{},
-	context map[string]interface{},
-) map[string]interface{} {
-	result := make(map[string]interface{})
-
-	for key, value := range data {
-		switch v := value.(type) {
-		case string:
-			// Check if it's a variable reference like "${order.id}"
-			if strings.HasPrefix(v, "${") && strings.HasSuffix(v, "}") {
-				varName := strings.TrimSuffix(strings.TrimPrefix(v, "${"), "}")
-				result[key] = ae.getContextValue(varName, context)
-			} else {
-				result[key] = v
-			}
-		case map[string]interface{}:
-			// Recursively interpolate nested maps
-			result[key] = ae.interpolateVariables(v, context)
-		default:
-			result[key] = value
-		}
-	}
-
-	return result
-}
-
-// getContextValue retrieves a value from context using dot notation
-func (ae *ActionExecutor) getContextValue(path string, context map[string]interface{}) interface{} {
-	parts := strings.Split(path, ".")
-	current := context
-
-	for i, part := range parts {
-		val, exists := current[part]
-		if !exists {
-			return nil
-		}
-
-		if i == len(parts)-1 {
-			return val
-		}
-
-		nextMap, ok := val.(map[string]interface{})
-		if !ok {
-			return nil
-		}
-		current = nextMap
-	}
-
-	return nil
-}
-
 // executeCreateApprovalRequest creates an approval request
 func (ae *ActionExecutor) executeCreateApprovalRequest(
 	ctx context.Context,
@@ -410,7 +883,11 @@ func (ae *ActionExecutor) executeCreateApprovalRequest(
 		return nil, fmt.Errorf("approval service not configured")
 	}
 
-	if ae.executionID == uuid.Nil {
+	executionID := executionIDFromContext(ctx)
+	if executionID == uuid.Nil {
+		executionID = ae.executionID
+	}
+	if executionID == uuid.Nil {
 		return nil, fmt.Errorf("execution context not set")
 	}
 
@@ -420,19 +897,8 @@ func (ae *ActionExecutor) executeCreateApprovalRequest(
 		return nil, fmt.Errorf("entity type is required for approval request")
 	}
 
-	// Interpolate entity ID if it contains variables
-	entityID := action.EntityID
-	if entityID != "" && strings.Contains(entityID, "{{") {
-		// Simple template replacement for {{variable}} format
-		entityID = strings.ReplaceAll(entityID, "{{", "${")
-		entityID = strings.ReplaceAll(entityID, "}}", "}")
-		if strings.HasPrefix(entityID, "${") && strings.HasSuffix(entityID, "}") {
-			varName := strings.TrimSuffix(strings.TrimPrefix(entityID, "${"), "}")
-			if val := ae.getContextValue(varName, execContext); val != nil {
-				entityID = fmt.Sprintf("%v", val)
-			}
-		}
-	}
+	// Render entity ID if it contains variables
+	entityID := renderTemplateString(action.EntityID, execContext, pathResolverFromContext(ctx))
 
 	// Extract approver role from data
 	approverRole := "manager" // default
@@ -458,23 +924,56 @@ func (ae *ActionExecutor) executeCreateApprovalRequest(
 			if err == nil {
 				expiresIn = &duration
 			} else {
-				ae.logger.Warnf("Failed to parse expires_in duration %s: %v", expiresInStr, err)
+				loggerFor(ctx, ae.logger).Warnf("Failed to parse expires_in duration %s: %v", expiresInStr, err)
 			}
 		}
 	}
 
+	// Parse escalation chain
+	var escalation models.ApprovalEscalationChain
+	if action.Data != nil && action.Data["escalation"] != nil {
+		raw, err := json.Marshal(action.Data["escalation"])
+		if err == nil {
+			err = json.Unmarshal(raw, &escalation)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid escalation chain: %w", err)
+		}
+	}
+
+	// Parse designated approvers and the quorum of them that must approve
+	var requiredApprovers []uuid.UUID
+	quorum := 0
+	if action.Data != nil {
+		if approvers, ok := action.Data["approvers"].([]interface{}); ok {
+			for _, approver := range approvers {
+				id, err := uuid.Parse(fmt.Sprint(approver))
+				if err != nil {
+					return nil, fmt.Errorf("invalid approver %v: %w", approver, err)
+				}
+				requiredApprovers = append(requiredApprovers, id)
+			}
+		}
+		if q, ok := action.Data["quorum"].(float64); ok {
+			quorum = int(q)
+		}
+	}
+
 	// Create approval request
-	ae.logger.Infof("Creating approval request: entity=%s/%s, approver=%s", entityType, entityID, approverRole)
+	loggerFor(ctx, ae.logger).Infof("Creating approval request: entity=%s/%s, approver=%s", entityType, entityID, approverRole)
 
 	approval, err := ae.approvalService.CreateApprovalRequest(
 		ctx,
-		ae.executionID,
+		executionID,
 		entityType,
 		entityID,
 		nil, // requesterID - can be extracted from context if needed
 		approverRole,
 		reason,
 		expiresIn,
+		escalation,
+		requiredApprovers,
+		quorum,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create approval request: %w", err)
@@ -494,8 +993,14 @@ func (ae *ActionExecutor) executeCreateApprovalRequest(
 	if approval.ExpiresAt != nil {
 		result["expires_at"] = approval.ExpiresAt.Unix()
 	}
+	if len(approval.RequiredApprovers) > 0 {
+		result["quorum"] = approval.Quorum
+	}
+	if approval.NextEscalationAt != nil {
+		result["next_escalation_at"] = approval.NextEscalationAt.Unix()
+	}
 
-	ae.logger.Infof("Approval request created successfully: %s", approval.RequestID)
+	loggerFor(ctx, ae.logger).Infof("Approval request created successfully: %s", approval.RequestID)
 
 	return result, nil
 }
@@ -2,12 +2,56 @@ package engine
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/egress"
+	"github.com/davidmoltin/intelligent-workflows/pkg/llm"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/slack"
+	"github.com/google/uuid"
 )
 
+// Mock ApprovalService that records the execution, escalation chain and approvers each request was made with
+type mockApprovalService struct {
+	executionIDs []uuid.UUID
+	escalations  []models.ApprovalEscalationChain
+	approvers    [][]uuid.UUID
+	quorums      []int
+}
+
+func (m *mockApprovalService) CreateApprovalRequest(
+	ctx context.Context,
+	executionID uuid.UUID,
+	entityType string,
+	entityID string,
+	requesterID *uuid.UUID,
+	approverRole string,
+	reason string,
+	expiresIn *time.Duration,
+	escalation models.ApprovalEscalationChain,
+	requiredApprovers []uuid.UUID,
+	quorum int,
+) (*models.ApprovalRequest, error) {
+	m.executionIDs = append(m.executionIDs, executionID)
+	m.escalations = append(m.escalations, escalation)
+	m.approvers = append(m.approvers, requiredApprovers)
+	m.quorums = append(m.quorums, quorum)
+	return &models.ApprovalRequest{ID: uuid.New(), RequestID: "apr_test", Status: models.ApprovalStatusPending, RequestedAt: time.Now()}, nil
+}
+
 func TestExecuteAction(t *testing.T) {
 	log := logger.NewForTesting()
 	executor := NewActionExecutor(log)
@@ -94,6 +138,193 @@ func TestExecuteAction(t *testing.T) {
 		}
 	})
 
+	t.Run("executes response action", func(t *testing.T) {
+		step := &models.Step{
+			ID:   "respond",
+			Type: "action",
+			Action: &models.Action{
+				Type:       "response",
+				StatusCode: 202,
+				Data: map[string]interface{}{
+					"order_id": "${order.id}",
+					"status":   "accepted",
+				},
+			},
+		}
+		execContext := map[string]interface{}{
+			"order": map[string]interface{}{"id": "ord-1"},
+		}
+
+		result, err := executor.ExecuteAction(ctx, step, execContext)
+		if err != nil {
+			t.Fatalf("ExecuteAction failed: %v", err)
+		}
+
+		if result.Data["status_code"] != 202 {
+			t.Errorf("Expected status code 202, got %v", result.Data["status_code"])
+		}
+		body, ok := result.Data["body"].(map[string]interface{})
+		if !ok || body["order_id"] != "ord-1" || body["status"] != "accepted" {
+			t.Errorf("Expected interpolated body, got %v", result.Data["body"])
+		}
+	})
+
+	t.Run("renders templates in response data", func(t *testing.T) {
+		step := &models.Step{
+			ID:   "respond",
+			Type: "action",
+			Action: &models.Action{
+				Type: "response",
+				Data: map[string]interface{}{
+					"message": "Order {{order.id}} needs review",
+					"total":   "{{order.total}}",
+					"missing": "{{order.missing}}",
+				},
+			},
+		}
+		execContext := map[string]interface{}{
+			"order": map[string]interface{}{"id": "ord-1", "total": 1500.0},
+		}
+
+		result, err := executor.ExecuteAction(withExecutionScope(ctx, uuid.New(), NewEvaluator().WithPathResolver(NewPathResolver())), step, execContext)
+		if err != nil {
+			t.Fatalf("ExecuteAction failed: %v", err)
+		}
+
+		body := result.Data["body"].(map[string]interface{})
+		if body["message"] != "Order ord-1 needs review" {
+			t.Errorf("Expected rendered message, got %v", body["message"])
+		}
+		if body["total"] != 1500.0 {
+			t.Errorf("Expected total to keep its numeric type, got %#v", body["total"])
+		}
+		if body["missing"] != nil {
+			t.Errorf("Expected nil for a missing variable, got %#v", body["missing"])
+		}
+	})
+
+	t.Run("rejects invalid response status code", func(t *testing.T) {
+		step := &models.Step{
+			ID:     "respond",
+			Type:   "action",
+			Action: &models.Action{Type: "response", StatusCode: 42},
+		}
+
+		if _, err := executor.ExecuteAction(ctx, step, map[string]interface{}{}); err == nil {
+			t.Error("Expected error for invalid status code")
+		}
+	})
+
+	t.Run("approval requests use the execution carried by ctx", func(t *testing.T) {
+		approvals := &mockApprovalService{}
+		approvalExecutor := NewActionExecutor(log)
+		approvalExecutor.SetApprovalService(approvals)
+		approvalExecutor.SetExecutionContext(uuid.New()) // ignored when ctx carries an execution
+
+		executionID := uuid.New()
+		step := &models.Step{
+			ID:     "approve",
+			Type:   "action",
+			Action: &models.Action{Type: "execute"},
+			Execute: []models.ExecuteAction{
+				{Type: "create_approval_request", Entity: "order", EntityID: "ord-1"},
+			},
+		}
+
+		if _, err := approvalExecutor.ExecuteAction(withExecutionScope(ctx, executionID, nil), step, map[string]interface{}{}); err != nil {
+			t.Fatalf("ExecuteAction failed: %v", err)
+		}
+
+		if len(approvals.executionIDs) != 1 || approvals.executionIDs[0] != executionID {
+			t.Errorf("Expected approval for execution %s, got %v", executionID, approvals.executionIDs)
+		}
+	})
+
+	t.Run("approval requests pass the escalation chain", func(t *testing.T) {
+		approvals := &mockApprovalService{}
+		approvalExecutor := NewActionExecutor(log)
+		approvalExecutor.SetApprovalService(approvals)
+
+		step := &models.Step{
+			ID:     "approve",
+			Type:   "action",
+			Action: &models.Action{Type: "execute"},
+			Execute: []models.ExecuteAction{
+				{
+					Type:     "create_approval_request",
+					Entity:   "order",
+					EntityID: "ord-1",
+					Data: map[string]interface{}{
+						"escalation": []interface{}{
+							map[string]interface{}{"approver_role": "director", "after": "4h", "extend_by": "2h"},
+							map[string]interface{}{"approver_email": "cfo@example.com", "after": "8h"},
+						},
+					},
+				},
+			},
+		}
+
+		if _, err := approvalExecutor.ExecuteAction(withExecutionScope(ctx, uuid.New(), nil), step, map[string]interface{}{}); err != nil {
+			t.Fatalf("ExecuteAction failed: %v", err)
+		}
+
+		if len(approvals.escalations) != 1 || len(approvals.escalations[0]) != 2 {
+			t.Fatalf("Expected a two-level escalation chain, got %v", approvals.escalations)
+		}
+		first := approvals.escalations[0][0]
+		if first.ApproverRole != "director" || first.After != "4h" || first.ExtendBy != "2h" {
+			t.Errorf("Unexpected first escalation level: %+v", first)
+		}
+		if approvals.escalations[0][1].ApproverEmail != "cfo@example.com" {
+			t.Errorf("Unexpected second escalation level: %+v", approvals.escalations[0][1])
+		}
+
+		step.Execute[0].Data["escalation"] = "director"
+		result, err := approvalExecutor.ExecuteAction(withExecutionScope(ctx, uuid.New(), nil), step, map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("ExecuteAction failed: %v", err)
+		}
+		results := result.Data["execute_results"].([]map[string]interface{})
+		if results[0]["success"] != false || len(approvals.escalations) != 1 {
+			t.Errorf("Expected a malformed escalation chain not to create an approval, got %v", results[0])
+		}
+	})
+
+	t.Run("approval requests pass designated approvers and quorum", func(t *testing.T) {
+		approvals := &mockApprovalService{}
+		approvalExecutor := NewActionExecutor(log)
+		approvalExecutor.SetApprovalService(approvals)
+
+		approvers := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+		step := &models.Step{
+			ID:     "approve",
+			Type:   "action",
+			Action: &models.Action{Type: "execute"},
+			Execute: []models.ExecuteAction{
+				{
+					Type:     "create_approval_request",
+					Entity:   "order",
+					EntityID: "ord-1",
+					Data: map[string]interface{}{
+						"approvers": []interface{}{approvers[0].String(), approvers[1].String(), approvers[2].String()},
+						"quorum":    float64(2),
+					},
+				},
+			},
+		}
+
+		if _, err := approvalExecutor.ExecuteAction(withExecutionScope(ctx, uuid.New(), nil), step, map[string]interface{}{}); err != nil {
+			t.Fatalf("ExecuteAction failed: %v", err)
+		}
+
+		if len(approvals.approvers) != 1 || len(approvals.approvers[0]) != 3 || approvals.approvers[0][1] != approvers[1] {
+			t.Errorf("Expected the three designated approvers, got %v", approvals.approvers)
+		}
+		if approvals.quorums[0] != 2 {
+			t.Errorf("Expected quorum 2, got %d", approvals.quorums[0])
+		}
+	})
+
 	t.Run("handles missing action", func(t *testing.T) {
 		step := &models.Step{
 			ID:     "action1",
@@ -108,3 +339,629 @@ func TestExecuteAction(t *testing.T) {
 		}
 	})
 }
+
+// mockAIService answers every request with a fixed label, extraction or error, recording the input.
+// A classification is streamed as one chunk if the context asks for streaming
+type mockAIService struct {
+	label     string
+	extracted map[string]interface{}
+	err       error
+	usage     *llm.Usage // Reported to the context's usage scope for each classification, if set
+	inputs    []string
+	streamed  bool
+}
+
+func (m *mockAIService) Classify(ctx context.Context, input string, labels []string, instructions string) (string, error) {
+	m.inputs = append(m.inputs, input)
+	if scope := llm.UsageScopeFromContext(ctx); scope != nil && m.usage != nil {
+		scope.OnUsage(m.usage)
+	}
+	if handler := llm.StreamHandlerFromContext(ctx); handler != nil {
+		m.streamed = true
+		if err := handler(&llm.StreamChunk{Delta: m.label}); err != nil {
+			return "", err
+		}
+	}
+	return m.label, m.err
+}
+
+func (m *mockAIService) Extract(ctx context.Context, input string, extract *models.ExtractAction) (map[string]interface{}, error) {
+	m.inputs = append(m.inputs, input)
+	return m.extracted, m.err
+}
+
+func TestExecuteAction_AIClassify(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+
+	classifyStep := func(outputVar string) *models.Step {
+		return &models.Step{
+			ID:   "triage",
+			Type: "action",
+			Action: &models.Action{
+				Type: "ai_classify",
+				Classify: &models.ClassifyAction{
+					Input:     "{{ticket.subject}}: {{ticket.body}}",
+					Labels:    []string{"billing", "bug", "other"},
+					OutputVar: outputVar,
+				},
+			},
+		}
+	}
+	newContext := func() map[string]interface{} {
+		return map[string]interface{}{
+			"ticket": map[string]interface{}{"subject": "Refund", "body": "I was charged twice"},
+		}
+	}
+
+	t.Run("stores the label in the output variable", func(t *testing.T) {
+		classifier := &mockAIService{label: "billing"}
+		executor := NewActionExecutor(log)
+		executor.SetAIService(classifier)
+		execContext := newContext()
+
+		result, err := executor.ExecuteAction(ctx, classifyStep("category"), execContext)
+
+		if err != nil {
+			t.Fatalf("ExecuteAction failed: %v", err)
+		}
+		if result.Data["label"] != "billing" || execContext["category"] != "billing" {
+			t.Errorf("Expected label billing in result and context, got %v and %v", result.Data["label"], execContext["category"])
+		}
+		if len(classifier.inputs) != 1 || classifier.inputs[0] != "Refund: I was charged twice" {
+			t.Errorf("Expected the rendered input, got %v", classifier.inputs)
+		}
+	})
+
+	t.Run("defaults the output variable to the step ID", func(t *testing.T) {
+		executor := NewActionExecutor(log)
+		executor.SetAIService(&mockAIService{label: "bug"})
+		execContext := newContext()
+
+		if _, err := executor.ExecuteAction(ctx, classifyStep(""), execContext); err != nil {
+			t.Fatalf("ExecuteAction failed: %v", err)
+		}
+		if execContext["triage"] != "bug" {
+			t.Errorf("Expected label bug under the step ID, got %v", execContext["triage"])
+		}
+	})
+
+	failures := []struct {
+		name       string
+		classifier AIService
+		errMsg     string
+	}{
+		{"provider error", &mockAIService{err: errors.New("rate_limit error from anthropic")}, "classification failed"},
+		{"label outside the candidates", &mockAIService{label: "shipping"}, "not one of the labels"},
+		{"no classifier", nil, "AI service not configured"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewActionExecutor(log)
+			if tt.classifier != nil {
+				executor.SetAIService(tt.classifier)
+			}
+			execContext := newContext()
+
+			result, err := executor.ExecuteAction(ctx, classifyStep("category"), execContext)
+
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Fatalf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+			if result == nil || result.Success {
+				t.Error("Expected an unsuccessful result")
+			}
+			if _, exists := execContext["category"]; exists {
+				t.Error("Expected no label in the context")
+			}
+		})
+	}
+}
+
+func TestExecuteAction_AIExtract(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+
+	extractStep := func(outputVar string) *models.Step {
+		return &models.Step{
+			ID:   "parse_email",
+			Type: "action",
+			Action: &models.Action{
+				Type: "ai_extract",
+				Extract: &models.ExtractAction{
+					Input: "{{email.body}}",
+					Schema: map[string]interface{}{
+						"type":     "object",
+						"required": []interface{}{"order_id"},
+						"properties": map[string]interface{}{
+							"order_id": map[string]interface{}{"type": "string"},
+						},
+					},
+					OutputVar: outputVar,
+				},
+			},
+		}
+	}
+	newContext := func() map[string]interface{} {
+		return map[string]interface{}{
+			"email": map[string]interface{}{"body": "Where is order ORD-42?"},
+		}
+	}
+
+	t.Run("stores the extracted object in the output variable", func(t *testing.T) {
+		service := &mockAIService{extracted: map[string]interface{}{"order_id": "ORD-42"}}
+		executor := NewActionExecutor(log)
+		executor.SetAIService(service)
+		execContext := newContext()
+
+		result, err := executor.ExecuteAction(ctx, extractStep("order"), execContext)
+
+		if err != nil {
+			t.Fatalf("ExecuteAction failed: %v", err)
+		}
+		order, ok := execContext["order"].(map[string]interface{})
+		if !ok || order["order_id"] != "ORD-42" {
+			t.Errorf("Expected the extracted order in the context, got %v", execContext["order"])
+		}
+		if result.Data["extracted"] == nil {
+			t.Error("Expected the extracted object in the result data")
+		}
+		if len(service.inputs) != 1 || service.inputs[0] != "Where is order ORD-42?" {
+			t.Errorf("Expected the rendered input, got %v", service.inputs)
+		}
+	})
+
+	t.Run("defaults the output variable to the step ID", func(t *testing.T) {
+		executor := NewActionExecutor(log)
+		executor.SetAIService(&mockAIService{extracted: map[string]interface{}{"order_id": "ORD-42"}})
+		execContext := newContext()
+
+		if _, err := executor.ExecuteAction(ctx, extractStep(""), execContext); err != nil {
+			t.Fatalf("ExecuteAction failed: %v", err)
+		}
+		if _, ok := execContext["parse_email"].(map[string]interface{}); !ok {
+			t.Errorf("Expected the extracted object under the step ID, got %v", execContext["parse_email"])
+		}
+	})
+
+	failures := []struct {
+		name    string
+		service AIService
+		errMsg  string
+	}{
+		{"invalid response", &mockAIService{err: errors.New("invalid extraction")}, "extraction failed"},
+		{"no AI service", nil, "AI service not configured"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewActionExecutor(log)
+			if tt.service != nil {
+				executor.SetAIService(tt.service)
+			}
+			execContext := newContext()
+
+			result, err := executor.ExecuteAction(ctx, extractStep("order"), execContext)
+
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Fatalf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+			if result == nil || result.Success {
+				t.Error("Expected an unsuccessful result")
+			}
+			if _, exists := execContext["order"]; exists {
+				t.Error("Expected nothing extracted into the context")
+			}
+		})
+	}
+}
+
+// checkDeliveryError checks that a delivery action failed its step exactly when it did not
+// succeed, with an error wrapping what it reported
+func checkDeliveryError(t *testing.T, result map[string]interface{}, err error) {
+	t.Helper()
+	if result["success"] == true {
+		if err != nil {
+			t.Fatalf("ExecuteAction failed: %v", err)
+		}
+		return
+	}
+	var actionErr *actionError
+	if !errors.As(err, &actionErr) || actionErr.result["error"] != result["error"] {
+		t.Fatalf("Expected the failed delivery to fail the step with its result, got %v", err)
+	}
+}
+
+func TestExecuteAction_Webhook(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+
+	// runWebhook executes a single webhook action and returns its result
+	runWebhook := func(t *testing.T, executor *ActionExecutor, action models.ExecuteAction, execContext map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		step := &models.Step{ID: "deliver", Type: "action", Action: &models.Action{Type: "execute"}, Execute: []models.ExecuteAction{action}}
+		result, err := executor.ExecuteAction(ctx, step, execContext)
+		results, _ := result.Data["execute_results"].([]map[string]interface{})
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 execute result, got %v", result.Data)
+		}
+		checkDeliveryError(t, results[0], err)
+		return results[0]
+	}
+
+	t.Run("redelivers after retryable failures", func(t *testing.T) {
+		var attempts int32
+		var requestIDs []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestIDs = append(requestIDs, r.Header.Get("X-Request-ID"))
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"accepted":true}`))
+		}))
+		defer server.Close()
+
+		result := runWebhook(t, NewActionExecutor(log), models.ExecuteAction{
+			Type:  "webhook",
+			URL:   server.URL,
+			Retry: &models.WebhookRetryConfig{MaxAttempts: 3, InitialDelay: "1ms"},
+		}, map[string]interface{}{})
+
+		if result["success"] != true || result["attempts"] != 3 || result["status_code"] != http.StatusOK {
+			t.Errorf("Expected delivery on the third attempt, got %v", result)
+		}
+		response, _ := result["response_json"].(map[string]interface{})
+		if response["accepted"] != true {
+			t.Errorf("Expected the JSON response to be captured, got %v", result["response_json"])
+		}
+		if requestIDs[0] == "" || requestIDs[0] != requestIDs[1] || requestIDs[1] != requestIDs[2] {
+			t.Errorf("Expected every attempt to carry the same request ID, got %v", requestIDs)
+		}
+	})
+
+	t.Run("stores the final attempt of a failed delivery", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error":"unknown order"}`))
+		}))
+		defer server.Close()
+
+		result := runWebhook(t, NewActionExecutor(log), models.ExecuteAction{
+			Type:  "webhook",
+			URL:   server.URL,
+			Retry: &models.WebhookRetryConfig{MaxAttempts: 3, InitialDelay: "1ms"},
+		}, map[string]interface{}{})
+
+		// A client error would fail the same way again
+		if atomic.LoadInt32(&attempts) != 1 {
+			t.Errorf("Expected 4xx responses not to be retried, got %d attempts", attempts)
+		}
+		if result["success"] != false || result["status_code"] != http.StatusUnprocessableEntity {
+			t.Errorf("Expected the failed status to be stored, got %v", result)
+		}
+		if result["response"] != `{"error":"unknown order"}` {
+			t.Errorf("Expected the response body to be stored, got %v", result["response"])
+		}
+		if !strings.Contains(fmt.Sprint(result["error"]), "422") {
+			t.Errorf("Expected the error to be stored, got %v", result["error"])
+		}
+	})
+
+	t.Run("records failures without failing the step when asked to", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		step := &models.Step{ID: "deliver", Type: "action", Action: &models.Action{Type: "execute"}, Execute: []models.ExecuteAction{
+			{Type: "webhook", URL: server.URL, ContinueOnError: true},
+			{Type: "log", Message: "delivered"},
+		}}
+		result, err := NewActionExecutor(log).ExecuteAction(ctx, step, map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("Expected the step to succeed, got %v", err)
+		}
+		results, _ := result.Data["execute_results"].([]map[string]interface{})
+		if len(results) != 2 || results[0]["success"] != false || results[0]["status_code"] != http.StatusBadGateway {
+			t.Errorf("Expected the failure to be recorded and the next action to run, got %v", results)
+		}
+	})
+
+	t.Run("signs the body and renders headers", func(t *testing.T) {
+		var signature, customer string
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			signature = r.Header.Get("X-Signature")
+			customer = r.Header.Get("X-Customer")
+			body, _ = io.ReadAll(r.Body)
+		}))
+		defer server.Close()
+
+		executor := NewActionExecutor(log)
+		executor.SetWebhookConfig(&config.WebhookConfig{SigningSecrets: map[string]string{"billing": "s3cret"}})
+
+		result := runWebhook(t, executor, models.ExecuteAction{
+			Type:          "webhook",
+			URL:           server.URL,
+			Method:        "put",
+			Headers:       map[string]string{"X-Customer": "{{order.customer_id}}", "X-Signature": "forged"},
+			Body:          map[string]interface{}{"order_id": "{{order.id}}"},
+			SigningSecret: "billing",
+		}, map[string]interface{}{"order": map[string]interface{}{"id": "ord_1", "customer_id": "cus_9"}})
+
+		if result["success"] != true || result["method"] != http.MethodPut {
+			t.Fatalf("Expected a successful PUT, got %v", result)
+		}
+		if customer != "cus_9" {
+			t.Errorf("Expected header rendered from context, got %q", customer)
+		}
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+			t.Errorf("Expected signature %s, got %s", want, signature)
+		}
+	})
+
+	t.Run("rejects unknown signing secrets and disallowed methods", func(t *testing.T) {
+		executor := NewActionExecutor(log)
+		for _, action := range []models.ExecuteAction{
+			{Type: "webhook", URL: "http://127.0.0.1:1/hook", SigningSecret: "missing"},
+			{Type: "webhook", URL: "http://127.0.0.1:1/hook", Method: "TRACE"},
+		} {
+			result := runWebhook(t, executor, action, map[string]interface{}{})
+			if result["success"] != false || result["error"] == nil {
+				t.Errorf("Expected action %+v to fail, got %v", action, result)
+			}
+		}
+	})
+
+	t.Run("times out each attempt", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		start := time.Now()
+		result := runWebhook(t, NewActionExecutor(log), models.ExecuteAction{
+			Type:    "webhook",
+			URL:     server.URL,
+			Timeout: "20ms",
+			Retry:   &models.WebhookRetryConfig{MaxAttempts: 2, InitialDelay: "1ms"},
+		}, map[string]interface{}{})
+
+		if result["success"] != false || result["attempts"] != 2 {
+			t.Errorf("Expected both attempts to time out, got %v", result)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("Expected attempts to be bounded by the timeout, took %v", elapsed)
+		}
+	})
+
+	t.Run("refuses internal destinations", func(t *testing.T) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+		}))
+		defer server.Close()
+
+		policy, err := egress.NewPolicy(&config.EgressConfig{})
+		if err != nil {
+			t.Fatalf("NewPolicy failed: %v", err)
+		}
+		executor := NewActionExecutor(log)
+		executor.SetEgressPolicy(policy)
+
+		result := runWebhook(t, executor, models.ExecuteAction{
+			Type:  "webhook",
+			URL:   server.URL,
+			Retry: &models.WebhookRetryConfig{MaxAttempts: 3, InitialDelay: "1ms"},
+		}, map[string]interface{}{})
+
+		if result["success"] != false || !strings.Contains(fmt.Sprint(result["error"]), "blocked destination") {
+			t.Errorf("Expected the delivery to be blocked, got %v", result)
+		}
+		if got := atomic.LoadInt32(&attempts); got != 0 {
+			t.Errorf("Expected no request to reach the server, got %d", got)
+		}
+	})
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	retry := &models.WebhookRetryConfig{MaxAttempts: 5, InitialDelay: "100ms", MaxBackoff: "350ms"}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 350 * time.Millisecond} {
+		if got := webhookBackoff(attempt, retry); got != want {
+			t.Errorf("Exponential backoff after attempt %d: expected %v, got %v", attempt, want, got)
+		}
+	}
+
+	retry.Backoff = "linear"
+	if got := webhookBackoff(3, retry); got != 300*time.Millisecond {
+		t.Errorf("Linear backoff after attempt 3: expected 300ms, got %v", got)
+	}
+}
+
+// mockSlackService fails posts with errs in turn, then posts to channel C1, recording each post
+type mockSlackService struct {
+	errs          []error
+	messages      []slack.Message
+	organizations []uuid.UUID
+}
+
+func (m *mockSlackService) PostSlackMessage(ctx context.Context, organizationID uuid.UUID, message slack.Message) (*slack.PostResult, error) {
+	m.messages = append(m.messages, message)
+	m.organizations = append(m.organizations, organizationID)
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		return nil, err
+	}
+	return &slack.PostResult{Channel: "C1", TS: "1700000000.000100"}, nil
+}
+
+func TestExecuteAction_Slack(t *testing.T) {
+	log := logger.NewForTesting()
+	organizationID := uuid.New()
+	executionCtx := (&WorkflowExecutor{evaluator: NewEvaluator()}).beginExecutionScope(context.Background(), &models.WorkflowExecution{ID: uuid.New(), OrganizationID: organizationID})
+	execContext := map[string]interface{}{"order": map[string]interface{}{"id": "ord_1", "total": 1500.0}}
+
+	// runSlack executes a single slack action and returns its result
+	runSlack := func(t *testing.T, service *mockSlackService, action models.ExecuteAction) map[string]interface{} {
+		t.Helper()
+		executor := NewActionExecutor(log)
+		executor.SetSlackService(service)
+		step := &models.Step{ID: "notify", Type: "action", Action: &models.Action{Type: "execute"}, Execute: []models.ExecuteAction{action}}
+		result, err := executor.ExecuteAction(executionCtx, step, execContext)
+		results, _ := result.Data["execute_results"].([]map[string]interface{})
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 execute result, got %v", result.Data)
+		}
+		checkDeliveryError(t, results[0], err)
+		return results[0]
+	}
+
+	t.Run("renders the message and captures where it was posted", func(t *testing.T) {
+		service := &mockSlackService{}
+		result := runSlack(t, service, models.ExecuteAction{
+			Type:     "slack",
+			Channel:  "#orders",
+			Message:  "Order {{order.id}} needs review",
+			Blocks:   []interface{}{map[string]interface{}{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": "Total: {{order.total}}"}}},
+			ThreadTS: "1699999999.000001",
+		})
+
+		if result["success"] != true || result["channel"] != "C1" || result["ts"] != "1700000000.000100" {
+			t.Errorf("Expected the posted message's channel and ts, got %v", result)
+		}
+		message := service.messages[0]
+		if message.Channel != "#orders" || message.Text != "Order ord_1 needs review" || message.ThreadTS != "1699999999.000001" {
+			t.Errorf("Unexpected message: %+v", message)
+		}
+		text := message.Blocks[0].(map[string]interface{})["text"].(map[string]interface{})["text"]
+		if text != "Total: 1500" {
+			t.Errorf("Expected blocks rendered from context, got %v", text)
+		}
+		if service.organizations[0] != organizationID {
+			t.Errorf("Expected the execution's organization, got %s", service.organizations[0])
+		}
+	})
+
+	t.Run("posts again after retryable failures", func(t *testing.T) {
+		service := &mockSlackService{errs: []error{
+			&slack.Error{StatusCode: http.StatusTooManyRequests, Code: "ratelimited"},
+			&slack.Error{Err: errors.New("connection reset")},
+		}}
+		result := runSlack(t, service, models.ExecuteAction{
+			Type:    "slack",
+			Message: "hello",
+			Retry:   &models.WebhookRetryConfig{MaxAttempts: 3, InitialDelay: "1ms"},
+		})
+
+		if result["success"] != true || result["attempts"] != 3 {
+			t.Errorf("Expected the message posted on the third attempt, got %v", result)
+		}
+	})
+
+	t.Run("does not retry permanent failures", func(t *testing.T) {
+		service := &mockSlackService{errs: []error{&slack.Error{StatusCode: http.StatusOK, Code: "channel_not_found"}}}
+		result := runSlack(t, service, models.ExecuteAction{
+			Type:    "slack",
+			Channel: "#missing",
+			Message: "hello",
+			Retry:   &models.WebhookRetryConfig{MaxAttempts: 3, InitialDelay: "1ms"},
+		})
+
+		if len(service.messages) != 1 {
+			t.Errorf("Expected a single attempt, got %d", len(service.messages))
+		}
+		if result["success"] != false || result["error_code"] != "channel_not_found" || result["error"] == nil {
+			t.Errorf("Expected the failure to be stored, got %v", result)
+		}
+	})
+
+	t.Run("fails without a slack service", func(t *testing.T) {
+		step := &models.Step{ID: "notify", Type: "action", Action: &models.Action{Type: "execute"}, Execute: []models.ExecuteAction{{Type: "slack", Message: "hello"}}}
+		result, err := NewActionExecutor(log).ExecuteAction(executionCtx, step, execContext)
+		if err == nil || !strings.Contains(err.Error(), "slack service not configured") {
+			t.Errorf("Expected the step to fail without a slack service, got %v", err)
+		}
+		results := result.Data["execute_results"].([]map[string]interface{})
+		if results[0]["success"] != false {
+			t.Errorf("Expected the action to fail, got %v", results[0])
+		}
+	})
+}
+
+// mockEmailService records the emails sent, failing each with err if set
+type mockEmailService struct {
+	emails []models.EmailMessage
+	err    error
+}
+
+func (m *mockEmailService) SendEmail(ctx context.Context, email models.EmailMessage) (string, error) {
+	m.emails = append(m.emails, email)
+	if m.err != nil {
+		return "", m.err
+	}
+	return "<msg-1@example.com>", nil
+}
+
+func TestExecuteAction_Email(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := withExecutionScope(context.Background(), uuid.New(), NewEvaluator().WithPathResolver(NewPathResolver()))
+	execContext := map[string]interface{}{
+		"customer": map[string]interface{}{"email": "ada@example.com", "name": "Ada <b>"},
+		"order":    map[string]interface{}{"id": "ord_1", "watchers": []interface{}{"ops@example.com", "billing@example.com"}},
+	}
+
+	// runEmail executes a single email action and returns its result
+	runEmail := func(t *testing.T, service *mockEmailService, action models.ExecuteAction) map[string]interface{} {
+		t.Helper()
+		executor := NewActionExecutor(log)
+		executor.SetEmailService(service)
+		step := &models.Step{ID: "notify", Type: "action", Action: &models.Action{Type: "execute"}, Execute: []models.ExecuteAction{action}}
+		result, err := executor.ExecuteAction(ctx, step, execContext)
+		results := result.Data["execute_results"].([]map[string]interface{})
+		checkDeliveryError(t, results[0], err)
+		return results[0]
+	}
+
+	t.Run("renders recipients, subject and bodies", func(t *testing.T) {
+		service := &mockEmailService{}
+		result := runEmail(t, service, models.ExecuteAction{
+			Type:    "email",
+			To:      []string{"{{customer.email}}"},
+			CC:      []string{"{{order.watchers}}", "{{order.missing}}"},
+			BCC:     []string{"audit@example.com"},
+			Subject: "Order {{order.id}} shipped",
+			Message: "Hi {{customer.name}}",
+			HTML:    "<p>Hi {{customer.name}}</p>",
+		})
+
+		if result["success"] != true || result["message_id"] != "<msg-1@example.com>" {
+			t.Errorf("Expected the message ID to be captured, got %v", result)
+		}
+		email := service.emails[0]
+		if fmt.Sprint(email.To) != "[ada@example.com]" || fmt.Sprint(email.CC) != "[ops@example.com billing@example.com]" {
+			t.Errorf("Expected recipients resolved from context, got to %v and cc %v", email.To, email.CC)
+		}
+		if email.Subject != "Order ord_1 shipped" || email.Text != "Hi Ada <b>" {
+			t.Errorf("Unexpected subject or text: %+v", email)
+		}
+		if email.HTML != "<p>Hi Ada &lt;b&gt;</p>" {
+			t.Errorf("Expected context values escaped in HTML, got %q", email.HTML)
+		}
+	})
+
+	t.Run("stores send failures", func(t *testing.T) {
+		service := &mockEmailService{err: errors.New("invalid to address")}
+		result := runEmail(t, service, models.ExecuteAction{Type: "email", To: []string{"{{customer.missing}}"}, Subject: "Hello", Message: "hi"})
+
+		if result["success"] != false || result["error"] != "invalid to address" {
+			t.Errorf("Expected the failure to be stored, got %v", result)
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrConcurrencyLimitReached is returned when an execution cannot get a slot of its workflow's
+// concurrency limit, at once under the reject policy or before the queue timeout under queue
+var ErrConcurrencyLimitReached = errors.New("workflow concurrency limit reached")
+
+const (
+	// concurrencyKeyPrefix namespaces the lease sets of workflow concurrency limits
+	concurrencyKeyPrefix = "concurrency:"
+
+	// DefaultConcurrencyLeaseTTL is how long a slot outlives its holder unless configured
+	DefaultConcurrencyLeaseTTL = 30 * time.Second
+
+	// DefaultConcurrencyPollInterval is how often a queued execution checks for a slot unless configured
+	DefaultConcurrencyPollInterval = time.Second
+)
+
+// acquireLeaseScript takes a slot of the lease set in KEYS[1] for the holder ARGV[3] when fewer
+// than ARGV[1] unexpired leases are held, and returns 1 if it did. Each lease is scored by the
+// Redis time it expires at, ARGV[2] milliseconds from now, so leases of crashed processes are
+// dropped without trusting the clocks of API instances
+var acquireLeaseScript = redis.NewScript(`
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+if redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], now + tonumber(ARGV[2]), ARGV[3])
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1
+`)
+
+// renewLeaseScript extends the holder ARGV[2]'s lease in KEYS[1] to ARGV[1] milliseconds from
+// now, and returns 0 if the lease has already expired
+var renewLeaseScript = redis.NewScript(`
+if not redis.call("ZSCORE", KEYS[1], ARGV[2]) then
+	return 0
+end
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+redis.call("ZADD", KEYS[1], "XX", now + tonumber(ARGV[1]), ARGV[2])
+redis.call("PEXPIRE", KEYS[1], ARGV[1])
+return 1
+`)
+
+// ConcurrencyLimiter enforces workflow concurrency limits across API instances. Every limited
+// key is a Redis sorted set of leases, one per running execution, which expire unless renewed so
+// a crashed process does not hold its slots forever
+type ConcurrencyLimiter struct {
+	redis        *redis.Client
+	logger       *logger.Logger
+	leaseTTL     time.Duration
+	queueTimeout time.Duration
+	pollInterval time.Duration
+}
+
+// NewConcurrencyLimiter creates a concurrency limiter holding its leases in redisClient
+func NewConcurrencyLimiter(redisClient *redis.Client, cfg *config.ConcurrencyConfig, log *logger.Logger) *ConcurrencyLimiter {
+	limiter := &ConcurrencyLimiter{
+		redis:        redisClient,
+		logger:       log,
+		leaseTTL:     cfg.LeaseTTL,
+		queueTimeout: cfg.QueueTimeout,
+		pollInterval: cfg.PollInterval,
+	}
+	if limiter.leaseTTL <= 0 {
+		limiter.leaseTTL = DefaultConcurrencyLeaseTTL
+	}
+	if limiter.pollInterval <= 0 {
+		limiter.pollInterval = DefaultConcurrencyPollInterval
+	}
+	return limiter
+}
+
+// concurrencyLease is a held slot of a concurrency limit. It is renewed in the background until
+// released
+type concurrencyLease struct {
+	limiter *ConcurrencyLimiter
+	key     string
+	holder  string
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+// Acquire takes a slot of the workflow's concurrency limit for an execution, queueing or
+// rejecting it as the workflow's policy says when the limit is reached. It returns a nil lease
+// when the workflow has no limit, or its key renders empty for this payload
+func (l *ConcurrencyLimiter) Acquire(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	workflow *models.Workflow,
+	triggerPayload map[string]interface{},
+	executionID uuid.UUID,
+) (*concurrencyLease, error) {
+	limit := workflow.Definition.Concurrency
+	if limit == nil {
+		return nil, nil
+	}
+
+	key := fmt.Sprintf("%s%s:%s", concurrencyKeyPrefix, organizationID, workflow.ID)
+	if limit.Key != "" {
+		value := renderTemplateString(limit.Key, triggerPayload, NewPathResolver())
+		if value == "" {
+			l.logger.Warnf("Concurrency key %s is empty for the trigger payload of workflow %s, running without a limit", limit.Key, workflow.ID)
+			return nil, nil
+		}
+		key += ":" + value
+	}
+
+	maxRunning := limit.Max
+	if maxRunning <= 0 {
+		maxRunning = 1
+	}
+
+	// Under the reject policy the first attempt is the only one
+	var deadline time.Time
+	if limit.Policy != models.ConcurrencyPolicyReject {
+		deadline = time.Now().Add(l.queueTimeoutFor(workflow))
+	}
+
+	holder := executionID.String()
+	for {
+		acquired, err := acquireLeaseScript.Run(ctx, l.redis, []string{key}, maxRunning, l.leaseTTL.Milliseconds(), holder).Int()
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire concurrency slot: %w", err)
+		}
+		if acquired == 1 {
+			lease := &concurrencyLease{
+				limiter: l,
+				key:     key,
+				holder:  holder,
+				stopCh:  make(chan struct{}),
+				doneCh:  make(chan struct{}),
+			}
+			go lease.renew()
+			return lease, nil
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return nil, fmt.Errorf("%w: %d running for %s", ErrConcurrencyLimitReached, maxRunning, key)
+		}
+
+		timer := time.NewTimer(min(wait, l.pollInterval))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// queueTimeoutFor returns how long an execution of the workflow waits for a slot, preferring
+// the workflow's own queue_timeout
+func (l *ConcurrencyLimiter) queueTimeoutFor(workflow *models.Workflow) time.Duration {
+	if value := workflow.Definition.Concurrency.QueueTimeout; value != "" {
+		if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+			return timeout
+		}
+		l.logger.Warnf("Invalid concurrency queue_timeout %q on workflow %s, using %s", value, workflow.ID, l.queueTimeout)
+	}
+	return l.queueTimeout
+}
+
+// renew extends the lease every third of its TTL until it is released
+func (c *concurrencyLease) renew() {
+	defer close(c.doneCh)
+
+	ticker := time.NewTicker(c.limiter.leaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), c.limiter.leaseTTL/3)
+			renewed, err := renewLeaseScript.Run(ctx, c.limiter.redis, []string{c.key}, c.limiter.leaseTTL.Milliseconds(), c.holder).Int()
+			cancel()
+			switch {
+			case err != nil:
+				c.limiter.logger.Warnf("Failed to renew concurrency slot %s of execution %s: %v", c.key, c.holder, err)
+			case renewed == 0:
+				c.limiter.logger.Warnf("Concurrency slot %s of execution %s expired before it was renewed", c.key, c.holder)
+			}
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// Release stops renewing the lease and frees its slot. If Redis is unreachable the slot is freed
+// when the lease expires
+func (c *concurrencyLease) Release() {
+	close(c.stopCh)
+	<-c.doneCh
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.limiter.leaseTTL)
+	defer cancel()
+	if err := c.limiter.redis.ZRem(ctx, c.key, c.holder).Err(); err != nil {
+		c.limiter.logger.Warnf("Failed to release concurrency slot %s of execution %s: %v", c.key, c.holder, err)
+	}
+}
@@ -0,0 +1,428 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedis starts an in-memory Redis that runs the limiters' Lua scripts, and a client for it
+func newTestRedis(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client, server
+}
+
+// heldLeases returns how many unexpired concurrency leases are held in total
+func heldLeases(t *testing.T, client *redis.Client) int {
+	t.Helper()
+	ctx := context.Background()
+	now, err := client.Time(ctx).Result()
+	if err != nil {
+		t.Fatalf("Failed to get redis time: %v", err)
+	}
+	keys, err := client.Keys(ctx, concurrencyKeyPrefix+"*").Result()
+	if err != nil {
+		t.Fatalf("Failed to list lease sets: %v", err)
+	}
+
+	held := 0
+	for _, key := range keys {
+		count, err := client.ZCount(ctx, key, fmt.Sprintf("(%d", now.UnixMilli()), "+inf").Result()
+		if err != nil {
+			t.Fatalf("Failed to count leases in %s: %v", key, err)
+		}
+		held += int(count)
+	}
+	return held
+}
+
+func limitedWorkflow(concurrency *models.ConcurrencyDefinition) *models.Workflow {
+	return &models.Workflow{
+		ID:   uuid.New(),
+		Name: "Fulfil order",
+		Definition: models.WorkflowDefinition{
+			Concurrency: concurrency,
+			Steps: []models.Step{
+				{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}},
+			},
+		},
+	}
+}
+
+func TestConcurrencyLimiter_Acquire(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+	organizationID := uuid.New()
+	cfg := &config.ConcurrencyConfig{LeaseTTL: 30 * time.Second, QueueTimeout: time.Minute, PollInterval: 5 * time.Millisecond}
+	order := func(id string) map[string]interface{} {
+		return map[string]interface{}{"order": map[string]interface{}{"id": id}}
+	}
+
+	t.Run("limits executions per key", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		limiter := NewConcurrencyLimiter(client, cfg, log)
+		workflow := limitedWorkflow(&models.ConcurrencyDefinition{Key: "{{order.id}}", Policy: models.ConcurrencyPolicyReject})
+
+		first, err := limiter.Acquire(ctx, organizationID, workflow, order("ord_1"), uuid.New())
+		if err != nil || first == nil {
+			t.Fatalf("Expected a slot for the first execution, got %v", err)
+		}
+
+		if _, err := limiter.Acquire(ctx, organizationID, workflow, order("ord_1"), uuid.New()); !errors.Is(err, ErrConcurrencyLimitReached) {
+			t.Errorf("Expected ErrConcurrencyLimitReached for the same order, got %v", err)
+		}
+
+		other, err := limiter.Acquire(ctx, organizationID, workflow, order("ord_2"), uuid.New())
+		if err != nil || other == nil {
+			t.Errorf("Expected a slot for another order, got %v", err)
+		}
+		other.Release()
+
+		first.Release()
+		again, err := limiter.Acquire(ctx, organizationID, workflow, order("ord_1"), uuid.New())
+		if err != nil || again == nil {
+			t.Errorf("Expected a slot once the first execution released it, got %v", err)
+		}
+		again.Release()
+	})
+
+	t.Run("allows up to max executions", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		limiter := NewConcurrencyLimiter(client, cfg, log)
+		workflow := limitedWorkflow(&models.ConcurrencyDefinition{Max: 2, Policy: models.ConcurrencyPolicyReject})
+
+		for i := 0; i < 2; i++ {
+			if _, err := limiter.Acquire(ctx, organizationID, workflow, nil, uuid.New()); err != nil {
+				t.Fatalf("Expected slot %d, got %v", i+1, err)
+			}
+		}
+		if _, err := limiter.Acquire(ctx, organizationID, workflow, nil, uuid.New()); !errors.Is(err, ErrConcurrencyLimitReached) {
+			t.Errorf("Expected ErrConcurrencyLimitReached for a third execution, got %v", err)
+		}
+		if heldLeases(t, client) != 2 {
+			t.Errorf("Expected 2 leases, got %d", heldLeases(t, client))
+		}
+	})
+
+	t.Run("queued execution waits for a slot", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		limiter := NewConcurrencyLimiter(client, cfg, log)
+		workflow := limitedWorkflow(&models.ConcurrencyDefinition{Key: "{{order.id}}"})
+
+		first, err := limiter.Acquire(ctx, organizationID, workflow, order("ord_1"), uuid.New())
+		if err != nil {
+			t.Fatalf("Expected a slot for the first execution, got %v", err)
+		}
+		time.AfterFunc(30*time.Millisecond, first.Release)
+
+		queued, err := limiter.Acquire(ctx, organizationID, workflow, order("ord_1"), uuid.New())
+		if err != nil || queued == nil {
+			t.Fatalf("Expected the queued execution to get the released slot, got %v", err)
+		}
+		queued.Release()
+	})
+
+	t.Run("queued execution gives up after the queue timeout", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		limiter := NewConcurrencyLimiter(client, cfg, log)
+		workflow := limitedWorkflow(&models.ConcurrencyDefinition{QueueTimeout: "20ms"})
+
+		if _, err := limiter.Acquire(ctx, organizationID, workflow, nil, uuid.New()); err != nil {
+			t.Fatalf("Expected a slot for the first execution, got %v", err)
+		}
+
+		start := time.Now()
+		if _, err := limiter.Acquire(ctx, organizationID, workflow, nil, uuid.New()); !errors.Is(err, ErrConcurrencyLimitReached) {
+			t.Errorf("Expected ErrConcurrencyLimitReached, got %v", err)
+		}
+		if waited := time.Since(start); waited < 20*time.Millisecond {
+			t.Errorf("Expected the execution to wait for the queue timeout, waited %s", waited)
+		}
+	})
+
+	t.Run("slots of crashed processes expire", func(t *testing.T) {
+		client, server := newTestRedis(t)
+		limiter := NewConcurrencyLimiter(client, cfg, log)
+		workflow := limitedWorkflow(&models.ConcurrencyDefinition{Policy: models.ConcurrencyPolicyReject})
+
+		// Never released, as if the process holding it died
+		if _, err := limiter.Acquire(ctx, organizationID, workflow, nil, uuid.New()); err != nil {
+			t.Fatalf("Expected a slot for the first execution, got %v", err)
+		}
+
+		server.SetTime(time.Now().Add(cfg.LeaseTTL + time.Second))
+		if _, err := limiter.Acquire(ctx, organizationID, workflow, nil, uuid.New()); err != nil {
+			t.Errorf("Expected the expired slot to be free, got %v", err)
+		}
+	})
+
+	t.Run("held slots are renewed past their ttl", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		shortCfg := &config.ConcurrencyConfig{LeaseTTL: 60 * time.Millisecond, QueueTimeout: time.Minute, PollInterval: 5 * time.Millisecond}
+		limiter := NewConcurrencyLimiter(client, shortCfg, log)
+		workflow := limitedWorkflow(&models.ConcurrencyDefinition{Policy: models.ConcurrencyPolicyReject})
+
+		lease, err := limiter.Acquire(ctx, organizationID, workflow, nil, uuid.New())
+		if err != nil {
+			t.Fatalf("Expected a slot for the first execution, got %v", err)
+		}
+
+		time.Sleep(3 * shortCfg.LeaseTTL)
+		if _, err := limiter.Acquire(ctx, organizationID, workflow, nil, uuid.New()); !errors.Is(err, ErrConcurrencyLimitReached) {
+			t.Errorf("Expected the renewed slot to still be held, got %v", err)
+		}
+
+		lease.Release()
+		if renewed, err := renewLeaseScript.Run(ctx, client, []string{lease.key}, shortCfg.LeaseTTL.Milliseconds(), lease.holder).Int(); err != nil || renewed != 0 {
+			t.Errorf("Expected a released slot not to be renewed, got %d, %v", renewed, err)
+		}
+	})
+
+	t.Run("no limit without a concurrency definition or key value", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		limiter := NewConcurrencyLimiter(client, cfg, log)
+
+		lease, err := limiter.Acquire(ctx, organizationID, limitedWorkflow(nil), order("ord_1"), uuid.New())
+		if err != nil || lease != nil {
+			t.Errorf("Expected no lease for an unlimited workflow, got %v, %v", lease, err)
+		}
+
+		keyed := limitedWorkflow(&models.ConcurrencyDefinition{Key: "{{customer.id}}"})
+		lease, err = limiter.Acquire(ctx, organizationID, keyed, order("ord_1"), uuid.New())
+		if err != nil || lease != nil {
+			t.Errorf("Expected no lease when the key is missing from the payload, got %v, %v", lease, err)
+		}
+		if heldLeases(t, client) != 0 {
+			t.Errorf("Expected no leases, got %d", heldLeases(t, client))
+		}
+	})
+}
+
+func TestExecuteWithOptions_ConcurrencyLimit(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+	organizationID := uuid.New()
+	cfg := &config.ConcurrencyConfig{LeaseTTL: 30 * time.Second, QueueTimeout: time.Minute, PollInterval: 5 * time.Millisecond}
+	payload := map[string]interface{}{"order": map[string]interface{}{"id": "ord_1"}}
+
+	t.Run("releases the slot when the execution completes or pauses", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		executor := NewWorkflowExecutor(client, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+		executor.SetConcurrencyLimiter(NewConcurrencyLimiter(client, cfg, log))
+
+		workflow := limitedWorkflow(&models.ConcurrencyDefinition{Key: "{{order.id}}", Policy: models.ConcurrencyPolicyReject})
+		for i := 0; i < 2; i++ {
+			execution, err := executor.Execute(ctx, organizationID, workflow, "order.created", payload)
+			if err != nil || execution.Status != models.ExecutionStatusCompleted {
+				t.Fatalf("Expected run %d to complete, got %v", i+1, err)
+			}
+		}
+
+		workflow.Definition.Steps = []models.Step{
+			{ID: "wait", Type: "wait", Wait: &models.WaitConfig{Event: "order.paid", Timeout: "1h"}},
+		}
+		execution, err := executor.Execute(ctx, organizationID, workflow, "order.created", payload)
+		if err != nil || execution.Status != models.ExecutionStatusWaiting {
+			t.Fatalf("Expected the execution to pause, got %v", err)
+		}
+		if heldLeases(t, client) != 0 {
+			t.Errorf("Expected every slot to be released, %d held", heldLeases(t, client))
+		}
+	})
+
+	t.Run("rejects the execution while the slot is held", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		limiter := NewConcurrencyLimiter(client, cfg, log)
+		created := 0
+		repo := &mockExecutionRepo{
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				created++
+				return nil
+			},
+		}
+		executor := NewWorkflowExecutor(client, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+		executor.SetConcurrencyLimiter(limiter)
+
+		workflow := limitedWorkflow(&models.ConcurrencyDefinition{Key: "{{order.id}}", Policy: models.ConcurrencyPolicyReject})
+		running, err := limiter.Acquire(ctx, organizationID, workflow, payload, uuid.New())
+		if err != nil {
+			t.Fatalf("Expected a slot for the running execution, got %v", err)
+		}
+		defer running.Release()
+
+		execution, err := executor.Execute(ctx, organizationID, workflow, "order.created", payload)
+		if !errors.Is(err, ErrConcurrencyLimitReached) || execution != nil {
+			t.Errorf("Expected ErrConcurrencyLimitReached without an execution, got %v, %v", execution, err)
+		}
+		if created != 0 {
+			t.Errorf("Expected no execution to be recorded, got %d", created)
+		}
+
+		// Dry runs have no side effects, so they are not limited
+		if _, err := executor.ExecuteWithOptions(ctx, organizationID, workflow, "order.created", payload, ExecutionOptions{DryRun: true}); err != nil {
+			t.Errorf("Expected the dry run to ignore the limit, got %v", err)
+		}
+	})
+
+	t.Run("runs without the limit when redis is unavailable", func(t *testing.T) {
+		client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+		executor := NewWorkflowExecutor(client, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+		executor.SetConcurrencyLimiter(NewConcurrencyLimiter(client, cfg, log))
+
+		workflow := limitedWorkflow(&models.ConcurrencyDefinition{Key: "{{order.id}}"})
+		if execution, err := executor.Execute(ctx, organizationID, workflow, "order.created", payload); err != nil || execution == nil {
+			t.Errorf("Expected the execution to run, got %v", err)
+		}
+	})
+}
+
+func TestResume_ConcurrencyLimit(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+	organizationID := uuid.New()
+	cfg := &config.ConcurrencyConfig{LeaseTTL: 30 * time.Second, QueueTimeout: time.Minute, PollInterval: 5 * time.Millisecond}
+	payload := models.JSONB{"order": map[string]interface{}{"id": "ord_1"}}
+
+	// newResumeExecutor returns an executor resuming execution of workflow, recording the steps it
+	// runs and the statuses the execution is saved with
+	newResumeExecutor := func(client *redis.Client, workflow *models.Workflow, execution *models.WorkflowExecution) (*WorkflowExecutor, *[]string, *[]models.ExecutionStatus) {
+		var ranSteps []string
+		var saved []models.ExecutionStatus
+		repo := &mockExecutionRepo{
+			getExecutionByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowExecution, error) {
+				return execution, nil
+			},
+			updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
+				saved = append(saved, execution.Status)
+				return nil
+			},
+			createStepExecutionFunc: func(ctx context.Context, step *models.StepExecution) error {
+				ranSteps = append(ranSteps, step.StepID)
+				return nil
+			},
+		}
+		workflowRepo := &mockWorkflowRepo{
+			getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error) {
+				return workflow, nil
+			},
+		}
+		executor := NewWorkflowExecutor(client, repo, workflowRepo, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+		executor.SetConcurrencyLimiter(NewConcurrencyLimiter(client, cfg, log))
+		return executor, &ranSteps, &saved
+	}
+
+	// holdSlot takes the workflow's only slot for another execution
+	holdSlot := func(t *testing.T, client *redis.Client, workflow *models.Workflow) *concurrencyLease {
+		t.Helper()
+		lease, err := NewConcurrencyLimiter(client, cfg, log).Acquire(ctx, organizationID, workflow, payload, uuid.New())
+		if err != nil || lease == nil {
+			t.Fatalf("Expected a slot for the running execution, got %v", err)
+		}
+		return lease
+	}
+
+	t.Run("event resume keeps waiting while the slot is held", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		workflow := limitedWorkflow(&models.ConcurrencyDefinition{Key: "{{order.id}}", Policy: models.ConcurrencyPolicyReject})
+		workflow.OrganizationID = organizationID
+		workflow.Definition.Steps = []models.Step{
+			{ID: "wait", Type: "wait", Wait: &models.WaitConfig{Event: "order.paid", Timeout: "1h"}},
+			{ID: "ship", Type: "execute", Execute: []models.ExecuteAction{{Type: "log", Message: "shipping"}}},
+		}
+		execution := &models.WorkflowExecution{
+			ID:             uuid.New(),
+			OrganizationID: organizationID,
+			WorkflowID:     workflow.ID,
+			TriggerPayload: payload,
+			Status:         models.ExecutionStatusWaiting,
+			CurrentStepID:  stringPtr("wait"),
+			WaitState:      &models.WaitState{Event: "order.paid", WaitingSince: time.Now()},
+		}
+		executor, ranSteps, saved := newResumeExecutor(client, workflow, execution)
+
+		running := holdSlot(t, client, workflow)
+		if _, err := executor.ResumeExecution(ctx, execution.ID, workflow, "order.paid", nil); !errors.Is(err, ErrConcurrencyLimitReached) {
+			t.Fatalf("Expected ErrConcurrencyLimitReached, got %v", err)
+		}
+		if execution.Status != models.ExecutionStatusWaiting || execution.WaitState == nil || len(*saved) != 0 || len(*ranSteps) != 0 {
+			t.Errorf("Expected the execution to keep waiting untouched, got status %s, saved %v, ran %v", execution.Status, *saved, *ranSteps)
+		}
+
+		running.Release()
+		if _, err := executor.ResumeExecution(ctx, execution.ID, workflow, "order.paid", nil); err != nil {
+			t.Fatalf("Expected the execution to resume once the slot was free, got %v", err)
+		}
+		if len(*ranSteps) != 1 || (*ranSteps)[0] != "ship" {
+			t.Errorf("Expected the step after the wait to run, got %v", *ranSteps)
+		}
+		if heldLeases(t, client) != 0 {
+			t.Errorf("Expected the resumed execution's slot to be released, %d held", heldLeases(t, client))
+		}
+	})
+
+	t.Run("delay resume goes back to waiting while the slot is held", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		workflow := limitedWorkflow(&models.ConcurrencyDefinition{Key: "{{order.id}}", Policy: models.ConcurrencyPolicyReject})
+		workflow.Definition.Steps = []models.Step{
+			{ID: "cool_off", Type: "delay", Delay: &models.DelayStep{Duration: "10m"}, Next: "ship"},
+			{ID: "ship", Type: "execute", Execute: []models.ExecuteAction{{Type: "log", Message: "shipping"}}},
+		}
+		resumeAt := time.Now().Add(-time.Second)
+		execution := &models.WorkflowExecution{
+			ID:             uuid.New(),
+			OrganizationID: organizationID,
+			WorkflowID:     workflow.ID,
+			TriggerPayload: payload,
+			Status:         models.ExecutionStatusRunning,
+			CurrentStepID:  stringPtr("cool_off"),
+			WaitState:      &models.WaitState{ResumeAt: &resumeAt},
+		}
+		executor, ranSteps, saved := newResumeExecutor(client, workflow, execution)
+
+		running := holdSlot(t, client, workflow)
+		defer running.Release()
+		if err := executor.ResumeDelayedExecution(ctx, execution); !errors.Is(err, ErrConcurrencyLimitReached) {
+			t.Fatalf("Expected ErrConcurrencyLimitReached, got %v", err)
+		}
+		if len(*saved) != 1 || (*saved)[0] != models.ExecutionStatusWaiting || len(*ranSteps) != 0 {
+			t.Errorf("Expected the execution to be saved as waiting without running steps, got saved %v, ran %v", *saved, *ranSteps)
+		}
+		if execution.CurrentStepID == nil || execution.WaitState == nil || execution.WaitState.ResumeAt == nil {
+			t.Error("Expected the execution to stay at its delay step so it is claimed again")
+		}
+	})
+
+	t.Run("paused resume returns the error while the slot is held", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		workflow := limitedWorkflow(&models.ConcurrencyDefinition{Key: "{{order.id}}", Policy: models.ConcurrencyPolicyReject})
+		execution := &models.WorkflowExecution{
+			ID:             uuid.New(),
+			OrganizationID: organizationID,
+			WorkflowID:     workflow.ID,
+			TriggerPayload: payload,
+			Status:         models.ExecutionStatusRunning,
+		}
+		executor, ranSteps, _ := newResumeExecutor(client, workflow, execution)
+
+		running := holdSlot(t, client, workflow)
+		defer running.Release()
+		if err := executor.ResumePausedExecution(ctx, execution); !errors.Is(err, ErrConcurrencyLimitReached) {
+			t.Fatalf("Expected ErrConcurrencyLimitReached, got %v", err)
+		}
+		if len(*ranSteps) != 0 || execution.Status == models.ExecutionStatusFailed {
+			t.Errorf("Expected the execution not to run or fail, got status %s, ran %v", execution.Status, *ranSteps)
+		}
+	})
+}
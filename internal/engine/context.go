@@ -1,17 +1,28 @@
 package engine
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/circuitbreaker"
 	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/correlation"
+	"github.com/davidmoltin/intelligent-workflows/pkg/egress"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/metrics"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
@@ -22,18 +33,104 @@ type ContextBuilder struct {
 	logger     *logger.Logger
 	config     *config.ContextEnrichmentConfig
 	httpClient *http.Client
+	metrics    *metrics.Metrics
+	tokens     TokenProvider
+	evaluator  *Evaluator // evaluates computed field expressions
+	breaker    *circuitbreaker.Breaker
+	egress     *egress.Policy // Destinations enrichment requests may reach; nil allows any
 }
 
-// NewContextBuilder creates a new context builder
-func NewContextBuilder(redisClient *redis.Client, log *logger.Logger, cfg *config.ContextEnrichmentConfig) *ContextBuilder {
-	return &ContextBuilder{
-		redis:  redisClient,
-		logger: log,
-		config: cfg,
+// errCacheMiss is returned by getFromCache when no entry exists for the resource
+var errCacheMiss = errors.New("cache miss")
+
+// Suffixes of the keys kept next to a resource's cache entry
+const (
+	staleKeySuffix  = ":stale"  // last good value, kept for StaleTTL to serve when a fetch fails
+	failedKeySuffix = ":failed" // marker that backs off fetching for NegativeCacheTTL after a failure
+)
+
+// ErrEnrichmentUnauthorized is returned (wrapped) when a microservice rejects an enrichment
+// request's credentials with 401 or 403
+var ErrEnrichmentUnauthorized = errors.New("context enrichment request unauthorized")
+
+// statusError is returned by makeHTTPRequest when the microservice answers with an error status
+type statusError struct {
+	statusCode int
+	body       string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("microservice returned error status %d: %s", e.statusCode, e.body)
+}
+
+// isEnrichmentFailure reports whether a failed enrichment request shows the microservice is
+// degraded: transport errors, timeouts, 5xx and 429 responses. Rejected requests and calls
+// canceled by the caller are not the microservice's fault
+func isEnrichmentFailure(err error) bool {
+	if errors.Is(err, ErrEnrichmentUnauthorized) || errors.Is(err, egress.ErrBlockedDestination) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.statusCode >= http.StatusInternalServerError || statusErr.statusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// NewEnrichmentCircuitBreaker creates a breaker for the context enrichment microservice
+func NewEnrichmentCircuitBreaker(cfg *config.CircuitBreakerConfig, log *logger.Logger, m *metrics.Metrics) *circuitbreaker.Breaker {
+	return circuitbreaker.New("context_enrichment", cfg, isEnrichmentFailure, log, m)
+}
+
+// TokenProvider returns the bearer token for an enrichment request. It is called for every
+// request so tokens can be rotated; an empty token sends no Authorization header
+type TokenProvider func(ctx context.Context) (string, error)
+
+// EnvTokenProvider reads the bearer token from an environment variable on every request
+func EnvTokenProvider(name string) TokenProvider {
+	return func(ctx context.Context) (string, error) {
+		return os.Getenv(name), nil
+	}
+}
+
+// NewContextBuilder creates a new context builder. Metrics are optional and may be nil
+func NewContextBuilder(redisClient *redis.Client, log *logger.Logger, cfg *config.ContextEnrichmentConfig, m *metrics.Metrics) *ContextBuilder {
+	cb := &ContextBuilder{
+		redis:     redisClient,
+		logger:    log,
+		config:    cfg,
+		metrics:   m,
+		evaluator: NewEvaluator(),
 		httpClient: &http.Client{
 			Timeout: cfg.Timeout,
 		},
 	}
+	if cfg.AuthTokenEnv != "" {
+		cb.tokens = EnvTokenProvider(cfg.AuthTokenEnv)
+	}
+	return cb
+}
+
+// SetTokenProvider sets the source of bearer tokens for enrichment requests, replacing any
+// configured AuthTokenEnv
+func (cb *ContextBuilder) SetTokenProvider(provider TokenProvider) {
+	cb.tokens = provider
+}
+
+// SetCircuitBreaker sets the breaker enrichment requests are made through, so they fail fast
+// while the microservice is degraded. Create it with NewEnrichmentCircuitBreaker
+func (cb *ContextBuilder) SetCircuitBreaker(breaker *circuitbreaker.Breaker) {
+	cb.breaker = breaker
+}
+
+// SetEgressPolicy sets the policy enrichment request destinations are checked against. The
+// configured BaseURL's host is allowed, since the operator chose it
+func (cb *ContextBuilder) SetEgressPolicy(policy *egress.Policy) {
+	if baseURL, err := url.Parse(cb.config.BaseURL); err == nil && baseURL.Hostname() != "" {
+		policy = policy.WithAllowedHosts(baseURL.Hostname())
+	}
+	cb.egress = policy
+	cb.httpClient = policy.Client("enrichment", cb.config.Timeout)
 }
 
 // BuildContext builds the execution context from trigger payload and context definition
@@ -52,21 +149,7 @@ func (cb *ContextBuilder) BuildContext(
 	}
 
 	// Load additional context data as specified
-	if len(contextDef.Load) > 0 {
-		for _, resource := range contextDef.Load {
-			cb.logger.Infof("Loading context resource: %s for organization: %s", resource, organizationID)
-
-			data, err := cb.loadResource(ctx, organizationID, resource, execContext)
-			if err != nil {
-				cb.logger.Errorf("Failed to load resource %s: %v", resource, err)
-				// Continue loading other resources even if one fails
-				continue
-			}
-
-			// Merge loaded data into context
-			cb.mergeIntoContext(execContext, resource, data)
-		}
-	}
+	cb.loadResources(ctx, organizationID, contextDef.Load, execContext, "Loading")
 
 	return execContext, nil
 }
@@ -79,64 +162,223 @@ func (cb *ContextBuilder) BuildContextFromExisting(
 	contextDef models.ContextDefinition,
 ) error {
 	// Load additional context data as specified, refreshing stale data
-	if len(contextDef.Load) > 0 {
-		for _, resource := range contextDef.Load {
-			cb.logger.Infof("Reloading context resource: %s for organization: %s", resource, organizationID)
-
-			data, err := cb.loadResource(ctx, organizationID, resource, existingContext)
-			if err != nil {
-				cb.logger.Errorf("Failed to reload resource %s: %v", resource, err)
-				// Continue loading other resources even if one fails
-				continue
-			}
+	cb.loadResources(ctx, organizationID, contextDef.Load, existingContext, "Reloading")
+
+	return nil
+}
 
-			// Merge reloaded data into context
-			cb.mergeIntoContext(existingContext, resource, data)
+// loadResources loads resources concurrently, at most MaxConcurrentEnrichment at a time, and
+// merges them into execContext in list order. Every load sees the context as it was before
+// loading started; a failed resource is logged and skipped so the others still load.
+func (cb *ContextBuilder) loadResources(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	resources []string,
+	execContext map[string]interface{},
+	verb string,
+) {
+	if len(resources) == 0 {
+		return
+	}
+
+	limit := cb.config.MaxConcurrentEnrichment
+	if limit <= 0 {
+		limit = 1
+	}
+
+	for _, resource := range resources {
+		loggerFor(ctx, cb.logger).Infof("%s context resource: %s for organization: %s", verb, resource, organizationID)
+	}
+
+	// Loads only read the context, so they share one snapshot instead of the map being merged into
+	snapshot := copyContext(execContext)
+	results := make([]map[string]interface{}, len(resources))
+	stale := make([]bool, len(resources))
+	errs := make([]error, len(resources))
+	slots := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	for i, resource := range resources {
+		wg.Add(1)
+		go func(index int, resource string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[index], stale[index], errs[index] = cb.loadResource(ctx, organizationID, resource, snapshot)
+		}(i, resource)
+	}
+
+	wg.Wait()
+
+	var staleResources []string
+	for i, resource := range resources {
+		if errs[i] != nil {
+			loggerFor(ctx, cb.logger).Errorf("Failed to load resource %s: %v", resource, errs[i])
+			// Continue loading other resources even if one fails
+			continue
+		}
+
+		// Merge loaded data into context
+		cb.mergeIntoContext(execContext, resource, results[i])
+		if stale[i] {
+			staleResources = append(staleResources, resource)
 		}
 	}
 
-	return nil
+	markStaleResources(execContext, staleResources)
+}
+
+// markStaleResources records under _meta.stale_resources which resources were served from a
+// stale copy, clearing the note when every resource loaded fresh
+func markStaleResources(execContext map[string]interface{}, resources []string) {
+	meta, ok := execContext["_meta"].(map[string]interface{})
+	if !ok {
+		if len(resources) == 0 {
+			return
+		}
+		meta = make(map[string]interface{})
+		execContext["_meta"] = meta
+	}
+
+	if len(resources) == 0 {
+		delete(meta, "stale_resources")
+		return
+	}
+	meta["stale_resources"] = resources
 }
 
-// loadResource loads a specific resource (e.g., order.details, customer.history)
+// loadResource loads a specific resource (e.g., order.details, customer.history). The bool
+// reports that a fetch failed and the last good value was served in place of fresh data
 func (cb *ContextBuilder) loadResource(
 	ctx context.Context,
 	organizationID uuid.UUID,
 	resource string,
 	currentContext map[string]interface{},
-) (map[string]interface{}, error) {
+) (map[string]interface{}, bool, error) {
 	// Try to load from cache first
 	cached, err := cb.getFromCache(ctx, organizationID, resource, currentContext)
 	if err == nil && cached != nil {
-		cb.logger.Debugf("Context cache hit for resource: %s (org: %s)", resource, organizationID)
-		return cached, nil
+		loggerFor(ctx, cb.logger).Debugf("Context cache hit for resource: %s (org: %s)", resource, organizationID)
+		cb.recordCacheRequest(resource, "hit")
+		return cached, false, nil
 	}
 
-	cb.logger.Debugf("Context cache miss for resource: %s (org: %s)", resource, organizationID)
+	loggerFor(ctx, cb.logger).Debugf("Context cache miss for resource: %s (org: %s)", resource, organizationID)
+	if err != nil && !errors.Is(err, errCacheMiss) {
+		cb.recordCacheRequest(resource, "error")
+	} else {
+		cb.recordCacheRequest(resource, "miss")
+	}
 
 	// Check if context enrichment is enabled
 	if !cb.config.Enabled {
-		cb.logger.Debugf("Context enrichment is disabled, returning empty data for resource: %s", resource)
-		return map[string]interface{}{}, nil
+		loggerFor(ctx, cb.logger).Debugf("Context enrichment is disabled, returning empty data for resource: %s", resource)
+		return map[string]interface{}{}, false, nil
+	}
+
+	// Resources without an identifier can't be fetched, so they have no stale copy or failure marker either
+	cacheKey, _ := cb.buildCacheKey(organizationID, resource, currentContext)
+
+	// Back off after a recent failure instead of calling the microservice again
+	if cb.recentlyFailed(ctx, cacheKey) {
+		loggerFor(ctx, cb.logger).Debugf("Skipping fetch of resource %s after a recent failure (org: %s)", resource, organizationID)
+		data, stale := cb.staleOrEmpty(ctx, cacheKey)
+		return data, stale, nil
 	}
 
 	// Load from microservice
+	fetchStart := time.Now()
 	data, err := cb.fetchFromMicroservice(ctx, organizationID, resource, currentContext)
+	if cb.metrics != nil {
+		cb.metrics.ContextFetchDuration.WithLabelValues(resource).Observe(time.Since(fetchStart).Seconds())
+	}
 	if err != nil {
-		cb.logger.Errorf("Failed to fetch resource %s from microservice: %v", resource, err)
-		// Return empty data on error to allow workflow to continue
-		return map[string]interface{}{}, nil
+		if errors.Is(err, ErrEnrichmentUnauthorized) {
+			loggerFor(ctx, cb.logger).Errorf("Authentication failed fetching resource %s from microservice: %v", resource, err)
+			cb.recordFetchError(resource, "unauthorized")
+		} else if errors.Is(err, egress.ErrBlockedDestination) {
+			loggerFor(ctx, cb.logger).Errorf("Refused to fetch resource %s from microservice: %v", resource, err)
+			cb.recordFetchError(resource, "blocked")
+		} else if errors.Is(err, circuitbreaker.ErrOpen) {
+			loggerFor(ctx, cb.logger).Warnf("Skipping fetch of resource %s while the microservice is degraded: %v", resource, err)
+			cb.recordFetchError(resource, "circuit_open")
+		} else {
+			loggerFor(ctx, cb.logger).Errorf("Failed to fetch resource %s from microservice: %v", resource, err)
+			cb.recordFetchError(resource, "error")
+		}
+		cb.markFailed(ctx, cacheKey)
+		// Return stale or empty data on error to allow workflow to continue
+		data, stale := cb.staleOrEmpty(ctx, cacheKey)
+		return data, stale, nil
 	}
 
 	// Cache the successful response
 	if len(data) > 0 {
 		if err := cb.setInCache(ctx, organizationID, resource, currentContext, data, cb.config.CacheTTL); err != nil {
-			cb.logger.Warnf("Failed to cache resource %s: %v", resource, err)
+			loggerFor(ctx, cb.logger).Warnf("Failed to cache resource %s: %v", resource, err)
 			// Continue even if caching fails
 		}
+		if cb.config.StaleWhileRevalidate && cacheKey != "" {
+			if err := cb.writeCache(ctx, cacheKey+staleKeySuffix, data, cb.config.StaleTTL); err != nil {
+				loggerFor(ctx, cb.logger).Warnf("Failed to keep stale copy of resource %s: %v", resource, err)
+			}
+		}
 	}
 
-	return data, nil
+	return data, false, nil
+}
+
+// recentlyFailed reports whether a fetch for the cache key failed within NegativeCacheTTL
+func (cb *ContextBuilder) recentlyFailed(ctx context.Context, cacheKey string) bool {
+	if cb.config.NegativeCacheTTL <= 0 || cacheKey == "" {
+		return false
+	}
+
+	exists, err := cb.redis.Exists(ctx, cacheKey+failedKeySuffix).Result()
+	return err == nil && exists > 0
+}
+
+// markFailed records a failed fetch so the next loads back off for NegativeCacheTTL
+func (cb *ContextBuilder) markFailed(ctx context.Context, cacheKey string) {
+	if cb.config.NegativeCacheTTL <= 0 || cacheKey == "" {
+		return
+	}
+
+	if err := cb.redis.Set(ctx, cacheKey+failedKeySuffix, "1", cb.config.NegativeCacheTTL).Err(); err != nil {
+		loggerFor(ctx, cb.logger).Warnf("Failed to record fetch failure for %s: %v", cacheKey, err)
+	}
+}
+
+// staleOrEmpty returns the last good value for the cache key when stale-while-revalidate is
+// enabled and one is kept, reporting whether it did; otherwise it returns empty data
+func (cb *ContextBuilder) staleOrEmpty(ctx context.Context, cacheKey string) (map[string]interface{}, bool) {
+	if !cb.config.StaleWhileRevalidate || cacheKey == "" {
+		return map[string]interface{}{}, false
+	}
+
+	data, err := cb.readCache(ctx, cacheKey+staleKeySuffix)
+	if err != nil {
+		return map[string]interface{}{}, false
+	}
+
+	loggerFor(ctx, cb.logger).Warnf("Serving stale data for %s after a failed fetch", cacheKey)
+	return data, true
+}
+
+// recordCacheRequest counts a cache lookup for a resource by result (hit, miss or error)
+func (cb *ContextBuilder) recordCacheRequest(resource, result string) {
+	if cb.metrics == nil {
+		return
+	}
+	cb.metrics.ContextCacheRequests.WithLabelValues(resource, result).Inc()
+}
+
+// recordFetchError counts a failed microservice fetch for a resource by reason (unauthorized or error)
+func (cb *ContextBuilder) recordFetchError(resource, reason string) {
+	if cb.metrics == nil {
+		return
+	}
+	cb.metrics.ContextFetchErrors.WithLabelValues(resource, reason).Inc()
 }
 
 // fetchFromMicroservice fetches resource data from external microservice
@@ -147,7 +389,7 @@ func (cb *ContextBuilder) fetchFromMicroservice(
 	currentContext map[string]interface{},
 ) (map[string]interface{}, error) {
 	// Get endpoint mapping for resource
-	endpointTemplate, exists := cb.config.EndpointMapping[resource]
+	endpointConfig, exists := cb.config.EndpointMapping[resource]
 	if !exists {
 		return nil, fmt.Errorf("no endpoint mapping found for resource: %s", resource)
 	}
@@ -159,18 +401,31 @@ func (cb *ContextBuilder) fetchFromMicroservice(
 	}
 
 	// Build endpoint URL by replacing {id} with actual identifier
-	endpoint := strings.ReplaceAll(endpointTemplate, "{id}", identifier)
+	endpoint := strings.ReplaceAll(endpointConfig.Path, "{id}", identifier)
 	url := cb.config.BaseURL + endpoint
 
+	method := strings.ToUpper(endpointConfig.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	// Render the body template once; every attempt sends the same body
+	var body []byte
+	if endpointConfig.Body != nil {
+		body, err = json.Marshal(renderTemplate(endpointConfig.Body, currentContext, nil))
+		if err != nil {
+			return nil, fmt.Errorf("failed to render request body for resource %s: %w", resource, err)
+		}
+	}
+
 	// Attempt to fetch with retry logic
 	var data map[string]interface{}
 	var lastErr error
 
 	for attempt := 0; attempt <= cb.config.MaxRetries; attempt++ {
 		if attempt > 0 {
-			// Calculate backoff delay with exponential backoff
-			backoffDelay := cb.config.RetryDelay * time.Duration(1<<uint(attempt-1))
-			cb.logger.Infof("Retrying fetch for resource %s (attempt %d/%d) after %v", resource, attempt, cb.config.MaxRetries, backoffDelay)
+			backoffDelay := cb.retryBackoff(attempt)
+			loggerFor(ctx, cb.logger).Infof("Retrying fetch for resource %s (attempt %d/%d) after %v", resource, attempt, cb.config.MaxRetries, backoffDelay)
 
 			select {
 			case <-time.After(backoffDelay):
@@ -179,36 +434,86 @@ func (cb *ContextBuilder) fetchFromMicroservice(
 			}
 		}
 
-		data, lastErr = cb.makeHTTPRequest(ctx, organizationID, url, resource)
+		lastErr = cb.breaker.Execute(func() error {
+			var err error
+			data, err = cb.makeHTTPRequest(ctx, organizationID, method, url, body, resource)
+			return err
+		})
 		if lastErr == nil {
-			cb.logger.Infof("Successfully fetched resource %s from microservice: %s (org: %s)", resource, url, organizationID)
+			loggerFor(ctx, cb.logger).Infof("Successfully fetched resource %s from microservice: %s (org: %s)", resource, url, organizationID)
 			return data, nil
 		}
 
-		cb.logger.Warnf("Attempt %d/%d failed to fetch resource %s: %v", attempt+1, cb.config.MaxRetries+1, resource, lastErr)
+		// Retrying with the same credentials would be rejected again, a blocked destination stays
+		// blocked, and an open breaker keeps failing until its timeout
+		if errors.Is(lastErr, ErrEnrichmentUnauthorized) || errors.Is(lastErr, egress.ErrBlockedDestination) ||
+			errors.Is(lastErr, circuitbreaker.ErrOpen) {
+			return nil, lastErr
+		}
+
+		loggerFor(ctx, cb.logger).Warnf("Attempt %d/%d failed to fetch resource %s: %v", attempt+1, cb.config.MaxRetries+1, resource, lastErr)
 	}
 
 	return nil, fmt.Errorf("failed to fetch resource after %d attempts: %w", cb.config.MaxRetries+1, lastErr)
 }
 
-// makeHTTPRequest makes a single HTTP request to fetch resource data
+// retryBackoff returns the delay before a retry: RetryDelay doubled for each earlier retry,
+// capped at RetryMaxBackoff and spread by RetryJitter
+func (cb *ContextBuilder) retryBackoff(attempt int) time.Duration {
+	backoff, ok := jitteredRetryBackoff(attempt, "exponential", cb.config.RetryDelay, cb.config.RetryMaxBackoff, cb.config.RetryJitter)
+	if !ok {
+		cb.logger.Warnf("Invalid context enrichment retry jitter '%s', using backoff without jitter", cb.config.RetryJitter)
+	}
+	return backoff
+}
+
+// makeHTTPRequest makes a single HTTP request to fetch resource data, sending body when non-nil
 func (cb *ContextBuilder) makeHTTPRequest(
 	ctx context.Context,
 	organizationID uuid.UUID,
+	method string,
 	url string,
+	body []byte,
 	resource string,
 ) (map[string]interface{}, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	if err := cb.egress.CheckURL(ctx, "enrichment", url); errors.Is(err, egress.ErrBlockedDestination) {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	// Configured headers first, so they can't replace the request metadata set below
+	for name, value := range cb.config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if cb.tokens != nil {
+		token, err := cb.tokens(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get auth token: %w", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "IntelligentWorkflows/1.0")
 	req.Header.Set("X-Request-ID", uuid.New().String())
 	req.Header.Set("X-Resource-Type", resource)
 	req.Header.Set("X-Organization-ID", organizationID.String())
+	if id := correlation.FromContext(ctx); id != "" {
+		req.Header.Set(correlation.Header, id)
+	}
 
 	// Execute request
 	resp, err := cb.httpClient.Do(req)
@@ -218,9 +523,13 @@ func (cb *ContextBuilder) makeHTTPRequest(
 	defer resp.Body.Close()
 
 	// Check response status
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%w: microservice returned status %d: %s", ErrEnrichmentUnauthorized, resp.StatusCode, string(bodyBytes))
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("microservice returned error status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, &statusError{statusCode: resp.StatusCode, body: string(bodyBytes)}
 	}
 
 	// Read and parse response body
@@ -344,12 +653,20 @@ func (cb *ContextBuilder) getFromCache(
 	resource string,
 	currentContext map[string]interface{},
 ) (map[string]interface{}, error) {
-	// Build cache key from resource and context
-	cacheKey := cb.buildCacheKey(organizationID, resource, currentContext)
+	// Build cache key from resource and context; without an identifier nothing can be cached
+	cacheKey, err := cb.buildCacheKey(organizationID, resource, currentContext)
+	if err != nil {
+		return nil, errCacheMiss
+	}
+
+	return cb.readCache(ctx, cacheKey)
+}
 
-	data, err := cb.redis.Get(ctx, cacheKey).Result()
+// readCache reads and decodes the cache entry stored under key
+func (cb *ContextBuilder) readCache(ctx context.Context, key string) (map[string]interface{}, error) {
+	data, err := cb.redis.Get(ctx, key).Result()
 	if err == redis.Nil {
-		return nil, fmt.Errorf("cache miss")
+		return nil, errCacheMiss
 	}
 	if err != nil {
 		return nil, fmt.Errorf("redis error: %w", err)
@@ -372,76 +689,109 @@ func (cb *ContextBuilder) setInCache(
 	data map[string]interface{},
 	ttl time.Duration,
 ) error {
-	cacheKey := cb.buildCacheKey(organizationID, resource, currentContext)
+	cacheKey, err := cb.buildCacheKey(organizationID, resource, currentContext)
+	if err != nil {
+		return fmt.Errorf("failed to build cache key: %w", err)
+	}
+
+	return cb.writeCache(ctx, cacheKey, data, ttl)
+}
 
+// writeCache encodes data and stores it under key
+func (cb *ContextBuilder) writeCache(ctx context.Context, key string, data map[string]interface{}, ttl time.Duration) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal data: %w", err)
 	}
 
-	return cb.redis.Set(ctx, cacheKey, jsonData, ttl).Err()
+	return cb.redis.Set(ctx, key, jsonData, ttl).Err()
 }
 
-// buildCacheKey creates a cache key for a resource with organization scoping
-func (cb *ContextBuilder) buildCacheKey(organizationID uuid.UUID, resource string, context map[string]interface{}) string {
-	// Extract relevant identifiers from context to build a unique key
-	// Example: "context:org_123:order.details:ord_123"
-
-	var identifier string
-
-	// Try to extract entity ID from context
-	switch resource {
-	case "order.details":
-		if order, ok := context["order"].(map[string]interface{}); ok {
-			if id, ok := order["id"].(string); ok {
-				identifier = id
-			}
-		}
-	case "customer.history":
-		if customer, ok := context["customer"].(map[string]interface{}); ok {
-			if id, ok := customer["id"].(string); ok {
-				identifier = id
-			}
-		}
-	case "product.inventory":
-		if product, ok := context["product"].(map[string]interface{}); ok {
-			if id, ok := product["id"].(string); ok {
-				identifier = id
-			}
-		}
-	}
-
-	if identifier == "" {
-		// Fallback to a generic identifier
-		identifier = uuid.New().String()
+// buildCacheKey creates a cache key for a resource with organization scoping, using the same
+// identifier the resource is fetched by so repeated loads of one entity share an entry.
+// Example: "context:org_123:order.details:ord_123"
+func (cb *ContextBuilder) buildCacheKey(organizationID uuid.UUID, resource string, context map[string]interface{}) (string, error) {
+	identifier, err := cb.extractIdentifier(resource, context)
+	if err != nil {
+		return "", err
 	}
 
 	// Include organizationID in cache key for multi-tenant isolation
-	return fmt.Sprintf("context:%s:%s:%s", organizationID.String(), resource, identifier)
+	return fmt.Sprintf("context:%s:%s:%s", organizationID.String(), resource, identifier), nil
 }
 
-// mergeIntoContext merges loaded data into the context at the appropriate path
+// mergeIntoContext nests loaded data at the resource path, so "order.details" lands under
+// context["order"]["details"] and rules can reference order.details.risk_score. Maps along the
+// path are copied before being written, leaving the trigger payload they came from unchanged.
+// If a key along the path holds a non-map value, that value is kept and the data is stored
+// under the flat resource name instead.
 func (cb *ContextBuilder) mergeIntoContext(
 	context map[string]interface{},
 	resource string,
 	data map[string]interface{},
 ) {
-	// Parse resource path (e.g., "order.details" -> ["order", "details"])
-	// and create nested structure
-
 	if len(data) == 0 {
 		return
 	}
 
-	// For now, just merge at the top level with the full resource name as key
-	// In a more sophisticated implementation, this would create nested structures
-	context[resource] = data
+	// Parse resource path (e.g., "order.details" -> ["order", "details"])
+	parts := strings.Split(resource, ".")
+	parents := parts[:len(parts)-1]
+
+	// Check the whole path before writing so a conflict leaves the context untouched
+	current := context
+	for i, key := range parts {
+		if key == "" {
+			cb.logger.Warnf("Resource %s has an empty path segment, storing it under its flat name", resource)
+			context[resource] = data
+			return
+		}
+		if i == len(parents) || current == nil {
+			continue
+		}
+
+		existing := current[key]
+		next, isMap := asContextMap(existing)
+		if existing != nil && !isMap {
+			cb.logger.Warnf("Cannot nest resource %s: %s holds a %T, storing it under its flat name", resource, strings.Join(parts[:i+1], "."), existing)
+			context[resource] = data
+			return
+		}
+		current = next
+	}
+
+	current = context
+	for _, key := range parents {
+		next, ok := asContextMap(current[key])
+		if ok {
+			next = copyContext(next)
+		} else {
+			next = make(map[string]interface{})
+		}
+		current[key] = next
+		current = next
+	}
+	current[parts[len(parts)-1]] = data
 }
 
-// EnrichContext adds computed fields and metadata to the context
+// asContextMap returns value as a context map if it is one
+func asContextMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case models.JSONB:
+		return map[string]interface{}(v), true
+	default:
+		return nil, false
+	}
+}
+
+// EnrichContext adds computed fields and metadata to the context. The workflow's computed
+// fields are evaluated alongside those configured for the deployment
 func (cb *ContextBuilder) EnrichContext(
 	ctx context.Context,
 	execContext map[string]interface{},
+	contextDef models.ContextDefinition,
 ) error {
 	// Add execution metadata
 	if execContext["_meta"] == nil {
@@ -452,46 +802,51 @@ func (cb *ContextBuilder) EnrichContext(
 	meta["enriched_at"] = time.Now().Unix()
 	meta["version"] = "1.0"
 
-	// Add computed fields (examples)
-	cb.addComputedFields(execContext)
+	cb.addComputedFields(execContext, contextDef.Computed)
 
 	return nil
 }
 
-// addComputedFields adds useful computed fields to the context
-func (cb *ContextBuilder) addComputedFields(context map[string]interface{}) {
-	// Example: Add day of week, hour, etc. for time-based rules
+// addComputedFields adds the time-based defaults and the configured computed fields to the context.
+// A field whose expression fails, e.g. because the entity it reads is not loaded, is left out
+func (cb *ContextBuilder) addComputedFields(context map[string]interface{}, workflowFields map[string]string) {
 	now := time.Now()
 
-	if context["_computed"] == nil {
-		context["_computed"] = make(map[string]interface{})
+	computed, ok := context["_computed"].(map[string]interface{})
+	if !ok {
+		computed = make(map[string]interface{})
+		context["_computed"] = computed
 	}
 
-	computed := context["_computed"].(map[string]interface{})
 	computed["current_time"] = now.Unix()
 	computed["current_hour"] = now.Hour()
 	computed["current_day_of_week"] = now.Weekday().String()
 	computed["current_date"] = now.Format("2006-01-02")
 
-	// Add order-specific computed fields if order exists
-	if order, ok := context["order"].(map[string]interface{}); ok {
-		if total, ok := order["total"].(float64); ok {
-			computed["order_is_high_value"] = total >= 10000
-			computed["order_is_medium_value"] = total >= 1000 && total < 10000
-			computed["order_is_low_value"] = total < 1000
-		}
+	// Workflow fields replace configured fields of the same name
+	fields := make(map[string]string, len(cb.config.ComputedFields)+len(workflowFields))
+	for name, expression := range cb.config.ComputedFields {
+		fields[name] = expression
+	}
+	for name, expression := range workflowFields {
+		fields[name] = expression
 	}
 
-	// Add customer-specific computed fields if customer exists
-	if customer, ok := context["customer"].(map[string]interface{}); ok {
-		if createdAt, ok := customer["created_at"].(string); ok {
-			// Parse created_at and compute account age
-			if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
-				accountAgeDays := int(time.Since(t).Hours() / 24)
-				computed["customer_account_age_days"] = accountAgeDays
-				computed["customer_is_new"] = accountAgeDays < 30
-			}
+	// Evaluate in name order so the results don't depend on map iteration
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value, err := cb.evaluator.evaluateValue(fields[name], context)
+		if err != nil {
+			cb.logger.Debugf("Skipping computed field %s: %v", name, err)
+			delete(computed, name)
+			continue
 		}
+		computed[name] = value
 	}
 }
 
@@ -513,8 +868,193 @@ func (cb *ContextBuilder) ClearCache(ctx context.Context, organizationID uuid.UU
 		if err := cb.redis.Del(ctx, keys...).Err(); err != nil {
 			return fmt.Errorf("delete error: %w", err)
 		}
-		cb.logger.Infof("Cleared %d cache entries for pattern: %s", len(keys), pattern)
+		loggerFor(ctx, cb.logger).Infof("Cleared %d cache entries for pattern: %s", len(keys), pattern)
+	}
+
+	return nil
+}
+
+// ClearCacheForEntity clears every cache entry for one entity across its resources and
+// organizations, e.g. after order ord_123 changes. Stale copies and failure markers go too
+func (cb *ContextBuilder) ClearCacheForEntity(ctx context.Context, entityType, id string) error {
+	iter := cb.redis.Scan(ctx, 0, "context:*:"+escapeKeyPattern(id)+"*", 0).Iterator()
+
+	var keys []string
+	for iter.Next(ctx) {
+		// The pattern also matches longer IDs and other entity types, so check each key exactly
+		if keyEntity, keyID, ok := parseCacheKey(iter.Val()); ok && keyEntity == entityType && keyID == id {
+			keys = append(keys, iter.Val())
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("scan error: %w", err)
+	}
+
+	if len(keys) > 0 {
+		if err := cb.redis.Del(ctx, keys...).Err(); err != nil {
+			return fmt.Errorf("delete error: %w", err)
+		}
+		loggerFor(ctx, cb.logger).Infof("Cleared %d cache entries for %s %s", len(keys), entityType, id)
 	}
 
 	return nil
 }
+
+// InvalidateResources drops the cache entries and failure markers of resources so the next
+// load fetches them. Stale copies are kept to fall back on if that fetch fails
+func (cb *ContextBuilder) InvalidateResources(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	resources []string,
+	currentContext map[string]interface{},
+) error {
+	var keys []string
+	for _, resource := range resources {
+		cacheKey, err := cb.buildCacheKey(organizationID, resource, currentContext)
+		if err != nil {
+			continue // nothing is cached without an identifier
+		}
+		keys = append(keys, cacheKey, cacheKey+failedKeySuffix)
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if err := cb.redis.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("delete error: %w", err)
+	}
+	return nil
+}
+
+// parseCacheKey returns the entity type and identifier of a key built by buildCacheKey,
+// including the stale and failed keys kept next to it
+func parseCacheKey(key string) (entityType, id string, ok bool) {
+	rest, ok := strings.CutPrefix(key, "context:")
+	if !ok {
+		return "", "", false
+	}
+	// Organization IDs and resource names contain no colons; the identifier may
+	_, rest, ok = strings.Cut(rest, ":")
+	if !ok {
+		return "", "", false
+	}
+	resource, id, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", "", false
+	}
+
+	for _, suffix := range []string{staleKeySuffix, failedKeySuffix} {
+		if trimmed, found := strings.CutSuffix(id, suffix); found {
+			id = trimmed
+			break
+		}
+	}
+
+	entityType, _, _ = strings.Cut(resource, ".")
+	return entityType, id, true
+}
+
+// escapeKeyPattern escapes the glob characters Redis' MATCH understands
+func escapeKeyPattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// cloneContext returns a deep copy of an execution context so that later mutations
+// (for example _meta, _computed, or variables set by subsequent steps) do not
+// affect the copy. Maps and slices are copied recursively; other values are shared.
+func cloneContext(context map[string]interface{}) map[string]interface{} {
+	if context == nil {
+		return nil
+	}
+	return cloneValue(context).(map[string]interface{})
+}
+
+// copyContext returns a shallow copy of an execution context. Steps only ever assign
+// top-level keys, so a shallow copy is enough to keep one branch's writes out of another's.
+func copyContext(context map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(context))
+	for key, value := range context {
+		copied[key] = value
+	}
+	return copied
+}
+
+// mergeBranchContext copies into dst every top-level key a branch added or reassigned
+// relative to dst, which must still hold the values the branch context was copied from
+func mergeBranchContext(dst, branch map[string]interface{}) {
+	for key, value := range branch {
+		if existing, ok := dst[key]; ok && sameValue(existing, value) {
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// sameValue reports whether two context values are the same value. Maps, slices and
+// pointers compare by identity, so a reassigned key is detected without a deep compare.
+func sameValue(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		return false
+	}
+
+	switch va.Kind() {
+	case reflect.Map, reflect.Pointer, reflect.Func, reflect.Chan:
+		return va.Pointer() == vb.Pointer()
+	case reflect.Slice:
+		return va.Pointer() == vb.Pointer() && va.Len() == vb.Len()
+	}
+
+	if va.Type().Comparable() {
+		return a == b
+	}
+	return false
+}
+
+// cloneValue deep-copies maps and slices while preserving their concrete types
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = cloneValue(item)
+		}
+		return copied
+	case models.JSONB:
+		copied := make(models.JSONB, len(v))
+		for key, item := range v {
+			copied[key] = cloneValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = cloneValue(item)
+		}
+		return copied
+	case []map[string]interface{}:
+		copied := make([]map[string]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = cloneValue(item).(map[string]interface{})
+		}
+		return copied
+	case []string:
+		return append([]string(nil), v...)
+	default:
+		return v
+	}
+}
@@ -2,15 +2,25 @@ package engine
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/circuitbreaker"
 	"github.com/davidmoltin/intelligent-workflows/pkg/config"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/metrics"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -23,10 +33,10 @@ func getTestContextEnrichmentConfig() *config.ContextEnrichmentConfig {
 		MaxRetries: 3,
 		RetryDelay: 500 * time.Millisecond,
 		CacheTTL:   5 * time.Minute,
-		EndpointMapping: map[string]string{
-			"order.details":     "/api/v1/orders/{id}/details",
-			"customer.history":  "/api/v1/customers/{id}/history",
-			"product.inventory": "/api/v1/products/{id}/inventory",
+		EndpointMapping: map[string]config.EnrichmentEndpoint{
+			"order.details":     {Path: "/api/v1/orders/{id}/details"},
+			"customer.history":  {Path: "/api/v1/customers/{id}/history"},
+			"product.inventory": {Path: "/api/v1/products/{id}/inventory"},
 		},
 	}
 }
@@ -35,7 +45,7 @@ func TestBuildContext(t *testing.T) {
 	log := logger.NewForTesting()
 	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
 	cfg := getTestContextEnrichmentConfig()
-	builder := NewContextBuilder(redisClient, log, cfg)
+	builder := NewContextBuilder(redisClient, log, cfg, nil)
 	ctx := context.Background()
 	orgID := uuid.New()
 
@@ -93,7 +103,7 @@ func TestBuildContextFromExisting(t *testing.T) {
 	log := logger.NewForTesting()
 	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
 	cfg := getTestContextEnrichmentConfig()
-	builder := NewContextBuilder(redisClient, log, cfg)
+	builder := NewContextBuilder(redisClient, log, cfg, nil)
 	ctx := context.Background()
 	orgID := uuid.New()
 
@@ -136,11 +146,785 @@ func TestBuildContextFromExisting(t *testing.T) {
 	})
 }
 
+// newUnreachableRedisClient returns a client whose every command fails fast, so cache lookups always miss
+func newUnreachableRedisClient() *redis.Client {
+	return redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+}
+
+// fakeRedis is an in-memory stand-in for the GET, SET, EXISTS, DEL and SCAN commands ContextBuilder issues.
+// Entries never expire; tests delete them to simulate expiry
+type fakeRedis struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+// newFakeRedisClient returns a client whose commands are served by a fakeRedis instead of a server
+func newFakeRedisClient() (*redis.Client, *fakeRedis) {
+	fake := &fakeRedis{data: make(map[string]string)}
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	client.AddHook(fake)
+	return client, fake
+}
+
+func (f *fakeRedis) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (f *fakeRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (f *fakeRedis) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		args := cmd.Args()
+		switch c := cmd.(type) {
+		case *redis.StringCmd: // GET key
+			value, ok := f.data[args[1].(string)]
+			if !ok {
+				c.SetErr(redis.Nil)
+				return redis.Nil
+			}
+			c.SetVal(value)
+		case *redis.StatusCmd: // SET key value [PX|EX ttl]
+			value := args[2]
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			f.data[args[1].(string)] = fmt.Sprint(value)
+			c.SetVal("OK")
+		case *redis.IntCmd: // EXISTS key... or DEL key...
+			var count int64
+			for _, key := range args[1:] {
+				if _, ok := f.data[key.(string)]; ok {
+					count++
+					if cmd.Name() == "del" {
+						delete(f.data, key.(string))
+					}
+				}
+			}
+			c.SetVal(count)
+		case *redis.ScanCmd: // SCAN cursor MATCH pattern, answered in a single page
+			var keys []string
+			for key := range f.data {
+				if matched, _ := path.Match(args[3].(string), key); matched {
+					keys = append(keys, key)
+				}
+			}
+			c.SetVal(keys, 0)
+		default:
+			err := fmt.Errorf("fake redis: unsupported command %s", cmd.Name())
+			cmd.SetErr(err)
+			return err
+		}
+		return nil
+	}
+}
+
+func (f *fakeRedis) has(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.data[key]
+	return ok
+}
+
+func (f *fakeRedis) delete(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.data, key)
+}
+
+func TestClearCacheForEntity(t *testing.T) {
+	ctx := context.Background()
+	client, fake := newFakeRedisClient()
+	builder := NewContextBuilder(client, logger.NewForTesting(), getTestContextEnrichmentConfig(), nil)
+
+	orgA, orgB := uuid.New(), uuid.New()
+	cleared := []string{
+		fmt.Sprintf("context:%s:order.details:ord_123", orgA),
+		fmt.Sprintf("context:%s:order.details:ord_123%s", orgA, staleKeySuffix),
+		fmt.Sprintf("context:%s:order.details:ord_123%s", orgA, failedKeySuffix),
+		fmt.Sprintf("context:%s:order.history:ord_123", orgA),
+		fmt.Sprintf("context:%s:order.details:ord_123", orgB),
+	}
+	kept := []string{
+		fmt.Sprintf("context:%s:order.details:ord_1234", orgA),
+		fmt.Sprintf("context:%s:customer.history:ord_123", orgA),
+		fmt.Sprintf("context:%s:order.details:ord_456", orgA),
+	}
+	for _, key := range append(append([]string{}, cleared...), kept...) {
+		if err := client.Set(ctx, key, "{}", 0).Err(); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+
+	if err := builder.ClearCacheForEntity(ctx, "order", "ord_123"); err != nil {
+		t.Fatalf("ClearCacheForEntity failed: %v", err)
+	}
+
+	for _, key := range cleared {
+		if fake.has(key) {
+			t.Errorf("Expected %s to be cleared", key)
+		}
+	}
+	for _, key := range kept {
+		if !fake.has(key) {
+			t.Errorf("Expected %s to be kept", key)
+		}
+	}
+}
+
+func TestInvalidateResources(t *testing.T) {
+	ctx := context.Background()
+	client, fake := newFakeRedisClient()
+	builder := NewContextBuilder(client, logger.NewForTesting(), getTestContextEnrichmentConfig(), nil)
+
+	orgID := uuid.New()
+	execContext := map[string]interface{}{"order_id": "ord_123"}
+	key := fmt.Sprintf("context:%s:order.details:ord_123", orgID)
+	for _, k := range []string{key, key + staleKeySuffix, key + failedKeySuffix} {
+		if err := client.Set(ctx, k, "{}", 0).Err(); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+
+	// customer.history has no identifier in the context, so it is skipped
+	if err := builder.InvalidateResources(ctx, orgID, []string{"order.details", "customer.history"}, execContext); err != nil {
+		t.Fatalf("InvalidateResources failed: %v", err)
+	}
+
+	if fake.has(key) || fake.has(key+failedKeySuffix) {
+		t.Error("Expected the cache entry and failure marker to be cleared")
+	}
+	if !fake.has(key + staleKeySuffix) {
+		t.Error("Expected the stale copy to be kept")
+	}
+}
+
+func TestLoadResource_FailureHandling(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+	orgID := uuid.New()
+	execContext := map[string]interface{}{"order_id": "ord-1"}
+
+	var requests int32
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"risk_score": 12}`))
+	}))
+	defer server.Close()
+
+	newBuilder := func(negativeTTL time.Duration, staleWhileRevalidate bool) (*ContextBuilder, *fakeRedis) {
+		cfg := getTestContextEnrichmentConfig()
+		cfg.Enabled = true
+		cfg.BaseURL = server.URL
+		cfg.MaxRetries = 0
+		cfg.NegativeCacheTTL = negativeTTL
+		cfg.StaleWhileRevalidate = staleWhileRevalidate
+		cfg.StaleTTL = time.Hour
+		client, fake := newFakeRedisClient()
+		return NewContextBuilder(client, log, cfg, nil), fake
+	}
+
+	t.Run("serves stale data and backs off after a failure", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		failing.Store(false)
+		builder, fake := newBuilder(time.Minute, true)
+		cacheKey, err := builder.buildCacheKey(orgID, "order.details", execContext)
+		if err != nil {
+			t.Fatalf("buildCacheKey failed: %v", err)
+		}
+
+		data, stale, err := builder.loadResource(ctx, orgID, "order.details", execContext)
+		if err != nil || stale || data["risk_score"] != 12.0 {
+			t.Fatalf("Expected fresh data, got %v (stale=%v, err=%v)", data, stale, err)
+		}
+		if !fake.has(cacheKey + staleKeySuffix) {
+			t.Error("Expected a stale copy to be kept")
+		}
+
+		// The fresh entry expires and the microservice goes down
+		fake.delete(cacheKey)
+		failing.Store(true)
+
+		data, stale, err = builder.loadResource(ctx, orgID, "order.details", execContext)
+		if err != nil || !stale || data["risk_score"] != 12.0 {
+			t.Fatalf("Expected stale data, got %v (stale=%v, err=%v)", data, stale, err)
+		}
+		if !fake.has(cacheKey + failedKeySuffix) {
+			t.Error("Expected the failure to be recorded")
+		}
+		if got := atomic.LoadInt32(&requests); got != 2 {
+			t.Fatalf("Expected 2 requests so far, got %d", got)
+		}
+
+		// Within the negative cache TTL the microservice is not called again
+		execCtx, err := builder.BuildContext(ctx, orgID, execContext, models.ContextDefinition{Load: []string{"order.details"}})
+		if err != nil {
+			t.Fatalf("BuildContext failed: %v", err)
+		}
+		if got := atomic.LoadInt32(&requests); got != 2 {
+			t.Errorf("Expected no request during back-off, got %d", got)
+		}
+		meta, _ := execCtx["_meta"].(map[string]interface{})
+		if staleResources, _ := meta["stale_resources"].([]string); len(staleResources) != 1 || staleResources[0] != "order.details" {
+			t.Errorf("Expected order.details noted as stale under _meta, got %v", execCtx["_meta"])
+		}
+	})
+
+	t.Run("negative cache alone returns empty data", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		failing.Store(true)
+		builder, _ := newBuilder(time.Minute, false)
+
+		for i := 0; i < 3; i++ {
+			data, stale, err := builder.loadResource(ctx, orgID, "order.details", execContext)
+			if err != nil || stale || len(data) != 0 {
+				t.Fatalf("Expected empty data, got %v (stale=%v, err=%v)", data, stale, err)
+			}
+		}
+		if got := atomic.LoadInt32(&requests); got != 1 {
+			t.Errorf("Expected repeated failures to back off after one request, got %d", got)
+		}
+	})
+
+	t.Run("disabled options keep calling the microservice", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		failing.Store(true)
+		builder, _ := newBuilder(0, false)
+
+		for i := 0; i < 2; i++ {
+			if _, _, err := builder.loadResource(ctx, orgID, "order.details", execContext); err != nil {
+				t.Fatalf("loadResource failed: %v", err)
+			}
+		}
+		if got := atomic.LoadInt32(&requests); got != 2 {
+			t.Errorf("Expected a request per load, got %d", got)
+		}
+	})
+}
+
+func TestBuildContext_ConcurrentLoading(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+	const delay = 200 * time.Millisecond
+
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+				break
+			}
+		}
+
+		time.Sleep(delay)
+		if strings.HasPrefix(r.URL.Path, "/payments/") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"resource": "` + r.Header.Get("X-Resource-Type") + `"}`))
+	}))
+	defer server.Close()
+
+	newBuilder := func(limit int) *ContextBuilder {
+		cfg := getTestContextEnrichmentConfig()
+		cfg.Enabled = true
+		cfg.BaseURL = server.URL
+		cfg.MaxRetries = 0
+		cfg.MaxConcurrentEnrichment = limit
+		cfg.EndpointMapping = map[string]config.EnrichmentEndpoint{
+			"order.details":     {Path: "/orders/{id}"},
+			"customer.history":  {Path: "/customers/{id}"},
+			"product.inventory": {Path: "/products/{id}"},
+			"payment.status":    {Path: "/payments/{id}"},
+		}
+		return NewContextBuilder(newUnreachableRedisClient(), log, cfg, nil)
+	}
+
+	payload := map[string]interface{}{"order_id": "ord-1", "customer_id": "cust-1", "product_id": "prod-1", "payment_id": "pay-1"}
+	contextDef := models.ContextDefinition{Load: []string{"order.details", "customer.history", "product.inventory", "payment.status"}}
+
+	t.Run("total time approaches the slowest resource", func(t *testing.T) {
+		atomic.StoreInt32(&maxInFlight, 0)
+		start := time.Now()
+		execContext, err := newBuilder(4).BuildContext(ctx, uuid.New(), payload, contextDef)
+		elapsed := time.Since(start)
+		if err != nil {
+			t.Fatalf("BuildContext failed: %v", err)
+		}
+
+		if elapsed >= 2*delay {
+			t.Errorf("Expected about %v for 4 concurrent loads, took %v", delay, elapsed)
+		}
+		for _, resource := range []string{"order.details", "customer.history", "product.inventory"} {
+			parts := strings.Split(resource, ".")
+			parent, _ := execContext[parts[0]].(map[string]interface{})
+			data, ok := parent[parts[1]].(map[string]interface{})
+			if !ok || data["resource"] != resource {
+				t.Errorf("Expected %s to be merged, got %v", resource, execContext[parts[0]])
+			}
+		}
+		// A failed resource is skipped without affecting the others
+		if _, ok := execContext["payment"]; ok {
+			t.Error("Expected failed payment.status to be skipped")
+		}
+	})
+
+	t.Run("fan-out is capped", func(t *testing.T) {
+		atomic.StoreInt32(&maxInFlight, 0)
+		start := time.Now()
+		if _, err := newBuilder(2).BuildContext(ctx, uuid.New(), payload, contextDef); err != nil {
+			t.Fatalf("BuildContext failed: %v", err)
+		}
+
+		if got := atomic.LoadInt32(&maxInFlight); got > 2 {
+			t.Errorf("Expected at most 2 concurrent requests, saw %d", got)
+		}
+		if elapsed := time.Since(start); elapsed < 2*delay {
+			t.Errorf("Expected 2 rounds of loads with a limit of 2, took %v", elapsed)
+		}
+	})
+}
+
+func TestRetryBackoff(t *testing.T) {
+	log := logger.NewForTesting()
+	newBuilder := func(maxBackoff time.Duration, jitter string) *ContextBuilder {
+		cfg := getTestContextEnrichmentConfig()
+		cfg.RetryDelay = 500 * time.Millisecond
+		cfg.RetryMaxBackoff = maxBackoff
+		cfg.RetryJitter = jitter
+		return NewContextBuilder(newUnreachableRedisClient(), log, cfg, nil)
+	}
+
+	t.Run("deterministic without jitter", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			attempt    int
+			maxBackoff time.Duration
+			jitter     string
+			expected   time.Duration
+		}{
+			{name: "first retry", attempt: 1, expected: 500 * time.Millisecond},
+			{name: "doubles per retry", attempt: 4, expected: 4 * time.Second},
+			{name: "uncapped", attempt: 8, expected: 64 * time.Second},
+			{name: "capped", attempt: 8, maxBackoff: 10 * time.Second, expected: 10 * time.Second},
+			{name: "below cap", attempt: 2, maxBackoff: 10 * time.Second, expected: time.Second},
+			{name: "many retries stay capped", attempt: 200, maxBackoff: 10 * time.Second, expected: 10 * time.Second},
+			{name: "invalid jitter ignored", attempt: 2, jitter: "lots", expected: time.Second},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if got := newBuilder(tt.maxBackoff, tt.jitter).retryBackoff(tt.attempt); got != tt.expected {
+					t.Errorf("Expected %v, got %v", tt.expected, got)
+				}
+			})
+		}
+	})
+
+	t.Run("jitter stays within bounds", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			maxBackoff time.Duration
+			jitter     string
+			min        time.Duration
+			max        time.Duration
+		}{
+			{name: "full", jitter: "full", min: 0, max: 4 * time.Second},
+			{name: "equal", jitter: "equal", min: 2 * time.Second, max: 4 * time.Second},
+			{name: "percentage", jitter: "25%", min: 3 * time.Second, max: 5 * time.Second},
+			{name: "percentage capped", maxBackoff: 4 * time.Second, jitter: "50%", min: 2 * time.Second, max: 4 * time.Second},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				builder := newBuilder(tt.maxBackoff, tt.jitter)
+				distinct := make(map[time.Duration]bool)
+				for i := 0; i < 200; i++ {
+					got := builder.retryBackoff(4)
+					if got < tt.min || got > tt.max {
+						t.Fatalf("Backoff %v outside [%v, %v]", got, tt.min, tt.max)
+					}
+					distinct[got] = true
+				}
+				if len(distinct) < 2 {
+					t.Error("Expected jittered backoff to vary between calls")
+				}
+			})
+		}
+	})
+}
+
+func TestFetchFromMicroservice_CancelledDuringBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := getTestContextEnrichmentConfig()
+	cfg.Enabled = true
+	cfg.BaseURL = server.URL
+	cfg.RetryDelay = time.Minute
+	builder := NewContextBuilder(newUnreachableRedisClient(), logger.NewForTesting(), cfg, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := builder.fetchFromMicroservice(ctx, uuid.New(), "order.details", map[string]interface{}{"order_id": "ord-1"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the backoff to end with the context, took %v", elapsed)
+	}
+}
+
+func TestFetchFromMicroservice_Auth(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+	orgID := uuid.New()
+	execContext := map[string]interface{}{"order_id": "ord-1"}
+
+	var requests int32
+	var mu sync.Mutex
+	var lastHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		mu.Lock()
+		lastHeaders = r.Header.Clone()
+		mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer good-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"status": "paid"}`))
+	}))
+	defer server.Close()
+
+	newBuilder := func(m *metrics.Metrics) *ContextBuilder {
+		cfg := getTestContextEnrichmentConfig()
+		cfg.Enabled = true
+		cfg.BaseURL = server.URL
+		cfg.MaxRetries = 2
+		cfg.RetryDelay = time.Millisecond
+		cfg.Headers = map[string]string{"X-Api-Key": "static-key", "X-Organization-ID": "spoofed"}
+		return NewContextBuilder(newUnreachableRedisClient(), log, cfg, m)
+	}
+
+	t.Run("sends configured headers and the current token", func(t *testing.T) {
+		builder := newBuilder(nil)
+		token := "good-token"
+		builder.SetTokenProvider(func(ctx context.Context) (string, error) { return token, nil })
+
+		data, err := builder.fetchFromMicroservice(ctx, orgID, "order.details", execContext)
+		if err != nil {
+			t.Fatalf("fetchFromMicroservice failed: %v", err)
+		}
+		if data["status"] != "paid" {
+			t.Errorf("Expected data, got %v", data)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if lastHeaders.Get("X-Api-Key") != "static-key" {
+			t.Errorf("Expected configured header, got %q", lastHeaders.Get("X-Api-Key"))
+		}
+		if lastHeaders.Get("X-Organization-ID") != orgID.String() {
+			t.Errorf("Expected configured headers not to replace the organization, got %q", lastHeaders.Get("X-Organization-ID"))
+		}
+	})
+
+	t.Run("reads the token from the environment on each request", func(t *testing.T) {
+		t.Setenv("TEST_ENRICHMENT_TOKEN", "good-token")
+		cfg := getTestContextEnrichmentConfig()
+		cfg.Enabled = true
+		cfg.BaseURL = server.URL
+		cfg.AuthTokenEnv = "TEST_ENRICHMENT_TOKEN"
+		builder := NewContextBuilder(newUnreachableRedisClient(), log, cfg, nil)
+
+		if _, err := builder.fetchFromMicroservice(ctx, orgID, "order.details", execContext); err != nil {
+			t.Fatalf("fetchFromMicroservice failed: %v", err)
+		}
+
+		// A rotated token is picked up without rebuilding
+		t.Setenv("TEST_ENRICHMENT_TOKEN", "revoked-token")
+		if _, err := builder.fetchFromMicroservice(ctx, orgID, "order.details", execContext); !errors.Is(err, ErrEnrichmentUnauthorized) {
+			t.Errorf("Expected the rotated token to be sent, got %v", err)
+		}
+	})
+
+	t.Run("401 is reported as unauthorized and not retried", func(t *testing.T) {
+		m := &metrics.Metrics{
+			ContextCacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "cache_requests"}, []string{"resource", "result"}),
+			ContextFetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "fetch_duration"}, []string{"resource"}),
+			ContextFetchErrors:   prometheus.NewCounterVec(prometheus.CounterOpts{Name: "fetch_errors"}, []string{"resource", "reason"}),
+		}
+		builder := newBuilder(m)
+		builder.SetTokenProvider(func(ctx context.Context) (string, error) { return "bad-token", nil })
+
+		atomic.StoreInt32(&requests, 0)
+		_, err := builder.fetchFromMicroservice(ctx, orgID, "order.details", execContext)
+		if !errors.Is(err, ErrEnrichmentUnauthorized) {
+			t.Fatalf("Expected ErrEnrichmentUnauthorized, got %v", err)
+		}
+		if got := atomic.LoadInt32(&requests); got != 1 {
+			t.Errorf("Expected a single attempt, got %d", got)
+		}
+
+		if _, _, err := builder.loadResource(ctx, orgID, "order.details", execContext); err != nil {
+			t.Fatalf("loadResource failed: %v", err)
+		}
+		if counts := metricCounts(t, m.ContextFetchErrors, "resource", "reason"); counts["order.details/unauthorized"] != 1 || len(counts) != 1 {
+			t.Errorf("Expected one unauthorized fetch error, got %v", counts)
+		}
+	})
+
+	t.Run("token provider errors fail the request", func(t *testing.T) {
+		builder := newBuilder(nil)
+		builder.SetTokenProvider(func(ctx context.Context) (string, error) { return "", fmt.Errorf("vault unavailable") })
+
+		atomic.StoreInt32(&requests, 0)
+		if _, err := builder.fetchFromMicroservice(ctx, orgID, "order.details", execContext); err == nil || errors.Is(err, ErrEnrichmentUnauthorized) {
+			t.Errorf("Expected a token error, got %v", err)
+		}
+		if got := atomic.LoadInt32(&requests); got != 0 {
+			t.Errorf("Expected no request without a token, got %d", got)
+		}
+	})
+}
+
+func TestFetchFromMicroservice_MethodAndBody(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+
+	type captured struct {
+		method string
+		path   string
+		body   map[string]interface{}
+	}
+	var mu sync.Mutex
+	var last captured
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		last = captured{method: r.Method, path: r.URL.Path, body: body}
+		mu.Unlock()
+		w.Write([]byte(`{"data": {"order": {"risk": "low"}}}`))
+	}))
+	defer server.Close()
+
+	cfg := getTestContextEnrichmentConfig()
+	cfg.Enabled = true
+	cfg.BaseURL = server.URL
+	cfg.EndpointMapping = map[string]config.EnrichmentEndpoint{
+		"order.details": {Path: "/orders/{id}"},
+		"order.risk": {
+			Path:   "/graphql",
+			Method: "post",
+			Body: map[string]interface{}{
+				"query":     "query($id: ID!, $region: String) { order(id: $id, region: $region) { risk } }",
+				"variables": map[string]interface{}{"id": "{{order.id}}", "region": "{{customer.region}}"},
+			},
+		},
+	}
+	builder := NewContextBuilder(newUnreachableRedisClient(), log, cfg, nil)
+	execContext := map[string]interface{}{
+		"order":    map[string]interface{}{"id": "ord-1"},
+		"customer": map[string]interface{}{"region": "eu"},
+	}
+
+	t.Run("path-only endpoints use GET without a body", func(t *testing.T) {
+		if _, err := builder.fetchFromMicroservice(ctx, uuid.New(), "order.details", execContext); err != nil {
+			t.Fatalf("fetchFromMicroservice failed: %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if last.method != http.MethodGet || last.path != "/orders/ord-1" || last.body != nil {
+			t.Errorf("Expected GET /orders/ord-1 without a body, got %+v", last)
+		}
+	})
+
+	t.Run("sends the method and rendered body", func(t *testing.T) {
+		data, err := builder.fetchFromMicroservice(ctx, uuid.New(), "order.risk", execContext)
+		if err != nil {
+			t.Fatalf("fetchFromMicroservice failed: %v", err)
+		}
+		if _, ok := data["data"]; !ok {
+			t.Errorf("Expected the response to be returned, got %v", data)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if last.method != http.MethodPost || last.path != "/graphql" {
+			t.Errorf("Expected POST /graphql, got %s %s", last.method, last.path)
+		}
+		variables, _ := last.body["variables"].(map[string]interface{})
+		if variables["id"] != "ord-1" || variables["region"] != "eu" {
+			t.Errorf("Expected rendered variables, got %v", last.body)
+		}
+		if !strings.HasPrefix(last.body["query"].(string), "query($id: ID!") {
+			t.Errorf("Expected the query to be sent unchanged, got %v", last.body["query"])
+		}
+	})
+}
+
+func TestFetchFromMicroservice_CircuitBreaker(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+	orgID := uuid.New()
+	execContext := map[string]interface{}{"order_id": "ord-1"}
+
+	var requests int32
+	var status int32 = http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+		w.Write([]byte(`{"status": "paid"}`))
+	}))
+	defer server.Close()
+
+	newBuilder := func(m *metrics.Metrics) *ContextBuilder {
+		cfg := getTestContextEnrichmentConfig()
+		cfg.Enabled = true
+		cfg.BaseURL = server.URL
+		cfg.MaxRetries = 0
+		builder := NewContextBuilder(newUnreachableRedisClient(), log, cfg, m)
+		builder.SetCircuitBreaker(NewEnrichmentCircuitBreaker(&config.CircuitBreakerConfig{
+			Enabled:      true,
+			FailureRatio: 0.5,
+			MinRequests:  2,
+			Interval:     time.Minute,
+			OpenTimeout:  300 * time.Millisecond,
+		}, log, m))
+		return builder
+	}
+
+	t.Run("opens after failures and fails fast until a probe succeeds", func(t *testing.T) {
+		m := &metrics.Metrics{
+			ContextCacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "cache_requests"}, []string{"resource", "result"}),
+			ContextFetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "fetch_duration"}, []string{"resource"}),
+			ContextFetchErrors:   prometheus.NewCounterVec(prometheus.CounterOpts{Name: "fetch_errors"}, []string{"resource", "reason"}),
+			CircuitBreakerState:  prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "breaker_state"}, []string{"name"}),
+		}
+		builder := newBuilder(m)
+		atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+		atomic.StoreInt32(&requests, 0)
+
+		for i := 0; i < 2; i++ {
+			if _, err := builder.fetchFromMicroservice(ctx, orgID, "order.details", execContext); err == nil {
+				t.Fatal("Expected the fetch to fail")
+			}
+		}
+		_, err := builder.fetchFromMicroservice(ctx, orgID, "order.details", execContext)
+		if !errors.Is(err, circuitbreaker.ErrOpen) {
+			t.Fatalf("Expected circuitbreaker.ErrOpen, got %v", err)
+		}
+		if got := atomic.LoadInt32(&requests); got != 2 {
+			t.Errorf("Expected no request while the breaker is open, got %d", got)
+		}
+		if state := metricCounts(t, m.CircuitBreakerState, "name")["context_enrichment"]; state != 2 {
+			t.Errorf("Expected the open state to be exported, got %v", state)
+		}
+
+		if _, _, err := builder.loadResource(ctx, orgID, "order.details", execContext); err != nil {
+			t.Fatalf("loadResource failed: %v", err)
+		}
+		if counts := metricCounts(t, m.ContextFetchErrors, "resource", "reason"); counts["order.details/circuit_open"] != 1 {
+			t.Errorf("Expected a circuit_open fetch error, got %v", counts)
+		}
+
+		atomic.StoreInt32(&status, http.StatusOK)
+		time.Sleep(350 * time.Millisecond)
+		if _, err := builder.fetchFromMicroservice(ctx, orgID, "order.details", execContext); err != nil {
+			t.Fatalf("Expected the probe to succeed, got %v", err)
+		}
+		if state := metricCounts(t, m.CircuitBreakerState, "name")["context_enrichment"]; state != 0 {
+			t.Errorf("Expected the breaker to close after the probe, got %v", state)
+		}
+	})
+
+	t.Run("client errors do not open the breaker", func(t *testing.T) {
+		builder := newBuilder(nil)
+		atomic.StoreInt32(&status, http.StatusNotFound)
+		atomic.StoreInt32(&requests, 0)
+
+		for i := 0; i < 4; i++ {
+			if _, err := builder.fetchFromMicroservice(ctx, orgID, "order.details", execContext); err == nil || errors.Is(err, circuitbreaker.ErrOpen) {
+				t.Fatalf("Expected the 404 to be returned, got %v", err)
+			}
+		}
+		if got := atomic.LoadInt32(&requests); got != 4 {
+			t.Errorf("Expected every request to be sent, got %d", got)
+		}
+	})
+}
+
+func TestLoadResource_Metrics(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "paid"}`))
+	}))
+	defer server.Close()
+
+	redisClient := newUnreachableRedisClient()
+	cfg := getTestContextEnrichmentConfig()
+	cfg.Enabled = true
+	cfg.BaseURL = server.URL
+	cfg.MaxRetries = 0
+
+	// Unregistered collectors, so the test doesn't touch the default registry
+	m := &metrics.Metrics{
+		ContextCacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "cache_requests"}, []string{"resource", "result"}),
+		ContextFetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "fetch_duration"}, []string{"resource"}),
+	}
+	builder := NewContextBuilder(redisClient, log, cfg, m)
+
+	data, _, err := builder.loadResource(ctx, uuid.New(), "order.details", map[string]interface{}{"order_id": "ord-1"})
+	if err != nil {
+		t.Fatalf("loadResource failed: %v", err)
+	}
+	if data["status"] != "paid" {
+		t.Errorf("Expected data from the microservice, got %v", data)
+	}
+
+	if counts := metricCounts(t, m.ContextCacheRequests, "resource", "result"); counts["order.details/error"] != 1 || len(counts) != 1 {
+		t.Errorf("Expected one cache error for order.details, got %v", counts)
+	}
+	if counts := metricCounts(t, m.ContextFetchDuration, "resource"); counts["order.details"] != 1 {
+		t.Errorf("Expected one fetch observation for order.details, got %v", counts)
+	}
+
+	t.Run("nil metrics are skipped", func(t *testing.T) {
+		builder := NewContextBuilder(redisClient, log, cfg, nil)
+		if _, _, err := builder.loadResource(ctx, uuid.New(), "order.details", map[string]interface{}{"order_id": "ord-1"}); err != nil {
+			t.Fatalf("loadResource failed: %v", err)
+		}
+	})
+}
+
 func TestEnrichContext(t *testing.T) {
 	log := logger.NewForTesting()
 	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
 	cfg := getTestContextEnrichmentConfig()
-	builder := NewContextBuilder(redisClient, log, cfg)
+	cfg.ComputedFields = map[string]string{
+		"order_is_high_value":   "order.total >= 10000.0",
+		"order_is_medium_value": "order.total >= 1000.0 && order.total < 10000.0",
+		"order_item_count":      "order.items.size()",
+	}
+	builder := NewContextBuilder(redisClient, log, cfg, nil)
 	ctx := context.Background()
 
 	t.Run("adds computed fields", func(t *testing.T) {
@@ -148,10 +932,11 @@ func TestEnrichContext(t *testing.T) {
 			"order": map[string]interface{}{
 				"id":    "ord-123",
 				"total": 1500.0,
+				"items": []interface{}{"a", "b"},
 			},
 		}
 
-		err := builder.EnrichContext(ctx, execContext)
+		err := builder.EnrichContext(ctx, execContext, models.ContextDefinition{})
 
 		if err != nil {
 			t.Fatalf("EnrichContext failed: %v", err)
@@ -181,15 +966,65 @@ func TestEnrichContext(t *testing.T) {
 			t.Error("current_hour should be computed")
 		}
 
-		if _, ok := computed["order_is_medium_value"]; !ok {
-			t.Error("order_is_medium_value should be computed")
+		if _, ok := computed["current_day_of_week"]; !ok {
+			t.Error("current_day_of_week should be computed")
+		}
+
+		if computed["order_is_medium_value"] != true {
+			t.Errorf("Expected order_is_medium_value true, got %v", computed["order_is_medium_value"])
+		}
+
+		if computed["order_is_high_value"] != false {
+			t.Errorf("Expected order_is_high_value false, got %v", computed["order_is_high_value"])
+		}
+
+		if computed["order_item_count"] != int64(2) {
+			t.Errorf("Expected order_item_count 2, got %v (%T)", computed["order_item_count"], computed["order_item_count"])
+		}
+	})
+
+	t.Run("workflow fields replace configured fields", func(t *testing.T) {
+		execContext := map[string]interface{}{
+			"order": map[string]interface{}{"total": 1500.0},
+		}
+		contextDef := models.ContextDefinition{
+			Computed: map[string]string{"order_is_high_value": "order.total >= 1000.0"},
+		}
+
+		if err := builder.EnrichContext(ctx, execContext, contextDef); err != nil {
+			t.Fatalf("EnrichContext failed: %v", err)
+		}
+
+		computed := execContext["_computed"].(map[string]interface{})
+		if computed["order_is_high_value"] != true {
+			t.Errorf("Expected the workflow's order_is_high_value, got %v", computed["order_is_high_value"])
+		}
+	})
+
+	t.Run("skips fields whose entity is missing", func(t *testing.T) {
+		execContext := map[string]interface{}{
+			"_computed": map[string]interface{}{"order_is_high_value": true},
+		}
+
+		if err := builder.EnrichContext(ctx, execContext, models.ContextDefinition{}); err != nil {
+			t.Fatalf("EnrichContext failed: %v", err)
+		}
+
+		computed := execContext["_computed"].(map[string]interface{})
+		for _, name := range []string{"order_is_high_value", "order_is_medium_value", "order_item_count"} {
+			if _, ok := computed[name]; ok {
+				t.Errorf("%s should not be computed without an order", name)
+			}
+		}
+		if _, ok := computed["current_hour"]; !ok {
+			t.Error("current_hour should still be computed")
 		}
 	})
 
 	t.Run("handles empty context", func(t *testing.T) {
 		execContext := map[string]interface{}{}
 
-		err := builder.EnrichContext(ctx, execContext)
+		err := builder.EnrichContext(ctx, execContext, models.ContextDefinition{})
 
 		if err != nil {
 			t.Fatalf("EnrichContext failed: %v", err)
@@ -205,7 +1040,7 @@ func TestBuildCacheKey(t *testing.T) {
 	log := logger.NewForTesting()
 	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
 	cfg := getTestContextEnrichmentConfig()
-	builder := NewContextBuilder(redisClient, log, cfg)
+	builder := NewContextBuilder(redisClient, log, cfg, nil)
 	orgID := uuid.MustParse("12345678-1234-1234-1234-123456789012")
 
 	t.Run("builds key for order.details", func(t *testing.T) {
@@ -215,7 +1050,10 @@ func TestBuildCacheKey(t *testing.T) {
 			},
 		}
 
-		key := builder.buildCacheKey(orgID, "order.details", context)
+		key, err := builder.buildCacheKey(orgID, "order.details", context)
+		if err != nil {
+			t.Fatalf("buildCacheKey failed: %v", err)
+		}
 
 		expectedKey := fmt.Sprintf("context:%s:order.details:ord-123", orgID.String())
 		if key != expectedKey {
@@ -230,7 +1068,10 @@ func TestBuildCacheKey(t *testing.T) {
 			},
 		}
 
-		key := builder.buildCacheKey(orgID, "customer.history", context)
+		key, err := builder.buildCacheKey(orgID, "customer.history", context)
+		if err != nil {
+			t.Fatalf("buildCacheKey failed: %v", err)
+		}
 
 		expectedKey := fmt.Sprintf("context:%s:customer.history:cust-456", orgID.String())
 		if key != expectedKey {
@@ -238,19 +1079,157 @@ func TestBuildCacheKey(t *testing.T) {
 		}
 	})
 
-	t.Run("builds fallback key for unknown resource", func(t *testing.T) {
-		context := map[string]interface{}{}
+	t.Run("same entity produces the same key for every mapped resource", func(t *testing.T) {
+		resources := map[string]string{
+			"order.details":     "order",
+			"customer.history":  "customer",
+			"product.inventory": "product",
+			"payment.status":    "payment",
+			"shipment.tracking": "shipment",
+			"user.preferences":  "user",
+			"subscription.info": "subscription",
+			"invoice.details":   "invoice",
+		}
+
+		for resource, entity := range resources {
+			nested := map[string]interface{}{entity: map[string]interface{}{"id": entity + "-1"}}
+			flat := map[string]interface{}{entity + "_id": entity + "-1", "unrelated": "changes"}
+
+			first, err := builder.buildCacheKey(orgID, resource, nested)
+			if err != nil {
+				t.Fatalf("buildCacheKey failed for %s: %v", resource, err)
+			}
+			second, err := builder.buildCacheKey(orgID, resource, nested)
+			if err != nil {
+				t.Fatalf("buildCacheKey failed for %s: %v", resource, err)
+			}
+			fromFlat, err := builder.buildCacheKey(orgID, resource, flat)
+			if err != nil {
+				t.Fatalf("buildCacheKey failed for %s: %v", resource, err)
+			}
+
+			expectedKey := fmt.Sprintf("context:%s:%s:%s-1", orgID.String(), resource, entity)
+			if first != expectedKey || second != expectedKey || fromFlat != expectedKey {
+				t.Errorf("Expected %s for every load of %s, got %s, %s, %s", expectedKey, resource, first, second, fromFlat)
+			}
+		}
+	})
+
+	t.Run("no key without an identifier", func(t *testing.T) {
+		if key, err := builder.buildCacheKey(orgID, "order.details", map[string]interface{}{}); err == nil {
+			t.Errorf("Expected an error for a missing identifier, got key %s", key)
+		}
+		if key, err := builder.buildCacheKey(orgID, "unknown.resource", map[string]interface{}{}); err == nil {
+			t.Errorf("Expected an error for an unknown entity type, got key %s", key)
+		}
+	})
+}
+
+func TestMergeIntoContext(t *testing.T) {
+	builder := NewContextBuilder(nil, logger.NewForTesting(), getTestContextEnrichmentConfig(), nil)
+	data := map[string]interface{}{"risk_score": 42.0}
+
+	t.Run("nests data under the resource path", func(t *testing.T) {
+		payloadOrder := map[string]interface{}{"id": "ord-1"}
+		execContext := map[string]interface{}{"order": payloadOrder}
+
+		builder.mergeIntoContext(execContext, "order.details", data)
+
+		value, err := NewPathResolver().Resolve("order.details.risk_score", execContext)
+		if err != nil || value != 42.0 {
+			t.Errorf("Expected order.details.risk_score to resolve to 42, got %v (%v)", value, err)
+		}
+		order := execContext["order"].(map[string]interface{})
+		if order["id"] != "ord-1" {
+			t.Errorf("Expected existing order fields to be kept, got %v", order)
+		}
+		if _, ok := payloadOrder["details"]; ok {
+			t.Error("Expected the original payload map not to be modified")
+		}
+		if _, ok := execContext["order.details"]; ok {
+			t.Error("Expected no flat order.details key")
+		}
+	})
+
+	t.Run("creates missing levels and descends into JSONB", func(t *testing.T) {
+		execContext := map[string]interface{}{"customer": models.JSONB{"id": "cust-1"}}
+
+		builder.mergeIntoContext(execContext, "customer.profile.history", data)
+		builder.mergeIntoContext(execContext, "shipment.tracking", data)
+
+		for _, path := range []string{"customer.profile.history.risk_score", "shipment.tracking.risk_score", "customer.id"} {
+			if _, err := NewPathResolver().Resolve(path, execContext); err != nil {
+				t.Errorf("Expected %s to resolve: %v", path, err)
+			}
+		}
+	})
+
+	t.Run("keeps a non-map value and falls back to the flat key", func(t *testing.T) {
+		execContext := map[string]interface{}{"order": "ord-1"}
 
-		key := builder.buildCacheKey(orgID, "unknown.resource", context)
+		builder.mergeIntoContext(execContext, "order.details", data)
 
-		if len(key) == 0 {
-			t.Error("Key should not be empty")
+		if execContext["order"] != "ord-1" {
+			t.Errorf("Expected order to keep its value, got %v", execContext["order"])
 		}
+		if flat, ok := execContext["order.details"].(map[string]interface{}); !ok || flat["risk_score"] != 42.0 {
+			t.Errorf("Expected data under the flat key, got %v", execContext["order.details"])
+		}
+	})
+
+	t.Run("skips empty data", func(t *testing.T) {
+		execContext := map[string]interface{}{}
+
+		builder.mergeIntoContext(execContext, "order.details", map[string]interface{}{})
 
-		// Should contain the organization ID and resource name
-		expectedPrefix := fmt.Sprintf("context:%s:unknown.resource:", orgID.String())
-		if !strings.HasPrefix(key, expectedPrefix) {
-			t.Errorf("Key should start with %s, got %s", expectedPrefix, key)
+		if len(execContext) != 0 {
+			t.Errorf("Expected no change, got %v", execContext)
 		}
 	})
 }
+
+func TestCloneContext(t *testing.T) {
+	original := map[string]interface{}{
+		"order": map[string]interface{}{
+			"id":    "ord_1",
+			"items": []interface{}{map[string]interface{}{"sku": "A1"}},
+		},
+		"tags":  []string{"vip"},
+		"extra": models.JSONB{"source": "api"},
+		"_meta": map[string]interface{}{"enriched_at": "now"},
+	}
+
+	clone := cloneContext(original)
+
+	// Mutate the original at every level
+	original["order"].(map[string]interface{})["id"] = "ord_2"
+	original["order"].(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})["sku"] = "B2"
+	original["tags"].([]string)[0] = "regular"
+	original["extra"].(models.JSONB)["source"] = "webhook"
+	original["_meta"].(map[string]interface{})["enriched_at"] = "later"
+	original["new_key"] = true
+
+	order := clone["order"].(map[string]interface{})
+	if order["id"] != "ord_1" {
+		t.Errorf("Expected cloned order id to be unchanged, got %v", order["id"])
+	}
+	if sku := order["items"].([]interface{})[0].(map[string]interface{})["sku"]; sku != "A1" {
+		t.Errorf("Expected cloned nested item to be unchanged, got %v", sku)
+	}
+	if clone["tags"].([]string)[0] != "vip" {
+		t.Error("Expected cloned string slice to be unchanged")
+	}
+	if extra, ok := clone["extra"].(models.JSONB); !ok || extra["source"] != "api" {
+		t.Errorf("Expected JSONB to be cloned with its type preserved, got %#v", clone["extra"])
+	}
+	if meta, ok := clone["_meta"].(map[string]interface{}); !ok || meta["enriched_at"] != "now" {
+		t.Errorf("Expected _meta to be cloned, got %#v", clone["_meta"])
+	}
+	if _, exists := clone["new_key"]; exists {
+		t.Error("Expected keys added later not to appear in the clone")
+	}
+
+	if cloneContext(nil) != nil {
+		t.Error("Expected nil clone for nil context")
+	}
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/correlation"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestExecutionCorrelationID(t *testing.T) {
+	log := logger.NewForTesting()
+	executor := NewWorkflowExecutor(redis.NewClient(&redis.Options{Addr: "localhost:6379"}), &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(correlation.Header)
+	}))
+	defer server.Close()
+
+	workflow := &models.Workflow{
+		ID:   uuid.New(),
+		Name: "Notify fulfilment",
+		Definition: models.WorkflowDefinition{
+			Steps: []models.Step{
+				{ID: "notify", Type: "execute", Execute: []models.ExecuteAction{{Type: "webhook", URL: server.URL, Method: "POST"}}},
+			},
+		},
+	}
+
+	t.Run("taken from the context and forwarded to webhooks", func(t *testing.T) {
+		ctx := correlation.WithID(context.Background(), "req-42")
+		execution, err := executor.Execute(ctx, uuid.New(), workflow, "order.created", map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if execution.CorrelationID != "req-42" {
+			t.Errorf("Expected correlation ID req-42 on the execution, got %q", execution.CorrelationID)
+		}
+		if received != "req-42" {
+			t.Errorf("Expected the webhook to receive correlation ID req-42, got %q", received)
+		}
+	})
+
+	t.Run("generated when the context has none", func(t *testing.T) {
+		execution, err := executor.Execute(context.Background(), uuid.New(), workflow, "order.created", map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if execution.CorrelationID == "" || received != execution.CorrelationID {
+			t.Errorf("Expected a generated correlation ID forwarded to the webhook, got %q and %q", execution.CorrelationID, received)
+		}
+	})
+}
+
+func TestRouteEventCorrelationID(t *testing.T) {
+	log := logger.NewForTesting()
+	executor := NewWorkflowExecutor(redis.NewClient(&redis.Options{Addr: "localhost:6379"}), &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+	router := NewEventRouter(&mockWorkflowRepo{}, &mockEventRepo{}, executor, log)
+
+	ctx := correlation.WithID(context.Background(), "req-42")
+	event, err := router.RouteEvent(ctx, uuid.New(), "order.created", "api", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("RouteEvent failed: %v", err)
+	}
+	if event.CorrelationID != "req-42" {
+		t.Errorf("Expected correlation ID req-42 on the event, got %q", event.CorrelationID)
+	}
+
+	event, err = router.RouteEvent(context.Background(), uuid.New(), "order.created", "api", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("RouteEvent failed: %v", err)
+	}
+	if event.CorrelationID == "" {
+		t.Error("Expected a correlation ID to be generated for the event")
+	}
+}
@@ -1,95 +1,183 @@
 package engine
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
 )
 
 // Evaluator handles condition evaluation
-type Evaluator struct{}
+type Evaluator struct {
+	expressions *expressionCache // shared by every evaluator derived from this one
+	paths       *PathResolver    // nil parses every path on each use
+}
 
 // NewEvaluator creates a new condition evaluator
 func NewEvaluator() *Evaluator {
-	return &Evaluator{}
+	return &Evaluator{expressions: &expressionCache{}}
+}
+
+// WithPathResolver returns an evaluator that shares this one's compiled expressions
+// but parses variable paths through paths, typically one resolver per execution
+func (e *Evaluator) WithPathResolver(paths *PathResolver) *Evaluator {
+	return &Evaluator{expressions: e.expressions, paths: paths}
 }
 
-// EvaluateCondition evaluates a condition against context data
+// EvaluateCondition evaluates a condition against context data.
+// A condition skipped by its on_missing policy counts as satisfied
 func (e *Evaluator) EvaluateCondition(condition *models.Condition, context map[string]interface{}) (bool, error) {
-	if condition == nil {
+	result, skipped, err := e.evaluate(condition, context)
+	if err != nil {
+		return false, err
+	}
+	if skipped {
 		return true, nil
 	}
+	return result, nil
+}
+
+// evaluate evaluates a condition and reports whether it was skipped: a leaf whose field is
+// missing under the "skip" policy, or a group in which every clause was skipped
+func (e *Evaluator) evaluate(condition *models.Condition, context map[string]interface{}) (bool, bool, error) {
+	if condition == nil {
+		return true, false, nil
+	}
 
-	// Handle AND conditions
+	// Expression conditions are evaluated as CEL instead of field/operator/value
+	if condition.Expression != "" {
+		result, err := e.evaluateExpression(condition.Expression, context)
+		return result, false, err
+	}
+
+	// Handle AND conditions (short-circuits on the first false clause; skipped clauses are ignored)
 	if len(condition.And) > 0 {
+		skipped := true
 		for _, subCondition := range condition.And {
-			result, err := e.EvaluateCondition(&subCondition, context)
+			result, subSkipped, err := e.evaluate(&subCondition, context)
 			if err != nil {
-				return false, err
+				return false, false, err
+			}
+			if subSkipped {
+				continue
 			}
+			skipped = false
 			if !result {
-				return false, nil
+				return false, false, nil
 			}
 		}
-		return true, nil
+		return true, skipped, nil
 	}
 
-	// Handle OR conditions
+	// Handle OR conditions (short-circuits on the first true clause; skipped clauses are ignored)
 	if len(condition.Or) > 0 {
+		skipped := true
 		for _, subCondition := range condition.Or {
-			result, err := e.EvaluateCondition(&subCondition, context)
+			result, subSkipped, err := e.evaluate(&subCondition, context)
 			if err != nil {
-				return false, err
+				return false, false, err
+			}
+			if subSkipped {
+				continue
 			}
+			skipped = false
 			if result {
-				return true, nil
+				return true, false, nil
 			}
 		}
-		return false, nil
+		return false, skipped, nil
 	}
 
 	// Evaluate single condition
 	fieldValue, err := e.getFieldValue(condition.Field, context)
 	if err != nil {
-		return false, fmt.Errorf("failed to get field value: %w", err)
+		if !errors.Is(err, ErrFieldNotFound) {
+			return false, false, fmt.Errorf("failed to get field value: %w", err)
+		}
+
+		// An absent field is the answer for exists; for every other operator the policy decides
+		if condition.Operator == "exists" {
+			return false, false, nil
+		}
+		switch condition.OnMissing {
+		case models.OnMissingFalse:
+			return false, false, nil
+		case models.OnMissingSkip:
+			return false, true, nil
+		default:
+			return false, false, fmt.Errorf("failed to get field value: %w", err)
+		}
 	}
 
-	return e.compareValues(fieldValue, condition.Operator, condition.Value)
+	result, err := e.compareValues(fieldValue, condition.Operator, condition.Value)
+	return result, false, err
 }
 
-// getFieldValue extracts a field value from context using dot notation
-// Example: "order.total" retrieves context["order"]["total"]
-func (e *Evaluator) getFieldValue(field string, context map[string]interface{}) (interface{}, error) {
-	parts := strings.Split(field, ".")
-	current := context
+// ResolveVariable resolves a dot-notation path against the context.
+// found is false when the path is absent; a present null value returns (nil, true)
+func (e *Evaluator) ResolveVariable(path string, context map[string]interface{}) (value interface{}, found bool) {
+	value, err := e.getFieldValue(path, context)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
 
-	for i, part := range parts {
-		val, exists := current[part]
-		if !exists {
-			return nil, fmt.Errorf("field not found: %s", field)
-		}
+// EvaluateSwitch resolves the switch field and returns the next step of the
+// first matching case, or the default when no case matches
+func (e *Evaluator) EvaluateSwitch(sw *models.SwitchStep, context map[string]interface{}) (string, error) {
+	if sw == nil {
+		return "", fmt.Errorf("switch step has no switch configuration")
+	}
 
-		// Last part - return the value
-		if i == len(parts)-1 {
-			return val, nil
-		}
+	field := strings.TrimSpace(sw.Field)
+	if strings.HasPrefix(field, "{{") && strings.HasSuffix(field, "}}") {
+		field = strings.TrimSpace(field[2 : len(field)-2])
+	}
+	if field == "" {
+		return "", fmt.Errorf("switch step has no field defined")
+	}
 
-		// Navigate deeper
-		nextMap, ok := val.(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("invalid path: %s is not an object", part)
+	value, err := e.getFieldValue(field, context)
+	if err != nil {
+		// A missing field cannot match any case
+		return sw.Default, nil
+	}
+
+	for _, c := range sw.Cases {
+		if e.equals(value, c.Value) {
+			return c.Next, nil
 		}
-		current = nextMap
 	}
 
-	return nil, fmt.Errorf("field not found: %s", field)
+	return sw.Default, nil
+}
+
+// ResolvePath resolves a path with nested keys and array indexes against the context
+// Example: "order.fulfillments[0].items"
+func (e *Evaluator) ResolvePath(path string, context map[string]interface{}) (interface{}, error) {
+	return e.getFieldValue(path, context)
+}
+
+// getFieldValue extracts a field value from context using dot notation
+// Example: "order.total" retrieves context["order"]["total"], "items[0].sku" indexes into a list
+func (e *Evaluator) getFieldValue(field string, context map[string]interface{}) (interface{}, error) {
+	return e.paths.Resolve(field, context)
 }
 
 // compareValues compares two values using the specified operator
 func (e *Evaluator) compareValues(fieldValue interface{}, operator string, conditionValue interface{}) (bool, error) {
 	switch operator {
+	case "exists":
+		// Reaching comparison means the field resolved, even if its value is null
+		return true, nil
+
+	case "is_null":
+		return fieldValue == nil, nil
+
 	case "eq", "==":
 		return e.equals(fieldValue, conditionValue), nil
 
@@ -119,9 +207,30 @@ func (e *Evaluator) compareValues(fieldValue interface{}, operator string, condi
 	case "in":
 		return e.in(fieldValue, conditionValue)
 
+	case "not_in":
+		result, err := e.in(fieldValue, conditionValue)
+		if err != nil {
+			return false, err
+		}
+		return !result, nil
+
 	case "contains":
 		return e.contains(fieldValue, conditionValue)
 
+	case "starts_with":
+		field, prefix, err := stringOperands("starts_with", fieldValue, conditionValue)
+		if err != nil {
+			return false, err
+		}
+		return strings.HasPrefix(field, prefix), nil
+
+	case "ends_with":
+		field, suffix, err := stringOperands("ends_with", fieldValue, conditionValue)
+		if err != nil {
+			return false, err
+		}
+		return strings.HasSuffix(field, suffix), nil
+
 	case "regex":
 		return e.matchesRegex(fieldValue, conditionValue)
 
@@ -131,34 +240,63 @@ func (e *Evaluator) compareValues(fieldValue interface{}, operator string, condi
 }
 
 // equals checks if two values are equal
+// Numbers of any type compare by value (1 == 1.0); everything else compares by string form, so "1" == 1
 func (e *Evaluator) equals(a, b interface{}) bool {
+	aFloat, aOk := toFloat64(a)
+	bFloat, bOk := toFloat64(b)
+	if aOk && bOk {
+		return aFloat == bFloat
+	}
 	return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
 }
 
-// greaterThan checks if a > b (for numbers)
+// greaterThan checks if a > b (for numbers, including numeric strings)
 func (e *Evaluator) greaterThan(a, b interface{}) (bool, error) {
-	aFloat, aOk := toFloat64(a)
-	bFloat, bOk := toFloat64(b)
-
-	if !aOk || !bOk {
-		return false, fmt.Errorf("cannot compare non-numeric values: %v > %v", a, b)
+	aFloat, bFloat, err := numericOperands(">", a, b)
+	if err != nil {
+		return false, err
 	}
 
 	return aFloat > bFloat, nil
 }
 
-// lessThan checks if a < b (for numbers)
+// lessThan checks if a < b (for numbers, including numeric strings)
 func (e *Evaluator) lessThan(a, b interface{}) (bool, error) {
-	aFloat, aOk := toFloat64(a)
-	bFloat, bOk := toFloat64(b)
-
-	if !aOk || !bOk {
-		return false, fmt.Errorf("cannot compare non-numeric values: %v < %v", a, b)
+	aFloat, bFloat, err := numericOperands("<", a, b)
+	if err != nil {
+		return false, err
 	}
 
 	return aFloat < bFloat, nil
 }
 
+// numericOperands coerces both sides of an ordering comparison to numbers.
+// Numeric strings such as "1500" are parsed; any other non-numeric value is an error
+func numericOperands(operator string, a, b interface{}) (float64, float64, error) {
+	aFloat, aOk := toNumber(a)
+	if !aOk {
+		return 0, 0, fmt.Errorf("cannot compare non-numeric value %v (%T) with %s", a, a, operator)
+	}
+	bFloat, bOk := toNumber(b)
+	if !bOk {
+		return 0, 0, fmt.Errorf("cannot compare with non-numeric value %v (%T) using %s", b, b, operator)
+	}
+	return aFloat, bFloat, nil
+}
+
+// stringOperands requires both the field and the condition value to be strings
+func stringOperands(operator string, fieldValue, conditionValue interface{}) (string, string, error) {
+	field, ok := fieldValue.(string)
+	if !ok {
+		return "", "", fmt.Errorf("'%s' operator requires a string field, got %T", operator, fieldValue)
+	}
+	value, ok := conditionValue.(string)
+	if !ok {
+		return "", "", fmt.Errorf("'%s' operator requires a string value, got %T", operator, conditionValue)
+	}
+	return field, value, nil
+}
+
 // in checks if a value is in a list
 func (e *Evaluator) in(value interface{}, list interface{}) (bool, error) {
 	switch listVal := list.(type) {
@@ -178,7 +316,7 @@ func (e *Evaluator) in(value interface{}, list interface{}) (bool, error) {
 		}
 		return false, nil
 	default:
-		return false, fmt.Errorf("'in' operator requires a list, got %T", list)
+		return false, fmt.Errorf("'in' and 'not_in' operators require a list value, got %T", list)
 	}
 }
 
@@ -210,8 +348,10 @@ func (e *Evaluator) contains(haystack interface{}, needle interface{}) (bool, er
 
 // matchesRegex checks if a string matches a regex pattern
 func (e *Evaluator) matchesRegex(value interface{}, pattern interface{}) (bool, error) {
-	valueStr := fmt.Sprintf("%v", value)
-	patternStr := fmt.Sprintf("%v", pattern)
+	valueStr, patternStr, err := stringOperands("regex", value, pattern)
+	if err != nil {
+		return false, err
+	}
 
 	matched, err := regexp.MatchString(patternStr, valueStr)
 	if err != nil {
@@ -221,6 +361,15 @@ func (e *Evaluator) matchesRegex(value interface{}, pattern interface{}) (bool,
 	return matched, nil
 }
 
+// toNumber converts numeric types and numeric strings to float64
+func toNumber(value interface{}) (float64, bool) {
+	if str, ok := value.(string); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		return f, err == nil
+	}
+	return toFloat64(value)
+}
+
 // toFloat64 converts various numeric types to float64
 func toFloat64(value interface{}) (float64, bool) {
 	switch v := value.(type) {
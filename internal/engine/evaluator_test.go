@@ -275,6 +275,90 @@ func TestEvaluateCondition(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			name:      "not_in list",
+			condition: &models.Condition{Field: "status", Operator: "not_in", Value: []interface{}{"blocked", "fraud"}},
+			context:   map[string]interface{}{"status": "active"},
+			expected:  true,
+		},
+		{
+			name:      "not_in list member",
+			condition: &models.Condition{Field: "status", Operator: "not_in", Value: []interface{}{"active", "fraud"}},
+			context:   map[string]interface{}{"status": "active"},
+			expected:  false,
+		},
+		{
+			name:      "in matches numbers across types",
+			condition: &models.Condition{Field: "priority", Operator: "in", Value: []interface{}{1.0, 2.0}},
+			context:   map[string]interface{}{"priority": 2},
+			expected:  true,
+		},
+		{
+			name:      "in with non-list value",
+			condition: &models.Condition{Field: "status", Operator: "in", Value: "active"},
+			context:   map[string]interface{}{"status": "active"},
+			shouldErr: true,
+		},
+		{
+			name:      "contains list item",
+			condition: &models.Condition{Field: "tags", Operator: "contains", Value: "vip"},
+			context:   map[string]interface{}{"tags": []interface{}{"new", "vip"}},
+			expected:  true,
+		},
+		{
+			name:      "contains on number",
+			condition: &models.Condition{Field: "total", Operator: "contains", Value: "1"},
+			context:   map[string]interface{}{"total": 150.0},
+			shouldErr: true,
+		},
+		{
+			name:      "starts_with",
+			condition: &models.Condition{Field: "sku", Operator: "starts_with", Value: "GIFT-"},
+			context:   map[string]interface{}{"sku": "GIFT-100"},
+			expected:  true,
+		},
+		{
+			name:      "ends_with",
+			condition: &models.Condition{Field: "email", Operator: "ends_with", Value: "@example.com"},
+			context:   map[string]interface{}{"email": "user@other.com"},
+			expected:  false,
+		},
+		{
+			name:      "starts_with on non-string field",
+			condition: &models.Condition{Field: "sku", Operator: "starts_with", Value: "1"},
+			context:   map[string]interface{}{"sku": 100},
+			shouldErr: true,
+		},
+		{
+			name:      "regex on non-string field",
+			condition: &models.Condition{Field: "total", Operator: "regex", Value: "^1"},
+			context:   map[string]interface{}{"total": 150.0},
+			shouldErr: true,
+		},
+		{
+			name:      "invalid regex pattern",
+			condition: &models.Condition{Field: "email", Operator: "regex", Value: "("},
+			context:   map[string]interface{}{"email": "user@example.com"},
+			shouldErr: true,
+		},
+		{
+			name:      "gt coerces numeric string",
+			condition: &models.Condition{Field: "order.total", Operator: "gt", Value: 1000},
+			context:   map[string]interface{}{"order": map[string]interface{}{"total": "1500.50"}},
+			expected:  true,
+		},
+		{
+			name:      "gt on non-numeric string",
+			condition: &models.Condition{Field: "order.total", Operator: "gt", Value: 1000},
+			context:   map[string]interface{}{"order": map[string]interface{}{"total": "lots"}},
+			shouldErr: true,
+		},
+		{
+			name:      "eq compares numbers by value",
+			condition: &models.Condition{Field: "quantity", Operator: "eq", Value: 2.0},
+			context:   map[string]interface{}{"quantity": int64(2)},
+			expected:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -385,3 +469,389 @@ func TestGetFieldValue(t *testing.T) {
 		})
 	}
 }
+
+func TestEvaluateSwitch(t *testing.T) {
+	evaluator := NewEvaluator()
+
+	sw := &models.SwitchStep{
+		Field: "order.status",
+		Cases: []models.SwitchCase{
+			{Value: "pending", Next: "handle_pending"},
+			{Value: "shipped", Next: "handle_shipped"},
+			{Value: "shipped", Next: "unreachable"},
+		},
+		Default: "handle_other",
+	}
+
+	tests := []struct {
+		name      string
+		sw        *models.SwitchStep
+		context   map[string]interface{}
+		expected  string
+		shouldErr bool
+	}{
+		{
+			name:     "matches case",
+			sw:       sw,
+			context:  map[string]interface{}{"order": map[string]interface{}{"status": "pending"}},
+			expected: "handle_pending",
+		},
+		{
+			name:     "first matching case wins",
+			sw:       sw,
+			context:  map[string]interface{}{"order": map[string]interface{}{"status": "shipped"}},
+			expected: "handle_shipped",
+		},
+		{
+			name:     "falls back to default",
+			sw:       sw,
+			context:  map[string]interface{}{"order": map[string]interface{}{"status": "cancelled"}},
+			expected: "handle_other",
+		},
+		{
+			name:     "missing field uses default",
+			sw:       sw,
+			context:  map[string]interface{}{},
+			expected: "handle_other",
+		},
+		{
+			name: "expression field with numeric case",
+			sw: &models.SwitchStep{
+				Field: "{{order.priority}}",
+				Cases: []models.SwitchCase{{Value: 1, Next: "urgent"}},
+			},
+			context:  map[string]interface{}{"order": map[string]interface{}{"priority": 1}},
+			expected: "urgent",
+		},
+		{
+			name:      "missing switch config",
+			sw:        nil,
+			context:   map[string]interface{}{},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := evaluator.EvaluateSwitch(tt.sw, tt.context)
+
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEvaluateCondition_Expression(t *testing.T) {
+	evaluator := NewEvaluator()
+
+	context := map[string]interface{}{
+		"order": map[string]interface{}{
+			"total": 1500.0,
+			"items": []interface{}{"sku-1", "sku-2"},
+		},
+		"customer": map[string]interface{}{
+			"tier": "gold",
+		},
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   bool
+		shouldErr  bool
+	}{
+		{
+			name:       "compound logic",
+			expression: `order.total > 1000 && customer.tier == "gold"`,
+			expected:   true,
+		},
+		{
+			name:       "compound logic false",
+			expression: `order.total > 1000 && customer.tier == "silver"`,
+			expected:   false,
+		},
+		{
+			name:       "list membership",
+			expression: `"sku-2" in order.items || size(order.items) > 5`,
+			expected:   true,
+		},
+		{
+			name:       "missing field",
+			expression: `order.discount > 0`,
+			shouldErr:  true,
+		},
+		{
+			name:       "non-boolean result",
+			expression: `order.total + 1`,
+			shouldErr:  true,
+		},
+		{
+			name:       "syntax error",
+			expression: `order.total >`,
+			shouldErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := evaluator.EvaluateCondition(&models.Condition{Expression: tt.expression}, context)
+
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected an error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("caches compiled programs by expression", func(t *testing.T) {
+		expression := `customer.tier == "gold"`
+		first, err := evaluator.compileExpression(expression)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		second, err := evaluator.compileExpression(expression)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if first != second {
+			t.Error("Expected the cached program to be reused")
+		}
+	})
+}
+
+func TestEvaluateCondition_Groups(t *testing.T) {
+	evaluator := NewEvaluator()
+
+	leaf := func(field, operator string, value interface{}) models.Condition {
+		return models.Condition{Field: field, Operator: operator, Value: value}
+	}
+	// An ordering comparison on a non-numeric field errors if it is ever evaluated
+	failing := leaf("customer.tier", "gt", 1)
+
+	context := map[string]interface{}{
+		"order":    map[string]interface{}{"total": 1500.0, "country": "US"},
+		"customer": map[string]interface{}{"tier": "gold", "verified": false},
+	}
+
+	tests := []struct {
+		name      string
+		condition *models.Condition
+		expected  bool
+		shouldErr bool
+	}{
+		{
+			name: "(A AND B) OR C with A and B true",
+			condition: &models.Condition{Or: []models.Condition{
+				{And: []models.Condition{leaf("order.total", "gt", 1000), leaf("customer.tier", "eq", "gold")}},
+				leaf("customer.verified", "eq", true),
+			}},
+			expected: true,
+		},
+		{
+			name: "(A AND B) OR C with only C false",
+			condition: &models.Condition{Or: []models.Condition{
+				{And: []models.Condition{leaf("order.total", "gt", 1000), leaf("customer.tier", "eq", "silver")}},
+				leaf("customer.verified", "eq", true),
+			}},
+			expected: false,
+		},
+		{
+			name: "four levels deep",
+			condition: &models.Condition{And: []models.Condition{
+				leaf("order.country", "eq", "US"),
+				{Or: []models.Condition{
+					leaf("customer.verified", "eq", true),
+					{And: []models.Condition{
+						leaf("order.total", "gte", 1500),
+						{Or: []models.Condition{
+							leaf("customer.tier", "in", []interface{}{"gold", "platinum"}),
+							leaf("order.total", "gt", 10000),
+						}},
+					}},
+				}},
+			}},
+			expected: true,
+		},
+		{
+			name:      "AND short-circuits on first false",
+			condition: &models.Condition{And: []models.Condition{leaf("order.country", "eq", "CA"), failing}},
+			expected:  false,
+		},
+		{
+			name:      "OR short-circuits on first true",
+			condition: &models.Condition{Or: []models.Condition{leaf("order.country", "eq", "US"), failing}},
+			expected:  true,
+		},
+		{
+			name:      "error in nested clause propagates",
+			condition: &models.Condition{Or: []models.Condition{{And: []models.Condition{leaf("order.country", "eq", "US"), failing}}}},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := evaluator.EvaluateCondition(tt.condition, context)
+
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected an error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEvaluateCondition_MissingFields(t *testing.T) {
+	evaluator := NewEvaluator()
+
+	context := map[string]interface{}{
+		"a": map[string]interface{}{
+			"present": "value",
+			"null":    nil,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		condition *models.Condition
+		expected  bool
+		shouldErr bool
+	}{
+		{name: "exists on present field", condition: &models.Condition{Field: "a.present", Operator: "exists"}, expected: true},
+		{name: "exists on null field", condition: &models.Condition{Field: "a.null", Operator: "exists"}, expected: true},
+		{name: "exists on missing nested path", condition: &models.Condition{Field: "a.b.c", Operator: "exists"}, expected: false},
+		{name: "exists under a null parent", condition: &models.Condition{Field: "a.null.c", Operator: "exists"}, expected: false},
+		{name: "is_null on null field", condition: &models.Condition{Field: "a.null", Operator: "is_null"}, expected: true},
+		{name: "is_null on present field", condition: &models.Condition{Field: "a.present", Operator: "is_null"}, expected: false},
+		{name: "is_null on missing field errors by default", condition: &models.Condition{Field: "a.b", Operator: "is_null"}, shouldErr: true},
+		{
+			name:      "missing nested path errors by default",
+			condition: &models.Condition{Field: "a.b.c", Operator: "eq", Value: "x"},
+			shouldErr: true,
+		},
+		{
+			name:      "missing nested path with error policy",
+			condition: &models.Condition{Field: "a.b.c", Operator: "eq", Value: "x", OnMissing: models.OnMissingError},
+			shouldErr: true,
+		},
+		{
+			name:      "missing nested path with false policy",
+			condition: &models.Condition{Field: "a.b.c", Operator: "neq", Value: "x", OnMissing: models.OnMissingFalse},
+			expected:  false,
+		},
+		{
+			name:      "missing nested path with skip policy passes alone",
+			condition: &models.Condition{Field: "a.b.c", Operator: "eq", Value: "x", OnMissing: models.OnMissingSkip},
+			expected:  true,
+		},
+		{
+			name: "skipped clause is ignored in AND",
+			condition: &models.Condition{And: []models.Condition{
+				{Field: "a.b.c", Operator: "eq", Value: "x", OnMissing: models.OnMissingSkip},
+				{Field: "a.present", Operator: "eq", Value: "other"},
+			}},
+			expected: false,
+		},
+		{
+			name: "skipped clause is ignored in OR",
+			condition: &models.Condition{Or: []models.Condition{
+				{Field: "a.b.c", Operator: "eq", Value: "x", OnMissing: models.OnMissingSkip},
+				{Field: "a.present", Operator: "eq", Value: "other"},
+			}},
+			expected: false,
+		},
+		{
+			name: "group of skipped clauses passes",
+			condition: &models.Condition{Or: []models.Condition{
+				{Field: "a.b.c", Operator: "eq", Value: "x", OnMissing: models.OnMissingSkip},
+				{Field: "a.b.d", Operator: "eq", Value: "y", OnMissing: models.OnMissingSkip},
+			}},
+			expected: true,
+		},
+		{
+			name:      "policy does not hide type errors",
+			condition: &models.Condition{Field: "a.present.c", Operator: "eq", Value: "x", OnMissing: models.OnMissingFalse},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := evaluator.EvaluateCondition(tt.condition, context)
+
+			if tt.shouldErr {
+				if err == nil {
+					t.Error("Expected an error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestResolveVariable(t *testing.T) {
+	evaluator := NewEvaluator()
+	context := map[string]interface{}{
+		"a": map[string]interface{}{"null": nil, "items": []interface{}{"x"}},
+	}
+
+	tests := []struct {
+		path          string
+		expectedFound bool
+	}{
+		{path: "a.null", expectedFound: true},
+		{path: "a.items[0]", expectedFound: true},
+		{path: "a.b.c", expectedFound: false},
+		{path: "a.null.c", expectedFound: false},
+		{path: "a.items[5]", expectedFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, found := evaluator.ResolveVariable(tt.path, context)
+			if found != tt.expectedFound {
+				t.Errorf("Expected found=%v, got %v", tt.expectedFound, found)
+			}
+		})
+	}
+}
@@ -2,21 +2,32 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"runtime/debug"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/correlation"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // WorkflowRepository defines the interface for workflow data access
 type WorkflowRepository interface {
 	GetWorkflowByID(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error)
+	GetByWorkflowID(ctx context.Context, organizationID uuid.UUID, workflowID string) (*models.Workflow, error)
 	ListWorkflows(ctx context.Context, organizationID uuid.UUID, enabled *bool, limit, offset int) ([]models.Workflow, int64, error)
 }
 
+// WorkflowVersionRepository defines the interface for loading recorded workflow definitions
+type WorkflowVersionRepository interface {
+	GetVersion(ctx context.Context, organizationID, workflowID uuid.UUID, version int) (*models.WorkflowVersion, error)
+}
+
 // EventRepository defines the interface for event persistence
 type EventRepository interface {
 	CreateEvent(ctx context.Context, event *models.Event) error
@@ -24,12 +35,51 @@ type EventRepository interface {
 	GetEventByID(ctx context.Context, organizationID, id uuid.UUID) (*models.Event, error)
 }
 
+// DeadLetterRepository defines the interface for persisting events that could not be processed
+type DeadLetterRepository interface {
+	CreateDeadLetterEvent(ctx context.Context, event *models.DeadLetterEvent) error
+	GetDeadLetterEvent(ctx context.Context, organizationID, id uuid.UUID) (*models.DeadLetterEvent, error)
+	ListDeadLetterEvents(ctx context.Context, organizationID uuid.UUID, replayed *bool, limit, offset int) ([]*models.DeadLetterEvent, int64, error)
+	MarkDeadLetterReplayed(ctx context.Context, organizationID, id uuid.UUID) (bool, error)
+}
+
+var (
+	// ErrWorkflowNotFound is returned when a workflow requested by ID cannot be loaded
+	ErrWorkflowNotFound = errors.New("workflow not found")
+
+	// ErrWorkflowVersionNotFound is returned when a manual trigger names, or a resumed execution
+	// started on, a version the workflow does not have
+	ErrWorkflowVersionNotFound = errors.New("workflow version not found")
+
+	// ErrWorkflowDisabled is returned when a disabled workflow is triggered manually
+	ErrWorkflowDisabled = errors.New("workflow is disabled")
+
+	// ErrExecutionNotRerunnable is returned when re-running an execution that did not fail, or was a dry run
+	ErrExecutionNotRerunnable = errors.New("execution is not rerunnable")
+
+	// ErrExecutionAlreadyRerun is returned when a failed execution has already been re-run
+	ErrExecutionAlreadyRerun = errors.New("execution has already been re-run")
+
+	// ErrDeadLetterUnavailable is returned when no dead-letter repository is configured
+	ErrDeadLetterUnavailable = errors.New("dead-letter queue is not available")
+
+	// ErrDeadLetterEventNotFound is returned when a dead-lettered event requested by ID cannot be loaded
+	ErrDeadLetterEventNotFound = errors.New("dead-letter event not found")
+
+	// ErrDeadLetterAlreadyReplayed is returned when a dead-lettered event has already been replayed
+	ErrDeadLetterAlreadyReplayed = errors.New("dead-letter event has already been replayed")
+)
+
 // EventRouter routes events to matching workflows
 type EventRouter struct {
 	workflowRepo WorkflowRepository
+	versionRepo  WorkflowVersionRepository
 	eventRepo    EventRepository
+	deadLetters  DeadLetterRepository
+	index        *WorkflowIndex
 	executor     *WorkflowExecutor
 	logger       *logger.Logger
+	routed       sync.WaitGroup // Executions RouteEvent started in the background
 }
 
 // NewEventRouter creates a new event router
@@ -47,6 +97,29 @@ func NewEventRouter(
 	}
 }
 
+// SetVersionRepository sets the store manual triggers load pinned workflow versions from (optional dependency)
+func (er *EventRouter) SetVersionRepository(versionRepo WorkflowVersionRepository) {
+	er.versionRepo = versionRepo
+}
+
+// SetDeadLetterRepository sets the store events that fail to route or run are kept in for replay (optional dependency)
+func (er *EventRouter) SetDeadLetterRepository(deadLetters DeadLetterRepository) {
+	er.deadLetters = deadLetters
+}
+
+// SetWorkflowIndex sets the in-memory index events are matched against instead of listing
+// workflows per event (optional dependency)
+func (er *EventRouter) SetWorkflowIndex(index *WorkflowIndex) {
+	er.index = index
+}
+
+// InvalidateWorkflows drops an organization's workflows from the index after they change
+func (er *EventRouter) InvalidateWorkflows(organizationID uuid.UUID) {
+	if er.index != nil {
+		er.index.Invalidate(organizationID)
+	}
+}
+
 // RouteEvent routes an event to matching workflows
 func (er *EventRouter) RouteEvent(
 	ctx context.Context,
@@ -55,7 +128,15 @@ func (er *EventRouter) RouteEvent(
 	source string,
 	payload map[string]interface{},
 ) (*models.Event, error) {
-	er.logger.Infof("Routing event: %s from %s for organization: %s", eventType, source, organizationID)
+	// An event that arrives without a correlation ID starts a new transaction
+	correlationID := correlation.FromContext(ctx)
+	if correlationID == "" {
+		correlationID = correlation.NewID()
+		ctx = correlation.WithID(ctx, correlationID)
+	}
+	log := er.logger.WithContext(ctx)
+
+	log.Infof("Routing event: %s from %s for organization: %s", eventType, source, organizationID)
 
 	// Create event record
 	event := &models.Event{
@@ -66,6 +147,7 @@ func (er *EventRouter) RouteEvent(
 		Source:         source,
 		Payload:        payload,
 		ReceivedAt:     time.Now(),
+		CorrelationID:  correlationID,
 	}
 
 	if err := er.eventRepo.CreateEvent(ctx, event); err != nil {
@@ -75,30 +157,36 @@ func (er *EventRouter) RouteEvent(
 	// Find matching workflows
 	workflows, err := er.findMatchingWorkflows(ctx, organizationID, eventType)
 	if err != nil {
-		er.logger.Errorf("Failed to find matching workflows: %v", err)
+		log.Errorf("Failed to find matching workflows: %v", err)
+		er.deadLetter(ctx, event, nil, models.DeadLetterReasonRoutingError, err.Error())
 		return event, err
 	}
 
+	workflows = er.filterWorkflows(workflows, eventType, payload)
+
 	if len(workflows) == 0 {
-		er.logger.Infof("No workflows found for event type: %s", eventType)
+		log.Infof("No workflows found for event type: %s", eventType)
 		now := time.Now()
 		event.ProcessedAt = &now
 		er.eventRepo.UpdateEvent(ctx, organizationID, event)
 		return event, nil
 	}
 
-	er.logger.Infof("Found %d matching workflows for event: %s", len(workflows), eventType)
+	log.Infof("Found %d matching workflows for event: %s", len(workflows), eventType)
 
 	// Execute matching workflows
 	triggeredWorkflows := make([]string, 0, len(workflows))
 
 	for _, workflow := range workflows {
-		er.logger.Infof("Triggering workflow: %s (ID: %s)", workflow.Name, workflow.ID)
+		log.Infof("Triggering workflow: %s (ID: %s)", workflow.Name, workflow.ID)
 
 		// Execute workflow asynchronously with panic recovery
+		er.routed.Add(1)
 		go func(wf models.Workflow) {
-			execCtx := context.Background()
-			er.safeExecuteWorkflow(execCtx, organizationID, &wf, eventType, payload)
+			defer er.routed.Done()
+			// Outlive the request that routed the event, but stay in its trace
+			execCtx := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+			er.safeExecuteWorkflow(execCtx, organizationID, &wf, event, ExecutionOptions{})
 		}(workflow)
 
 		triggeredWorkflows = append(triggeredWorkflows, workflow.WorkflowID)
@@ -110,24 +198,50 @@ func (er *EventRouter) RouteEvent(
 	event.ProcessedAt = &now
 
 	if err := er.eventRepo.UpdateEvent(ctx, organizationID, event); err != nil {
-		er.logger.Errorf("Failed to update event: %v", err)
+		log.Errorf("Failed to update event: %v", err)
 	}
 
 	return event, nil
 }
 
-// safeExecuteWorkflow executes a workflow with panic recovery
+// Drain waits for the executions routed events started to return, so events that could not
+// run, such as those turned away from a rate limit queue while the executor drains, are
+// dead-lettered before the process exits. It returns false if ctx is done first
+func (er *EventRouter) Drain(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		er.routed.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// safeExecuteWorkflow executes a workflow for an event with panic recovery. Events the workflow
+// panics on, or cannot start an execution for, are dead-lettered
 func (er *EventRouter) safeExecuteWorkflow(
 	ctx context.Context,
 	organizationID uuid.UUID,
 	workflow *models.Workflow,
-	eventType string,
-	payload map[string]interface{},
+	event *models.Event,
+	opts ExecutionOptions,
 ) {
+	// Executions share the correlation ID of the event they run for
+	if event.CorrelationID != "" {
+		ctx = correlation.WithID(ctx, event.CorrelationID)
+	}
+	log := er.logger.WithContext(ctx)
+
+	eventType := event.EventType
 	defer func() {
 		if rec := recover(); rec != nil {
 			stack := string(debug.Stack())
-			er.logger.Errorf(
+			log.Errorf(
 				"PANIC in workflow execution goroutine - workflow_id: %s, workflow_name: %s, event_type: %s, panic: %v, stack: %s",
 				workflow.ID,
 				workflow.Name,
@@ -146,6 +260,7 @@ func (er *EventRouter) safeExecuteWorkflow(
 				WorkflowID:     workflow.ID,
 				TriggerEvent:   eventType,
 				Status:         models.ExecutionStatusFailed,
+				CorrelationID:  event.CorrelationID,
 				StartedAt:      now,
 				CompletedAt:    &now,
 				ErrorMessage:   &errorMsg,
@@ -158,16 +273,62 @@ func (er *EventRouter) safeExecuteWorkflow(
 			// Attempt to record the panic execution (best effort)
 			if er.executor.executionRepo != nil {
 				if err := er.executor.executionRepo.CreateExecution(ctx, execution); err != nil {
-					er.logger.Errorf("Failed to record panic execution: %v", err)
+					log.Errorf("Failed to record panic execution: %v", err)
 				}
 			}
+
+			er.deadLetter(ctx, event, &workflow.ID, models.DeadLetterReasonPanic, errorMsg)
 		}
 	}()
 
 	// Execute the workflow
-	_, err := er.executor.Execute(ctx, organizationID, workflow, eventType, payload)
+	execution, err := er.executor.ExecuteWithOptions(ctx, organizationID, workflow, eventType, event.Payload, opts)
 	if err != nil {
-		er.logger.Errorf("Workflow execution failed: %s - %v", workflow.Name, err)
+		log.Errorf("Workflow execution failed: %s - %v", workflow.Name, err)
+		// A failed execution that was recorded can be re-run; one that never started is dead-lettered
+		if execution == nil {
+			reason := models.DeadLetterReasonExecutionError
+			switch {
+			case errors.Is(err, ErrConcurrencyLimitReached):
+				reason = models.DeadLetterReasonConcurrencyLimit
+			case errors.Is(err, ErrRateLimited):
+				reason = models.DeadLetterReasonRateLimited
+			}
+			er.deadLetter(ctx, event, &workflow.ID, reason, err.Error())
+		}
+	}
+}
+
+// deadLetter records an event that could not be processed, so it can be replayed (best effort)
+func (er *EventRouter) deadLetter(
+	ctx context.Context,
+	event *models.Event,
+	workflowID *uuid.UUID,
+	reason models.DeadLetterReason,
+	errorMsg string,
+) {
+	if er.deadLetters == nil {
+		return
+	}
+
+	deadLetter := &models.DeadLetterEvent{
+		ID:             uuid.New(),
+		OrganizationID: event.OrganizationID,
+		EventType:      event.EventType,
+		Source:         event.Source,
+		Payload:        event.Payload,
+		WorkflowID:     workflowID,
+		Reason:         reason,
+		Error:          errorMsg,
+		FailedAt:       time.Now(),
+	}
+	if event.ID != uuid.Nil {
+		eventID := event.ID
+		deadLetter.EventID = &eventID
+	}
+
+	if err := er.deadLetters.CreateDeadLetterEvent(ctx, deadLetter); err != nil {
+		er.logger.Errorf("Failed to dead-letter event %s (%s): %v", event.EventID, reason, err)
 	}
 }
 
@@ -177,6 +338,10 @@ func (er *EventRouter) findMatchingWorkflows(
 	organizationID uuid.UUID,
 	eventType string,
 ) ([]models.Workflow, error) {
+	if er.index != nil {
+		return er.index.Match(ctx, organizationID, eventType)
+	}
+
 	// Get all enabled workflows for this organization
 	enabled := true
 	workflows, _, err := er.workflowRepo.ListWorkflows(ctx, organizationID, &enabled, 1000, 0)
@@ -196,6 +361,31 @@ func (er *EventRouter) findMatchingWorkflows(
 	return matchingWorkflows, nil
 }
 
+// filterWorkflows keeps the workflows whose trigger filter accepts the event payload. A filter
+// that cannot be evaluated rejects the event, so a malformed filter never triggers its workflow
+func (er *EventRouter) filterWorkflows(workflows []models.Workflow, eventType string, payload map[string]interface{}) []models.Workflow {
+	accepted := make([]models.Workflow, 0, len(workflows))
+	for _, workflow := range workflows {
+		filter := workflow.Definition.Trigger.Filter
+		if filter == nil {
+			accepted = append(accepted, workflow)
+			continue
+		}
+
+		matched, err := er.executor.evaluator.EvaluateCondition(filter, payload)
+		if err != nil {
+			er.logger.Warnf("Trigger filter of workflow %s (ID: %s) failed for event %s, not triggering: %v", workflow.Name, workflow.ID, eventType, err)
+			continue
+		}
+		if !matched {
+			er.logger.Debugf("Trigger filter of workflow %s (ID: %s) rejected event %s", workflow.Name, workflow.ID, eventType)
+			continue
+		}
+		accepted = append(accepted, workflow)
+	}
+	return accepted
+}
+
 // workflowMatchesEvent checks if a workflow should be triggered by an event
 func (er *EventRouter) workflowMatchesEvent(workflow models.Workflow, eventType string) bool {
 	trigger := workflow.Definition.Trigger
@@ -205,25 +395,43 @@ func (er *EventRouter) workflowMatchesEvent(workflow models.Workflow, eventType
 		return false
 	}
 
-	// Check if event type matches
-	if trigger.Event == "" {
+	for _, pattern := range trigger.EventPatterns() {
+		if matchEventPattern(pattern, eventType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchEventPattern reports whether an event type matches a trigger's event pattern. Patterns
+// are matched segment by segment, split on dots: "*" matches exactly one segment and "**" zero
+// or more, so "order.*" matches "order.created" and "order.**" also matches "order.items.added"
+func matchEventPattern(pattern, eventType string) bool {
+	if pattern == "" {
 		return false
 	}
+	return matchEventSegments(strings.Split(pattern, "."), strings.Split(eventType, "."))
+}
 
-	// Exact match
-	if trigger.Event == eventType {
-		return true
+func matchEventSegments(pattern, event []string) bool {
+	if len(pattern) == 0 {
+		return len(event) == 0
 	}
 
-	// Wildcard match (e.g., "order.*" matches "order.created", "order.updated")
-	if len(trigger.Event) > 0 && trigger.Event[len(trigger.Event)-1] == '*' {
-		prefix := trigger.Event[:len(trigger.Event)-1]
-		if len(eventType) >= len(prefix) && eventType[:len(prefix)] == prefix {
-			return true
+	switch pattern[0] {
+	case "**":
+		for i := 0; i <= len(event); i++ {
+			if matchEventSegments(pattern[1:], event[i:]) {
+				return true
+			}
 		}
+		return false
+	case "*":
+		return len(event) > 0 && matchEventSegments(pattern[1:], event[1:])
+	default:
+		return len(event) > 0 && pattern[0] == event[0] && matchEventSegments(pattern[1:], event[1:])
 	}
-
-	return false
 }
 
 // ProcessScheduledWorkflows finds and executes workflows with schedule triggers
@@ -236,30 +444,270 @@ func (er *EventRouter) ProcessScheduledWorkflows(ctx context.Context) error {
 	return nil
 }
 
-// TriggerWorkflowManually triggers a workflow manually
+// TriggerWorkflowManually triggers a workflow manually. A version greater than zero runs that
+// recorded version of the workflow's definition instead of the current one
 func (er *EventRouter) TriggerWorkflowManually(
 	ctx context.Context,
 	organizationID uuid.UUID,
 	workflowID uuid.UUID,
+	version int,
 	payload map[string]interface{},
 ) (*models.WorkflowExecution, error) {
 	er.logger.Infof("Manually triggering workflow: %s for organization: %s", workflowID, organizationID)
 
+	return er.triggerWorkflow(ctx, organizationID, workflowID, version, payload, ExecutionOptions{})
+}
+
+// TriggerScheduledWorkflow runs a workflow for one of its schedule's runs. A non-nil catchUpFor
+// marks a run fired late for a missed time; it is recorded in the execution's metadata
+func (er *EventRouter) TriggerScheduledWorkflow(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	workflowID uuid.UUID,
+	payload map[string]interface{},
+	catchUpFor *time.Time,
+) (*models.WorkflowExecution, error) {
+	return er.triggerWorkflow(ctx, organizationID, workflowID, 0, payload, ExecutionOptions{CatchUpFor: catchUpFor})
+}
+
+// triggerWorkflow loads an enabled workflow, pinned to version when it is greater than zero, and runs it
+func (er *EventRouter) triggerWorkflow(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	workflowID uuid.UUID,
+	version int,
+	payload map[string]interface{},
+	opts ExecutionOptions,
+) (*models.WorkflowExecution, error) {
 	// Get workflow
 	workflow, err := er.workflowRepo.GetWorkflowByID(ctx, organizationID, workflowID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get workflow: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrWorkflowNotFound, err)
 	}
 
 	if !workflow.Enabled {
-		return nil, fmt.Errorf("workflow is disabled: %s", workflow.Name)
+		return nil, fmt.Errorf("%w: %s", ErrWorkflowDisabled, workflow.Name)
 	}
 
-	// Execute workflow
-	execution, err := er.executor.Execute(ctx, organizationID, workflow, "manual", payload)
+	if version > 0 && version != workflow.DefinitionVersion {
+		workflow, err = er.pinVersion(ctx, organizationID, workflow, version)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Execute workflow; a failed run still returns its execution so callers can trace it
+	execution, err := er.executor.ExecuteWithOptions(ctx, organizationID, workflow, "manual", payload, opts)
 	if err != nil {
-		return nil, fmt.Errorf("workflow execution failed: %w", err)
+		return execution, fmt.Errorf("workflow execution failed: %w", err)
+	}
+
+	return execution, nil
+}
+
+// pinVersion returns a copy of the workflow running the given recorded version of its definition.
+// The executor records the copy's definition version on the execution
+func (er *EventRouter) pinVersion(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	workflow *models.Workflow,
+	version int,
+) (*models.Workflow, error) {
+	pinned, err := pinWorkflowVersion(ctx, er.versionRepo, organizationID, workflow, version)
+	if err != nil {
+		return nil, err
+	}
+
+	er.logger.Infof("Running version %d of workflow %s (current version %d)", version, workflow.WorkflowID, workflow.DefinitionVersion)
+	return pinned, nil
+}
+
+// pinWorkflowVersion returns a copy of the workflow with the given recorded version of its
+// definition, loaded from the version store
+func pinWorkflowVersion(
+	ctx context.Context,
+	versionRepo WorkflowVersionRepository,
+	organizationID uuid.UUID,
+	workflow *models.Workflow,
+	version int,
+) (*models.Workflow, error) {
+	if versionRepo == nil {
+		return nil, fmt.Errorf("%w: version history is not available", ErrWorkflowVersionNotFound)
+	}
+
+	recorded, err := versionRepo.GetVersion(ctx, organizationID, workflow.ID, version)
+	if err != nil {
+		return nil, fmt.Errorf("%w: version %d: %v", ErrWorkflowVersionNotFound, version, err)
+	}
+
+	pinned := *workflow
+	pinned.Definition = recorded.Definition
+	pinned.DefinitionVersion = recorded.Version
+	return &pinned, nil
+}
+
+// RerunExecution re-queues a failed execution, replaying its original trigger event and payload
+// through its workflow. The execution is marked as re-run before the new run starts, so each failed
+// execution is replayed at most once; the new execution records it under the rerun_of metadata key.
+func (er *EventRouter) RerunExecution(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	execution *models.WorkflowExecution,
+) error {
+	if execution.Status != models.ExecutionStatusFailed {
+		return fmt.Errorf("%w: status is %s", ErrExecutionNotRerunnable, execution.Status)
+	}
+	if dryRun, _ := execution.Metadata["dry_run"].(bool); dryRun {
+		return fmt.Errorf("%w: dry runs cannot be re-run", ErrExecutionNotRerunnable)
+	}
+
+	workflow, err := er.workflowRepo.GetWorkflowByID(ctx, organizationID, execution.WorkflowID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWorkflowNotFound, err)
+	}
+
+	if !workflow.Enabled {
+		return fmt.Errorf("%w: %s", ErrWorkflowDisabled, workflow.Name)
+	}
+
+	claimed, err := er.executor.executionRepo.MarkExecutionRerun(ctx, organizationID, execution.ID)
+	if err != nil {
+		return fmt.Errorf("failed to mark execution for re-run: %w", err)
+	}
+	if !claimed {
+		return ErrExecutionAlreadyRerun
+	}
+
+	er.logger.Infof("Re-running execution %s of workflow %s for organization: %s", execution.ExecutionID, workflow.Name, organizationID)
+
+	// Execute asynchronously with panic recovery, as for routed events
+	rerunOf := execution.ID
+	event := &models.Event{
+		OrganizationID: organizationID,
+		EventType:      execution.TriggerEvent,
+		Source:         "rerun",
+		Payload:        execution.TriggerPayload,
+		CorrelationID:  execution.CorrelationID,
+	}
+	go func() {
+		er.safeExecuteWorkflow(context.Background(), organizationID, workflow, event, ExecutionOptions{RerunOf: &rerunOf})
+	}()
+
+	return nil
+}
+
+// ListDeadLetterEvents lists events that could not be processed, newest first. A non-nil
+// replayed filters on whether the events have been replayed
+func (er *EventRouter) ListDeadLetterEvents(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	replayed *bool,
+	limit, offset int,
+) ([]*models.DeadLetterEvent, int64, error) {
+	if er.deadLetters == nil {
+		return nil, 0, ErrDeadLetterUnavailable
+	}
+	return er.deadLetters.ListDeadLetterEvents(ctx, organizationID, replayed, limit, offset)
+}
+
+// ReplayDeadLetterEvent processes a dead-lettered event again. An event that failed in a workflow
+// is re-run through that workflow asynchronously; one that failed before it was routed is routed
+// again. Each dead-lettered event is replayed at most once; a replay that fails again is
+// dead-lettered anew
+func (er *EventRouter) ReplayDeadLetterEvent(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	id uuid.UUID,
+) (*models.DeadLetterEvent, error) {
+	if er.deadLetters == nil {
+		return nil, ErrDeadLetterUnavailable
+	}
+
+	deadLetter, err := er.deadLetters.GetDeadLetterEvent(ctx, organizationID, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDeadLetterEventNotFound, err)
+	}
+	if deadLetter.ReplayedAt != nil {
+		return nil, ErrDeadLetterAlreadyReplayed
+	}
+
+	var workflow *models.Workflow
+	if deadLetter.WorkflowID != nil {
+		workflow, err = er.workflowRepo.GetWorkflowByID(ctx, organizationID, *deadLetter.WorkflowID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrWorkflowNotFound, err)
+		}
+		if !workflow.Enabled {
+			return nil, fmt.Errorf("%w: %s", ErrWorkflowDisabled, workflow.Name)
+		}
+	}
+
+	claimed, err := er.deadLetters.MarkDeadLetterReplayed(ctx, organizationID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrDeadLetterAlreadyReplayed
+	}
+
+	er.logger.Infof("Replaying dead-letter event %s (%s) for organization: %s", id, deadLetter.EventType, organizationID)
+
+	now := time.Now()
+	deadLetter.ReplayedAt = &now
+
+	if workflow == nil {
+		if _, err := er.RouteEvent(ctx, organizationID, deadLetter.EventType, deadLetter.Source, deadLetter.Payload); err != nil {
+			return deadLetter, fmt.Errorf("failed to route event: %w", err)
+		}
+		return deadLetter, nil
+	}
+
+	event := &models.Event{
+		OrganizationID: organizationID,
+		EventType:      deadLetter.EventType,
+		Source:         deadLetter.Source,
+		Payload:        deadLetter.Payload,
+	}
+	if deadLetter.EventID != nil {
+		event.ID = *deadLetter.EventID
+	}
+	go func() {
+		er.safeExecuteWorkflow(context.Background(), organizationID, workflow, event, ExecutionOptions{})
+	}()
+
+	return deadLetter, nil
+}
+
+// CancelExecution interrupts an execution running in this process; see WorkflowExecutor.CancelExecution
+func (er *EventRouter) CancelExecution(executionID uuid.UUID) bool {
+	return er.executor.CancelExecution(executionID)
+}
+
+// DryRunWorkflow executes a workflow in dry-run mode: conditions are evaluated and the
+// path is recorded, but side-effecting actions are skipped. Disabled workflows may be dry-run.
+func (er *EventRouter) DryRunWorkflow(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	workflowID uuid.UUID,
+	triggerEvent string,
+	payload map[string]interface{},
+) (*models.WorkflowExecution, error) {
+	er.logger.Infof("Dry-running workflow: %s for organization: %s", workflowID, organizationID)
+
+	workflow, err := er.workflowRepo.GetWorkflowByID(ctx, organizationID, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWorkflowNotFound, err)
+	}
+
+	if patterns := workflow.Definition.Trigger.EventPatterns(); triggerEvent == "" && len(patterns) > 0 {
+		triggerEvent = patterns[0]
+	}
+
+	execution, err := er.executor.ExecuteWithOptions(ctx, organizationID, workflow, triggerEvent, payload, ExecutionOptions{DryRun: true})
+	if err != nil && execution == nil {
+		return nil, fmt.Errorf("workflow dry run failed: %w", err)
 	}
 
+	// A failing path is a valid dry-run outcome; it is reported on the execution
 	return execution, nil
 }
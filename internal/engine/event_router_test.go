@@ -2,7 +2,10 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -22,16 +25,17 @@ func getTestContextEnrichmentConfigForEventRouter() *config.ContextEnrichmentCon
 		MaxRetries: 3,
 		RetryDelay: 500 * time.Millisecond,
 		CacheTTL:   5 * time.Minute,
-		EndpointMapping: map[string]string{
-			"order.details": "/api/v1/orders/{id}/details",
+		EndpointMapping: map[string]config.EnrichmentEndpoint{
+			"order.details": {Path: "/api/v1/orders/{id}/details"},
 		},
 	}
 }
 
 // Mock WorkflowRepository for testing
 type mockWorkflowRepo struct {
-	getByIDFunc   func(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error)
-	listFunc      func(ctx context.Context, organizationID uuid.UUID, enabled *bool, limit, offset int) ([]models.Workflow, int64, error)
+	getByIDFunc         func(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error)
+	getByWorkflowIDFunc func(ctx context.Context, organizationID uuid.UUID, workflowID string) (*models.Workflow, error)
+	listFunc            func(ctx context.Context, organizationID uuid.UUID, enabled *bool, limit, offset int) ([]models.Workflow, int64, error)
 }
 
 func (m *mockWorkflowRepo) GetWorkflowByID(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error) {
@@ -41,6 +45,13 @@ func (m *mockWorkflowRepo) GetWorkflowByID(ctx context.Context, organizationID,
 	return nil, fmt.Errorf("not found")
 }
 
+func (m *mockWorkflowRepo) GetByWorkflowID(ctx context.Context, organizationID uuid.UUID, workflowID string) (*models.Workflow, error) {
+	if m.getByWorkflowIDFunc != nil {
+		return m.getByWorkflowIDFunc(ctx, organizationID, workflowID)
+	}
+	return nil, fmt.Errorf("not found")
+}
+
 func (m *mockWorkflowRepo) ListWorkflows(ctx context.Context, organizationID uuid.UUID, enabled *bool, limit, offset int) ([]models.Workflow, int64, error) {
 	if m.listFunc != nil {
 		return m.listFunc(ctx, organizationID, enabled, limit, offset)
@@ -48,6 +59,18 @@ func (m *mockWorkflowRepo) ListWorkflows(ctx context.Context, organizationID uui
 	return []models.Workflow{}, 0, nil
 }
 
+// Mock WorkflowVersionRepository for testing
+type mockVersionRepo struct {
+	versions map[int]*models.WorkflowVersion
+}
+
+func (m *mockVersionRepo) GetVersion(ctx context.Context, organizationID, workflowID uuid.UUID, version int) (*models.WorkflowVersion, error) {
+	if v, ok := m.versions[version]; ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("not found")
+}
+
 // Mock EventRepository for testing
 type mockEventRepo struct {
 	createFunc   func(ctx context.Context, event *models.Event) error
@@ -110,7 +133,7 @@ func TestSafeExecuteWorkflow_NormalExecution(t *testing.T) {
 
 	ctx := context.Background()
 	orgID := uuid.New()
-	router.safeExecuteWorkflow(ctx, orgID, workflow, "test.event", map[string]interface{}{})
+	router.safeExecuteWorkflow(ctx, orgID, workflow, &models.Event{EventType: "test.event", Payload: map[string]interface{}{}}, ExecutionOptions{})
 
 	// Give goroutine time to complete
 	time.Sleep(100 * time.Millisecond)
@@ -177,7 +200,7 @@ func TestSafeExecuteWorkflow_PanicRecovery(t *testing.T) {
 
 		ctx := context.Background()
 		orgID := uuid.New()
-		router.safeExecuteWorkflow(ctx, orgID, workflow, "test.event", map[string]interface{}{})
+		router.safeExecuteWorkflow(ctx, orgID, workflow, &models.Event{EventType: "test.event", Payload: map[string]interface{}{}}, ExecutionOptions{})
 	}()
 
 	// Wait for completion or timeout
@@ -248,6 +271,67 @@ func TestRouteEvent_WithWorkflows(t *testing.T) {
 	t.Log("Event routing completed successfully")
 }
 
+// TestRouteEvent_TriggerFilter tests that trigger filters decide which events run a workflow
+func TestRouteEvent_TriggerFilter(t *testing.T) {
+	log := logger.NewForTesting()
+
+	newWorkflow := func(workflowID string, filter *models.Condition) models.Workflow {
+		return models.Workflow{
+			ID:         uuid.New(),
+			WorkflowID: workflowID,
+			Name:       workflowID,
+			Enabled:    true,
+			Definition: models.WorkflowDefinition{
+				Trigger: models.TriggerDefinition{Type: "event", Event: "order.created", Filter: filter},
+				Steps: []models.Step{
+					{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}},
+				},
+			},
+		}
+	}
+	workflows := []models.Workflow{
+		newWorkflow("unfiltered", nil),
+		newWorkflow("high-value", &models.Condition{Field: "order.total", Operator: "gt", Value: 1000}),
+		newWorkflow("malformed", &models.Condition{Field: "order.total", Operator: "bogus", Value: 1000}),
+	}
+
+	workflowRepo := &mockWorkflowRepo{
+		listFunc: func(ctx context.Context, organizationID uuid.UUID, enabled *bool, limit, offset int) ([]models.Workflow, int64, error) {
+			return workflows, int64(len(workflows)), nil
+		},
+	}
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, workflowRepo, nil, log, nil, getTestContextEnrichmentConfigForEventRouter())
+	router := NewEventRouter(workflowRepo, &mockEventRepo{}, executor, log)
+
+	tests := []struct {
+		name      string
+		total     float64
+		triggered []string
+	}{
+		{name: "filter matches", total: 1500, triggered: []string{"unfiltered", "high-value"}},
+		{name: "filter rejects", total: 500, triggered: []string{"unfiltered"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := map[string]interface{}{"order": map[string]interface{}{"total": tt.total}}
+
+			event, err := router.RouteEvent(context.Background(), uuid.New(), "order.created", "shop", payload)
+			if err != nil {
+				t.Fatalf("RouteEvent failed: %v", err)
+			}
+
+			if fmt.Sprint(event.TriggeredWorkflows) != fmt.Sprint(tt.triggered) {
+				t.Errorf("Expected workflows %v to be triggered, got %v", tt.triggered, event.TriggeredWorkflows)
+			}
+			if event.ProcessedAt == nil {
+				t.Error("Expected the event to be recorded as processed")
+			}
+		})
+	}
+}
+
 // TestWorkflowMatchesEvent tests event matching logic
 func TestWorkflowMatchesEvent(t *testing.T) {
 	log := logger.NewForTesting()
@@ -285,6 +369,59 @@ func TestWorkflowMatchesEvent(t *testing.T) {
 			eventType:   "order.updated",
 			shouldMatch: true,
 		},
+		{
+			name: "no match - single-segment wildcard on a deeper event",
+			workflow: models.Workflow{
+				Definition: models.WorkflowDefinition{
+					Trigger: models.TriggerDefinition{
+						Type:  "event",
+						Event: "order.*",
+					},
+				},
+			},
+			eventType:   "order.items.added",
+			shouldMatch: false,
+		},
+		{
+			name: "multi-segment wildcard match",
+			workflow: models.Workflow{
+				Definition: models.WorkflowDefinition{
+					Trigger: models.TriggerDefinition{
+						Type:  "event",
+						Event: "order.**",
+					},
+				},
+			},
+			eventType:   "order.items.added",
+			shouldMatch: true,
+		},
+		{
+			name: "match in event pattern list",
+			workflow: models.Workflow{
+				Definition: models.WorkflowDefinition{
+					Trigger: models.TriggerDefinition{
+						Type:   "event",
+						Events: []string{"user.created", "order.*.shipped"},
+					},
+				},
+			},
+			eventType:   "order.partial.shipped",
+			shouldMatch: true,
+		},
+		{
+			name: "no match in event pattern list",
+			workflow: models.Workflow{
+				Definition: models.WorkflowDefinition{
+					Trigger: models.TriggerDefinition{
+						Type:   "event",
+						Event:  "order.created",
+						Events: []string{"user.created"},
+					},
+				},
+			},
+			eventType:   "order.updated",
+			shouldMatch: false,
+		},
 		{
 			name: "no match - different event",
 			workflow: models.Workflow{
@@ -323,6 +460,38 @@ func TestWorkflowMatchesEvent(t *testing.T) {
 	}
 }
 
+// TestMatchEventPattern tests matching event types against single- and multi-segment wildcards
+func TestMatchEventPattern(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		eventType string
+		expected  bool
+	}{
+		{"order.created", "order.created", true},
+		{"order.created", "order.created.v2", false},
+		{"order.*", "order.created", true},
+		{"order.*", "order", false},
+		{"order.*", "order.items.added", false},
+		{"order.**", "order.items.added", true},
+		{"order.**", "order", true},
+		{"order.**", "orders.created", false},
+		{"**.added", "order.items.added", true},
+		{"order.**.added", "order.added", true},
+		{"order.**.added", "order.items.removed", false},
+		{"*.created", "user.created", true},
+		{"**", "anything.at.all", true},
+		{"", "order.created", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.eventType, func(t *testing.T) {
+			if matched := matchEventPattern(tt.pattern, tt.eventType); matched != tt.expected {
+				t.Errorf("Expected match=%v, got match=%v", tt.expected, matched)
+			}
+		})
+	}
+}
+
 // TestTriggerWorkflowManually tests manual workflow triggering
 func TestTriggerWorkflowManually(t *testing.T) {
 	log := logger.NewForTesting()
@@ -357,7 +526,7 @@ func TestTriggerWorkflowManually(t *testing.T) {
 	router := NewEventRouter(workflowRepo, eventRepo, executor, log)
 
 	ctx := context.Background()
-	execution, err := router.TriggerWorkflowManually(ctx, orgID, workflowID, map[string]interface{}{
+	execution, err := router.TriggerWorkflowManually(ctx, orgID, workflowID, 0, map[string]interface{}{
 		"manual": "trigger",
 	})
 
@@ -404,7 +573,7 @@ func TestTriggerWorkflowManually_DisabledWorkflow(t *testing.T) {
 	router := NewEventRouter(workflowRepo, eventRepo, executor, log)
 
 	ctx := context.Background()
-	execution, err := router.TriggerWorkflowManually(ctx, orgID, workflowID, map[string]interface{}{})
+	execution, err := router.TriggerWorkflowManually(ctx, orgID, workflowID, 0, map[string]interface{}{})
 
 	if err == nil {
 		t.Fatal("Expected error when triggering disabled workflow")
@@ -441,7 +610,7 @@ func TestTriggerWorkflowManually_NotFound(t *testing.T) {
 	ctx := context.Background()
 	orgID := uuid.New()
 	workflowID := uuid.New()
-	execution, err := router.TriggerWorkflowManually(ctx, orgID, workflowID, map[string]interface{}{})
+	execution, err := router.TriggerWorkflowManually(ctx, orgID, workflowID, 0, map[string]interface{}{})
 
 	if err == nil {
 		t.Fatal("Expected error when triggering non-existent workflow")
@@ -453,3 +622,566 @@ func TestTriggerWorkflowManually_NotFound(t *testing.T) {
 
 	t.Log("Non-existent workflow correctly handled")
 }
+
+// TestTriggerWorkflowManually_PinnedVersion tests running a recorded version instead of the current definition
+func TestTriggerWorkflowManually_PinnedVersion(t *testing.T) {
+	log := logger.NewForTesting()
+
+	orgID := uuid.New()
+	workflowID := uuid.New()
+	workflow := &models.Workflow{
+		ID:                workflowID,
+		OrganizationID:    orgID,
+		WorkflowID:        "versioned-workflow",
+		Name:              "Versioned Workflow",
+		Enabled:           true,
+		DefinitionVersion: 2,
+		Definition: models.WorkflowDefinition{
+			Steps: []models.Step{{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}}},
+		},
+	}
+
+	workflowRepo := &mockWorkflowRepo{
+		getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error) {
+			return workflow, nil
+		},
+	}
+	versionRepo := &mockVersionRepo{versions: map[int]*models.WorkflowVersion{
+		1: {
+			WorkflowID: workflowID,
+			Version:    1,
+			Definition: models.WorkflowDefinition{
+				Steps: []models.Step{{ID: "block", Type: "action", Action: &models.Action{Type: "block", Reason: "old rules"}}},
+			},
+		},
+	}}
+
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, workflowRepo, nil, log, nil, getTestContextEnrichmentConfigForEventRouter())
+	router := NewEventRouter(workflowRepo, &mockEventRepo{}, executor, log)
+	router.SetVersionRepository(versionRepo)
+
+	ctx := context.Background()
+
+	t.Run("runs the requested version", func(t *testing.T) {
+		execution, err := router.TriggerWorkflowManually(ctx, orgID, workflowID, 1, map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("TriggerWorkflowManually failed: %v", err)
+		}
+		if execution.WorkflowVersion == nil || *execution.WorkflowVersion != 1 {
+			t.Errorf("Expected execution to record version 1, got %v", execution.WorkflowVersion)
+		}
+		if execution.Result == nil || *execution.Result != models.ExecutionResultBlocked {
+			t.Errorf("Expected version 1 to block, got %v", execution.Result)
+		}
+		if workflow.Definition.Steps[0].ID != "allow" {
+			t.Error("Expected the current definition to be left unchanged")
+		}
+	})
+
+	t.Run("defaults to the current version", func(t *testing.T) {
+		execution, err := router.TriggerWorkflowManually(ctx, orgID, workflowID, 0, map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("TriggerWorkflowManually failed: %v", err)
+		}
+		if execution.WorkflowVersion == nil || *execution.WorkflowVersion != 2 {
+			t.Errorf("Expected execution to record version 2, got %v", execution.WorkflowVersion)
+		}
+		if execution.Result == nil || *execution.Result != models.ExecutionResultAllowed {
+			t.Errorf("Expected the current version to allow, got %v", execution.Result)
+		}
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		execution, err := router.TriggerWorkflowManually(ctx, orgID, workflowID, 7, map[string]interface{}{})
+		if !errors.Is(err, ErrWorkflowVersionNotFound) {
+			t.Errorf("Expected ErrWorkflowVersionNotFound, got %v", err)
+		}
+		if execution != nil {
+			t.Error("Expected nil execution for an unknown version")
+		}
+	})
+}
+
+// TestTriggerScheduledWorkflow tests recording catch-up runs in execution metadata
+func TestTriggerScheduledWorkflow(t *testing.T) {
+	log := logger.NewForTesting()
+
+	orgID := uuid.New()
+	workflowID := uuid.New()
+	workflowRepo := &mockWorkflowRepo{
+		getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error) {
+			return &models.Workflow{
+				ID:             workflowID,
+				OrganizationID: orgID,
+				WorkflowID:     "scheduled-workflow",
+				Enabled:        true,
+				Definition: models.WorkflowDefinition{
+					Steps: []models.Step{{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}}},
+				},
+			}, nil
+		},
+	}
+
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, workflowRepo, nil, log, nil, getTestContextEnrichmentConfigForEventRouter())
+	router := NewEventRouter(workflowRepo, &mockEventRepo{}, executor, log)
+
+	ctx := context.Background()
+
+	t.Run("catch-up run", func(t *testing.T) {
+		missed := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+		execution, err := router.TriggerScheduledWorkflow(ctx, orgID, workflowID, map[string]interface{}{}, &missed)
+		if err != nil {
+			t.Fatalf("TriggerScheduledWorkflow failed: %v", err)
+		}
+		if execution.Metadata["catch_up"] != true {
+			t.Errorf("Expected catch_up metadata, got %v", execution.Metadata)
+		}
+		if execution.Metadata["missed_run_at"] != "2024-03-01T09:00:00Z" {
+			t.Errorf("Expected missed_run_at 2024-03-01T09:00:00Z, got %v", execution.Metadata["missed_run_at"])
+		}
+	})
+
+	t.Run("on-time run", func(t *testing.T) {
+		execution, err := router.TriggerScheduledWorkflow(ctx, orgID, workflowID, map[string]interface{}{}, nil)
+		if err != nil {
+			t.Fatalf("TriggerScheduledWorkflow failed: %v", err)
+		}
+		if _, ok := execution.Metadata["catch_up"]; ok {
+			t.Errorf("Expected no catch_up metadata, got %v", execution.Metadata)
+		}
+	})
+}
+
+// TestRerunExecution tests re-queuing failed executions through the event router
+func TestRerunExecution(t *testing.T) {
+	log := logger.NewForTesting()
+	orgID := uuid.New()
+
+	workflow := &models.Workflow{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Name:           "Rerun Workflow",
+		Enabled:        true,
+		Definition: models.WorkflowDefinition{
+			Trigger: models.TriggerDefinition{Type: "event", Event: "order.created", IdempotencyKey: "order.id"},
+			Steps: []models.Step{
+				{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}},
+			},
+		},
+	}
+
+	newRouter := func(executionRepo *mockExecutionRepo, workflow *models.Workflow) *EventRouter {
+		workflowRepo := &mockWorkflowRepo{
+			getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error) {
+				if id != workflow.ID {
+					return nil, fmt.Errorf("not found")
+				}
+				return workflow, nil
+			},
+		}
+		redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+		executor := NewWorkflowExecutor(redisClient, executionRepo, workflowRepo, nil, log, nil, getTestContextEnrichmentConfigForEventRouter())
+		return NewEventRouter(workflowRepo, &mockEventRepo{}, executor, log)
+	}
+
+	failedExecution := func() *models.WorkflowExecution {
+		return &models.WorkflowExecution{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			WorkflowID:     workflow.ID,
+			TriggerEvent:   "order.created",
+			TriggerPayload: models.JSONB{"order": map[string]interface{}{"id": "ord-1"}},
+			Status:         models.ExecutionStatusFailed,
+			Metadata:       models.JSONB{},
+		}
+	}
+
+	t.Run("replays the original trigger and links the new execution", func(t *testing.T) {
+		original := failedExecution()
+		created := make(chan *models.WorkflowExecution, 1)
+
+		executionRepo := &mockExecutionRepo{
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				created <- execution
+				return nil
+			},
+			// The original execution holds the idempotency key; a re-run must not be deduplicated against it
+			getByIdempotencyKeyFunc: func(ctx context.Context, organizationID, workflowID uuid.UUID, key string) (*models.WorkflowExecution, error) {
+				return original, nil
+			},
+		}
+
+		if err := newRouter(executionRepo, workflow).RerunExecution(context.Background(), orgID, original); err != nil {
+			t.Fatalf("RerunExecution failed: %v", err)
+		}
+
+		select {
+		case rerun := <-created:
+			if rerun.TriggerEvent != "order.created" {
+				t.Errorf("Expected trigger event order.created, got %s", rerun.TriggerEvent)
+			}
+			if rerun.Metadata["rerun_of"] != original.ID.String() {
+				t.Errorf("Expected rerun_of %s, got %v", original.ID, rerun.Metadata["rerun_of"])
+			}
+			if rerun.IdempotencyKey != nil {
+				t.Errorf("Expected no idempotency key on a re-run, got %s", *rerun.IdempotencyKey)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the re-run execution to be created")
+		}
+	})
+
+	t.Run("skips executions that did not fail", func(t *testing.T) {
+		execution := failedExecution()
+		execution.Status = models.ExecutionStatusCompleted
+
+		err := newRouter(&mockExecutionRepo{}, workflow).RerunExecution(context.Background(), orgID, execution)
+		if !errors.Is(err, ErrExecutionNotRerunnable) {
+			t.Errorf("Expected ErrExecutionNotRerunnable, got %v", err)
+		}
+	})
+
+	t.Run("skips dry runs", func(t *testing.T) {
+		execution := failedExecution()
+		execution.Metadata["dry_run"] = true
+
+		err := newRouter(&mockExecutionRepo{}, workflow).RerunExecution(context.Background(), orgID, execution)
+		if !errors.Is(err, ErrExecutionNotRerunnable) {
+			t.Errorf("Expected ErrExecutionNotRerunnable, got %v", err)
+		}
+	})
+
+	t.Run("skips executions already re-run", func(t *testing.T) {
+		executionRepo := &mockExecutionRepo{
+			markExecutionRerunFunc: func(ctx context.Context, organizationID, id uuid.UUID) (bool, error) {
+				return false, nil
+			},
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				t.Error("Expected no execution to be created")
+				return nil
+			},
+		}
+
+		err := newRouter(executionRepo, workflow).RerunExecution(context.Background(), orgID, failedExecution())
+		if !errors.Is(err, ErrExecutionAlreadyRerun) {
+			t.Errorf("Expected ErrExecutionAlreadyRerun, got %v", err)
+		}
+	})
+
+	t.Run("skips disabled workflows", func(t *testing.T) {
+		disabled := *workflow
+		disabled.Enabled = false
+
+		err := newRouter(&mockExecutionRepo{}, &disabled).RerunExecution(context.Background(), orgID, failedExecution())
+		if !errors.Is(err, ErrWorkflowDisabled) {
+			t.Errorf("Expected ErrWorkflowDisabled, got %v", err)
+		}
+	})
+}
+
+// Mock DeadLetterRepository for testing
+type mockDeadLetterRepo struct {
+	mu     sync.Mutex
+	events map[uuid.UUID]*models.DeadLetterEvent
+	added  chan *models.DeadLetterEvent
+}
+
+func newMockDeadLetterRepo() *mockDeadLetterRepo {
+	return &mockDeadLetterRepo{events: make(map[uuid.UUID]*models.DeadLetterEvent), added: make(chan *models.DeadLetterEvent, 10)}
+}
+
+func (m *mockDeadLetterRepo) CreateDeadLetterEvent(ctx context.Context, event *models.DeadLetterEvent) error {
+	m.mu.Lock()
+	m.events[event.ID] = event
+	m.mu.Unlock()
+	m.added <- event
+	return nil
+}
+
+func (m *mockDeadLetterRepo) GetDeadLetterEvent(ctx context.Context, organizationID, id uuid.UUID) (*models.DeadLetterEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if event, ok := m.events[id]; ok {
+		copied := *event
+		return &copied, nil
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+func (m *mockDeadLetterRepo) ListDeadLetterEvents(ctx context.Context, organizationID uuid.UUID, replayed *bool, limit, offset int) ([]*models.DeadLetterEvent, int64, error) {
+	return nil, 0, nil
+}
+
+func (m *mockDeadLetterRepo) MarkDeadLetterReplayed(ctx context.Context, organizationID, id uuid.UUID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	event, ok := m.events[id]
+	if !ok || event.ReplayedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	event.ReplayedAt = &now
+	return true, nil
+}
+
+// waitForDeadLetter returns the next dead-lettered event, failing the test if none is recorded
+func waitForDeadLetter(t *testing.T, repo *mockDeadLetterRepo) *models.DeadLetterEvent {
+	t.Helper()
+	select {
+	case event := <-repo.added:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the event to be dead-lettered")
+		return nil
+	}
+}
+
+// TestDeadLetterEvents tests that events failing to route or run are kept and can be replayed
+func TestDeadLetterEvents(t *testing.T) {
+	log := logger.NewForTesting()
+	orgID := uuid.New()
+	payload := map[string]interface{}{"order": map[string]interface{}{"id": "ord-1"}}
+
+	workflow := models.Workflow{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		WorkflowID:     "dead-letter-wf",
+		Name:           "Dead Letter Workflow",
+		Enabled:        true,
+		Definition: models.WorkflowDefinition{
+			Trigger: models.TriggerDefinition{Type: "event", Event: "order.created"},
+			Steps: []models.Step{
+				{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}},
+			},
+		},
+	}
+
+	newRouter := func(workflowRepo *mockWorkflowRepo, executionRepo *mockExecutionRepo, deadLetters *mockDeadLetterRepo) *EventRouter {
+		redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+		executor := NewWorkflowExecutor(redisClient, executionRepo, workflowRepo, nil, log, nil, getTestContextEnrichmentConfigForEventRouter())
+		router := NewEventRouter(workflowRepo, &mockEventRepo{}, executor, log)
+		router.SetDeadLetterRepository(deadLetters)
+		return router
+	}
+	listWorkflow := &mockWorkflowRepo{
+		listFunc: func(ctx context.Context, organizationID uuid.UUID, enabled *bool, limit, offset int) ([]models.Workflow, int64, error) {
+			return []models.Workflow{workflow}, 1, nil
+		},
+		getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error) {
+			return &workflow, nil
+		},
+	}
+
+	t.Run("keeps events whose workflows cannot be listed", func(t *testing.T) {
+		deadLetters := newMockDeadLetterRepo()
+		workflowRepo := &mockWorkflowRepo{
+			listFunc: func(ctx context.Context, organizationID uuid.UUID, enabled *bool, limit, offset int) ([]models.Workflow, int64, error) {
+				return nil, 0, errors.New("connection refused")
+			},
+		}
+
+		event, err := newRouter(workflowRepo, &mockExecutionRepo{}, deadLetters).RouteEvent(context.Background(), orgID, "order.created", "shop", payload)
+		if err == nil {
+			t.Fatal("Expected routing to fail")
+		}
+
+		deadLetter := waitForDeadLetter(t, deadLetters)
+		if deadLetter.Reason != models.DeadLetterReasonRoutingError || deadLetter.WorkflowID != nil {
+			t.Errorf("Expected a routing error without a workflow, got %s for %v", deadLetter.Reason, deadLetter.WorkflowID)
+		}
+		if deadLetter.EventID == nil || *deadLetter.EventID != event.ID || deadLetter.Source != "shop" {
+			t.Errorf("Expected the dead letter to reference event %s from shop, got %v from %s", event.ID, deadLetter.EventID, deadLetter.Source)
+		}
+	})
+
+	t.Run("keeps events a workflow cannot start an execution for", func(t *testing.T) {
+		deadLetters := newMockDeadLetterRepo()
+		executionRepo := &mockExecutionRepo{
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				return errors.New("connection refused")
+			},
+		}
+
+		if _, err := newRouter(listWorkflow, executionRepo, deadLetters).RouteEvent(context.Background(), orgID, "order.created", "shop", payload); err != nil {
+			t.Fatalf("RouteEvent failed: %v", err)
+		}
+
+		deadLetter := waitForDeadLetter(t, deadLetters)
+		if deadLetter.Reason != models.DeadLetterReasonExecutionError {
+			t.Errorf("Expected an execution error, got %s", deadLetter.Reason)
+		}
+		if deadLetter.WorkflowID == nil || *deadLetter.WorkflowID != workflow.ID {
+			t.Errorf("Expected the dead letter to name workflow %s, got %v", workflow.ID, deadLetter.WorkflowID)
+		}
+	})
+
+	t.Run("keeps events a workflow's concurrency limit rejects", func(t *testing.T) {
+		deadLetters := newMockDeadLetterRepo()
+		limited := workflow
+		limited.Definition.Concurrency = &models.ConcurrencyDefinition{Policy: models.ConcurrencyPolicyReject}
+		workflowRepo := &mockWorkflowRepo{
+			listFunc: func(ctx context.Context, organizationID uuid.UUID, enabled *bool, limit, offset int) ([]models.Workflow, int64, error) {
+				return []models.Workflow{limited}, 1, nil
+			},
+		}
+
+		redisClient, _ := newTestRedis(t)
+		limiter := NewConcurrencyLimiter(redisClient, &config.ConcurrencyConfig{}, log)
+		running, err := limiter.Acquire(context.Background(), orgID, &limited, payload, uuid.New())
+		if err != nil {
+			t.Fatalf("Expected a slot for the running execution, got %v", err)
+		}
+		defer running.Release()
+
+		router := newRouter(workflowRepo, &mockExecutionRepo{}, deadLetters)
+		router.executor.SetConcurrencyLimiter(limiter)
+		if _, err := router.RouteEvent(context.Background(), orgID, "order.created", "shop", payload); err != nil {
+			t.Fatalf("RouteEvent failed: %v", err)
+		}
+
+		deadLetter := waitForDeadLetter(t, deadLetters)
+		if deadLetter.Reason != models.DeadLetterReasonConcurrencyLimit {
+			t.Errorf("Expected a concurrency limit rejection, got %s", deadLetter.Reason)
+		}
+	})
+
+	t.Run("keeps events queued by a rate limit when the executor drains", func(t *testing.T) {
+		deadLetters := newMockDeadLetterRepo()
+		limited := workflow
+		limited.Definition.RateLimit = &models.RateLimitDefinition{Rate: 1, Per: "10m"}
+		workflowRepo := &mockWorkflowRepo{
+			listFunc: func(ctx context.Context, organizationID uuid.UUID, enabled *bool, limit, offset int) ([]models.Workflow, int64, error) {
+				return []models.Workflow{limited}, 1, nil
+			},
+		}
+
+		redisClient, _ := newTestRedis(t)
+		limiter := NewRateLimiter(redisClient, &config.WorkflowRateLimitConfig{QueueTimeout: time.Hour, PollInterval: 5 * time.Millisecond}, log, nil)
+		if err := limiter.Take(context.Background(), orgID, &limited, 0); err != nil {
+			t.Fatalf("Expected the running execution to take the token, got %v", err)
+		}
+
+		router := newRouter(workflowRepo, &mockExecutionRepo{}, deadLetters)
+		router.executor.SetRateLimiter(limiter)
+		if _, err := router.RouteEvent(context.Background(), orgID, "order.created", "shop", payload); err != nil {
+			t.Fatalf("RouteEvent failed: %v", err)
+		}
+		queueKey := fmt.Sprintf("%s%s:%s", rateLimitKeyPrefix, orgID, limited.ID)
+		for queuedExecutions(t, redisClient, queueKey) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		// The queue does not outlive the process, so draining turns the execution away
+		if interrupted := router.executor.Drain(context.Background()); interrupted != 0 {
+			t.Errorf("Expected no running executions to interrupt, got %d", interrupted)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if !router.Drain(ctx) {
+			t.Fatal("Expected the routed execution to return")
+		}
+
+		select {
+		case deadLetter := <-deadLetters.added:
+			if deadLetter.Reason != models.DeadLetterReasonRateLimited || !strings.Contains(deadLetter.Error, ErrExecutionInterrupted.Error()) {
+				t.Errorf("Expected a rate limit rejection on shutdown, got %s: %s", deadLetter.Reason, deadLetter.Error)
+			}
+		default:
+			t.Fatal("Expected the event to be dead-lettered before the router drained")
+		}
+		if queued := queuedExecutions(t, redisClient, queueKey); queued != 0 {
+			t.Errorf("Expected the execution to leave the queue, %d queued", queued)
+		}
+	})
+
+	t.Run("keeps events a workflow panics on", func(t *testing.T) {
+		deadLetters := newMockDeadLetterRepo()
+		executionRepo := &mockExecutionRepo{
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				if execution.Status == models.ExecutionStatusRunning {
+					panic("unexpected nil")
+				}
+				return nil
+			},
+		}
+
+		if _, err := newRouter(listWorkflow, executionRepo, deadLetters).RouteEvent(context.Background(), orgID, "order.created", "shop", payload); err != nil {
+			t.Fatalf("RouteEvent failed: %v", err)
+		}
+
+		deadLetter := waitForDeadLetter(t, deadLetters)
+		if deadLetter.Reason != models.DeadLetterReasonPanic {
+			t.Errorf("Expected a panic, got %s", deadLetter.Reason)
+		}
+	})
+
+	t.Run("replays an event through its workflow once", func(t *testing.T) {
+		deadLetters := newMockDeadLetterRepo()
+		deadLetter := &models.DeadLetterEvent{ID: uuid.New(), OrganizationID: orgID, EventType: "order.created", Payload: payload, WorkflowID: &workflow.ID, Reason: models.DeadLetterReasonPanic}
+		deadLetters.events[deadLetter.ID] = deadLetter
+
+		created := make(chan *models.WorkflowExecution, 1)
+		executionRepo := &mockExecutionRepo{
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				created <- execution
+				return nil
+			},
+		}
+		router := newRouter(listWorkflow, executionRepo, deadLetters)
+
+		replayed, err := router.ReplayDeadLetterEvent(context.Background(), orgID, deadLetter.ID)
+		if err != nil {
+			t.Fatalf("ReplayDeadLetterEvent failed: %v", err)
+		}
+		if replayed.ReplayedAt == nil {
+			t.Error("Expected the dead letter to be marked replayed")
+		}
+
+		select {
+		case execution := <-created:
+			if execution.WorkflowID != workflow.ID || execution.TriggerEvent != "order.created" {
+				t.Errorf("Expected workflow %s to run for order.created, got %s for %s", workflow.ID, execution.WorkflowID, execution.TriggerEvent)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the replay to start an execution")
+		}
+
+		if _, err := router.ReplayDeadLetterEvent(context.Background(), orgID, deadLetter.ID); !errors.Is(err, ErrDeadLetterAlreadyReplayed) {
+			t.Errorf("Expected ErrDeadLetterAlreadyReplayed, got %v", err)
+		}
+	})
+
+	t.Run("routes an unrouted event again", func(t *testing.T) {
+		deadLetters := newMockDeadLetterRepo()
+		deadLetter := &models.DeadLetterEvent{ID: uuid.New(), OrganizationID: orgID, EventType: "order.created", Source: "shop", Payload: payload, Reason: models.DeadLetterReasonRoutingError}
+		deadLetters.events[deadLetter.ID] = deadLetter
+
+		created := make(chan *models.WorkflowExecution, 1)
+		executionRepo := &mockExecutionRepo{
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				created <- execution
+				return nil
+			},
+		}
+
+		if _, err := newRouter(listWorkflow, executionRepo, deadLetters).ReplayDeadLetterEvent(context.Background(), orgID, deadLetter.ID); err != nil {
+			t.Fatalf("ReplayDeadLetterEvent failed: %v", err)
+		}
+
+		select {
+		case <-created:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the routed event to start an execution")
+		}
+	})
+
+	t.Run("rejects unknown dead letters", func(t *testing.T) {
+		_, err := newRouter(listWorkflow, &mockExecutionRepo{}, newMockDeadLetterRepo()).ReplayDeadLetterEvent(context.Background(), orgID, uuid.New())
+		if !errors.Is(err, ErrDeadLetterEventNotFound) {
+			t.Errorf("Expected ErrDeadLetterEventNotFound, got %v", err)
+		}
+	})
+}
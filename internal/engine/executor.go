@@ -2,17 +2,31 @@ package engine
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
 	"github.com/davidmoltin/intelligent-workflows/internal/websocket"
+	"github.com/davidmoltin/intelligent-workflows/pkg/circuitbreaker"
 	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/correlation"
+	"github.com/davidmoltin/intelligent-workflows/pkg/egress"
+	"github.com/davidmoltin/intelligent-workflows/pkg/llm"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
 	"github.com/davidmoltin/intelligent-workflows/pkg/metrics"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ExecutionRepository defines the interface for execution persistence
@@ -23,6 +37,9 @@ type ExecutionRepository interface {
 	CreateStepExecution(ctx context.Context, step *models.StepExecution) error
 	UpdateStepExecution(ctx context.Context, organizationID uuid.UUID, step *models.StepExecution) error
 	GetTimedOutExecutions(ctx context.Context, organizationID uuid.UUID, limit int) ([]*models.WorkflowExecution, error)
+	GetExecutionByIdempotencyKey(ctx context.Context, organizationID, workflowID uuid.UUID, key string) (*models.WorkflowExecution, error)
+	MarkExecutionRerun(ctx context.Context, organizationID, id uuid.UUID) (bool, error)
+	InterruptExecution(ctx context.Context, organizationID, id uuid.UUID, reason string) (bool, error)
 }
 
 // RuleService interface for loading rules
@@ -37,12 +54,25 @@ type WorkflowExecutor struct {
 	actionExecutor *ActionExecutor
 	executionRepo  ExecutionRepository
 	workflowRepo   WorkflowRepository
+	versionRepo    WorkflowVersionRepository
 	ruleService    RuleService
+	queryRunner    *QueryRunner
+	concurrency    *ConcurrencyLimiter
+	rateLimiter    *RateLimiter
 	wsHub          *websocket.Hub
 	logger         *logger.Logger
 	metrics        *metrics.Metrics
 	maxRetries     int
 	defaultTimeout time.Duration
+	maxStepVisits  int
+	retryPatterns  sync.Map // Compiled "re:" retry_on patterns keyed by pattern string
+	running        sync.Map // Live executions in this process: execution ID -> *runningExecution
+	tracer         trace.Tracer
+
+	admissions     context.Context // Done once Drain begins, turning away executions queued for admission
+	stopAdmissions context.CancelCauseFunc
+
+	invalidateOnResume bool // Drop cached context resources before a resumed execution reloads them
 }
 
 // NewWorkflowExecutor creates a new workflow executor
@@ -55,9 +85,10 @@ func NewWorkflowExecutor(
 	m *metrics.Metrics,
 	contextEnrichmentCfg *config.ContextEnrichmentConfig,
 ) *WorkflowExecutor {
+	admissions, stopAdmissions := context.WithCancelCause(context.Background())
 	return &WorkflowExecutor{
 		evaluator:      NewEvaluator(),
-		contextBuilder: NewContextBuilder(redis, log, contextEnrichmentCfg),
+		contextBuilder: NewContextBuilder(redis, log, contextEnrichmentCfg, m),
 		actionExecutor: NewActionExecutor(log),
 		executionRepo:  executionRepo,
 		workflowRepo:   workflowRepo,
@@ -66,6 +97,13 @@ func NewWorkflowExecutor(
 		metrics:        m,
 		maxRetries:     3,
 		defaultTimeout: 30 * time.Second,
+		maxStepVisits:  1000,
+		tracer:         otel.Tracer(tracerName),
+
+		admissions:     admissions,
+		stopAdmissions: stopAdmissions,
+
+		invalidateOnResume: contextEnrichmentCfg.InvalidateOnResume,
 	}
 }
 
@@ -74,11 +112,94 @@ func (we *WorkflowExecutor) SetRuleService(ruleService RuleService) {
 	we.ruleService = ruleService
 }
 
+// SetVersionRepository sets the store resumed executions load the workflow version they started
+// on from (optional dependency); without one, executions of edited workflows cannot be resumed
+func (we *WorkflowExecutor) SetVersionRepository(versionRepo WorkflowVersionRepository) {
+	we.versionRepo = versionRepo
+}
+
+// SetQueryRunner sets the runner query steps run their SQL through (optional dependency)
+func (we *WorkflowExecutor) SetQueryRunner(runner *QueryRunner) {
+	we.queryRunner = runner
+}
+
+// SetConcurrencyLimiter sets the limiter workflow concurrency limits are enforced with (optional
+// dependency); without one, workflows run without their limits
+func (we *WorkflowExecutor) SetConcurrencyLimiter(limiter *ConcurrencyLimiter) {
+	we.concurrency = limiter
+}
+
+// SetRateLimiter sets the limiter workflow and organization execution rate limits are enforced
+// with (optional dependency); without one, executions start unlimited
+func (we *WorkflowExecutor) SetRateLimiter(limiter *RateLimiter) {
+	we.rateLimiter = limiter
+}
+
+// SetEnrichmentTokenProvider sets the source of bearer tokens for context enrichment requests (optional)
+func (we *WorkflowExecutor) SetEnrichmentTokenProvider(provider TokenProvider) {
+	we.contextBuilder.SetTokenProvider(provider)
+}
+
+// SetEnrichmentCircuitBreaker sets the circuit breaker context enrichment requests are made
+// through (optional dependency)
+func (we *WorkflowExecutor) SetEnrichmentCircuitBreaker(breaker *circuitbreaker.Breaker) {
+	we.contextBuilder.SetCircuitBreaker(breaker)
+}
+
 // SetApprovalService sets the approval service for the action executor (optional dependency)
 func (we *WorkflowExecutor) SetApprovalService(approvalService ApprovalService) {
 	we.actionExecutor.SetApprovalService(approvalService)
 }
 
+// SetAIService sets the AI service for the action executor's ai_* actions (optional dependency)
+func (we *WorkflowExecutor) SetAIService(aiService AIService) {
+	we.actionExecutor.SetAIService(aiService)
+}
+
+// SetEmailService sets the service the action executor's email actions are sent through (optional dependency)
+func (we *WorkflowExecutor) SetEmailService(emailService EmailService) {
+	we.actionExecutor.SetEmailService(emailService)
+}
+
+// SetSlackService sets the service the action executor's slack actions post through (optional dependency)
+func (we *WorkflowExecutor) SetSlackService(slackService SlackService) {
+	we.actionExecutor.SetSlackService(slackService)
+}
+
+// SetWebhookConfig sets the action executor's webhook delivery timeout and signing secrets
+// (optional dependency)
+func (we *WorkflowExecutor) SetWebhookConfig(cfg *config.WebhookConfig) {
+	we.actionExecutor.SetWebhookConfig(cfg)
+}
+
+// SetEgressPolicy sets the policy destinations of webhook actions and context enrichment
+// requests are checked against (optional dependency)
+func (we *WorkflowExecutor) SetEgressPolicy(policy *egress.Policy) {
+	we.actionExecutor.SetEgressPolicy(policy)
+	we.contextBuilder.SetEgressPolicy(policy)
+}
+
+// ExecutionOptions controls how a single workflow execution runs
+type ExecutionOptions struct {
+	// DryRun evaluates conditions and resolves context but skips side-effecting
+	// actions (webhooks, notifications, records, approvals) and wait pauses
+	DryRun bool
+
+	// ParentExecutionID links a sub-workflow execution started by a call step to its caller
+	ParentExecutionID *uuid.UUID
+
+	// IdempotencyKey deduplicates executions of the workflow. When empty, the key is
+	// derived from the trigger's idempotency_key path or the payload's idempotency_key field
+	IdempotencyKey string
+
+	// RerunOf links a re-run to the failed execution whose trigger it replays. Re-runs skip
+	// idempotency deduplication, since the original execution already holds the key
+	RerunOf *uuid.UUID
+
+	// CatchUpFor marks a scheduled run fired late, in place of the run missed at this time
+	CatchUpFor *time.Time
+}
+
 // Execute executes a workflow
 func (we *WorkflowExecutor) Execute(
 	ctx context.Context,
@@ -86,18 +207,74 @@ func (we *WorkflowExecutor) Execute(
 	workflow *models.Workflow,
 	triggerEvent string,
 	triggerPayload map[string]interface{},
+) (*models.WorkflowExecution, error) {
+	return we.ExecuteWithOptions(ctx, organizationID, workflow, triggerEvent, triggerPayload, ExecutionOptions{})
+}
+
+// ExecuteWithOptions executes a workflow with the given execution options
+func (we *WorkflowExecutor) ExecuteWithOptions(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	workflow *models.Workflow,
+	triggerEvent string,
+	triggerPayload map[string]interface{},
+	opts ExecutionOptions,
 ) (*models.WorkflowExecution, error) {
 	// Track execution start time for metrics
 	startTime := time.Now()
 	workflowIDStr := workflow.ID.String()
 
+	// Record this workflow in the call chain so call steps can detect recursion
+	ctx = withCallFrame(ctx, workflow)
+
+	// Executions started without a correlation ID, e.g. by a schedule, start a new transaction;
+	// called workflows share their caller's
+	correlationID := correlation.FromContext(ctx)
+	if correlationID == "" {
+		correlationID = correlation.NewID()
+		ctx = correlation.WithID(ctx, correlationID)
+	}
+
+	// A duplicate delivery returns the execution already recorded under its key without re-running
+	idempotencyKey := ""
+	if !opts.DryRun && opts.RerunOf == nil {
+		idempotencyKey = we.resolveIdempotencyKey(ctx, workflow, triggerPayload, opts)
+	}
+	if idempotencyKey != "" {
+		if existing, err := we.executionRepo.GetExecutionByIdempotencyKey(ctx, organizationID, workflow.ID, idempotencyKey); err == nil {
+			we.loggerFor(ctx).Infof("Skipping duplicate execution of workflow %s for idempotency key %s (existing: %s)", workflow.ID, idempotencyKey, existing.ExecutionID)
+			return existing, nil
+		}
+	}
+
+	// Take a rate limit token and hold a slot of the workflow's concurrency limit until the run
+	// completes, fails or pauses
+	executionID := uuid.New()
+	if !opts.DryRun {
+		lease, err := we.admitExecution(ctx, organizationID, workflow, triggerPayload, executionID)
+		if err != nil {
+			return nil, err
+		}
+		if lease != nil {
+			defer lease.Release()
+		}
+	}
+
+	// Dry runs are excluded from execution metrics
+	m := we.metrics
+	if opts.DryRun {
+		m = nil
+	}
+
 	// Increment active workflows gauge
-	if we.metrics != nil {
-		we.metrics.ActiveWorkflows.WithLabelValues(workflowIDStr).Inc()
-		defer we.metrics.ActiveWorkflows.WithLabelValues(workflowIDStr).Dec()
+	var orgLabel string
+	if m != nil {
+		orgLabel = m.OrganizationLabel(organizationID.String())
+		m.ActiveWorkflows.WithLabelValues(orgLabel, workflowIDStr).Inc()
+		defer m.ActiveWorkflows.WithLabelValues(orgLabel, workflowIDStr).Dec()
 	}
 
-	we.logger.Infof("Starting workflow execution: %s (ID: %s) for organization: %s", workflow.Name, workflow.ID, organizationID)
+	we.loggerFor(ctx).Infof("Starting workflow execution: %s (ID: %s) for organization: %s", workflow.Name, workflow.ID, organizationID)
 
 	// Get timeout for this workflow (check Definition.Timeout first, then trigger data, then default)
 	timeout := we.getWorkflowTimeout(workflow)
@@ -107,12 +284,12 @@ func (we *WorkflowExecutor) Execute(
 	if timeout > 0 {
 		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
-		we.logger.Infof("Workflow timeout set to: %v", timeout)
+		we.loggerFor(ctx).Infof("Workflow timeout set to: %v", timeout)
 	}
 
 	// Create execution record
 	execution := &models.WorkflowExecution{
-		ID:             uuid.New(),
+		ID:             executionID,
 		OrganizationID: organizationID,
 		WorkflowID:     workflow.ID,
 		ExecutionID:    fmt.Sprintf("exec_%s", uuid.New().String()[:8]),
@@ -121,6 +298,29 @@ func (we *WorkflowExecutor) Execute(
 		Status:         models.ExecutionStatusRunning,
 		StartedAt:      time.Now(),
 		Metadata:       make(models.JSONB),
+		CorrelationID:  correlationID,
+	}
+
+	if opts.DryRun {
+		execution.Metadata["dry_run"] = true
+	}
+	if opts.ParentExecutionID != nil {
+		execution.Metadata["parent_execution_id"] = opts.ParentExecutionID.String()
+	}
+	if opts.RerunOf != nil {
+		execution.Metadata["rerun_of"] = opts.RerunOf.String()
+	}
+	if opts.CatchUpFor != nil {
+		execution.Metadata["catch_up"] = true
+		execution.Metadata["missed_run_at"] = opts.CatchUpFor.Format(time.RFC3339)
+	}
+	if idempotencyKey != "" {
+		execution.IdempotencyKey = &idempotencyKey
+	}
+	// Record which definition ran so the trace can be matched to it after the workflow is edited
+	if workflow.DefinitionVersion > 0 {
+		version := workflow.DefinitionVersion
+		execution.WorkflowVersion = &version
 	}
 
 	// Set timeout fields if timeout is configured
@@ -134,11 +334,26 @@ func (we *WorkflowExecutor) Execute(
 	}
 
 	if err := we.executionRepo.CreateExecution(ctx, execution); err != nil {
+		// A concurrent delivery may have claimed the key between the lookup and the insert
+		if idempotencyKey != "" {
+			if existing, lookupErr := we.executionRepo.GetExecutionByIdempotencyKey(ctx, organizationID, workflow.ID, idempotencyKey); lookupErr == nil {
+				we.loggerFor(ctx).Infof("Skipping duplicate execution of workflow %s for idempotency key %s (existing: %s)", workflow.ID, idempotencyKey, existing.ExecutionID)
+				return existing, nil
+			}
+		}
 		return nil, fmt.Errorf("failed to create execution: %w", err)
 	}
 
-	// Set execution context for action executor (needed for approval requests)
-	we.actionExecutor.SetExecutionContext(execution.ID)
+	// Trace the execution, with a child span per step, under the trigger's trace if it has one
+	ctx, span := we.startExecutionSpan(ctx, workflow, execution)
+	defer endExecutionSpan(span, execution)
+
+	// Register the run so a cancel request can interrupt it
+	ctx, untrack := we.trackExecution(ctx, organizationID, execution.ID)
+	defer untrack()
+
+	// Carry the execution through ctx so actions (e.g. approval requests) can reference it
+	ctx = we.beginExecutionScope(ctx, execution)
 
 	// Broadcast execution started event
 	we.broadcastExecutionEvent(execution)
@@ -146,20 +361,20 @@ func (we *WorkflowExecutor) Execute(
 	// Build execution context
 	execContext, err := we.contextBuilder.BuildContext(ctx, workflow.OrganizationID, triggerPayload, workflow.Definition.Context)
 	if err != nil {
-		we.logger.Errorf("Failed to build context: %v", err)
+		we.loggerFor(ctx).Errorf("Failed to build context: %v", err)
 		we.completeExecution(ctx, execution, models.ExecutionResultFailed, fmt.Sprintf("Context build failed: %v", err))
 		// Record metrics for failed execution
-		if we.metrics != nil {
-			we.metrics.WorkflowExecutionsTotal.WithLabelValues(workflowIDStr, "failed").Inc()
-			we.metrics.WorkflowDuration.WithLabelValues(workflowIDStr).Observe(time.Since(startTime).Seconds())
-			we.metrics.WorkflowErrors.WithLabelValues(workflowIDStr, "context_build_error").Inc()
+		if m != nil {
+			m.WorkflowExecutionsTotal.WithLabelValues(orgLabel, workflowIDStr, "failed").Inc()
+			m.WorkflowDuration.WithLabelValues(orgLabel, workflowIDStr).Observe(time.Since(startTime).Seconds())
+			m.WorkflowErrors.WithLabelValues(orgLabel, workflowIDStr, "context_build_error").Inc()
 		}
 		return execution, err
 	}
 
 	// Enrich context
-	if err := we.contextBuilder.EnrichContext(ctx, execContext); err != nil {
-		we.logger.Warnf("Failed to enrich context: %v", err)
+	if err := we.contextBuilder.EnrichContext(ctx, execContext, workflow.Definition.Context); err != nil {
+		we.loggerFor(ctx).Warnf("Failed to enrich context: %v", err)
 		// Continue execution even if enrichment fails
 	}
 
@@ -169,37 +384,57 @@ func (we *WorkflowExecutor) Execute(
 	result, err := we.executeSteps(ctx, execution, workflow, execContext)
 	if err != nil {
 		// Check if execution was paused (not a real error)
-		if err == ErrExecutionPaused {
-			we.logger.Infof("Workflow execution paused: %s", execution.ExecutionID)
+		if errors.Is(err, ErrExecutionPaused) {
+			we.loggerFor(ctx).Infof("Workflow execution paused: %s", execution.ExecutionID)
 			// Record metrics for paused execution
-			if we.metrics != nil {
-				we.metrics.WorkflowExecutionsTotal.WithLabelValues(workflowIDStr, "paused").Inc()
-				we.metrics.WorkflowDuration.WithLabelValues(workflowIDStr).Observe(time.Since(startTime).Seconds())
+			if m != nil {
+				m.WorkflowExecutionsTotal.WithLabelValues(orgLabel, workflowIDStr, "paused").Inc()
+				m.WorkflowDuration.WithLabelValues(orgLabel, workflowIDStr).Observe(time.Since(startTime).Seconds())
 			}
 			return execution, nil
 		}
 
+		if errors.Is(context.Cause(ctx), ErrExecutionCancelled) {
+			we.loggerFor(ctx).Infof("Workflow execution cancelled: %s", execution.ExecutionID)
+			we.recordCancellation(ctx, execution)
+			if m != nil {
+				m.WorkflowExecutionsTotal.WithLabelValues(orgLabel, workflowIDStr, "cancelled").Inc()
+				m.WorkflowDuration.WithLabelValues(orgLabel, workflowIDStr).Observe(time.Since(startTime).Seconds())
+			}
+			return execution, ErrExecutionCancelled
+		}
+
+		// Drain has already recorded the interruption
+		if errors.Is(context.Cause(ctx), ErrExecutionInterrupted) {
+			we.loggerFor(ctx).Warnf("Workflow execution interrupted by shutdown: %s", execution.ExecutionID)
+			if m != nil {
+				m.WorkflowExecutionsTotal.WithLabelValues(orgLabel, workflowIDStr, "interrupted").Inc()
+				m.WorkflowDuration.WithLabelValues(orgLabel, workflowIDStr).Observe(time.Since(startTime).Seconds())
+			}
+			return execution, ErrExecutionInterrupted
+		}
+
 		// Check for timeout
 		if ctx.Err() == context.DeadlineExceeded {
-			we.logger.Errorf("Workflow execution timed out: %s", execution.ExecutionID)
+			we.loggerFor(ctx).Errorf("Workflow execution timed out: %s", execution.ExecutionID)
 			timeoutMsg := fmt.Sprintf("Workflow execution timed out after %v", timeout)
 			we.completeExecution(context.Background(), execution, models.ExecutionResultFailed, timeoutMsg)
 			// Record metrics for timeout
-			if we.metrics != nil {
-				we.metrics.WorkflowExecutionsTotal.WithLabelValues(workflowIDStr, "timeout").Inc()
-				we.metrics.WorkflowDuration.WithLabelValues(workflowIDStr).Observe(time.Since(startTime).Seconds())
-				we.metrics.WorkflowErrors.WithLabelValues(workflowIDStr, "timeout").Inc()
+			if m != nil {
+				m.WorkflowExecutionsTotal.WithLabelValues(orgLabel, workflowIDStr, "timeout").Inc()
+				m.WorkflowDuration.WithLabelValues(orgLabel, workflowIDStr).Observe(time.Since(startTime).Seconds())
+				m.WorkflowErrors.WithLabelValues(orgLabel, workflowIDStr, "timeout").Inc()
 			}
 			return execution, fmt.Errorf("%s: %w", timeoutMsg, ctx.Err())
 		}
 
-		we.logger.Errorf("Workflow execution failed: %v", err)
+		we.loggerFor(ctx).Errorf("Workflow execution failed: %v", err)
 		we.completeExecution(ctx, execution, models.ExecutionResultFailed, err.Error())
 		// Record metrics for failed execution
-		if we.metrics != nil {
-			we.metrics.WorkflowExecutionsTotal.WithLabelValues(workflowIDStr, "failed").Inc()
-			we.metrics.WorkflowDuration.WithLabelValues(workflowIDStr).Observe(time.Since(startTime).Seconds())
-			we.metrics.WorkflowErrors.WithLabelValues(workflowIDStr, "execution_error").Inc()
+		if m != nil {
+			m.WorkflowExecutionsTotal.WithLabelValues(orgLabel, workflowIDStr, "failed").Inc()
+			m.WorkflowDuration.WithLabelValues(orgLabel, workflowIDStr).Observe(time.Since(startTime).Seconds())
+			m.WorkflowErrors.WithLabelValues(orgLabel, workflowIDStr, "execution_error").Inc()
 		}
 		return execution, err
 	}
@@ -207,12 +442,12 @@ func (we *WorkflowExecutor) Execute(
 	// Complete execution successfully
 	we.completeExecution(ctx, execution, result, "")
 
-	we.logger.Infof("Workflow execution completed: %s - Result: %s", execution.ExecutionID, result)
+	we.loggerFor(ctx).Infof("Workflow execution completed: %s - Result: %s", execution.ExecutionID, result)
 
 	// Record metrics for successful execution
-	if we.metrics != nil {
-		we.metrics.WorkflowExecutionsTotal.WithLabelValues(workflowIDStr, string(result)).Inc()
-		we.metrics.WorkflowDuration.WithLabelValues(workflowIDStr).Observe(time.Since(startTime).Seconds())
+	if m != nil {
+		m.WorkflowExecutionsTotal.WithLabelValues(orgLabel, workflowIDStr, string(result)).Inc()
+		m.WorkflowDuration.WithLabelValues(orgLabel, workflowIDStr).Observe(time.Since(startTime).Seconds())
 	}
 
 	return execution, nil
@@ -240,17 +475,14 @@ func (we *WorkflowExecutor) executeSteps(
 	currentStepID := workflow.Definition.Steps[0].ID
 	var finalResult models.ExecutionResult = models.ExecutionResultExecuted
 
+	visits := make(map[string]int)
+	maxVisits := we.getMaxStepVisits(workflow)
+
 	// Execute steps
 	for currentStepID != "" {
 		// Check for context cancellation (timeout or manual cancellation)
-		select {
-		case <-ctx.Done():
-			if ctx.Err() == context.DeadlineExceeded {
-				return models.ExecutionResultFailed, fmt.Errorf("workflow execution timed out")
-			}
-			return models.ExecutionResultFailed, ctx.Err()
-		default:
-			// Continue execution
+		if err := interruption(ctx); err != nil {
+			return models.ExecutionResultFailed, err
 		}
 
 		step, exists := stepMap[currentStepID]
@@ -258,14 +490,26 @@ func (we *WorkflowExecutor) executeSteps(
 			return models.ExecutionResultFailed, fmt.Errorf("step not found: %s", currentStepID)
 		}
 
-		we.logger.Infof("Executing step: %s (type: %s)", step.ID, step.Type)
+		visits[step.ID]++
+		if visits[step.ID] > maxVisits {
+			return models.ExecutionResultFailed, fmt.Errorf("step %s exceeded the maximum of %d visits, possible infinite loop", step.ID, maxVisits)
+		}
+
+		we.loggerFor(ctx).Infof("Executing step: %s (type: %s)", step.ID, step.Type)
 
 		// Execute step with retry logic
 		nextStepID, result, err := we.executeStepWithRetry(ctx, execution, step, execContext)
 		if err != nil {
+			if next, ok := we.recoverStepError(ctx, execution, step, err); ok {
+				currentStepID = next
+				continue
+			}
+			we.compensateFailure(ctx, execution, stepMap, execContext, err)
 			return models.ExecutionResultFailed, fmt.Errorf("step %s failed: %w", step.ID, err)
 		}
 
+		recordCompensation(execution, step)
+
 		// Update final result based on action result
 		if result != nil && result.Action == "block" {
 			finalResult = models.ExecutionResultBlocked
@@ -273,12 +517,34 @@ func (we *WorkflowExecutor) executeSteps(
 			finalResult = models.ExecutionResultAllowed
 		}
 
+		// A dry run follows the path the workflow would take once the awaited event arrives
+		if step.Type == "wait" && isDryRun(execution) {
+			nextStepID = resumeStepAfterWait(workflow, step)
+		}
+
 		currentStepID = nextStepID
 	}
 
 	return finalResult, nil
 }
 
+// resumeStepAfterWait returns the step a workflow continues with after a wait step
+// resumes: the step's on_resume metadata if set, otherwise the next step in sequence
+func resumeStepAfterWait(workflow *models.Workflow, waitStep *models.Step) string {
+	if waitStep.Metadata != nil {
+		if next, ok := waitStep.Metadata["on_resume"].(string); ok && next != "" {
+			return next
+		}
+	}
+
+	for i, s := range workflow.Definition.Steps {
+		if s.ID == waitStep.ID && i+1 < len(workflow.Definition.Steps) {
+			return workflow.Definition.Steps[i+1].ID
+		}
+	}
+	return ""
+}
+
 // executeStepWithRetry executes a single step with retry logic
 func (we *WorkflowExecutor) executeStepWithRetry(
 	ctx context.Context,
@@ -297,29 +563,61 @@ func (we *WorkflowExecutor) executeStepWithRetry(
 		if timeout > 0 {
 			stepCtx, cancel = context.WithTimeout(ctx, timeout)
 			defer cancel()
-			we.logger.Infof("Step %s timeout set to: %v", step.ID, timeout)
+			we.loggerFor(ctx).Infof("Step %s timeout set to: %v", step.ID, timeout)
 		}
 	}
 
 	// Check if step has retry configuration
-	if step.Retry != nil && step.Retry.MaxAttempts > 0 {
-		maxAttempts = step.Retry.MaxAttempts
+	var maxElapsed time.Duration
+	if step.Retry != nil {
+		if step.Retry.MaxAttempts > 0 {
+			maxAttempts = step.Retry.MaxAttempts
+		}
+		maxElapsed = we.parseTimeout(step.Retry.MaxElapsed, 0)
 	}
 
+	firstAttempt := time.Now()
+	attempts := 0
+
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		if attempt > 1 {
-			we.logger.Infof("Retrying step %s (attempt %d/%d)", step.ID, attempt, maxAttempts)
+			backoff := we.calculateBackoff(ctx, attempt, step.Retry)
+
+			// Stop retrying if the next attempt could not start within the retry budget
+			if maxElapsed > 0 && time.Since(firstAttempt)+backoff > maxElapsed {
+				we.loggerFor(ctx).Warnf("Step %s retry budget of %v exhausted after %d attempts", step.ID, maxElapsed, attempts)
+				return "", nil, fmt.Errorf("step failed after %d attempts (retry budget %v exhausted): %w", attempts, maxElapsed, lastErr)
+			}
+
+			// Likewise if the backoff would outlast the step-level timeout
+			if deadline, ok := stepCtx.Deadline(); ok && time.Now().Add(backoff).After(deadline) {
+				we.loggerFor(ctx).Warnf("Step %s timeout would expire during retry backoff after %d attempts", step.ID, attempts)
+				return "", nil, fmt.Errorf("step failed after %d attempts (step timeout reached before next retry): %w", attempts, lastErr)
+			}
+
+			we.loggerFor(ctx).Infof("Retrying step %s (attempt %d/%d)", step.ID, attempt, maxAttempts)
 
-			// Apply backoff
-			backoff := we.calculateBackoff(attempt, step.Retry)
-			time.Sleep(backoff)
+			// Apply backoff, giving up immediately if the execution is cancelled or times out
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-stepCtx.Done():
+				timer.Stop()
+				return "", nil, fmt.Errorf("step %s aborted during retry backoff: %w", step.ID, stepCtx.Err())
+			}
 		}
 
+		attempts++
 		nextStepID, result, err := we.executeStep(stepCtx, execution, step, execContext)
 		if err == nil {
 			return nextStepID, result, nil
 		}
 
+		// A pause is not a failure and must never be retried
+		if errors.Is(err, ErrExecutionPaused) {
+			return "", nil, err
+		}
+
 		// Check for step timeout
 		if stepCtx.Err() == context.DeadlineExceeded {
 			return "", nil, fmt.Errorf("step %s timed out: %w", step.ID, stepCtx.Err())
@@ -328,12 +626,215 @@ func (we *WorkflowExecutor) executeStepWithRetry(
 		lastErr = err
 
 		// Check if error is retryable
-		if step.Retry != nil && !we.isRetryableError(err, step.Retry.RetryOn) {
+		if step.Retry != nil && !we.isRetryableError(ctx, err, step.Retry.RetryOn) {
 			break
 		}
 	}
 
-	return "", nil, fmt.Errorf("step failed after %d attempts: %w", maxAttempts, lastErr)
+	return "", nil, fmt.Errorf("step failed after %d attempts: %w", attempts, lastErr)
+}
+
+// snapshotContext captures the context as a step sees it when it starts, since the live
+// map keeps changing as the workflow runs. The map is only written by the goroutine running
+// the step (parallel branches and foreach iterations each get their own copy), so no lock is needed.
+func snapshotContext(execContext map[string]interface{}) models.JSONB {
+	return models.JSONB(cloneContext(execContext))
+}
+
+// recoverStepError decides whether a failed step is non-fatal. When the step sets
+// on_error or continue_on_error, the failure is counted in execution metadata and the
+// next step to run is returned; otherwise ok is false and the workflow should fail.
+func (we *WorkflowExecutor) recoverStepError(
+	ctx context.Context,
+	execution *models.WorkflowExecution,
+	step *models.Step,
+	err error,
+) (string, bool) {
+	if errors.Is(err, ErrExecutionPaused) || (step.OnError == "" && !step.ContinueOnError) {
+		return "", false
+	}
+
+	nextStepID := step.OnError
+	if nextStepID == "" {
+		nextStepID = step.Next
+	}
+
+	we.loggerFor(ctx).Warnf("Step %s failed but is non-fatal, continuing to %q: %v", step.ID, nextStepID, err)
+
+	if execution.Metadata == nil {
+		execution.Metadata = make(models.JSONB)
+	}
+	// The counter may have been round-tripped through JSON on resume
+	count, _ := toFloat64(execution.Metadata["non_fatal_errors"])
+	execution.Metadata["non_fatal_errors"] = int(count) + 1
+
+	return nextStepID, true
+}
+
+// compensationStackKey is the execution metadata key listing the IDs of completed steps
+// that define compensations, in completion order. Keeping the stack in metadata means it is
+// persisted with the execution and survives a pause and resume.
+const compensationStackKey = "compensation_stack"
+
+// recordCompensation pushes a completed step onto the execution's compensation stack
+// if it defines compensating actions
+func recordCompensation(execution *models.WorkflowExecution, step *models.Step) {
+	if step.Compensate == nil || len(step.Compensate.Execute) == 0 {
+		return
+	}
+	if execution.Metadata == nil {
+		execution.Metadata = make(models.JSONB)
+	}
+	execution.Metadata[compensationStackKey] = append(compensationStack(execution), step.ID)
+}
+
+// compensationStack returns the step IDs on the execution's compensation stack.
+// After a resume the stack has been round-tripped through JSON as []interface{}.
+func compensationStack(execution *models.WorkflowExecution) []string {
+	switch stack := execution.Metadata[compensationStackKey].(type) {
+	case []string:
+		return stack
+	case []interface{}:
+		ids := make([]string, 0, len(stack))
+		for _, id := range stack {
+			if s, ok := id.(string); ok {
+				ids = append(ids, s)
+			}
+		}
+		return ids
+	}
+	return nil
+}
+
+// compensateFailure runs the compensation stack after a fatal step error. Pauses are not
+// failures, and dry runs never performed anything that needs undoing.
+func (we *WorkflowExecutor) compensateFailure(
+	ctx context.Context,
+	execution *models.WorkflowExecution,
+	stepMap map[string]*models.Step,
+	execContext map[string]interface{},
+	err error,
+) {
+	if errors.Is(err, ErrExecutionPaused) || isDryRun(execution) {
+		return
+	}
+
+	var steps []*models.Step
+	for _, id := range compensationStack(execution) {
+		if step, ok := stepMap[id]; ok && step.Compensate != nil {
+			steps = append(steps, step)
+		}
+	}
+
+	we.runCompensations(ctx, execution, steps, execContext)
+	delete(execution.Metadata, compensationStackKey)
+}
+
+// runCompensations undoes completed steps in reverse order after a failure (saga pattern).
+// Each compensation is recorded as its own step execution and summarized in execution metadata.
+func (we *WorkflowExecutor) runCompensations(
+	ctx context.Context,
+	execution *models.WorkflowExecution,
+	steps []*models.Step,
+	execContext map[string]interface{},
+) {
+	if len(steps) == 0 {
+		return
+	}
+
+	// Compensations must run even when the failure was a timeout or cancellation
+	ctx = context.WithoutCancel(ctx)
+
+	we.loggerFor(ctx).Infof("Running %d compensations for execution %s", len(steps), execution.ExecutionID)
+
+	summary := make([]map[string]interface{}, 0, len(steps))
+	for i := len(steps) - 1; i >= 0; i-- {
+		err := we.runCompensation(ctx, execution, steps[i], execContext)
+
+		entry := map[string]interface{}{
+			"step_id": steps[i].ID,
+			"success": err == nil,
+		}
+		if err != nil {
+			we.loggerFor(ctx).Errorf("Compensation for step %s failed: %v", steps[i].ID, err)
+			entry["error"] = err.Error()
+		}
+		summary = append(summary, entry)
+	}
+
+	if execution.Metadata == nil {
+		execution.Metadata = make(models.JSONB)
+	}
+	execution.Metadata["compensations"] = summary
+}
+
+// runCompensation executes the compensating actions for a single step
+func (we *WorkflowExecutor) runCompensation(
+	ctx context.Context,
+	execution *models.WorkflowExecution,
+	step *models.Step,
+	execContext map[string]interface{},
+) error {
+	stepExec := &models.StepExecution{
+		ID:             uuid.New(),
+		OrganizationID: execution.OrganizationID,
+		ExecutionID:    execution.ID,
+		StepID:         step.ID + ".compensate",
+		StepType:       "compensation",
+		Status:         models.StepStatusRunning,
+		Input:          models.JSONB{"compensates": step.ID},
+		StartedAt:      time.Now(),
+	}
+
+	if err := we.executionRepo.CreateStepExecution(ctx, stepExec); err != nil {
+		we.loggerFor(ctx).Errorf("Failed to create compensation step execution: %v", err)
+	}
+
+	compensationStep := &models.Step{
+		ID:      stepExec.StepID,
+		Type:    "action",
+		Action:  &models.Action{Type: "execute"},
+		Execute: step.Compensate.Execute,
+	}
+
+	result, err := we.actionExecutor.ExecuteAction(ctx, compensationStep, execContext)
+	if err == nil && result != nil {
+		// Individual action failures are reported in the results rather than as an error
+		if results, ok := result.Data["execute_results"].([]map[string]interface{}); ok {
+			for _, r := range results {
+				if success, ok := r["success"].(bool); ok && !success {
+					err = fmt.Errorf("compensating action %v failed: %v", r["type"], r["error"])
+					break
+				}
+			}
+		}
+	}
+
+	now := time.Now()
+	stepExec.CompletedAt = &now
+	duration := int(now.Sub(stepExec.StartedAt).Milliseconds())
+	stepExec.DurationMs = &duration
+	stepExec.Output = models.JSONB{
+		"compensates": step.ID,
+		"success":     err == nil,
+	}
+	if result != nil {
+		stepExec.Output["data"] = result.Data
+	}
+
+	if err != nil {
+		stepExec.Status = models.StepStatusFailed
+		errMsg := err.Error()
+		stepExec.ErrorMessage = &errMsg
+	} else {
+		stepExec.Status = models.StepStatusCompleted
+	}
+
+	if updateErr := we.executionRepo.UpdateStepExecution(ctx, stepExec.OrganizationID, stepExec); updateErr != nil {
+		we.loggerFor(ctx).Errorf("Failed to update compensation step execution: %v", updateErr)
+	}
+
+	return err
 }
 
 // executeStep executes a single workflow step
@@ -351,7 +852,7 @@ func (we *WorkflowExecutor) executeStep(
 		StepID:         step.ID,
 		StepType:       step.Type,
 		Status:         models.StepStatusRunning,
-		Input:          execContext,
+		Input:          snapshotContext(execContext),
 		StartedAt:      time.Now(),
 	}
 
@@ -359,8 +860,12 @@ func (we *WorkflowExecutor) executeStep(
 		return "", nil, fmt.Errorf("failed to create step execution: %w", err)
 	}
 
+	ctx, span := we.startStepSpan(ctx, step)
+	defer endStepSpan(span, stepExec)
+
 	var nextStepID string
 	var actionResult *ActionResult
+	var stepOutput models.JSONB
 	var err error
 
 	// Execute based on step type
@@ -368,26 +873,59 @@ func (we *WorkflowExecutor) executeStep(
 	case "condition":
 		nextStepID, err = we.executeConditionStep(ctx, execution, step, execContext)
 
+	case "switch":
+		nextStepID, err = we.executeSwitchStep(ctx, step, execContext)
+
 	case "action":
-		actionResult, err = we.executeActionStep(ctx, step, execContext)
-		nextStepID = "" // Action steps end the flow
+		actionCtx := ctx
+		if isAIAction(step.Action) {
+			actionCtx = we.withUsageScope(we.withStepProgress(ctx, execution, step), execution)
+		}
+		actionResult, err = we.executeActionStep(actionCtx, step, execContext, isDryRun(execution))
+		nextStepID = "" // Action steps end the flow, except AI actions, which feed later steps
+		if isAIAction(step.Action) {
+			nextStepID = step.Next
+		}
 
 	case "execute":
-		actionResult, err = we.executeExecuteStep(ctx, step, execContext)
+		actionResult, err = we.executeExecuteStep(ctx, step, execContext, isDryRun(execution))
 		nextStepID = "" // Execute steps end the flow
 
 	case "parallel":
-		err = we.executeParallelStep(ctx, execution, step, execContext)
-		nextStepID = step.Next // Support next step after parallel
+		nextStepID, stepOutput, err = we.executeParallelStep(ctx, execution, step, execContext)
 
 	case "foreach":
 		err = we.executeForEachStep(ctx, execution, step, execContext)
 		nextStepID = step.Next // Support next step after foreach
 
+	case "call":
+		nextStepID, stepOutput, err = we.executeCallStep(ctx, execution, step, execContext)
+
+	case "query":
+		nextStepID, stepOutput, err = we.executeQueryStep(ctx, step, execContext)
+
+	case "set":
+		stepOutput, err = we.executeSetStep(ctx, execution, step, execContext)
+		nextStepID = step.Next
+
 	case "wait":
-		err = we.executeWaitStep(ctx, execution, step, execContext)
+		if isDryRun(execution) {
+			// Never pause a dry run; executeSteps continues along the resume path instead
+			stepOutput = models.JSONB{"skipped": true, "dry_run": true}
+		} else {
+			err = we.executeWaitStep(ctx, execution, step, execContext)
+		}
 		nextStepID = "" // Wait steps pause the flow
 
+	case "delay":
+		if isDryRun(execution) {
+			// Never pause a dry run; continue as if the delay had passed
+			stepOutput = models.JSONB{"skipped": true, "dry_run": true}
+			nextStepID = step.Next
+		} else {
+			err = we.executeDelayStep(ctx, execution, step, execContext)
+		}
+
 	default:
 		err = fmt.Errorf("unsupported step type: %s", step.Type)
 	}
@@ -398,29 +936,90 @@ func (we *WorkflowExecutor) executeStep(
 	duration := int(now.Sub(stepExec.StartedAt).Milliseconds())
 	stepExec.DurationMs = &duration
 
+	if stepOutput != nil {
+		stepExec.Output = stepOutput
+	}
+
+	// Failed actions keep what they reported too, such as a webhook's final response
+	if actionResult != nil {
+		output := make(models.JSONB)
+		output["action"] = actionResult.Action
+		output["success"] = actionResult.Success
+		output["reason"] = actionResult.Reason
+		output["data"] = actionResult.Data
+		stepExec.Output = output
+	}
+
 	if err != nil {
 		stepExec.Status = models.StepStatusFailed
 		errMsg := err.Error()
 		stepExec.ErrorMessage = &errMsg
 	} else {
 		stepExec.Status = models.StepStatusCompleted
-		if actionResult != nil {
-			output := make(models.JSONB)
-			output["action"] = actionResult.Action
-			output["success"] = actionResult.Success
-			output["reason"] = actionResult.Reason
-			output["data"] = actionResult.Data
-			stepExec.Output = output
+		if actionResult != nil && actionResult.Action == "response" {
+			we.recordResponse(ctx, execution, actionResult)
 		}
 	}
 
+	// Dry runs are excluded from step metrics, as they are from execution metrics
+	if we.metrics != nil && !isDryRun(execution) {
+		status := string(stepExec.Status)
+		we.metrics.StepDuration.WithLabelValues(step.Type, status).Observe(float64(duration) / 1000)
+		we.metrics.StepExecutionsTotal.WithLabelValues(step.Type, status).Inc()
+	}
+
 	if updateErr := we.executionRepo.UpdateStepExecution(ctx, stepExec.OrganizationID, stepExec); updateErr != nil {
-		we.logger.Errorf("Failed to update step execution: %v", updateErr)
+		we.loggerFor(ctx).Errorf("Failed to update step execution: %v", updateErr)
 	}
 
 	return nextStepID, actionResult, err
 }
 
+// recordResponse stores a response action's status and body on the execution so a
+// synchronous caller can return it. A later response action replaces an earlier one.
+func (we *WorkflowExecutor) recordResponse(ctx context.Context, execution *models.WorkflowExecution, result *ActionResult) {
+	// Response actions may run in concurrent parallel branches of the same execution
+	if scope := scopeFromContext(ctx); scope != nil {
+		scope.metadataMu.Lock()
+		defer scope.metadataMu.Unlock()
+	}
+
+	if execution.Metadata == nil {
+		execution.Metadata = make(models.JSONB)
+	}
+	execution.Metadata["response"] = map[string]interface{}{
+		"status_code": result.Data["status_code"],
+		"body":        result.Data["body"],
+	}
+
+	if body, ok := result.Data["body"].(map[string]interface{}); ok {
+		mergeResultPayload(execution, body)
+	}
+}
+
+// recordResultPayload adds values to the execution's result payload, replacing any values
+// already recorded under the same keys
+func (we *WorkflowExecutor) recordResultPayload(ctx context.Context, execution *models.WorkflowExecution, values map[string]interface{}) {
+	// Set steps may run in concurrent parallel branches of the same execution
+	if scope := scopeFromContext(ctx); scope != nil {
+		scope.metadataMu.Lock()
+		defer scope.metadataMu.Unlock()
+	}
+
+	mergeResultPayload(execution, values)
+}
+
+// mergeResultPayload adds values to the execution's result payload. Callers hold the scope's
+// metadata lock
+func mergeResultPayload(execution *models.WorkflowExecution, values map[string]interface{}) {
+	if execution.ResultPayload == nil {
+		execution.ResultPayload = make(models.JSONB)
+	}
+	for key, value := range values {
+		execution.ResultPayload[key] = cloneValue(value)
+	}
+}
+
 // executeConditionStep executes a condition step
 func (we *WorkflowExecutor) executeConditionStep(
 	ctx context.Context,
@@ -458,7 +1057,7 @@ func (we *WorkflowExecutor) executeConditionStep(
 		}
 
 		condition = &rule.Definition.Conditions[0]
-		we.logger.Infof("Using rule %s for condition evaluation", step.RuleID)
+		we.loggerFor(ctx).Infof("Using rule %s for condition evaluation", step.RuleID)
 	} else if step.Condition != nil {
 		// Use inline condition
 		condition = step.Condition
@@ -467,12 +1066,12 @@ func (we *WorkflowExecutor) executeConditionStep(
 	}
 
 	// Evaluate the condition
-	result, err := we.evaluator.EvaluateCondition(condition, execContext)
+	result, err := we.evaluatorFor(ctx).EvaluateCondition(condition, execContext)
 	if err != nil {
 		return "", fmt.Errorf("condition evaluation failed: %w", err)
 	}
 
-	we.logger.Infof("Condition evaluated to: %v", result)
+	we.loggerFor(ctx).Infof("Condition evaluated to: %v", result)
 
 	if result {
 		return step.OnTrue, nil
@@ -480,12 +1079,32 @@ func (we *WorkflowExecutor) executeConditionStep(
 	return step.OnFalse, nil
 }
 
+// executeSwitchStep selects the next step from the first matching switch case
+func (we *WorkflowExecutor) executeSwitchStep(
+	ctx context.Context,
+	step *models.Step,
+	execContext map[string]interface{},
+) (string, error) {
+	nextStepID, err := we.evaluatorFor(ctx).EvaluateSwitch(step.Switch, execContext)
+	if err != nil {
+		return "", fmt.Errorf("switch evaluation failed: %w", err)
+	}
+
+	we.loggerFor(ctx).Infof("Switch step %s routed to: %s", step.ID, nextStepID)
+	return nextStepID, nil
+}
+
 // executeActionStep executes an action step
+// In a dry run, allow/block decisions are still made but side-effecting actions are skipped
 func (we *WorkflowExecutor) executeActionStep(
 	ctx context.Context,
 	step *models.Step,
 	execContext map[string]interface{},
+	dryRun bool,
 ) (*ActionResult, error) {
+	if dryRun && step.Action != nil && step.Action.Type == "execute" {
+		return dryRunResult(step.Action.Type, step.Execute), nil
+	}
 	return we.actionExecutor.ExecuteAction(ctx, step, execContext)
 }
 
@@ -494,11 +1113,16 @@ func (we *WorkflowExecutor) executeExecuteStep(
 	ctx context.Context,
 	step *models.Step,
 	execContext map[string]interface{},
+	dryRun bool,
 ) (*ActionResult, error) {
 	if len(step.Execute) == 0 {
 		return nil, fmt.Errorf("execute step has no execute actions defined")
 	}
 
+	if dryRun {
+		return dryRunResult("execute", step.Execute), nil
+	}
+
 	// Create a synthetic Action with type "execute" to reuse existing logic
 	syntheticStep := &models.Step{
 		ID:   step.ID,
@@ -512,70 +1136,152 @@ func (we *WorkflowExecutor) executeExecuteStep(
 	return we.actionExecutor.ExecuteAction(ctx, syntheticStep, execContext)
 }
 
-// executeParallelStep executes steps in parallel
+// dryRunResult builds the synthetic result returned for skipped actions in a dry run
+func dryRunResult(actionType string, actions []models.ExecuteAction) *ActionResult {
+	skipped := make([]string, 0, len(actions))
+	for _, action := range actions {
+		skipped = append(skipped, action.Type)
+	}
+
+	return &ActionResult{
+		Action:  actionType,
+		Success: true,
+		Reason:  "Skipped in dry run",
+		Data: map[string]interface{}{
+			"skipped":         true,
+			"skipped_actions": skipped,
+		},
+	}
+}
+
+// isDryRun reports whether an execution was started in dry-run mode
+func isDryRun(execution *models.WorkflowExecution) bool {
+	if execution == nil || execution.Metadata == nil {
+		return false
+	}
+	dryRun, _ := execution.Metadata["dry_run"].(bool)
+	return dryRun
+}
+
+// executeParallelStep executes steps in parallel and resolves the next step
+// from the aggregate outcome. The returned output records per-branch failures.
 func (we *WorkflowExecutor) executeParallelStep(
 	ctx context.Context,
 	execution *models.WorkflowExecution,
 	step *models.Step,
 	execContext map[string]interface{},
-) error {
+) (string, models.JSONB, error) {
 	if step.Parallel == nil || len(step.Parallel.Steps) == 0 {
-		return fmt.Errorf("parallel step has no steps defined")
+		return "", nil, fmt.Errorf("parallel step has no steps defined")
 	}
 
-	we.logger.Infof("Executing %d steps in parallel", len(step.Parallel.Steps))
+	we.loggerFor(ctx).Infof("Executing %d steps in parallel", len(step.Parallel.Steps))
 
 	var wg sync.WaitGroup
 	results := make([]error, len(step.Parallel.Steps))
+	branchData := make([]interface{}, len(step.Parallel.Steps))
+
+	// Each branch runs against its own copy of the context so branches never read a map
+	// another branch is writing; their writes are merged back once all branches finish
+	branchContexts := make([]map[string]interface{}, len(step.Parallel.Steps))
+	for i := range branchContexts {
+		branchContexts[i] = copyContext(execContext)
+	}
 
 	for i, parallelStep := range step.Parallel.Steps {
 		wg.Add(1)
 		go func(index int, s models.Step) {
 			defer wg.Done()
-			_, _, err := we.executeStep(ctx, execution, &s, execContext)
+			_, actionResult, err := we.executeStep(ctx, execution, &s, branchContexts[index])
 			results[index] = err
+			// Failed branches and branches without result data are collected as nil
+			if err == nil && actionResult != nil && actionResult.Data != nil {
+				branchData[index] = actionResult.Data
+			}
 		}(i, parallelStep)
 	}
 
 	wg.Wait()
 
-	// Evaluate results based on strategy
-	strategy := step.Parallel.Strategy
+	// Later branches win when two branches set the same variable
+	for _, branchContext := range branchContexts {
+		mergeBranchContext(execContext, branchContext)
+	}
+
+	// Merge branch outputs into the context so subsequent steps can inspect them
+	if step.Parallel.CollectInto != "" {
+		execContext[step.Parallel.CollectInto] = branchData
+	}
+
+	// Evaluate results based on strategy
+	strategy := step.Parallel.Strategy
 	if strategy == "" {
 		strategy = "all_must_pass"
 	}
 
+	failures := make([]map[string]interface{}, 0)
+	for i, err := range results {
+		if err != nil {
+			failures = append(failures, map[string]interface{}{
+				"index":   i,
+				"step_id": step.Parallel.Steps[i].ID,
+				"error":   err.Error(),
+			})
+		}
+	}
+	succeeded := len(results) - len(failures)
+
+	output := models.JSONB{
+		"strategy":  strategy,
+		"succeeded": succeeded,
+		"failed":    len(failures),
+		"failures":  failures,
+	}
+
+	onSuccess := step.Parallel.OnSuccess
+	if onSuccess == "" {
+		onSuccess = step.Next
+	}
+
+	// Determine whether the strategy was satisfied
+	var strategyErr error
 	switch strategy {
 	case "all_must_pass":
 		for i, err := range results {
 			if err != nil {
-				return fmt.Errorf("parallel step %d failed: %w", i, err)
+				strategyErr = fmt.Errorf("parallel step %d failed: %w", i, err)
+				break
 			}
 		}
-		return nil
 
 	case "any_can_pass":
-		for _, err := range results {
-			if err == nil {
-				return nil
-			}
+		if succeeded == 0 {
+			strategyErr = fmt.Errorf("all parallel steps failed")
 		}
-		return fmt.Errorf("all parallel steps failed")
 
 	case "best_effort":
-		// Continue even if some steps fail
-		failedCount := 0
-		for _, err := range results {
-			if err != nil {
-				failedCount++
-			}
+		// Continue even if some steps fail, but allow a distinct branch for partial failure
+		we.loggerFor(ctx).Infof("Parallel execution: %d/%d steps succeeded", succeeded, len(results))
+		if len(failures) > 0 && step.Parallel.OnFailure != "" {
+			output["next_step"] = step.Parallel.OnFailure
+			return step.Parallel.OnFailure, output, nil
 		}
-		we.logger.Infof("Parallel execution: %d/%d steps succeeded", len(results)-failedCount, len(results))
-		return nil
 
 	default:
-		return fmt.Errorf("unknown parallel strategy: %s", strategy)
+		return "", output, fmt.Errorf("unknown parallel strategy: %s", strategy)
 	}
+
+	if strategyErr != nil {
+		if step.Parallel.OnFailure == "" {
+			return "", output, strategyErr
+		}
+		we.loggerFor(ctx).Infof("Parallel step %s failed (%v), routing to %s", step.ID, strategyErr, step.Parallel.OnFailure)
+		output["next_step"] = step.Parallel.OnFailure
+		return step.Parallel.OnFailure, output, nil
+	}
+
+	output["next_step"] = onSuccess
+	return onSuccess, output, nil
 }
 
 // executeForEachStep executes steps for each item in a collection
@@ -598,66 +1304,171 @@ func (we *WorkflowExecutor) executeForEachStep(
 	}
 
 	// Resolve the items collection from context
-	items, err := we.resolveItemsCollection(step.ForEach.Items, execContext)
+	items, err := we.resolveItemsCollection(ctx, step.ForEach.Items, execContext)
 	if err != nil {
 		return fmt.Errorf("failed to resolve items collection: %w", err)
 	}
 
-	we.logger.Infof("Executing foreach loop over %d items", len(items))
+	concurrency := step.ForEach.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if concurrency > len(items) {
+		concurrency = len(items)
+	}
 
-	// Execute steps for each item
-	for i, item := range items {
-		// Create a new context with the item variable
-		itemContext := make(map[string]interface{})
-		for k, v := range execContext {
-			itemContext[k] = v
-		}
+	we.loggerFor(ctx).Infof("Executing foreach loop over %d items (concurrency %d)", len(items), concurrency)
+
+	// Execute all steps for a single item against its own copy of the context
+	runIteration := func(i int, item interface{}) (interface{}, error) {
+		itemContext := copyContext(execContext)
 		itemContext[step.ForEach.ItemVar] = item
 		itemContext["_index"] = i
 
-		we.logger.Infof("Executing foreach iteration %d/%d", i+1, len(items))
+		we.loggerFor(ctx).Infof("Executing foreach iteration %d/%d", i+1, len(items))
 
-		// Execute all steps for this item
+		var lastResult *ActionResult
 		for _, foreachStep := range step.ForEach.Steps {
-			_, _, err := we.executeStep(ctx, execution, &foreachStep, itemContext)
+			_, actionResult, err := we.executeStep(ctx, execution, &foreachStep, itemContext)
 			if err != nil {
-				return fmt.Errorf("foreach iteration %d, step %s failed: %w", i, foreachStep.ID, err)
+				return nil, fmt.Errorf("foreach iteration %d, step %s failed: %w", i, foreachStep.ID, err)
+			}
+			if actionResult != nil {
+				lastResult = actionResult
+			}
+		}
+
+		return we.foreachIterationResult(ctx, step.ForEach, itemContext, lastResult), nil
+	}
+
+	errs := make([]error, len(items))
+	results := make([]interface{}, len(items))
+
+	if concurrency <= 1 {
+		for i, item := range items {
+			results[i], errs[i] = runIteration(i, item)
+			if errs[i] != nil && !step.ForEach.ContinueOnError {
+				return errs[i]
 			}
 		}
+	} else {
+		jobs := make(chan int)
+		var stopped atomic.Bool
+		var wg sync.WaitGroup
+
+		for w := 0; w < concurrency; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					result, err := runIteration(i, items[i])
+					if err != nil {
+						errs[i] = err
+						if !step.ForEach.ContinueOnError {
+							stopped.Store(true)
+						}
+						continue
+					}
+					results[i] = result
+				}
+			}()
+		}
+
+		// Stop dispatching new items after a failure; in-flight iterations finish
+		for i := range items {
+			if stopped.Load() {
+				break
+			}
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+
+		// Report the first failure by index so errors are deterministic
+		if !step.ForEach.ContinueOnError {
+			for _, err := range errs {
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Expose aggregated results to subsequent steps
+	if step.ForEach.ResultVar != "" {
+		execContext[step.ForEach.ResultVar] = results
+	}
+
+	failedCount := 0
+	for _, err := range errs {
+		if err != nil {
+			failedCount++
+		}
+	}
+	if failedCount > 0 {
+		we.loggerFor(ctx).Warnf("Foreach loop completed with %d/%d failed iterations", failedCount, len(items))
 	}
 
-	we.logger.Infof("Foreach loop completed: processed %d items", len(items))
+	we.loggerFor(ctx).Infof("Foreach loop completed: processed %d items", len(items))
 	return nil
 }
 
-// resolveItemsCollection resolves a collection from a variable reference or JSONPath
-func (we *WorkflowExecutor) resolveItemsCollection(items string, execContext map[string]interface{}) ([]interface{}, error) {
-	// Handle variable references like {{items}} or {{order.line_items}}
-	if len(items) > 4 && items[:2] == "{{" && items[len(items)-2:] == "}}" {
-		varPath := items[2 : len(items)-2]
-		value := we.evaluator.ResolveVariable(varPath, execContext)
+// foreachIterationResult builds the value recorded for a completed iteration.
+// Iterations without an action result (or an unresolvable expression) yield nil.
+func (we *WorkflowExecutor) foreachIterationResult(
+	ctx context.Context,
+	forEach *models.ForEachStep,
+	itemContext map[string]interface{},
+	lastResult *ActionResult,
+) interface{} {
+	if forEach.ResultExpr != "" {
+		expr := strings.TrimSpace(forEach.ResultExpr)
+		if strings.HasPrefix(expr, "{{") && strings.HasSuffix(expr, "}}") {
+			expr = strings.TrimSpace(expr[2 : len(expr)-2])
+		}
+		value, _ := we.evaluatorFor(ctx).ResolveVariable(expr, itemContext)
+		return value
+	}
+
+	if lastResult == nil {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"action":  lastResult.Action,
+		"success": lastResult.Success,
+		"reason":  lastResult.Reason,
+		"data":    lastResult.Data,
+	}
+}
 
-		if value == nil {
-			return nil, fmt.Errorf("variable %s not found in context", varPath)
+// resolveItemsCollection resolves a collection from a literal JSON array or a variable reference
+func (we *WorkflowExecutor) resolveItemsCollection(ctx context.Context, items string, execContext map[string]interface{}) ([]interface{}, error) {
+	// Handle inline arrays like ["a","b"] or [{"sku":"A1"}]
+	if trimmed := strings.TrimSpace(items); strings.HasPrefix(trimmed, "[") {
+		var literal []interface{}
+		if err := json.Unmarshal([]byte(trimmed), &literal); err != nil {
+			return nil, fmt.Errorf("items is not a valid JSON array: %w", err)
 		}
+		return literal, nil
+	}
 
-		// Convert value to array
-		switch v := value.(type) {
-		case []interface{}:
-			return v, nil
-		case []map[string]interface{}:
-			result := make([]interface{}, len(v))
-			for i, item := range v {
-				result[i] = item
-			}
-			return result, nil
-		default:
+	// Handle variable references like {{items}} or {{order.fulfillments[0].items}}
+	if len(items) > 4 && items[:2] == "{{" && items[len(items)-2:] == "}}" {
+		varPath := strings.TrimSpace(items[2 : len(items)-2])
+		value, err := we.evaluatorFor(ctx).ResolvePath(varPath, execContext)
+		if err != nil {
+			return nil, err
+		}
+
+		collection, ok := toSlice(value)
+		if !ok {
 			return nil, fmt.Errorf("variable %s is not a collection (type: %T)", varPath, value)
 		}
+		return collection, nil
 	}
 
-	// If not a variable reference, try to interpret as literal JSON array
-	return nil, fmt.Errorf("items must be a variable reference like {{variable}}")
+	return nil, fmt.Errorf("items must be a variable reference like {{variable}} or a JSON array")
 }
 
 // ErrExecutionPaused is returned when a workflow execution is paused (waiting)
@@ -674,26 +1485,33 @@ func (we *WorkflowExecutor) executeWaitStep(
 		return fmt.Errorf("wait step has no wait configuration")
 	}
 
-	we.logger.Infof("Wait step: pausing execution to wait for event %s", step.Wait.Event)
+	events := step.Wait.EventNames()
+	if len(events) == 0 {
+		return fmt.Errorf("wait step has no event to wait for")
+	}
+
+	we.loggerFor(ctx).Infof("Wait step: pausing execution to wait for event %s", strings.Join(events, " or "))
 
 	// Calculate timeout if specified
 	var timeoutAt *time.Time
 	if step.Wait.Timeout != "" {
-		duration, err := time.ParseDuration(step.Wait.Timeout)
+		deadline, err := we.resolveWaitDeadline(ctx, step.Wait.Timeout, execContext, time.Now())
 		if err != nil {
-			return fmt.Errorf("invalid timeout duration: %w", err)
+			return err
 		}
-		timeout := time.Now().Add(duration)
-		timeoutAt = &timeout
+		timeoutAt = &deadline
 	}
 
 	// Create wait state
 	waitState := &models.WaitState{
-		Event:        step.Wait.Event,
+		Event:        events[0],
 		TimeoutAt:    timeoutAt,
 		OnTimeout:    step.Wait.OnTimeout,
 		WaitingSince: time.Now(),
 	}
+	if len(events) > 1 {
+		waitState.Events = events
+	}
 
 	// Update execution to waiting status
 	execution.Status = models.ExecutionStatusWaiting
@@ -704,12 +1522,409 @@ func (we *WorkflowExecutor) executeWaitStep(
 		return fmt.Errorf("failed to update execution to waiting state: %w", err)
 	}
 
-	we.logger.Infof("Execution %s paused, waiting for event: %s", execution.ExecutionID, step.Wait.Event)
+	we.loggerFor(ctx).Infof("Execution %s paused, waiting for event: %s", execution.ExecutionID, strings.Join(events, " or "))
 
 	// Return special error to signal pause
 	return ErrExecutionPaused
 }
 
+// executeDelayStep pauses the execution until the step's delay has passed. The resume time is
+// stored in the execution's wait state, where ResumeDelayedExecution picks it up
+func (we *WorkflowExecutor) executeDelayStep(
+	ctx context.Context,
+	execution *models.WorkflowExecution,
+	step *models.Step,
+	execContext map[string]interface{},
+) error {
+	if step.Delay == nil || step.Delay.Duration == "" {
+		return fmt.Errorf("delay step has no duration defined")
+	}
+
+	now := time.Now()
+	resumeAt, err := we.resolveWaitDeadline(ctx, step.Delay.Duration, execContext, now)
+	if err != nil {
+		return fmt.Errorf("invalid delay: %w", err)
+	}
+
+	execution.Status = models.ExecutionStatusWaiting
+	execution.CurrentStepID = &step.ID
+	execution.WaitState = &models.WaitState{ResumeAt: &resumeAt, WaitingSince: now}
+
+	if err := we.executionRepo.UpdateExecution(ctx, execution.OrganizationID, execution); err != nil {
+		return fmt.Errorf("failed to update execution to waiting state: %w", err)
+	}
+
+	we.loggerFor(ctx).Infof("Execution %s delayed until %s", execution.ExecutionID, resumeAt.Format(time.RFC3339))
+
+	return ErrExecutionPaused
+}
+
+// resolveWaitDeadline computes when a wait step times out. The timeout may be a static
+// duration ("24h") or a {{expression}} resolving to a duration string or RFC3339 timestamp.
+func (we *WorkflowExecutor) resolveWaitDeadline(ctx context.Context, timeout string, execContext map[string]interface{}, now time.Time) (time.Time, error) {
+	expr := strings.TrimSpace(timeout)
+	if !strings.HasPrefix(expr, "{{") || !strings.HasSuffix(expr, "}}") {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timeout duration: %w", err)
+		}
+		return now.Add(duration), nil
+	}
+
+	path := strings.TrimSpace(expr[2 : len(expr)-2])
+	value, err := we.evaluatorFor(ctx).ResolvePath(path, execContext)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to resolve wait timeout %s: %w", timeout, err)
+	}
+
+	var deadline time.Time
+	switch v := value.(type) {
+	case time.Time:
+		deadline = v
+	case string:
+		if duration, err := time.ParseDuration(v); err == nil {
+			deadline = now.Add(duration)
+		} else if parsed, err := time.Parse(time.RFC3339, v); err == nil {
+			deadline = parsed
+		} else {
+			return time.Time{}, fmt.Errorf("wait timeout %s resolved to %q, which is neither a duration nor an RFC3339 timestamp", timeout, v)
+		}
+	default:
+		return time.Time{}, fmt.Errorf("wait timeout %s resolved to unsupported type %T", timeout, value)
+	}
+
+	if !deadline.After(now) {
+		return time.Time{}, fmt.Errorf("wait timeout %s resolved to %s, which is not in the future", timeout, deadline.Format(time.RFC3339))
+	}
+
+	return deadline, nil
+}
+
+// executionScope carries per-execution state through ctx, so concurrent executions
+// sharing one WorkflowExecutor never read or write each other's state
+type executionScope struct {
+	executionID    uuid.UUID
+	organizationID uuid.UUID
+	metadataMu     sync.Mutex     // Guards execution metadata and result payload written from concurrent parallel branches
+	evaluator      *Evaluator     // Bound to this execution's path cache; nil uses the executor's evaluator
+	logger         *logger.Logger // Logs with this execution's correlation ID; nil uses the caller's logger
+}
+
+// executionScopeKey is the context key for the current executionScope
+type executionScopeKey struct{}
+
+// withExecutionScope starts a new execution scope; a sub-workflow gets its own
+func withExecutionScope(ctx context.Context, executionID uuid.UUID, evaluator *Evaluator) context.Context {
+	return context.WithValue(ctx, executionScopeKey{}, &executionScope{executionID: executionID, evaluator: evaluator})
+}
+
+// beginExecutionScope starts the scope for an execution with a fresh path cache. The execution's
+// correlation ID is carried through ctx, so it is logged and forwarded on outbound requests
+func (we *WorkflowExecutor) beginExecutionScope(ctx context.Context, execution *models.WorkflowExecution) context.Context {
+	if execution.CorrelationID != "" {
+		ctx = correlation.WithID(ctx, execution.CorrelationID)
+	}
+
+	ctx = withExecutionScope(ctx, execution.ID, we.evaluator.WithPathResolver(NewPathResolver()))
+	scope := scopeFromContext(ctx)
+	scope.organizationID = execution.OrganizationID
+	scope.logger = we.logger.WithContext(ctx)
+	return ctx
+}
+
+// pathResolverFromContext returns the path cache of the execution running on ctx, or nil
+func pathResolverFromContext(ctx context.Context) *PathResolver {
+	if scope := scopeFromContext(ctx); scope != nil && scope.evaluator != nil {
+		return scope.evaluator.paths
+	}
+	return nil
+}
+
+// evaluatorFor returns the evaluator of the execution running on ctx, falling back to the shared one
+func (we *WorkflowExecutor) evaluatorFor(ctx context.Context) *Evaluator {
+	if scope := scopeFromContext(ctx); scope != nil && scope.evaluator != nil {
+		return scope.evaluator
+	}
+	return we.evaluator
+}
+
+// loggerFor returns the logger of the execution running on ctx, which adds its correlation ID
+func (we *WorkflowExecutor) loggerFor(ctx context.Context) *logger.Logger {
+	return loggerFor(ctx, we.logger)
+}
+
+// loggerFor returns the logger of the execution running on ctx, falling back to log with any
+// correlation ID ctx carries
+func loggerFor(ctx context.Context, log *logger.Logger) *logger.Logger {
+	if scope := scopeFromContext(ctx); scope != nil && scope.logger != nil {
+		return scope.logger
+	}
+	return log.WithContext(ctx)
+}
+
+// scopeFromContext returns the scope of the execution running on ctx, or nil
+func scopeFromContext(ctx context.Context) *executionScope {
+	scope, _ := ctx.Value(executionScopeKey{}).(*executionScope)
+	return scope
+}
+
+// executionIDFromContext returns the ID of the execution running on ctx, or uuid.Nil
+func executionIDFromContext(ctx context.Context) uuid.UUID {
+	if scope := scopeFromContext(ctx); scope != nil {
+		return scope.executionID
+	}
+	return uuid.Nil
+}
+
+// organizationIDFromContext returns the organization of the execution running on ctx, or uuid.Nil
+func organizationIDFromContext(ctx context.Context) uuid.UUID {
+	if scope := scopeFromContext(ctx); scope != nil {
+		return scope.organizationID
+	}
+	return uuid.Nil
+}
+
+// maxCallDepth bounds how deeply call steps may nest sub-workflows
+const maxCallDepth = 10
+
+// callChainKey is the context key for the chain of workflows currently executing via call steps
+type callChainKey struct{}
+
+// callChain returns the workflows on the current call path, outermost first
+func callChain(ctx context.Context) []string {
+	chain, _ := ctx.Value(callChainKey{}).([]string)
+	return chain
+}
+
+// withCallFrame appends workflow to the call chain carried by ctx
+func withCallFrame(ctx context.Context, workflow *models.Workflow) context.Context {
+	chain := callChain(ctx)
+	next := make([]string, len(chain), len(chain)+1)
+	copy(next, chain)
+	return context.WithValue(ctx, callChainKey{}, append(next, callFrameID(workflow)))
+}
+
+// callFrameID identifies a workflow in the call chain by its WorkflowID, falling back to its row ID
+func callFrameID(workflow *models.Workflow) string {
+	if workflow.WorkflowID != "" {
+		return workflow.WorkflowID
+	}
+	return workflow.ID.String()
+}
+
+// executeCallStep runs another workflow as a sub-workflow. The child runs in the parent's
+// organization under the parent's remaining timeout budget, and its result is stored in the
+// parent context under the call's result variable.
+func (we *WorkflowExecutor) executeCallStep(
+	ctx context.Context,
+	execution *models.WorkflowExecution,
+	step *models.Step,
+	execContext map[string]interface{},
+) (string, models.JSONB, error) {
+	if step.Call == nil || step.Call.WorkflowID == "" {
+		return "", nil, fmt.Errorf("call step has no workflow_id defined")
+	}
+	if we.workflowRepo == nil {
+		return "", nil, fmt.Errorf("call step requires a workflow repository")
+	}
+
+	chain := callChain(ctx)
+	for _, id := range chain {
+		if id == step.Call.WorkflowID {
+			return "", nil, fmt.Errorf("recursive workflow call: %s -> %s", strings.Join(chain, " -> "), step.Call.WorkflowID)
+		}
+	}
+	if len(chain) >= maxCallDepth {
+		return "", nil, fmt.Errorf("call to workflow %s exceeds the maximum sub-workflow depth of %d", step.Call.WorkflowID, maxCallDepth)
+	}
+
+	child, err := we.workflowRepo.GetByWorkflowID(ctx, execution.OrganizationID, step.Call.WorkflowID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load called workflow %s: %w", step.Call.WorkflowID, err)
+	}
+	if !child.Enabled {
+		return "", nil, fmt.Errorf("called workflow %s is disabled", step.Call.WorkflowID)
+	}
+
+	payload, err := we.buildCallInput(ctx, step.Call.Input, execContext)
+	if err != nil {
+		return "", nil, err
+	}
+
+	we.loggerFor(ctx).Infof("Call step %s: invoking workflow %s", step.ID, step.Call.WorkflowID)
+
+	parentID := execution.ID
+	childExec, childErr := we.ExecuteWithOptions(ctx, execution.OrganizationID, child, child.Definition.Trigger.Event, payload, ExecutionOptions{
+		DryRun:            isDryRun(execution),
+		ParentExecutionID: &parentID,
+	})
+
+	if childExec == nil {
+		return "", nil, fmt.Errorf("called workflow %s failed to start: %w", step.Call.WorkflowID, childErr)
+	}
+
+	output := models.JSONB{
+		"workflow_id":  step.Call.WorkflowID,
+		"execution_id": childExec.ID.String(),
+		"status":       string(childExec.Status),
+	}
+	if childExec.Result != nil {
+		output["result"] = string(*childExec.Result)
+	}
+
+	if childErr != nil {
+		return "", output, fmt.Errorf("called workflow %s failed: %w", step.Call.WorkflowID, childErr)
+	}
+
+	// A paused child has only run part of its steps, so there is no result to continue with
+	if childExec.Status == models.ExecutionStatusWaiting || childExec.Status == models.ExecutionStatusPaused {
+		return "", output, fmt.Errorf("called workflow %s paused (status: %s); call steps require sub-workflows that run to completion", step.Call.WorkflowID, childExec.Status)
+	}
+
+	resultVar := step.Call.ResultVar
+	if resultVar == "" {
+		resultVar = step.ID
+	}
+
+	childResult := map[string]interface{}{
+		"execution_id": output["execution_id"],
+		"status":       output["status"],
+		"result":       output["result"],
+		"context":      cloneContext(childExec.Context),
+	}
+
+	execContext[resultVar] = childResult
+
+	return step.Next, output, nil
+}
+
+// executeQueryStep runs a query step's read-only SQL and stores the rows it read in context
+// under the step's result variable
+func (we *WorkflowExecutor) executeQueryStep(
+	ctx context.Context,
+	step *models.Step,
+	execContext map[string]interface{},
+) (string, models.JSONB, error) {
+	if step.Query == nil {
+		return "", nil, fmt.Errorf("query step has no query defined")
+	}
+	if we.queryRunner == nil {
+		return "", nil, fmt.Errorf("query steps require configured datasources")
+	}
+
+	var timeout time.Duration
+	if step.Query.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(step.Query.Timeout); err != nil {
+			return "", nil, fmt.Errorf("invalid query timeout: %w", err)
+		}
+	}
+
+	// Parameters are rendered keeping their types, and are only ever bound, never spliced into the SQL
+	paths := pathResolverFromContext(ctx)
+	params := make([]interface{}, len(step.Query.Params))
+	for i, param := range step.Query.Params {
+		params[i] = renderString(param, execContext, paths)
+	}
+
+	result, err := we.queryRunner.Run(ctx, step.Query.Datasource, step.Query.SQL, params, step.Query.MaxRows, timeout)
+	if err != nil {
+		return "", models.JSONB{"datasource": step.Query.Datasource}, fmt.Errorf("query step %s: %w", step.ID, err)
+	}
+
+	resultVar := step.Query.ResultVar
+	if resultVar == "" {
+		resultVar = step.ID
+	}
+	execContext[resultVar] = result.Rows
+
+	output := models.JSONB{
+		"datasource": step.Query.Datasource,
+		"columns":    result.Columns,
+		"row_count":  len(result.Rows),
+		"truncated":  result.Truncated,
+	}
+
+	return step.Next, output, nil
+}
+
+// executeSetStep assigns a set step's variables in order, each expression seeing the variables
+// assigned before it. Context is only updated once every expression has evaluated
+func (we *WorkflowExecutor) executeSetStep(
+	ctx context.Context,
+	execution *models.WorkflowExecution,
+	step *models.Step,
+	execContext map[string]interface{},
+) (models.JSONB, error) {
+	if len(step.Set) == 0 {
+		return nil, fmt.Errorf("set step has no variables defined")
+	}
+
+	scope := make(map[string]interface{}, len(execContext)+len(step.Set))
+	for key, value := range execContext {
+		scope[key] = value
+	}
+
+	evaluator := we.evaluatorFor(ctx)
+	assigned := make(map[string]interface{}, len(step.Set))
+	for _, variable := range step.Set {
+		value, err := evaluator.evaluateValue(variable.Expression, scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set %s: %w", variable.Name, err)
+		}
+		scope[variable.Name] = value
+		assigned[variable.Name] = value
+	}
+
+	results := make(map[string]interface{})
+	for _, variable := range step.Set {
+		execContext[variable.Name] = assigned[variable.Name]
+		if variable.Result {
+			results[variable.Name] = assigned[variable.Name]
+		}
+	}
+	if len(results) > 0 {
+		we.recordResultPayload(ctx, execution, results)
+	}
+
+	return models.JSONB{"variables": assigned}, nil
+}
+
+// buildCallInput builds a sub-workflow's trigger payload from parent context paths
+func (we *WorkflowExecutor) buildCallInput(ctx context.Context, input map[string]string, execContext map[string]interface{}) (map[string]interface{}, error) {
+	payload := make(map[string]interface{}, len(input))
+	for key, path := range input {
+		path = strings.TrimSpace(path)
+		if strings.HasPrefix(path, "{{") && strings.HasSuffix(path, "}}") {
+			path = strings.TrimSpace(path[2 : len(path)-2])
+		}
+
+		value, err := we.evaluatorFor(ctx).ResolvePath(path, execContext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve call input %s: %w", key, err)
+		}
+		payload[key] = cloneValue(value)
+	}
+
+	return payload, nil
+}
+
+// workflowForExecution returns the workflow with the definition version the execution started on,
+// so an execution resumed after its workflow was edited continues the steps it was paused in
+func (we *WorkflowExecutor) workflowForExecution(
+	ctx context.Context,
+	execution *models.WorkflowExecution,
+	workflow *models.Workflow,
+) (*models.Workflow, error) {
+	if execution.WorkflowVersion == nil || *execution.WorkflowVersion == workflow.DefinitionVersion {
+		return workflow, nil
+	}
+
+	we.loggerFor(ctx).Infof("Resuming execution %s on version %d of workflow %s (current version %d)",
+		execution.ExecutionID, *execution.WorkflowVersion, workflow.WorkflowID, workflow.DefinitionVersion)
+	return pinWorkflowVersion(ctx, we.versionRepo, execution.OrganizationID, workflow, *execution.WorkflowVersion)
+}
+
 // ResumeExecution resumes a paused workflow execution
 func (we *WorkflowExecutor) ResumeExecution(
 	ctx context.Context,
@@ -718,7 +1933,7 @@ func (we *WorkflowExecutor) ResumeExecution(
 	resumeEvent string,
 	resumeData map[string]interface{},
 ) (*models.WorkflowExecution, error) {
-	we.logger.Infof("Resuming workflow execution: %s with event: %s", executionID, resumeEvent)
+	we.loggerFor(ctx).Infof("Resuming workflow execution: %s with event: %s", executionID, resumeEvent)
 
 	// Load execution from database
 	// Use workflow's organization ID if available, otherwise uuid.Nil
@@ -731,6 +1946,10 @@ func (we *WorkflowExecutor) ResumeExecution(
 		return nil, fmt.Errorf("failed to load execution: %w", err)
 	}
 
+	ctx, untrack := we.trackExecution(ctx, execution.OrganizationID, execution.ID)
+	defer untrack()
+	ctx = we.beginExecutionScope(ctx, execution)
+
 	// Verify execution is in waiting state
 	if execution.Status != models.ExecutionStatusWaiting {
 		return nil, fmt.Errorf("execution is not in waiting state, current status: %s", execution.Status)
@@ -740,26 +1959,55 @@ func (we *WorkflowExecutor) ResumeExecution(
 		return nil, fmt.Errorf("execution has no wait state")
 	}
 
-	// Verify event matches what we're waiting for
-	if execution.WaitState.Event != resumeEvent {
-		return nil, fmt.Errorf("unexpected event: waiting for %s, got %s", execution.WaitState.Event, resumeEvent)
+	// Verify event matches one of those we're waiting for
+	if !execution.WaitState.Accepts(resumeEvent) {
+		expected := execution.WaitState.Event
+		if len(execution.WaitState.Events) > 0 {
+			expected = strings.Join(execution.WaitState.Events, " or ")
+		}
+		return nil, fmt.Errorf("unexpected event: waiting for %s, got %s", expected, resumeEvent)
+	}
+
+	workflow, err = we.workflowForExecution(ctx, execution, workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	// A resumed execution takes a rate limit token and a concurrency slot like a new one. When it
+	// gets neither the execution keeps waiting, so the event can be delivered again
+	lease, err := we.admitExecution(ctx, execution.OrganizationID, workflow, execution.TriggerPayload, execution.ID)
+	if err != nil {
+		return nil, err
+	}
+	if lease != nil {
+		defer lease.Release()
 	}
 
 	// Load and enrich context with resume data
 	execContext := map[string]interface{}(execution.Context)
+	if execContext == nil {
+		execContext = make(map[string]interface{})
+	}
 	if resumeData != nil {
 		// Merge resume data into context
 		execContext["resume_event"] = resumeData
 	}
+	// Record which event fired so later steps can branch on it
+	execContext["resume_event_name"] = resumeEvent
 
 	// Reload context data from sources to ensure freshness
+	if we.invalidateOnResume {
+		if err := we.contextBuilder.InvalidateResources(ctx, workflow.OrganizationID, workflow.Definition.Context.Load, execContext); err != nil {
+			we.loggerFor(ctx).Warnf("Failed to invalidate cached context: %v", err)
+		}
+	}
 	if err := we.contextBuilder.BuildContextFromExisting(ctx, workflow.OrganizationID, execContext, workflow.Definition.Context); err != nil {
-		we.logger.Warnf("Failed to reload context: %v", err)
+		we.loggerFor(ctx).Warnf("Failed to reload context: %v", err)
 		// Continue with existing context
 	}
 
-	if err := we.contextBuilder.EnrichContext(ctx, execContext); err != nil {
-		we.logger.Warnf("Failed to enrich context: %v", err)
+	if err := we.contextBuilder.EnrichContext(ctx, execContext, workflow.Definition.Context); err != nil {
+		we.loggerFor(ctx).Warnf("Failed to enrich context: %v", err)
 	}
 
 	execution.Context = execContext
@@ -789,21 +2037,7 @@ func (we *WorkflowExecutor) ResumeExecution(
 		}
 
 		// Determine next step based on wait step metadata or sequential flow
-		if waitStep.Metadata != nil {
-			if next, ok := waitStep.Metadata["on_resume"].(string); ok {
-				nextStepID = next
-			}
-		}
-
-		// If no explicit next step, find the step after wait step in sequence
-		if nextStepID == "" {
-			for i, s := range workflow.Definition.Steps {
-				if s.ID == *execution.CurrentStepID && i+1 < len(workflow.Definition.Steps) {
-					nextStepID = workflow.Definition.Steps[i+1].ID
-					break
-				}
-			}
-		}
+		nextStepID = resumeStepAfterWait(workflow, waitStep)
 	}
 
 	execution.CurrentStepID = nil
@@ -812,12 +2046,23 @@ func (we *WorkflowExecutor) ResumeExecution(
 	result, err := we.continueStepsFrom(ctx, execution, workflow, execContext, nextStepID)
 	if err != nil {
 		// Check if execution was paused again
-		if err == ErrExecutionPaused {
-			we.logger.Infof("Workflow execution paused again: %s", execution.ExecutionID)
+		if errors.Is(err, ErrExecutionPaused) {
+			we.loggerFor(ctx).Infof("Workflow execution paused again: %s", execution.ExecutionID)
 			return execution, nil
 		}
 
-		we.logger.Errorf("Workflow execution failed after resume: %v", err)
+		if errors.Is(context.Cause(ctx), ErrExecutionCancelled) {
+			we.loggerFor(ctx).Infof("Resumed workflow execution cancelled: %s", execution.ExecutionID)
+			we.recordCancellation(ctx, execution)
+			return execution, ErrExecutionCancelled
+		}
+
+		if errors.Is(context.Cause(ctx), ErrExecutionInterrupted) {
+			we.loggerFor(ctx).Warnf("Resumed workflow execution interrupted by shutdown: %s", execution.ExecutionID)
+			return execution, ErrExecutionInterrupted
+		}
+
+		we.loggerFor(ctx).Errorf("Workflow execution failed after resume: %v", err)
 		we.completeExecution(ctx, execution, models.ExecutionResultFailed, err.Error())
 		return execution, err
 	}
@@ -825,11 +2070,111 @@ func (we *WorkflowExecutor) ResumeExecution(
 	// Complete execution successfully
 	we.completeExecution(ctx, execution, result, "")
 
-	we.logger.Infof("Resumed workflow execution completed: %s - Result: %s", execution.ExecutionID, result)
+	we.loggerFor(ctx).Infof("Resumed workflow execution completed: %s - Result: %s", execution.ExecutionID, result)
 
 	return execution, nil
 }
 
+// ResumeDelayedExecution continues an execution whose delay step is due with the delay's next
+// step. The execution must already be claimed, i.e. set running, so it is only continued once
+func (we *WorkflowExecutor) ResumeDelayedExecution(ctx context.Context, execution *models.WorkflowExecution) error {
+	ctx, untrack := we.trackExecution(ctx, execution.OrganizationID, execution.ID)
+	defer untrack()
+	ctx = we.beginExecutionScope(ctx, execution)
+
+	if execution.Status != models.ExecutionStatusRunning {
+		return fmt.Errorf("execution must be in running state to resume (current: %s)", execution.Status)
+	}
+	if execution.CurrentStepID == nil || execution.WaitState == nil || execution.WaitState.ResumeAt == nil {
+		return fmt.Errorf("execution %s is not delayed", execution.ID)
+	}
+
+	workflow, err := we.workflowRepo.GetWorkflowByID(ctx, execution.OrganizationID, execution.WorkflowID)
+	if err != nil {
+		err = fmt.Errorf("failed to load workflow: %w", err)
+		we.completeExecution(ctx, execution, models.ExecutionResultFailed, err.Error())
+		return err
+	}
+	workflow, err = we.workflowForExecution(ctx, execution, workflow)
+	if err != nil {
+		we.completeExecution(ctx, execution, models.ExecutionResultFailed, err.Error())
+		return err
+	}
+
+	var delayStep *models.Step
+	for i := range workflow.Definition.Steps {
+		if step := &workflow.Definition.Steps[i]; step.ID == *execution.CurrentStepID && step.Type == "delay" {
+			delayStep = step
+		}
+	}
+	if delayStep == nil {
+		err := fmt.Errorf("delay step not found: %s", *execution.CurrentStepID)
+		we.completeExecution(ctx, execution, models.ExecutionResultFailed, err.Error())
+		return err
+	}
+
+	// A resumed execution takes a rate limit token and a concurrency slot like a new one. When it
+	// gets neither the execution goes back to waiting, so the delay worker claims it again on its
+	// next check
+	lease, err := we.admitExecution(ctx, execution.OrganizationID, workflow, execution.TriggerPayload, execution.ID)
+	if err != nil {
+		execution.Status = models.ExecutionStatusWaiting
+		if updateErr := we.executionRepo.UpdateExecution(ctx, execution.OrganizationID, execution); updateErr != nil {
+			we.loggerFor(ctx).Errorf("Failed to return delayed execution %s to waiting: %v", execution.ExecutionID, updateErr)
+		}
+		return err
+	}
+	if lease != nil {
+		defer lease.Release()
+	}
+
+	we.loggerFor(ctx).Infof("Resuming delayed execution %s after step %s", execution.ExecutionID, delayStep.ID)
+
+	execContext := map[string]interface{}(execution.Context)
+	if execContext == nil {
+		execContext = make(map[string]interface{})
+	}
+	execution.Context = execContext
+
+	now := time.Now()
+	execution.CurrentStepID = nil
+	execution.WaitState = nil
+	execution.LastResumedAt = &now
+	execution.ResumeCount++
+
+	if err := we.executionRepo.UpdateExecution(ctx, execution.OrganizationID, execution); err != nil {
+		return fmt.Errorf("failed to update execution status: %w", err)
+	}
+
+	result, err := we.continueStepsFrom(ctx, execution, workflow, execContext, delayStep.Next)
+	if err != nil {
+		if errors.Is(err, ErrExecutionPaused) {
+			we.loggerFor(ctx).Infof("Workflow execution paused again: %s", execution.ExecutionID)
+			return nil
+		}
+
+		if errors.Is(context.Cause(ctx), ErrExecutionCancelled) {
+			we.loggerFor(ctx).Infof("Delayed workflow execution cancelled: %s", execution.ExecutionID)
+			we.recordCancellation(ctx, execution)
+			return ErrExecutionCancelled
+		}
+
+		if errors.Is(context.Cause(ctx), ErrExecutionInterrupted) {
+			we.loggerFor(ctx).Warnf("Delayed workflow execution interrupted by shutdown: %s", execution.ExecutionID)
+			return ErrExecutionInterrupted
+		}
+
+		we.loggerFor(ctx).Errorf("Workflow execution failed after delay: %v", err)
+		we.completeExecution(ctx, execution, models.ExecutionResultFailed, err.Error())
+		return err
+	}
+
+	we.completeExecution(ctx, execution, result, "")
+	we.loggerFor(ctx).Infof("Delayed workflow execution completed: %s - Result: %s", execution.ExecutionID, result)
+
+	return nil
+}
+
 // continueStepsFrom continues execution from a specific step ID
 func (we *WorkflowExecutor) continueStepsFrom(
 	ctx context.Context,
@@ -847,22 +2192,40 @@ func (we *WorkflowExecutor) continueStepsFrom(
 
 	currentStepID := startStepID
 	var finalResult models.ExecutionResult = models.ExecutionResultExecuted
+	visits := make(map[string]int)
+	maxVisits := we.getMaxStepVisits(workflow)
 
 	// Execute steps from the given starting point
 	for currentStepID != "" {
+		if err := interruption(ctx); err != nil {
+			return models.ExecutionResultFailed, err
+		}
+
 		step, exists := stepMap[currentStepID]
 		if !exists {
 			return models.ExecutionResultFailed, fmt.Errorf("step not found: %s", currentStepID)
 		}
 
-		we.logger.Infof("Executing step: %s (type: %s)", step.ID, step.Type)
+		visits[step.ID]++
+		if visits[step.ID] > maxVisits {
+			return models.ExecutionResultFailed, fmt.Errorf("step %s exceeded the maximum of %d visits, possible infinite loop", step.ID, maxVisits)
+		}
+
+		we.loggerFor(ctx).Infof("Executing step: %s (type: %s)", step.ID, step.Type)
 
 		// Execute step with retry logic
 		nextStepID, result, err := we.executeStepWithRetry(ctx, execution, step, execContext)
 		if err != nil {
+			if next, ok := we.recoverStepError(ctx, execution, step, err); ok {
+				currentStepID = next
+				continue
+			}
+			we.compensateFailure(ctx, execution, stepMap, execContext, err)
 			return models.ExecutionResultFailed, fmt.Errorf("step %s failed: %w", step.ID, err)
 		}
 
+		recordCompensation(execution, step)
+
 		// Update final result based on action result
 		if result != nil && result.Action == "block" {
 			finalResult = models.ExecutionResultBlocked
@@ -897,13 +2260,162 @@ func (we *WorkflowExecutor) completeExecution(
 	}
 
 	if err := we.executionRepo.UpdateExecution(ctx, execution.OrganizationID, execution); err != nil {
-		we.logger.Errorf("Failed to update execution: %v", err)
+		we.loggerFor(ctx).Errorf("Failed to update execution: %v", err)
 	}
 
 	// Broadcast execution completion event
 	we.broadcastExecutionEvent(execution)
 }
 
+// ErrExecutionCancelled is returned when a running execution is interrupted by a cancel request
+var ErrExecutionCancelled = errors.New("execution cancelled")
+
+// ErrExecutionInterrupted is returned when a running execution is stopped because the process is
+// shutting down
+var ErrExecutionInterrupted = errors.New("execution interrupted by shutdown")
+
+// interruptRecordTimeout bounds saving the executions Drain interrupts, after its deadline passed
+const interruptRecordTimeout = 5 * time.Second
+
+// runningExecution is the registry entry for an execution running in this process
+type runningExecution struct {
+	cancel         context.CancelCauseFunc
+	organizationID uuid.UUID
+	done           chan struct{} // Closed when the run stops
+}
+
+// trackExecution derives a cancellable context for a run and registers it under the execution ID.
+// The returned func unregisters the run and releases the context; callers defer it so finished
+// runs never linger in the registry.
+func (we *WorkflowExecutor) trackExecution(ctx context.Context, organizationID, executionID uuid.UUID) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	entry := &runningExecution{cancel: cancel, organizationID: organizationID, done: make(chan struct{})}
+	we.running.Store(executionID, entry)
+
+	return ctx, func() {
+		// Only remove our own entry in case the execution was re-registered by a later resume
+		we.running.CompareAndDelete(executionID, entry)
+		cancel(nil)
+		close(entry.done)
+	}
+}
+
+// Drain waits for the executions running in this process to finish, so shutdown does not cut
+// them off mid-step. Executions started while draining are waited on too. Those still running
+// when ctx is done are interrupted before their next step and recorded as interrupted, since the
+// process will not be around to finish them. It returns the number of executions interrupted.
+//
+// Executions queued for a rate limit token or a concurrency slot are turned away as soon as
+// Drain begins, as if their limit had dropped them, since their queue lives in this process
+func (we *WorkflowExecutor) Drain(ctx context.Context) int {
+	we.stopAdmissions(ErrExecutionInterrupted)
+
+	for {
+		var pending []*runningExecution
+		we.running.Range(func(_, value any) bool {
+			pending = append(pending, value.(*runningExecution))
+			return true
+		})
+		if len(pending) == 0 {
+			return 0
+		}
+
+		we.loggerFor(ctx).Infof("Waiting for %d running executions to finish", len(pending))
+		for _, entry := range pending {
+			select {
+			case <-entry.done:
+			case <-ctx.Done():
+				return we.interruptRunning(context.WithoutCancel(ctx))
+			}
+		}
+	}
+}
+
+// interruptRunning stops the executions running in this process and records them as interrupted.
+// All runs are stopped before any is recorded, so none overwrites its recorded state with progress
+// made in the meantime. It returns the number of executions recorded
+func (we *WorkflowExecutor) interruptRunning(ctx context.Context) int {
+	running := make(map[uuid.UUID]*runningExecution)
+	we.running.Range(func(key, value any) bool {
+		entry := value.(*runningExecution)
+		entry.cancel(ErrExecutionInterrupted)
+		running[key.(uuid.UUID)] = entry
+		return true
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, interruptRecordTimeout)
+	defer cancel()
+
+	interrupted := 0
+	for executionID, entry := range running {
+		recorded, err := we.executionRepo.InterruptExecution(ctx, entry.organizationID, executionID, ErrExecutionInterrupted.Error())
+		if err != nil {
+			we.loggerFor(ctx).Errorf("Failed to record interrupted execution %s: %v", executionID, err)
+			continue
+		}
+		if recorded {
+			we.loggerFor(ctx).Warnf("Execution %s interrupted by shutdown", executionID)
+			interrupted++
+		}
+	}
+
+	return interrupted
+}
+
+// CancelExecution interrupts an execution running in this process. The run stops before its next
+// step (or as soon as the in-flight step observes ctx) and is recorded as cancelled. It reports
+// false when the execution is not running here, e.g. it already finished or runs on another instance.
+func (we *WorkflowExecutor) CancelExecution(executionID uuid.UUID) bool {
+	value, ok := we.running.Load(executionID)
+	if !ok {
+		return false
+	}
+
+	value.(*runningExecution).cancel(ErrExecutionCancelled)
+	return true
+}
+
+// interruption reports why the execution on ctx must stop before its next step, or nil to continue
+func interruption(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	if errors.Is(context.Cause(ctx), ErrExecutionCancelled) {
+		return ErrExecutionCancelled
+	}
+	if errors.Is(context.Cause(ctx), ErrExecutionInterrupted) {
+		return ErrExecutionInterrupted
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("workflow execution timed out")
+	}
+	return ctx.Err()
+}
+
+// recordCancellation marks an interrupted execution as cancelled
+func (we *WorkflowExecutor) recordCancellation(ctx context.Context, execution *models.WorkflowExecution) {
+	now := time.Now()
+	execution.CompletedAt = &now
+	duration := int(now.Sub(execution.StartedAt).Milliseconds())
+	execution.DurationMs = &duration
+	execution.Status = models.ExecutionStatusCancelled
+	errorMsg := ErrExecutionCancelled.Error()
+	execution.ErrorMessage = &errorMsg
+
+	// ctx is already cancelled, but the final state must still be saved
+	if err := we.executionRepo.UpdateExecution(context.WithoutCancel(ctx), execution.OrganizationID, execution); err != nil {
+		we.loggerFor(ctx).Errorf("Failed to update cancelled execution: %v", err)
+	}
+
+	we.broadcastExecutionEvent(execution)
+}
+
+// BroadcastExecution broadcasts an execution state change made outside the executor, e.g. an
+// execution expired by the workflow resumer, via WebSocket
+func (we *WorkflowExecutor) BroadcastExecution(execution *models.WorkflowExecution) {
+	we.broadcastExecutionEvent(execution)
+}
+
 // broadcastExecutionEvent broadcasts an execution state change via WebSocket
 func (we *WorkflowExecutor) broadcastExecutionEvent(execution *models.WorkflowExecution) {
 	if we.wsHub == nil {
@@ -952,25 +2464,166 @@ func (we *WorkflowExecutor) broadcastExecutionEvent(execution *models.WorkflowEx
 	we.wsHub.BroadcastExecutionEvent(msgType, eventData)
 }
 
+// withStepProgress streams the output of an AI action step to WebSocket clients as step
+// progress events while the LLM generates it. The AI service falls back to a single response if
+// the provider cannot stream, and the parsed result is stored in the step output either way
+func (we *WorkflowExecutor) withStepProgress(ctx context.Context, execution *models.WorkflowExecution, step *models.Step) context.Context {
+	if we.wsHub == nil {
+		return ctx
+	}
+
+	sequence := 0
+	return llm.WithStreamHandler(ctx, func(chunk *llm.StreamChunk) error {
+		if chunk.Delta == "" && !chunk.IsComplete {
+			return nil
+		}
+		sequence++
+		we.wsHub.BroadcastStepProgress(&websocket.StepProgressData{
+			ExecutionID: execution.ExecutionID,
+			StepID:      step.ID,
+			Delta:       chunk.Delta,
+			Sequence:    sequence,
+			Done:        chunk.IsComplete,
+		})
+		return nil
+	})
+}
+
+// withUsageScope attributes the LLM usage of an AI action step to its execution, totalling it in
+// the execution's llm_usage metadata
+func (we *WorkflowExecutor) withUsageScope(ctx context.Context, execution *models.WorkflowExecution) context.Context {
+	return llm.WithUsageScope(ctx, &llm.UsageScope{
+		OrganizationID: execution.OrganizationID.String(),
+		WorkflowID:     execution.WorkflowID.String(),
+		ExecutionID:    execution.ExecutionID,
+		OnUsage: func(usage *llm.Usage) {
+			recordLLMUsage(ctx, execution, usage)
+		},
+	})
+}
+
+// recordLLMUsage adds a completion's token usage and estimated cost to the execution's
+// llm_usage metadata. Totals read back from the database are JSON numbers, so all are float64
+func recordLLMUsage(ctx context.Context, execution *models.WorkflowExecution, usage *llm.Usage) {
+	// AI steps may run in concurrent parallel branches of the same execution
+	if scope := scopeFromContext(ctx); scope != nil {
+		scope.metadataMu.Lock()
+		defer scope.metadataMu.Unlock()
+	}
+
+	if execution.Metadata == nil {
+		execution.Metadata = make(models.JSONB)
+	}
+	totals, _ := execution.Metadata["llm_usage"].(map[string]interface{})
+	if totals == nil {
+		totals = make(map[string]interface{})
+	}
+
+	add := func(key string, value float64) {
+		current, _ := toFloat64(totals[key])
+		totals[key] = current + value
+	}
+	add("calls", 1)
+	add("prompt_tokens", float64(usage.PromptTokens))
+	add("completion_tokens", float64(usage.CompletionTokens))
+	add("total_tokens", float64(usage.PromptTokens+usage.CompletionTokens))
+	add("cost_usd", usage.CostUSD)
+
+	execution.Metadata["llm_usage"] = totals
+}
+
 // calculateBackoff calculates backoff duration for retries
-func (we *WorkflowExecutor) calculateBackoff(attempt int, retryConfig *models.RetryConfig) time.Duration {
-	if retryConfig == nil || retryConfig.Backoff == "" {
-		// Default: exponential backoff
-		return time.Duration(1<<uint(attempt-1)) * time.Second
+func (we *WorkflowExecutor) calculateBackoff(ctx context.Context, attempt int, retryConfig *models.RetryConfig) time.Duration {
+	if retryConfig == nil {
+		return retryBackoff(attempt, "exponential", time.Second, 0)
 	}
 
-	switch retryConfig.Backoff {
+	maxBackoff := we.parseTimeout(retryConfig.MaxBackoff, 0)
+	backoff, ok := jitteredRetryBackoff(attempt, retryConfig.Backoff, time.Second, maxBackoff, retryConfig.Jitter)
+	if !ok {
+		we.loggerFor(ctx).Warnf("Invalid retry jitter '%s', using backoff without jitter", retryConfig.Jitter)
+	}
+	return backoff
+}
+
+// retryBackoff returns the delay before the given retry attempt (1 for the first retry) without
+// jitter: the initial delay, multiplied by the attempt for linear backoff and doubled for each
+// earlier retry for exponential (or unset) backoff, capped at maxBackoff when set. Any other
+// strategy keeps the initial delay
+func retryBackoff(attempt int, strategy string, initial, maxBackoff time.Duration) time.Duration {
+	backoff := initial
+	switch strategy {
 	case "linear":
-		return time.Duration(attempt) * time.Second
-	case "exponential":
-		return time.Duration(1<<uint(attempt-1)) * time.Second
-	default:
-		return time.Second
+		if attempt > 1 {
+			if backoff > time.Duration(math.MaxInt64)/time.Duration(attempt) {
+				backoff = time.Duration(math.MaxInt64)
+			} else {
+				backoff *= time.Duration(attempt)
+			}
+		}
+	case "", "exponential":
+		for i := 1; i < attempt; i++ {
+			// Stop doubling once past the cap, and before the delay can overflow
+			if (maxBackoff > 0 && backoff >= maxBackoff) || backoff > time.Duration(math.MaxInt64/2) {
+				break
+			}
+			backoff *= 2
+		}
+	}
+
+	if maxBackoff > 0 && backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+// jitteredRetryBackoff returns retryBackoff spread by jitter and still capped at maxBackoff. An
+// unrecognized jitter returns the backoff without jitter and false
+func jitteredRetryBackoff(attempt int, strategy string, initial, maxBackoff time.Duration, jitter string) (time.Duration, bool) {
+	backoff, ok := jitterBackoff(retryBackoff(attempt, strategy, initial, maxBackoff), jitter)
+
+	// Percentage jitter can push the delay above the cap
+	if maxBackoff > 0 && backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff, ok
+}
+
+// jitterBackoff randomizes a backoff delay to spread out concurrent retries
+//   - full: uniform in [0, backoff]
+//   - equal: uniform in [backoff/2, backoff]
+//   - "N%": uniform in [backoff-N%, backoff+N%]
+//
+// An unrecognized jitter returns the backoff unchanged and false
+func jitterBackoff(backoff time.Duration, jitter string) (time.Duration, bool) {
+	if jitter == "" || backoff <= 0 {
+		return backoff, true
+	}
+
+	switch jitter {
+	case "full":
+		return time.Duration(rand.Int64N(int64(backoff) + 1)), true
+	case "equal":
+		half := backoff / 2
+		return half + time.Duration(rand.Int64N(int64(backoff-half)+1)), true
+	}
+
+	if strings.HasSuffix(jitter, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(jitter, "%"), 64)
+		if err == nil && percent > 0 && percent <= 100 {
+			spread := float64(backoff) * percent / 100
+			return backoff + time.Duration((rand.Float64()*2-1)*spread), true
+		}
 	}
+
+	return backoff, false
 }
 
 // isRetryableError checks if an error should be retried
-func (we *WorkflowExecutor) isRetryableError(err error, retryOn []string) bool {
+// Patterns may be "*", an exact error message, "timeout" (matches context.DeadlineExceeded),
+// "circuit_open" (matches calls failed fast by an open circuit breaker), or a regular expression
+// prefixed with "re:"
+func (we *WorkflowExecutor) isRetryableError(ctx context.Context, err error, retryOn []string) bool {
 	if len(retryOn) == 0 {
 		// Retry all errors by default
 		return true
@@ -978,11 +2631,22 @@ func (we *WorkflowExecutor) isRetryableError(err error, retryOn []string) bool {
 
 	errMsg := err.Error()
 	for _, pattern := range retryOn {
-		if pattern == "*" {
+		switch {
+		case pattern == "*":
 			return true
-		}
-		// Simple string matching (could be enhanced with regex)
-		if fmt.Sprintf("%v", err) == pattern || errMsg == pattern {
+		case pattern == "timeout":
+			if errors.Is(err, context.DeadlineExceeded) {
+				return true
+			}
+		case pattern == "circuit_open":
+			if errors.Is(err, circuitbreaker.ErrOpen) {
+				return true
+			}
+		case strings.HasPrefix(pattern, "re:"):
+			if re := we.retryPattern(ctx, pattern); re != nil && re.MatchString(errMsg) {
+				return true
+			}
+		case errMsg == pattern:
 			return true
 		}
 	}
@@ -990,6 +2654,23 @@ func (we *WorkflowExecutor) isRetryableError(err error, retryOn []string) bool {
 	return false
 }
 
+// retryPattern returns the compiled regex for a "re:" retry pattern, compiling it once.
+// Invalid patterns are cached as nil so the warning is only logged the first time.
+func (we *WorkflowExecutor) retryPattern(ctx context.Context, pattern string) *regexp.Regexp {
+	if cached, ok := we.retryPatterns.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+
+	re, err := regexp.Compile(strings.TrimPrefix(pattern, "re:"))
+	if err != nil {
+		we.loggerFor(ctx).Warnf("Invalid retry_on pattern '%s', ignoring: %v", pattern, err)
+		re = nil
+	}
+
+	we.retryPatterns.Store(pattern, re)
+	return re
+}
+
 // parseTimeout parses a timeout string (e.g., "5m", "30s", "1h") and returns a duration
 // Returns defaultTimeout if timeoutStr is empty or invalid
 func (we *WorkflowExecutor) parseTimeout(timeoutStr string, defaultTimeout time.Duration) time.Duration {
@@ -1013,7 +2694,11 @@ func (we *WorkflowExecutor) parseTimeout(timeoutStr string, defaultTimeout time.
 
 // ResumePausedExecution resumes a paused workflow execution
 func (we *WorkflowExecutor) ResumePausedExecution(ctx context.Context, execution *models.WorkflowExecution) error {
-	we.logger.Infof("Resuming paused execution %s (resume count: %d)", execution.ID, execution.ResumeCount)
+	we.loggerFor(ctx).Infof("Resuming paused execution %s (resume count: %d)", execution.ID, execution.ResumeCount)
+
+	ctx, untrack := we.trackExecution(ctx, execution.OrganizationID, execution.ID)
+	defer untrack()
+	ctx = we.beginExecutionScope(ctx, execution)
 
 	// Validate execution state
 	if execution.Status != models.ExecutionStatusRunning {
@@ -1025,6 +2710,20 @@ func (we *WorkflowExecutor) ResumePausedExecution(ctx context.Context, execution
 	if err != nil {
 		return fmt.Errorf("failed to load workflow: %w", err)
 	}
+	workflow, err = we.workflowForExecution(ctx, execution, workflow)
+	if err != nil {
+		return err
+	}
+
+	// A resumed execution takes a rate limit token and a concurrency slot like a new one. When it
+	// gets neither the error is returned for the caller to pause the execution again
+	lease, err := we.admitExecution(ctx, execution.OrganizationID, workflow, execution.TriggerPayload, execution.ID)
+	if err != nil {
+		return err
+	}
+	if lease != nil {
+		defer lease.Release()
+	}
 
 	// Restore execution context from resume_data
 	execContext := make(map[string]interface{})
@@ -1044,30 +2743,41 @@ func (we *WorkflowExecutor) ResumePausedExecution(ctx context.Context, execution
 	if execution.NextStepID != nil {
 		// Resume from the next step
 		startStepID = execution.NextStepID.String()
-		we.logger.Infof("Resuming from next step: %s", startStepID)
+		we.loggerFor(ctx).Infof("Resuming from next step: %s", startStepID)
 	} else if execution.PausedStepID != nil {
 		// Re-execute the paused step (e.g., after approval)
 		startStepID = execution.PausedStepID.String()
-		we.logger.Infof("Re-executing paused step: %s", startStepID)
+		we.loggerFor(ctx).Infof("Re-executing paused step: %s", startStepID)
 	} else {
 		// No specific step, start from the beginning
 		if len(workflow.Definition.Steps) > 0 {
 			startStepID = workflow.Definition.Steps[0].ID
 		}
-		we.logger.Warn("No paused/next step specified, starting from beginning")
+		we.loggerFor(ctx).Warn("No paused/next step specified, starting from beginning")
 	}
 
 	// Continue execution from the specified step
 	result, err := we.continueFromStep(ctx, execution, workflow, execContext, startStepID)
 	if err != nil {
-		we.logger.Errorf("Failed to resume workflow execution: %v", err)
+		if errors.Is(context.Cause(ctx), ErrExecutionCancelled) {
+			we.loggerFor(ctx).Infof("Resumed workflow execution cancelled: %s", execution.ExecutionID)
+			we.recordCancellation(ctx, execution)
+			return ErrExecutionCancelled
+		}
+
+		if errors.Is(context.Cause(ctx), ErrExecutionInterrupted) {
+			we.loggerFor(ctx).Warnf("Resumed workflow execution interrupted by shutdown: %s", execution.ExecutionID)
+			return ErrExecutionInterrupted
+		}
+
+		we.loggerFor(ctx).Errorf("Failed to resume workflow execution: %v", err)
 		we.completeExecution(ctx, execution, models.ExecutionResultFailed, err.Error())
 		return err
 	}
 
 	// Complete execution successfully
 	we.completeExecution(ctx, execution, result, "")
-	we.logger.Infof("Workflow execution resumed and completed: %s - Result: %s", execution.ExecutionID, result)
+	we.loggerFor(ctx).Infof("Workflow execution resumed and completed: %s - Result: %s", execution.ExecutionID, result)
 
 	return nil
 }
@@ -1080,7 +2790,7 @@ func (we *WorkflowExecutor) PauseCurrentExecution(
 	pausedStepID string,
 	nextStepID string,
 ) error {
-	we.logger.Infof("Pausing execution %s at step %s: %s", execution.ID, pausedStepID, reason)
+	we.loggerFor(ctx).Infof("Pausing execution %s at step %s: %s", execution.ID, pausedStepID, reason)
 
 	now := time.Now()
 	execution.Status = models.ExecutionStatusPaused
@@ -1108,7 +2818,7 @@ func (we *WorkflowExecutor) PauseCurrentExecution(
 		return fmt.Errorf("failed to pause execution: %w", err)
 	}
 
-	we.logger.Infof("Successfully paused execution %s", execution.ID)
+	we.loggerFor(ctx).Infof("Successfully paused execution %s", execution.ID)
 	return nil
 }
 
@@ -1136,22 +2846,40 @@ func (we *WorkflowExecutor) continueFromStep(
 
 	currentStepID := startStepID
 	var finalResult models.ExecutionResult = models.ExecutionResultExecuted
+	visits := make(map[string]int)
+	maxVisits := we.getMaxStepVisits(workflow)
 
 	// Execute steps from the specified start point
 	for currentStepID != "" {
+		if err := interruption(ctx); err != nil {
+			return models.ExecutionResultFailed, err
+		}
+
 		step, exists := stepMap[currentStepID]
 		if !exists {
 			return models.ExecutionResultFailed, fmt.Errorf("step not found: %s", currentStepID)
 		}
 
-		we.logger.Infof("Executing step: %s (type: %s)", step.ID, step.Type)
+		visits[step.ID]++
+		if visits[step.ID] > maxVisits {
+			return models.ExecutionResultFailed, fmt.Errorf("step %s exceeded the maximum of %d visits, possible infinite loop", step.ID, maxVisits)
+		}
+
+		we.loggerFor(ctx).Infof("Executing step: %s (type: %s)", step.ID, step.Type)
 
 		// Execute step with retry logic
 		nextStepID, result, err := we.executeStepWithRetry(ctx, execution, step, execContext)
 		if err != nil {
+			if next, ok := we.recoverStepError(ctx, execution, step, err); ok {
+				currentStepID = next
+				continue
+			}
+			we.compensateFailure(ctx, execution, stepMap, execContext, err)
 			return models.ExecutionResultFailed, fmt.Errorf("step %s failed: %w", step.ID, err)
 		}
 
+		recordCompensation(execution, step)
+
 		// Update final result based on action result
 		if result != nil && result.Action == "block" {
 			finalResult = models.ExecutionResultBlocked
@@ -1165,6 +2893,132 @@ func (we *WorkflowExecutor) continueFromStep(
 	return finalResult, nil
 }
 
+// SetMaxStepVisits sets the default limit on how many times a single step may run
+// within one execution (guards against cycles in the step graph)
+func (we *WorkflowExecutor) SetMaxStepVisits(maxVisits int) {
+	if maxVisits > 0 {
+		we.maxStepVisits = maxVisits
+	}
+}
+
+// getMaxStepVisits returns the per-step visit limit, preferring the workflow's own setting
+func (we *WorkflowExecutor) getMaxStepVisits(workflow *models.Workflow) int {
+	if workflow.Definition.MaxStepVisits > 0 {
+		return workflow.Definition.MaxStepVisits
+	}
+	return we.maxStepVisits
+}
+
+// admitExecution takes a token from the workflow's and organization's rate limits, so executions
+// reach downstream systems at the limited rate, then a slot of the workflow's concurrency limit,
+// which the caller releases when the run completes, fails or pauses. Executions take both when
+// they start and again when they resume. The token comes first, so executions queued by a rate
+// limit do not hold slots of the workflow while they wait.
+//
+// It returns a nil lease when there is no concurrency limit to hold. If Redis is unavailable the
+// execution runs without its limits rather than not at all. Executions still queued when Drain
+// begins fail with the limit's error wrapping ErrExecutionInterrupted, so callers handle them like
+// executions their limit dropped
+func (we *WorkflowExecutor) admitExecution(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	workflow *models.Workflow,
+	triggerPayload map[string]interface{},
+	executionID uuid.UUID,
+) (*concurrencyLease, error) {
+	queueCtx, stopQueue := context.WithCancelCause(ctx)
+	defer stopQueue(nil)
+	stopOnDrain := context.AfterFunc(we.admissions, func() { stopQueue(context.Cause(we.admissions)) })
+	defer stopOnDrain()
+
+	if we.rateLimiter != nil {
+		err := we.rateLimiter.Take(queueCtx, organizationID, workflow, we.executionPriority(ctx, workflow, triggerPayload))
+		if err != nil && queueCtx.Err() != nil && ctx.Err() == nil {
+			err = fmt.Errorf("%w: %w", ErrRateLimited, context.Cause(queueCtx))
+		}
+		if err != nil && (errors.Is(err, ErrRateLimited) || ctx.Err() != nil) {
+			we.loggerFor(ctx).Warnf("Not running workflow %s: %v", workflow.ID, err)
+			return nil, err
+		}
+		if err != nil {
+			we.loggerFor(ctx).Warnf("Running workflow %s without its rate limit: %v", workflow.ID, err)
+		}
+	}
+
+	if we.concurrency == nil {
+		return nil, nil
+	}
+	lease, err := we.concurrency.Acquire(queueCtx, organizationID, workflow, triggerPayload, executionID)
+	if err != nil && queueCtx.Err() != nil && ctx.Err() == nil {
+		err = fmt.Errorf("%w: %w", ErrConcurrencyLimitReached, context.Cause(queueCtx))
+	}
+	if err != nil && (errors.Is(err, ErrConcurrencyLimitReached) || ctx.Err() != nil) {
+		we.loggerFor(ctx).Warnf("Not running workflow %s: %v", workflow.ID, err)
+		return nil, err
+	}
+	if err != nil {
+		we.loggerFor(ctx).Warnf("Running workflow %s without its concurrency limit: %v", workflow.ID, err)
+		return nil, nil
+	}
+	return lease, nil
+}
+
+// executionPriority evaluates the trigger's priority expression, or the workflow's, against the
+// trigger payload. Executions without a priority, or whose expression fails or is not a number,
+// have priority 0
+func (we *WorkflowExecutor) executionPriority(ctx context.Context, workflow *models.Workflow, triggerPayload map[string]interface{}) float64 {
+	expression := workflow.Definition.Trigger.Priority
+	if expression == "" {
+		expression = workflow.Definition.Priority
+	}
+	if expression == "" {
+		return 0
+	}
+
+	if triggerPayload == nil {
+		triggerPayload = map[string]interface{}{}
+	}
+	value, err := we.evaluator.evaluateValue(expression, triggerPayload)
+	if err != nil {
+		we.loggerFor(ctx).Warnf("Failed to evaluate priority of workflow %s, using 0: %v", workflow.ID, err)
+		return 0
+	}
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		we.loggerFor(ctx).Warnf("Priority %q of workflow %s is a %T rather than a number, using 0", expression, workflow.ID, value)
+		return 0
+	}
+}
+
+// resolveIdempotencyKey returns the key that deduplicates this execution, or "" when there is none.
+// It checks (1) the explicit option, (2) the trigger's idempotency_key path, then (3) the payload's idempotency_key field
+func (we *WorkflowExecutor) resolveIdempotencyKey(ctx context.Context, workflow *models.Workflow, triggerPayload map[string]interface{}, opts ExecutionOptions) string {
+	if opts.IdempotencyKey != "" {
+		return opts.IdempotencyKey
+	}
+
+	if path := workflow.Definition.Trigger.IdempotencyKey; path != "" {
+		value, err := we.evaluator.ResolvePath(path, triggerPayload)
+		if err != nil || value == nil {
+			we.loggerFor(ctx).Warnf("Idempotency key path %s not found in trigger payload for workflow %s", path, workflow.ID)
+			return ""
+		}
+		return fmt.Sprintf("%v", value)
+	}
+
+	if key, ok := triggerPayload["idempotency_key"].(string); ok {
+		return key
+	}
+
+	return ""
+}
+
 // getWorkflowTimeout returns the timeout duration for a workflow
 // It checks (1) workflow Definition.Timeout, (2) trigger data timeout_seconds, or (3) default
 func (we *WorkflowExecutor) getWorkflowTimeout(workflow *models.Workflow) time.Duration {
@@ -2,15 +2,29 @@ package engine
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/internal/websocket"
+	"github.com/davidmoltin/intelligent-workflows/pkg/circuitbreaker"
 	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/llm"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/metrics"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 )
 
 // getTestContextEnrichmentConfigForExecutor returns a test configuration with enrichment disabled
@@ -22,20 +36,23 @@ func getTestContextEnrichmentConfigForExecutor() *config.ContextEnrichmentConfig
 		MaxRetries: 3,
 		RetryDelay: 500 * time.Millisecond,
 		CacheTTL:   5 * time.Minute,
-		EndpointMapping: map[string]string{
-			"order.details": "/api/v1/orders/{id}/details",
+		EndpointMapping: map[string]config.EnrichmentEndpoint{
+			"order.details": {Path: "/api/v1/orders/{id}/details"},
 		},
 	}
 }
 
 // Mock ExecutionRepository
 type mockExecutionRepo struct {
-	createExecutionFunc     func(ctx context.Context, execution *models.WorkflowExecution) error
-	updateExecutionFunc     func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error
-	getExecutionByIDFunc    func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowExecution, error)
-	createStepExecutionFunc func(ctx context.Context, step *models.StepExecution) error
-	updateStepExecutionFunc func(ctx context.Context, organizationID uuid.UUID, step *models.StepExecution) error
+	createExecutionFunc       func(ctx context.Context, execution *models.WorkflowExecution) error
+	updateExecutionFunc       func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error
+	getExecutionByIDFunc      func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowExecution, error)
+	createStepExecutionFunc   func(ctx context.Context, step *models.StepExecution) error
+	updateStepExecutionFunc   func(ctx context.Context, organizationID uuid.UUID, step *models.StepExecution) error
 	getTimedOutExecutionsFunc func(ctx context.Context, organizationID uuid.UUID, limit int) ([]*models.WorkflowExecution, error)
+	getByIdempotencyKeyFunc   func(ctx context.Context, organizationID, workflowID uuid.UUID, key string) (*models.WorkflowExecution, error)
+	markExecutionRerunFunc    func(ctx context.Context, organizationID, id uuid.UUID) (bool, error)
+	interruptExecutionFunc    func(ctx context.Context, organizationID, id uuid.UUID, reason string) (bool, error)
 }
 
 func (m *mockExecutionRepo) CreateExecution(ctx context.Context, execution *models.WorkflowExecution) error {
@@ -80,6 +97,27 @@ func (m *mockExecutionRepo) GetTimedOutExecutions(ctx context.Context, organizat
 	return nil, nil
 }
 
+func (m *mockExecutionRepo) GetExecutionByIdempotencyKey(ctx context.Context, organizationID, workflowID uuid.UUID, key string) (*models.WorkflowExecution, error) {
+	if m.getByIdempotencyKeyFunc != nil {
+		return m.getByIdempotencyKeyFunc(ctx, organizationID, workflowID, key)
+	}
+	return nil, errors.New("not found")
+}
+
+func (m *mockExecutionRepo) MarkExecutionRerun(ctx context.Context, organizationID, id uuid.UUID) (bool, error) {
+	if m.markExecutionRerunFunc != nil {
+		return m.markExecutionRerunFunc(ctx, organizationID, id)
+	}
+	return true, nil
+}
+
+func (m *mockExecutionRepo) InterruptExecution(ctx context.Context, organizationID, id uuid.UUID, reason string) (bool, error) {
+	if m.interruptExecutionFunc != nil {
+		return m.interruptExecutionFunc(ctx, organizationID, id, reason)
+	}
+	return true, nil
+}
+
 func TestExecuteWaitStep(t *testing.T) {
 	log := logger.NewForTesting()
 	ctx := context.Background()
@@ -148,6 +186,43 @@ func TestExecuteWaitStep(t *testing.T) {
 		}
 	})
 
+	t.Run("records alternative events", func(t *testing.T) {
+		var updatedExecution *models.WorkflowExecution
+		repo := &mockExecutionRepo{
+			updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
+				updatedExecution = execution
+				return nil
+			},
+		}
+		redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		execution := &models.WorkflowExecution{ID: uuid.New(), Status: models.ExecutionStatusRunning}
+		step := &models.Step{
+			ID:   "wait1",
+			Type: "wait",
+			Wait: &models.WaitConfig{
+				Event:  "approval.granted",
+				Events: []string{"approval.granted", "approval.auto_approved"},
+			},
+		}
+
+		if err := executor.executeWaitStep(ctx, execution, step, map[string]interface{}{}); err != ErrExecutionPaused {
+			t.Fatalf("Expected ErrExecutionPaused, got %v", err)
+		}
+
+		ws := updatedExecution.WaitState
+		if ws.Event != "approval.granted" {
+			t.Errorf("Expected primary event approval.granted, got %s", ws.Event)
+		}
+		if len(ws.Events) != 2 || ws.Events[1] != "approval.auto_approved" {
+			t.Errorf("Expected both events recorded without duplicates, got %v", ws.Events)
+		}
+		if !ws.Accepts("approval.auto_approved") || ws.Accepts("approval.denied") {
+			t.Error("WaitState should accept only the configured events")
+		}
+	})
+
 	t.Run("handles missing wait config", func(t *testing.T) {
 		repo := &mockExecutionRepo{}
 		redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
@@ -365,6 +440,70 @@ func TestResumeExecution(t *testing.T) {
 			t.Error("Expected error for wrong event type")
 		}
 	})
+
+	t.Run("resumes on any alternative event and records which fired", func(t *testing.T) {
+		executionID := uuid.New()
+
+		var updatedExecution *models.WorkflowExecution
+		repo := &mockExecutionRepo{
+			getExecutionByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowExecution, error) {
+				return &models.WorkflowExecution{
+					ID:            executionID,
+					Status:        models.ExecutionStatusWaiting,
+					CurrentStepID: stringPtr("wait1"),
+					WaitState: &models.WaitState{
+						Event:  "approval.granted",
+						Events: []string{"approval.granted", "approval.auto_approved"},
+					},
+					Context: models.JSONB{},
+				}, nil
+			},
+			updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
+				updatedExecution = execution
+				return nil
+			},
+		}
+
+		redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		workflow := &models.Workflow{
+			Definition: models.WorkflowDefinition{
+				Steps: []models.Step{
+					{
+						ID:   "wait1",
+						Type: "wait",
+						Wait: &models.WaitConfig{Events: []string{"approval.granted", "approval.auto_approved"}},
+					},
+					{
+						ID:   "check_auto",
+						Type: "condition",
+						Condition: &models.Condition{
+							Field:    "resume_event_name",
+							Operator: "eq",
+							Value:    "approval.auto_approved",
+						},
+						OnTrue:  "allow",
+						OnFalse: "block",
+					},
+					{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}},
+					{ID: "block", Type: "action", Action: &models.Action{Type: "block"}},
+				},
+			},
+		}
+
+		_, err := executor.ResumeExecution(ctx, executionID, workflow, "approval.auto_approved", nil)
+		if err != nil {
+			t.Fatalf("ResumeExecution failed: %v", err)
+		}
+
+		if updatedExecution.Context["resume_event_name"] != "approval.auto_approved" {
+			t.Errorf("Expected resume_event_name approval.auto_approved, got %v", updatedExecution.Context["resume_event_name"])
+		}
+		if updatedExecution.Result == nil || *updatedExecution.Result != models.ExecutionResultAllowed {
+			t.Errorf("Expected allowed result, got %v", updatedExecution.Result)
+		}
+	})
 }
 
 func TestExecuteConditionStep(t *testing.T) {
@@ -467,323 +606,2750 @@ func TestExecuteConditionStep(t *testing.T) {
 	})
 }
 
-func stringPtr(s string) *string {
-	return &s
-}
-
-// TestExecuteWorkflow_Timeout tests that workflows timeout correctly
-func TestExecuteWorkflow_Timeout(t *testing.T) {
+func TestExecuteParallelStep(t *testing.T) {
 	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	repo := &mockExecutionRepo{}
+	executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
 	ctx := context.Background()
 
-	t.Run("workflow times out after default timeout", func(t *testing.T) {
-		executionCreated := false
-		executionCompleted := false
+	passing := models.Step{ID: "ok", Type: "action", Action: &models.Action{Type: "allow"}}
+	failing := models.Step{ID: "broken", Type: "condition"} // no condition defined
 
-		repo := &mockExecutionRepo{
-			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
-				executionCreated = true
-				// Verify timeout is stored in metadata
-				if _, ok := execution.Metadata["timeout_seconds"]; !ok {
-					t.Error("Expected timeout_seconds in metadata")
+	execution := &models.WorkflowExecution{
+		ID:          uuid.New(),
+		ExecutionID: "test-exec",
+	}
+
+	tests := []struct {
+		name         string
+		parallel     *models.ParallelStep
+		next         string
+		expectedNext string
+		expectError  bool
+		expectFailed int
+	}{
+		{
+			name:         "all_must_pass routes to on_success",
+			parallel:     &models.ParallelStep{Steps: []models.Step{passing, passing}, OnSuccess: "success", OnFailure: "failure"},
+			next:         "next",
+			expectedNext: "success",
+		},
+		{
+			name:         "on_success defaults to next",
+			parallel:     &models.ParallelStep{Steps: []models.Step{passing}},
+			next:         "next",
+			expectedNext: "next",
+		},
+		{
+			name:         "all_must_pass failure routes to on_failure",
+			parallel:     &models.ParallelStep{Steps: []models.Step{passing, failing}, OnSuccess: "success", OnFailure: "failure"},
+			expectedNext: "failure",
+			expectFailed: 1,
+		},
+		{
+			name:         "all_must_pass failure without on_failure aborts",
+			parallel:     &models.ParallelStep{Steps: []models.Step{passing, failing}},
+			expectError:  true,
+			expectFailed: 1,
+		},
+		{
+			name:         "any_can_pass with one success routes to on_success",
+			parallel:     &models.ParallelStep{Steps: []models.Step{passing, failing}, Strategy: "any_can_pass", OnSuccess: "success", OnFailure: "failure"},
+			expectedNext: "success",
+			expectFailed: 1,
+		},
+		{
+			name:         "any_can_pass with no success routes to on_failure",
+			parallel:     &models.ParallelStep{Steps: []models.Step{failing, failing}, Strategy: "any_can_pass", OnFailure: "failure"},
+			expectedNext: "failure",
+			expectFailed: 2,
+		},
+		{
+			name:         "best_effort partial failure routes to on_failure",
+			parallel:     &models.ParallelStep{Steps: []models.Step{passing, failing}, Strategy: "best_effort", OnFailure: "failure"},
+			next:         "next",
+			expectedNext: "failure",
+			expectFailed: 1,
+		},
+		{
+			name:         "best_effort partial failure without on_failure continues",
+			parallel:     &models.ParallelStep{Steps: []models.Step{passing, failing}, Strategy: "best_effort"},
+			next:         "next",
+			expectedNext: "next",
+			expectFailed: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &models.Step{
+				ID:       "parallel1",
+				Type:     "parallel",
+				Parallel: tt.parallel,
+				Next:     tt.next,
+			}
+
+			nextStep, output, err := executor.executeParallelStep(ctx, execution, step, map[string]interface{}{})
+
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("Expected error, got nil")
 				}
-				// Note: timeout will be 1ns = 0.000000001 seconds
-				return nil
-			},
-			updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
-				executionCompleted = true
-				// Verify it was marked as failed due to timeout
-				if execution.Status != models.ExecutionStatusFailed {
-					t.Errorf("Expected status failed, got %s", execution.Status)
+			} else if err != nil {
+				t.Fatalf("executeParallelStep failed: %v", err)
+			}
+
+			if nextStep != tt.expectedNext {
+				t.Errorf("Expected next step %q, got %q", tt.expectedNext, nextStep)
+			}
+
+			if output["failed"] != tt.expectFailed {
+				t.Errorf("Expected %d failed branches, got %v", tt.expectFailed, output["failed"])
+			}
+
+			failures, ok := output["failures"].([]map[string]interface{})
+			if !ok || len(failures) != tt.expectFailed {
+				t.Fatalf("Expected %d failure entries, got %v", tt.expectFailed, output["failures"])
+			}
+			for _, failure := range failures {
+				if failure["step_id"] != "broken" {
+					t.Errorf("Expected failure for step broken, got %v", failure["step_id"])
 				}
-				if execution.ErrorMessage == nil || !contains(*execution.ErrorMessage, "timed out") {
-					t.Error("Expected error message to contain 'timed out'")
+				if failure["error"] == "" {
+					t.Error("Expected failure reason to be recorded")
 				}
-				return nil
-			},
-		}
+			}
+		})
+	}
+}
 
-		redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
-		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+func TestExecuteParallelStep_CollectInto(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
 
-		// Create a workflow with a single step
-		workflow := &models.Workflow{
-			ID:   uuid.New(),
-			Name: "timeout-workflow",
-			Definition: models.WorkflowDefinition{
-				Trigger: models.TriggerDefinition{
-					Type: "event",
-				},
-				Steps: []models.Step{
-					{
-						ID:   "step1",
-						Type: "action",
-						Action: &models.Action{
-							Type: "allow",
-						},
+	execution := &models.WorkflowExecution{
+		ID:          uuid.New(),
+		ExecutionID: "test-exec",
+	}
+
+	step := &models.Step{
+		ID:   "parallel1",
+		Type: "parallel",
+		Parallel: &models.ParallelStep{
+			Strategy: "best_effort",
+			Steps: []models.Step{
+				{
+					ID:   "notify",
+					Type: "execute",
+					Execute: []models.ExecuteAction{
+						{Type: "log", Message: "branch ran"},
 					},
 				},
+				{ID: "broken", Type: "condition"},
 			},
-		}
-
-		// Set an impossibly short timeout to guarantee timeout
-		executor.defaultTimeout = 1 * time.Nanosecond
+			CollectInto: "branch_results",
+		},
+	}
 
-		orgID := uuid.New()
-		execution, err := executor.Execute(ctx, orgID, workflow, "test.event", map[string]interface{}{})
+	execContext := map[string]interface{}{}
+	if _, _, err := executor.executeParallelStep(context.Background(), execution, step, execContext); err != nil {
+		t.Fatalf("executeParallelStep failed: %v", err)
+	}
 
-		if !executionCreated {
-			t.Error("Expected execution to be created")
-		}
+	collected, ok := execContext["branch_results"].([]interface{})
+	if !ok {
+		t.Fatalf("Expected branch_results slice in context, got %T", execContext["branch_results"])
+	}
+	if len(collected) != 2 {
+		t.Fatalf("Expected 2 collected results, got %d", len(collected))
+	}
 
-		// Should timeout
-		if err == nil {
-			t.Error("Expected timeout error")
-		}
+	data, ok := collected[0].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected map data for first branch, got %T", collected[0])
+	}
+	if _, exists := data["execute_results"]; !exists {
+		t.Error("Expected execute_results in first branch data")
+	}
+	if collected[1] != nil {
+		t.Errorf("Expected nil for failed branch, got %v", collected[1])
+	}
+}
 
-		if execution == nil {
-			t.Fatal("Expected execution to be returned")
-		}
+func TestExecuteParallelStep_MergesBranchContext(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
 
-		// Give a moment for update to complete
-		time.Sleep(50 * time.Millisecond)
+	execution := &models.WorkflowExecution{
+		ID:          uuid.New(),
+		ExecutionID: "test-exec",
+	}
 
-		if !executionCompleted {
-			t.Error("Expected execution to be marked as completed")
+	branch := func(id, resultVar string) models.Step {
+		return models.Step{
+			ID:   id,
+			Type: "foreach",
+			ForEach: &models.ForEachStep{
+				Items:     `[1, 2]`,
+				ItemVar:   "n",
+				ResultVar: resultVar,
+				Steps: []models.Step{
+					{ID: id + "_allow", Type: "action", Action: &models.Action{Type: "allow"}},
+				},
+			},
 		}
-	})
+	}
 
-	t.Run("workflow completes before timeout", func(t *testing.T) {
-		executionCreated := false
-		executionCompleted := false
+	step := &models.Step{
+		ID:   "parallel1",
+		Type: "parallel",
+		Parallel: &models.ParallelStep{
+			Steps: []models.Step{branch("left", "left_results"), branch("right", "right_results")},
+		},
+	}
 
-		repo := &mockExecutionRepo{
-			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
-				executionCreated = true
-				return nil
-			},
-			updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
-				executionCompleted = true
-				// Should complete successfully
-				if execution.Status != models.ExecutionStatusCompleted {
-					t.Errorf("Expected status completed, got %s", execution.Status)
-				}
-				return nil
-			},
+	original := map[string]interface{}{"id": "A1"}
+	execContext := map[string]interface{}{"order": original}
+	if _, _, err := executor.executeParallelStep(context.Background(), execution, step, execContext); err != nil {
+		t.Fatalf("executeParallelStep failed: %v", err)
+	}
+
+	for _, key := range []string{"left_results", "right_results"} {
+		results, ok := execContext[key].([]interface{})
+		if !ok || len(results) != 2 {
+			t.Errorf("Expected %s with 2 results merged from its branch, got %v", key, execContext[key])
 		}
+	}
+	if order, ok := execContext["order"].(map[string]interface{}); !ok || order["id"] != "A1" {
+		t.Errorf("Expected untouched order to survive the merge, got %v", execContext["order"])
+	}
+}
 
-		redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
-		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+func TestExecuteForEachStep(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	ctx := context.Background()
 
-		workflow := &models.Workflow{
-			ID:   uuid.New(),
-			Name: "fast-workflow",
-			Definition: models.WorkflowDefinition{
-				Trigger: models.TriggerDefinition{
-					Type: "event",
-				},
+	execution := &models.WorkflowExecution{
+		ID:          uuid.New(),
+		ExecutionID: "test-exec",
+	}
+
+	// Numeric items pass the condition; non-numeric items fail the comparison
+	newStep := func(concurrency int, continueOnError bool) *models.Step {
+		return &models.Step{
+			ID:   "loop",
+			Type: "foreach",
+			ForEach: &models.ForEachStep{
+				Items:   "{{items}}",
+				ItemVar: "item",
 				Steps: []models.Step{
 					{
-						ID:   "step1",
-						Type: "action",
-						Action: &models.Action{
-							Type: "allow",
-						},
+						ID:        "check",
+						Type:      "condition",
+						Condition: &models.Condition{Field: "item", Operator: "gt", Value: 0},
 					},
 				},
+				Concurrency:     concurrency,
+				ContinueOnError: continueOnError,
 			},
 		}
+	}
 
-		// Generous timeout
-		executor.defaultTimeout = 5 * time.Second
-
-		orgID := uuid.New()
-		execution, err := executor.Execute(ctx, orgID, workflow, "test.event", map[string]interface{}{})
-
-		if err != nil {
-			t.Errorf("Expected no error, got %v", err)
-		}
+	tests := []struct {
+		name            string
+		items           []interface{}
+		concurrency     int
+		continueOnError bool
+		expectError     string
+		expectRuns      int32
+	}{
+		{
+			name:        "sequential by default",
+			items:       []interface{}{1, 2, 3},
+			concurrency: 0,
+			expectRuns:  3,
+		},
+		{
+			name:        "sequential stops at first failure",
+			items:       []interface{}{1, "bad", 3},
+			concurrency: 1,
+			expectError: "foreach iteration 1",
+			expectRuns:  2,
+		},
+		{
+			name:        "concurrent runs all items",
+			items:       []interface{}{1, 2, 3, 4, 5, 6},
+			concurrency: 3,
+			expectRuns:  6,
+		},
+		{
+			name:        "concurrent returns first error by index",
+			items:       []interface{}{1, 2, "bad", "worse"},
+			concurrency: 4,
+			expectError: "foreach iteration 2",
+			expectRuns:  -1, // later items may not be dispatched once a failure is seen
+		},
+		{
+			name:            "continue on error processes all items",
+			items:           []interface{}{"bad", 2, "worse", 4},
+			concurrency:     2,
+			continueOnError: true,
+			expectRuns:      4,
+		},
+	}
 
-		if !executionCreated {
-			t.Error("Expected execution to be created")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs atomic.Int32
+			repo := &mockExecutionRepo{
+				createStepExecutionFunc: func(ctx context.Context, step *models.StepExecution) error {
+					runs.Add(1)
+					return nil
+				},
+			}
+			executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+			execContext := map[string]interface{}{"items": tt.items}
+			err := executor.executeForEachStep(ctx, execution, newStep(tt.concurrency, tt.continueOnError), execContext)
+
+			if tt.expectError != "" {
+				if err == nil {
+					t.Fatalf("Expected error containing %q, got nil", tt.expectError)
+				}
+				if !contains(err.Error(), tt.expectError) {
+					t.Errorf("Expected error containing %q, got %v", tt.expectError, err)
+				}
+			} else if err != nil {
+				t.Fatalf("executeForEachStep failed: %v", err)
+			}
+
+			if tt.expectRuns >= 0 && runs.Load() != tt.expectRuns {
+				t.Errorf("Expected %d iterations to run, got %d", tt.expectRuns, runs.Load())
+			}
+		})
+	}
+}
+
+func TestExecuteForEachStep_ResultVar(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+	ctx := context.Background()
+
+	execution := &models.WorkflowExecution{
+		ID:          uuid.New(),
+		ExecutionID: "test-exec",
+	}
+
+	check := models.Step{
+		ID:        "check",
+		Type:      "condition",
+		Condition: &models.Condition{Field: "item", Operator: "gt", Value: 0},
+	}
+	allow := models.Step{
+		ID:     "allow",
+		Type:   "action",
+		Action: &models.Action{Type: "allow", Reason: "item ok"},
+	}
+
+	t.Run("collects final action results with nil for failures", func(t *testing.T) {
+		step := &models.Step{
+			ID:   "loop",
+			Type: "foreach",
+			ForEach: &models.ForEachStep{
+				Items:           "{{items}}",
+				ItemVar:         "item",
+				Steps:           []models.Step{check, allow},
+				Concurrency:     2,
+				ContinueOnError: true,
+				ResultVar:       "loop_results",
+			},
 		}
 
-		if !executionCompleted {
-			t.Error("Expected execution to be completed")
+		execContext := map[string]interface{}{"items": []interface{}{1, "bad", 3}}
+		if err := executor.executeForEachStep(ctx, execution, step, execContext); err != nil {
+			t.Fatalf("executeForEachStep failed: %v", err)
 		}
 
-		if execution == nil {
-			t.Fatal("Expected execution to be returned")
+		results, ok := execContext["loop_results"].([]interface{})
+		if !ok || len(results) != 3 {
+			t.Fatalf("Expected 3 loop results, got %v", execContext["loop_results"])
 		}
 
-		if execution.Status != models.ExecutionStatusCompleted {
-			t.Errorf("Expected status completed, got %s", execution.Status)
+		first, ok := results[0].(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected map result for first iteration, got %T", results[0])
+		}
+		if first["action"] != "allow" || first["success"] != true {
+			t.Errorf("Unexpected first result: %v", first)
+		}
+		if results[1] != nil {
+			t.Errorf("Expected nil result for failed iteration, got %v", results[1])
+		}
+		if results[2] == nil {
+			t.Error("Expected result for third iteration")
 		}
 	})
 
-	t.Run("workflow with custom timeout", func(t *testing.T) {
-		customTimeout := 60.0 // seconds
+	t.Run("iterations without action result yield nil", func(t *testing.T) {
+		step := &models.Step{
+			ID:   "loop",
+			Type: "foreach",
+			ForEach: &models.ForEachStep{
+				Items:     "{{items}}",
+				ItemVar:   "item",
+				Steps:     []models.Step{check},
+				ResultVar: "loop_results",
+			},
+		}
 
-		repo := &mockExecutionRepo{
-			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
-				// Verify custom timeout is used
-				if timeoutVal, ok := execution.Metadata["timeout_seconds"]; !ok {
-					t.Error("Expected timeout_seconds in metadata")
-				} else {
-					if timeout, ok := timeoutVal.(float64); ok {
-						if timeout != customTimeout {
-							t.Errorf("Expected timeout %v, got %v", customTimeout, timeout)
-						}
-					}
-				}
-				return nil
+		execContext := map[string]interface{}{"items": []interface{}{1, 2}}
+		if err := executor.executeForEachStep(ctx, execution, step, execContext); err != nil {
+			t.Fatalf("executeForEachStep failed: %v", err)
+		}
+
+		results := execContext["loop_results"].([]interface{})
+		if len(results) != 2 || results[0] != nil || results[1] != nil {
+			t.Errorf("Expected [nil nil], got %v", results)
+		}
+	})
+
+	t.Run("uses result expression when configured", func(t *testing.T) {
+		step := &models.Step{
+			ID:   "loop",
+			Type: "foreach",
+			ForEach: &models.ForEachStep{
+				Items:      "{{items}}",
+				ItemVar:    "item",
+				Steps:      []models.Step{allow},
+				ResultVar:  "ids",
+				ResultExpr: "{{item.id}}",
 			},
-			updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
-				return nil
+		}
+
+		execContext := map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"id": "a"},
+				map[string]interface{}{"id": "b"},
 			},
 		}
+		if err := executor.executeForEachStep(ctx, execution, step, execContext); err != nil {
+			t.Fatalf("executeForEachStep failed: %v", err)
+		}
 
-		redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
-		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+		ids := execContext["ids"].([]interface{})
+		if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+			t.Errorf("Expected [a b], got %v", ids)
+		}
+	})
+}
 
-		workflow := &models.Workflow{
-			ID:   uuid.New(),
-			Name: "custom-timeout-workflow",
-			Definition: models.WorkflowDefinition{
-				Trigger: models.TriggerDefinition{
-					Type: "event",
-					Data: map[string]interface{}{
-						"timeout_seconds": customTimeout,
-					},
-				},
-				Steps: []models.Step{
-					{
-						ID:   "step1",
-						Type: "action",
-						Action: &models.Action{
-							Type: "allow",
-						},
-					},
-				},
+func TestResolveItemsCollection(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	execContext := map[string]interface{}{
+		"order": map[string]interface{}{
+			"line_items": []interface{}{"x", "y"},
+			"total":      100.0,
+			"fulfillments": []interface{}{
+				map[string]interface{}{"items": []map[string]interface{}{{"sku": "A1"}}},
 			},
-		}
+		},
+	}
 
-		// Default timeout is shorter
-		executor.defaultTimeout = 5 * time.Second
+	tests := []struct {
+		name        string
+		items       string
+		expectedLen int
+		expectError string
+	}{
+		{name: "variable reference", items: "{{order.line_items}}", expectedLen: 2},
+		{name: "literal string array", items: `["a","b","c"]`, expectedLen: 3},
+		{name: "literal object array", items: ` [{"sku":"A1"},{"sku":"B2"}]`, expectedLen: 2},
+		{name: "empty literal array", items: "[]", expectedLen: 0},
+		{name: "malformed JSON", items: `["a",`, expectError: "not a valid JSON array"},
+		{name: "JSON that is not an array", items: `[1] extra`, expectError: "not a valid JSON array"},
+		{name: "indexed variable reference", items: "{{order.fulfillments[0].items}}", expectedLen: 1},
+		{name: "missing segment is named", items: "{{order.fulfillments[2].items}}", expectError: "segment order.fulfillments[2]"},
+		{name: "non-collection variable", items: "{{order.total}}", expectError: "is not a collection"},
+		{name: "unsupported format", items: "order.line_items", expectError: "must be a variable reference"},
+	}
 
-		orgID := uuid.New()
-		execution, err := executor.Execute(ctx, orgID, workflow, "test.event", map[string]interface{}{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executor.resolveItemsCollection(context.Background(), tt.items, execContext)
 
-		if err != nil {
-			t.Errorf("Expected no error, got %v", err)
+			if tt.expectError != "" {
+				if err == nil || !contains(err.Error(), tt.expectError) {
+					t.Errorf("Expected error containing %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(result) != tt.expectedLen {
+				t.Errorf("Expected %d items, got %d", tt.expectedLen, len(result))
+			}
+		})
+	}
+}
+
+func TestCalculateBackoff(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	t.Run("deterministic without jitter", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			attempt  int
+			config   *models.RetryConfig
+			expected time.Duration
+		}{
+			{name: "default exponential", attempt: 3, config: nil, expected: 4 * time.Second},
+			{name: "linear", attempt: 3, config: &models.RetryConfig{Backoff: "linear"}, expected: 3 * time.Second},
+			{name: "exponential", attempt: 4, config: &models.RetryConfig{Backoff: "exponential"}, expected: 8 * time.Second},
+			{name: "capped by max backoff", attempt: 10, config: &models.RetryConfig{Backoff: "exponential", MaxBackoff: "30s"}, expected: 30 * time.Second},
+			{name: "below max backoff", attempt: 2, config: &models.RetryConfig{Backoff: "exponential", MaxBackoff: "30s"}, expected: 2 * time.Second},
+			{name: "many attempts capped without overflowing", attempt: 100, config: &models.RetryConfig{Backoff: "exponential", MaxBackoff: "30s"}, expected: 30 * time.Second},
+			{name: "unknown strategy keeps the initial delay", attempt: 3, config: &models.RetryConfig{Backoff: "constant"}, expected: time.Second},
+			{name: "invalid jitter ignored", attempt: 2, config: &models.RetryConfig{Backoff: "linear", Jitter: "lots"}, expected: 2 * time.Second},
 		}
 
-		if execution == nil {
-			t.Fatal("Expected execution to be returned")
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if got := executor.calculateBackoff(context.Background(), tt.attempt, tt.config); got != tt.expected {
+					t.Errorf("Expected %v, got %v", tt.expected, got)
+				}
+			})
+		}
+	})
+
+	t.Run("jitter stays within bounds", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			config *models.RetryConfig
+			min    time.Duration
+			max    time.Duration
+		}{
+			{name: "full", config: &models.RetryConfig{Backoff: "exponential", Jitter: "full"}, min: 0, max: 8 * time.Second},
+			{name: "equal", config: &models.RetryConfig{Backoff: "exponential", Jitter: "equal"}, min: 4 * time.Second, max: 8 * time.Second},
+			{name: "percentage", config: &models.RetryConfig{Backoff: "exponential", Jitter: "25%"}, min: 6 * time.Second, max: 10 * time.Second},
+			{name: "percentage capped", config: &models.RetryConfig{Backoff: "exponential", Jitter: "50%", MaxBackoff: "8s"}, min: 4 * time.Second, max: 8 * time.Second},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				distinct := make(map[time.Duration]bool)
+				for i := 0; i < 200; i++ {
+					got := executor.calculateBackoff(context.Background(), 4, tt.config)
+					if got < tt.min || got > tt.max {
+						t.Fatalf("Backoff %v outside [%v, %v]", got, tt.min, tt.max)
+					}
+					distinct[got] = true
+				}
+				if len(distinct) < 2 {
+					t.Error("Expected jittered backoff to vary between calls")
+				}
+			})
 		}
 	})
 }
 
-// TestGetWorkflowTimeout tests the timeout extraction logic
-func TestGetWorkflowTimeout(t *testing.T) {
+func TestExecuteStepWithRetry_CancelledDuringBackoff(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	execution := &models.WorkflowExecution{
+		ID:          uuid.New(),
+		ExecutionID: "test-exec",
+	}
+
+	// Always fails (no condition defined); the first linear backoff is 2s
+	step := &models.Step{
+		ID:   "flaky",
+		Type: "condition",
+		Retry: &models.RetryConfig{
+			MaxAttempts: 3,
+			Backoff:     "linear",
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, _, err := executor.executeStepWithRetry(ctx, execution, step, map[string]interface{}{})
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("Expected error when context is cancelled")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("Expected prompt return after cancellation, took %v", elapsed)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
 	log := logger.NewForTesting()
 	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
 	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
 
 	tests := []struct {
 		name     string
-		workflow *models.Workflow
-		expected time.Duration
+		err      error
+		retryOn  []string
+		expected bool
+	}{
+		{name: "no patterns retries everything", err: errors.New("boom"), retryOn: nil, expected: true},
+		{name: "wildcard", err: errors.New("boom"), retryOn: []string{"*"}, expected: true},
+		{name: "exact match", err: errors.New("service unavailable"), retryOn: []string{"service unavailable"}, expected: true},
+		{name: "exact mismatch", err: errors.New("service unavailable (req 42)"), retryOn: []string{"service unavailable"}, expected: false},
+		{name: "regex match", err: errors.New("webhook returned status 503 (request id abc-123)"), retryOn: []string{"re:status 5\\d\\d"}, expected: true},
+		{name: "regex mismatch", err: errors.New("webhook returned status 404"), retryOn: []string{"re:status 5\\d\\d"}, expected: false},
+		{name: "invalid regex is skipped", err: errors.New("anything"), retryOn: []string{"re:([", "anything"}, expected: true},
+		{name: "timeout token matches deadline", err: fmt.Errorf("call failed: %w", context.DeadlineExceeded), retryOn: []string{"timeout"}, expected: true},
+		{name: "timeout token ignores other errors", err: errors.New("timeout"), retryOn: []string{"timeout"}, expected: false},
+		{name: "circuit_open token matches open breaker", err: fmt.Errorf("classification failed: %w", circuitbreaker.ErrOpen), retryOn: []string{"circuit_open"}, expected: true},
+		{name: "circuit_open token ignores other errors", err: errors.New("circuit open"), retryOn: []string{"circuit_open"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := executor.isRetryableError(context.Background(), tt.err, tt.retryOn); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("compiled patterns are cached", func(t *testing.T) {
+		executor.isRetryableError(context.Background(), errors.New("status 500"), []string{"re:status 5\\d\\d"})
+		if _, ok := executor.retryPatterns.Load("re:status 5\\d\\d"); !ok {
+			t.Error("Expected compiled pattern to be cached")
+		}
+	})
+}
+
+func TestExecuteStepWithRetry_MaxElapsed(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+
+	execution := &models.WorkflowExecution{
+		ID:          uuid.New(),
+		ExecutionID: "test-exec",
+	}
+
+	tests := []struct {
+		name        string
+		step        *models.Step
+		expectError string
+		expectCalls int32
+		maxDuration time.Duration
 	}{
 		{
-			name: "uses default timeout when no custom timeout set",
-			workflow: &models.Workflow{
-				Definition: models.WorkflowDefinition{
-					Trigger: models.TriggerDefinition{
-						Type: "event",
-					},
-				},
-			},
-			expected: 30 * time.Second, // default
-		},
-		{
-			name: "uses custom timeout from trigger data (float64)",
-			workflow: &models.Workflow{
-				Definition: models.WorkflowDefinition{
-					Trigger: models.TriggerDefinition{
-						Type: "event",
-						Data: map[string]interface{}{
-							"timeout_seconds": 120.0,
-						},
-					},
-				},
-			},
-			expected: 120 * time.Second,
-		},
-		{
-			name: "uses custom timeout from trigger data (int)",
-			workflow: &models.Workflow{
-				Definition: models.WorkflowDefinition{
-					Trigger: models.TriggerDefinition{
-						Type: "event",
-						Data: map[string]interface{}{
-							"timeout_seconds": 90,
-						},
-					},
-				},
+			name: "stops when next backoff exceeds budget",
+			step: &models.Step{
+				ID:    "flaky",
+				Type:  "condition",
+				Retry: &models.RetryConfig{MaxAttempts: 5, Backoff: "linear", MaxElapsed: "1500ms"},
 			},
-			expected: 90 * time.Second,
+			expectError: "retry budget 1.5s exhausted",
+			expectCalls: 1,
+			maxDuration: time.Second,
 		},
 		{
-			name: "ignores invalid timeout (zero)",
-			workflow: &models.Workflow{
-				Definition: models.WorkflowDefinition{
-					Trigger: models.TriggerDefinition{
-						Type: "event",
-						Data: map[string]interface{}{
-							"timeout_seconds": 0,
-						},
-					},
-				},
+			name: "retries within budget",
+			step: &models.Step{
+				ID:    "flaky",
+				Type:  "condition",
+				Retry: &models.RetryConfig{MaxAttempts: 2, Backoff: "exponential", MaxBackoff: "10ms", MaxElapsed: "1s"},
 			},
-			expected: 30 * time.Second, // falls back to default
+			expectError: "step failed after 2 attempts",
+			expectCalls: 2,
+			maxDuration: time.Second,
 		},
 		{
-			name: "ignores invalid timeout (negative)",
-			workflow: &models.Workflow{
-				Definition: models.WorkflowDefinition{
-					Trigger: models.TriggerDefinition{
-						Type: "event",
-						Data: map[string]interface{}{
-							"timeout_seconds": -10.0,
-						},
-					},
-				},
+			name: "stops when backoff would outlast step timeout",
+			step: &models.Step{
+				ID:      "flaky",
+				Type:    "condition",
+				Timeout: "500ms",
+				Retry:   &models.RetryConfig{MaxAttempts: 3, Backoff: "linear", MaxElapsed: "1m"},
 			},
-			expected: 30 * time.Second, // falls back to default
+			expectError: "step timeout reached before next retry",
+			expectCalls: 1,
+			maxDuration: 400 * time.Millisecond,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			timeout := executor.getWorkflowTimeout(tt.workflow)
-			if timeout != tt.expected {
-				t.Errorf("Expected timeout %v, got %v", tt.expected, timeout)
+			var calls atomic.Int32
+			repo := &mockExecutionRepo{
+				createStepExecutionFunc: func(ctx context.Context, step *models.StepExecution) error {
+					calls.Add(1)
+					return nil
+				},
+			}
+			executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+			start := time.Now()
+			_, _, err := executor.executeStepWithRetry(context.Background(), execution, tt.step, map[string]interface{}{})
+			elapsed := time.Since(start)
+
+			if err == nil || !contains(err.Error(), tt.expectError) {
+				t.Errorf("Expected error containing %q, got %v", tt.expectError, err)
+			}
+			if calls.Load() != tt.expectCalls {
+				t.Errorf("Expected %d attempts, got %d", tt.expectCalls, calls.Load())
+			}
+			if elapsed > tt.maxDuration {
+				t.Errorf("Expected to finish within %v, took %v", tt.maxDuration, elapsed)
 			}
 		})
 	}
 }
 
-// Helper function to check if string contains substring
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && containsHelper(s, substr))
+func TestExecuteSteps_Compensation(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+
+	var mu sync.Mutex
+	var compensationRecords []*models.StepExecution
+	repo := &mockExecutionRepo{
+		updateStepExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, step *models.StepExecution) error {
+			if step.StepType == "compensation" {
+				mu.Lock()
+				compensationRecords = append(compensationRecords, step)
+				mu.Unlock()
+			}
+			return nil
+		},
+	}
+	executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	undo := func(message string) *models.CompensationConfig {
+		return &models.CompensationConfig{
+			Execute: []models.ExecuteAction{{Type: "log", Message: message}},
+		}
+	}
+	branch := []models.Step{{ID: "noop", Type: "action", Action: &models.Action{Type: "allow"}}}
+
+	workflow := &models.Workflow{
+		ID: uuid.New(),
+		Definition: models.WorkflowDefinition{
+			Steps: []models.Step{
+				{ID: "reserve", Type: "parallel", Parallel: &models.ParallelStep{Steps: branch}, Compensate: undo("release"), Next: "charge"},
+				{ID: "charge", Type: "parallel", Parallel: &models.ParallelStep{Steps: branch}, Compensate: undo("refund"), Next: "audit"},
+				{ID: "audit", Type: "parallel", Parallel: &models.ParallelStep{Steps: branch}, Next: "ship"},
+				{ID: "ship", Type: "condition"}, // fails: no condition defined
+			},
+		},
+	}
+
+	execution := &models.WorkflowExecution{
+		ID:          uuid.New(),
+		ExecutionID: "test-exec",
+		Metadata:    make(models.JSONB),
+	}
+
+	_, err := executor.executeSteps(context.Background(), execution, workflow, map[string]interface{}{})
+	if err == nil {
+		t.Fatal("Expected workflow to fail")
+	}
+
+	if len(compensationRecords) != 2 {
+		t.Fatalf("Expected 2 compensation records, got %d", len(compensationRecords))
+	}
+	// Compensations run in reverse order
+	if compensationRecords[0].StepID != "charge.compensate" || compensationRecords[1].StepID != "reserve.compensate" {
+		t.Errorf("Unexpected compensation order: %s, %s", compensationRecords[0].StepID, compensationRecords[1].StepID)
+	}
+	for _, record := range compensationRecords {
+		if record.Status != models.StepStatusCompleted {
+			t.Errorf("Expected compensation %s to complete, got %s", record.StepID, record.Status)
+		}
+	}
+
+	summary, ok := execution.Metadata["compensations"].([]map[string]interface{})
+	if !ok || len(summary) != 2 {
+		t.Fatalf("Expected compensation summary in metadata, got %v", execution.Metadata["compensations"])
+	}
+	if summary[0]["step_id"] != "charge" || summary[0]["success"] != true {
+		t.Errorf("Unexpected summary entry: %v", summary[0])
+	}
+}
+
+func TestResumeExecution_RunsCompensations(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	ctx := context.Background()
+
+	var saved *models.WorkflowExecution
+	var compensated []string
+	repo := &mockExecutionRepo{
+		updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
+			saved = execution
+			return nil
+		},
+		getExecutionByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowExecution, error) {
+			// Simulate loading the execution back from the database
+			raw, err := json.Marshal(saved)
+			if err != nil {
+				return nil, err
+			}
+			var loaded models.WorkflowExecution
+			if err := json.Unmarshal(raw, &loaded); err != nil {
+				return nil, err
+			}
+			return &loaded, nil
+		},
+		updateStepExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, step *models.StepExecution) error {
+			if step.StepType == "compensation" {
+				compensated = append(compensated, step.StepID)
+			}
+			return nil
+		},
+	}
+	executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	branch := []models.Step{{ID: "noop", Type: "action", Action: &models.Action{Type: "allow"}}}
+	workflow := &models.Workflow{
+		ID: uuid.New(),
+		Definition: models.WorkflowDefinition{
+			Steps: []models.Step{
+				{
+					ID:         "reserve",
+					Type:       "parallel",
+					Parallel:   &models.ParallelStep{Steps: branch},
+					Compensate: &models.CompensationConfig{Execute: []models.ExecuteAction{{Type: "log", Message: "release"}}},
+					Next:       "wait_payment",
+				},
+				{ID: "wait_payment", Type: "wait", Wait: &models.WaitConfig{Event: "payment.received"}},
+				{ID: "ship", Type: "condition"}, // fails: no condition defined
+			},
+		},
+	}
+
+	execution := &models.WorkflowExecution{
+		ID:          uuid.New(),
+		ExecutionID: "test-exec",
+		Metadata:    make(models.JSONB),
+	}
+
+	_, err := executor.executeSteps(ctx, execution, workflow, map[string]interface{}{})
+	if !errors.Is(err, ErrExecutionPaused) {
+		t.Fatalf("Expected execution to pause at the wait step, got %v", err)
+	}
+	if len(compensated) != 0 {
+		t.Fatalf("Expected no compensations before the pause, got %v", compensated)
+	}
+
+	resumed, err := executor.ResumeExecution(ctx, execution.ID, workflow, "payment.received", nil)
+	if err == nil {
+		t.Fatal("Expected resumed execution to fail at the ship step")
+	}
+	if resumed == nil || resumed.Status != models.ExecutionStatusFailed {
+		t.Fatalf("Expected failed execution after resume, got %+v", resumed)
+	}
+
+	if len(compensated) != 1 || compensated[0] != "reserve.compensate" {
+		t.Fatalf("Expected the step completed before the pause to be compensated, got %v", compensated)
+	}
+	if _, exists := resumed.Metadata[compensationStackKey]; exists {
+		t.Error("Expected the compensation stack to be cleared once compensations ran")
+	}
+}
+
+func TestExecuteWithOptions_DryRun(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	stepOutputs := make(map[string]models.JSONB)
+	repo := &mockExecutionRepo{
+		updateStepExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, step *models.StepExecution) error {
+			mu.Lock()
+			stepOutputs[step.StepID] = step.Output
+			mu.Unlock()
+			return nil
+		},
+	}
+	executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	workflow := &models.Workflow{
+		ID:   uuid.New(),
+		Name: "dry-run-workflow",
+		Definition: models.WorkflowDefinition{
+			Trigger: models.TriggerDefinition{Type: "event", Event: "order.created"},
+			Steps: []models.Step{
+				{
+					ID:        "check_total",
+					Type:      "condition",
+					Condition: &models.Condition{Field: "order.total", Operator: "gt", Value: 1000},
+					OnTrue:    "notify",
+					OnFalse:   "allow",
+				},
+				{
+					ID:   "notify",
+					Type: "execute",
+					Execute: []models.ExecuteAction{
+						// Would fail if actually called
+						{Type: "webhook", URL: "http://127.0.0.1:1/unreachable", Method: "POST"},
+					},
+				},
+				{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}},
+			},
+		},
+	}
+
+	payload := map[string]interface{}{"order": map[string]interface{}{"total": 1500}}
+	execution, err := executor.ExecuteWithOptions(ctx, uuid.New(), workflow, "order.created", payload, ExecutionOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+
+	if execution.Metadata["dry_run"] != true {
+		t.Error("Expected execution to be flagged as dry run")
+	}
+	if execution.Status != models.ExecutionStatusCompleted {
+		t.Errorf("Expected completed status, got %s", execution.Status)
+	}
+
+	// The trace records the path taken, with the side effect skipped
+	output, ok := stepOutputs["notify"]
+	if !ok {
+		t.Fatal("Expected notify step to be recorded")
+	}
+	data, _ := output["data"].(map[string]interface{})
+	if data["skipped"] != true {
+		t.Errorf("Expected notify step to be skipped, got %v", output)
+	}
+	if _, ran := stepOutputs["allow"]; ran {
+		t.Error("Expected allow step not to be on the dry-run path")
+	}
+}
+
+func TestExecuteWithOptions_DryRunContinuesPastWait(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+
+	var mu sync.Mutex
+	var ranSteps []string
+	repo := &mockExecutionRepo{
+		updateStepExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, step *models.StepExecution) error {
+			mu.Lock()
+			ranSteps = append(ranSteps, step.StepID)
+			mu.Unlock()
+			return nil
+		},
+	}
+	executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	workflow := &models.Workflow{
+		ID: uuid.New(),
+		Definition: models.WorkflowDefinition{
+			Steps: []models.Step{
+				{
+					ID:       "wait_payment",
+					Type:     "wait",
+					Wait:     &models.WaitConfig{Event: "payment.received"},
+					Metadata: map[string]interface{}{"on_resume": "block"},
+				},
+				{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}},
+				{ID: "block", Type: "action", Action: &models.Action{Type: "block"}},
+			},
+		},
+	}
+
+	execution, err := executor.ExecuteWithOptions(context.Background(), uuid.New(), workflow, "order.created", map[string]interface{}{}, ExecutionOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+
+	if execution.Status != models.ExecutionStatusCompleted {
+		t.Errorf("Expected completed status, got %s", execution.Status)
+	}
+	if execution.Result == nil || *execution.Result != models.ExecutionResultBlocked {
+		t.Errorf("Expected blocked result from the on_resume path, got %v", execution.Result)
+	}
+	if len(ranSteps) != 2 || ranSteps[0] != "wait_payment" || ranSteps[1] != "block" {
+		t.Errorf("Expected dry run to continue from the wait step to its on_resume step, got %v", ranSteps)
+	}
+}
+
+func TestExecuteWithOptions_IdempotencyKey(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	newWorkflow := func(keyPath string) *models.Workflow {
+		return &models.Workflow{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			Definition: models.WorkflowDefinition{
+				Trigger: models.TriggerDefinition{Type: "event", Event: "order.created", IdempotencyKey: keyPath},
+				Steps:   []models.Step{{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}}},
+			},
+		}
+	}
+
+	t.Run("returns the existing execution without re-running", func(t *testing.T) {
+		existing := &models.WorkflowExecution{ID: uuid.New(), ExecutionID: "exec_existing", Status: models.ExecutionStatusCompleted}
+		var lookedUp string
+		created := false
+		repo := &mockExecutionRepo{
+			getByIdempotencyKeyFunc: func(ctx context.Context, organizationID, workflowID uuid.UUID, key string) (*models.WorkflowExecution, error) {
+				lookedUp = key
+				return existing, nil
+			},
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				created = true
+				return nil
+			},
+		}
+		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		payload := map[string]interface{}{"event": map[string]interface{}{"id": "evt_1"}}
+		execution, err := executor.Execute(ctx, orgID, newWorkflow("event.id"), "order.created", payload)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+
+		if execution != existing {
+			t.Errorf("Expected the existing execution, got %v", execution)
+		}
+		if lookedUp != "evt_1" {
+			t.Errorf("Expected key resolved from the trigger path, got %q", lookedUp)
+		}
+		if created {
+			t.Error("Expected no new execution to be created")
+		}
+	})
+
+	t.Run("records the key on a new execution", func(t *testing.T) {
+		var recorded *string
+		repo := &mockExecutionRepo{
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				recorded = execution.IdempotencyKey
+				return nil
+			},
+		}
+		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		payload := map[string]interface{}{"idempotency_key": "from-payload"}
+		if _, err := executor.ExecuteWithOptions(ctx, orgID, newWorkflow(""), "order.created", payload, ExecutionOptions{IdempotencyKey: "explicit"}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if recorded == nil || *recorded != "explicit" {
+			t.Errorf("Expected the explicit key to take precedence, got %v", recorded)
+		}
+
+		if _, err := executor.Execute(ctx, orgID, newWorkflow(""), "order.created", payload); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if recorded == nil || *recorded != "from-payload" {
+			t.Errorf("Expected the payload key, got %v", recorded)
+		}
+	})
+
+	t.Run("returns the execution that won a concurrent insert", func(t *testing.T) {
+		winner := &models.WorkflowExecution{ID: uuid.New(), ExecutionID: "exec_winner"}
+		lookups := 0
+		repo := &mockExecutionRepo{
+			getByIdempotencyKeyFunc: func(ctx context.Context, organizationID, workflowID uuid.UUID, key string) (*models.WorkflowExecution, error) {
+				lookups++
+				if lookups == 1 {
+					return nil, errors.New("execution not found")
+				}
+				return winner, nil
+			},
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				return errors.New("duplicate key value violates unique constraint")
+			},
+		}
+		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		execution, err := executor.ExecuteWithOptions(ctx, orgID, newWorkflow(""), "order.created", map[string]interface{}{}, ExecutionOptions{IdempotencyKey: "dup"})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if execution != winner {
+			t.Errorf("Expected the concurrently created execution, got %v", execution)
+		}
+	})
+
+	t.Run("dry runs ignore the key", func(t *testing.T) {
+		repo := &mockExecutionRepo{
+			getByIdempotencyKeyFunc: func(ctx context.Context, organizationID, workflowID uuid.UUID, key string) (*models.WorkflowExecution, error) {
+				t.Error("Expected no idempotency lookup for a dry run")
+				return nil, errors.New("execution not found")
+			},
+		}
+		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		execution, err := executor.ExecuteWithOptions(ctx, orgID, newWorkflow(""), "order.created", map[string]interface{}{}, ExecutionOptions{DryRun: true, IdempotencyKey: "dup"})
+		if err != nil {
+			t.Fatalf("Dry run failed: %v", err)
+		}
+		if execution.IdempotencyKey != nil {
+			t.Errorf("Expected no key on a dry run, got %v", *execution.IdempotencyKey)
+		}
+	})
+}
+
+func TestExecuteSteps_StepInputSnapshot(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+
+	var mu sync.Mutex
+	inputs := make(map[string]models.JSONB)
+	repo := &mockExecutionRepo{
+		createStepExecutionFunc: func(ctx context.Context, step *models.StepExecution) error {
+			mu.Lock()
+			inputs[step.StepID] = step.Input
+			mu.Unlock()
+			return nil
+		},
+	}
+	executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	workflow := &models.Workflow{
+		ID: uuid.New(),
+		Definition: models.WorkflowDefinition{
+			Steps: []models.Step{
+				{
+					ID:   "collect",
+					Type: "foreach",
+					ForEach: &models.ForEachStep{
+						Items:     "{{order.items}}",
+						ItemVar:   "item",
+						ResultVar: "order_results",
+						Steps: []models.Step{
+							{ID: "allow_item", Type: "action", Action: &models.Action{Type: "allow"}},
+						},
+					},
+					Next: "finish",
+				},
+				{ID: "finish", Type: "action", Action: &models.Action{Type: "allow"}},
+			},
+		},
+	}
+
+	execution := &models.WorkflowExecution{
+		ID:          uuid.New(),
+		ExecutionID: "test-exec",
+		Metadata:    make(models.JSONB),
+	}
+	order := map[string]interface{}{"items": []interface{}{"a", "b"}}
+	execContext := map[string]interface{}{"order": order}
+
+	if _, err := executor.executeSteps(context.Background(), execution, workflow, execContext); err != nil {
+		t.Fatalf("executeSteps failed: %v", err)
+	}
+
+	// Mutate the live context after the run; recorded inputs must not change
+	order["items"] = []interface{}{"changed"}
+
+	collectInput := inputs["collect"]
+	if _, exists := collectInput["order_results"]; exists {
+		t.Error("Expected collect input to be captured before the step set order_results")
+	}
+	items := collectInput["order"].(map[string]interface{})["items"].([]interface{})
+	if len(items) != 2 || items[0] != "a" {
+		t.Errorf("Expected collect input to keep the original items, got %v", items)
+	}
+
+	if _, exists := inputs["finish"]["order_results"]; !exists {
+		t.Error("Expected finish input to include order_results set by the earlier step")
+	}
+}
+
+func TestExecuteSteps_ContinueOnError(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+
+	var failedSteps []string
+	repo := &mockExecutionRepo{
+		updateStepExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, step *models.StepExecution) error {
+			if step.Status == models.StepStatusFailed {
+				failedSteps = append(failedSteps, step.StepID)
+			}
+			return nil
+		},
+	}
+	executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	workflow := &models.Workflow{
+		ID: uuid.New(),
+		Definition: models.WorkflowDefinition{
+			Steps: []models.Step{
+				// Both fail (no condition defined) but are non-fatal
+				{ID: "analytics", Type: "condition", ContinueOnError: true, Next: "enrich"},
+				{ID: "enrich", Type: "condition", OnError: "fallback", Next: "unreachable"},
+				{ID: "fallback", Type: "action", Action: &models.Action{Type: "allow"}},
+				{ID: "unreachable", Type: "action", Action: &models.Action{Type: "block"}},
+			},
+		},
+	}
+
+	execution := &models.WorkflowExecution{
+		ID:          uuid.New(),
+		ExecutionID: "test-exec",
+		Metadata:    make(models.JSONB),
+	}
+
+	result, err := executor.executeSteps(context.Background(), execution, workflow, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Expected workflow to continue past non-fatal errors, got %v", err)
+	}
+	if result != models.ExecutionResultAllowed {
+		t.Errorf("Expected allowed result from fallback step, got %s", result)
+	}
+	if execution.Metadata["non_fatal_errors"] != 2 {
+		t.Errorf("Expected 2 non-fatal errors, got %v", execution.Metadata["non_fatal_errors"])
+	}
+	if len(failedSteps) != 2 {
+		t.Errorf("Expected failed step executions to be recorded, got %v", failedSteps)
+	}
+}
+
+func TestExecuteSteps_FailedWebhook(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+
+	var deliveries atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("maintenance"))
+	}))
+	defer server.Close()
+
+	var failedOutput models.JSONB
+	repo := &mockExecutionRepo{
+		updateStepExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, step *models.StepExecution) error {
+			if step.Status == models.StepStatusFailed {
+				failedOutput = step.Output
+			}
+			return nil
+		},
+	}
+	executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	// The step's retries and on_error see the delivery fail after the action's own retries
+	workflow := &models.Workflow{
+		ID: uuid.New(),
+		Definition: models.WorkflowDefinition{
+			Steps: []models.Step{
+				{
+					ID:      "notify_erp",
+					Type:    "execute",
+					Execute: []models.ExecuteAction{{Type: "webhook", URL: server.URL}},
+					Retry:   &models.RetryConfig{MaxAttempts: 2, MaxBackoff: "10ms"},
+					OnError: "fallback",
+				},
+				{ID: "fallback", Type: "action", Action: &models.Action{Type: "block", Reason: "ERP unavailable"}},
+			},
+		},
+	}
+	execution := &models.WorkflowExecution{ID: uuid.New(), ExecutionID: "test-exec", Metadata: make(models.JSONB)}
+
+	result, err := executor.executeSteps(context.Background(), execution, workflow, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Expected the fallback step to handle the failure, got %v", err)
+	}
+	if result != models.ExecutionResultBlocked {
+		t.Errorf("Expected blocked result from the fallback step, got %s", result)
+	}
+	if got := deliveries.Load(); got != 2 {
+		t.Errorf("Expected the step to be retried once, got %d deliveries", got)
+	}
+
+	data, _ := failedOutput["data"].(map[string]interface{})
+	results, _ := data["execute_results"].([]map[string]interface{})
+	if len(results) != 1 || results[0]["status_code"] != http.StatusServiceUnavailable || results[0]["response"] != "maintenance" {
+		t.Errorf("Expected the failed step to record the final response, got %v", failedOutput)
+	}
+}
+
+func TestExecuteSteps_MaxStepVisits(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	// Two condition steps that always route to each other
+	loop := []models.Step{
+		{ID: "a", Type: "condition", Condition: &models.Condition{Field: "x", Operator: "eq", Value: 1}, OnTrue: "b", OnFalse: "b"},
+		{ID: "b", Type: "condition", Condition: &models.Condition{Field: "x", Operator: "eq", Value: 1}, OnTrue: "a", OnFalse: "a"},
+	}
+
+	execution := &models.WorkflowExecution{ID: uuid.New(), ExecutionID: "test-exec"}
+	execContext := map[string]interface{}{"x": 1}
+
+	t.Run("uses workflow limit", func(t *testing.T) {
+		workflow := &models.Workflow{ID: uuid.New(), Definition: models.WorkflowDefinition{Steps: loop, MaxStepVisits: 5}}
+
+		result, err := executor.executeSteps(context.Background(), execution, workflow, execContext)
+		if err == nil {
+			t.Fatal("Expected loop to be detected")
+		}
+		if result != models.ExecutionResultFailed {
+			t.Errorf("Expected failed result, got %s", result)
+		}
+		if !contains(err.Error(), "step a exceeded the maximum of 5 visits") {
+			t.Errorf("Expected error naming step a, got %v", err)
+		}
+	})
+
+	t.Run("falls back to executor default", func(t *testing.T) {
+		executor.SetMaxStepVisits(3)
+		workflow := &models.Workflow{ID: uuid.New(), Definition: models.WorkflowDefinition{Steps: loop}}
+
+		_, err := executor.executeSteps(context.Background(), execution, workflow, execContext)
+		if err == nil || !contains(err.Error(), "maximum of 3 visits") {
+			t.Errorf("Expected executor default limit, got %v", err)
+		}
+	})
+}
+
+func TestResolveWaitDeadline(t *testing.T) {
+	log := logger.NewForTesting()
+	executor := NewWorkflowExecutor(redis.NewClient(&redis.Options{Addr: "localhost:6379"}), &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	execContext := map[string]interface{}{
+		"order": map[string]interface{}{
+			"sla_deadline": "2024-06-02T12:00:00Z",
+			"sla_window":   "2h",
+			"expired":      "2024-05-31T12:00:00Z",
+			"garbage":      "soon",
+			"count":        3,
+			"due":          now.Add(30 * time.Minute),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		timeout  string
+		expected time.Time
+		errMsg   string
+	}{
+		{name: "static duration", timeout: "24h", expected: now.Add(24 * time.Hour)},
+		{name: "expression resolving to timestamp", timeout: "{{order.sla_deadline}}", expected: now.Add(24 * time.Hour)},
+		{name: "expression resolving to duration", timeout: "{{ order.sla_window }}", expected: now.Add(2 * time.Hour)},
+		{name: "expression resolving to time value", timeout: "{{order.due}}", expected: now.Add(30 * time.Minute)},
+		{name: "invalid static duration", timeout: "invalid", errMsg: "invalid timeout duration"},
+		{name: "deadline in the past", timeout: "{{order.expired}}", errMsg: "not in the future"},
+		{name: "unparseable string", timeout: "{{order.garbage}}", errMsg: "neither a duration nor an RFC3339 timestamp"},
+		{name: "unsupported type", timeout: "{{order.count}}", errMsg: "unsupported type"},
+		{name: "missing field", timeout: "{{order.missing}}", errMsg: "failed to resolve wait timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deadline, err := executor.resolveWaitDeadline(context.Background(), tt.timeout, execContext, now)
+			if tt.errMsg != "" {
+				if err == nil || !contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !deadline.Equal(tt.expected) {
+				t.Errorf("Expected deadline %s, got %s", tt.expected, deadline)
+			}
+		})
+	}
+}
+
+func TestExecuteCallStep(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	callWorkflow := func(id, target string) *models.Workflow {
+		return &models.Workflow{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			WorkflowID:     id,
+			Enabled:        true,
+			Definition: models.WorkflowDefinition{
+				Steps: []models.Step{
+					{ID: "call", Type: "call", Call: &models.CallStep{WorkflowID: target}},
+				},
+			},
+		}
+	}
+
+	newExecutor := func(workflows map[string]*models.Workflow, repo *mockExecutionRepo) *WorkflowExecutor {
+		workflowRepo := &mockWorkflowRepo{
+			getByWorkflowIDFunc: func(ctx context.Context, organizationID uuid.UUID, workflowID string) (*models.Workflow, error) {
+				if organizationID != orgID {
+					return nil, fmt.Errorf("wrong organization")
+				}
+				if wf, ok := workflows[workflowID]; ok {
+					return wf, nil
+				}
+				return nil, fmt.Errorf("workflow not found")
+			},
+		}
+		return NewWorkflowExecutor(redis.NewClient(&redis.Options{Addr: "localhost:6379"}), repo, workflowRepo, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+	}
+
+	t.Run("runs child workflow and stores its result", func(t *testing.T) {
+		child := &models.Workflow{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			WorkflowID:     "child",
+			Enabled:        true,
+			Definition: models.WorkflowDefinition{
+				Steps: []models.Step{
+					{
+						ID:   "check",
+						Type: "condition",
+						Condition: &models.Condition{
+							Field:    "order.total",
+							Operator: "gt",
+							Value:    100,
+						},
+						OnTrue:  "block",
+						OnFalse: "allow",
+					},
+					{ID: "block", Type: "action", Action: &models.Action{Type: "block"}},
+					{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}},
+				},
+			},
+		}
+
+		var mu sync.Mutex
+		var created []*models.WorkflowExecution
+		repo := &mockExecutionRepo{
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				mu.Lock()
+				defer mu.Unlock()
+				created = append(created, execution)
+				return nil
+			},
+		}
+		executor := newExecutor(map[string]*models.Workflow{"child": child}, repo)
+
+		execution := &models.WorkflowExecution{ID: uuid.New(), OrganizationID: orgID}
+		step := &models.Step{
+			ID:   "call_child",
+			Type: "call",
+			Call: &models.CallStep{
+				WorkflowID: "child",
+				Input:      map[string]string{"order": "{{order}}"},
+				ResultVar:  "child_result",
+			},
+			Next: "after",
+		}
+		execContext := map[string]interface{}{
+			"order": map[string]interface{}{"total": 250},
+		}
+
+		next, output, err := executor.executeCallStep(ctx, execution, step, execContext)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if next != "after" {
+			t.Errorf("Expected next step after, got %s", next)
+		}
+
+		if len(created) != 1 {
+			t.Fatalf("Expected one child execution, got %d", len(created))
+		}
+		childExec := created[0]
+		if childExec.OrganizationID != orgID {
+			t.Error("Child execution should inherit the parent's organization")
+		}
+		if childExec.Metadata["parent_execution_id"] != execution.ID.String() {
+			t.Errorf("Expected parent_execution_id %s, got %v", execution.ID, childExec.Metadata["parent_execution_id"])
+		}
+		if output["execution_id"] != childExec.ID.String() {
+			t.Errorf("Expected output execution_id %s, got %v", childExec.ID, output["execution_id"])
+		}
+		if output["result"] != string(models.ExecutionResultBlocked) {
+			t.Errorf("Expected child result blocked, got %v", output["result"])
+		}
+
+		result, ok := execContext["child_result"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected child_result in context, got %T", execContext["child_result"])
+		}
+		if result["result"] != string(models.ExecutionResultBlocked) {
+			t.Errorf("Expected merged result blocked, got %v", result["result"])
+		}
+	})
+
+	t.Run("rejects direct recursion", func(t *testing.T) {
+		self := callWorkflow("self", "self")
+		executor := newExecutor(map[string]*models.Workflow{"self": self}, &mockExecutionRepo{})
+
+		_, err := executor.Execute(ctx, orgID, self, "test", map[string]interface{}{})
+		if err == nil || !contains(err.Error(), "recursive workflow call: self -> self") {
+			t.Errorf("Expected recursion error, got %v", err)
+		}
+	})
+
+	t.Run("rejects indirect recursion", func(t *testing.T) {
+		a := callWorkflow("a", "b")
+		b := callWorkflow("b", "a")
+		executor := newExecutor(map[string]*models.Workflow{"a": a, "b": b}, &mockExecutionRepo{})
+
+		_, err := executor.Execute(ctx, orgID, a, "test", map[string]interface{}{})
+		if err == nil || !contains(err.Error(), "recursive workflow call: a -> b -> a") {
+			t.Errorf("Expected recursion error, got %v", err)
+		}
+	})
+
+	t.Run("child inherits the parent's deadline", func(t *testing.T) {
+		child := &models.Workflow{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			WorkflowID:     "slow",
+			Enabled:        true,
+			Definition: models.WorkflowDefinition{
+				Timeout: "1h",
+				Steps:   []models.Step{{ID: "noop", Type: "condition", Condition: &models.Condition{Field: "x", Operator: "eq", Value: 1}}},
+			},
+		}
+
+		var childDeadline time.Time
+		var hasDeadline bool
+		repo := &mockExecutionRepo{
+			createStepExecutionFunc: func(ctx context.Context, step *models.StepExecution) error {
+				if step.StepID == "noop" {
+					childDeadline, hasDeadline = ctx.Deadline()
+				}
+				return nil
+			},
+		}
+		executor := newExecutor(map[string]*models.Workflow{"slow": child}, repo)
+
+		parentCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		execution := &models.WorkflowExecution{ID: uuid.New(), OrganizationID: orgID}
+		step := &models.Step{ID: "call_slow", Type: "call", Call: &models.CallStep{WorkflowID: "slow"}}
+		execContext := map[string]interface{}{}
+
+		if _, _, err := executor.executeCallStep(parentCtx, execution, step, execContext); err == nil {
+			t.Fatal("Expected the child to fail on the missing field")
+		}
+
+		if !hasDeadline || time.Until(childDeadline) > 5*time.Second {
+			t.Errorf("Expected child to run within the parent's 5s budget, got deadline %v (set: %v)", childDeadline, hasDeadline)
+		}
+	})
+
+	t.Run("fails when the child pauses", func(t *testing.T) {
+		child := &models.Workflow{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			WorkflowID:     "waits",
+			Enabled:        true,
+			Definition: models.WorkflowDefinition{
+				Steps: []models.Step{
+					{ID: "wait_payment", Type: "wait", Wait: &models.WaitConfig{Event: "payment.received"}},
+					{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}},
+				},
+			},
+		}
+		executor := newExecutor(map[string]*models.Workflow{"waits": child}, &mockExecutionRepo{})
+
+		execution := &models.WorkflowExecution{ID: uuid.New(), OrganizationID: orgID}
+		step := &models.Step{ID: "call_waits", Type: "call", Call: &models.CallStep{WorkflowID: "waits", ResultVar: "child_result"}, Next: "after"}
+		execContext := map[string]interface{}{}
+
+		_, output, err := executor.executeCallStep(ctx, execution, step, execContext)
+		if err == nil || !contains(err.Error(), "paused") {
+			t.Fatalf("Expected paused child to fail the call step, got %v", err)
+		}
+		if output["status"] != string(models.ExecutionStatusWaiting) {
+			t.Errorf("Expected output to report the waiting child, got %v", output["status"])
+		}
+		if _, exists := execContext["child_result"]; exists {
+			t.Error("Expected no child result for a paused child")
+		}
+	})
+
+	t.Run("requires a workflow id", func(t *testing.T) {
+		executor := newExecutor(nil, &mockExecutionRepo{})
+		execution := &models.WorkflowExecution{ID: uuid.New(), OrganizationID: orgID}
+
+		_, _, err := executor.executeCallStep(ctx, execution, &models.Step{ID: "call", Type: "call"}, map[string]interface{}{})
+		if err == nil {
+			t.Error("Expected error for missing call configuration")
+		}
+	})
+}
+
+func TestExecute_ResponseAction(t *testing.T) {
+	log := logger.NewForTesting()
+	executor := NewWorkflowExecutor(redis.NewClient(&redis.Options{Addr: "localhost:6379"}), &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	workflow := &models.Workflow{
+		ID: uuid.New(),
+		Definition: models.WorkflowDefinition{
+			Steps: []models.Step{
+				{
+					ID:   "respond",
+					Type: "action",
+					Action: &models.Action{
+						Type:       "response",
+						StatusCode: 201,
+						Data:       map[string]interface{}{"quote": "${quote.amount}"},
+					},
+				},
+			},
+		},
+	}
+
+	execution, err := executor.Execute(context.Background(), uuid.New(), workflow, "manual", map[string]interface{}{
+		"quote": map[string]interface{}{"amount": 42.5},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	resp := execution.Response()
+	if resp == nil {
+		t.Fatal("Expected a response to be recorded on the execution")
+	}
+	if resp.StatusCode != 201 {
+		t.Errorf("Expected status code 201, got %d", resp.StatusCode)
+	}
+	if resp.Body["quote"] != 42.5 {
+		t.Errorf("Expected quote 42.5 in body, got %v", resp.Body)
+	}
+	if execution.ResultPayload["quote"] != 42.5 {
+		t.Errorf("Expected the response body in the result payload, got %v", execution.ResultPayload)
+	}
+}
+
+func TestExecuteSteps_AIClassifyContinues(t *testing.T) {
+	log := logger.NewForTesting()
+	executor := NewWorkflowExecutor(redis.NewClient(&redis.Options{Addr: "localhost:6379"}), &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+	executor.SetAIService(&mockAIService{label: "billing"})
+
+	workflow := &models.Workflow{
+		ID: uuid.New(),
+		Definition: models.WorkflowDefinition{
+			Steps: []models.Step{
+				{
+					ID:   "triage",
+					Type: "action",
+					Action: &models.Action{
+						Type:     "ai_classify",
+						Classify: &models.ClassifyAction{Input: "{{ticket.body}}", Labels: []string{"billing", "bug"}, OutputVar: "category"},
+					},
+					Next: "route",
+				},
+				{
+					ID:        "route",
+					Type:      "condition",
+					Condition: &models.Condition{Field: "category", Operator: "eq", Value: "billing"},
+					OnTrue:    "escalate",
+					OnFalse:   "close",
+				},
+				{ID: "escalate", Type: "action", Action: &models.Action{Type: "block", Reason: "Billing ticket"}},
+				{ID: "close", Type: "action", Action: &models.Action{Type: "allow"}},
+			},
+		},
+	}
+
+	execution := &models.WorkflowExecution{ID: uuid.New(), ExecutionID: "test-exec"}
+	execContext := map[string]interface{}{"ticket": map[string]interface{}{"body": "I was charged twice"}}
+
+	result, err := executor.executeSteps(context.Background(), execution, workflow, execContext)
+	if err != nil {
+		t.Fatalf("executeSteps failed: %v", err)
+	}
+	if result != models.ExecutionResultBlocked {
+		t.Errorf("Expected the classification to route to the blocking step, got %s", result)
+	}
+	if execContext["category"] != "billing" {
+		t.Errorf("Expected category billing in the context, got %v", execContext["category"])
+	}
+}
+
+// TestExecuteSteps_AIStepProgress tests that AI steps are asked to stream only when progress can be broadcast
+func TestExecuteSteps_AIStepProgress(t *testing.T) {
+	log := logger.NewForTesting()
+	workflow := &models.Workflow{
+		ID: uuid.New(),
+		Definition: models.WorkflowDefinition{
+			Steps: []models.Step{
+				{
+					ID:   "triage",
+					Type: "action",
+					Action: &models.Action{
+						Type:     "ai_classify",
+						Classify: &models.ClassifyAction{Input: "{{ticket.body}}", Labels: []string{"billing", "bug"}},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		wsHub    *websocket.Hub
+		streamed bool
+	}{
+		{"with a WebSocket hub", websocket.NewHub(nil, zap.NewNop()), true},
+		{"without a WebSocket hub", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewWorkflowExecutor(redis.NewClient(&redis.Options{Addr: "localhost:6379"}), &mockExecutionRepo{}, nil, tt.wsHub, log, nil, getTestContextEnrichmentConfigForExecutor())
+			aiService := &mockAIService{label: "billing"}
+			executor.SetAIService(aiService)
+
+			execution := &models.WorkflowExecution{ID: uuid.New(), ExecutionID: "test-exec"}
+			execContext := map[string]interface{}{"ticket": map[string]interface{}{"body": "I was charged twice"}}
+
+			if _, err := executor.executeSteps(context.Background(), execution, workflow, execContext); err != nil {
+				t.Fatalf("executeSteps failed: %v", err)
+			}
+			if aiService.streamed != tt.streamed {
+				t.Errorf("Expected streamed %v, got %v", tt.streamed, aiService.streamed)
+			}
+			if execContext["triage"] != "billing" {
+				t.Errorf("Expected the streamed label in the context, got %v", execContext["triage"])
+			}
+		})
+	}
+}
+
+// TestExecuteSteps_AIUsage tests that the LLM usage of AI steps is totalled in the execution metadata
+func TestExecuteSteps_AIUsage(t *testing.T) {
+	log := logger.NewForTesting()
+	executor := NewWorkflowExecutor(redis.NewClient(&redis.Options{Addr: "localhost:6379"}), &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+	executor.SetAIService(&mockAIService{
+		label: "billing",
+		usage: &llm.Usage{Model: "claude-3-5-haiku-20241022", PromptTokens: 120, CompletionTokens: 8, CostUSD: 0.0001},
+	})
+
+	classify := func(id, next string) models.Step {
+		return models.Step{
+			ID:   id,
+			Type: "action",
+			Action: &models.Action{
+				Type:     "ai_classify",
+				Classify: &models.ClassifyAction{Input: "{{ticket.body}}", Labels: []string{"billing", "bug"}},
+			},
+			Next: next,
+		}
+	}
+	workflow := &models.Workflow{
+		ID: uuid.New(),
+		Definition: models.WorkflowDefinition{
+			Steps: []models.Step{classify("triage", "priority"), classify("priority", "")},
+		},
+	}
+
+	// Usage recorded before the execution paused is read back from the database as JSON numbers
+	execution := &models.WorkflowExecution{
+		ID:          uuid.New(),
+		ExecutionID: "test-exec",
+		Metadata: models.JSONB{"llm_usage": map[string]interface{}{
+			"calls": 1.0, "prompt_tokens": 100.0, "completion_tokens": 4.0, "total_tokens": 104.0, "cost_usd": 0.0001,
+		}},
+	}
+	execContext := map[string]interface{}{"ticket": map[string]interface{}{"body": "I was charged twice"}}
+
+	if _, err := executor.executeSteps(context.Background(), execution, workflow, execContext); err != nil {
+		t.Fatalf("executeSteps failed: %v", err)
+	}
+
+	totals, ok := execution.Metadata["llm_usage"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected llm_usage in the execution metadata, got %v", execution.Metadata)
+	}
+	expected := map[string]float64{"calls": 3, "prompt_tokens": 340, "completion_tokens": 20, "total_tokens": 360}
+	for key, value := range expected {
+		if totals[key] != value {
+			t.Errorf("Expected %s %v, got %v", key, value, totals[key])
+		}
+	}
+	if cost, _ := totals["cost_usd"].(float64); cost < 0.000299 || cost > 0.000301 {
+		t.Errorf("Expected cost_usd 0.0003, got %v", totals["cost_usd"])
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// TestExecuteWorkflow_Timeout tests that workflows timeout correctly
+func TestExecuteWorkflow_Timeout(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+
+	t.Run("workflow times out after default timeout", func(t *testing.T) {
+		executionCreated := false
+		executionCompleted := false
+
+		repo := &mockExecutionRepo{
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				executionCreated = true
+				// Verify timeout is stored in metadata
+				if _, ok := execution.Metadata["timeout_seconds"]; !ok {
+					t.Error("Expected timeout_seconds in metadata")
+				}
+				// Note: timeout will be 1ns = 0.000000001 seconds
+				return nil
+			},
+			updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
+				executionCompleted = true
+				// Verify it was marked as failed due to timeout
+				if execution.Status != models.ExecutionStatusFailed {
+					t.Errorf("Expected status failed, got %s", execution.Status)
+				}
+				if execution.ErrorMessage == nil || !contains(*execution.ErrorMessage, "timed out") {
+					t.Error("Expected error message to contain 'timed out'")
+				}
+				return nil
+			},
+		}
+
+		redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		// Create a workflow with a single step
+		workflow := &models.Workflow{
+			ID:   uuid.New(),
+			Name: "timeout-workflow",
+			Definition: models.WorkflowDefinition{
+				Trigger: models.TriggerDefinition{
+					Type: "event",
+				},
+				Steps: []models.Step{
+					{
+						ID:   "step1",
+						Type: "action",
+						Action: &models.Action{
+							Type: "allow",
+						},
+					},
+				},
+			},
+		}
+
+		// Set an impossibly short timeout to guarantee timeout
+		executor.defaultTimeout = 1 * time.Nanosecond
+
+		orgID := uuid.New()
+		execution, err := executor.Execute(ctx, orgID, workflow, "test.event", map[string]interface{}{})
+
+		if !executionCreated {
+			t.Error("Expected execution to be created")
+		}
+
+		// Should timeout
+		if err == nil {
+			t.Error("Expected timeout error")
+		}
+
+		if execution == nil {
+			t.Fatal("Expected execution to be returned")
+		}
+
+		// Give a moment for update to complete
+		time.Sleep(50 * time.Millisecond)
+
+		if !executionCompleted {
+			t.Error("Expected execution to be marked as completed")
+		}
+	})
+
+	t.Run("workflow completes before timeout", func(t *testing.T) {
+		executionCreated := false
+		executionCompleted := false
+
+		repo := &mockExecutionRepo{
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				executionCreated = true
+				return nil
+			},
+			updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
+				executionCompleted = true
+				// Should complete successfully
+				if execution.Status != models.ExecutionStatusCompleted {
+					t.Errorf("Expected status completed, got %s", execution.Status)
+				}
+				return nil
+			},
+		}
+
+		redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		workflow := &models.Workflow{
+			ID:   uuid.New(),
+			Name: "fast-workflow",
+			Definition: models.WorkflowDefinition{
+				Trigger: models.TriggerDefinition{
+					Type: "event",
+				},
+				Steps: []models.Step{
+					{
+						ID:   "step1",
+						Type: "action",
+						Action: &models.Action{
+							Type: "allow",
+						},
+					},
+				},
+			},
+		}
+
+		// Generous timeout
+		executor.defaultTimeout = 5 * time.Second
+
+		orgID := uuid.New()
+		execution, err := executor.Execute(ctx, orgID, workflow, "test.event", map[string]interface{}{})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if !executionCreated {
+			t.Error("Expected execution to be created")
+		}
+
+		if !executionCompleted {
+			t.Error("Expected execution to be completed")
+		}
+
+		if execution == nil {
+			t.Fatal("Expected execution to be returned")
+		}
+
+		if execution.Status != models.ExecutionStatusCompleted {
+			t.Errorf("Expected status completed, got %s", execution.Status)
+		}
+	})
+
+	t.Run("workflow with custom timeout", func(t *testing.T) {
+		customTimeout := 60.0 // seconds
+
+		repo := &mockExecutionRepo{
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				// Verify custom timeout is used
+				if timeoutVal, ok := execution.Metadata["timeout_seconds"]; !ok {
+					t.Error("Expected timeout_seconds in metadata")
+				} else {
+					if timeout, ok := timeoutVal.(float64); ok {
+						if timeout != customTimeout {
+							t.Errorf("Expected timeout %v, got %v", customTimeout, timeout)
+						}
+					}
+				}
+				return nil
+			},
+			updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
+				return nil
+			},
+		}
+
+		redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		workflow := &models.Workflow{
+			ID:   uuid.New(),
+			Name: "custom-timeout-workflow",
+			Definition: models.WorkflowDefinition{
+				Trigger: models.TriggerDefinition{
+					Type: "event",
+					Data: map[string]interface{}{
+						"timeout_seconds": customTimeout,
+					},
+				},
+				Steps: []models.Step{
+					{
+						ID:   "step1",
+						Type: "action",
+						Action: &models.Action{
+							Type: "allow",
+						},
+					},
+				},
+			},
+		}
+
+		// Default timeout is shorter
+		executor.defaultTimeout = 5 * time.Second
+
+		orgID := uuid.New()
+		execution, err := executor.Execute(ctx, orgID, workflow, "test.event", map[string]interface{}{})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if execution == nil {
+			t.Fatal("Expected execution to be returned")
+		}
+	})
+}
+
+// TestGetWorkflowTimeout tests the timeout extraction logic
+func TestGetWorkflowTimeout(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	tests := []struct {
+		name     string
+		workflow *models.Workflow
+		expected time.Duration
+	}{
+		{
+			name: "uses default timeout when no custom timeout set",
+			workflow: &models.Workflow{
+				Definition: models.WorkflowDefinition{
+					Trigger: models.TriggerDefinition{
+						Type: "event",
+					},
+				},
+			},
+			expected: 30 * time.Second, // default
+		},
+		{
+			name: "uses custom timeout from trigger data (float64)",
+			workflow: &models.Workflow{
+				Definition: models.WorkflowDefinition{
+					Trigger: models.TriggerDefinition{
+						Type: "event",
+						Data: map[string]interface{}{
+							"timeout_seconds": 120.0,
+						},
+					},
+				},
+			},
+			expected: 120 * time.Second,
+		},
+		{
+			name: "uses custom timeout from trigger data (int)",
+			workflow: &models.Workflow{
+				Definition: models.WorkflowDefinition{
+					Trigger: models.TriggerDefinition{
+						Type: "event",
+						Data: map[string]interface{}{
+							"timeout_seconds": 90,
+						},
+					},
+				},
+			},
+			expected: 90 * time.Second,
+		},
+		{
+			name: "ignores invalid timeout (zero)",
+			workflow: &models.Workflow{
+				Definition: models.WorkflowDefinition{
+					Trigger: models.TriggerDefinition{
+						Type: "event",
+						Data: map[string]interface{}{
+							"timeout_seconds": 0,
+						},
+					},
+				},
+			},
+			expected: 30 * time.Second, // falls back to default
+		},
+		{
+			name: "ignores invalid timeout (negative)",
+			workflow: &models.Workflow{
+				Definition: models.WorkflowDefinition{
+					Trigger: models.TriggerDefinition{
+						Type: "event",
+						Data: map[string]interface{}{
+							"timeout_seconds": -10.0,
+						},
+					},
+				},
+			},
+			expected: 30 * time.Second, // falls back to default
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeout := executor.getWorkflowTimeout(tt.workflow)
+			if timeout != tt.expected {
+				t.Errorf("Expected timeout %v, got %v", tt.expected, timeout)
+			}
+		})
+	}
+}
+
+// Helper function to check if string contains substring
+func contains(s, substr string) bool {
+	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && containsHelper(s, substr))
+}
+
+func containsHelper(s, substr string) bool {
+	for i := 0; i <= len(s)-len(substr); i++ {
+		if s[i:i+len(substr)] == substr {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCancelExecution(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	workflow := &models.Workflow{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Definition: models.WorkflowDefinition{
+			Trigger: models.TriggerDefinition{Type: "event", Event: "order.created"},
+			Steps: []models.Step{
+				{ID: "check", Type: "condition", Condition: &models.Condition{Field: "x", Operator: "eq", Value: 1}, OnTrue: "approve", OnFalse: "approve"},
+				{ID: "approve", Type: "action", Action: &models.Action{Type: "allow"}},
+			},
+		},
+	}
+
+	t.Run("interrupts a running execution before its next step", func(t *testing.T) {
+		var executor *WorkflowExecutor
+		var executionID uuid.UUID
+		var executedSteps []string
+		var saved *models.WorkflowExecution
+
+		repo := &mockExecutionRepo{
+			createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+				executionID = execution.ID
+				return nil
+			},
+			createStepExecutionFunc: func(ctx context.Context, step *models.StepExecution) error {
+				executedSteps = append(executedSteps, step.StepID)
+				// Cancel while the first step is in flight
+				if !executor.CancelExecution(executionID) {
+					t.Error("Expected the execution to be registered while running")
+				}
+				return nil
+			},
+			updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
+				if ctx.Err() != nil {
+					t.Error("Expected the final state to be saved with a live context")
+				}
+				saved = execution
+				return nil
+			},
+		}
+		executor = NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		execution, err := executor.Execute(ctx, orgID, workflow, "order.created", map[string]interface{}{"x": 1})
+		if !errors.Is(err, ErrExecutionCancelled) {
+			t.Fatalf("Expected ErrExecutionCancelled, got %v", err)
+		}
+
+		if len(executedSteps) != 1 || executedSteps[0] != "check" {
+			t.Errorf("Expected only the first step to run, got %v", executedSteps)
+		}
+		if execution.Status != models.ExecutionStatusCancelled {
+			t.Errorf("Expected status cancelled, got %s", execution.Status)
+		}
+		if saved == nil || saved.Status != models.ExecutionStatusCancelled || saved.CompletedAt == nil {
+			t.Errorf("Expected the cancelled state to be saved, got %+v", saved)
+		}
+		if executor.CancelExecution(executionID) {
+			t.Error("Expected the execution to be unregistered once it stopped")
+		}
+	})
+
+	t.Run("finished executions are unregistered", func(t *testing.T) {
+		executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		execution, err := executor.Execute(ctx, orgID, workflow, "order.created", map[string]interface{}{"x": 1})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if execution.Status != models.ExecutionStatusCompleted {
+			t.Errorf("Expected status completed, got %s", execution.Status)
+		}
+		if executor.CancelExecution(execution.ID) {
+			t.Error("Expected no registered run after completion")
+		}
+	})
+
+	t.Run("unknown execution is not cancelled", func(t *testing.T) {
+		executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		if executor.CancelExecution(uuid.New()) {
+			t.Error("Expected false for an execution not running in this process")
+		}
+	})
 }
 
-func containsHelper(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
+func TestDrain(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	orgID := uuid.New()
+
+	t.Run("returns at once with nothing running", func(t *testing.T) {
+		executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if interrupted := executor.Drain(ctx); interrupted != 0 {
+			t.Errorf("Expected no interrupted executions, got %d", interrupted)
+		}
+		if ctx.Err() != nil {
+			t.Error("Expected Drain to return before its deadline")
+		}
+	})
+
+	t.Run("waits for executions that finish in time", func(t *testing.T) {
+		repo := &mockExecutionRepo{
+			interruptExecutionFunc: func(ctx context.Context, organizationID, id uuid.UUID, reason string) (bool, error) {
+				t.Error("Expected no execution to be interrupted")
+				return true, nil
+			},
+		}
+		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		_, untrack := executor.trackExecution(context.Background(), orgID, uuid.New())
+		var finished atomic.Bool
+		time.AfterFunc(50*time.Millisecond, func() {
+			finished.Store(true)
+			untrack()
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if interrupted := executor.Drain(ctx); interrupted != 0 {
+			t.Errorf("Expected no interrupted executions, got %d", interrupted)
+		}
+		if !finished.Load() {
+			t.Error("Expected Drain to wait for the running execution")
+		}
+	})
+
+	t.Run("interrupts executions still running at the deadline", func(t *testing.T) {
+		executionID := uuid.New()
+		var recordedOrg, recordedID uuid.UUID
+		repo := &mockExecutionRepo{
+			interruptExecutionFunc: func(ctx context.Context, organizationID, id uuid.UUID, reason string) (bool, error) {
+				if ctx.Err() != nil {
+					t.Error("Expected the interruption to be saved with a live context")
+				}
+				recordedOrg, recordedID = organizationID, id
+				return true, nil
+			},
+		}
+		executor := NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		runCtx, untrack := executor.trackExecution(context.Background(), orgID, executionID)
+		defer untrack()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if interrupted := executor.Drain(ctx); interrupted != 1 {
+			t.Errorf("Expected one interrupted execution, got %d", interrupted)
+		}
+		if recordedOrg != orgID || recordedID != executionID {
+			t.Errorf("Expected execution %s of %s to be recorded, got %s of %s", executionID, orgID, recordedID, recordedOrg)
+		}
+		if err := interruption(runCtx); !errors.Is(err, ErrExecutionInterrupted) {
+			t.Errorf("Expected the run to stop with ErrExecutionInterrupted, got %v", err)
+		}
+	})
+
+	t.Run("interrupted execution is not recorded as failed", func(t *testing.T) {
+		var executor *WorkflowExecutor
+		var updates []models.ExecutionStatus
+		workflow := &models.Workflow{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			Definition: models.WorkflowDefinition{
+				Trigger: models.TriggerDefinition{Type: "event", Event: "order.created"},
+				Steps: []models.Step{
+					{ID: "check", Type: "condition", Condition: &models.Condition{Field: "x", Operator: "eq", Value: 1}, OnTrue: "approve", OnFalse: "approve"},
+					{ID: "approve", Type: "action", Action: &models.Action{Type: "allow"}},
+				},
+			},
+		}
+		repo := &mockExecutionRepo{
+			createStepExecutionFunc: func(ctx context.Context, step *models.StepExecution) error {
+				// Shut down while the first step is in flight
+				expired, cancel := context.WithCancel(context.Background())
+				cancel()
+				executor.Drain(expired)
+				return nil
+			},
+			updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
+				updates = append(updates, execution.Status)
+				return nil
+			},
+		}
+		executor = NewWorkflowExecutor(redisClient, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		_, err := executor.Execute(context.Background(), orgID, workflow, "order.created", map[string]interface{}{"x": 1})
+		if !errors.Is(err, ErrExecutionInterrupted) {
+			t.Fatalf("Expected ErrExecutionInterrupted, got %v", err)
+		}
+		for _, status := range updates {
+			if status == models.ExecutionStatusFailed || status == models.ExecutionStatusCompleted {
+				t.Errorf("Expected the interrupted state to be left as recorded, got an update to %s", status)
+			}
 		}
+	})
+}
+
+// metricCounts gathers a counter, gauge or histogram from its own registry, returning the
+// counter or gauge value or histogram sample count keyed by the given label values joined with "/"
+func metricCounts(t *testing.T, collector prometheus.Collector, labelNames ...string) map[string]float64 {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
 	}
-	return false
+
+	counts := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			values := make([]string, len(labelNames))
+			for i, name := range labelNames {
+				values[i] = labels[name]
+			}
+			key := strings.Join(values, "/")
+			if histogram := metric.GetHistogram(); histogram != nil {
+				counts[key] = float64(histogram.GetSampleCount())
+			} else if gauge := metric.GetGauge(); gauge != nil {
+				counts[key] = gauge.GetValue()
+			} else {
+				counts[key] = metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return counts
+}
+
+func TestExecuteStep_Metrics(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	ctx := context.Background()
+
+	// Unregistered collectors, so the test doesn't touch the default registry
+	m := &metrics.Metrics{
+		StepDuration:        prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "step_duration"}, []string{"step_type", "status"}),
+		StepExecutionsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "step_executions"}, []string{"step_type", "status"}),
+	}
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, m, getTestContextEnrichmentConfigForExecutor())
+
+	condition := &models.Step{ID: "check", Type: "condition", Condition: &models.Condition{Field: "x", Operator: "eq", Value: 1}, OnTrue: "done"}
+	unsupported := &models.Step{ID: "bad", Type: "unknown"}
+	execContext := map[string]interface{}{"x": 1}
+
+	execution := &models.WorkflowExecution{ID: uuid.New(), Metadata: models.JSONB{}}
+	if _, _, err := executor.executeStep(ctx, execution, condition, execContext); err != nil {
+		t.Fatalf("executeStep failed: %v", err)
+	}
+	if _, _, err := executor.executeStep(ctx, execution, unsupported, execContext); err == nil {
+		t.Fatal("Expected error for unsupported step type")
+	}
+
+	for name, collector := range map[string]prometheus.Collector{"count": m.StepExecutionsTotal, "duration": m.StepDuration} {
+		counts := metricCounts(t, collector, "step_type", "status")
+		if counts["condition/completed"] != 1 || counts["unknown/failed"] != 1 || len(counts) != 2 {
+			t.Errorf("Expected one %s for a completed condition and a failed unknown step, got %v", name, counts)
+		}
+	}
+
+	t.Run("dry runs are not recorded", func(t *testing.T) {
+		dryRun := &models.WorkflowExecution{ID: uuid.New(), Metadata: models.JSONB{"dry_run": true}}
+		if _, _, err := executor.executeStep(ctx, dryRun, condition, execContext); err != nil {
+			t.Fatalf("executeStep failed: %v", err)
+		}
+		if got := metricCounts(t, m.StepExecutionsTotal, "step_type", "status")["condition/completed"]; got != 1 {
+			t.Errorf("Expected dry run to be excluded, got %v", got)
+		}
+	})
+}
+
+func TestExecuteSetStep(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+	execution := &models.WorkflowExecution{ID: uuid.New(), ExecutionID: "test-exec"}
+
+	t.Run("variables see those set before them", func(t *testing.T) {
+		step := &models.Step{
+			ID:   "compute",
+			Type: "set",
+			Set: []models.SetVariable{
+				{Name: "total_with_tax", Expression: "order.total * 1.2"},
+				{Name: "needs_review", Expression: "total_with_tax > 1000.0"},
+				{Name: "summary", Expression: `{"skus": order.items.map(i, i.sku), "review": needs_review}`},
+			},
+			Next: "route",
+		}
+		execContext := map[string]interface{}{
+			"order": map[string]interface{}{
+				"total": float64(1000),
+				"items": []interface{}{map[string]interface{}{"sku": "A1"}, map[string]interface{}{"sku": "B2"}},
+			},
+		}
+
+		next, _, err := executor.executeStep(context.Background(), execution, step, execContext)
+		if err != nil {
+			t.Fatalf("executeStep failed: %v", err)
+		}
+		if next != "route" {
+			t.Errorf("Expected next step route, got %q", next)
+		}
+		if execContext["total_with_tax"] != float64(1200) || execContext["needs_review"] != true {
+			t.Errorf("Expected total_with_tax 1200 and needs_review true, got %v and %v", execContext["total_with_tax"], execContext["needs_review"])
+		}
+		expected := map[string]interface{}{"skus": []interface{}{"A1", "B2"}, "review": true}
+		if !reflect.DeepEqual(execContext["summary"], expected) {
+			t.Errorf("Expected summary %v as plain values, got %#v", expected, execContext["summary"])
+		}
+	})
+
+	t.Run("result variables populate the result payload", func(t *testing.T) {
+		execution := &models.WorkflowExecution{ID: uuid.New(), ExecutionID: "test-exec", ResultPayload: models.JSONB{"decision": "pending"}}
+		step := &models.Step{
+			ID:   "decide",
+			Type: "set",
+			Set: []models.SetVariable{
+				{Name: "score", Expression: "order.total / 10.0"},
+				{Name: "decision", Expression: `score > 5.0 ? "approve" : "review"`, Result: true},
+			},
+		}
+		execContext := map[string]interface{}{"order": map[string]interface{}{"total": float64(80)}}
+
+		if _, err := executor.executeSetStep(context.Background(), execution, step, execContext); err != nil {
+			t.Fatalf("executeSetStep failed: %v", err)
+		}
+		expected := models.JSONB{"decision": "approve"}
+		if !reflect.DeepEqual(execution.ResultPayload, expected) {
+			t.Errorf("Expected result payload %v, got %v", expected, execution.ResultPayload)
+		}
+		if execContext["score"] != float64(8) {
+			t.Errorf("Expected score to still be set in context, got %v", execContext["score"])
+		}
+	})
+
+	t.Run("a failed expression sets nothing", func(t *testing.T) {
+		step := &models.Step{
+			ID:   "compute",
+			Type: "set",
+			Set: []models.SetVariable{
+				{Name: "subtotal", Expression: "order.total"},
+				{Name: "discount", Expression: "customer.discount * subtotal"},
+			},
+		}
+		execContext := map[string]interface{}{"order": map[string]interface{}{"total": float64(50)}}
+
+		if _, err := executor.executeSetStep(context.Background(), execution, step, execContext); err == nil || !strings.Contains(err.Error(), "failed to set discount") {
+			t.Fatalf("Expected discount to fail, got %v", err)
+		}
+		if _, exists := execContext["subtotal"]; exists {
+			t.Error("Expected no variables to be set when one fails")
+		}
+	})
+}
+
+func TestDelayStep(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+
+	workflow := &models.Workflow{
+		ID: uuid.New(),
+		Definition: models.WorkflowDefinition{
+			Trigger: models.TriggerDefinition{Type: "event", Event: "order.created"},
+			Steps: []models.Step{
+				{ID: "cool_off", Type: "delay", Delay: &models.DelayStep{Duration: "10m"}, Next: "follow_up"},
+				{ID: "follow_up", Type: "execute", Execute: []models.ExecuteAction{{Type: "log", Message: "following up"}}},
+			},
+		},
+	}
+
+	var mu sync.Mutex
+	var saved []models.WorkflowExecution
+	var ranSteps []string
+	repo := &mockExecutionRepo{
+		updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
+			mu.Lock()
+			defer mu.Unlock()
+			saved = append(saved, *execution)
+			return nil
+		},
+		createStepExecutionFunc: func(ctx context.Context, step *models.StepExecution) error {
+			mu.Lock()
+			defer mu.Unlock()
+			ranSteps = append(ranSteps, step.StepID)
+			return nil
+		},
+	}
+	workflowRepo := &mockWorkflowRepo{
+		getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error) {
+			return workflow, nil
+		},
+	}
+	executor := NewWorkflowExecutor(redisClient, repo, workflowRepo, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	t.Run("pauses with a persisted resume time", func(t *testing.T) {
+		saved = nil
+		execution, err := executor.Execute(context.Background(), uuid.Nil, workflow, "order.created", map[string]interface{}{"order": map[string]interface{}{"id": "ord_1"}})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if execution.Status != models.ExecutionStatusWaiting {
+			t.Fatalf("Expected the execution to wait, got %s", execution.Status)
+		}
+
+		paused := saved[len(saved)-1]
+		if paused.CurrentStepID == nil || *paused.CurrentStepID != "cool_off" {
+			t.Errorf("Expected the delay step to be saved as the current step, got %v", paused.CurrentStepID)
+		}
+		if paused.WaitState == nil || paused.WaitState.ResumeAt == nil {
+			t.Fatalf("Expected a resume time to be saved, got %+v", paused.WaitState)
+		}
+		if until := time.Until(*paused.WaitState.ResumeAt); until < 9*time.Minute || until > 10*time.Minute {
+			t.Errorf("Expected to resume in 10 minutes, got %s", until)
+		}
+		if paused.WaitState.Accepts("") {
+			t.Error("Expected a delay not to be resumed by an event")
+		}
+	})
+
+	t.Run("continues with the next step once claimed", func(t *testing.T) {
+		ranSteps = nil
+		resumeAt := time.Now().Add(-time.Second)
+		stepID := "cool_off"
+		execution := &models.WorkflowExecution{
+			ID:            uuid.New(),
+			WorkflowID:    workflow.ID,
+			ExecutionID:   "exec_delayed",
+			Status:        models.ExecutionStatusRunning,
+			Context:       models.JSONB{"order": map[string]interface{}{"id": "ord_1"}},
+			CurrentStepID: &stepID,
+			WaitState:     &models.WaitState{ResumeAt: &resumeAt},
+		}
+
+		if err := executor.ResumeDelayedExecution(context.Background(), execution); err != nil {
+			t.Fatalf("ResumeDelayedExecution failed: %v", err)
+		}
+		if execution.Status != models.ExecutionStatusCompleted {
+			t.Errorf("Expected the execution to complete, got %s", execution.Status)
+		}
+		if execution.CurrentStepID != nil || execution.WaitState != nil {
+			t.Error("Expected the delay to be cleared")
+		}
+		if strings.Join(ranSteps, ",") != "follow_up" {
+			t.Errorf("Expected only follow_up to run, got %v", ranSteps)
+		}
+	})
+
+	t.Run("dry runs don't pause", func(t *testing.T) {
+		ranSteps = nil
+		execution, err := executor.ExecuteWithOptions(context.Background(), uuid.Nil, workflow, "order.created", map[string]interface{}{}, ExecutionOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("ExecuteWithOptions failed: %v", err)
+		}
+		if execution.Status != models.ExecutionStatusCompleted {
+			t.Errorf("Expected the dry run to complete, got %s", execution.Status)
+		}
+		if strings.Join(ranSteps, ",") != "cool_off,follow_up" {
+			t.Errorf("Expected both steps to run, got %v", ranSteps)
+		}
+	})
+}
+
+// newEditedWorkflow returns a workflow edited to version 2 and a version store holding version 1,
+// with the steps each version's definition has
+func newEditedWorkflow(steps func(version string) []models.Step) (*models.Workflow, *mockVersionRepo) {
+	workflow := &models.Workflow{
+		ID:                uuid.New(),
+		WorkflowID:        "edited-wf",
+		DefinitionVersion: 2,
+		Definition: models.WorkflowDefinition{
+			Trigger: models.TriggerDefinition{Type: "event", Event: "order.created"},
+			Steps:   steps("v2"),
+		},
+	}
+	versionRepo := &mockVersionRepo{versions: map[int]*models.WorkflowVersion{
+		1: {
+			WorkflowID: workflow.ID,
+			Version:    1,
+			Definition: models.WorkflowDefinition{
+				Trigger: models.TriggerDefinition{Type: "event", Event: "order.created"},
+				Steps:   steps("v1"),
+			},
+		},
+	}}
+	return workflow, versionRepo
+}
+
+func TestResume_RunsStartedWorkflowVersion(t *testing.T) {
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	startedOn := 1
+
+	var ranSteps []string
+	newExecutor := func(workflow *models.Workflow, execution *models.WorkflowExecution) *WorkflowExecutor {
+		ranSteps = nil
+		repo := &mockExecutionRepo{
+			getExecutionByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowExecution, error) {
+				return execution, nil
+			},
+			createStepExecutionFunc: func(ctx context.Context, step *models.StepExecution) error {
+				ranSteps = append(ranSteps, step.StepID)
+				return nil
+			},
+		}
+		workflowRepo := &mockWorkflowRepo{
+			getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error) {
+				return workflow, nil
+			},
+		}
+		return NewWorkflowExecutor(redisClient, repo, workflowRepo, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+	}
+
+	t.Run("event resume", func(t *testing.T) {
+		workflow, versionRepo := newEditedWorkflow(func(version string) []models.Step {
+			return []models.Step{
+				{ID: "wait1", Type: "wait", Wait: &models.WaitConfig{Event: "approval.granted", Timeout: "24h"}},
+				{ID: "follow_up_" + version, Type: "execute", Execute: []models.ExecuteAction{{Type: "log", Message: "following up"}}},
+			}
+		})
+		execution := &models.WorkflowExecution{
+			ID:              uuid.New(),
+			WorkflowID:      workflow.ID,
+			ExecutionID:     "exec_waiting",
+			Status:          models.ExecutionStatusWaiting,
+			CurrentStepID:   stringPtr("wait1"),
+			WaitState:       &models.WaitState{Event: "approval.granted", WaitingSince: time.Now()},
+			WorkflowVersion: &startedOn,
+		}
+		executor := newExecutor(workflow, execution)
+		executor.SetVersionRepository(versionRepo)
+
+		if _, err := executor.ResumeExecution(context.Background(), execution.ID, workflow, "approval.granted", nil); err != nil {
+			t.Fatalf("ResumeExecution failed: %v", err)
+		}
+		if strings.Join(ranSteps, ",") != "follow_up_v1" {
+			t.Errorf("Expected the started version's step to run, got %v", ranSteps)
+		}
+	})
+
+	t.Run("delay resume", func(t *testing.T) {
+		workflow, versionRepo := newEditedWorkflow(func(version string) []models.Step {
+			return []models.Step{
+				{ID: "cool_off", Type: "delay", Delay: &models.DelayStep{Duration: "10m"}, Next: "follow_up_" + version},
+				{ID: "follow_up_" + version, Type: "execute", Execute: []models.ExecuteAction{{Type: "log", Message: "following up"}}},
+			}
+		})
+		resumeAt := time.Now().Add(-time.Second)
+		execution := &models.WorkflowExecution{
+			ID:              uuid.New(),
+			WorkflowID:      workflow.ID,
+			ExecutionID:     "exec_delayed",
+			Status:          models.ExecutionStatusRunning,
+			CurrentStepID:   stringPtr("cool_off"),
+			WaitState:       &models.WaitState{ResumeAt: &resumeAt},
+			WorkflowVersion: &startedOn,
+		}
+		executor := newExecutor(workflow, execution)
+		executor.SetVersionRepository(versionRepo)
+
+		if err := executor.ResumeDelayedExecution(context.Background(), execution); err != nil {
+			t.Fatalf("ResumeDelayedExecution failed: %v", err)
+		}
+		if strings.Join(ranSteps, ",") != "follow_up_v1" {
+			t.Errorf("Expected the started version's step to run, got %v", ranSteps)
+		}
+	})
+
+	t.Run("paused resume", func(t *testing.T) {
+		workflow, versionRepo := newEditedWorkflow(func(version string) []models.Step {
+			return []models.Step{
+				{ID: "review_" + version, Type: "execute", Execute: []models.ExecuteAction{{Type: "log", Message: "reviewing"}}},
+			}
+		})
+		execution := &models.WorkflowExecution{
+			ID:              uuid.New(),
+			WorkflowID:      workflow.ID,
+			ExecutionID:     "exec_paused",
+			Status:          models.ExecutionStatusRunning,
+			WorkflowVersion: &startedOn,
+		}
+		executor := newExecutor(workflow, execution)
+		executor.SetVersionRepository(versionRepo)
+
+		if err := executor.ResumePausedExecution(context.Background(), execution); err != nil {
+			t.Fatalf("ResumePausedExecution failed: %v", err)
+		}
+		if strings.Join(ranSteps, ",") != "review_v1" {
+			t.Errorf("Expected the started version's step to run, got %v", ranSteps)
+		}
+	})
+
+	t.Run("fails delays whose version is unavailable", func(t *testing.T) {
+		workflow, _ := newEditedWorkflow(func(version string) []models.Step {
+			return []models.Step{
+				{ID: "cool_off", Type: "delay", Delay: &models.DelayStep{Duration: "10m"}, Next: "follow_up_" + version},
+				{ID: "follow_up_" + version, Type: "execute", Execute: []models.ExecuteAction{{Type: "log", Message: "following up"}}},
+			}
+		})
+		resumeAt := time.Now().Add(-time.Second)
+		execution := &models.WorkflowExecution{
+			ID:              uuid.New(),
+			WorkflowID:      workflow.ID,
+			ExecutionID:     "exec_delayed",
+			Status:          models.ExecutionStatusRunning,
+			CurrentStepID:   stringPtr("cool_off"),
+			WaitState:       &models.WaitState{ResumeAt: &resumeAt},
+			WorkflowVersion: &startedOn,
+		}
+		executor := newExecutor(workflow, execution)
+		executor.SetVersionRepository(&mockVersionRepo{})
+
+		err := executor.ResumeDelayedExecution(context.Background(), execution)
+		if !errors.Is(err, ErrWorkflowVersionNotFound) {
+			t.Fatalf("Expected ErrWorkflowVersionNotFound, got %v", err)
+		}
+		if len(ranSteps) != 0 {
+			t.Errorf("Expected no steps of the current version to run, got %v", ranSteps)
+		}
+		if execution.Status != models.ExecutionStatusFailed {
+			t.Errorf("Expected the execution to fail, got %s", execution.Status)
+		}
+	})
 }
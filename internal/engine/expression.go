@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// expressionCache holds the CEL environment, created lazily, and compiled programs keyed by expression text
+type expressionCache struct {
+	envOnce  sync.Once
+	env      *cel.Env
+	envErr   error
+	programs sync.Map // map[string]cel.Program
+}
+
+// evaluateExpression evaluates a CEL expression against the context and returns its boolean result.
+// Top-level context keys are available as identifiers, e.g. `order.total > 1000 && customer.tier == "gold"`
+func (e *Evaluator) evaluateExpression(expression string, context map[string]interface{}) (bool, error) {
+	value, err := e.evaluateValue(expression, context)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q must evaluate to a boolean, got %T", expression, value)
+	}
+
+	return result, nil
+}
+
+// evaluateValue evaluates a CEL expression against the context and returns its result, whatever its type.
+// Lists and maps built by the expression are returned as []interface{} and map[string]interface{}
+func (e *Evaluator) evaluateValue(expression string, context map[string]interface{}) (interface{}, error) {
+	program, err := e.compileExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	out, _, err := program.Eval(context)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression %q: %w", expression, err)
+	}
+
+	return nativeValue(out), nil
+}
+
+// nativeValue converts a CEL value to the plain Go value it represents, so it can be stored in context
+func nativeValue(val ref.Val) interface{} {
+	switch v := val.(type) {
+	case types.Null:
+		return nil
+	case traits.Mapper:
+		result := make(map[string]interface{})
+		for it := v.Iterator(); it.HasNext() == types.True; {
+			key := it.Next()
+			result[fmt.Sprint(nativeValue(key))] = nativeValue(v.Get(key))
+		}
+		return result
+	case traits.Lister:
+		result := make([]interface{}, 0)
+		for it := v.Iterator(); it.HasNext() == types.True; {
+			result = append(result, nativeValue(it.Next()))
+		}
+		return result
+	default:
+		return val.Value()
+	}
+}
+
+// compileExpression returns the program for an expression, compiling it on first use.
+// Expressions are parsed without type checking so identifiers resolve against whatever the context holds at evaluation time
+func (e *Evaluator) compileExpression(expression string) (cel.Program, error) {
+	if cached, ok := e.expressions.programs.Load(expression); ok {
+		return cached.(cel.Program), nil
+	}
+
+	env, err := e.celEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Parse(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expression, issues.Err())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expression, err)
+	}
+
+	actual, _ := e.expressions.programs.LoadOrStore(expression, program)
+	return actual.(cel.Program), nil
+}
+
+// celEnv returns the shared CEL environment, creating it on first use
+func (e *Evaluator) celEnv() (*cel.Env, error) {
+	cache := e.expressions
+	cache.envOnce.Do(func() {
+		cache.env, cache.envErr = cel.NewEnv()
+	})
+	if cache.envErr != nil {
+		return nil, fmt.Errorf("failed to create expression environment: %w", cache.envErr)
+	}
+	return cache.env, nil
+}
@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrFieldNotFound is returned (wrapped) when a path does not exist in the context,
+// as opposed to existing with a null value
+var ErrFieldNotFound = errors.New("field not found")
+
+// indexOutOfRangeError reports an index past the end of a list; the element is absent, so it matches ErrFieldNotFound
+type indexOutOfRangeError struct {
+	index  int
+	length int
+}
+
+func (e indexOutOfRangeError) Error() string {
+	return fmt.Sprintf("index %d out of range (length %d)", e.index, e.length)
+}
+
+func (e indexOutOfRangeError) Is(target error) bool {
+	return target == ErrFieldNotFound
+}
+
+// pathSegment is a single step in a variable path: either a map key or an array index
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// String renders the segment as it appears in a path
+func (s pathSegment) String() string {
+	if s.isIndex {
+		return fmt.Sprintf("[%d]", s.index)
+	}
+	return s.key
+}
+
+// parsePath splits a variable path into segments
+// Example: "order.fulfillments[0].items" -> order, fulfillments, [0], items
+func parsePath(path string) ([]pathSegment, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	var segments []pathSegment
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			return nil, fmt.Errorf("invalid path %q: empty segment", path)
+		}
+
+		// Leading key before any index, e.g. "fulfillments" in "fulfillments[0]"
+		key := part
+		if bracket := strings.Index(part, "["); bracket >= 0 {
+			key = part[:bracket]
+			part = part[bracket:]
+		} else {
+			part = ""
+		}
+		if key != "" {
+			segments = append(segments, pathSegment{key: key})
+		}
+
+		// Any number of trailing indexes, e.g. "[0][1]"
+		for part != "" {
+			end := strings.Index(part, "]")
+			if part[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("invalid path %q: malformed index in %q", path, part)
+			}
+			index, err := strconv.Atoi(strings.TrimSpace(part[1:end]))
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid path %q: index %q must be a non-negative integer", path, part[1:end])
+			}
+			segments = append(segments, pathSegment{index: index, isIndex: true})
+			part = part[end+1:]
+		}
+	}
+
+	return segments, nil
+}
+
+// PathResolver memoizes parsed variable paths by path string. Only the segments are cached:
+// the context changes as steps run, so values are always resolved against the current context.
+// It is safe for concurrent use by parallel branches.
+type PathResolver struct {
+	segments sync.Map // map[string][]pathSegment
+}
+
+// NewPathResolver creates an empty path cache
+func NewPathResolver() *PathResolver {
+	return &PathResolver{}
+}
+
+// Resolve resolves path against the context, parsing it at most once per resolver.
+// A nil resolver parses the path on every call
+func (r *PathResolver) Resolve(path string, context map[string]interface{}) (interface{}, error) {
+	segments, err := r.parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolvePath(path, segments, context)
+}
+
+// parse returns the cached segments for path, parsing and caching them on first use.
+// Invalid paths are not cached
+func (r *PathResolver) parse(path string) ([]pathSegment, error) {
+	if r == nil {
+		return parsePath(path)
+	}
+
+	if cached, ok := r.segments.Load(path); ok {
+		return cached.([]pathSegment), nil
+	}
+
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r.segments.Store(path, segments)
+	return segments, nil
+}
+
+// resolvePath walks the parsed segments through nested maps and slices.
+// Errors name the path prefix at which resolution failed.
+func resolvePath(path string, segments []pathSegment, context map[string]interface{}) (interface{}, error) {
+	var current interface{} = context
+
+	for i, seg := range segments {
+		// Nothing can be nested under a null value, so the rest of the path is absent
+		if current == nil {
+			return nil, fmt.Errorf("%w: %s: segment %s does not exist (parent is null)", ErrFieldNotFound, path, joinSegments(segments[:i+1]))
+		}
+
+		if seg.isIndex {
+			value, err := indexValue(current, seg.index)
+			if err != nil {
+				return nil, fmt.Errorf("cannot resolve %s: segment %s: %w", path, joinSegments(segments[:i+1]), err)
+			}
+			current = value
+			continue
+		}
+
+		value, found, isMap := mapValue(current, seg.key)
+		if !isMap {
+			return nil, fmt.Errorf("invalid path: %s: segment %s: parent is not an object (got %T)", path, joinSegments(segments[:i+1]), current)
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s: segment %s does not exist", ErrFieldNotFound, path, joinSegments(segments[:i+1]))
+		}
+		current = value
+	}
+
+	return current, nil
+}
+
+// joinSegments renders segments back into path form, e.g. "order.fulfillments[0]".
+// Only error paths need it, so resolution doesn't build the prefix as it walks
+func joinSegments(segments []pathSegment) string {
+	var b strings.Builder
+	for i, seg := range segments {
+		if !seg.isIndex && i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(seg.String())
+	}
+	return b.String()
+}
+
+// mapValue looks up key in a string-keyed map of any concrete type
+func mapValue(container interface{}, key string) (value interface{}, found bool, isMap bool) {
+	if m, ok := container.(map[string]interface{}); ok {
+		value, found = m[key]
+		return value, found, true
+	}
+
+	rv := reflect.ValueOf(container)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false, false
+	}
+
+	v := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+	if !v.IsValid() {
+		return nil, false, true
+	}
+	return v.Interface(), true, true
+}
+
+// indexValue returns the element at index from any slice or array
+func indexValue(container interface{}, index int) (interface{}, error) {
+	if list, ok := container.([]interface{}); ok {
+		if index >= len(list) {
+			return nil, indexOutOfRangeError{index: index, length: len(list)}
+		}
+		return list[index], nil
+	}
+
+	rv := reflect.ValueOf(container)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot index into %T", container)
+	}
+	if index >= rv.Len() {
+		return nil, indexOutOfRangeError{index: index, length: rv.Len()}
+	}
+	return rv.Index(index).Interface(), nil
+}
+
+// toSlice converts any slice or array value into []interface{}
+func toSlice(value interface{}) ([]interface{}, bool) {
+	if list, ok := value.([]interface{}); ok {
+		return list, true
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	result := make([]interface{}, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		result[i] = rv.Index(i).Interface()
+	}
+	return result, true
+}
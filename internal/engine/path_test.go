@@ -0,0 +1,190 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		expected  string
+		shouldErr bool
+	}{
+		{name: "single key", path: "status", expected: "status"},
+		{name: "nested keys", path: "order.customer.id", expected: "order|customer|id"},
+		{name: "indexed key", path: "order.fulfillments[0].items", expected: "order|fulfillments|[0]|items"},
+		{name: "multiple indexes", path: "matrix[1][2]", expected: "matrix|[1]|[2]"},
+		{name: "leading index", path: "[0].id", expected: "[0]|id"},
+		{name: "empty path", path: "", shouldErr: true},
+		{name: "empty segment", path: "order..id", shouldErr: true},
+		{name: "unterminated index", path: "items[0", shouldErr: true},
+		{name: "non-numeric index", path: "items[first]", shouldErr: true},
+		{name: "negative index", path: "items[-1]", shouldErr: true},
+		{name: "trailing garbage", path: "items[0]x", shouldErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, err := parsePath(tt.path)
+
+			if tt.shouldErr {
+				if err == nil {
+					t.Errorf("Expected error for path %q", tt.path)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			parts := make([]string, len(segments))
+			for i, seg := range segments {
+				parts[i] = seg.String()
+			}
+			if got := strings.Join(parts, "|"); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	context := map[string]interface{}{
+		"order": map[string]interface{}{
+			"fulfillments": []interface{}{
+				map[string]interface{}{
+					"items": []interface{}{"a", "b"},
+				},
+			},
+			"tags":   []string{"vip", "rush"},
+			"totals": map[string]float64{"net": 90},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		expected    interface{}
+		expectError string
+		notFound    bool
+	}{
+		{name: "indexed nested value", path: "order.fulfillments[0].items[1]", expected: "b"},
+		{name: "typed slice", path: "order.tags[0]", expected: "vip"},
+		{name: "typed map", path: "order.totals.net", expected: 90.0},
+		{name: "missing key names segment", path: "order.shipments[0]", expectError: "segment order.shipments does not exist", notFound: true},
+		{name: "index out of range names segment", path: "order.fulfillments[3].items", expectError: "segment order.fulfillments[3]: index 3 out of range", notFound: true},
+		{name: "indexing a map names segment", path: "order[0]", expectError: "segment order[0]: cannot index"},
+		{name: "key on a list names segment", path: "order.tags.first", expectError: "segment order.tags.first: parent is not an object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, err := parsePath(tt.path)
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %v", err)
+			}
+
+			value, err := resolvePath(tt.path, segments, context)
+
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("Expected error containing %q, got %v", tt.expectError, err)
+				}
+				if err != nil && errors.Is(err, ErrFieldNotFound) != tt.notFound {
+					t.Errorf("Expected errors.Is(err, ErrFieldNotFound)=%v, got %v", tt.notFound, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if value != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, value)
+			}
+		})
+	}
+}
+
+func TestPathResolver(t *testing.T) {
+	t.Run("caches segments but resolves against the current context", func(t *testing.T) {
+		resolver := NewPathResolver()
+		context := map[string]interface{}{"order": map[string]interface{}{"status": "pending"}}
+
+		value, err := resolver.Resolve("order.status", context)
+		if err != nil || value != "pending" {
+			t.Fatalf("Expected pending, got %v (err: %v)", value, err)
+		}
+		if _, ok := resolver.segments.Load("order.status"); !ok {
+			t.Error("Expected parsed segments to be cached")
+		}
+
+		context["order"] = map[string]interface{}{"status": "shipped"}
+		value, err = resolver.Resolve("order.status", context)
+		if err != nil || value != "shipped" {
+			t.Errorf("Expected shipped after the context changed, got %v (err: %v)", value, err)
+		}
+	})
+
+	t.Run("does not cache invalid paths", func(t *testing.T) {
+		resolver := NewPathResolver()
+		if _, err := resolver.Resolve("items[", map[string]interface{}{}); err == nil {
+			t.Error("Expected parse error")
+		}
+		if _, ok := resolver.segments.Load("items["); ok {
+			t.Error("Expected invalid path not to be cached")
+		}
+	})
+
+	t.Run("nil resolver parses every call", func(t *testing.T) {
+		var resolver *PathResolver
+		value, err := resolver.Resolve("a[0]", map[string]interface{}{"a": []interface{}{"x"}})
+		if err != nil || value != "x" {
+			t.Errorf("Expected x, got %v (err: %v)", value, err)
+		}
+	})
+}
+
+// BenchmarkEvaluateCondition_PathResolver compares parsing every variable reference
+// on each evaluation with parsing once per execution, for a workflow whose conditions
+// reference many fields and are evaluated repeatedly (e.g. once per foreach item)
+func BenchmarkEvaluateCondition_PathResolver(b *testing.B) {
+	const fields = 50
+
+	lines := make([]interface{}, fields)
+	clauses := make([]models.Condition, fields)
+	for i := 0; i < fields; i++ {
+		lines[i] = map[string]interface{}{
+			"sku":      fmt.Sprintf("sku-%d", i),
+			"quantity": float64(i + 1),
+			"pricing":  map[string]interface{}{"unit": 9.99, "currency": "USD"},
+		}
+		clauses[i] = models.Condition{Field: fmt.Sprintf("order.lines[%d].pricing.currency", i), Operator: "eq", Value: "USD"}
+	}
+	context := map[string]interface{}{"order": map[string]interface{}{"lines": lines}}
+	condition := &models.Condition{And: clauses}
+
+	run := func(b *testing.B, evaluator *Evaluator) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if ok, err := evaluator.EvaluateCondition(condition, context); err != nil || !ok {
+				b.Fatalf("Unexpected result %v (err: %v)", ok, err)
+			}
+		}
+	}
+
+	b.Run("parse per reference", func(b *testing.B) {
+		run(b, NewEvaluator())
+	})
+
+	b.Run("per-execution cache", func(b *testing.B) {
+		run(b, NewEvaluator().WithPathResolver(NewPathResolver()))
+	})
+}
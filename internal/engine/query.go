@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/database"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+)
+
+// QueryResult holds the rows a query step read
+type QueryResult struct {
+	Columns   []string
+	Rows      []interface{} // One map[string]interface{} per row, keyed by column
+	Truncated bool          // More rows matched than were read
+}
+
+// QueryRunner runs query steps' SQL against configured datasources. Queries must pass
+// database.CheckReadOnlyQuery and run in a read-only transaction that is always rolled back,
+// with at most the configured rows read and within the configured timeout
+type QueryRunner struct {
+	datasources map[string]*sql.DB
+	maxRows     int
+	timeout     time.Duration
+	logger      *logger.Logger
+}
+
+// NewQueryRunner creates a query runner over datasources, keyed by the name steps refer to
+func NewQueryRunner(datasources map[string]*sql.DB, cfg *config.QueryConfig, log *logger.Logger) *QueryRunner {
+	return &QueryRunner{
+		datasources: datasources,
+		maxRows:     cfg.MaxRows,
+		timeout:     cfg.Timeout,
+		logger:      log,
+	}
+}
+
+// Run runs query against datasource with params bound to its placeholders. maxRows and timeout
+// default to the configured limits, and can only lower them
+func (r *QueryRunner) Run(
+	ctx context.Context,
+	datasource string,
+	query string,
+	params []interface{},
+	maxRows int,
+	timeout time.Duration,
+) (*QueryResult, error) {
+	if err := database.CheckReadOnlyQuery(query, len(params)); err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+
+	db, ok := r.datasources[datasource]
+	if !ok {
+		return nil, fmt.Errorf("unknown datasource: %s", datasource)
+	}
+
+	if maxRows <= 0 || maxRows > r.maxRows {
+		maxRows = r.maxRows
+	}
+	if timeout <= 0 || timeout > r.timeout {
+		timeout = r.timeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin read-only transaction: %w", err)
+	}
+	// Nothing a query step does is ever committed
+	defer tx.Rollback()
+
+	// Let the server stop the query too, in case the cancellation doesn't reach it
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+		return nil, fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+
+	args := make([]interface{}, len(params))
+	for i, param := range params {
+		args[i], err = queryParam(param)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter $%d: %w", i+1, err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query columns: %w", err)
+	}
+
+	result := &QueryResult{Columns: make([]string, len(columnTypes)), Rows: []interface{}{}}
+	for i, column := range columnTypes {
+		result.Columns[i] = column.Name()
+	}
+
+	values := make([]interface{}, len(columnTypes))
+	dest := make([]interface{}, len(columnTypes))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if len(result.Rows) == maxRows {
+			// Cancel the query rather than let closing the rows read every remaining one
+			result.Truncated = true
+			cancel()
+			r.logger.Debugf("Query against %s stopped after %d rows", datasource, maxRows)
+			break
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to read query row: %w", err)
+		}
+
+		row := make(map[string]interface{}, len(columnTypes))
+		for i, column := range columnTypes {
+			row[column.Name()] = queryValue(values[i], column.DatabaseTypeName())
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	// The cancellation of a truncated query may be reported as an error
+	if err := rows.Err(); err != nil && !result.Truncated {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// queryParam converts a rendered parameter to a value the driver can bind. Objects and lists
+// are bound as JSON
+func queryParam(value interface{}) (interface{}, error) {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	default:
+		return value, nil
+	}
+}
+
+// queryValue converts a scanned column value to one that can be stored in context
+func queryValue(value interface{}, databaseType string) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		switch databaseType {
+		case "JSON", "JSONB":
+			var decoded interface{}
+			if err := json.Unmarshal(v, &decoded); err == nil {
+				return decoded
+			}
+		case "NUMERIC":
+			if f, err := strconv.ParseFloat(string(v), 64); err == nil {
+				return f
+			}
+		}
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
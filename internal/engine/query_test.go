@@ -0,0 +1,253 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeQueryConn is a database connection answering every query with the same rows, recording
+// how it was used
+type fakeQueryConn struct {
+	columns []string
+	types   []string
+	rows    [][]driver.Value
+
+	mu         sync.Mutex
+	readOnly   bool
+	execs      []string
+	query      string
+	args       []interface{}
+	committed  bool
+	rolledBack bool
+}
+
+func (c *fakeQueryConn) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c *fakeQueryConn) Driver() driver.Driver                        { return nil }
+func (c *fakeQueryConn) Prepare(string) (driver.Stmt, error)          { return nil, errors.New("not supported") }
+func (c *fakeQueryConn) Close() error                                 { return nil }
+func (c *fakeQueryConn) Begin() (driver.Tx, error)                    { return nil, errors.New("not supported") }
+
+func (c *fakeQueryConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readOnly = opts.ReadOnly
+	return fakeQueryTx{c}, nil
+}
+
+func (c *fakeQueryConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.execs = append(c.execs, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeQueryConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.query = query
+	for _, arg := range args {
+		c.args = append(c.args, arg.Value)
+	}
+	return &fakeQueryRows{conn: c}, nil
+}
+
+type fakeQueryTx struct{ conn *fakeQueryConn }
+
+func (tx fakeQueryTx) Commit() error {
+	tx.conn.mu.Lock()
+	defer tx.conn.mu.Unlock()
+	tx.conn.committed = true
+	return nil
+}
+
+func (tx fakeQueryTx) Rollback() error {
+	tx.conn.mu.Lock()
+	defer tx.conn.mu.Unlock()
+	tx.conn.rolledBack = true
+	return nil
+}
+
+type fakeQueryRows struct {
+	conn *fakeQueryConn
+	next int
+}
+
+func (r *fakeQueryRows) Columns() []string { return r.conn.columns }
+func (r *fakeQueryRows) Close() error      { return nil }
+
+func (r *fakeQueryRows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.conn.types[index]
+}
+
+func (r *fakeQueryRows) Next(dest []driver.Value) error {
+	if r.next == len(r.conn.rows) {
+		return io.EOF
+	}
+	copy(dest, r.conn.rows[r.next])
+	r.next++
+	return nil
+}
+
+func newQueryTestExecutor(t *testing.T, conn *fakeQueryConn) *WorkflowExecutor {
+	t.Helper()
+	log := logger.NewForTesting()
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	db := sql.OpenDB(conn)
+	t.Cleanup(func() { db.Close() })
+	executor.SetQueryRunner(NewQueryRunner(
+		map[string]*sql.DB{"analytics": db},
+		&config.QueryConfig{MaxRows: 100, Timeout: 2 * time.Second},
+		log,
+	))
+	return executor
+}
+
+func TestExecuteQueryStep(t *testing.T) {
+	placedAt := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	conn := &fakeQueryConn{
+		columns: []string{"id", "total", "placed_at", "attributes", "items"},
+		types:   []string{"TEXT", "NUMERIC", "TIMESTAMPTZ", "JSONB", "INT8"},
+		rows: [][]driver.Value{
+			{[]byte("ord_1"), []byte("120.50"), placedAt, []byte(`{"gift":true}`), int64(3)},
+			{[]byte("ord_2"), []byte("80"), placedAt, nil, int64(1)},
+		},
+	}
+	executor := newQueryTestExecutor(t, conn)
+
+	step := &models.Step{
+		ID:   "recent_orders",
+		Type: "query",
+		Query: &models.QueryStep{
+			Datasource: "analytics",
+			SQL:        "SELECT id, total, placed_at, attributes, items FROM orders WHERE customer_id = $1 AND total > $2",
+			Params:     []string{"{{customer.id}}", "{{threshold}}"},
+			ResultVar:  "orders",
+			Timeout:    "10s",
+		},
+		Next: "check_orders",
+	}
+	execContext := map[string]interface{}{
+		"customer":  map[string]interface{}{"id": "cus_1"},
+		"threshold": float64(50),
+	}
+
+	next, output, err := executor.executeQueryStep(context.Background(), step, execContext)
+	if err != nil {
+		t.Fatalf("executeQueryStep failed: %v", err)
+	}
+	if next != "check_orders" {
+		t.Errorf("Expected next step check_orders, got %q", next)
+	}
+
+	expected := []interface{}{
+		map[string]interface{}{
+			"id":         "ord_1",
+			"total":      120.5,
+			"placed_at":  "2026-03-01T12:30:00Z",
+			"attributes": map[string]interface{}{"gift": true},
+			"items":      int64(3),
+		},
+		map[string]interface{}{
+			"id":         "ord_2",
+			"total":      float64(80),
+			"placed_at":  "2026-03-01T12:30:00Z",
+			"attributes": nil,
+			"items":      int64(1),
+		},
+	}
+	if !reflect.DeepEqual(execContext["orders"], expected) {
+		t.Errorf("Expected rows %v in context, got %v", expected, execContext["orders"])
+	}
+	if output["row_count"] != 2 || output["truncated"] != false {
+		t.Errorf("Expected 2 untruncated rows in the output, got %v", output)
+	}
+
+	if !reflect.DeepEqual(conn.args, []interface{}{"cus_1", float64(50)}) {
+		t.Errorf("Expected parameters to be bound with their types, got %v", conn.args)
+	}
+	if !conn.readOnly || conn.committed || !conn.rolledBack {
+		t.Errorf("Expected a read-only transaction that is rolled back, got read-only %v, committed %v, rolled back %v",
+			conn.readOnly, conn.committed, conn.rolledBack)
+	}
+	if len(conn.execs) != 1 || conn.execs[0] != "SET LOCAL statement_timeout = 2000" {
+		t.Errorf("Expected the statement timeout to be capped by the configured timeout, got %v", conn.execs)
+	}
+}
+
+func TestExecuteQueryStep_Limits(t *testing.T) {
+	t.Run("rows beyond max_rows are not read", func(t *testing.T) {
+		conn := &fakeQueryConn{
+			columns: []string{"id"},
+			types:   []string{"INT8"},
+			rows:    [][]driver.Value{{int64(1)}, {int64(2)}, {int64(3)}},
+		}
+		executor := newQueryTestExecutor(t, conn)
+
+		step := &models.Step{ID: "ids", Type: "query", Query: &models.QueryStep{Datasource: "analytics", SQL: "SELECT id FROM orders", MaxRows: 2}}
+		execContext := map[string]interface{}{}
+
+		_, output, err := executor.executeQueryStep(context.Background(), step, execContext)
+		if err != nil {
+			t.Fatalf("executeQueryStep failed: %v", err)
+		}
+		if rows, _ := execContext["ids"].([]interface{}); len(rows) != 2 {
+			t.Errorf("Expected 2 rows under the step ID, got %v", execContext["ids"])
+		}
+		if output["truncated"] != true {
+			t.Errorf("Expected the output to report truncation, got %v", output)
+		}
+	})
+
+	t.Run("statements other than a single SELECT are never run", func(t *testing.T) {
+		for _, query := range []string{"DELETE FROM orders", "SELECT 1; DROP TABLE orders"} {
+			conn := &fakeQueryConn{}
+			executor := newQueryTestExecutor(t, conn)
+
+			step := &models.Step{ID: "bad", Type: "query", Query: &models.QueryStep{Datasource: "analytics", SQL: query}}
+			if _, _, err := executor.executeQueryStep(context.Background(), step, map[string]interface{}{}); err == nil {
+				t.Errorf("Expected %q to be rejected", query)
+			}
+			if conn.query != "" {
+				t.Errorf("Expected %q not to reach the datasource", query)
+			}
+		}
+	})
+
+	t.Run("unknown datasource", func(t *testing.T) {
+		executor := newQueryTestExecutor(t, &fakeQueryConn{})
+
+		step := &models.Step{ID: "lookup", Type: "query", Query: &models.QueryStep{Datasource: "billing", SQL: "SELECT 1"}}
+		_, _, err := executor.executeQueryStep(context.Background(), step, map[string]interface{}{})
+		if err == nil || !strings.Contains(err.Error(), "unknown datasource: billing") {
+			t.Errorf("Expected an unknown datasource error, got %v", err)
+		}
+	})
+
+	t.Run("without configured datasources", func(t *testing.T) {
+		log := logger.NewForTesting()
+		redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+		executor := NewWorkflowExecutor(redisClient, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+		step := &models.Step{ID: "lookup", Type: "query", Query: &models.QueryStep{Datasource: "analytics", SQL: "SELECT 1"}}
+		execution := &models.WorkflowExecution{ID: uuid.New(), ExecutionID: "test-exec"}
+		if _, _, err := executor.executeStep(context.Background(), execution, step, map[string]interface{}{}); err == nil {
+			t.Error("Expected query steps to fail without a query runner")
+		}
+	})
+}
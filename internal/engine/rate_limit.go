@@ -0,0 +1,379 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/metrics"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrRateLimited is returned when an execution gets no token from its workflow's or
+// organization's rate limit, at once under the drop policy or before the queue timeout under queue
+var ErrRateLimited = errors.New("execution rate limit exceeded")
+
+const (
+	// rateLimitKeyPrefix namespaces the token buckets of execution rate limits
+	rateLimitKeyPrefix = "execution_rate:"
+
+	// defaultRateLimitInterval is the interval a workflow's rate applies to unless it sets per
+	defaultRateLimitInterval = time.Minute
+
+	// DefaultRateLimitPollInterval is how often a queued execution ranked behind others checks
+	// whether it is next unless configured
+	DefaultRateLimitPollInterval = 250 * time.Millisecond
+
+	// rateLimitQueueGrace is how long a queued execution keeps its place past its next check, so
+	// the place of a crashed process is given up soon after
+	rateLimitQueueGrace = 5 * time.Second
+)
+
+// takeTokenScript refills the token buckets in the first third of KEYS from the time they were
+// last checked and takes a token from each, only if every bucket has one and no execution waiting
+// for them is ranked ahead of the caller. ARGV holds the capacity, rate and interval in
+// milliseconds of each bucket in turn, then the caller's ticket, priority, enqueue time (0 when
+// first checked), the milliseconds it may still wait, the poll interval and the queue grace.
+//
+// Waiting executions are ranked in a sorted set per bucket, the second third of KEYS, scored by
+// negated priority with members "<enqueue time>:<ticket>", so the highest priority comes first
+// and ties go to the longest waiting. The last third of KEYS holds when each waiting execution
+// gives up its place unless it checks again. It returns whether the tokens were taken, the
+// milliseconds until the caller should check again, its enqueue time and the whole tokens left in
+// each bucket. Times are Redis time, so the clocks of API instances do not matter
+var takeTokenScript = redis.NewScript(`
+local n = #KEYS / 3
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local ticket = ARGV[n * 3 + 1]
+local score = -tonumber(ARGV[n * 3 + 2])
+local enqueuedAt = tonumber(ARGV[n * 3 + 3])
+local remaining = tonumber(ARGV[n * 3 + 4])
+local poll = tonumber(ARGV[n * 3 + 5])
+local grace = tonumber(ARGV[n * 3 + 6])
+if enqueuedAt == 0 then
+	enqueuedAt = now
+end
+local member = string.format("%013d:%s", enqueuedAt, ticket)
+
+local ahead = false
+for i = 1, n do
+	local queue, deadlines = KEYS[n + i], KEYS[n * 2 + i]
+	for _, expired in ipairs(redis.call("ZRANGEBYSCORE", deadlines, "-inf", now)) do
+		redis.call("ZREM", queue, expired)
+		redis.call("ZREM", deadlines, expired)
+	end
+	redis.call("ZADD", queue, score, member)
+	if redis.call("ZRANK", queue, member) > 0 then
+		ahead = true
+	end
+end
+
+local tokens = {}
+local wait = 0
+for i = 1, n do
+	local capacity = tonumber(ARGV[i * 3 - 2])
+	local rate = tonumber(ARGV[i * 3 - 1])
+	local interval = tonumber(ARGV[i * 3])
+	local bucket = redis.call("HMGET", KEYS[i], "tokens", "checked_at")
+	local available = tonumber(bucket[1]) or capacity
+	local checkedAt = tonumber(bucket[2]) or now
+	available = math.min(capacity, available + math.max(0, now - checkedAt) * rate / interval)
+	if available < 1 then
+		wait = math.max(wait, math.ceil((1 - available) * interval / rate))
+	end
+	tokens[i] = available
+end
+if ahead then
+	wait = math.max(wait, poll)
+end
+local taken = 0
+if wait == 0 then
+	taken = 1
+end
+
+local result = {taken, wait, enqueuedAt}
+for i = 1, n do
+	local capacity = tonumber(ARGV[i * 3 - 2])
+	local rate = tonumber(ARGV[i * 3 - 1])
+	local interval = tonumber(ARGV[i * 3])
+	local available = tokens[i] - taken
+	redis.call("HSET", KEYS[i], "tokens", tostring(available), "checked_at", now)
+	redis.call("PEXPIRE", KEYS[i], math.ceil(capacity * interval / rate))
+	result[#result + 1] = math.floor(available)
+
+	local queue, deadlines = KEYS[n + i], KEYS[n * 2 + i]
+	if taken == 0 and wait <= remaining then
+		redis.call("ZADD", deadlines, now + wait + grace, member)
+		for _, key in ipairs({queue, deadlines}) do
+			if redis.call("PTTL", key) < wait + grace then
+				redis.call("PEXPIRE", key, wait + grace)
+			end
+		end
+	else
+		redis.call("ZREM", queue, member)
+		redis.call("ZREM", deadlines, member)
+	end
+end
+return result
+`)
+
+// leaveQueueScript removes the member ARGV[1] from the rate limit queues and deadline sets in KEYS
+var leaveQueueScript = redis.NewScript(`
+for _, key in ipairs(KEYS) do
+	redis.call("ZREM", key, ARGV[1])
+end
+return 0
+`)
+
+// tokenBucket is a rate limit: Rate tokens per Interval, up to Capacity at once
+type tokenBucket struct {
+	key          string
+	workflowID   string // metric label, empty for the organization's bucket
+	capacity     int
+	rate         int
+	interval     time.Duration
+	policy       models.RateLimitPolicy
+	queueTimeout time.Duration
+}
+
+// RateLimiter caps how many executions start per interval, per workflow with a rate_limit and
+// optionally per organization, so workflows do not overwhelm the downstream systems they call.
+// The limits are token buckets in Redis, shared by every API instance. Executions waiting for a
+// token stay in the process that started them, ranked across instances by priority so the
+// highest-priority one waiting starts first when a token frees up
+type RateLimiter struct {
+	redis        *redis.Client
+	logger       *logger.Logger
+	metrics      *metrics.Metrics
+	cfg          *config.WorkflowRateLimitConfig
+	pollInterval time.Duration
+}
+
+// NewRateLimiter creates an execution rate limiter holding its buckets in redisClient. m may be
+// nil to record no metrics
+func NewRateLimiter(redisClient *redis.Client, cfg *config.WorkflowRateLimitConfig, log *logger.Logger, m *metrics.Metrics) *RateLimiter {
+	limiter := &RateLimiter{
+		redis:        redisClient,
+		logger:       log,
+		metrics:      m,
+		cfg:          cfg,
+		pollInterval: cfg.PollInterval,
+	}
+	if limiter.pollInterval <= 0 {
+		limiter.pollInterval = DefaultRateLimitPollInterval
+	}
+	return limiter
+}
+
+// Take takes a token for an execution of the workflow from its own bucket and its
+// organization's, queueing it until both have one or dropping it as the policy says. The
+// workflow's policy applies when it has a rate limit, otherwise the organization's. Queued
+// executions start in order of priority, highest first, then of how long they have waited
+func (l *RateLimiter) Take(ctx context.Context, organizationID uuid.UUID, workflow *models.Workflow, priority float64) error {
+	buckets := l.bucketsFor(organizationID, workflow)
+	if len(buckets) == 0 {
+		return nil
+	}
+	policy, queueTimeout := buckets[0].policy, buckets[0].queueTimeout
+
+	// Bucket keys, then their queues, then the deadlines of their queued executions
+	keys := make([]string, 0, 3*len(buckets))
+	bucketArgs := make([]interface{}, 0, 3*len(buckets))
+	for _, bucket := range buckets {
+		keys = append(keys, bucket.key)
+		bucketArgs = append(bucketArgs, bucket.capacity, bucket.rate, bucket.interval.Milliseconds())
+	}
+	for _, bucket := range buckets {
+		keys = append(keys, bucket.key+":queue")
+	}
+	for _, bucket := range buckets {
+		keys = append(keys, bucket.key+":deadlines")
+	}
+
+	// Under the drop policy the first attempt is the only one
+	var deadline time.Time
+	if policy != models.RateLimitPolicyDrop {
+		deadline = time.Now().Add(queueTimeout)
+	}
+
+	ticket := uuid.NewString()
+	var enqueuedAt int64
+	var queuedAt time.Time
+	for {
+		remaining := max(0, time.Until(deadline))
+		args := append(bucketArgs[:len(bucketArgs):len(bucketArgs)],
+			ticket, priority, enqueuedAt, remaining.Milliseconds(), l.pollInterval.Milliseconds(), rateLimitQueueGrace.Milliseconds())
+		result, err := takeTokenScript.Run(ctx, l.redis, keys, args...).Int64Slice()
+		if err != nil {
+			return fmt.Errorf("failed to take rate limit token: %w", err)
+		}
+		if len(result) != 3+len(buckets) {
+			return fmt.Errorf("unexpected rate limit script result: %v", result)
+		}
+		enqueuedAt = result[2]
+		l.recordTokens(organizationID, buckets, result[3:])
+
+		if result[0] == 1 {
+			l.recordDecision(organizationID, workflow, "allowed")
+			if !queuedAt.IsZero() {
+				l.recordDequeued(organizationID, priority, time.Since(queuedAt), true)
+			}
+			return nil
+		}
+
+		// The script gave up the execution's place when it cannot wait any longer
+		wait := time.Duration(result[1]) * time.Millisecond
+		if wait > remaining {
+			l.recordDecision(organizationID, workflow, "dropped")
+			if !queuedAt.IsZero() {
+				l.recordDequeued(organizationID, priority, time.Since(queuedAt), false)
+			}
+			return fmt.Errorf("%w for workflow %s", ErrRateLimited, workflow.ID)
+		}
+
+		if queuedAt.IsZero() {
+			queuedAt = time.Now()
+			l.recordDecision(organizationID, workflow, "queued")
+			l.recordQueued(organizationID, priority)
+			l.logger.Infof("Queueing execution of workflow %s with priority %g by its rate limit", workflow.ID, priority)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			l.leaveQueue(keys[len(buckets):], fmt.Sprintf("%013d:%s", enqueuedAt, ticket))
+			l.recordDequeued(organizationID, priority, time.Since(queuedAt), false)
+			return ctx.Err()
+		}
+	}
+}
+
+// leaveQueue gives up the place of an execution that stopped waiting. If Redis is unreachable
+// the place is given up when its deadline passes
+func (l *RateLimiter) leaveQueue(keys []string, member string) {
+	ctx, cancel := context.WithTimeout(context.Background(), rateLimitQueueGrace)
+	defer cancel()
+	if err := leaveQueueScript.Run(ctx, l.redis, keys, member).Err(); err != nil {
+		l.logger.Warnf("Failed to leave rate limit queue: %v", err)
+	}
+}
+
+// bucketsFor returns the rate limits an execution of the workflow takes a token from: its own,
+// if it has one, then its organization's, if configured
+func (l *RateLimiter) bucketsFor(organizationID uuid.UUID, workflow *models.Workflow) []tokenBucket {
+	var buckets []tokenBucket
+
+	if limit := workflow.Definition.RateLimit; limit != nil && limit.Rate > 0 {
+		interval := defaultRateLimitInterval
+		if limit.Per != "" {
+			if per, err := time.ParseDuration(limit.Per); err == nil && per > 0 {
+				interval = per
+			} else {
+				l.logger.Warnf("Invalid rate_limit per %q on workflow %s, using %s", limit.Per, workflow.ID, interval)
+			}
+		}
+
+		queueTimeout := l.cfg.QueueTimeout
+		if limit.QueueTimeout != "" {
+			if timeout, err := time.ParseDuration(limit.QueueTimeout); err == nil && timeout > 0 {
+				queueTimeout = timeout
+			} else {
+				l.logger.Warnf("Invalid rate_limit queue_timeout %q on workflow %s, using %s", limit.QueueTimeout, workflow.ID, queueTimeout)
+			}
+		}
+
+		buckets = append(buckets, tokenBucket{
+			key:          fmt.Sprintf("%s%s:%s", rateLimitKeyPrefix, organizationID, workflow.ID),
+			workflowID:   workflow.ID.String(),
+			capacity:     burstOrRate(limit.Burst, limit.Rate),
+			rate:         limit.Rate,
+			interval:     interval,
+			policy:       limit.Policy,
+			queueTimeout: queueTimeout,
+		})
+	}
+
+	if l.cfg.OrganizationRate > 0 {
+		buckets = append(buckets, tokenBucket{
+			key:          rateLimitKeyPrefix + organizationID.String(),
+			capacity:     burstOrRate(l.cfg.OrganizationBurst, l.cfg.OrganizationRate),
+			rate:         l.cfg.OrganizationRate,
+			interval:     l.cfg.OrganizationInterval,
+			policy:       models.RateLimitPolicy(l.cfg.OrganizationPolicy),
+			queueTimeout: l.cfg.QueueTimeout,
+		})
+	}
+
+	return buckets
+}
+
+// burstOrRate returns the capacity of a bucket, which is its rate unless a burst is set
+func burstOrRate(burst, rate int) int {
+	if burst > 0 {
+		return burst
+	}
+	return rate
+}
+
+// recordTokens reports the tokens left in each bucket
+func (l *RateLimiter) recordTokens(organizationID uuid.UUID, buckets []tokenBucket, tokens []int64) {
+	if l.metrics == nil {
+		return
+	}
+	orgLabel := l.metrics.OrganizationLabel(organizationID.String())
+	for i, bucket := range buckets {
+		l.metrics.RateLimitTokens.WithLabelValues(orgLabel, bucket.workflowID).Set(float64(tokens[i]))
+	}
+}
+
+// recordQueued counts an execution waiting for a token in the queue depth
+func (l *RateLimiter) recordQueued(organizationID uuid.UUID, priority float64) {
+	if l.metrics == nil {
+		return
+	}
+	orgLabel := l.metrics.OrganizationLabel(organizationID.String())
+	l.metrics.RateLimitQueueDepth.WithLabelValues(orgLabel, priorityLabel(priority)).Inc()
+}
+
+// recordDequeued removes a queued execution from the queue depth and, if it started, records how
+// long it waited
+func (l *RateLimiter) recordDequeued(organizationID uuid.UUID, priority float64, waited time.Duration, started bool) {
+	if l.metrics == nil {
+		return
+	}
+	orgLabel := l.metrics.OrganizationLabel(organizationID.String())
+	l.metrics.RateLimitQueueDepth.WithLabelValues(orgLabel, priorityLabel(priority)).Dec()
+	if started {
+		l.metrics.RateLimitQueueWait.WithLabelValues(orgLabel, priorityLabel(priority)).Observe(waited.Seconds())
+	}
+}
+
+// priorityLabel buckets a priority into a metric label value, so priorities taken from trigger
+// payloads cannot create unbounded metric series
+func priorityLabel(priority float64) string {
+	switch {
+	case priority > 0:
+		return "high"
+	case priority < 0:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// recordDecision counts an execution checked against the rate limits by result
+func (l *RateLimiter) recordDecision(organizationID uuid.UUID, workflow *models.Workflow, result string) {
+	if l.metrics == nil {
+		return
+	}
+	orgLabel := l.metrics.OrganizationLabel(organizationID.String())
+	l.metrics.RateLimitDecisions.WithLabelValues(orgLabel, workflow.ID.String(), result).Inc()
+}
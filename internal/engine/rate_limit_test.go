@@ -0,0 +1,464 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/metrics"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+// bucketTokens returns the tokens left in a bucket as of its last check, or -1 without one
+func bucketTokens(t *testing.T, client *redis.Client, key string) float64 {
+	t.Helper()
+	tokens, err := client.HGet(context.Background(), key, "tokens").Float64()
+	if errors.Is(err, redis.Nil) {
+		return -1
+	}
+	if err != nil {
+		t.Fatalf("Failed to get the tokens of %s: %v", key, err)
+	}
+	return tokens
+}
+
+// queuedExecutions returns how many executions wait for a bucket
+func queuedExecutions(t *testing.T, client *redis.Client, key string) int {
+	t.Helper()
+	count, err := client.ZCard(context.Background(), key+":queue").Result()
+	if err != nil {
+		t.Fatalf("Failed to count the executions queued for %s: %v", key, err)
+	}
+	return int(count)
+}
+
+func rateLimitedWorkflow(rateLimit *models.RateLimitDefinition) *models.Workflow {
+	return &models.Workflow{
+		ID:   uuid.New(),
+		Name: "Sync to ERP",
+		Definition: models.WorkflowDefinition{
+			RateLimit: rateLimit,
+			Steps: []models.Step{
+				{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}},
+			},
+		},
+	}
+}
+
+func TestRateLimiter_Take(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+	organizationID := uuid.New()
+	cfg := &config.WorkflowRateLimitConfig{QueueTimeout: time.Minute}
+
+	t.Run("drops executions over the workflow's rate", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		limiter := NewRateLimiter(client, cfg, log, nil)
+		workflow := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 2, Per: "1h", Policy: models.RateLimitPolicyDrop})
+
+		for i := 0; i < 2; i++ {
+			if err := limiter.Take(ctx, organizationID, workflow, 0); err != nil {
+				t.Fatalf("Expected execution %d to start, got %v", i+1, err)
+			}
+		}
+		if err := limiter.Take(ctx, organizationID, workflow, 0); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("Expected ErrRateLimited, got %v", err)
+		}
+
+		// Other workflows have buckets of their own
+		other := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 1, Per: "1h", Policy: models.RateLimitPolicyDrop})
+		if err := limiter.Take(ctx, organizationID, other, 0); err != nil {
+			t.Errorf("Expected another workflow to start, got %v", err)
+		}
+	})
+
+	t.Run("burst allows executions to start at once", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		limiter := NewRateLimiter(client, cfg, log, nil)
+		workflow := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 1, Per: "1h", Burst: 3, Policy: models.RateLimitPolicyDrop})
+
+		started := 0
+		for limiter.Take(ctx, organizationID, workflow, 0) == nil && started < 10 {
+			started++
+		}
+		if started != 3 {
+			t.Errorf("Expected 3 executions to start, got %d", started)
+		}
+	})
+
+	t.Run("queues executions until the bucket refills", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		limiter := NewRateLimiter(client, cfg, log, nil)
+		workflow := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 1, Per: "50ms"})
+
+		if err := limiter.Take(ctx, organizationID, workflow, 0); err != nil {
+			t.Fatalf("Expected the first execution to start, got %v", err)
+		}
+
+		start := time.Now()
+		if err := limiter.Take(ctx, organizationID, workflow, 0); err != nil {
+			t.Fatalf("Expected the queued execution to start, got %v", err)
+		}
+		if waited := time.Since(start); waited < 40*time.Millisecond {
+			t.Errorf("Expected the execution to wait for the bucket to refill, waited %s", waited)
+		}
+	})
+
+	t.Run("drops queued executions the queue timeout cannot cover", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		limiter := NewRateLimiter(client, cfg, log, nil)
+		workflow := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 1, Per: "1h", QueueTimeout: "1s"})
+
+		if err := limiter.Take(ctx, organizationID, workflow, 0); err != nil {
+			t.Fatalf("Expected the first execution to start, got %v", err)
+		}
+
+		start := time.Now()
+		if err := limiter.Take(ctx, organizationID, workflow, 0); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("Expected ErrRateLimited, got %v", err)
+		}
+		if waited := time.Since(start); waited > 500*time.Millisecond {
+			t.Errorf("Expected the execution to be dropped without waiting, waited %s", waited)
+		}
+	})
+
+	t.Run("queued executions start by priority", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		m := &metrics.Metrics{
+			RateLimitTokens:     prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "tokens"}, []string{"organization_id", "workflow_id"}),
+			RateLimitDecisions:  prometheus.NewCounterVec(prometheus.CounterOpts{Name: "decisions"}, []string{"organization_id", "workflow_id", "result"}),
+			RateLimitQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "depth"}, []string{"organization_id", "priority"}),
+			RateLimitQueueWait:  prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "wait"}, []string{"organization_id", "priority"}),
+		}
+		limiter := NewRateLimiter(client, &config.WorkflowRateLimitConfig{QueueTimeout: time.Minute, PollInterval: 5 * time.Millisecond}, log, m)
+		workflow := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 1, Per: "100ms"})
+		queueKey := fmt.Sprintf("%s%s:%s", rateLimitKeyPrefix, organizationID, workflow.ID)
+
+		if err := limiter.Take(ctx, organizationID, workflow, 0); err != nil {
+			t.Fatalf("Expected the first execution to start, got %v", err)
+		}
+
+		var mu sync.Mutex
+		var started []float64
+		var wg sync.WaitGroup
+		enqueue := func(priority float64, queued int) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := limiter.Take(ctx, organizationID, workflow, priority); err != nil {
+					t.Errorf("Expected the execution with priority %g to start, got %v", priority, err)
+				}
+				mu.Lock()
+				started = append(started, priority)
+				mu.Unlock()
+			}()
+			for queuedExecutions(t, client, queueKey) < queued {
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		// The later, higher-priority execution starts first, then ties start in the order they queued
+		enqueue(0, 1)
+		enqueue(1, 2)
+		enqueue(10, 3)
+		enqueue(1, 4)
+		wg.Wait()
+
+		if fmt.Sprint(started) != "[10 1 1 0]" {
+			t.Errorf("Expected executions to start by priority, got %v", started)
+		}
+		if queuedExecutions(t, client, queueKey) != 0 {
+			t.Errorf("Expected the queue to be empty, got %d", queuedExecutions(t, client, queueKey))
+		}
+		if depth := testutil.ToFloat64(m.RateLimitQueueDepth.WithLabelValues("", "high")); depth != 0 {
+			t.Errorf("Expected no queued executions, got %v", depth)
+		}
+		if waits := testutil.CollectAndCount(m.RateLimitQueueWait); waits != 2 {
+			t.Errorf("Expected waits recorded for the high and normal priorities, got %d", waits)
+		}
+	})
+
+	t.Run("cancelled execution gives up its place", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		limiter := NewRateLimiter(client, cfg, log, nil)
+		workflow := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 1, Per: "1s"})
+		queueKey := fmt.Sprintf("%s%s:%s", rateLimitKeyPrefix, organizationID, workflow.ID)
+
+		if err := limiter.Take(ctx, organizationID, workflow, 0); err != nil {
+			t.Fatalf("Expected the first execution to start, got %v", err)
+		}
+
+		cancelCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		if err := limiter.Take(cancelCtx, organizationID, workflow, 0); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the context's error, got %v", err)
+		}
+		if queuedExecutions(t, client, queueKey) != 0 {
+			t.Errorf("Expected the execution to leave the queue, %d queued", queuedExecutions(t, client, queueKey))
+		}
+	})
+
+	t.Run("places of crashed processes expire", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		limiter := NewRateLimiter(client, cfg, log, nil)
+		workflow := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 1, Per: "1h", Policy: models.RateLimitPolicyDrop})
+		queueKey := fmt.Sprintf("%s%s:%s", rateLimitKeyPrefix, organizationID, workflow.ID)
+
+		// A higher-priority execution whose process died without leaving the queue
+		now, err := client.Time(ctx).Result()
+		if err != nil {
+			t.Fatalf("Failed to get redis time: %v", err)
+		}
+		crashed := fmt.Sprintf("%013d:%s", now.UnixMilli(), uuid.NewString())
+		client.ZAdd(ctx, queueKey+":queue", redis.Z{Score: -10, Member: crashed})
+		client.ZAdd(ctx, queueKey+":deadlines", redis.Z{Score: float64(now.UnixMilli() - 1), Member: crashed})
+
+		if err := limiter.Take(ctx, organizationID, workflow, 0); err != nil {
+			t.Errorf("Expected the execution to start once the crashed place expired, got %v", err)
+		}
+		if queuedExecutions(t, client, queueKey) != 0 {
+			t.Errorf("Expected the expired place to be removed, %d queued", queuedExecutions(t, client, queueKey))
+		}
+	})
+
+	t.Run("organization limit covers all its workflows", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		orgCfg := &config.WorkflowRateLimitConfig{OrganizationRate: 1, OrganizationInterval: time.Hour, OrganizationPolicy: "drop"}
+		limiter := NewRateLimiter(client, orgCfg, log, nil)
+
+		if err := limiter.Take(ctx, organizationID, rateLimitedWorkflow(nil), 0); err != nil {
+			t.Fatalf("Expected the first execution to start, got %v", err)
+		}
+
+		limited := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 5, Per: "1h", Policy: models.RateLimitPolicyDrop})
+		if err := limiter.Take(ctx, organizationID, limited, 0); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("Expected ErrRateLimited once the organization's bucket is empty, got %v", err)
+		}
+		// The workflow's own token is only taken along with the organization's
+		if tokens := bucketTokens(t, client, fmt.Sprintf("%s%s:%s", rateLimitKeyPrefix, organizationID, limited.ID)); tokens != 5 {
+			t.Errorf("Expected the workflow's bucket to stay full, got %v tokens", tokens)
+		}
+
+		if err := limiter.Take(ctx, uuid.New(), rateLimitedWorkflow(nil), 0); err != nil {
+			t.Errorf("Expected another organization's execution to start, got %v", err)
+		}
+	})
+
+	t.Run("records tokens left and decisions", func(t *testing.T) {
+		client, _ := newTestRedis(t)
+		m := &metrics.Metrics{
+			RateLimitTokens:     prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "tokens"}, []string{"organization_id", "workflow_id"}),
+			RateLimitDecisions:  prometheus.NewCounterVec(prometheus.CounterOpts{Name: "decisions"}, []string{"organization_id", "workflow_id", "result"}),
+			RateLimitQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "depth"}, []string{"organization_id", "priority"}),
+			RateLimitQueueWait:  prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "wait"}, []string{"organization_id", "priority"}),
+		}
+		limiter := NewRateLimiter(client, cfg, log, m)
+		workflow := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 3, Per: "1h", Policy: models.RateLimitPolicyDrop})
+
+		for i := 0; i < 4; i++ {
+			_ = limiter.Take(ctx, organizationID, workflow, 0)
+		}
+
+		workflowID := workflow.ID.String()
+		if tokens := testutil.ToFloat64(m.RateLimitTokens.WithLabelValues("", workflowID)); tokens != 0 {
+			t.Errorf("Expected no tokens left, got %v", tokens)
+		}
+		if allowed := testutil.ToFloat64(m.RateLimitDecisions.WithLabelValues("", workflowID, "allowed")); allowed != 3 {
+			t.Errorf("Expected 3 allowed executions, got %v", allowed)
+		}
+		if dropped := testutil.ToFloat64(m.RateLimitDecisions.WithLabelValues("", workflowID, "dropped")); dropped != 1 {
+			t.Errorf("Expected 1 dropped execution, got %v", dropped)
+		}
+	})
+}
+
+func TestExecuteWithOptions_RateLimit(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+	organizationID := uuid.New()
+
+	client, _ := newTestRedis(t)
+	created := 0
+	repo := &mockExecutionRepo{
+		createExecutionFunc: func(ctx context.Context, execution *models.WorkflowExecution) error {
+			created++
+			return nil
+		},
+	}
+	executor := NewWorkflowExecutor(client, repo, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+	executor.SetRateLimiter(NewRateLimiter(client, &config.WorkflowRateLimitConfig{}, log, nil))
+
+	workflow := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 1, Per: "1h", Policy: models.RateLimitPolicyDrop})
+	if _, err := executor.Execute(ctx, organizationID, workflow, "order.created", map[string]interface{}{}); err != nil {
+		t.Fatalf("Expected the first execution to run, got %v", err)
+	}
+
+	execution, err := executor.Execute(ctx, organizationID, workflow, "order.created", map[string]interface{}{})
+	if !errors.Is(err, ErrRateLimited) || execution != nil {
+		t.Errorf("Expected ErrRateLimited without an execution, got %v, %v", execution, err)
+	}
+	if created != 1 {
+		t.Errorf("Expected 1 recorded execution, got %d", created)
+	}
+
+	// Dry runs have no side effects, so they are not limited
+	if _, err := executor.ExecuteWithOptions(ctx, organizationID, workflow, "order.created", map[string]interface{}{}, ExecutionOptions{DryRun: true}); err != nil {
+		t.Errorf("Expected the dry run to ignore the limit, got %v", err)
+	}
+}
+
+func TestExecuteWithOptions_RateLimitBeforeConcurrency(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+	organizationID := uuid.New()
+
+	client, _ := newTestRedis(t)
+	executor := NewWorkflowExecutor(client, &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+	executor.SetRateLimiter(NewRateLimiter(client, &config.WorkflowRateLimitConfig{QueueTimeout: time.Minute, PollInterval: 5 * time.Millisecond}, log, nil))
+	executor.SetConcurrencyLimiter(NewConcurrencyLimiter(client, &config.ConcurrencyConfig{LeaseTTL: 30 * time.Second}, log))
+
+	workflow := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 1, Per: "300ms"})
+	workflow.Definition.Concurrency = &models.ConcurrencyDefinition{Policy: models.ConcurrencyPolicyReject}
+	queueKey := fmt.Sprintf("%s%s:%s", rateLimitKeyPrefix, organizationID, workflow.ID)
+
+	if _, err := executor.Execute(ctx, organizationID, workflow, "order.created", map[string]interface{}{}); err != nil {
+		t.Fatalf("Expected the first execution to run, got %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := executor.Execute(ctx, organizationID, workflow, "order.created", map[string]interface{}{})
+		done <- err
+	}()
+	for queuedExecutions(t, client, queueKey) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The execution waiting for a token holds no slot, so it does not block others from running
+	if held := heldLeases(t, client); held != 0 {
+		t.Errorf("Expected the queued execution to hold no concurrency slot, %d held", held)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Expected the queued execution to run, got %v", err)
+	}
+}
+
+func TestResume_RateLimit(t *testing.T) {
+	log := logger.NewForTesting()
+	ctx := context.Background()
+	organizationID := uuid.New()
+
+	// newResumeExecutor returns an executor whose workflow has spent its only token, recording
+	// the steps it runs and the statuses executions are saved with
+	newResumeExecutor := func(t *testing.T, workflow *models.Workflow) (*WorkflowExecutor, *[]string, *[]models.ExecutionStatus) {
+		client, _ := newTestRedis(t)
+		var ranSteps []string
+		var saved []models.ExecutionStatus
+		repo := &mockExecutionRepo{
+			updateExecutionFunc: func(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
+				saved = append(saved, execution.Status)
+				return nil
+			},
+			createStepExecutionFunc: func(ctx context.Context, step *models.StepExecution) error {
+				ranSteps = append(ranSteps, step.StepID)
+				return nil
+			},
+		}
+		workflowRepo := &mockWorkflowRepo{
+			getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error) {
+				return workflow, nil
+			},
+		}
+		limiter := NewRateLimiter(client, &config.WorkflowRateLimitConfig{}, log, nil)
+		if err := limiter.Take(ctx, organizationID, workflow, 0); err != nil {
+			t.Fatalf("Expected the running execution to take the token, got %v", err)
+		}
+
+		executor := NewWorkflowExecutor(client, repo, workflowRepo, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+		executor.SetRateLimiter(limiter)
+		return executor, &ranSteps, &saved
+	}
+
+	t.Run("delay resume goes back to waiting without a token", func(t *testing.T) {
+		workflow := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 1, Per: "1h", Policy: models.RateLimitPolicyDrop})
+		workflow.Definition.Steps = []models.Step{
+			{ID: "cool_off", Type: "delay", Delay: &models.DelayStep{Duration: "10m"}, Next: "sync"},
+			{ID: "sync", Type: "execute", Execute: []models.ExecuteAction{{Type: "log", Message: "syncing"}}},
+		}
+		resumeAt := time.Now().Add(-time.Second)
+		execution := &models.WorkflowExecution{
+			ID:             uuid.New(),
+			OrganizationID: organizationID,
+			WorkflowID:     workflow.ID,
+			Status:         models.ExecutionStatusRunning,
+			CurrentStepID:  stringPtr("cool_off"),
+			WaitState:      &models.WaitState{ResumeAt: &resumeAt},
+		}
+		executor, ranSteps, saved := newResumeExecutor(t, workflow)
+
+		if err := executor.ResumeDelayedExecution(ctx, execution); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("Expected ErrRateLimited, got %v", err)
+		}
+		if len(*saved) != 1 || (*saved)[0] != models.ExecutionStatusWaiting || len(*ranSteps) != 0 {
+			t.Errorf("Expected the execution to be saved as waiting without running steps, got saved %v, ran %v", *saved, *ranSteps)
+		}
+	})
+
+	t.Run("paused resume returns the error without a token", func(t *testing.T) {
+		workflow := rateLimitedWorkflow(&models.RateLimitDefinition{Rate: 1, Per: "1h", Policy: models.RateLimitPolicyDrop})
+		execution := &models.WorkflowExecution{
+			ID:             uuid.New(),
+			OrganizationID: organizationID,
+			WorkflowID:     workflow.ID,
+			Status:         models.ExecutionStatusRunning,
+		}
+		executor, ranSteps, _ := newResumeExecutor(t, workflow)
+
+		if err := executor.ResumePausedExecution(ctx, execution); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("Expected ErrRateLimited, got %v", err)
+		}
+		if len(*ranSteps) != 0 || execution.Status == models.ExecutionStatusFailed {
+			t.Errorf("Expected the execution not to run or fail, got status %s, ran %v", execution.Status, *ranSteps)
+		}
+	})
+}
+
+func TestExecutionPriority(t *testing.T) {
+	executor := NewWorkflowExecutor(nil, &mockExecutionRepo{}, nil, nil, logger.NewForTesting(), nil, getTestContextEnrichmentConfigForExecutor())
+	payload := map[string]interface{}{"order": map[string]interface{}{"total": 2500}}
+
+	tests := []struct {
+		name     string
+		workflow string
+		trigger  string
+		want     float64
+	}{
+		{name: "no priority", want: 0},
+		{name: "workflow priority", workflow: "5", want: 5},
+		{name: "trigger priority overrides the workflow's", workflow: "5", trigger: "order.total >= 1000 ? 10 : 1", want: 10},
+		{name: "fractional priority", trigger: "double(order.total) / 1000.0", want: 2.5},
+		{name: "priority that is not a number", trigger: "'high'", want: 0},
+		{name: "priority that fails", trigger: "customer.tier == 'gold' ? 10 : 0", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workflow := rateLimitedWorkflow(nil)
+			workflow.Definition.Priority = tt.workflow
+			workflow.Definition.Trigger.Priority = tt.trigger
+
+			if got := executor.executionPriority(context.Background(), workflow, payload); got != tt.want {
+				t.Errorf("Expected priority %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// renderTemplate returns a copy of value with {{path}} tokens replaced by context values.
+// Maps and lists are walked recursively; other values are returned unchanged.
+//
+//   - A string that is exactly one token, e.g. "{{order.total}}", yields the raw resolved
+//     value (number, map, list...) rather than its string form. The legacy "${order.total}"
+//     form behaves the same way.
+//   - Tokens embedded in text, e.g. "Order {{order.id}} needs review", are formatted:
+//     strings as-is, maps and lists as JSON, everything else with %v.
+//   - A missing variable yields nil as a whole-string token and "" inside text.
+//   - "\{{" renders a literal "{{".
+func renderTemplate(value interface{}, context map[string]interface{}, paths *PathResolver) interface{} {
+	switch v := value.(type) {
+	case string:
+		return renderString(v, context, paths)
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			rendered[key] = renderTemplate(item, context, paths)
+		}
+		return rendered
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, item := range v {
+			rendered[i] = renderTemplate(item, context, paths)
+		}
+		return rendered
+	default:
+		return value
+	}
+}
+
+// renderTemplateMap renders every value of a data map; a nil map stays nil
+func renderTemplateMap(data map[string]interface{}, context map[string]interface{}, paths *PathResolver) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	return renderTemplate(data, context, paths).(map[string]interface{})
+}
+
+// renderTemplateString renders a string field, formatting a whole-string token as text
+func renderTemplateString(s string, context map[string]interface{}, paths *PathResolver) string {
+	return formatTemplateValue(renderString(s, context, paths))
+}
+
+// renderString renders a single string, preserving the resolved type for whole-string tokens
+func renderString(s string, context map[string]interface{}, paths *PathResolver) interface{} {
+	if path, ok := wholeToken(s); ok {
+		value, err := paths.Resolve(path, context)
+		if err != nil {
+			return nil
+		}
+		return value
+	}
+
+	return renderText(s, context, paths, formatTemplateValue)
+}
+
+// renderTemplateHTML renders an HTML string, escaping every resolved value so context data
+// can't inject markup
+func renderTemplateHTML(s string, context map[string]interface{}, paths *PathResolver) string {
+	if path, ok := wholeToken(s); ok {
+		value, _ := paths.Resolve(path, context)
+		return html.EscapeString(formatTemplateValue(value))
+	}
+	return renderText(s, context, paths, func(value interface{}) string {
+		return html.EscapeString(formatTemplateValue(value))
+	})
+}
+
+// renderText replaces the tokens embedded in s with their resolved values, formatted by format
+func renderText(s string, context map[string]interface{}, paths *PathResolver, format func(interface{}) string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+
+	var b strings.Builder
+	rest := s
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			b.WriteString(rest)
+			break
+		}
+
+		// An escaped opener is written literally
+		if start > 0 && rest[start-1] == '\\' {
+			b.WriteString(rest[:start-1])
+			b.WriteString("{{")
+			rest = rest[start+2:]
+			continue
+		}
+
+		end := strings.Index(rest[start+2:], "}}")
+		if end < 0 {
+			// Unterminated token: keep the remainder as text
+			b.WriteString(rest)
+			break
+		}
+
+		b.WriteString(rest[:start])
+		path := strings.TrimSpace(rest[start+2 : start+2+end])
+		if value, err := paths.Resolve(path, context); err == nil {
+			b.WriteString(format(value))
+		}
+		rest = rest[start+2+end+2:]
+	}
+
+	return b.String()
+}
+
+// wholeToken reports whether s consists of a single {{path}} or ${path} token and returns the path
+func wholeToken(s string) (string, bool) {
+	var inner string
+	switch {
+	case strings.HasPrefix(s, "{{") && strings.HasSuffix(s, "}}") && len(s) > 4:
+		inner = s[2 : len(s)-2]
+		if strings.Contains(inner, "{{") || strings.Contains(inner, "}}") {
+			return "", false
+		}
+	case strings.HasPrefix(s, "${") && strings.HasSuffix(s, "}") && len(s) > 3:
+		inner = s[2 : len(s)-1]
+	default:
+		return "", false
+	}
+
+	path := strings.TrimSpace(inner)
+	return path, path != ""
+}
+
+// formatTemplateValue renders a resolved value for embedding in text
+func formatTemplateValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(encoded)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
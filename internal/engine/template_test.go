@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	context := map[string]interface{}{
+		"order": map[string]interface{}{
+			"id":    "ord-1",
+			"total": 1500.5,
+			"paid":  true,
+			"items": []interface{}{"sku-1", "sku-2"},
+			"customer": map[string]interface{}{
+				"tier": "gold",
+			},
+			"note": nil,
+		},
+	}
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected interface{}
+	}{
+		{name: "plain string", input: "no tokens", expected: "no tokens"},
+		{name: "embedded token", input: "Order {{order.id}} needs review", expected: "Order ord-1 needs review"},
+		{name: "multiple tokens with spaces", input: "{{ order.id }}: {{order.total}}", expected: "ord-1: 1500.5"},
+		{name: "whole token keeps number", input: "{{order.total}}", expected: 1500.5},
+		{name: "whole token keeps bool", input: "{{ order.paid }}", expected: true},
+		{name: "whole token keeps list", input: "{{order.items}}", expected: []interface{}{"sku-1", "sku-2"}},
+		{name: "whole token keeps map", input: "{{order.customer}}", expected: map[string]interface{}{"tier": "gold"}},
+		{name: "legacy whole token", input: "${order.id}", expected: "ord-1"},
+		{name: "indexed path", input: "first: {{order.items[0]}}", expected: "first: sku-1"},
+		{name: "embedded list is JSON", input: "items={{order.items}}", expected: `items=["sku-1","sku-2"]`},
+		{name: "missing whole token is nil", input: "{{order.missing}}", expected: nil},
+		{name: "missing embedded token is empty", input: "Ref: {{order.missing}}!", expected: "Ref: !"},
+		{name: "null embedded token is empty", input: "Note: {{order.note}}", expected: "Note: "},
+		{name: "escaped opener", input: `Use \{{name}} placeholders for {{order.id}}`, expected: "Use {{name}} placeholders for ord-1"},
+		{name: "escaped whole string", input: `\{{order.id}}`, expected: "{{order.id}}"},
+		{name: "unterminated token", input: "Broken {{order.id", expected: "Broken {{order.id"},
+		{name: "two tokens are not a whole token", input: "{{order.id}}{{order.total}}", expected: "ord-11500.5"},
+		{name: "non-string value", input: 42, expected: 42},
+		{
+			name:     "nested map and list",
+			input:    map[string]interface{}{"message": "Order {{order.id}}", "lines": []interface{}{"{{order.total}}", 7}},
+			expected: map[string]interface{}{"message": "Order ord-1", "lines": []interface{}{1500.5, 7}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := renderTemplate(tt.input, context, NewPathResolver())
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %#v, got %#v", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("does not modify the input", func(t *testing.T) {
+		data := map[string]interface{}{"id": "{{order.id}}"}
+		renderTemplateMap(data, context, nil)
+		if data["id"] != "{{order.id}}" {
+			t.Errorf("Expected input to be unchanged, got %v", data["id"])
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the engine's spans
+const tracerName = "github.com/davidmoltin/intelligent-workflows/internal/engine"
+
+// SetTracerProvider sets the provider execution and step spans are created with (optional). By
+// default the global provider is used, which is a no-op unless tracing is configured
+func (we *WorkflowExecutor) SetTracerProvider(provider trace.TracerProvider) {
+	we.tracer = provider.Tracer(tracerName)
+}
+
+// startExecutionSpan starts the root span of an execution, as a child of any trace context
+// already in ctx
+func (we *WorkflowExecutor) startExecutionSpan(
+	ctx context.Context,
+	workflow *models.Workflow,
+	execution *models.WorkflowExecution,
+) (context.Context, trace.Span) {
+	return we.tracer.Start(ctx, "workflow.execute",
+		trace.WithTimestamp(execution.StartedAt),
+		trace.WithAttributes(
+			attribute.String("workflow.id", workflow.ID.String()),
+			attribute.String("workflow.name", workflow.Name),
+			attribute.String("execution.id", execution.ExecutionID),
+			attribute.String("execution.trigger_event", execution.TriggerEvent),
+			attribute.String("organization.id", execution.OrganizationID.String()),
+			attribute.Bool("execution.dry_run", isDryRun(execution)),
+		),
+	)
+}
+
+// endExecutionSpan records the execution's outcome on its span and ends it
+func endExecutionSpan(span trace.Span, execution *models.WorkflowExecution) {
+	span.SetAttributes(attribute.String("execution.status", string(execution.Status)))
+	if execution.Result != nil {
+		span.SetAttributes(attribute.String("execution.result", string(*execution.Result)))
+	}
+	if execution.DurationMs != nil {
+		span.SetAttributes(attribute.Int("execution.duration_ms", *execution.DurationMs))
+	}
+	if execution.Status == models.ExecutionStatusFailed && execution.ErrorMessage != nil {
+		span.SetStatus(codes.Error, *execution.ErrorMessage)
+	}
+	span.End()
+}
+
+// startStepSpan starts the span of one step run, as a child of the execution's span
+func (we *WorkflowExecutor) startStepSpan(ctx context.Context, step *models.Step) (context.Context, trace.Span) {
+	return we.tracer.Start(ctx, "workflow.step "+step.ID,
+		trace.WithAttributes(
+			attribute.String("step.id", step.ID),
+			attribute.String("step.type", step.Type),
+		),
+	)
+}
+
+// endStepSpan records a step run's outcome on its span and ends it
+func endStepSpan(span trace.Span, stepExec *models.StepExecution) {
+	span.SetAttributes(attribute.String("step.status", string(stepExec.Status)))
+	if stepExec.DurationMs != nil {
+		span.SetAttributes(attribute.Int("step.duration_ms", *stepExec.DurationMs))
+	}
+	if stepExec.Status == models.StepStatusFailed && stepExec.ErrorMessage != nil {
+		span.SetAttributes(attribute.String("step.error", *stepExec.ErrorMessage))
+		span.SetStatus(codes.Error, *stepExec.ErrorMessage)
+	}
+	span.End()
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanAttribute returns the value of a span's attribute, or an invalid value if it isn't set
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestExecutionTracing(t *testing.T) {
+	log := logger.NewForTesting()
+	executor := NewWorkflowExecutor(redis.NewClient(&redis.Options{Addr: "localhost:6379"}), &mockExecutionRepo{}, nil, nil, log, nil, getTestContextEnrichmentConfigForExecutor())
+
+	recorder := tracetest.NewSpanRecorder()
+	executor.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	workflow := &models.Workflow{
+		ID:   uuid.New(),
+		Name: "Score order",
+		Definition: models.WorkflowDefinition{
+			Steps: []models.Step{
+				{ID: "score", Type: "set", Set: []models.SetVariable{{Name: "score", Expression: "order.total / 10.0"}}, Next: "discount"},
+				{ID: "discount", Type: "set", Set: []models.SetVariable{{Name: "discount", Expression: "customer.tier"}}},
+			},
+		},
+	}
+
+	// The trace context of the request that triggered the execution
+	caller := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), caller)
+
+	execution, err := executor.Execute(ctx, uuid.New(), workflow, "order.created", map[string]interface{}{
+		"order": map[string]interface{}{"total": float64(80)},
+	})
+	if err == nil {
+		t.Fatal("Expected the discount step to fail on the missing customer")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected an execution span and 2 step spans, got %d", len(spans))
+	}
+	score, discount, root := spans[0], spans[1], spans[2]
+
+	if root.Name() != "workflow.execute" {
+		t.Errorf("Expected the execution span to end last, got %s", root.Name())
+	}
+	if root.SpanContext().TraceID() != caller.TraceID() || root.Parent().SpanID() != caller.SpanID() {
+		t.Error("Expected the execution span to join the caller's trace")
+	}
+	if spanAttribute(root, "execution.id").AsString() != execution.ExecutionID {
+		t.Errorf("Expected execution.id %s, got %v", execution.ExecutionID, spanAttribute(root, "execution.id"))
+	}
+	if spanAttribute(root, "execution.status").AsString() != "failed" || root.Status().Code != codes.Error {
+		t.Errorf("Expected a failed execution span, got status %v", root.Status())
+	}
+
+	for _, span := range []sdktrace.ReadOnlySpan{score, discount} {
+		if span.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("Expected %s to be a child of the execution span", span.Name())
+		}
+		if spanAttribute(span, "step.type").AsString() != "set" {
+			t.Errorf("Expected %s to record its step type, got %v", span.Name(), spanAttribute(span, "step.type"))
+		}
+	}
+
+	if score.Name() != "workflow.step score" || spanAttribute(score, "step.status").AsString() != "completed" {
+		t.Errorf("Expected a completed score step span, got %s with status %v", score.Name(), spanAttribute(score, "step.status"))
+	}
+	if spanAttribute(discount, "step.status").AsString() != "failed" || discount.Status().Code != codes.Error {
+		t.Errorf("Expected a failed discount step span, got status %v", discount.Status())
+	}
+	if spanAttribute(discount, "step.error").AsString() == "" {
+		t.Error("Expected the discount step span to record its error")
+	}
+}
@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// maxIndexedWorkflows is the number of enabled workflows loaded per organization
+const maxIndexedWorkflows = 1000
+
+// WorkflowIndex keeps each organization's enabled event-triggered workflows in memory, keyed by
+// trigger event pattern, so routing an event does not list workflows from the database. An
+// organization is loaded on its first event and reloaded on every refresh until it is invalidated
+type WorkflowIndex struct {
+	workflowRepo    WorkflowRepository
+	logger          *logger.Logger
+	refreshInterval time.Duration
+
+	mu          sync.RWMutex
+	orgs        map[uuid.UUID]*orgWorkflowIndex
+	generations map[uuid.UUID]uint64 // Bumped on invalidation so in-flight loads are discarded
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// orgWorkflowIndex holds one organization's workflows by trigger event pattern
+type orgWorkflowIndex struct {
+	workflows []models.Workflow
+	exact     map[string][]int // Event type -> indexes of workflows with that literal pattern
+	wildcards []indexedPattern
+}
+
+// indexedPattern is a wildcard pattern of the workflow at the given index
+type indexedPattern struct {
+	pattern  string
+	workflow int
+}
+
+// NewWorkflowIndex creates a workflow index refreshed on the given interval
+func NewWorkflowIndex(workflowRepo WorkflowRepository, log *logger.Logger, refreshInterval time.Duration) *WorkflowIndex {
+	if refreshInterval == 0 {
+		refreshInterval = 30 * time.Second // Default to 30 seconds
+	}
+
+	return &WorkflowIndex{
+		workflowRepo:    workflowRepo,
+		logger:          log,
+		refreshInterval: refreshInterval,
+		orgs:            make(map[uuid.UUID]*orgWorkflowIndex),
+		generations:     make(map[uuid.UUID]uint64),
+		stopCh:          make(chan struct{}),
+		doneCh:          make(chan struct{}),
+	}
+}
+
+// Start starts refreshing the index in the background
+func (idx *WorkflowIndex) Start(ctx context.Context) {
+	idx.logger.Info("Starting workflow index refresh",
+		logger.String("interval", idx.refreshInterval.String()),
+	)
+
+	go idx.run(ctx)
+}
+
+// Stop stops refreshing the index
+func (idx *WorkflowIndex) Stop() {
+	close(idx.stopCh)
+	<-idx.doneCh
+	idx.logger.Info("Workflow index refresh stopped")
+}
+
+func (idx *WorkflowIndex) run(ctx context.Context) {
+	defer close(idx.doneCh)
+
+	ticker := time.NewTicker(idx.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			idx.Refresh(ctx)
+		case <-idx.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Refresh reloads every organization in the index. An organization that fails to reload keeps
+// its previous workflows until the next refresh
+func (idx *WorkflowIndex) Refresh(ctx context.Context) {
+	idx.mu.RLock()
+	organizationIDs := make([]uuid.UUID, 0, len(idx.orgs))
+	for organizationID := range idx.orgs {
+		organizationIDs = append(organizationIDs, organizationID)
+	}
+	idx.mu.RUnlock()
+
+	for _, organizationID := range organizationIDs {
+		if _, err := idx.load(ctx, organizationID); err != nil {
+			idx.logger.Warnf("Failed to refresh workflow index for organization %s: %v", organizationID, err)
+		}
+	}
+}
+
+// Invalidate drops an organization from the index, so its next event reloads its workflows
+func (idx *WorkflowIndex) Invalidate(organizationID uuid.UUID) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	delete(idx.orgs, organizationID)
+	idx.generations[organizationID]++
+}
+
+// Match returns the enabled workflows with an event trigger matching the event type, in the
+// order they were listed. An organization not yet in the index is loaded from the database
+func (idx *WorkflowIndex) Match(ctx context.Context, organizationID uuid.UUID, eventType string) ([]models.Workflow, error) {
+	idx.mu.RLock()
+	org, ok := idx.orgs[organizationID]
+	idx.mu.RUnlock()
+
+	if !ok {
+		var err error
+		if org, err = idx.load(ctx, organizationID); err != nil {
+			return nil, err
+		}
+	}
+
+	return org.match(eventType), nil
+}
+
+// load lists an organization's enabled workflows and stores them in the index, unless the
+// organization was invalidated while they were being listed
+func (idx *WorkflowIndex) load(ctx context.Context, organizationID uuid.UUID) (*orgWorkflowIndex, error) {
+	idx.mu.RLock()
+	generation := idx.generations[organizationID]
+	idx.mu.RUnlock()
+
+	enabled := true
+	workflows, _, err := idx.workflowRepo.ListWorkflows(ctx, organizationID, &enabled, maxIndexedWorkflows, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+	org := newOrgWorkflowIndex(workflows)
+
+	idx.mu.Lock()
+	if idx.generations[organizationID] == generation {
+		idx.orgs[organizationID] = org
+	}
+	idx.mu.Unlock()
+
+	return org, nil
+}
+
+func newOrgWorkflowIndex(workflows []models.Workflow) *orgWorkflowIndex {
+	org := &orgWorkflowIndex{
+		exact: make(map[string][]int),
+	}
+	for _, workflow := range workflows {
+		if workflow.Definition.Trigger.Type != "event" {
+			continue
+		}
+		i := len(org.workflows)
+		org.workflows = append(org.workflows, workflow)
+		for _, pattern := range workflow.Definition.Trigger.EventPatterns() {
+			if pattern == "" {
+				continue
+			}
+			if strings.Contains(pattern, "*") {
+				org.wildcards = append(org.wildcards, indexedPattern{pattern: pattern, workflow: i})
+			} else {
+				org.exact[pattern] = append(org.exact[pattern], i)
+			}
+		}
+	}
+	return org
+}
+
+func (org *orgWorkflowIndex) match(eventType string) []models.Workflow {
+	matched := make(map[int]bool)
+	for _, i := range org.exact[eventType] {
+		matched[i] = true
+	}
+	for _, wildcard := range org.wildcards {
+		if !matched[wildcard.workflow] && matchEventPattern(wildcard.pattern, eventType) {
+			matched[wildcard.workflow] = true
+		}
+	}
+
+	indexes := make([]int, 0, len(matched))
+	for i := range matched {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	workflows := make([]models.Workflow, 0, len(indexes))
+	for _, i := range indexes {
+		workflows = append(workflows, org.workflows[i])
+	}
+	return workflows
+}
@@ -0,0 +1,271 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func indexedWorkflow(workflowID, triggerType string, events ...string) models.Workflow {
+	return models.Workflow{
+		ID:         uuid.New(),
+		WorkflowID: workflowID,
+		Enabled:    true,
+		Definition: models.WorkflowDefinition{
+			Trigger: models.TriggerDefinition{Type: triggerType, Events: events},
+		},
+	}
+}
+
+// countingWorkflowRepo counts the workflow lists the index makes
+func countingWorkflowRepo(lists *int64, workflows func() ([]models.Workflow, error)) *mockWorkflowRepo {
+	return &mockWorkflowRepo{
+		listFunc: func(ctx context.Context, organizationID uuid.UUID, enabled *bool, limit, offset int) ([]models.Workflow, int64, error) {
+			atomic.AddInt64(lists, 1)
+			if enabled == nil || !*enabled {
+				return nil, 0, errors.New("expected only enabled workflows to be listed")
+			}
+			list, err := workflows()
+			return list, int64(len(list)), err
+		},
+	}
+}
+
+func matchedIDs(workflows []models.Workflow) []string {
+	ids := make([]string, len(workflows))
+	for i, workflow := range workflows {
+		ids[i] = workflow.WorkflowID
+	}
+	return ids
+}
+
+func TestWorkflowIndex_Match(t *testing.T) {
+	var lists int64
+	workflows := []models.Workflow{
+		indexedWorkflow("created", "event", "order.created"),
+		indexedWorkflow("any-order", "event", "order.*", "order.**"),
+		indexedWorkflow("scheduled", "schedule"),
+		indexedWorkflow("items", "event", "order.items.added", "order.items.removed"),
+		indexedWorkflow("everything", "event", "**"),
+	}
+	index := NewWorkflowIndex(countingWorkflowRepo(&lists, func() ([]models.Workflow, error) {
+		return workflows, nil
+	}), logger.NewForTesting(), 0)
+
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	tests := []struct {
+		eventType string
+		expected  []string
+	}{
+		{"order.created", []string{"created", "any-order", "everything"}},
+		{"order.items.added", []string{"any-order", "items", "everything"}},
+		{"order.items.removed", []string{"any-order", "items", "everything"}},
+		{"customer.created", []string{"everything"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.eventType, func(t *testing.T) {
+			matched, err := index.Match(ctx, orgID, tt.eventType)
+			if err != nil {
+				t.Fatalf("Match failed: %v", err)
+			}
+			ids := matchedIDs(matched)
+			if len(ids) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.expected[i] {
+					t.Fatalf("Expected %v, got %v", tt.expected, ids)
+				}
+			}
+		})
+	}
+
+	if lists != 1 {
+		t.Errorf("Expected the organization to be listed once, got %d lists", lists)
+	}
+
+	// Organizations are indexed separately
+	if _, err := index.Match(ctx, uuid.New(), "order.created"); err != nil {
+		t.Fatalf("Match failed: %v", err)
+	}
+	if lists != 2 {
+		t.Errorf("Expected a second organization to be listed, got %d lists", lists)
+	}
+}
+
+func TestWorkflowIndex_Invalidate(t *testing.T) {
+	var lists int64
+	var mu sync.Mutex
+	workflows := []models.Workflow{indexedWorkflow("created", "event", "order.created")}
+	index := NewWorkflowIndex(countingWorkflowRepo(&lists, func() ([]models.Workflow, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return workflows, nil
+	}), logger.NewForTesting(), 0)
+
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	if matched, _ := index.Match(ctx, orgID, "order.updated"); len(matched) != 0 {
+		t.Fatalf("Expected no workflows for order.updated, got %v", matchedIDs(matched))
+	}
+
+	mu.Lock()
+	workflows = append(workflows, indexedWorkflow("updated", "event", "order.updated"))
+	mu.Unlock()
+
+	// The cached workflows are used until the organization is invalidated
+	if matched, _ := index.Match(ctx, orgID, "order.updated"); len(matched) != 0 {
+		t.Fatalf("Expected the cached workflows to be used, got %v", matchedIDs(matched))
+	}
+
+	index.Invalidate(orgID)
+
+	matched, err := index.Match(ctx, orgID, "order.updated")
+	if err != nil {
+		t.Fatalf("Match failed: %v", err)
+	}
+	if len(matched) != 1 || matched[0].WorkflowID != "updated" {
+		t.Errorf("Expected the new workflow after invalidation, got %v", matchedIDs(matched))
+	}
+	if lists != 2 {
+		t.Errorf("Expected 2 lists, got %d", lists)
+	}
+}
+
+func TestWorkflowIndex_Refresh(t *testing.T) {
+	var lists int64
+	var mu sync.Mutex
+	workflows := []models.Workflow{indexedWorkflow("created", "event", "order.created")}
+	var listErr error
+	index := NewWorkflowIndex(countingWorkflowRepo(&lists, func() ([]models.Workflow, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return workflows, listErr
+	}), logger.NewForTesting(), 0)
+
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	// A cold index falls back to the database, and a failed list is an error
+	mu.Lock()
+	listErr = errors.New("database unavailable")
+	mu.Unlock()
+	if _, err := index.Match(ctx, orgID, "order.created"); err == nil {
+		t.Fatal("Expected an error when a cold organization cannot be listed")
+	}
+
+	mu.Lock()
+	listErr = nil
+	mu.Unlock()
+	if matched, _ := index.Match(ctx, orgID, "order.created"); len(matched) != 1 {
+		t.Fatalf("Expected 1 workflow, got %v", matchedIDs(matched))
+	}
+
+	// A refresh picks up disabled workflows
+	mu.Lock()
+	workflows = nil
+	mu.Unlock()
+	index.Refresh(ctx)
+	if matched, _ := index.Match(ctx, orgID, "order.created"); len(matched) != 0 {
+		t.Fatalf("Expected no workflows after refresh, got %v", matchedIDs(matched))
+	}
+
+	// A failed refresh keeps the previous workflows
+	mu.Lock()
+	workflows = []models.Workflow{indexedWorkflow("created", "event", "order.created")}
+	mu.Unlock()
+	index.Refresh(ctx)
+	mu.Lock()
+	listErr = errors.New("database unavailable")
+	mu.Unlock()
+	index.Refresh(ctx)
+
+	matched, err := index.Match(ctx, orgID, "order.created")
+	if err != nil {
+		t.Fatalf("Expected the previous workflows after a failed refresh, got %v", err)
+	}
+	if len(matched) != 1 {
+		t.Errorf("Expected 1 workflow, got %v", matchedIDs(matched))
+	}
+}
+
+func TestWorkflowIndex_ConcurrentAccess(t *testing.T) {
+	var lists int64
+	workflows := []models.Workflow{
+		indexedWorkflow("created", "event", "order.created"),
+		indexedWorkflow("any-order", "event", "order.*"),
+	}
+	index := NewWorkflowIndex(countingWorkflowRepo(&lists, func() ([]models.Workflow, error) {
+		return workflows, nil
+	}), logger.NewForTesting(), 0)
+
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				switch {
+				case i == 0 && j%10 == 0:
+					index.Invalidate(orgID)
+				case i == 1 && j%10 == 0:
+					index.Refresh(ctx)
+				default:
+					matched, err := index.Match(ctx, orgID, "order.created")
+					if err != nil {
+						t.Errorf("Match failed: %v", err)
+						return
+					}
+					if len(matched) != 2 {
+						t.Errorf("Expected 2 workflows, got %v", matchedIDs(matched))
+						return
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// TestRouteEvent_WorkflowIndex tests that routing through the index does not list workflows per event
+func TestRouteEvent_WorkflowIndex(t *testing.T) {
+	var lists int64
+	workflowRepo := countingWorkflowRepo(&lists, func() ([]models.Workflow, error) {
+		return []models.Workflow{indexedWorkflow("skipped", "event", "order.shipped")}, nil
+	})
+	log := logger.NewForTesting()
+	router := NewEventRouter(workflowRepo, &mockEventRepo{}, nil, log)
+	router.SetWorkflowIndex(NewWorkflowIndex(workflowRepo, log, 0))
+
+	ctx := context.Background()
+	orgID := uuid.New()
+	for i := 0; i < 3; i++ {
+		if _, err := router.RouteEvent(ctx, orgID, "order.created", "test-source", map[string]interface{}{}); err != nil {
+			t.Fatalf("RouteEvent failed: %v", err)
+		}
+	}
+	if lists != 1 {
+		t.Errorf("Expected workflows to be listed once, got %d lists", lists)
+	}
+
+	router.InvalidateWorkflows(orgID)
+	if _, err := router.RouteEvent(ctx, orgID, "order.created", "test-source", map[string]interface{}{}); err != nil {
+		t.Fatalf("RouteEvent failed: %v", err)
+	}
+	if lists != 2 {
+		t.Errorf("Expected workflows to be listed again after invalidation, got %d lists", lists)
+	}
+}
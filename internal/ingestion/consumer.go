@@ -0,0 +1,234 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/correlation"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// defaultRetryDelay is how long the consumer waits before fetching again after a failed fetch
+const defaultRetryDelay = 5 * time.Second
+
+var (
+	// ErrMissingEventType is returned for messages without an event type
+	ErrMissingEventType = errors.New("message has no event_type")
+	// ErrMissingPayload is returned for messages without a payload object
+	ErrMissingPayload = errors.New("message has no payload")
+	// ErrMissingOrganization is returned for messages naming no organization when the consumer has no default
+	ErrMissingOrganization = errors.New("message has no organization_id")
+)
+
+// Message is a message read from a broker
+type Message struct {
+	// Subject is the topic or subject the message was published to
+	Subject string
+	// Data is the message body
+	Data []byte
+	// ID identifies the message to its source, which commits it by ID
+	ID string
+	// Header holds the message's headers, if the broker supports them
+	Header textproto.MIMEHeader
+}
+
+// Source is a broker events are consumed from. Messages are delivered at least once: a message
+// that is fetched but never committed is delivered again, to this or another consumer in the group
+type Source interface {
+	// Name identifies the source in logs and in the source of events without one
+	Name() string
+	// Fetch waits for the next messages, returning none if nothing arrived in time
+	Fetch(ctx context.Context) ([]Message, error)
+	// Commit marks a message processed, so it is not delivered again
+	Commit(ctx context.Context, message Message) error
+	// Close disconnects from the broker
+	Close() error
+}
+
+// EventRouter defines the interface for routing events to workflows
+type EventRouter interface {
+	RouteEvent(ctx context.Context, organizationID uuid.UUID, eventType string, source string, payload map[string]interface{}) (*models.Event, error)
+}
+
+// envelope is the JSON body of an event message, shaped like the HTTP event request
+type envelope struct {
+	EventType      string                 `json:"event_type"`
+	Source         string                 `json:"source"`
+	OrganizationID uuid.UUID              `json:"organization_id"`
+	Payload        map[string]interface{} `json:"payload"`
+}
+
+// Consumer reads event messages from a source and routes them to workflows. A message is
+// committed only once it is routed, so messages in flight when the process stops are delivered
+// again rather than lost
+type Consumer struct {
+	source         Source
+	router         EventRouter
+	organizationID uuid.UUID
+	logger         *logger.Logger
+	retryDelay     time.Duration
+	stopCh         chan struct{}
+	doneCh         chan struct{}
+}
+
+// NewConsumer creates a consumer routing messages from source. Messages naming no organization
+// are routed to organizationID; with uuid.Nil, they are discarded
+func NewConsumer(source Source, router EventRouter, organizationID uuid.UUID, log *logger.Logger) *Consumer {
+	return &Consumer{
+		source:         source,
+		router:         router,
+		organizationID: organizationID,
+		logger:         log,
+		retryDelay:     defaultRetryDelay,
+		stopCh:         make(chan struct{}),
+		doneCh:         make(chan struct{}),
+	}
+}
+
+// Start starts consuming in the background
+func (c *Consumer) Start(ctx context.Context) {
+	c.logger.Info("Starting event consumer", logger.String("source", c.source.Name()))
+
+	go c.run(ctx)
+}
+
+// Stop stops consuming gracefully. The message being routed is finished and committed; the rest
+// of its batch is left for redelivery
+func (c *Consumer) Stop() {
+	c.logger.Info("Stopping event consumer", logger.String("source", c.source.Name()))
+	close(c.stopCh)
+	<-c.doneCh
+	c.logger.Info("Event consumer stopped", logger.String("source", c.source.Name()))
+}
+
+// run is the main consuming loop
+func (c *Consumer) run(ctx context.Context) {
+	defer close(c.doneCh)
+	defer func() {
+		if err := c.source.Close(); err != nil {
+			c.logger.Warn("Failed to close event source", logger.String("source", c.source.Name()), zap.Error(err))
+		}
+	}()
+
+	// Stopping abandons a pending fetch, but not a message being routed
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.stopCh:
+			cancel()
+		case <-fetchCtx.Done():
+		}
+	}()
+
+	for {
+		messages, err := c.source.Fetch(fetchCtx)
+		if fetchCtx.Err() != nil {
+			return
+		}
+		if err != nil {
+			c.logger.Error("Failed to fetch event messages",
+				logger.String("source", c.source.Name()),
+				zap.Error(err),
+			)
+			select {
+			case <-time.After(c.retryDelay):
+				continue
+			case <-fetchCtx.Done():
+				return
+			}
+		}
+
+		for _, message := range messages {
+			if fetchCtx.Err() != nil {
+				return
+			}
+			c.process(ctx, message)
+		}
+	}
+}
+
+// process routes a message and commits it. Malformed messages are committed without routing,
+// since delivering them again would fail the same way
+func (c *Consumer) process(ctx context.Context, message Message) {
+	event, err := c.decode(message)
+	if err != nil {
+		c.logger.Warn("Discarding malformed event message",
+			logger.String("source", c.source.Name()),
+			logger.String("subject", message.Subject),
+			zap.Error(err),
+		)
+		c.commit(ctx, message)
+		return
+	}
+
+	// Keep the publisher's correlation ID; without one, routing generates a new one
+	if id := message.Header.Get(correlation.Header); correlation.Valid(id) {
+		ctx = correlation.WithID(ctx, id)
+	}
+
+	routed, err := c.router.RouteEvent(ctx, event.OrganizationID, event.EventType, event.Source, event.Payload)
+	if routed == nil {
+		// The event was not recorded, so leave the message to be delivered again
+		c.logger.Error("Failed to route event message; it will be redelivered",
+			logger.String("source", c.source.Name()),
+			logger.String("event_type", event.EventType),
+			zap.Error(err),
+		)
+		return
+	}
+	if err != nil {
+		// The event was recorded and dead-lettered, where it can be replayed
+		c.logger.Warn("Event message was dead-lettered",
+			logger.String("event_id", routed.EventID),
+			zap.Error(err),
+		)
+	}
+
+	c.commit(ctx, message)
+}
+
+// commit commits a message, leaving it to be delivered again if the commit fails
+func (c *Consumer) commit(ctx context.Context, message Message) {
+	if err := c.source.Commit(ctx, message); err != nil {
+		c.logger.Error("Failed to commit event message",
+			logger.String("source", c.source.Name()),
+			logger.String("subject", message.Subject),
+			zap.Error(err),
+		)
+	}
+}
+
+// decode maps a message to the event it carries. Events without a source are attributed to the
+// broker subject they were published to
+func (c *Consumer) decode(message Message) (*envelope, error) {
+	var event envelope
+	if err := json.Unmarshal(message.Data, &event); err != nil {
+		return nil, fmt.Errorf("invalid event message: %w", err)
+	}
+
+	if event.EventType == "" {
+		return nil, ErrMissingEventType
+	}
+	if event.Payload == nil {
+		return nil, ErrMissingPayload
+	}
+	if event.OrganizationID == uuid.Nil {
+		event.OrganizationID = c.organizationID
+	}
+	if event.OrganizationID == uuid.Nil {
+		return nil, ErrMissingOrganization
+	}
+	if event.Source == "" {
+		event.Source = fmt.Sprintf("%s:%s", c.source.Name(), message.Subject)
+	}
+
+	return &event, nil
+}
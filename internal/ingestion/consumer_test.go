@@ -0,0 +1,229 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/correlation"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// fakeSource hands out its batches in order, then blocks until the fetch is cancelled
+type fakeSource struct {
+	mu        sync.Mutex
+	batches   [][]Message
+	committed []string
+	closed    bool
+
+	// drained is closed once a fetch finds no batches left
+	drained     chan struct{}
+	drainedOnce sync.Once
+}
+
+func newFakeSource(batches ...[]Message) *fakeSource {
+	return &fakeSource{batches: batches, drained: make(chan struct{})}
+}
+
+func (f *fakeSource) Name() string {
+	return "fake"
+}
+
+func (f *fakeSource) Fetch(ctx context.Context) ([]Message, error) {
+	f.mu.Lock()
+	if len(f.batches) > 0 {
+		batch := f.batches[0]
+		f.batches = f.batches[1:]
+		f.mu.Unlock()
+		return batch, nil
+	}
+	f.mu.Unlock()
+
+	f.drainedOnce.Do(func() { close(f.drained) })
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (f *fakeSource) Commit(ctx context.Context, message Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.committed = append(f.committed, message.ID)
+	return nil
+}
+
+func (f *fakeSource) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakeSource) commits() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.committed...)
+}
+
+// routedEvent records a RouteEvent call
+type routedEvent struct {
+	organizationID uuid.UUID
+	eventType      string
+	source         string
+	payload        map[string]interface{}
+	correlationID  string
+}
+
+// fakeRouter records events, failing for event types in fail and dead-lettering those in deadLetter
+type fakeRouter struct {
+	mu         sync.Mutex
+	routed     []routedEvent
+	fail       map[string]bool
+	deadLetter map[string]bool
+}
+
+func (f *fakeRouter) RouteEvent(ctx context.Context, organizationID uuid.UUID, eventType string, source string, payload map[string]interface{}) (*models.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fail[eventType] {
+		return nil, errors.New("failed to create event")
+	}
+	f.routed = append(f.routed, routedEvent{organizationID, eventType, source, payload, correlation.FromContext(ctx)})
+
+	event := &models.Event{ID: uuid.New(), EventID: "evt_test", EventType: eventType}
+	if f.deadLetter[eventType] {
+		return event, errors.New("failed to find matching workflows")
+	}
+	return event, nil
+}
+
+// consumeAll runs a consumer until it has processed every batch the source hands out
+func consumeAll(t *testing.T, source *fakeSource, router *fakeRouter, organizationID uuid.UUID) {
+	t.Helper()
+
+	consumer := NewConsumer(source, router, organizationID, logger.NewForTesting())
+	consumer.Start(context.Background())
+
+	select {
+	case <-source.drained:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Consumer did not process every batch")
+	}
+	consumer.Stop()
+}
+
+func TestConsumer_RoutesAndCommits(t *testing.T) {
+	organizationID := uuid.New()
+	source := newFakeSource([]Message{
+		{
+			Subject: "orders",
+			ID:      "1",
+			Data:    []byte(`{"event_type":"order.created","source":"shop","payload":{"total":10}}`),
+			Header:  textproto.MIMEHeader{"X-Correlation-Id": {"req-42"}},
+		},
+		{Subject: "orders", ID: "2", Data: []byte(`{"event_type":"order.paid","payload":{}}`)},
+	})
+	router := &fakeRouter{}
+
+	consumeAll(t, source, router, organizationID)
+
+	if len(router.routed) != 2 {
+		t.Fatalf("Expected 2 routed events, got %d", len(router.routed))
+	}
+	first := router.routed[0]
+	if first.eventType != "order.created" || first.source != "shop" || first.organizationID != organizationID {
+		t.Errorf("Unexpected routed event: %+v", first)
+	}
+	if first.payload["total"] != float64(10) {
+		t.Errorf("Expected payload to be routed, got %v", first.payload)
+	}
+	if router.routed[1].source != "fake:orders" {
+		t.Errorf("Expected source to default to the subject, got %s", router.routed[1].source)
+	}
+	if first.correlationID != "req-42" || router.routed[1].correlationID != "" {
+		t.Errorf("Expected only the first event to be routed with a correlation ID, got %q and %q", first.correlationID, router.routed[1].correlationID)
+	}
+
+	if commits := source.commits(); len(commits) != 2 || commits[0] != "1" || commits[1] != "2" {
+		t.Errorf("Expected both messages committed in order, got %v", commits)
+	}
+	if !source.closed {
+		t.Error("Expected source to be closed on stop")
+	}
+}
+
+func TestConsumer_MessageOrganization(t *testing.T) {
+	messageOrganization := uuid.New()
+	source := newFakeSource([]Message{
+		{ID: "1", Data: []byte(`{"event_type":"order.created","organization_id":"` + messageOrganization.String() + `","payload":{}}`)},
+	})
+	router := &fakeRouter{}
+
+	consumeAll(t, source, router, uuid.New())
+
+	if len(router.routed) != 1 || router.routed[0].organizationID != messageOrganization {
+		t.Errorf("Expected the message's organization to be used, got %+v", router.routed)
+	}
+}
+
+func TestConsumer_DiscardsMalformedMessages(t *testing.T) {
+	source := newFakeSource([]Message{
+		{ID: "invalid", Data: []byte(`not json`)},
+		{ID: "no-type", Data: []byte(`{"payload":{}}`)},
+		{ID: "no-payload", Data: []byte(`{"event_type":"order.created"}`)},
+		{ID: "no-organization", Data: []byte(`{"event_type":"order.created","payload":{}}`)},
+	})
+	router := &fakeRouter{}
+
+	// Without a default organization, messages must name theirs
+	consumeAll(t, source, router, uuid.Nil)
+
+	if len(router.routed) != 0 {
+		t.Errorf("Expected no events routed, got %+v", router.routed)
+	}
+	if commits := source.commits(); len(commits) != 4 {
+		t.Errorf("Expected malformed messages to be committed, got %v", commits)
+	}
+}
+
+func TestConsumer_LeavesUnroutedMessagesUncommitted(t *testing.T) {
+	source := newFakeSource([]Message{
+		{ID: "failed", Data: []byte(`{"event_type":"order.failed","payload":{}}`)},
+		{ID: "dead-lettered", Data: []byte(`{"event_type":"order.dead","payload":{}}`)},
+	})
+	router := &fakeRouter{
+		fail:       map[string]bool{"order.failed": true},
+		deadLetter: map[string]bool{"order.dead": true},
+	}
+
+	consumeAll(t, source, router, uuid.New())
+
+	// The failed event was never recorded, so it must be delivered again; the dead-lettered one
+	// was recorded and can be replayed
+	if commits := source.commits(); len(commits) != 1 || commits[0] != "dead-lettered" {
+		t.Errorf("Expected only the dead-lettered message committed, got %v", commits)
+	}
+}
+
+func TestConsumer_StopWhileFetching(t *testing.T) {
+	source := newFakeSource()
+	consumer := NewConsumer(source, &fakeRouter{}, uuid.New(), logger.NewForTesting())
+	consumer.Start(context.Background())
+
+	stopped := make(chan struct{})
+	go func() {
+		consumer.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not abandon the pending fetch")
+	}
+}
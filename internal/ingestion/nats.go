@@ -0,0 +1,197 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// natsDialTimeout bounds connecting to the server and setting up the consumer
+const natsDialTimeout = 10 * time.Second
+
+// NATSSource consumes events from a JetStream stream through a durable pull consumer. Every
+// instance pulling from the same consumer gets a share of the stream's messages, and a message
+// left unacknowledged for the consumer's ack wait is delivered again. The consumer is created
+// if it does not exist; an existing one is used as configured.
+//
+// The source is used by one consumer at a time
+type NATSSource struct {
+	cfg       *config.NATSConfig
+	batchSize int
+	fetchWait time.Duration
+	logger    *logger.Logger
+
+	conn     *nats.Conn
+	consumer jetstream.Consumer
+	// pending holds the messages of the last fetch that are not yet committed, by ID
+	pending map[string]jetstream.Msg
+}
+
+// NewNATSSource creates a source fetching up to batchSize messages at a time, each fetch waiting
+// up to fetchWait for messages to arrive. It connects on the first fetch
+func NewNATSSource(cfg *config.NATSConfig, batchSize int, fetchWait time.Duration, log *logger.Logger) *NATSSource {
+	return &NATSSource{
+		cfg:       cfg,
+		batchSize: batchSize,
+		fetchWait: fetchWait,
+		logger:    log,
+	}
+}
+
+// Name identifies the source
+func (s *NATSSource) Name() string {
+	return "nats"
+}
+
+// Fetch pulls the next batch of messages, returning early with what arrived when the pull
+// request expires. A connection the client gave up reconnecting is made again on the next fetch
+func (s *NATSSource) Fetch(ctx context.Context) ([]Message, error) {
+	if s.conn == nil || s.conn.IsClosed() {
+		if err := s.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	batch, err := s.consumer.Fetch(s.batchSize, jetstream.FetchMaxWait(s.fetchWait))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from NATS: %w", err)
+	}
+
+	// Messages of earlier fetches left uncommitted are delivered again after the ack wait
+	s.pending = make(map[string]jetstream.Msg, s.batchSize)
+	messages := make([]Message, 0, s.batchSize)
+	for {
+		select {
+		case msg, ok := <-batch.Messages():
+			if !ok {
+				if err := batch.Error(); err != nil {
+					return nil, fmt.Errorf("pull request failed: %w", err)
+				}
+				return messages, nil
+			}
+
+			// Read headers as MIME headers, so they are looked up regardless of case
+			header := make(textproto.MIMEHeader, len(msg.Headers()))
+			for key, values := range msg.Headers() {
+				for _, value := range values {
+					header.Add(key, value)
+				}
+			}
+
+			s.pending[msg.Reply()] = msg
+			messages = append(messages, Message{Subject: msg.Subject(), Data: msg.Data(), ID: msg.Reply(), Header: header})
+		case <-ctx.Done():
+			// Messages read so far were not acknowledged and will be delivered again
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Commit acknowledges a message
+func (s *NATSSource) Commit(ctx context.Context, message Message) error {
+	msg, ok := s.pending[message.ID]
+	if !ok {
+		return fmt.Errorf("message %s was not fetched by the last fetch", message.ID)
+	}
+	if err := msg.Ack(); err != nil {
+		return fmt.Errorf("failed to acknowledge NATS message: %w", err)
+	}
+	delete(s.pending, message.ID)
+	return nil
+}
+
+// Close disconnects from the server
+func (s *NATSSource) Close() error {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	s.consumer = nil
+	s.pending = nil
+	return nil
+}
+
+// connect connects to the server and looks up the durable consumer, creating it if it does
+// not exist
+func (s *NATSSource) connect(ctx context.Context) error {
+	options := []nats.Option{
+		nats.Name("intelligent-workflows"),
+		nats.Timeout(natsDialTimeout),
+		nats.SetCustomDialer(contextDialer{ctx: ctx}),
+	}
+	if s.cfg.User != "" {
+		options = append(options, nats.UserInfo(s.cfg.User, s.cfg.Password))
+	}
+	if s.cfg.Token != "" {
+		options = append(options, nats.Token(s.cfg.Token))
+	}
+
+	conn, err := nats.Connect(s.cfg.URL, options...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open JetStream: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, natsDialTimeout)
+	defer cancel()
+
+	consumer, err := js.Consumer(ctx, s.cfg.Stream, s.cfg.Consumer)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) {
+		consumer, err = js.CreateConsumer(ctx, s.cfg.Stream, jetstream.ConsumerConfig{
+			Durable:       s.cfg.Consumer,
+			AckPolicy:     jetstream.AckExplicitPolicy,
+			AckWait:       s.cfg.AckWait,
+			DeliverPolicy: jetstream.DeliverAllPolicy,
+			FilterSubject: s.cfg.Subject,
+		})
+		if err == nil {
+			s.logger.Info("Created NATS consumer",
+				logger.String("stream", s.cfg.Stream),
+				logger.String("consumer", s.cfg.Consumer),
+			)
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to set up NATS consumer: %w", err)
+	}
+
+	s.conn = conn
+	s.consumer = consumer
+
+	s.logger.Info("Connected to NATS",
+		logger.String("url", conn.ConnectedUrlRedacted()),
+		logger.String("stream", s.cfg.Stream),
+		logger.String("consumer", s.cfg.Consumer),
+	)
+	return nil
+}
+
+// contextDialer dials connections that are closed once ctx is done, so stopping the consumer
+// abandons a connection attempt the server does not answer
+type contextDialer struct {
+	ctx context.Context
+}
+
+func (d contextDialer) Dial(network, address string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: natsDialTimeout}
+	conn, err := dialer.DialContext(d.ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	context.AfterFunc(d.ctx, func() { conn.Close() })
+	return conn, nil
+}
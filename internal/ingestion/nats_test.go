@@ -0,0 +1,204 @@
+package ingestion
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// newTestJetStream starts an in-process NATS server with JetStream and an "events" stream of
+// orders.> subjects, returning the server's URL and a JetStream client for the test to use
+func newTestJetStream(t *testing.T) (string, jetstream.JetStream) {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create NATS server: %v", err)
+	}
+	go srv.Start()
+	t.Cleanup(srv.Shutdown)
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+
+	conn, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Failed to connect to NATS: %v", err)
+	}
+	t.Cleanup(conn.Close)
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		t.Fatalf("Failed to open JetStream: %v", err)
+	}
+	if _, err := js.CreateStream(context.Background(), jetstream.StreamConfig{Name: "events", Subjects: []string{"orders.>"}}); err != nil {
+		t.Fatalf("Failed to create stream: %v", err)
+	}
+
+	return srv.ClientURL(), js
+}
+
+func TestNATSSource_FetchAndCommit(t *testing.T) {
+	url, js := newTestJetStream(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := js.Publish(ctx, "orders.created", []byte(`{"event_type":"order.created"}`)); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	paid := nats.NewMsg("orders.paid")
+	paid.Data = []byte(`{"event_type":"order.paid"}`)
+	paid.Header.Set("X-Correlation-ID", "req-42")
+	if _, err := js.PublishMsg(ctx, paid); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	source := NewNATSSource(&config.NATSConfig{
+		URL:      url,
+		Stream:   "events",
+		Consumer: "workers",
+		Subject:  "orders.>",
+		AckWait:  time.Second,
+	}, 10, 200*time.Millisecond, logger.NewForTesting())
+	defer source.Close()
+
+	messages, err := source.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	// The missing consumer is created as a durable, explicitly acknowledged consumer
+	consumer, err := js.Consumer(ctx, "events", "workers")
+	if err != nil {
+		t.Fatalf("Expected the consumer to be created: %v", err)
+	}
+	if cfg := consumer.CachedInfo().Config; cfg.Durable != "workers" || cfg.AckPolicy != jetstream.AckExplicitPolicy || cfg.FilterSubject != "orders.>" {
+		t.Errorf("Unexpected consumer config: %+v", cfg)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages before the pull request expired, got %d", len(messages))
+	}
+	if messages[0].Subject != "orders.created" || string(messages[0].Data) != `{"event_type":"order.created"}` {
+		t.Errorf("Unexpected message: %+v", messages[0])
+	}
+	if string(messages[1].Data) != `{"event_type":"order.paid"}` || messages[1].Header.Get("X-Correlation-ID") != "req-42" {
+		t.Errorf("Expected the message's headers to be read apart from its data, got %+v", messages[1])
+	}
+
+	// Only the committed message is acknowledged; the other is delivered again after the ack wait
+	if err := source.Commit(ctx, messages[0]); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if err := source.Commit(ctx, messages[0]); err == nil {
+		t.Error("Expected committing a message twice to fail")
+	}
+
+	messages, err = source.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("Expected no messages before the ack wait, got %d", len(messages))
+	}
+
+	time.Sleep(time.Second)
+	messages, err = source.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Subject != "orders.paid" {
+		t.Fatalf("Expected the uncommitted message to be delivered again, got %+v", messages)
+	}
+	if err := source.Commit(ctx, messages[0]); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+}
+
+func TestNATSSource_UsesExistingConsumer(t *testing.T) {
+	url, js := newTestJetStream(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := js.CreateConsumer(ctx, "events", jetstream.ConsumerConfig{
+		Durable:       "workers",
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		FilterSubject: "orders.paid",
+	}); err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	for _, subject := range []string{"orders.created", "orders.paid"} {
+		if _, err := js.Publish(ctx, subject, []byte(`{}`)); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+
+	source := NewNATSSource(&config.NATSConfig{
+		URL:      url,
+		Stream:   "events",
+		Consumer: "workers",
+		Subject:  "orders.>",
+		AckWait:  time.Second,
+	}, 10, 200*time.Millisecond, logger.NewForTesting())
+	defer source.Close()
+
+	messages, err := source.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Subject != "orders.paid" {
+		t.Errorf("Expected the existing consumer's filter to apply, got %+v", messages)
+	}
+}
+
+func TestNATSSource_FetchCancelled(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	// The server accepts the connection but never greets it
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	source := NewNATSSource(&config.NATSConfig{
+		URL:      "nats://" + listener.Addr().String(),
+		Stream:   "events",
+		Consumer: "workers",
+	}, 10, time.Second, logger.NewForTesting())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	if _, err := source.Fetch(ctx); err == nil {
+		t.Fatal("Expected a cancelled fetch to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the cancelled fetch to give up connecting, took %s", elapsed)
+	}
+	if source.conn != nil {
+		t.Error("Expected the failed connection to be dropped")
+	}
+}
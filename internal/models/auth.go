@@ -27,12 +27,19 @@ type User struct {
 
 // Role represents a user role
 type Role struct {
-	ID          uuid.UUID    `json:"id" db:"id"`
-	Name        string       `json:"name" db:"name"`
-	Description *string      `json:"description,omitempty" db:"description"`
-	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at"`
-	Permissions []Permission `json:"permissions,omitempty" db:"-"` // Loaded separately
+	ID          uuid.UUID `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description *string   `json:"description,omitempty" db:"description"`
+	// OrganizationID is the organization that created a custom role, nil for built-in roles
+	OrganizationID *uuid.UUID   `json:"organization_id,omitempty" db:"organization_id"`
+	CreatedAt      time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at" db:"updated_at"`
+	Permissions    []Permission `json:"permissions,omitempty" db:"-"` // Loaded separately
+}
+
+// IsBuiltIn reports whether the role is one of the built-in roles, which cannot be modified
+func (r *Role) IsBuiltIn() bool {
+	return r.OrganizationID == nil
 }
 
 // Permission represents a specific permission
@@ -84,6 +91,10 @@ type RefreshToken struct {
 	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	// FamilyID is the ID of the token issued at login that this token was rotated from
+	FamilyID uuid.UUID `json:"family_id" db:"family_id"`
+	// ReplacedBy is the token this token was rotated into when it was used
+	ReplacedBy *uuid.UUID `json:"replaced_by,omitempty" db:"replaced_by"`
 }
 
 // RateLimit represents rate limit tracking
@@ -108,11 +119,17 @@ type LoginAttempt struct {
 
 // Request/Response DTOs
 
+// FieldError is a request field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 // RegisterRequest represents a user registration request
 type RegisterRequest struct {
 	Username  string  `json:"username" validate:"required,min=3,max=50"`
 	Email     string  `json:"email" validate:"required,email"`
-	Password  string  `json:"password" validate:"required,min=8"`
+	Password  string  `json:"password" validate:"required"`
 	FirstName *string `json:"first_name,omitempty"`
 	LastName  *string `json:"last_name,omitempty"`
 }
@@ -164,7 +181,7 @@ type UpdateUserRequest struct {
 // ChangePasswordRequest represents a password change request
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" validate:"required"`
-	NewPassword string `json:"new_password" validate:"required,min=8"`
+	NewPassword string `json:"new_password" validate:"required"`
 }
 
 // AssignRoleRequest represents a request to assign a role to a user
@@ -172,6 +189,18 @@ type AssignRoleRequest struct {
 	RoleID uuid.UUID `json:"role_id" validate:"required"`
 }
 
+// CreateRoleRequest represents a request to create a custom role
+type CreateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=50"`
+	Description *string  `json:"description,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// RolePermissionsRequest represents a request to attach permissions to a role
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions" validate:"required,min=1"`
+}
+
 // Claims represents JWT token claims
 type Claims struct {
 	UserID         uuid.UUID `json:"user_id"`
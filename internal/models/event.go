@@ -1,6 +1,8 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +19,8 @@ type Event struct {
 	TriggeredWorkflows []string   `json:"triggered_workflows,omitempty" db:"triggered_workflows"`
 	ReceivedAt         time.Time  `json:"received_at" db:"received_at"`
 	ProcessedAt        *time.Time `json:"processed_at,omitempty" db:"processed_at"`
+	// CorrelationID ties the event to the request that sent it and the executions it triggered
+	CorrelationID string `json:"correlation_id,omitempty" db:"correlation_id"`
 }
 
 // CreateEventRequest represents the request to emit an event
@@ -26,6 +30,59 @@ type CreateEventRequest struct {
 	Payload   map[string]interface{} `json:"payload" validate:"required"`
 }
 
+// DeadLetterReason describes why an event was dead-lettered
+type DeadLetterReason string
+
+const (
+	// DeadLetterReasonPanic is recorded when a workflow panicked while running for the event
+	DeadLetterReasonPanic DeadLetterReason = "panic"
+	// DeadLetterReasonExecutionError is recorded when a workflow could not start an execution for the event
+	DeadLetterReasonExecutionError DeadLetterReason = "execution_error"
+	// DeadLetterReasonRoutingError is recorded when the workflows matching the event could not be found
+	DeadLetterReasonRoutingError DeadLetterReason = "routing_error"
+	// DeadLetterReasonConcurrencyLimit is recorded when a workflow's concurrency limit rejected
+	// the execution started for the event
+	DeadLetterReasonConcurrencyLimit DeadLetterReason = "concurrency_limit"
+	// DeadLetterReasonRateLimited is recorded when a workflow's or organization's execution rate
+	// limit dropped the execution started for the event
+	DeadLetterReasonRateLimited DeadLetterReason = "rate_limited"
+)
+
+// DeadLetterEvent is an event that could not be processed, kept for replay. WorkflowID is
+// the workflow that failed, or nil if the event failed before it was routed
+type DeadLetterEvent struct {
+	ID             uuid.UUID        `json:"id" db:"id"`
+	OrganizationID uuid.UUID        `json:"organization_id" db:"organization_id"`
+	EventID        *uuid.UUID       `json:"event_id,omitempty" db:"event_id"`
+	EventType      string           `json:"event_type" db:"event_type"`
+	Source         string           `json:"source" db:"source"`
+	Payload        JSONB            `json:"payload" db:"payload"`
+	WorkflowID     *uuid.UUID       `json:"workflow_id,omitempty" db:"workflow_id"`
+	Reason         DeadLetterReason `json:"reason" db:"reason"`
+	Error          string           `json:"error" db:"error"`
+	FailedAt       time.Time        `json:"failed_at" db:"failed_at"`
+	ReplayedAt     *time.Time       `json:"replayed_at,omitempty" db:"replayed_at"`
+}
+
+// DeadLetterEventListResponse represents the response for listing dead-lettered events
+type DeadLetterEventListResponse struct {
+	Events   []*DeadLetterEvent `json:"events"`
+	Total    int64              `json:"total"`
+	Page     int                `json:"page"`
+	PageSize int                `json:"page_size"`
+}
+
+// SetWebhookSecretRequest represents a request to set the secret that signs events from a source
+type SetWebhookSecretRequest struct {
+	Secret string `json:"secret" validate:"required,min=16"`
+}
+
+// DryRunRequest represents a request to dry-run a workflow against a payload
+type DryRunRequest struct {
+	EventType string                 `json:"event_type"` // Defaults to the workflow's trigger event
+	Payload   map[string]interface{} `json:"payload" validate:"required"`
+}
+
 // ApprovalStatus represents the status of an approval request
 type ApprovalStatus string
 
@@ -53,6 +110,129 @@ type ApprovalRequest struct {
 	RequestedAt    time.Time      `json:"requested_at" db:"requested_at"`
 	DecidedAt      *time.Time     `json:"decided_at,omitempty" db:"decided_at"`
 	ExpiresAt      *time.Time     `json:"expires_at,omitempty" db:"expires_at"`
+
+	// Escalation
+	EscalationChain  ApprovalEscalationChain `json:"escalation_chain,omitempty" db:"escalation_chain"`
+	EscalationLevel  int                     `json:"escalation_level" db:"escalation_level"` // Levels of the chain escalated to so far
+	NextEscalationAt *time.Time              `json:"next_escalation_at,omitempty" db:"next_escalation_at"`
+	Escalations      ApprovalEscalations     `json:"escalations,omitempty" db:"escalations"`
+
+	// Quorum
+	RequiredApprovers []uuid.UUID        `json:"required_approvers,omitempty" db:"required_approvers"` // Only these users may decide, if set
+	Quorum            int                `json:"quorum" db:"quorum"`                                   // Approvals needed to approve
+	Decisions         []ApprovalDecision `json:"decisions,omitempty" db:"-"`
+}
+
+// Individual approval decisions
+const (
+	ApprovalDecisionApprove = "approve"
+	ApprovalDecisionReject  = "reject"
+)
+
+// ApprovalDecision is one approver's decision on an approval request
+type ApprovalDecision struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	ApprovalID     uuid.UUID  `json:"approval_id" db:"approval_id"`
+	ApproverID     uuid.UUID  `json:"approver_id" db:"approver_id"`
+	DelegateID     *uuid.UUID `json:"delegate_id,omitempty" db:"delegate_id"` // Set when a delegate decided on the approver's behalf
+	Decision       string     `json:"decision" db:"decision"`                 // approve, reject
+	Reason         *string    `json:"reason,omitempty" db:"reason"`
+	DecidedAt      time.Time  `json:"decided_at" db:"decided_at"`
+}
+
+// ApprovalDelegation hands an approver's approvals to a delegate for a time window
+type ApprovalDelegation struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	OrganizationID uuid.UUID `json:"organization_id" db:"organization_id"`
+	DelegatorID    uuid.UUID `json:"delegator_id" db:"delegator_id"`
+	DelegateID     uuid.UUID `json:"delegate_id" db:"delegate_id"`
+	StartsAt       time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt         time.Time `json:"ends_at" db:"ends_at"`
+	Reason         *string   `json:"reason,omitempty" db:"reason"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// IsActive reports whether the delegation applies at the given time
+func (d *ApprovalDelegation) IsActive(at time.Time) bool {
+	return !at.Before(d.StartsAt) && at.Before(d.EndsAt)
+}
+
+// CreateApprovalDelegationRequest represents a request to delegate the caller's approvals
+type CreateApprovalDelegationRequest struct {
+	DelegateID uuid.UUID  `json:"delegate_id" validate:"required"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"` // Defaults to now
+	EndsAt     time.Time  `json:"ends_at" validate:"required"`
+	Reason     *string    `json:"reason,omitempty"`
+}
+
+// ApprovalEscalationLevel is a step of an approval's escalation chain: who is asked to decide
+// once the approval has gone undecided for After at the previous level
+type ApprovalEscalationLevel struct {
+	ApproverRole  string `json:"approver_role,omitempty"`
+	ApproverEmail string `json:"approver_email,omitempty"`
+	After         string `json:"after"`               // Duration with no decision before escalating, e.g. "4h"
+	ExtendBy      string `json:"extend_by,omitempty"` // Added to the approval's deadline on escalation, defaults to After
+}
+
+// ApprovalEscalationChain is the ordered list of levels an approval escalates through
+type ApprovalEscalationChain []ApprovalEscalationLevel
+
+// Scan implements the sql.Scanner interface for ApprovalEscalationChain
+func (c *ApprovalEscalationChain) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+
+	return json.Unmarshal(bytes, c)
+}
+
+// Value implements the driver.Valuer interface for ApprovalEscalationChain
+func (c ApprovalEscalationChain) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(c)
+}
+
+// ApprovalEscalation records an approval being escalated to the next level of its chain
+type ApprovalEscalation struct {
+	Level         int        `json:"level"` // 1 for the first level of the chain
+	FromRole      string     `json:"from_role"`
+	ToRole        string     `json:"to_role"`
+	ApproverEmail string     `json:"approver_email,omitempty"`
+	EscalatedAt   time.Time  `json:"escalated_at"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"` // The approval's deadline after escalating
+}
+
+// ApprovalEscalations is an approval's escalation history, oldest first
+type ApprovalEscalations []ApprovalEscalation
+
+// Scan implements the sql.Scanner interface for ApprovalEscalations
+func (e *ApprovalEscalations) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+
+	return json.Unmarshal(bytes, e)
+}
+
+// Value implements the driver.Valuer interface for ApprovalEscalations
+func (e ApprovalEscalations) Value() (driver.Value, error) {
+	if e == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(e)
 }
 
 // ApprovalDecisionRequest represents the request to approve/reject
@@ -85,3 +265,10 @@ type AuditLog struct {
 	Changes        JSONB      `json:"changes" db:"changes"`
 	Timestamp      time.Time  `json:"timestamp" db:"timestamp"`
 }
+
+// TriggerWorkflowRequest represents a request to run a workflow synchronously
+type TriggerWorkflowRequest struct {
+	Payload map[string]interface{} `json:"payload"`
+	// Version runs a recorded version of the workflow's definition; omitted or 0 runs the current one
+	Version int `json:"version,omitempty"`
+}
@@ -2,7 +2,10 @@ package models
 
 import (
 	"database/sql/driver"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,6 +23,9 @@ const (
 	ExecutionStatusBlocked   ExecutionStatus = "blocked"
 	ExecutionStatusCancelled ExecutionStatus = "cancelled"
 	ExecutionStatusPaused    ExecutionStatus = "paused"
+
+	// ExecutionStatusInterrupted marks executions still running when their process shut down
+	ExecutionStatusInterrupted ExecutionStatus = "interrupted"
 )
 
 // ExecutionResult represents the result of a workflow execution
@@ -50,6 +56,16 @@ type WorkflowExecution struct {
 	DurationMs     *int             `json:"duration_ms,omitempty" db:"duration_ms"`
 	ErrorMessage   *string          `json:"error_message,omitempty" db:"error_message"`
 	Metadata       JSONB            `json:"metadata,omitempty" db:"metadata"`
+	IdempotencyKey *string          `json:"idempotency_key,omitempty" db:"idempotency_key"`
+	// WorkflowVersion is the definition version the execution ran against; nil for executions
+	// recorded before versions were tracked
+	WorkflowVersion *int `json:"workflow_version,omitempty" db:"workflow_version"`
+	// ResultPayload is the structured output of the execution, set by response actions and by
+	// set variables marked as results. Unlike Context, it only holds what steps chose to return
+	ResultPayload JSONB `json:"result_payload,omitempty" db:"result_payload"`
+	// CorrelationID is shared with the event or request that started the execution, and with the
+	// executions it calls; it is logged with the execution and forwarded on outbound requests
+	CorrelationID string `json:"correlation_id,omitempty" db:"correlation_id"`
 
 	// Timeout enforcement fields
 	TimeoutAt       *time.Time `json:"timeout_at,omitempty" db:"timeout_at"`
@@ -63,14 +79,61 @@ type WorkflowExecution struct {
 	ResumeData    JSONB      `json:"resume_data,omitempty" db:"resume_data"`
 	ResumeCount   int        `json:"resume_count" db:"resume_count"`
 	LastResumedAt *time.Time `json:"last_resumed_at,omitempty" db:"last_resumed_at"`
+	ApprovalID    *uuid.UUID `json:"approval_id,omitempty" db:"approval_id"` // Approval the execution is paused for
+}
+
+// ExecutionResponse is the HTTP response a workflow's response action returns to a synchronous caller
+type ExecutionResponse struct {
+	StatusCode int                    `json:"status_code"`
+	Body       map[string]interface{} `json:"body,omitempty"`
+}
+
+// Response returns the response recorded by the execution's last response action, or nil if none ran
+func (e *WorkflowExecution) Response() *ExecutionResponse {
+	raw, ok := e.Metadata["response"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	resp := &ExecutionResponse{StatusCode: 200}
+	switch code := raw["status_code"].(type) {
+	case int:
+		resp.StatusCode = code
+	case float64:
+		resp.StatusCode = int(code)
+	}
+	if body, ok := raw["body"].(map[string]interface{}); ok {
+		resp.Body = body
+	}
+
+	return resp
 }
 
 // WaitState represents the state of a waiting execution
 type WaitState struct {
-	Event        string     `json:"event"`
+	Event        string     `json:"event"`            // First accepted event, kept for backward compatibility
+	Events       []string   `json:"events,omitempty"` // All accepted events when the wait step lists alternatives
 	TimeoutAt    *time.Time `json:"timeout_at,omitempty"`
 	OnTimeout    string     `json:"on_timeout,omitempty"`
 	WaitingSince time.Time  `json:"waiting_since"`
+	// ResumeAt is when a delay step continues the execution. Delays wait for no event
+	ResumeAt *time.Time `json:"resume_at,omitempty"`
+}
+
+// Accepts reports whether the given event resumes the waiting execution
+func (w *WaitState) Accepts(event string) bool {
+	if w.ResumeAt != nil {
+		return false
+	}
+	if w.Event == event {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
 }
 
 // Scan implements the sql.Scanner interface for WaitState
@@ -103,6 +166,15 @@ const (
 	StepStatusSkipped   StepExecutionStatus = "skipped"
 )
 
+// IsValid reports whether the status is a known step execution status
+func (s StepExecutionStatus) IsValid() bool {
+	switch s {
+	case StepStatusPending, StepStatusRunning, StepStatusCompleted, StepStatusFailed, StepStatusSkipped:
+		return true
+	}
+	return false
+}
+
 // StepExecution represents an execution of a workflow step
 type StepExecution struct {
 	ID             uuid.UUID           `json:"id" db:"id"`
@@ -117,6 +189,7 @@ type StepExecution struct {
 	CompletedAt    *time.Time          `json:"completed_at,omitempty" db:"completed_at"`
 	DurationMs     *int                `json:"duration_ms,omitempty" db:"duration_ms"`
 	ErrorMessage   *string             `json:"error_message,omitempty" db:"error_message"`
+	Sequence       int64               `json:"sequence" db:"sequence"` // Creation order, breaking ties between steps that started together
 }
 
 // JSONB is a custom type for handling JSONB columns
@@ -158,6 +231,80 @@ type ExecutionListResponse struct {
 	Total      int64               `json:"total"`
 	Page       int                 `json:"page"`
 	PageSize   int                 `json:"page_size"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// StepExecutionListResponse represents a paginated list of an execution's step executions
+type StepExecutionListResponse struct {
+	Steps    []StepExecution `json:"steps"`
+	Total    int64           `json:"total"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"page_size"`
+}
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ExecutionCursor marks a position in the execution list, which is ordered newest first by (started_at, id)
+type ExecutionCursor struct {
+	StartedAt time.Time
+	ID        uuid.UUID
+}
+
+// Encode returns the opaque token handed to clients as next_cursor
+func (c ExecutionCursor) Encode() string {
+	raw := c.StartedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeExecutionCursor parses a token produced by ExecutionCursor.Encode
+func DecodeExecutionCursor(token string) (*ExecutionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	startedAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &ExecutionCursor{}
+	if cursor.StartedAt, err = time.Parse(time.RFC3339Nano, startedAt); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.ID, err = uuid.Parse(id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// RerunExecutionsRequest selects failed executions to re-run, either by ID or by filter
+type RerunExecutionsRequest struct {
+	ExecutionIDs []uuid.UUID  `json:"execution_ids,omitempty"`
+	Filter       *RerunFilter `json:"filter,omitempty"`
+}
+
+// RerunFilter selects failed executions that have not been re-run yet
+type RerunFilter struct {
+	WorkflowID    *uuid.UUID `json:"workflow_id,omitempty"`
+	TriggerEvent  *string    `json:"trigger_event,omitempty"`
+	StartedAfter  *time.Time `json:"started_after,omitempty"`
+	StartedBefore *time.Time `json:"started_before,omitempty"`
+}
+
+// RerunResult reports the outcome of re-running one execution
+type RerunResult struct {
+	ExecutionID uuid.UUID `json:"execution_id"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+// RerunExecutionsResponse summarizes a bulk re-run. Remaining counts filter matches beyond the batch size
+type RerunExecutionsResponse struct {
+	Requeued  []RerunResult `json:"requeued"`
+	Skipped   []RerunResult `json:"skipped"`
+	Remaining int64         `json:"remaining,omitempty"`
 }
 
 // ExecutionTraceResponse represents the trace of a workflow execution
@@ -1,9 +1,12 @@
 package models
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -247,3 +250,56 @@ func TestStepExecutionStatus_Constants(t *testing.T) {
 		uniqueStatuses[status] = true
 	}
 }
+
+func TestWorkflowExecution_Response(t *testing.T) {
+	t.Run("no response recorded", func(t *testing.T) {
+		execution := &WorkflowExecution{Metadata: JSONB{}}
+		assert.Nil(t, execution.Response())
+	})
+
+	t.Run("response loaded from JSON metadata", func(t *testing.T) {
+		// Values read back from the database decode numbers as float64
+		execution := &WorkflowExecution{Metadata: JSONB{
+			"response": map[string]interface{}{
+				"status_code": float64(202),
+				"body":        map[string]interface{}{"ok": true},
+			},
+		}}
+
+		resp := execution.Response()
+		assert.NotNil(t, resp)
+		assert.Equal(t, 202, resp.StatusCode)
+		assert.Equal(t, true, resp.Body["ok"])
+	})
+}
+
+func TestExecutionCursor_RoundTrip(t *testing.T) {
+	cursor := ExecutionCursor{
+		StartedAt: time.Date(2025, 3, 14, 9, 26, 53, 589793000, time.UTC),
+		ID:        uuid.New(),
+	}
+
+	decoded, err := DecodeExecutionCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.True(t, cursor.StartedAt.Equal(decoded.StartedAt))
+	assert.Equal(t, cursor.ID, decoded.ID)
+}
+
+func TestDecodeExecutionCursor_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "not base64", token: "!!!"},
+		{name: "missing separator", token: base64.RawURLEncoding.EncodeToString([]byte("2025-03-14T09:26:53Z"))},
+		{name: "bad timestamp", token: base64.RawURLEncoding.EncodeToString([]byte("yesterday|" + uuid.NewString()))},
+		{name: "bad id", token: base64.RawURLEncoding.EncodeToString([]byte("2025-03-14T09:26:53Z|not-a-uuid"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeExecutionCursor(tt.token)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package models
+
+// EmailMessage is an email sent by a workflow's email action. Recipients may be bare addresses
+// or RFC 5322 lists such as "Ops <ops@example.com>, billing@example.com"
+type EmailMessage struct {
+	To      []string `json:"to"`
+	CC      []string `json:"cc,omitempty"`
+	BCC     []string `json:"bcc,omitempty"`
+	Subject string   `json:"subject"`
+	Text    string   `json:"text,omitempty"` // Plain text body
+	HTML    string   `json:"html,omitempty"` // HTML body; sent as an alternative to Text when both are set
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RetentionPolicy is an organization's own retention window, overriding the configured default
+type RetentionPolicy struct {
+	OrganizationID uuid.UUID `json:"organization_id" db:"organization_id"`
+	RetentionDays  int       `json:"retention_days" db:"retention_days"` // 0 keeps executions forever
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RetentionSettings describes how long an organization's finished executions are kept
+type RetentionSettings struct {
+	Enabled              bool   `json:"enabled"`                // whether the retention worker runs
+	RetentionDays        int    `json:"retention_days"`         // window in effect for the organization; 0 keeps executions forever
+	DefaultRetentionDays int    `json:"default_retention_days"` // window of organizations without their own
+	Overridden           bool   `json:"overridden"`             // the organization sets its own window
+	PurgeAfter           string `json:"purge_after"`            // how long soft-deleted executions are kept before they are purged
+	ArchiveEnabled       bool   `json:"archive_enabled"`        // executions are exported to cold storage before they are purged
+}
+
+// SetRetentionPolicyRequest represents a request to set an organization's retention window
+type SetRetentionPolicyRequest struct {
+	RetentionDays *int `json:"retention_days" validate:"required,min=0,max=36500"`
+}
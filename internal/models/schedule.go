@@ -6,7 +6,23 @@ import (
 	"github.com/google/uuid"
 )
 
-// WorkflowSchedule represents a cron-based schedule for a workflow
+// MissedRunPolicy controls how a schedule handles runs that fell due while no scheduler was running
+type MissedRunPolicy string
+
+const (
+	// MissedRunPolicySkip fires once, for the most recent missed run
+	MissedRunPolicySkip MissedRunPolicy = "skip"
+	// MissedRunPolicyCatchUp fires once for every missed run, up to the scheduler's catch-up limit
+	MissedRunPolicyCatchUp MissedRunPolicy = "catch_up"
+)
+
+// IsValid reports whether the policy is a known missed run policy
+func (p MissedRunPolicy) IsValid() bool {
+	return p == MissedRunPolicySkip || p == MissedRunPolicyCatchUp
+}
+
+// WorkflowSchedule represents a schedule for a workflow. A recurring schedule runs on its cron
+// expression; a one-shot schedule has no cron expression and runs once at RunAt
 type WorkflowSchedule struct {
 	ID              uuid.UUID  `json:"id" db:"id"`
 	OrganizationID  uuid.UUID  `json:"organization_id" db:"organization_id"`
@@ -18,20 +34,35 @@ type WorkflowSchedule struct {
 	NextTriggerAt   *time.Time `json:"next_trigger_at,omitempty" db:"next_trigger_at"`
 	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+
+	// MissedRunPolicy decides how many runs to fire when the schedule is found overdue
+	MissedRunPolicy MissedRunPolicy `json:"missed_run_policy" db:"missed_run_policy"`
+	// RunAt is the time a one-shot schedule runs at; once it has run the schedule is disabled
+	RunAt *time.Time `json:"run_at,omitempty" db:"run_at"`
+}
+
+// IsOneShot reports whether the schedule runs once at RunAt rather than on a cron expression
+func (s *WorkflowSchedule) IsOneShot() bool {
+	return s.RunAt != nil
 }
 
-// CreateScheduleRequest represents the request body for creating a schedule
+// CreateScheduleRequest represents the request body for creating a schedule. Exactly one of
+// CronExpression and RunAt must be set
 type CreateScheduleRequest struct {
-	CronExpression string `json:"cron_expression" validate:"required"`
-	Timezone       string `json:"timezone"`
-	Enabled        *bool  `json:"enabled"`
+	CronExpression  string          `json:"cron_expression"`
+	RunAt           *time.Time      `json:"run_at"` // Must be in the future
+	Timezone        string          `json:"timezone"`
+	Enabled         *bool           `json:"enabled"`
+	MissedRunPolicy MissedRunPolicy `json:"missed_run_policy" validate:"omitempty,oneof=skip catch_up"` // Defaults to skip
 }
 
 // UpdateScheduleRequest represents the request body for updating a schedule
 type UpdateScheduleRequest struct {
-	CronExpression *string `json:"cron_expression"`
-	Timezone       *string `json:"timezone"`
-	Enabled        *bool   `json:"enabled"`
+	CronExpression  *string          `json:"cron_expression"` // Recurring schedules only
+	RunAt           *time.Time       `json:"run_at"`          // One-shot schedules only
+	Timezone        *string          `json:"timezone"`
+	Enabled         *bool            `json:"enabled"`
+	MissedRunPolicy *MissedRunPolicy `json:"missed_run_policy"`
 }
 
 // ScheduleListResponse represents the response for listing schedules
@@ -47,3 +78,13 @@ type NextRunsResponse struct {
 	ScheduleID uuid.UUID `json:"schedule_id"`
 	NextRuns   []time.Time `json:"next_runs"`
 }
+
+// SchedulePreviewResponse represents the upcoming runs of a schedule, in its timezone
+type SchedulePreviewResponse struct {
+	ScheduleID  uuid.UUID   `json:"schedule_id"`
+	WorkflowID  uuid.UUID   `json:"workflow_id"`
+	Description string      `json:"description"`
+	Timezone    string      `json:"timezone"`
+	Enabled     bool        `json:"enabled"`
+	NextRuns    []time.Time `json:"next_runs"`
+}
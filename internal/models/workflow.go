@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,14 +24,84 @@ type Workflow struct {
 	UpdatedAt      time.Time          `json:"updated_at" db:"updated_at"`
 	CreatedBy      *uuid.UUID         `json:"created_by,omitempty" db:"created_by"`
 	Tags           []string           `json:"tags,omitempty" db:"tags"`
+
+	// DefinitionVersion numbers the current definition in the workflow's version history
+	DefinitionVersion int `json:"definition_version" db:"definition_version"`
 }
 
 // WorkflowDefinition represents the complete workflow definition
 type WorkflowDefinition struct {
-	Trigger TriggerDefinition `json:"trigger"`
-	Context ContextDefinition `json:"context,omitempty"`
-	Steps   []Step            `json:"steps"`
-	Timeout string            `json:"timeout,omitempty"` // Global timeout duration, e.g., "5m", "1h", "30s"
+	Trigger       TriggerDefinition `json:"trigger"`
+	Context       ContextDefinition `json:"context,omitempty"`
+	Steps         []Step            `json:"steps"`
+	Timeout       string            `json:"timeout,omitempty"`         // Global timeout duration, e.g., "5m", "1h", "30s"
+	MaxStepVisits int               `json:"max_step_visits,omitempty"` // Max runs of any single step per execution (default 1000)
+	// MaxPausedDuration is how long a paused execution can still be resumed, e.g. "336h";
+	// defaults to the configured max paused duration
+	MaxPausedDuration string `json:"max_paused_duration,omitempty"`
+	// Concurrency limits how many executions of the workflow run at once
+	Concurrency *ConcurrencyDefinition `json:"concurrency,omitempty"`
+	// RateLimit caps how many executions of the workflow start per interval
+	RateLimit *RateLimitDefinition `json:"rate_limit,omitempty"`
+	// Priority is a CEL expression evaluated against the trigger payload, e.g. "10", ranking the
+	// workflow's executions queued by a rate limit; higher starts first. The trigger's overrides it
+	Priority string `json:"priority,omitempty"`
+}
+
+// ConcurrencyPolicy decides what happens to an execution started while its workflow's
+// concurrency limit is reached
+type ConcurrencyPolicy string
+
+const (
+	// ConcurrencyPolicyQueue waits for a running execution to finish (the default)
+	ConcurrencyPolicyQueue ConcurrencyPolicy = "queue"
+	// ConcurrencyPolicyReject fails the execution at once, dead-lettering the event it was
+	// started for so it can be replayed later
+	ConcurrencyPolicyReject ConcurrencyPolicy = "reject"
+)
+
+// RateLimitPolicy decides what happens to an execution started while its workflow's rate limit
+// is exhausted
+type RateLimitPolicy string
+
+const (
+	// RateLimitPolicyQueue waits for the bucket to refill (the default)
+	RateLimitPolicyQueue RateLimitPolicy = "queue"
+	// RateLimitPolicyDrop does not start the execution, dead-lettering the event it was started
+	// for with the reason
+	RateLimitPolicyDrop RateLimitPolicy = "drop"
+)
+
+// RateLimitDefinition caps how many executions of a workflow start per interval, to protect the
+// downstream systems it calls. Every execution takes a token from a bucket shared by all API
+// instances when it starts and again when it resumes, refilled with Rate tokens per Per
+type RateLimitDefinition struct {
+	// Rate is how many executions may start per Per
+	Rate int `json:"rate"`
+	// Per is the interval the rate applies to, e.g. "1s" (default "1m")
+	Per string `json:"per,omitempty"`
+	// Burst is how many executions may start at once after a quiet period (default Rate)
+	Burst int `json:"burst,omitempty"`
+	// Policy is queue (default) or drop
+	Policy RateLimitPolicy `json:"policy,omitempty"`
+	// QueueTimeout is how long a queued execution waits for a token before it is dropped,
+	// e.g. "10m"; defaults to the configured queue timeout
+	QueueTimeout string `json:"queue_timeout,omitempty"`
+}
+
+// ConcurrencyDefinition limits how many executions of a workflow run at once, across every
+// API instance. A running execution holds its slot until it completes, fails or pauses
+type ConcurrencyDefinition struct {
+	// Key is rendered against the trigger payload, e.g. "{{order.id}}", so the limit applies
+	// per entity; empty limits all executions of the workflow together
+	Key string `json:"key,omitempty"`
+	// Max is how many executions with the same key may run at once (default 1)
+	Max int `json:"max,omitempty"`
+	// Policy is queue (default) or reject
+	Policy ConcurrencyPolicy `json:"policy,omitempty"`
+	// QueueTimeout is how long a queued execution waits for a slot before it is rejected,
+	// e.g. "10m"; defaults to the configured queue timeout
+	QueueTimeout string `json:"queue_timeout,omitempty"`
 }
 
 // TriggerDefinition defines what starts the workflow
@@ -38,64 +110,184 @@ type TriggerDefinition struct {
 	Event string                 `json:"event,omitempty"`
 	Cron  string                 `json:"cron,omitempty"`
 	Data  map[string]interface{} `json:"data,omitempty"`
+	// Events lists further event patterns the trigger matches, alongside Event. In a pattern "*"
+	// matches one dot-separated segment and "**" zero or more, e.g. "order.**" matches "order.items.added"
+	Events []string `json:"events,omitempty"`
+	// Filter is evaluated against the event payload; events it rejects are recorded without
+	// running the workflow
+	Filter *Condition `json:"filter,omitempty"`
+	// IdempotencyKey is a payload path (e.g. "event_id") whose value deduplicates executions
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// Priority is a CEL expression evaluated against the event payload ranking executions queued
+	// by a rate limit, e.g. "order.total >= 1000 ? 10 : 0"; higher starts first
+	Priority string `json:"priority,omitempty"`
+}
+
+// EventPatterns returns the event patterns an event trigger matches
+func (t TriggerDefinition) EventPatterns() []string {
+	patterns := make([]string, 0, len(t.Events)+1)
+	if t.Event != "" {
+		patterns = append(patterns, t.Event)
+	}
+	return append(patterns, t.Events...)
 }
 
 // ContextDefinition defines what data to load
 type ContextDefinition struct {
 	Load []string `json:"load,omitempty"` // e.g., ["order.details", "customer.history"]
+	// Computed maps field names to CEL expressions stored under _computed, e.g. {"order_is_high_value": "order.total >= 10000"}.
+	// They add to, or replace, the computed fields configured for the deployment
+	Computed map[string]string `json:"computed,omitempty"`
 }
 
 // Step represents a workflow step
 type Step struct {
-	ID        string                 `json:"id"`
-	Type      string                 `json:"type"` // condition, action, parallel, foreach, execute, wait
-	RuleID    string                 `json:"rule_id,omitempty"`    // Reference to a named rule (for condition steps)
-	Condition *Condition             `json:"condition,omitempty"`
-	Action    *Action                `json:"action,omitempty"`
-	OnTrue    string                 `json:"on_true,omitempty"`
-	OnFalse   string                 `json:"on_false,omitempty"`
-	Parallel  *ParallelStep          `json:"parallel,omitempty"`
-	ForEach   *ForEachStep           `json:"foreach,omitempty"`
-	Execute   []ExecuteAction        `json:"execute,omitempty"`
-	Wait      *WaitConfig            `json:"wait,omitempty"`
-	Retry     *RetryConfig           `json:"retry,omitempty"`
-	Timeout   string                 `json:"timeout,omitempty"` // Step-level timeout, e.g., "30s", "2m"
-	Metadata  map[string]interface{} `json:"metadata,omitempty"`
-	Next      string                 `json:"next,omitempty"` // Next step ID for sequential flow
+	ID              string                 `json:"id"`
+	Type            string                 `json:"type"`              // condition, switch, action, parallel, foreach, execute, wait, delay, call, query, set
+	RuleID          string                 `json:"rule_id,omitempty"` // Reference to a named rule (for condition steps)
+	Condition       *Condition             `json:"condition,omitempty"`
+	Action          *Action                `json:"action,omitempty"`
+	OnTrue          string                 `json:"on_true,omitempty"`
+	OnFalse         string                 `json:"on_false,omitempty"`
+	Switch          *SwitchStep            `json:"switch,omitempty"`
+	Parallel        *ParallelStep          `json:"parallel,omitempty"`
+	ForEach         *ForEachStep           `json:"foreach,omitempty"`
+	Execute         []ExecuteAction        `json:"execute,omitempty"`
+	Wait            *WaitConfig            `json:"wait,omitempty"`
+	Delay           *DelayStep             `json:"delay,omitempty"`
+	Call            *CallStep              `json:"call,omitempty"`
+	Query           *QueryStep             `json:"query,omitempty"`
+	Set             []SetVariable          `json:"set,omitempty"`
+	Retry           *RetryConfig           `json:"retry,omitempty"`
+	Compensate      *CompensationConfig    `json:"compensate,omitempty"` // Undo actions run if a later step fails
+	Timeout         string                 `json:"timeout,omitempty"`    // Step-level timeout, e.g., "30s", "2m"
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	Next            string                 `json:"next,omitempty"`              // Next step ID for sequential flow
+	ContinueOnError bool                   `json:"continue_on_error,omitempty"` // Treat a failure as non-fatal and proceed to Next
+	OnError         string                 `json:"on_error,omitempty"`          // Step to run when this step fails (takes precedence over ContinueOnError)
 }
 
 // Condition represents a conditional expression
 type Condition struct {
 	Field    string      `json:"field"`
-	Operator string      `json:"operator"` // eq, neq, gt, gte, lt, lte, in, contains, regex
+	Operator string      `json:"operator"` // eq, neq, gt, gte, lt, lte, in, not_in, contains, starts_with, ends_with, regex, exists, is_null
 	Value    interface{} `json:"value"`
 	And      []Condition `json:"and,omitempty"`
 	Or       []Condition `json:"or,omitempty"`
+	// Expression is a CEL expression evaluated instead of field/operator/value when set
+	Expression string `json:"expression,omitempty"`
+	// OnMissing controls what happens when Field cannot be resolved: error (default), false, or skip
+	OnMissing string `json:"on_missing,omitempty"`
 }
 
+// Condition OnMissing policies
+const (
+	OnMissingError = "error" // fail the evaluation
+	OnMissingFalse = "false" // evaluate the condition as false
+	OnMissingSkip  = "skip"  // ignore the condition; within a group it doesn't count, alone it passes
+)
+
 // Action represents an action to take
 type Action struct {
-	Type     string                 `json:"action"` // allow, block, execute
-	Reason   string                 `json:"reason,omitempty"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Type       string                 `json:"action"` // allow, block, execute, response
+	Reason     string                 `json:"reason,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	StatusCode int                    `json:"status_code,omitempty"` // HTTP status returned by a response action (default 200)
+	Data       map[string]interface{} `json:"data,omitempty"`        // Response body for a response action; "${path}" values are read from context
+	Classify   *ClassifyAction        `json:"classify,omitempty"`    // Configuration of an ai_classify action
+	Extract    *ExtractAction         `json:"extract,omitempty"`     // Configuration of an ai_extract action
+}
+
+// ClassifyAction classifies text taken from the context into one of a set of labels with the
+// AI service. Unlike other actions, an ai_classify step continues to its next step
+type ClassifyAction struct {
+	Input        string   `json:"input"`                  // Text to classify, with {{path}} tokens, e.g., "{{ticket.subject}}: {{ticket.body}}"
+	Labels       []string `json:"labels"`                 // Candidate labels; the result is always one of them
+	OutputVar    string   `json:"output_var,omitempty"`   // Context variable receiving the label (defaults to the step ID)
+	Instructions string   `json:"instructions,omitempty"` // Optional guidance on how to choose between the labels
+}
+
+// ExtractAction extracts an object matching a JSON schema from text taken from the context with
+// the AI service. Like ai_classify, an ai_extract step continues to its next step
+type ExtractAction struct {
+	Input        string                 `json:"input"`                  // Text to extract from, with {{path}} tokens, e.g., "{{email.body}}"
+	Schema       map[string]interface{} `json:"schema"`                 // JSON schema of the object to extract; its type must be object
+	OutputVar    string                 `json:"output_var,omitempty"`   // Context variable receiving the object (defaults to the step ID)
+	Instructions string                 `json:"instructions,omitempty"` // Optional guidance on what to extract
+	Model        string                 `json:"model,omitempty"`        // Overrides the configured default model
+	Temperature  *float64               `json:"temperature,omitempty"`  // Overrides the provider's default temperature
+}
+
+// SwitchStep represents multi-way branching on a context value
+type SwitchStep struct {
+	Field   string       `json:"field"`             // Context path or {{expression}}, e.g., "order.status"
+	Cases   []SwitchCase `json:"cases"`             // Evaluated in order; the first match wins
+	Default string       `json:"default,omitempty"` // Next step when no case matches
+}
+
+// SwitchCase maps a value to the next step
+type SwitchCase struct {
+	Value interface{} `json:"value"`
+	Next  string      `json:"next"`
 }
 
 // ParallelStep represents parallel execution of steps
 type ParallelStep struct {
-	Steps    []Step `json:"steps"`
-	Strategy string `json:"strategy"` // all_must_pass, any_can_pass, best_effort
+	Steps       []Step `json:"steps"`
+	Strategy    string `json:"strategy"`               // all_must_pass, any_can_pass, best_effort
+	OnSuccess   string `json:"on_success,omitempty"`   // Next step when the strategy is satisfied (defaults to step.Next)
+	OnFailure   string `json:"on_failure,omitempty"`   // Next step when the strategy fails, or when any best_effort branch fails
+	CollectInto string `json:"collect_into,omitempty"` // Context variable receiving branch data in order (nil for failed or empty branches)
 }
 
 // ForEachStep represents iteration over a collection
 type ForEachStep struct {
-	Items   string `json:"items"`    // JSONPath or variable reference to collection, e.g., "{{items}}", "{{order.line_items}}"
-	ItemVar string `json:"item_var"` // Variable name for current item in loop, e.g., "item", "line_item"
-	Steps   []Step `json:"steps"`    // Steps to execute for each item
+	Items           string `json:"items"`                       // JSONPath or variable reference to collection, e.g., "{{items}}", "{{order.line_items}}"
+	ItemVar         string `json:"item_var"`                    // Variable name for current item in loop, e.g., "item", "line_item"
+	Steps           []Step `json:"steps"`                       // Steps to execute for each item
+	Concurrency     int    `json:"concurrency,omitempty"`       // Max iterations run concurrently (default 1, sequential)
+	ContinueOnError bool   `json:"continue_on_error,omitempty"` // Keep processing remaining items when an iteration fails
+	ResultVar       string `json:"result_var,omitempty"`        // Context variable receiving per-iteration results in item order
+	ResultExpr      string `json:"result_expr,omitempty"`       // Optional {{expression}} evaluated per iteration instead of the final action result
+}
+
+// DelayStep pauses an execution for a time and then continues with the step's next step,
+// without waiting for an event. The resume time is persisted, so delays survive restarts
+type DelayStep struct {
+	Duration string `json:"duration"` // e.g. "10m", or a {{path}} resolving to a duration or an RFC3339 timestamp
+}
+
+// CallStep invokes another workflow as a sub-workflow
+type CallStep struct {
+	WorkflowID string            `json:"workflow_id"`          // WorkflowID of the workflow to run (latest version)
+	Input      map[string]string `json:"input,omitempty"`      // Child payload key -> parent context path, e.g., {"order": "order"}
+	ResultVar  string            `json:"result_var,omitempty"` // Context variable receiving the child's result (defaults to the step ID)
+}
+
+// SetVariable assigns a context variable the result of a CEL expression. A set step's variables
+// are assigned in order, so an expression can use variables set before it in the same step.
+// They are a list rather than an object because definitions are stored as JSONB, which doesn't
+// keep the order of object keys
+type SetVariable struct {
+	Name       string `json:"name"`             // Top-level context variable, e.g. "total_with_tax"
+	Expression string `json:"expression"`       // CEL expression, e.g. "order.total * 1.2"
+	Result     bool   `json:"result,omitempty"` // Also store the value under Name in the execution's result payload
+}
+
+// QueryStep runs a read-only SELECT against a configured datasource and stores the rows in
+// context. Values are only ever bound as parameters, never spliced into the SQL
+type QueryStep struct {
+	Datasource string   `json:"datasource"`           // Name of a datasource from QUERY_DATASOURCES
+	SQL        string   `json:"sql"`                  // A single SELECT using $1, $2, ... placeholders
+	Params     []string `json:"params,omitempty"`     // Values bound to $1, $2, ...; may be {{templates}}
+	ResultVar  string   `json:"result_var,omitempty"` // Context variable receiving the rows (defaults to the step ID)
+	MaxRows    int      `json:"max_rows,omitempty"`   // Rows read at most (defaults to, and is capped by, QUERY_MAX_ROWS)
+	Timeout    string   `json:"timeout,omitempty"`    // Query timeout, e.g. "2s" (defaults to, and is capped by, QUERY_TIMEOUT)
 }
 
 // ExecuteAction represents an action to execute
 type ExecuteAction struct {
-	Type       string                 `json:"type"` // notify, webhook, create_record, http_request, etc.
+	Type       string                 `json:"type"` // notify, email, slack, webhook, create_record, http_request, etc.
 	Recipients []string               `json:"recipients,omitempty"`
 	Message    string                 `json:"message,omitempty"`
 	URL        string                 `json:"url,omitempty"`
@@ -105,13 +297,145 @@ type ExecuteAction struct {
 	Entity     string                 `json:"entity,omitempty"`
 	EntityID   string                 `json:"entity_id,omitempty"`
 	Data       map[string]interface{} `json:"data,omitempty"`
+
+	// ContinueOnError records a failed delivery in the step's results instead of failing the step
+	ContinueOnError bool `json:"continue_on_error,omitempty"`
+
+	// Webhook delivery
+	Timeout       string              `json:"timeout,omitempty"`        // Bound on each delivery attempt, e.g., "10s"
+	Retry         *WebhookRetryConfig `json:"retry,omitempty"`          // Redelivery of failed requests, apart from the step's retries
+	SigningSecret string              `json:"signing_secret,omitempty"` // Name of the configured secret the request body is signed with
+
+	// Slack messages, with Message as their text
+	Channel  string        `json:"channel,omitempty"`   // Channel ID or name; webhooks post to their own channel
+	Blocks   []interface{} `json:"blocks,omitempty"`    // Block Kit layout blocks
+	ThreadTS string        `json:"thread_ts,omitempty"` // Timestamp of the message to reply to in a thread
+
+	// Email, with Message as the text body
+	To      []string `json:"to,omitempty"` // Addresses, lists, or tokens resolving to either
+	CC      []string `json:"cc,omitempty"`
+	BCC     []string `json:"bcc,omitempty"`
+	Subject string   `json:"subject,omitempty"`
+	HTML    string   `json:"html,omitempty"` // HTML body; rendered values are escaped
+}
+
+// WebhookRetryConfig configures redelivery of a webhook request that failed with a network
+// error, a timeout, or a 408, 429 or 5xx response, and of Slack messages that failed transiently
+type WebhookRetryConfig struct {
+	MaxAttempts  int    `json:"max_attempts"`            // Total delivery attempts, including the first
+	Backoff      string `json:"backoff,omitempty"`       // linear, exponential (default)
+	InitialDelay string `json:"initial_delay,omitempty"` // Delay before the first retry (default "1s")
+	MaxBackoff   string `json:"max_backoff,omitempty"`   // Cap on a single backoff delay, e.g., "30s"
+	Jitter       string `json:"jitter,omitempty"`        // full, equal, or a percentage such as "20%"
 }
 
 // WaitConfig represents wait/timeout configuration
 type WaitConfig struct {
-	Event     string `json:"event"`
-	Timeout   string `json:"timeout"` // duration string, e.g., "24h"
-	OnTimeout string `json:"on_timeout"`
+	Event     string   `json:"event,omitempty"`
+	Events    []string `json:"events,omitempty"` // Alternative events, any of which resumes the execution
+	Timeout   string   `json:"timeout"`          // duration string ("24h") or {{expression}} resolving to a duration or RFC3339 timestamp
+	OnTimeout string   `json:"on_timeout"`
+	// ResumeSchema lists the fields expected in resume_data when the execution is resumed through
+	// the API. Other fields are accepted as-is
+	ResumeSchema []ResumeField `json:"resume_schema,omitempty"`
+}
+
+// ResumeField describes a field of the resume data a wait step expects
+type ResumeField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"` // string, number, integer, boolean, object, array
+	Required bool   `json:"required,omitempty"`
+}
+
+// ResumeFieldTypes are the types a resume field can declare
+var ResumeFieldTypes = map[string]bool{
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"object":  true,
+	"array":   true,
+}
+
+// ResumeFieldError is a resume data field that does not match a wait step's resume schema
+type ResumeFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// EventNames returns every event the wait step accepts, combining Event and Events without duplicates
+func (w *WaitConfig) EventNames() []string {
+	names := make([]string, 0, len(w.Events)+1)
+	seen := make(map[string]bool)
+	for _, name := range append([]string{w.Event}, w.Events...) {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// ValidateResumeData checks resume data against the resume schema, returning an error for each
+// field that is missing or has the wrong type
+func (w *WaitConfig) ValidateResumeData(data map[string]interface{}) []ResumeFieldError {
+	var fieldErrors []ResumeFieldError
+	for _, field := range w.ResumeSchema {
+		value, exists := data[field.Name]
+		if !exists || value == nil {
+			if field.Required {
+				fieldErrors = append(fieldErrors, ResumeFieldError{Field: field.Name, Message: "is required"})
+			}
+			continue
+		}
+		if !field.Matches(value) {
+			fieldErrors = append(fieldErrors, ResumeFieldError{
+				Field:   field.Name,
+				Message: fmt.Sprintf("must be of type %s, got %s", field.Type, jsonType(value)),
+			})
+		}
+	}
+	return fieldErrors
+}
+
+// Matches reports whether a value decoded from JSON has the field's type
+func (f ResumeField) Matches(value interface{}) bool {
+	switch f.Type {
+	case "integer":
+		switch n := value.(type) {
+		case float64:
+			return n == math.Trunc(n)
+		case int, int32, int64:
+			return true
+		case json.Number:
+			_, err := n.Int64()
+			return err == nil
+		}
+		return false
+	default:
+		return jsonType(value) == f.Type
+	}
+}
+
+// jsonType returns the JSON type of a value, as decoded from JSON
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64, int, int32, int64, json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
 }
 
 // RetryConfig represents retry configuration
@@ -119,6 +443,14 @@ type RetryConfig struct {
 	MaxAttempts int      `json:"max_attempts"`
 	Backoff     string   `json:"backoff"` // linear, exponential
 	RetryOn     []string `json:"retry_on,omitempty"`
+	Jitter      string   `json:"jitter,omitempty"`      // full, equal, or a percentage such as "20%"
+	MaxBackoff  string   `json:"max_backoff,omitempty"` // Cap on a single backoff delay, e.g., "30s"
+	MaxElapsed  string   `json:"max_elapsed,omitempty"` // Total retry budget measured from the first attempt, e.g., "2m"
+}
+
+// CompensationConfig describes how to undo a completed step's side effects (saga rollback)
+type CompensationConfig struct {
+	Execute []ExecuteAction `json:"execute"` // e.g., a refund webhook or a record update
 }
 
 // CreateWorkflowRequest represents the request to create a workflow
@@ -131,6 +463,11 @@ type CreateWorkflowRequest struct {
 	Tags        []string           `json:"tags,omitempty"`
 }
 
+// ValidateWorkflowRequest represents the request to validate a workflow definition without saving it
+type ValidateWorkflowRequest struct {
+	Definition WorkflowDefinition `json:"definition"`
+}
+
 // UpdateWorkflowRequest represents the request to update a workflow
 type UpdateWorkflowRequest struct {
 	Name        *string             `json:"name,omitempty"`
@@ -96,3 +96,78 @@ func TestRuleDefinitionScan(t *testing.T) {
 		t.Errorf("Expected 1 condition, got %d", len(def.Conditions))
 	}
 }
+
+func TestWaitConfigEventNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   WaitConfig
+		expected []string
+	}{
+		{name: "single event", config: WaitConfig{Event: "approval.granted"}, expected: []string{"approval.granted"}},
+		{name: "event list", config: WaitConfig{Events: []string{"a", "b"}}, expected: []string{"a", "b"}},
+		{name: "both forms deduplicated", config: WaitConfig{Event: "a", Events: []string{"a", "b", ""}}, expected: []string{"a", "b"}},
+		{name: "none", config: WaitConfig{}, expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.EventNames()
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWaitConfigValidateResumeData(t *testing.T) {
+	config := WaitConfig{ResumeSchema: []ResumeField{
+		{Name: "approved", Type: "boolean", Required: true},
+		{Name: "quantity", Type: "integer"},
+		{Name: "notes", Type: "string"},
+		{Name: "items", Type: "array"},
+	}}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []ResumeFieldError
+	}{
+		{name: "valid", data: `{"approved": true, "quantity": 3, "notes": "ok", "items": [], "extra": 1}`},
+		{name: "optional fields omitted", data: `{"approved": false}`},
+		{name: "missing required field", data: `{"notes": "ok"}`, expected: []ResumeFieldError{{Field: "approved", Message: "is required"}}},
+		{name: "null required field", data: `{"approved": null}`, expected: []ResumeFieldError{{Field: "approved", Message: "is required"}}},
+		{
+			name: "wrong types",
+			data: `{"approved": "yes", "quantity": 1.5, "items": {}}`,
+			expected: []ResumeFieldError{
+				{Field: "approved", Message: "must be of type boolean, got string"},
+				{Field: "quantity", Message: "must be of type integer, got number"},
+				{Field: "items", Message: "must be of type array, got object"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatalf("Invalid test data: %v", err)
+			}
+
+			got := config.ValidateResumeData(data)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WorkflowVersion is an immutable snapshot of a workflow's definition, recorded when the
+// workflow is created and on every update that changes its definition
+type WorkflowVersion struct {
+	ID             uuid.UUID          `json:"id" db:"id"`
+	OrganizationID uuid.UUID          `json:"organization_id" db:"organization_id"`
+	WorkflowID     uuid.UUID          `json:"workflow_id" db:"workflow_id"`
+	Version        int                `json:"version" db:"version_number"`
+	Definition     WorkflowDefinition `json:"definition" db:"definition"`
+	CreatedBy      *uuid.UUID         `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time          `json:"created_at" db:"created_at"`
+}
+
+// DefinitionDiff summarizes how a workflow definition changed between two versions
+type DefinitionDiff struct {
+	FromVersion    int      `json:"from_version"`
+	ToVersion      int      `json:"to_version"`
+	AddedSteps     []string `json:"added_steps"`   // Step IDs only in the newer definition
+	RemovedSteps   []string `json:"removed_steps"` // Step IDs only in the older definition
+	ChangedSteps   []string `json:"changed_steps"` // Step IDs in both whose configuration differs
+	TriggerChanged bool     `json:"trigger_changed"`
+	ContextChanged bool     `json:"context_changed"`
+	// SettingsChanged reports a change to the definition's timeout, max_step_visits, concurrency,
+	// rate_limit or priority
+	SettingsChanged bool `json:"settings_changed"`
+}
+
+// DiffDefinitions compares two versions of a workflow definition by step ID. Step IDs are
+// listed in the order they appear in their definition
+func DiffDefinitions(from, to *WorkflowVersion) *DefinitionDiff {
+	diff := &DefinitionDiff{
+		FromVersion:     from.Version,
+		ToVersion:       to.Version,
+		AddedSteps:      []string{},
+		RemovedSteps:    []string{},
+		ChangedSteps:    []string{},
+		TriggerChanged:  !reflect.DeepEqual(from.Definition.Trigger, to.Definition.Trigger),
+		ContextChanged:  !reflect.DeepEqual(from.Definition.Context, to.Definition.Context),
+		SettingsChanged: settingsChanged(&from.Definition, &to.Definition),
+	}
+
+	oldSteps := make(map[string]*Step, len(from.Definition.Steps))
+	for i := range from.Definition.Steps {
+		oldSteps[from.Definition.Steps[i].ID] = &from.Definition.Steps[i]
+	}
+	newSteps := make(map[string]bool, len(to.Definition.Steps))
+
+	for i := range to.Definition.Steps {
+		step := &to.Definition.Steps[i]
+		newSteps[step.ID] = true
+
+		old, ok := oldSteps[step.ID]
+		switch {
+		case !ok:
+			diff.AddedSteps = append(diff.AddedSteps, step.ID)
+		case !reflect.DeepEqual(old, step):
+			diff.ChangedSteps = append(diff.ChangedSteps, step.ID)
+		}
+	}
+
+	for _, step := range from.Definition.Steps {
+		if !newSteps[step.ID] {
+			diff.RemovedSteps = append(diff.RemovedSteps, step.ID)
+		}
+	}
+
+	return diff
+}
+
+// settingsChanged reports whether the timeout, max_step_visits, concurrency, rate limit or
+// priority differ
+func settingsChanged(from, to *WorkflowDefinition) bool {
+	return from.Timeout != to.Timeout || from.MaxStepVisits != to.MaxStepVisits || from.Priority != to.Priority ||
+		!reflect.DeepEqual(from.Concurrency, to.Concurrency) || !reflect.DeepEqual(from.RateLimit, to.RateLimit)
+}
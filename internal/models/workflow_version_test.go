@@ -0,0 +1,60 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffDefinitions(t *testing.T) {
+	from := &WorkflowVersion{
+		Version: 1,
+		Definition: WorkflowDefinition{
+			Trigger: TriggerDefinition{Type: "event", Event: "order.created"},
+			Steps: []Step{
+				{ID: "check", Type: "condition", Condition: &Condition{Field: "order.total", Operator: "gt", Value: 1000.0}, OnTrue: "block"},
+				{ID: "block", Type: "action", Action: &Action{Type: "block"}},
+				{ID: "notify", Type: "execute", Execute: []ExecuteAction{{Type: "notify", Recipients: []string{"ops"}, Message: "blocked"}}},
+			},
+		},
+	}
+	to := &WorkflowVersion{
+		Version: 2,
+		Definition: WorkflowDefinition{
+			Trigger: TriggerDefinition{Type: "event", Event: "order.created"},
+			Steps: []Step{
+				{ID: "check", Type: "condition", Condition: &Condition{Field: "order.total", Operator: "gt", Value: 5000.0}, OnTrue: "block"},
+				{ID: "block", Type: "action", Action: &Action{Type: "block"}},
+				{ID: "review", Type: "action", Action: &Action{Type: "allow"}},
+			},
+			Timeout: "5m",
+		},
+	}
+
+	diff := DiffDefinitions(from, to)
+
+	if diff.FromVersion != 1 || diff.ToVersion != 2 {
+		t.Errorf("Expected versions 1 and 2, got %d and %d", diff.FromVersion, diff.ToVersion)
+	}
+	if !reflect.DeepEqual(diff.AddedSteps, []string{"review"}) {
+		t.Errorf("Expected added steps [review], got %v", diff.AddedSteps)
+	}
+	if !reflect.DeepEqual(diff.RemovedSteps, []string{"notify"}) {
+		t.Errorf("Expected removed steps [notify], got %v", diff.RemovedSteps)
+	}
+	if !reflect.DeepEqual(diff.ChangedSteps, []string{"check"}) {
+		t.Errorf("Expected changed steps [check], got %v", diff.ChangedSteps)
+	}
+	if diff.TriggerChanged || diff.ContextChanged {
+		t.Error("Expected trigger and context to be unchanged")
+	}
+	if !diff.SettingsChanged {
+		t.Error("Expected the timeout change to be reported")
+	}
+
+	t.Run("identical versions", func(t *testing.T) {
+		diff := DiffDefinitions(from, from)
+		if len(diff.AddedSteps)+len(diff.RemovedSteps)+len(diff.ChangedSteps) != 0 || diff.SettingsChanged {
+			t.Errorf("Expected no differences, got %+v", diff)
+		}
+	})
+}
@@ -249,19 +249,22 @@ func NewRefreshTokenRepository(db *sql.DB) *RefreshTokenRepository {
 	return &RefreshTokenRepository{db: db}
 }
 
-// Create creates a new refresh token
+// Create creates a new refresh token. A token without a family starts its own
 func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
 	query := `
-		INSERT INTO refresh_tokens (id, token_hash, user_id, expires_at, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO refresh_tokens (id, token_hash, user_id, family_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at`
 
 	token.ID = uuid.New()
 	token.CreatedAt = time.Now()
+	if token.FamilyID == uuid.Nil {
+		token.FamilyID = token.ID
+	}
 
 	err := r.db.QueryRowContext(
 		ctx, query,
-		token.ID, token.TokenHash, token.UserID, token.ExpiresAt, token.CreatedAt,
+		token.ID, token.TokenHash, token.UserID, token.FamilyID, token.ExpiresAt, token.CreatedAt,
 	).Scan(&token.ID, &token.CreatedAt)
 
 	if err != nil {
@@ -275,13 +278,14 @@ func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.Refre
 func (r *RefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
 	token := &models.RefreshToken{}
 	query := `
-		SELECT id, token_hash, user_id, expires_at, created_at, revoked_at
+		SELECT id, token_hash, user_id, expires_at, created_at, revoked_at, family_id, replaced_by
 		FROM refresh_tokens
 		WHERE token_hash = $1`
 
 	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
 		&token.ID, &token.TokenHash, &token.UserID,
 		&token.ExpiresAt, &token.CreatedAt, &token.RevokedAt,
+		&token.FamilyID, &token.ReplacedBy,
 	)
 
 	if err == sql.ErrNoRows {
@@ -318,6 +322,54 @@ func (r *RefreshTokenRepository) Revoke(ctx context.Context, tokenHash string) e
 	return nil
 }
 
+// Rotate revokes a refresh token that is still active and stores next in its family, recording
+// next as its replacement. It reports false without storing next if the token was already used
+// or revoked, so concurrent uses of a token rotate it once
+func (r *RefreshTokenRepository) Rotate(ctx context.Context, tokenID uuid.UUID, next *models.RefreshToken) (bool, error) {
+	query := `
+		WITH used AS (
+			UPDATE refresh_tokens
+			SET revoked_at = $1, replaced_by = $2
+			WHERE id = $3 AND revoked_at IS NULL
+			RETURNING user_id, family_id
+		)
+		INSERT INTO refresh_tokens (id, token_hash, user_id, family_id, expires_at, created_at)
+		SELECT $2, $4, user_id, family_id, $5, $1 FROM used
+		RETURNING user_id, family_id`
+
+	next.ID = uuid.New()
+	next.CreatedAt = time.Now()
+
+	err := r.db.QueryRowContext(
+		ctx, query,
+		next.CreatedAt, next.ID, tokenID, next.TokenHash, next.ExpiresAt,
+	).Scan(&next.UserID, &next.FamilyID)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	return true, nil
+}
+
+// RevokeFamily revokes every active refresh token rotated from the same login
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $1
+		WHERE family_id = $2 AND revoked_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+
+	return nil
+}
+
 // RevokeAllForUser revokes all refresh tokens for a user
 func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
 	query := `
@@ -8,6 +8,7 @@ import (
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // ApprovalRepository handles approval database operations
@@ -26,8 +27,10 @@ func (r *ApprovalRepository) CreateApproval(ctx context.Context, approval *model
 		INSERT INTO approval_requests (
 			id, organization_id, request_id, execution_id, entity_type, entity_id,
 			requester_id, approver_role, approver_id, status, reason,
-			decision_reason, requested_at, decided_at, expires_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			decision_reason, requested_at, decided_at, expires_at,
+			escalation_chain, escalation_level, next_escalation_at, escalations,
+			required_approvers, quorum
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 		RETURNING id, requested_at`
 
 	err := r.db.QueryRowContext(
@@ -37,6 +40,8 @@ func (r *ApprovalRepository) CreateApproval(ctx context.Context, approval *model
 		approval.ApproverRole, approval.ApproverID, approval.Status,
 		approval.Reason, approval.DecisionReason, approval.RequestedAt,
 		approval.DecidedAt, approval.ExpiresAt,
+		approval.EscalationChain, approval.EscalationLevel, approval.NextEscalationAt, approval.Escalations,
+		pq.Array(approval.RequiredApprovers), approval.Quorum,
 	).Scan(&approval.ID, &approval.RequestedAt)
 
 	if err != nil {
@@ -122,7 +127,9 @@ func (r *ApprovalRepository) GetApprovalByID(ctx context.Context, organizationID
 	query := `
 		SELECT id, organization_id, request_id, execution_id, entity_type, entity_id,
 		       requester_id, approver_role, approver_id, status, reason,
-		       decision_reason, requested_at, decided_at, expires_at
+		       decision_reason, requested_at, decided_at, expires_at,
+		       escalation_chain, escalation_level, next_escalation_at, escalations,
+		       required_approvers, quorum
 		FROM approval_requests
 		WHERE organization_id = $1 AND id = $2`
 
@@ -132,6 +139,8 @@ func (r *ApprovalRepository) GetApprovalByID(ctx context.Context, organizationID
 		&approval.ApproverRole, &approval.ApproverID, &approval.Status,
 		&approval.Reason, &approval.DecisionReason, &approval.RequestedAt,
 		&approval.DecidedAt, &approval.ExpiresAt,
+		&approval.EscalationChain, &approval.EscalationLevel, &approval.NextEscalationAt, &approval.Escalations,
+		pq.Array(&approval.RequiredApprovers), &approval.Quorum,
 	)
 
 	if err == sql.ErrNoRows {
@@ -150,7 +159,9 @@ func (r *ApprovalRepository) GetApprovalByRequestID(ctx context.Context, organiz
 	query := `
 		SELECT id, organization_id, request_id, execution_id, entity_type, entity_id,
 		       requester_id, approver_role, approver_id, status, reason,
-		       decision_reason, requested_at, decided_at, expires_at
+		       decision_reason, requested_at, decided_at, expires_at,
+		       escalation_chain, escalation_level, next_escalation_at, escalations,
+		       required_approvers, quorum
 		FROM approval_requests
 		WHERE organization_id = $1 AND request_id = $2`
 
@@ -160,6 +171,8 @@ func (r *ApprovalRepository) GetApprovalByRequestID(ctx context.Context, organiz
 		&approval.ApproverRole, &approval.ApproverID, &approval.Status,
 		&approval.Reason, &approval.DecisionReason, &approval.RequestedAt,
 		&approval.DecidedAt, &approval.ExpiresAt,
+		&approval.EscalationChain, &approval.EscalationLevel, &approval.NextEscalationAt, &approval.Escalations,
+		pq.Array(&approval.RequiredApprovers), &approval.Quorum,
 	)
 
 	if err == sql.ErrNoRows {
@@ -172,12 +185,13 @@ func (r *ApprovalRepository) GetApprovalByRequestID(ctx context.Context, organiz
 	return approval, nil
 }
 
-// ListApprovals retrieves approvals with pagination and filters within an organization
+// ListApprovals retrieves approvals with pagination and filters within an organization. With
+// approver IDs, only approvals decided by or designated to one of them are listed
 func (r *ApprovalRepository) ListApprovals(
 	ctx context.Context,
 	organizationID uuid.UUID,
 	status *models.ApprovalStatus,
-	approverID *uuid.UUID,
+	approverIDs []uuid.UUID,
 	limit, offset int,
 ) ([]models.ApprovalRequest, int64, error) {
 	// Count total
@@ -186,10 +200,10 @@ func (r *ApprovalRepository) ListApprovals(
 		FROM approval_requests
 		WHERE organization_id = $1
 		  AND ($2::varchar IS NULL OR status = $2)
-		  AND ($3::uuid IS NULL OR approver_id = $3)`
+		  AND ($3::uuid[] IS NULL OR approver_id = ANY($3) OR required_approvers && $3)`
 
 	var total int64
-	err := r.db.QueryRowContext(ctx, countQuery, organizationID, status, approverID).Scan(&total)
+	err := r.db.QueryRowContext(ctx, countQuery, organizationID, status, pq.Array(approverIDs)).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count approvals: %w", err)
 	}
@@ -198,15 +212,17 @@ func (r *ApprovalRepository) ListApprovals(
 	query := `
 		SELECT id, organization_id, request_id, execution_id, entity_type, entity_id,
 		       requester_id, approver_role, approver_id, status, reason,
-		       decision_reason, requested_at, decided_at, expires_at
+		       decision_reason, requested_at, decided_at, expires_at,
+		       escalation_chain, escalation_level, next_escalation_at, escalations,
+		       required_approvers, quorum
 		FROM approval_requests
 		WHERE organization_id = $1
 		  AND ($2::varchar IS NULL OR status = $2)
-		  AND ($3::uuid IS NULL OR approver_id = $3)
+		  AND ($3::uuid[] IS NULL OR approver_id = ANY($3) OR required_approvers && $3)
 		ORDER BY requested_at DESC
 		LIMIT $4 OFFSET $5`
 
-	rows, err := r.db.QueryContext(ctx, query, organizationID, status, approverID, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, organizationID, status, pq.Array(approverIDs), limit, offset)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list approvals: %w", err)
 	}
@@ -221,6 +237,8 @@ func (r *ApprovalRepository) ListApprovals(
 			&approval.ApproverRole, &approval.ApproverID, &approval.Status,
 			&approval.Reason, &approval.DecisionReason, &approval.RequestedAt,
 			&approval.DecidedAt, &approval.ExpiresAt,
+			&approval.EscalationChain, &approval.EscalationLevel, &approval.NextEscalationAt, &approval.Escalations,
+			pq.Array(&approval.RequiredApprovers), &approval.Quorum,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan approval: %w", err)
@@ -236,7 +254,9 @@ func (r *ApprovalRepository) GetApprovalsByExecution(ctx context.Context, organi
 	query := `
 		SELECT id, organization_id, request_id, execution_id, entity_type, entity_id,
 		       requester_id, approver_role, approver_id, status, reason,
-		       decision_reason, requested_at, decided_at, expires_at
+		       decision_reason, requested_at, decided_at, expires_at,
+		       escalation_chain, escalation_level, next_escalation_at, escalations,
+		       required_approvers, quorum
 		FROM approval_requests
 		WHERE organization_id = $1 AND execution_id = $2
 		ORDER BY requested_at DESC`
@@ -256,6 +276,8 @@ func (r *ApprovalRepository) GetApprovalsByExecution(ctx context.Context, organi
 			&approval.ApproverRole, &approval.ApproverID, &approval.Status,
 			&approval.Reason, &approval.DecisionReason, &approval.RequestedAt,
 			&approval.DecidedAt, &approval.ExpiresAt,
+			&approval.EscalationChain, &approval.EscalationLevel, &approval.NextEscalationAt, &approval.Escalations,
+			pq.Array(&approval.RequiredApprovers), &approval.Quorum,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan approval: %w", err)
@@ -271,7 +293,9 @@ func (r *ApprovalRepository) GetExpiredApprovals(ctx context.Context, organizati
 	query := `
 		SELECT id, organization_id, request_id, execution_id, entity_type, entity_id,
 		       requester_id, approver_role, approver_id, status, reason,
-		       decision_reason, requested_at, decided_at, expires_at
+		       decision_reason, requested_at, decided_at, expires_at,
+		       escalation_chain, escalation_level, next_escalation_at, escalations,
+		       required_approvers, quorum
 		FROM approval_requests
 		WHERE organization_id = $1
 		  AND status = $2
@@ -295,6 +319,52 @@ func (r *ApprovalRepository) GetExpiredApprovals(ctx context.Context, organizati
 			&approval.ApproverRole, &approval.ApproverID, &approval.Status,
 			&approval.Reason, &approval.DecisionReason, &approval.RequestedAt,
 			&approval.DecidedAt, &approval.ExpiresAt,
+			&approval.EscalationChain, &approval.EscalationLevel, &approval.NextEscalationAt, &approval.Escalations,
+			pq.Array(&approval.RequiredApprovers), &approval.Quorum,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan approval: %w", err)
+		}
+		approvals = append(approvals, approval)
+	}
+
+	return approvals, nil
+}
+
+// GetEscalationDueApprovals retrieves pending approvals across all organizations whose next
+// escalation is due and that have not expired
+func (r *ApprovalRepository) GetEscalationDueApprovals(ctx context.Context, limit int) ([]models.ApprovalRequest, error) {
+	query := `
+		SELECT id, organization_id, request_id, execution_id, entity_type, entity_id,
+		       requester_id, approver_role, approver_id, status, reason,
+		       decision_reason, requested_at, decided_at, expires_at,
+		       escalation_chain, escalation_level, next_escalation_at, escalations,
+		       required_approvers, quorum
+		FROM approval_requests
+		WHERE status = $1
+		  AND next_escalation_at IS NOT NULL
+		  AND next_escalation_at <= NOW()
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY next_escalation_at ASC
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, models.ApprovalStatusPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get escalation due approvals: %w", err)
+	}
+	defer rows.Close()
+
+	var approvals []models.ApprovalRequest
+	for rows.Next() {
+		approval := models.ApprovalRequest{}
+		err := rows.Scan(
+			&approval.ID, &approval.OrganizationID, &approval.RequestID, &approval.ExecutionID,
+			&approval.EntityType, &approval.EntityID, &approval.RequesterID,
+			&approval.ApproverRole, &approval.ApproverID, &approval.Status,
+			&approval.Reason, &approval.DecisionReason, &approval.RequestedAt,
+			&approval.DecidedAt, &approval.ExpiresAt,
+			&approval.EscalationChain, &approval.EscalationLevel, &approval.NextEscalationAt, &approval.Escalations,
+			pq.Array(&approval.RequiredApprovers), &approval.Quorum,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan approval: %w", err)
@@ -304,3 +374,226 @@ func (r *ApprovalRepository) GetExpiredApprovals(ctx context.Context, organizati
 
 	return approvals, nil
 }
+
+// EscalateApproval saves an approval's escalation to the next level of its chain. It only
+// applies while the approval is still pending at fromLevel, and reports whether it did, so an
+// escalation is recorded once when several workers find it due
+func (r *ApprovalRepository) EscalateApproval(ctx context.Context, approval *models.ApprovalRequest, fromLevel int) (bool, error) {
+	query := `
+		UPDATE approval_requests
+		SET approver_role = $4,
+		    escalation_level = $5,
+		    next_escalation_at = $6,
+		    expires_at = $7,
+		    escalations = $8
+		WHERE organization_id = $1 AND id = $2
+		  AND status = 'pending'
+		  AND escalation_level = $3`
+
+	result, err := r.db.ExecContext(
+		ctx, query,
+		approval.OrganizationID, approval.ID, fromLevel,
+		approval.ApproverRole, approval.EscalationLevel, approval.NextEscalationAt,
+		approval.ExpiresAt, approval.Escalations,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to escalate approval: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// DecideApproval sets the final status of an approval that is still pending, and reports whether
+// it did, so an approval is decided once when several decisions reach its quorum together
+func (r *ApprovalRepository) DecideApproval(
+	ctx context.Context,
+	organizationID, id uuid.UUID,
+	status models.ApprovalStatus,
+	approverID *uuid.UUID,
+	decision *string,
+	decidedAt *time.Time,
+) (bool, error) {
+	query := `
+		UPDATE approval_requests
+		SET status = $3,
+		    approver_id = $4,
+		    decision_reason = $5,
+		    decided_at = $6,
+		    next_escalation_at = NULL
+		WHERE organization_id = $1 AND id = $2 AND status = 'pending'`
+
+	result, err := r.db.ExecContext(ctx, query, organizationID, id, status, approverID, decision, decidedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to decide approval: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// RecordApprovalDecision records an approver's decision on an approval. It reports false if the
+// approver has already decided it
+func (r *ApprovalRepository) RecordApprovalDecision(ctx context.Context, decision *models.ApprovalDecision) (bool, error) {
+	query := `
+		INSERT INTO approval_decisions (
+			id, organization_id, approval_id, approver_id, delegate_id, decision, reason, decided_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (approval_id, approver_id) DO NOTHING`
+
+	result, err := r.db.ExecContext(
+		ctx, query,
+		decision.ID, decision.OrganizationID, decision.ApprovalID, decision.ApproverID, decision.DelegateID,
+		decision.Decision, decision.Reason, decision.DecidedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record approval decision: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// ListApprovalDecisions retrieves the decisions made on an approval, oldest first
+func (r *ApprovalRepository) ListApprovalDecisions(ctx context.Context, organizationID, approvalID uuid.UUID) ([]models.ApprovalDecision, error) {
+	query := `
+		SELECT id, organization_id, approval_id, approver_id, delegate_id, decision, reason, decided_at
+		FROM approval_decisions
+		WHERE organization_id = $1 AND approval_id = $2
+		ORDER BY decided_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, organizationID, approvalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approval decisions: %w", err)
+	}
+	defer rows.Close()
+
+	var decisions []models.ApprovalDecision
+	for rows.Next() {
+		decision := models.ApprovalDecision{}
+		err := rows.Scan(
+			&decision.ID, &decision.OrganizationID, &decision.ApprovalID, &decision.ApproverID, &decision.DelegateID,
+			&decision.Decision, &decision.Reason, &decision.DecidedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan approval decision: %w", err)
+		}
+		decisions = append(decisions, decision)
+	}
+
+	return decisions, nil
+}
+
+// CreateDelegation creates an approval delegation
+func (r *ApprovalRepository) CreateDelegation(ctx context.Context, delegation *models.ApprovalDelegation) error {
+	query := `
+		INSERT INTO approval_delegations (
+			id, organization_id, delegator_id, delegate_id, starts_at, ends_at, reason
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at`
+
+	err := r.db.QueryRowContext(
+		ctx, query,
+		delegation.ID, delegation.OrganizationID, delegation.DelegatorID, delegation.DelegateID,
+		delegation.StartsAt, delegation.EndsAt, delegation.Reason,
+	).Scan(&delegation.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create delegation: %w", err)
+	}
+
+	return nil
+}
+
+// ListDelegations retrieves the delegations a user made or received that have not ended,
+// soonest ending first
+func (r *ApprovalRepository) ListDelegations(ctx context.Context, organizationID, userID uuid.UUID) ([]models.ApprovalDelegation, error) {
+	query := `
+		SELECT id, organization_id, delegator_id, delegate_id, starts_at, ends_at, reason, created_at
+		FROM approval_delegations
+		WHERE organization_id = $1
+		  AND (delegator_id = $2 OR delegate_id = $2)
+		  AND ends_at > NOW()
+		ORDER BY ends_at ASC`
+
+	return r.queryDelegations(ctx, query, organizationID, userID)
+}
+
+// GetActiveDelegationsTo retrieves the delegations to a delegate that apply now
+func (r *ApprovalRepository) GetActiveDelegationsTo(ctx context.Context, organizationID, delegateID uuid.UUID) ([]models.ApprovalDelegation, error) {
+	query := `
+		SELECT id, organization_id, delegator_id, delegate_id, starts_at, ends_at, reason, created_at
+		FROM approval_delegations
+		WHERE organization_id = $1
+		  AND delegate_id = $2
+		  AND starts_at <= NOW() AND ends_at > NOW()
+		ORDER BY created_at ASC`
+
+	return r.queryDelegations(ctx, query, organizationID, delegateID)
+}
+
+// GetActiveDelegationsFrom retrieves the delegations by any of the given delegators that apply now
+func (r *ApprovalRepository) GetActiveDelegationsFrom(ctx context.Context, organizationID uuid.UUID, delegatorIDs []uuid.UUID) ([]models.ApprovalDelegation, error) {
+	query := `
+		SELECT id, organization_id, delegator_id, delegate_id, starts_at, ends_at, reason, created_at
+		FROM approval_delegations
+		WHERE organization_id = $1
+		  AND delegator_id = ANY($2)
+		  AND starts_at <= NOW() AND ends_at > NOW()
+		ORDER BY created_at ASC`
+
+	return r.queryDelegations(ctx, query, organizationID, pq.Array(delegatorIDs))
+}
+
+// DeleteDelegation deletes a delegation made by the given delegator, reporting whether it existed
+func (r *ApprovalRepository) DeleteDelegation(ctx context.Context, organizationID, id, delegatorID uuid.UUID) (bool, error) {
+	query := `
+		DELETE FROM approval_delegations
+		WHERE organization_id = $1 AND id = $2 AND delegator_id = $3`
+
+	result, err := r.db.ExecContext(ctx, query, organizationID, id, delegatorID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete delegation: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+func (r *ApprovalRepository) queryDelegations(ctx context.Context, query string, args ...interface{}) ([]models.ApprovalDelegation, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list delegations: %w", err)
+	}
+	defer rows.Close()
+
+	var delegations []models.ApprovalDelegation
+	for rows.Next() {
+		delegation := models.ApprovalDelegation{}
+		err := rows.Scan(
+			&delegation.ID, &delegation.OrganizationID, &delegation.DelegatorID, &delegation.DelegateID,
+			&delegation.StartsAt, &delegation.EndsAt, &delegation.Reason, &delegation.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delegation: %w", err)
+		}
+		delegations = append(delegations, delegation)
+	}
+
+	return delegations, nil
+}
@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/google/uuid"
+)
+
+// DeadLetterRepository handles dead-lettered event database operations
+type DeadLetterRepository struct {
+	db *sql.DB
+}
+
+// NewDeadLetterRepository creates a new dead-letter repository
+func NewDeadLetterRepository(db *sql.DB) *DeadLetterRepository {
+	return &DeadLetterRepository{db: db}
+}
+
+const deadLetterColumns = `id, organization_id, event_id, event_type, COALESCE(source, ''), payload,
+		       workflow_id, reason, error, failed_at, replayed_at`
+
+// CreateDeadLetterEvent records an event that could not be processed
+func (r *DeadLetterRepository) CreateDeadLetterEvent(ctx context.Context, event *models.DeadLetterEvent) error {
+	query := `
+		INSERT INTO dead_letter_events (
+			id, organization_id, event_id, event_type, source, payload,
+			workflow_id, reason, error, failed_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := r.db.ExecContext(
+		ctx, query,
+		event.ID, event.OrganizationID, event.EventID, event.EventType, event.Source, event.Payload,
+		event.WorkflowID, event.Reason, event.Error, event.FailedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create dead-letter event: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeadLetterEvent retrieves a dead-lettered event by ID within an organization
+func (r *DeadLetterRepository) GetDeadLetterEvent(ctx context.Context, organizationID, id uuid.UUID) (*models.DeadLetterEvent, error) {
+	query := `
+		SELECT ` + deadLetterColumns + `
+		FROM dead_letter_events
+		WHERE organization_id = $1 AND id = $2`
+
+	event, err := scanDeadLetterEvent(r.db.QueryRowContext(ctx, query, organizationID, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("dead-letter event not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead-letter event: %w", err)
+	}
+
+	return event, nil
+}
+
+// ListDeadLetterEvents retrieves dead-lettered events with pagination, newest first. A non-nil
+// replayed filters on whether the events have been replayed
+func (r *DeadLetterRepository) ListDeadLetterEvents(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	replayed *bool,
+	limit, offset int,
+) ([]*models.DeadLetterEvent, int64, error) {
+	countQuery := `
+		SELECT COUNT(*)
+		FROM dead_letter_events
+		WHERE organization_id = $1
+		  AND ($2::boolean IS NULL OR (replayed_at IS NOT NULL) = $2)`
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, countQuery, organizationID, replayed).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead-letter events: %w", err)
+	}
+
+	query := `
+		SELECT ` + deadLetterColumns + `
+		FROM dead_letter_events
+		WHERE organization_id = $1
+		  AND ($2::boolean IS NULL OR (replayed_at IS NOT NULL) = $2)
+		ORDER BY failed_at DESC
+		LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.QueryContext(ctx, query, organizationID, replayed, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead-letter events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*models.DeadLetterEvent, 0)
+	for rows.Next() {
+		event, err := scanDeadLetterEvent(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan dead-letter event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, total, rows.Err()
+}
+
+// MarkDeadLetterReplayed records that a dead-lettered event is being replayed. It reports false
+// when the event was already replayed, so concurrent replay requests replay it only once
+func (r *DeadLetterRepository) MarkDeadLetterReplayed(ctx context.Context, organizationID, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE dead_letter_events
+		SET replayed_at = NOW()
+		WHERE organization_id = $1 AND id = $2 AND replayed_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, organizationID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark dead-letter event replayed: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows == 1, nil
+}
+
+func scanDeadLetterEvent(row interface{ Scan(...interface{}) error }) (*models.DeadLetterEvent, error) {
+	event := &models.DeadLetterEvent{}
+	err := row.Scan(
+		&event.ID, &event.OrganizationID, &event.EventID, &event.EventType, &event.Source, &event.Payload,
+		&event.WorkflowID, &event.Reason, &event.Error, &event.FailedAt, &event.ReplayedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
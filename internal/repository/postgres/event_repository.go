@@ -25,15 +25,15 @@ func (r *EventRepository) CreateEvent(ctx context.Context, event *models.Event)
 	query := `
 		INSERT INTO events (
 			id, organization_id, event_id, event_type, source, payload,
-			triggered_workflows, received_at, processed_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			triggered_workflows, received_at, processed_at, correlation_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, received_at`
 
 	err := r.db.QueryRowContext(
 		ctx, query,
 		event.ID, event.OrganizationID, event.EventID, event.EventType, event.Source,
 		event.Payload, pq.Array(event.TriggeredWorkflows),
-		event.ReceivedAt, event.ProcessedAt,
+		event.ReceivedAt, event.ProcessedAt, event.CorrelationID,
 	).Scan(&event.ID, &event.ReceivedAt)
 
 	if err != nil {
@@ -77,7 +77,7 @@ func (r *EventRepository) GetEventByID(ctx context.Context, organizationID, id u
 	event := &models.Event{}
 	query := `
 		SELECT id, organization_id, event_id, event_type, source, payload,
-		       triggered_workflows, received_at, processed_at
+		       triggered_workflows, received_at, processed_at, correlation_id
 		FROM events
 		WHERE organization_id = $1 AND id = $2`
 
@@ -85,7 +85,7 @@ func (r *EventRepository) GetEventByID(ctx context.Context, organizationID, id u
 	err := r.db.QueryRowContext(ctx, query, organizationID, id).Scan(
 		&event.ID, &event.OrganizationID, &event.EventID, &event.EventType, &event.Source,
 		&event.Payload, &triggeredWorkflows, &event.ReceivedAt,
-		&event.ProcessedAt,
+		&event.ProcessedAt, &event.CorrelationID,
 	)
 
 	if err == sql.ErrNoRows {
@@ -124,7 +124,7 @@ func (r *EventRepository) ListEvents(
 	// Get events
 	query := `
 		SELECT id, organization_id, event_id, event_type, source, payload,
-		       triggered_workflows, received_at, processed_at
+		       triggered_workflows, received_at, processed_at, correlation_id
 		FROM events
 		WHERE organization_id = $1
 		  AND ($2::varchar IS NULL OR event_type = $2)
@@ -145,7 +145,7 @@ func (r *EventRepository) ListEvents(
 		err := rows.Scan(
 			&event.ID, &event.OrganizationID, &event.EventID, &event.EventType, &event.Source,
 			&event.Payload, &triggeredWorkflows, &event.ReceivedAt,
-			&event.ProcessedAt,
+			&event.ProcessedAt, &event.CorrelationID,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan event: %w", err)
@@ -3,10 +3,22 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrExecutionNotFound is returned when an execution does not exist within the caller's organization
+	ErrExecutionNotFound = errors.New("execution not found")
+
+	// ErrExecutionNotCancellable is returned when cancelling an execution that is not running or waiting
+	ErrExecutionNotCancellable = errors.New("execution not found or not in cancellable state")
 )
 
 // ExecutionRepository handles execution database operations
@@ -25,8 +37,9 @@ func (r *ExecutionRepository) CreateExecution(ctx context.Context, execution *mo
 		INSERT INTO workflow_executions (
 			id, organization_id, workflow_id, execution_id, trigger_event, trigger_payload,
 			context, status, result, started_at, completed_at, duration_ms,
-			error_message, metadata, timeout_at, timeout_duration
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+			error_message, metadata, timeout_at, timeout_duration, idempotency_key, workflow_version,
+			correlation_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 		RETURNING id, started_at`
 
 	err := r.db.QueryRowContext(
@@ -36,6 +49,7 @@ func (r *ExecutionRepository) CreateExecution(ctx context.Context, execution *mo
 		execution.Status, execution.Result, execution.StartedAt,
 		execution.CompletedAt, execution.DurationMs, execution.ErrorMessage,
 		execution.Metadata, execution.TimeoutAt, execution.TimeoutDuration,
+		execution.IdempotencyKey, execution.WorkflowVersion, execution.CorrelationID,
 	).Scan(&execution.ID, &execution.StartedAt)
 
 	if err != nil {
@@ -62,7 +76,11 @@ func (r *ExecutionRepository) UpdateExecution(ctx context.Context, organizationI
 		    next_step_id = $13,
 		    resume_data = $14,
 		    resume_count = $15,
-		    last_resumed_at = $16
+		    last_resumed_at = $16,
+		    approval_id = $17,
+		    current_step_id = $18,
+		    wait_state = $19,
+		    result_payload = $20
 		WHERE organization_id = $1 AND id = $2`
 
 	result, err := r.db.ExecContext(
@@ -72,7 +90,8 @@ func (r *ExecutionRepository) UpdateExecution(ctx context.Context, organizationI
 		execution.ErrorMessage, execution.Metadata,
 		execution.PausedAt, execution.PausedReason, execution.PausedStepID,
 		execution.NextStepID, execution.ResumeData, execution.ResumeCount,
-		execution.LastResumedAt,
+		execution.LastResumedAt, execution.ApprovalID,
+		execution.CurrentStepID, execution.WaitState, execution.ResultPayload,
 	)
 
 	if err != nil {
@@ -85,7 +104,7 @@ func (r *ExecutionRepository) UpdateExecution(ctx context.Context, organizationI
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("execution not found")
+		return ErrExecutionNotFound
 	}
 
 	return nil
@@ -98,9 +117,10 @@ func (r *ExecutionRepository) GetExecutionByID(ctx context.Context, organization
 		SELECT id, organization_id, workflow_id, execution_id, trigger_event, trigger_payload,
 		       context, status, result, started_at, completed_at, duration_ms,
 		       error_message, metadata, paused_at, paused_reason, paused_step_id,
-		       next_step_id, resume_data, resume_count, last_resumed_at
+		       next_step_id, resume_data, resume_count, last_resumed_at, workflow_version,
+		       approval_id, current_step_id, wait_state, result_payload, correlation_id
 		FROM workflow_executions
-		WHERE organization_id = $1 AND id = $2`
+		WHERE organization_id = $1 AND id = $2 AND deleted_at IS NULL`
 
 	err := r.db.QueryRowContext(ctx, query, organizationID, id).Scan(
 		&execution.ID, &execution.OrganizationID, &execution.WorkflowID, &execution.ExecutionID,
@@ -109,11 +129,47 @@ func (r *ExecutionRepository) GetExecutionByID(ctx context.Context, organization
 		&execution.CompletedAt, &execution.DurationMs, &execution.ErrorMessage,
 		&execution.Metadata, &execution.PausedAt, &execution.PausedReason,
 		&execution.PausedStepID, &execution.NextStepID, &execution.ResumeData,
-		&execution.ResumeCount, &execution.LastResumedAt,
+		&execution.ResumeCount, &execution.LastResumedAt, &execution.WorkflowVersion,
+		&execution.ApprovalID, &execution.CurrentStepID, &execution.WaitState,
+		&execution.ResultPayload, &execution.CorrelationID,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("execution not found")
+		return nil, ErrExecutionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get execution: %w", err)
+	}
+
+	return execution, nil
+}
+
+// GetExecutionByIdempotencyKey retrieves the execution of a workflow recorded under an idempotency key
+func (r *ExecutionRepository) GetExecutionByIdempotencyKey(ctx context.Context, organizationID, workflowID uuid.UUID, key string) (*models.WorkflowExecution, error) {
+	execution := &models.WorkflowExecution{}
+	query := `
+		SELECT id, organization_id, workflow_id, execution_id, trigger_event, trigger_payload,
+		       context, status, result, started_at, completed_at, duration_ms,
+		       error_message, metadata, idempotency_key, paused_at, paused_reason,
+		       paused_step_id, next_step_id, resume_data, resume_count, last_resumed_at,
+		       workflow_version, approval_id, result_payload, correlation_id
+		FROM workflow_executions
+		WHERE organization_id = $1 AND workflow_id = $2 AND idempotency_key = $3`
+
+	err := r.db.QueryRowContext(ctx, query, organizationID, workflowID, key).Scan(
+		&execution.ID, &execution.OrganizationID, &execution.WorkflowID, &execution.ExecutionID,
+		&execution.TriggerEvent, &execution.TriggerPayload, &execution.Context,
+		&execution.Status, &execution.Result, &execution.StartedAt,
+		&execution.CompletedAt, &execution.DurationMs, &execution.ErrorMessage,
+		&execution.Metadata, &execution.IdempotencyKey, &execution.PausedAt,
+		&execution.PausedReason, &execution.PausedStepID, &execution.NextStepID,
+		&execution.ResumeData, &execution.ResumeCount, &execution.LastResumedAt,
+		&execution.WorkflowVersion, &execution.ApprovalID, &execution.ResultPayload,
+		&execution.CorrelationID,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrExecutionNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get execution: %w", err)
@@ -128,7 +184,7 @@ func (r *ExecutionRepository) GetExecutionByExecutionID(ctx context.Context, org
 	query := `
 		SELECT id, organization_id, workflow_id, execution_id, trigger_event, trigger_payload,
 		       context, status, result, started_at, completed_at, duration_ms,
-		       error_message, metadata
+		       error_message, metadata, workflow_version, correlation_id
 		FROM workflow_executions
 		WHERE organization_id = $1 AND execution_id = $2`
 
@@ -137,11 +193,11 @@ func (r *ExecutionRepository) GetExecutionByExecutionID(ctx context.Context, org
 		&execution.TriggerEvent, &execution.TriggerPayload, &execution.Context,
 		&execution.Status, &execution.Result, &execution.StartedAt,
 		&execution.CompletedAt, &execution.DurationMs, &execution.ErrorMessage,
-		&execution.Metadata,
+		&execution.Metadata, &execution.WorkflowVersion, &execution.CorrelationID,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("execution not found")
+		return nil, ErrExecutionNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get execution: %w", err)
@@ -150,46 +206,170 @@ func (r *ExecutionRepository) GetExecutionByExecutionID(ctx context.Context, org
 	return execution, nil
 }
 
+// ExecutionListFilters represents filters for execution list queries; nil fields are not applied
+type ExecutionListFilters struct {
+	WorkflowID    *uuid.UUID
+	Status        *models.ExecutionStatus
+	TriggerEvent  *string
+	StartedAfter  *time.Time // inclusive
+	StartedBefore *time.Time // exclusive
+	ExcludeRerun  bool       // omit failed executions that have already been re-run
+	CorrelationID *string
+}
+
 // ListExecutions retrieves executions with pagination and filters within an organization
 func (r *ExecutionRepository) ListExecutions(
 	ctx context.Context,
 	organizationID uuid.UUID,
-	workflowID *uuid.UUID,
-	status *models.ExecutionStatus,
+	filters *ExecutionListFilters,
 	limit, offset int,
 ) ([]models.WorkflowExecution, int64, error) {
-	// Count total
-	countQuery := `
-		SELECT COUNT(*)
-		FROM workflow_executions
-		WHERE organization_id = $1
-		  AND ($2::uuid IS NULL OR workflow_id = $2)
-		  AND ($3::varchar IS NULL OR status = $3)`
+	whereClause, args := executionListWhere(organizationID, filters)
 
-	var total int64
-	err := r.db.QueryRowContext(ctx, countQuery, organizationID, workflowID, status).Scan(&total)
+	total, err := r.countExecutions(ctx, whereClause, args)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count executions: %w", err)
+		return nil, 0, err
 	}
 
 	// Get executions
-	query := `
+	query := fmt.Sprintf(`
 		SELECT id, organization_id, workflow_id, execution_id, trigger_event, trigger_payload,
 		       context, status, result, started_at, completed_at, duration_ms,
-		       error_message, metadata
+		       error_message, metadata, correlation_id
 		FROM workflow_executions
-		WHERE organization_id = $1
-		  AND ($2::uuid IS NULL OR workflow_id = $2)
-		  AND ($3::varchar IS NULL OR status = $3)
-		ORDER BY started_at DESC
-		LIMIT $4 OFFSET $5`
+		%s
+		ORDER BY started_at DESC, id DESC
+		LIMIT $%d OFFSET $%d`, whereClause, len(args)+1, len(args)+2)
 
-	rows, err := r.db.QueryContext(ctx, query, organizationID, workflowID, status, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit, offset)...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list executions: %w", err)
 	}
 	defer rows.Close()
 
+	executions, err := scanExecutionList(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return executions, total, nil
+}
+
+// ListExecutionsByCursor retrieves a page of executions within an organization using keyset pagination
+// on (started_at, id), which stays fast and stable as new executions arrive. An empty cursor starts at
+// the newest execution; the returned cursor is empty on the last page. Returns models.ErrInvalidCursor
+// when the cursor cannot be decoded
+func (r *ExecutionRepository) ListExecutionsByCursor(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	filters *ExecutionListFilters,
+	cursor string,
+	limit int,
+) ([]models.WorkflowExecution, int64, string, error) {
+	var after *models.ExecutionCursor
+	if cursor != "" {
+		decoded, err := models.DecodeExecutionCursor(cursor)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		after = decoded
+	}
+
+	whereClause, args := executionListWhere(organizationID, filters)
+
+	total, err := r.countExecutions(ctx, whereClause, args)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	// The total covers every page, so the cursor position only narrows the select
+	if after != nil {
+		whereClause += fmt.Sprintf(" AND (started_at, id) < ($%d::timestamp, $%d::uuid)", len(args)+1, len(args)+2)
+		args = append(args, after.StartedAt, after.ID)
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	query := fmt.Sprintf(`
+		SELECT id, organization_id, workflow_id, execution_id, trigger_event, trigger_payload,
+		       context, status, result, started_at, completed_at, duration_ms,
+		       error_message, metadata, correlation_id
+		FROM workflow_executions
+		%s
+		ORDER BY started_at DESC, id DESC
+		LIMIT $%d`, whereClause, len(args)+1)
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit+1)...)
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("failed to list executions: %w", err)
+	}
+	defer rows.Close()
+
+	executions, err := scanExecutionList(rows)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	nextCursor := ""
+	if len(executions) > limit {
+		executions = executions[:limit]
+		last := executions[limit-1]
+		nextCursor = models.ExecutionCursor{StartedAt: last.StartedAt, ID: last.ID}.Encode()
+	}
+
+	return executions, total, nextCursor, nil
+}
+
+// executionListWhere builds the WHERE clause shared by the execution count and list queries
+func executionListWhere(organizationID uuid.UUID, filters *ExecutionListFilters) (string, []interface{}) {
+	whereClauses := []string{"organization_id = $1", "deleted_at IS NULL"}
+	args := []interface{}{organizationID}
+
+	add := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		whereClauses = append(whereClauses, fmt.Sprintf(clause, len(args)))
+	}
+
+	if filters != nil {
+		if filters.WorkflowID != nil {
+			add("workflow_id = $%d", *filters.WorkflowID)
+		}
+		if filters.Status != nil {
+			add("status = $%d", *filters.Status)
+		}
+		if filters.TriggerEvent != nil {
+			add("trigger_event = $%d", *filters.TriggerEvent)
+		}
+		if filters.CorrelationID != nil {
+			add("correlation_id = $%d", *filters.CorrelationID)
+		}
+		if filters.StartedAfter != nil {
+			add("started_at >= $%d::timestamp", *filters.StartedAfter)
+		}
+		if filters.StartedBefore != nil {
+			add("started_at < $%d::timestamp", *filters.StartedBefore)
+		}
+		if filters.ExcludeRerun {
+			whereClauses = append(whereClauses, "NOT (COALESCE(metadata, '{}'::jsonb) ? 'rerun_at')")
+		}
+	}
+
+	return "WHERE " + strings.Join(whereClauses, " AND "), args
+}
+
+// countExecutions counts executions matching a WHERE clause built by executionListWhere
+func (r *ExecutionRepository) countExecutions(ctx context.Context, whereClause string, args []interface{}) (int64, error) {
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM workflow_executions %s", whereClause)
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count executions: %w", err)
+	}
+
+	return total, nil
+}
+
+// scanExecutionList scans the summary columns selected by the list queries
+func scanExecutionList(rows *sql.Rows) ([]models.WorkflowExecution, error) {
 	var executions []models.WorkflowExecution
 	for rows.Next() {
 		execution := models.WorkflowExecution{}
@@ -198,15 +378,19 @@ func (r *ExecutionRepository) ListExecutions(
 			&execution.TriggerEvent, &execution.TriggerPayload, &execution.Context,
 			&execution.Status, &execution.Result, &execution.StartedAt,
 			&execution.CompletedAt, &execution.DurationMs, &execution.ErrorMessage,
-			&execution.Metadata,
+			&execution.Metadata, &execution.CorrelationID,
 		)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to scan execution: %w", err)
+			return nil, fmt.Errorf("failed to scan execution: %w", err)
 		}
 		executions = append(executions, execution)
 	}
 
-	return executions, total, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating executions: %w", err)
+	}
+
+	return executions, nil
 }
 
 // CreateStepExecution creates a new step execution
@@ -216,14 +400,14 @@ func (r *ExecutionRepository) CreateStepExecution(ctx context.Context, step *mod
 			id, organization_id, execution_id, step_id, step_type, status,
 			input, output, started_at, completed_at, duration_ms, error_message
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		RETURNING id, started_at`
+		RETURNING id, started_at, sequence`
 
 	err := r.db.QueryRowContext(
 		ctx, query,
 		step.ID, step.OrganizationID, step.ExecutionID, step.StepID, step.StepType,
 		step.Status, step.Input, step.Output, step.StartedAt,
 		step.CompletedAt, step.DurationMs, step.ErrorMessage,
-	).Scan(&step.ID, &step.StartedAt)
+	).Scan(&step.ID, &step.StartedAt, &step.Sequence)
 
 	if err != nil {
 		return fmt.Errorf("failed to create step execution: %w", err)
@@ -265,14 +449,15 @@ func (r *ExecutionRepository) UpdateStepExecution(ctx context.Context, organizat
 	return nil
 }
 
-// GetStepExecutions retrieves all step executions for an execution within an organization
+// GetStepExecutions retrieves all step executions for an execution within an organization in the order
+// they started, with steps that started together in the order they were created
 func (r *ExecutionRepository) GetStepExecutions(ctx context.Context, organizationID, executionID uuid.UUID) ([]models.StepExecution, error) {
 	query := `
 		SELECT id, organization_id, execution_id, step_id, step_type, status,
-		       input, output, started_at, completed_at, duration_ms, error_message
+		       input, output, started_at, completed_at, duration_ms, error_message, sequence
 		FROM step_executions
 		WHERE organization_id = $1 AND execution_id = $2
-		ORDER BY started_at ASC`
+		ORDER BY started_at ASC, sequence ASC`
 
 	rows, err := r.db.QueryContext(ctx, query, organizationID, executionID)
 	if err != nil {
@@ -280,13 +465,67 @@ func (r *ExecutionRepository) GetStepExecutions(ctx context.Context, organizatio
 	}
 	defer rows.Close()
 
-	var steps []models.StepExecution
+	return scanStepExecutionList(rows)
+}
+
+// StepExecutionListFilters represents filters for step execution list queries; nil fields are not applied
+type StepExecutionListFilters struct {
+	Status *models.StepExecutionStatus
+}
+
+// ListStepExecutions retrieves a page of an execution's step executions within an organization, in
+// the order they started, with the total number matching the filters
+func (r *ExecutionRepository) ListStepExecutions(
+	ctx context.Context,
+	organizationID, executionID uuid.UUID,
+	filters *StepExecutionListFilters,
+	limit, offset int,
+) ([]models.StepExecution, int64, error) {
+	whereClause := "WHERE organization_id = $1 AND execution_id = $2"
+	args := []interface{}{organizationID, executionID}
+
+	if filters != nil && filters.Status != nil {
+		args = append(args, *filters.Status)
+		whereClause += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	var total int64
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM step_executions %s", whereClause)
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count step executions: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, organization_id, execution_id, step_id, step_type, status,
+		       input, output, started_at, completed_at, duration_ms, error_message, sequence
+		FROM step_executions
+		%s
+		ORDER BY started_at ASC, sequence ASC
+		LIMIT $%d OFFSET $%d`, whereClause, len(args)+1, len(args)+2)
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list step executions: %w", err)
+	}
+	defer rows.Close()
+
+	steps, err := scanStepExecutionList(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return steps, total, nil
+}
+
+// scanStepExecutionList scans the columns selected by the step execution queries
+func scanStepExecutionList(rows *sql.Rows) ([]models.StepExecution, error) {
+	steps := []models.StepExecution{}
 	for rows.Next() {
 		step := models.StepExecution{}
 		err := rows.Scan(
 			&step.ID, &step.OrganizationID, &step.ExecutionID, &step.StepID, &step.StepType,
 			&step.Status, &step.Input, &step.Output, &step.StartedAt,
-			&step.CompletedAt, &step.DurationMs, &step.ErrorMessage,
+			&step.CompletedAt, &step.DurationMs, &step.ErrorMessage, &step.Sequence,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan step execution: %w", err)
@@ -294,10 +533,15 @@ func (r *ExecutionRepository) GetStepExecutions(ctx context.Context, organizatio
 		steps = append(steps, step)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating step executions: %w", err)
+	}
+
 	return steps, nil
 }
 
-// GetExecutionTrace retrieves full execution trace with steps
+// GetExecutionTrace retrieves full execution trace with steps in the order they started.
+// Returns ErrExecutionNotFound when the execution is not in the organization
 func (r *ExecutionRepository) GetExecutionTrace(ctx context.Context, organizationID, id uuid.UUID) (*models.ExecutionTraceResponse, error) {
 	// Get execution
 	execution, err := r.GetExecutionByID(ctx, organizationID, id)
@@ -319,13 +563,36 @@ func (r *ExecutionRepository) GetExecutionTrace(ctx context.Context, organizatio
 	return trace, nil
 }
 
+// MarkExecutionRerun records that a failed execution is being re-run. It reports false when the
+// execution is not failed or was already marked, so concurrent re-run requests replay it only once
+func (r *ExecutionRepository) MarkExecutionRerun(ctx context.Context, organizationID, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE workflow_executions
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('rerun_at', NOW())
+		WHERE organization_id = $1 AND id = $2 AND status = $3
+		  AND NOT (COALESCE(metadata, '{}'::jsonb) ? 'rerun_at')`
+
+	result, err := r.db.ExecContext(ctx, query, organizationID, id, models.ExecutionStatusFailed)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark execution for re-run: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows == 1, nil
+}
+
 // GetPausedExecutions retrieves paused executions within an organization
 func (r *ExecutionRepository) GetPausedExecutions(ctx context.Context, organizationID uuid.UUID, limit int) ([]*models.WorkflowExecution, error) {
 	query := `
 		SELECT id, organization_id, workflow_id, execution_id, trigger_event, trigger_payload,
 		       context, status, result, started_at, completed_at, duration_ms,
 		       error_message, metadata, paused_at, paused_reason, paused_step_id,
-		       next_step_id, resume_data, resume_count, last_resumed_at
+		       next_step_id, resume_data, resume_count, last_resumed_at, approval_id,
+		       result_payload, correlation_id
 		FROM workflow_executions
 		WHERE organization_id = $1 AND status = $2
 		ORDER BY paused_at ASC
@@ -347,7 +614,8 @@ func (r *ExecutionRepository) GetPausedExecutions(ctx context.Context, organizat
 			&execution.CompletedAt, &execution.DurationMs, &execution.ErrorMessage,
 			&execution.Metadata, &execution.PausedAt, &execution.PausedReason,
 			&execution.PausedStepID, &execution.NextStepID, &execution.ResumeData,
-			&execution.ResumeCount, &execution.LastResumedAt,
+			&execution.ResumeCount, &execution.LastResumedAt, &execution.ApprovalID,
+			&execution.ResultPayload, &execution.CorrelationID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan paused execution: %w", err)
@@ -369,7 +637,7 @@ func (r *ExecutionRepository) GetTimedOutExecutions(ctx context.Context, organiz
 		       context, status, result, started_at, completed_at, duration_ms,
 		       error_message, metadata, paused_at, paused_reason, paused_step_id,
 		       next_step_id, resume_data, resume_count, last_resumed_at,
-		       timeout_at, timeout_duration
+		       timeout_at, timeout_duration, approval_id, result_payload, correlation_id
 		FROM workflow_executions
 		WHERE organization_id = $1
 		  AND timeout_at IS NOT NULL
@@ -399,7 +667,8 @@ func (r *ExecutionRepository) GetTimedOutExecutions(ctx context.Context, organiz
 			&execution.Metadata, &execution.PausedAt, &execution.PausedReason,
 			&execution.PausedStepID, &execution.NextStepID, &execution.ResumeData,
 			&execution.ResumeCount, &execution.LastResumedAt,
-			&execution.TimeoutAt, &execution.TimeoutDuration,
+			&execution.TimeoutAt, &execution.TimeoutDuration, &execution.ApprovalID,
+			&execution.ResultPayload, &execution.CorrelationID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan timed-out execution: %w", err)
@@ -414,6 +683,62 @@ func (r *ExecutionRepository) GetTimedOutExecutions(ctx context.Context, organiz
 	return executions, nil
 }
 
+// ClaimDueDelays claims up to limit executions, across all organizations, waiting at a delay
+// step that is due at now. Claimed executions are set running, so each is handed to a single
+// caller, and held until now+claimTTL; rows being claimed by a concurrent caller are skipped.
+// An execution still delayed once its claim expires was never continued, e.g. because its
+// claimer died, and is claimed again
+func (r *ExecutionRepository) ClaimDueDelays(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]*models.WorkflowExecution, error) {
+	query := `
+		UPDATE workflow_executions
+		SET status = $2, delay_claimed_until = $4
+		WHERE id IN (
+			SELECT id FROM workflow_executions
+			WHERE (status = $1 OR (status = $2 AND delay_claimed_until <= $3))
+			  AND wait_state->>'resume_at' IS NOT NULL
+			  AND (wait_state->>'resume_at')::timestamptz <= $3
+			ORDER BY (wait_state->>'resume_at')::timestamptz ASC
+			LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, organization_id, workflow_id, execution_id, trigger_event, trigger_payload,
+		          context, status, result, started_at, completed_at, duration_ms,
+		          error_message, metadata, resume_data, resume_count, last_resumed_at,
+		          workflow_version, current_step_id, wait_state, result_payload, correlation_id`
+
+	rows, err := r.db.QueryContext(ctx, query,
+		models.ExecutionStatusWaiting,
+		models.ExecutionStatusRunning,
+		now.UTC(),
+		now.Add(claimTTL).UTC(),
+		limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due delays: %w", err)
+	}
+	defer rows.Close()
+
+	executions := []*models.WorkflowExecution{}
+	for rows.Next() {
+		execution := &models.WorkflowExecution{}
+		err := rows.Scan(
+			&execution.ID, &execution.OrganizationID, &execution.WorkflowID, &execution.ExecutionID,
+			&execution.TriggerEvent, &execution.TriggerPayload, &execution.Context,
+			&execution.Status, &execution.Result, &execution.StartedAt,
+			&execution.CompletedAt, &execution.DurationMs, &execution.ErrorMessage,
+			&execution.Metadata, &execution.ResumeData, &execution.ResumeCount,
+			&execution.LastResumedAt, &execution.WorkflowVersion,
+			&execution.CurrentStepID, &execution.WaitState, &execution.ResultPayload,
+			&execution.CorrelationID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delayed execution: %w", err)
+		}
+		executions = append(executions, execution)
+	}
+
+	return executions, rows.Err()
+}
+
 // CancelExecution cancels a running execution
 func (r *ExecutionRepository) CancelExecution(ctx context.Context, organizationID, id uuid.UUID) error {
 	query := `
@@ -441,8 +766,185 @@ func (r *ExecutionRepository) CancelExecution(ctx context.Context, organizationI
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("execution not found or not in cancellable state")
+		return ErrExecutionNotCancellable
 	}
 
 	return nil
 }
+
+// InterruptExecution records a running execution as interrupted, with reason as its error
+// message. It reports false when the execution is no longer running
+func (r *ExecutionRepository) InterruptExecution(ctx context.Context, organizationID, id uuid.UUID, reason string) (bool, error) {
+	query := `
+		UPDATE workflow_executions
+		SET status = $3,
+		    error_message = $4,
+		    completed_at = NOW(),
+		    duration_ms = (EXTRACT(EPOCH FROM (NOW() - started_at)) * 1000)::INTEGER
+		WHERE organization_id = $1 AND id = $2 AND status = $5`
+
+	result, err := r.db.ExecContext(ctx, query,
+		organizationID,
+		id,
+		models.ExecutionStatusInterrupted,
+		reason,
+		models.ExecutionStatusRunning,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to interrupt execution: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// SoftDeleteExpiredExecutions soft-deletes up to limit completed and failed executions that finished
+// before their organization's retention window, hiding them from the API until they are purged.
+// Organizations without a retention policy keep executions for defaultRetentionDays; a window of 0
+// keeps them forever. Returns the number of executions soft-deleted
+func (r *ExecutionRepository) SoftDeleteExpiredExecutions(ctx context.Context, defaultRetentionDays, limit int) (int, error) {
+	query := `
+		UPDATE workflow_executions
+		SET deleted_at = NOW()
+		WHERE id IN (
+			SELECT e.id FROM workflow_executions e
+			LEFT JOIN retention_policies p ON p.organization_id = e.organization_id
+			WHERE e.deleted_at IS NULL
+			  AND e.status IN ($1, $2)
+			  AND COALESCE(p.retention_days, $3) > 0
+			  AND COALESCE(e.completed_at, e.started_at) < NOW() - make_interval(days => COALESCE(p.retention_days, $3))
+			LIMIT $4
+			FOR UPDATE OF e SKIP LOCKED
+		)`
+
+	result, err := r.db.ExecContext(ctx, query,
+		models.ExecutionStatusCompleted,
+		models.ExecutionStatusFailed,
+		defaultRetentionDays,
+		limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to soft-delete expired executions: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rows), nil
+}
+
+// PurgeDeletedExecutions permanently deletes up to limit executions soft-deleted more than purgeAfter
+// ago, with their step executions, in one transaction. When export is set, it is called with the
+// batch before anything is deleted, and nothing is deleted if it fails. Returns the number of
+// executions purged
+func (r *ExecutionRepository) PurgeDeletedExecutions(
+	ctx context.Context,
+	purgeAfter time.Duration,
+	limit int,
+	export func(ctx context.Context, traces []models.ExecutionTraceResponse) error,
+) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Claim the batch, so concurrent workers purge different executions
+	query := `
+		SELECT id, organization_id, workflow_id, execution_id, trigger_event, trigger_payload,
+		       context, status, result, started_at, completed_at, duration_ms,
+		       error_message, metadata, paused_at, paused_reason, paused_step_id,
+		       next_step_id, resume_data, resume_count, last_resumed_at, workflow_version,
+		       approval_id, current_step_id, wait_state, result_payload, correlation_id
+		FROM workflow_executions
+		WHERE deleted_at < NOW() - make_interval(secs => $1)
+		ORDER BY deleted_at ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.QueryContext(ctx, query, purgeAfter.Seconds(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim deleted executions: %w", err)
+	}
+
+	traces := []models.ExecutionTraceResponse{}
+	ids := []string{}
+	for rows.Next() {
+		execution := &models.WorkflowExecution{}
+		err := rows.Scan(
+			&execution.ID, &execution.OrganizationID, &execution.WorkflowID, &execution.ExecutionID,
+			&execution.TriggerEvent, &execution.TriggerPayload, &execution.Context,
+			&execution.Status, &execution.Result, &execution.StartedAt,
+			&execution.CompletedAt, &execution.DurationMs, &execution.ErrorMessage,
+			&execution.Metadata, &execution.PausedAt, &execution.PausedReason,
+			&execution.PausedStepID, &execution.NextStepID, &execution.ResumeData,
+			&execution.ResumeCount, &execution.LastResumedAt, &execution.WorkflowVersion,
+			&execution.ApprovalID, &execution.CurrentStepID, &execution.WaitState,
+			&execution.ResultPayload, &execution.CorrelationID,
+		)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan deleted execution: %w", err)
+		}
+		traces = append(traces, models.ExecutionTraceResponse{Execution: execution, Steps: []models.StepExecution{}})
+		ids = append(ids, execution.ID.String())
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating deleted executions: %w", err)
+	}
+
+	if len(traces) == 0 {
+		return 0, nil
+	}
+
+	if export != nil {
+		stepQuery := `
+			SELECT id, organization_id, execution_id, step_id, step_type, status,
+			       input, output, started_at, completed_at, duration_ms, error_message, sequence
+			FROM step_executions
+			WHERE execution_id = ANY($1::uuid[])
+			ORDER BY started_at ASC, sequence ASC`
+
+		stepRows, err := tx.QueryContext(ctx, stepQuery, pq.Array(ids))
+		if err != nil {
+			return 0, fmt.Errorf("failed to get step executions: %w", err)
+		}
+		steps, err := scanStepExecutionList(stepRows)
+		stepRows.Close()
+		if err != nil {
+			return 0, err
+		}
+
+		byExecution := make(map[uuid.UUID]int, len(traces))
+		for i, trace := range traces {
+			byExecution[trace.Execution.ID] = i
+		}
+		for _, step := range steps {
+			i := byExecution[step.ExecutionID]
+			traces[i].Steps = append(traces[i].Steps, step)
+		}
+
+		if err := export(ctx, traces); err != nil {
+			return 0, fmt.Errorf("failed to export executions: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM step_executions WHERE execution_id = ANY($1::uuid[])`, pq.Array(ids)); err != nil {
+		return 0, fmt.Errorf("failed to purge step executions: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM workflow_executions WHERE id = ANY($1::uuid[])`, pq.Array(ids)); err != nil {
+		return 0, fmt.Errorf("failed to purge executions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(traces), nil
+}
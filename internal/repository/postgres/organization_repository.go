@@ -225,7 +225,8 @@ func (r *OrganizationRepository) GetUserOrganizations(ctx context.Context, userI
 	return orgs, nil
 }
 
-// AddUser adds a user to an organization with a role
+// AddUser adds a user to an organization with a role, assigning them the role within the
+// organization
 func (r *OrganizationRepository) AddUser(ctx context.Context, orgID, userID, roleID uuid.UUID, invitedBy *uuid.UUID) (*models.OrganizationUser, error) {
 	orgUser := &models.OrganizationUser{
 		ID:             uuid.New(),
@@ -243,7 +244,13 @@ func (r *OrganizationRepository) AddUser(ctx context.Context, orgID, userID, rol
 		) VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, joined_at`
 
-	err := r.db.QueryRowContext(
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(
 		ctx, query,
 		orgUser.ID, orgUser.OrganizationID, orgUser.UserID, orgUser.RoleID,
 		orgUser.IsActive, orgUser.JoinedAt, orgUser.InvitedBy,
@@ -253,13 +260,32 @@ func (r *OrganizationRepository) AddUser(ctx context.Context, orgID, userID, rol
 		return nil, fmt.Errorf("failed to add user to organization: %w", err)
 	}
 
+	roleQuery := `
+		INSERT INTO user_roles (user_id, role_id, organization_id, assigned_at, assigned_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING`
+
+	if _, err := tx.ExecContext(ctx, roleQuery, userID, roleID, orgID, orgUser.JoinedAt, invitedBy); err != nil {
+		return nil, fmt.Errorf("failed to assign organization role: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return orgUser, nil
 }
 
-// RemoveUser removes a user from an organization
+// RemoveUser removes a user from an organization along with their roles in it
 func (r *OrganizationRepository) RemoveUser(ctx context.Context, orgID, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `DELETE FROM organization_users WHERE organization_id = $1 AND user_id = $2`
-	result, err := r.db.ExecContext(ctx, query, orgID, userID)
+	result, err := tx.ExecContext(ctx, query, orgID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to remove user from organization: %w", err)
 	}
@@ -273,6 +299,15 @@ func (r *OrganizationRepository) RemoveUser(ctx context.Context, orgID, userID u
 		return fmt.Errorf("user not found in organization")
 	}
 
+	roleQuery := `DELETE FROM user_roles WHERE organization_id = $1 AND user_id = $2`
+	if _, err := tx.ExecContext(ctx, roleQuery, orgID, userID); err != nil {
+		return fmt.Errorf("failed to remove organization roles: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
@@ -321,25 +356,54 @@ func (r *OrganizationRepository) GetOrganizationUsers(ctx context.Context, orgID
 	return users, nil
 }
 
-// UpdateUserRole updates a user's role in an organization
+// UpdateUserRole updates a user's role in an organization, replacing the role assigned to them
+// within the organization
 func (r *OrganizationRepository) UpdateUserRole(ctx context.Context, orgID, userID, roleID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the membership so concurrent updates swap the assigned role one at a time
+	var previousRoleID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		SELECT role_id FROM organization_users
+		WHERE organization_id = $1 AND user_id = $2
+		FOR UPDATE`, orgID, userID).Scan(&previousRoleID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user not found in organization")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user role: %w", err)
+	}
+
 	query := `
 		UPDATE organization_users
 		SET role_id = $1
 		WHERE organization_id = $2 AND user_id = $3`
 
-	result, err := r.db.ExecContext(ctx, query, roleID, orgID, userID)
-	if err != nil {
+	if _, err := tx.ExecContext(ctx, query, roleID, orgID, userID); err != nil {
 		return fmt.Errorf("failed to update user role: %w", err)
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM user_roles
+		WHERE organization_id = $1 AND user_id = $2 AND role_id = $3`,
+		orgID, userID, previousRoleID); err != nil {
+		return fmt.Errorf("failed to remove previous organization role: %w", err)
 	}
 
-	if rows == 0 {
-		return fmt.Errorf("user not found in organization")
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_roles (user_id, role_id, organization_id, assigned_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`,
+		userID, roleID, orgID, time.Now()); err != nil {
+		return fmt.Errorf("failed to assign organization role: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
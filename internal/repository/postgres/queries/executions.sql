@@ -64,7 +64,7 @@ WHERE id = $1;
 -- name: ListStepExecutions :many
 SELECT * FROM step_executions
 WHERE execution_id = $1
-ORDER BY started_at ASC;
+ORDER BY started_at ASC, sequence ASC;
 
 -- name: GetStepExecution :one
 SELECT * FROM step_executions
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrRetentionPolicyNotFound is returned when an organization has no retention window of its own
+var ErrRetentionPolicyNotFound = errors.New("retention policy not found")
+
+// RetentionRepository handles retention policy database operations
+type RetentionRepository struct {
+	db *sql.DB
+}
+
+// NewRetentionRepository creates a new retention repository
+func NewRetentionRepository(db *sql.DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+// GetPolicy returns the organization's own retention window, or ErrRetentionPolicyNotFound
+// when the configured default applies
+func (r *RetentionRepository) GetPolicy(ctx context.Context, organizationID uuid.UUID) (*models.RetentionPolicy, error) {
+	query := `
+		SELECT organization_id, retention_days, created_at, updated_at
+		FROM retention_policies
+		WHERE organization_id = $1`
+
+	policy := &models.RetentionPolicy{}
+	err := r.db.QueryRowContext(ctx, query, organizationID).Scan(
+		&policy.OrganizationID, &policy.RetentionDays, &policy.CreatedAt, &policy.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrRetentionPolicyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// SetPolicy creates or replaces the organization's retention window
+func (r *RetentionRepository) SetPolicy(ctx context.Context, organizationID uuid.UUID, retentionDays int) (*models.RetentionPolicy, error) {
+	query := `
+		INSERT INTO retention_policies (organization_id, retention_days)
+		VALUES ($1, $2)
+		ON CONFLICT (organization_id) DO UPDATE SET retention_days = EXCLUDED.retention_days
+		RETURNING organization_id, retention_days, created_at, updated_at`
+
+	policy := &models.RetentionPolicy{}
+	err := r.db.QueryRowContext(ctx, query, organizationID, retentionDays).Scan(
+		&policy.OrganizationID, &policy.RetentionDays, &policy.CreatedAt, &policy.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set retention policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// DeletePolicy removes the organization's retention window, so the configured default applies
+func (r *RetentionRepository) DeletePolicy(ctx context.Context, organizationID uuid.UUID) error {
+	query := `DELETE FROM retention_policies WHERE organization_id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to delete retention policy: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrRetentionPolicyNotFound
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,6 +11,13 @@ import (
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrRoleNotFound is returned when a role does not exist
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrPermissionNotGranted is returned when revoking a permission a role was not granted
+	ErrPermissionNotGranted = errors.New("permission grant not found")
+)
+
 // RoleRepository handles role database operations
 type RoleRepository struct {
 	db *sql.DB
@@ -24,17 +32,17 @@ func NewRoleRepository(db *sql.DB) *RoleRepository {
 func (r *RoleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Role, error) {
 	role := &models.Role{}
 	query := `
-		SELECT id, name, description, created_at, updated_at
+		SELECT id, name, description, organization_id, created_at, updated_at
 		FROM roles
 		WHERE id = $1`
 
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&role.ID, &role.Name, &role.Description,
+		&role.ID, &role.Name, &role.Description, &role.OrganizationID,
 		&role.CreatedAt, &role.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("role not found")
+		return nil, ErrRoleNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get role: %w", err)
@@ -47,17 +55,17 @@ func (r *RoleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Rol
 func (r *RoleRepository) GetByName(ctx context.Context, name string) (*models.Role, error) {
 	role := &models.Role{}
 	query := `
-		SELECT id, name, description, created_at, updated_at
+		SELECT id, name, description, organization_id, created_at, updated_at
 		FROM roles
 		WHERE name = $1`
 
 	err := r.db.QueryRowContext(ctx, query, name).Scan(
-		&role.ID, &role.Name, &role.Description,
+		&role.ID, &role.Name, &role.Description, &role.OrganizationID,
 		&role.CreatedAt, &role.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("role not found")
+		return nil, ErrRoleNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get role: %w", err)
@@ -69,7 +77,7 @@ func (r *RoleRepository) GetByName(ctx context.Context, name string) (*models.Ro
 // List retrieves all roles
 func (r *RoleRepository) List(ctx context.Context) ([]*models.Role, error) {
 	query := `
-		SELECT id, name, description, created_at, updated_at
+		SELECT id, name, description, organization_id, created_at, updated_at
 		FROM roles
 		ORDER BY name`
 
@@ -79,11 +87,33 @@ func (r *RoleRepository) List(ctx context.Context) ([]*models.Role, error) {
 	}
 	defer rows.Close()
 
+	return scanRoles(rows)
+}
+
+// ListForOrganization retrieves the built-in roles and the custom roles of an organization
+func (r *RoleRepository) ListForOrganization(ctx context.Context, organizationID uuid.UUID) ([]*models.Role, error) {
+	query := `
+		SELECT id, name, description, organization_id, created_at, updated_at
+		FROM roles
+		WHERE organization_id IS NULL OR organization_id = $1
+		ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRoles(rows)
+}
+
+// scanRoles scans the roles in rows
+func scanRoles(rows *sql.Rows) ([]*models.Role, error) {
 	var roles []*models.Role
 	for rows.Next() {
 		role := &models.Role{}
 		err := rows.Scan(
-			&role.ID, &role.Name, &role.Description,
+			&role.ID, &role.Name, &role.Description, &role.OrganizationID,
 			&role.CreatedAt, &role.UpdatedAt,
 		)
 		if err != nil {
@@ -95,6 +125,49 @@ func (r *RoleRepository) List(ctx context.Context) ([]*models.Role, error) {
 	return roles, nil
 }
 
+// Create creates a role granted permissionIDs
+func (r *RoleRepository) Create(ctx context.Context, role *models.Role, permissionIDs []uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	role.ID = uuid.New()
+	now := time.Now()
+	role.CreatedAt = now
+	role.UpdatedAt = now
+
+	query := `
+		INSERT INTO roles (id, name, description, organization_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err = tx.ExecContext(ctx, query,
+		role.ID, role.Name, role.Description, role.OrganizationID, role.CreatedAt, role.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+
+	for _, permissionID := range permissionIDs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO role_permissions (role_id, permission_id, granted_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (role_id, permission_id) DO NOTHING`,
+			role.ID, permissionID, now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to grant permission: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 // GetRolePermissions retrieves all permissions for a role
 func (r *RoleRepository) GetRolePermissions(ctx context.Context, roleID uuid.UUID) ([]*models.Permission, error) {
 	query := `
@@ -156,7 +229,7 @@ func (r *RoleRepository) RevokePermission(ctx context.Context, roleID, permissio
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("permission grant not found")
+		return ErrPermissionNotGranted
 	}
 
 	return nil
@@ -20,20 +20,32 @@ func NewScheduleRepository(db *sql.DB) *ScheduleRepository {
 	return &ScheduleRepository{db: db}
 }
 
+// utcTime returns t converted to UTC, or nil when t is nil. Trigger times are stored in
+// TIMESTAMP columns, which drop the UTC offset, while schedules compute them in their own
+// timezone, so they are converted to UTC before they are written or compared
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
 // Create creates a new workflow schedule
 func (r *ScheduleRepository) Create(ctx context.Context, schedule *models.WorkflowSchedule) error {
 	query := `
 		INSERT INTO workflow_schedules (
 			id, organization_id, workflow_id, cron_expression, timezone, enabled,
-			last_triggered_at, next_trigger_at, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			last_triggered_at, next_trigger_at, created_at, updated_at, missed_run_policy, run_at
+		) VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at, updated_at`
 
 	err := r.db.QueryRowContext(
 		ctx, query,
 		schedule.ID, schedule.OrganizationID, schedule.WorkflowID, schedule.CronExpression,
-		schedule.Timezone, schedule.Enabled, schedule.LastTriggeredAt,
-		schedule.NextTriggerAt, schedule.CreatedAt, schedule.UpdatedAt,
+		schedule.Timezone, schedule.Enabled, utcTime(schedule.LastTriggeredAt),
+		utcTime(schedule.NextTriggerAt), schedule.CreatedAt, schedule.UpdatedAt, schedule.MissedRunPolicy,
+		utcTime(schedule.RunAt),
 	).Scan(&schedule.ID, &schedule.CreatedAt, &schedule.UpdatedAt)
 
 	if err != nil {
@@ -47,15 +59,15 @@ func (r *ScheduleRepository) Create(ctx context.Context, schedule *models.Workfl
 func (r *ScheduleRepository) GetByID(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowSchedule, error) {
 	schedule := &models.WorkflowSchedule{}
 	query := `
-		SELECT id, organization_id, workflow_id, cron_expression, timezone, enabled,
-		       last_triggered_at, next_trigger_at, created_at, updated_at
+		SELECT id, organization_id, workflow_id, COALESCE(cron_expression, ''), timezone, enabled,
+		       last_triggered_at, next_trigger_at, created_at, updated_at, missed_run_policy, run_at
 		FROM workflow_schedules
 		WHERE organization_id = $1 AND id = $2`
 
 	err := r.db.QueryRowContext(ctx, query, organizationID, id).Scan(
 		&schedule.ID, &schedule.OrganizationID, &schedule.WorkflowID, &schedule.CronExpression,
 		&schedule.Timezone, &schedule.Enabled, &schedule.LastTriggeredAt,
-		&schedule.NextTriggerAt, &schedule.CreatedAt, &schedule.UpdatedAt,
+		&schedule.NextTriggerAt, &schedule.CreatedAt, &schedule.UpdatedAt, &schedule.MissedRunPolicy, &schedule.RunAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -71,8 +83,8 @@ func (r *ScheduleRepository) GetByID(ctx context.Context, organizationID, id uui
 // GetByWorkflowID retrieves all schedules for a workflow within an organization
 func (r *ScheduleRepository) GetByWorkflowID(ctx context.Context, organizationID, workflowID uuid.UUID) ([]*models.WorkflowSchedule, error) {
 	query := `
-		SELECT id, organization_id, workflow_id, cron_expression, timezone, enabled,
-		       last_triggered_at, next_trigger_at, created_at, updated_at
+		SELECT id, organization_id, workflow_id, COALESCE(cron_expression, ''), timezone, enabled,
+		       last_triggered_at, next_trigger_at, created_at, updated_at, missed_run_policy, run_at
 		FROM workflow_schedules
 		WHERE organization_id = $1 AND workflow_id = $2
 		ORDER BY created_at DESC`
@@ -89,7 +101,7 @@ func (r *ScheduleRepository) GetByWorkflowID(ctx context.Context, organizationID
 		err := rows.Scan(
 			&schedule.ID, &schedule.OrganizationID, &schedule.WorkflowID, &schedule.CronExpression,
 			&schedule.Timezone, &schedule.Enabled, &schedule.LastTriggeredAt,
-			&schedule.NextTriggerAt, &schedule.CreatedAt, &schedule.UpdatedAt,
+			&schedule.NextTriggerAt, &schedule.CreatedAt, &schedule.UpdatedAt, &schedule.MissedRunPolicy, &schedule.RunAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan schedule: %w", err)
@@ -103,16 +115,17 @@ func (r *ScheduleRepository) GetByWorkflowID(ctx context.Context, organizationID
 // GetDueSchedules retrieves all enabled schedules that are due to run within an organization
 func (r *ScheduleRepository) GetDueSchedules(ctx context.Context, organizationID uuid.UUID) ([]*models.WorkflowSchedule, error) {
 	query := `
-		SELECT id, organization_id, workflow_id, cron_expression, timezone, enabled,
-		       last_triggered_at, next_trigger_at, created_at, updated_at
+		SELECT id, organization_id, workflow_id, COALESCE(cron_expression, ''), timezone, enabled,
+		       last_triggered_at, next_trigger_at, created_at, updated_at, missed_run_policy, run_at
 		FROM workflow_schedules
 		WHERE organization_id = $1
 		  AND enabled = true
 		  AND next_trigger_at IS NOT NULL
 		  AND next_trigger_at <= $2
+		  AND (claimed_until IS NULL OR claimed_until <= $2)
 		ORDER BY next_trigger_at ASC`
 
-	rows, err := r.db.QueryContext(ctx, query, organizationID, time.Now())
+	rows, err := r.db.QueryContext(ctx, query, organizationID, time.Now().UTC())
 	if err != nil {
 		return nil, fmt.Errorf("failed to query due schedules: %w", err)
 	}
@@ -124,7 +137,7 @@ func (r *ScheduleRepository) GetDueSchedules(ctx context.Context, organizationID
 		err := rows.Scan(
 			&schedule.ID, &schedule.OrganizationID, &schedule.WorkflowID, &schedule.CronExpression,
 			&schedule.Timezone, &schedule.Enabled, &schedule.LastTriggeredAt,
-			&schedule.NextTriggerAt, &schedule.CreatedAt, &schedule.UpdatedAt,
+			&schedule.NextTriggerAt, &schedule.CreatedAt, &schedule.UpdatedAt, &schedule.MissedRunPolicy, &schedule.RunAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan schedule: %w", err)
@@ -135,16 +148,75 @@ func (r *ScheduleRepository) GetDueSchedules(ctx context.Context, organizationID
 	return schedules, nil
 }
 
+// ClaimDueSchedules claims up to limit enabled schedules, across all organizations, that are due
+// at now and not already claimed. Each claim lasts until now+claimTTL; rows being claimed by a
+// concurrent caller are skipped, so each due run is handed to a single caller
+func (r *ScheduleRepository) ClaimDueSchedules(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]*models.WorkflowSchedule, error) {
+	query := `
+		UPDATE workflow_schedules
+		SET claimed_until = $2
+		WHERE id IN (
+			SELECT id FROM workflow_schedules
+			WHERE enabled = true
+			  AND next_trigger_at IS NOT NULL
+			  AND next_trigger_at <= $1
+			  AND (claimed_until IS NULL OR claimed_until <= $1)
+			ORDER BY next_trigger_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, organization_id, workflow_id, COALESCE(cron_expression, ''), timezone, enabled,
+		          last_triggered_at, next_trigger_at, created_at, updated_at, missed_run_policy, run_at`
+
+	rows, err := r.db.QueryContext(ctx, query, now.UTC(), now.Add(claimTTL).UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []*models.WorkflowSchedule{}
+	for rows.Next() {
+		schedule := &models.WorkflowSchedule{}
+		err := rows.Scan(
+			&schedule.ID, &schedule.OrganizationID, &schedule.WorkflowID, &schedule.CronExpression,
+			&schedule.Timezone, &schedule.Enabled, &schedule.LastTriggeredAt,
+			&schedule.NextTriggerAt, &schedule.CreatedAt, &schedule.UpdatedAt, &schedule.MissedRunPolicy, &schedule.RunAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+// ReleaseClaim releases a schedule's claim without advancing it, so the run is retried
+func (r *ScheduleRepository) ReleaseClaim(ctx context.Context, organizationID, id uuid.UUID) error {
+	query := `
+		UPDATE workflow_schedules
+		SET claimed_until = NULL
+		WHERE organization_id = $1 AND id = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, organizationID, id); err != nil {
+		return fmt.Errorf("failed to release schedule claim: %w", err)
+	}
+
+	return nil
+}
+
 // Update updates a workflow schedule within an organization
 func (r *ScheduleRepository) Update(ctx context.Context, organizationID uuid.UUID, schedule *models.WorkflowSchedule) error {
 	query := `
 		UPDATE workflow_schedules
-		SET cron_expression = $3,
+		SET cron_expression = NULLIF($3, ''),
 		    timezone = $4,
 		    enabled = $5,
 		    last_triggered_at = $6,
 		    next_trigger_at = $7,
-		    updated_at = $8
+		    updated_at = $8,
+		    missed_run_policy = $9,
+		    run_at = $10
 		WHERE organization_id = $1 AND id = $2`
 
 	schedule.UpdatedAt = time.Now()
@@ -152,8 +224,8 @@ func (r *ScheduleRepository) Update(ctx context.Context, organizationID uuid.UUI
 	result, err := r.db.ExecContext(
 		ctx, query,
 		organizationID, schedule.ID, schedule.CronExpression, schedule.Timezone,
-		schedule.Enabled, schedule.LastTriggeredAt, schedule.NextTriggerAt,
-		schedule.UpdatedAt,
+		schedule.Enabled, utcTime(schedule.LastTriggeredAt), utcTime(schedule.NextTriggerAt),
+		schedule.UpdatedAt, schedule.MissedRunPolicy, utcTime(schedule.RunAt),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update schedule: %w", err)
@@ -181,7 +253,7 @@ func (r *ScheduleRepository) UpdateNextTrigger(ctx context.Context, organization
 
 	result, err := r.db.ExecContext(
 		ctx, query,
-		organizationID, id, lastTriggered, nextTrigger, time.Now(),
+		organizationID, id, lastTriggered.UTC(), nextTrigger.UTC(), time.Now(),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update schedule trigger times: %w", err)
@@ -198,6 +270,32 @@ func (r *ScheduleRepository) UpdateNextTrigger(ctx context.Context, organization
 	return nil
 }
 
+// Complete records a one-shot schedule's run and disables it, so it does not run again
+func (r *ScheduleRepository) Complete(ctx context.Context, organizationID, id uuid.UUID, lastTriggered time.Time) error {
+	query := `
+		UPDATE workflow_schedules
+		SET last_triggered_at = $3,
+		    next_trigger_at = NULL,
+		    enabled = false,
+		    updated_at = $4
+		WHERE organization_id = $1 AND id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, organizationID, id, lastTriggered.UTC(), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to complete schedule: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("schedule not found")
+	}
+
+	return nil
+}
+
 // Delete deletes a workflow schedule within an organization
 func (r *ScheduleRepository) Delete(ctx context.Context, organizationID, id uuid.UUID) error {
 	query := `DELETE FROM workflow_schedules WHERE organization_id = $1 AND id = $2`
@@ -230,8 +328,8 @@ func (r *ScheduleRepository) List(ctx context.Context, organizationID uuid.UUID,
 
 	// Get schedules
 	query := `
-		SELECT id, organization_id, workflow_id, cron_expression, timezone, enabled,
-		       last_triggered_at, next_trigger_at, created_at, updated_at
+		SELECT id, organization_id, workflow_id, COALESCE(cron_expression, ''), timezone, enabled,
+		       last_triggered_at, next_trigger_at, created_at, updated_at, missed_run_policy, run_at
 		FROM workflow_schedules
 		WHERE organization_id = $1
 		ORDER BY created_at DESC
@@ -249,7 +347,7 @@ func (r *ScheduleRepository) List(ctx context.Context, organizationID uuid.UUID,
 		err := rows.Scan(
 			&schedule.ID, &schedule.OrganizationID, &schedule.WorkflowID, &schedule.CronExpression,
 			&schedule.Timezone, &schedule.Enabled, &schedule.LastTriggeredAt,
-			&schedule.NextTriggerAt, &schedule.CreatedAt, &schedule.UpdatedAt,
+			&schedule.NextTriggerAt, &schedule.CreatedAt, &schedule.UpdatedAt, &schedule.MissedRunPolicy, &schedule.RunAt,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan schedule: %w", err)
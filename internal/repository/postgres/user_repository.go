@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,6 +11,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrRoleAssignmentNotFound is returned when removing a role a user was not assigned
+var ErrRoleAssignmentNotFound = errors.New("role assignment not found")
+
 // UserRepository handles user database operations
 type UserRepository struct {
 	db *sql.DB
@@ -255,15 +259,16 @@ func (r *UserRepository) List(ctx context.Context) ([]*models.User, error) {
 	return users, nil
 }
 
-// GetUserRoles retrieves all roles for a user
-func (r *UserRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+// GetUserRoles retrieves a user's roles in an organization, including their global roles
+func (r *UserRepository) GetUserRoles(ctx context.Context, userID, organizationID uuid.UUID) ([]string, error) {
 	query := `
-		SELECT r.name
+		SELECT DISTINCT r.name
 		FROM roles r
 		INNER JOIN user_roles ur ON ur.role_id = r.id
-		WHERE ur.user_id = $1`
+		WHERE ur.user_id = $1
+		  AND (ur.organization_id = $2 OR ur.organization_id IS NULL)`
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, userID, organizationID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user roles: %w", err)
 	}
@@ -281,16 +286,18 @@ func (r *UserRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]
 	return roles, nil
 }
 
-// GetUserPermissions retrieves all permissions for a user
-func (r *UserRepository) GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]string, error) {
+// GetUserPermissions retrieves a user's permissions in an organization, including those granted
+// by their global roles
+func (r *UserRepository) GetUserPermissions(ctx context.Context, userID, organizationID uuid.UUID) ([]string, error) {
 	query := `
 		SELECT DISTINCT p.name
 		FROM permissions p
 		INNER JOIN role_permissions rp ON rp.permission_id = p.id
 		INNER JOIN user_roles ur ON ur.role_id = rp.role_id
-		WHERE ur.user_id = $1`
+		WHERE ur.user_id = $1
+		  AND (ur.organization_id = $2 OR ur.organization_id IS NULL)`
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, userID, organizationID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user permissions: %w", err)
 	}
@@ -308,14 +315,14 @@ func (r *UserRepository) GetUserPermissions(ctx context.Context, userID uuid.UUI
 	return permissions, nil
 }
 
-// AssignRole assigns a role to a user
-func (r *UserRepository) AssignRole(ctx context.Context, userID, roleID, assignedBy uuid.UUID) error {
+// AssignRole assigns a role to a user within an organization, or globally if organizationID is nil
+func (r *UserRepository) AssignRole(ctx context.Context, userID, roleID uuid.UUID, organizationID *uuid.UUID, assignedBy uuid.UUID) error {
 	query := `
-		INSERT INTO user_roles (user_id, role_id, assigned_at, assigned_by)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (user_id, role_id) DO NOTHING`
+		INSERT INTO user_roles (user_id, role_id, organization_id, assigned_at, assigned_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING`
 
-	_, err := r.db.ExecContext(ctx, query, userID, roleID, time.Now(), assignedBy)
+	_, err := r.db.ExecContext(ctx, query, userID, roleID, organizationID, time.Now(), assignedBy)
 	if err != nil {
 		return fmt.Errorf("failed to assign role: %w", err)
 	}
@@ -323,11 +330,13 @@ func (r *UserRepository) AssignRole(ctx context.Context, userID, roleID, assigne
 	return nil
 }
 
-// RemoveRole removes a role from a user
-func (r *UserRepository) RemoveRole(ctx context.Context, userID, roleID uuid.UUID) error {
-	query := `DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2`
+// RemoveRole removes a role from a user within an organization, or globally if organizationID is nil
+func (r *UserRepository) RemoveRole(ctx context.Context, userID, roleID uuid.UUID, organizationID *uuid.UUID) error {
+	query := `
+		DELETE FROM user_roles
+		WHERE user_id = $1 AND role_id = $2 AND organization_id IS NOT DISTINCT FROM $3`
 
-	result, err := r.db.ExecContext(ctx, query, userID, roleID)
+	result, err := r.db.ExecContext(ctx, query, userID, roleID, organizationID)
 	if err != nil {
 		return fmt.Errorf("failed to remove role: %w", err)
 	}
@@ -338,7 +347,7 @@ func (r *UserRepository) RemoveRole(ctx context.Context, userID, roleID uuid.UUI
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("role assignment not found")
+		return ErrRoleAssignmentNotFound
 	}
 
 	return nil
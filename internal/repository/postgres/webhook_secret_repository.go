@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// WebhookSecretRepository handles webhook secret database operations
+type WebhookSecretRepository struct {
+	db *sql.DB
+}
+
+// NewWebhookSecretRepository creates a new webhook secret repository
+func NewWebhookSecretRepository(db *sql.DB) *WebhookSecretRepository {
+	return &WebhookSecretRepository{db: db}
+}
+
+// GetSecret returns the secret that signs events from a source, falling back to the
+// organization's '*' secret. It returns an empty secret if neither is set
+func (r *WebhookSecretRepository) GetSecret(ctx context.Context, organizationID uuid.UUID, source string) (string, error) {
+	query := `
+		SELECT secret FROM webhook_secrets
+		WHERE organization_id = $1 AND source IN ($2, '*')
+		ORDER BY source = '*'
+		LIMIT 1`
+
+	var secret string
+	err := r.db.QueryRowContext(ctx, query, organizationID, source).Scan(&secret)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get webhook secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// SetSecret creates or replaces the secret for a source
+func (r *WebhookSecretRepository) SetSecret(ctx context.Context, organizationID uuid.UUID, source, secret string) error {
+	query := `
+		INSERT INTO webhook_secrets (organization_id, source, secret)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id, source) DO UPDATE SET secret = EXCLUDED.secret`
+
+	if _, err := r.db.ExecContext(ctx, query, organizationID, source, secret); err != nil {
+		return fmt.Errorf("failed to set webhook secret: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSecret removes the secret for a source
+func (r *WebhookSecretRepository) DeleteSecret(ctx context.Context, organizationID uuid.UUID, source string) error {
+	query := `DELETE FROM webhook_secrets WHERE organization_id = $1 AND source = $2`
+
+	result, err := r.db.ExecContext(ctx, query, organizationID, source)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook secret: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("webhook secret not found")
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,6 +12,9 @@ import (
 	"github.com/lib/pq"
 )
 
+// ErrWorkflowVersionNotFound is returned when a workflow has no definition with the requested version number
+var ErrWorkflowVersionNotFound = errors.New("workflow version not found")
+
 // WorkflowRepository handles workflow database operations
 type WorkflowRepository struct {
 	db *sql.DB
@@ -36,14 +40,23 @@ func (r *WorkflowRepository) Create(ctx context.Context, organizationID uuid.UUI
 		UpdatedAt:      time.Now(),
 		CreatedBy:      createdBy,
 		Tags:           req.Tags,
+
+		DefinitionVersion: 1,
 	}
 
+	// The definition is recorded as the workflow's first version in the same statement
 	query := `
-		INSERT INTO workflows (
-			id, organization_id, workflow_id, version, name, description, definition,
-			enabled, created_at, updated_at, created_by, tags
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		RETURNING id, created_at, updated_at`
+		WITH created AS (
+			INSERT INTO workflows (
+				id, organization_id, workflow_id, version, name, description, definition,
+				enabled, created_at, updated_at, created_by, tags, definition_version
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, 1)
+			RETURNING id, organization_id, definition, created_at, updated_at, created_by
+		), version AS (
+			INSERT INTO workflow_versions (organization_id, workflow_id, version_number, definition, created_by, created_at)
+			SELECT organization_id, id, 1, definition, created_by, created_at FROM created
+		)
+		SELECT id, created_at, updated_at FROM created`
 
 	err := r.db.QueryRowContext(
 		ctx, query,
@@ -65,7 +78,7 @@ func (r *WorkflowRepository) GetByID(ctx context.Context, organizationID, id uui
 	workflow := &models.Workflow{}
 	query := `
 		SELECT id, organization_id, workflow_id, version, name, description, definition,
-		       enabled, created_at, updated_at, created_by, tags
+		       enabled, created_at, updated_at, created_by, tags, definition_version
 		FROM workflows
 		WHERE organization_id = $1 AND id = $2`
 
@@ -73,7 +86,7 @@ func (r *WorkflowRepository) GetByID(ctx context.Context, organizationID, id uui
 	err := r.db.QueryRowContext(ctx, query, organizationID, id).Scan(
 		&workflow.ID, &workflow.OrganizationID, &workflow.WorkflowID, &workflow.Version, &workflow.Name,
 		&workflow.Description, &workflow.Definition, &workflow.Enabled,
-		&workflow.CreatedAt, &workflow.UpdatedAt, &workflow.CreatedBy, &tags,
+		&workflow.CreatedAt, &workflow.UpdatedAt, &workflow.CreatedBy, &tags, &workflow.DefinitionVersion,
 	)
 
 	if err == sql.ErrNoRows {
@@ -92,7 +105,7 @@ func (r *WorkflowRepository) GetByWorkflowID(ctx context.Context, organizationID
 	workflow := &models.Workflow{}
 	query := `
 		SELECT id, organization_id, workflow_id, version, name, description, definition,
-		       enabled, created_at, updated_at, created_by, tags
+		       enabled, created_at, updated_at, created_by, tags, definition_version
 		FROM workflows
 		WHERE organization_id = $1 AND workflow_id = $2
 		ORDER BY created_at DESC
@@ -102,7 +115,7 @@ func (r *WorkflowRepository) GetByWorkflowID(ctx context.Context, organizationID
 	err := r.db.QueryRowContext(ctx, query, organizationID, workflowID).Scan(
 		&workflow.ID, &workflow.OrganizationID, &workflow.WorkflowID, &workflow.Version, &workflow.Name,
 		&workflow.Description, &workflow.Definition, &workflow.Enabled,
-		&workflow.CreatedAt, &workflow.UpdatedAt, &workflow.CreatedBy, &tags,
+		&workflow.CreatedAt, &workflow.UpdatedAt, &workflow.CreatedBy, &tags, &workflow.DefinitionVersion,
 	)
 
 	if err == sql.ErrNoRows {
@@ -129,7 +142,7 @@ func (r *WorkflowRepository) List(ctx context.Context, organizationID uuid.UUID,
 	// Get workflows
 	query := `
 		SELECT id, organization_id, workflow_id, version, name, description, definition,
-		       enabled, created_at, updated_at, created_by, tags
+		       enabled, created_at, updated_at, created_by, tags, definition_version
 		FROM workflows
 		WHERE organization_id = $1 AND ($2::boolean IS NULL OR enabled = $2)
 		ORDER BY created_at DESC
@@ -148,7 +161,7 @@ func (r *WorkflowRepository) List(ctx context.Context, organizationID uuid.UUID,
 		err := rows.Scan(
 			&workflow.ID, &workflow.OrganizationID, &workflow.WorkflowID, &workflow.Version, &workflow.Name,
 			&workflow.Description, &workflow.Definition, &workflow.Enabled,
-			&workflow.CreatedAt, &workflow.UpdatedAt, &workflow.CreatedBy, &tags,
+			&workflow.CreatedAt, &workflow.UpdatedAt, &workflow.CreatedBy, &tags, &workflow.DefinitionVersion,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan workflow: %w", err)
@@ -160,29 +173,41 @@ func (r *WorkflowRepository) List(ctx context.Context, organizationID uuid.UUID,
 	return workflows, total, nil
 }
 
-// Update updates a workflow within an organization
-func (r *WorkflowRepository) Update(ctx context.Context, organizationID, id uuid.UUID, req *models.UpdateWorkflowRequest) (*models.Workflow, error) {
+// Update updates a workflow within an organization. A new definition is recorded as the
+// workflow's next version, authored by updatedBy
+func (r *WorkflowRepository) Update(ctx context.Context, organizationID, id uuid.UUID, req *models.UpdateWorkflowRequest, updatedBy *uuid.UUID) (*models.Workflow, error) {
 	query := `
-		UPDATE workflows
-		SET name = COALESCE($3, name),
-		    description = COALESCE($4, description),
-		    definition = COALESCE($5, definition),
-		    tags = COALESCE($6, tags),
-		    updated_at = NOW()
-		WHERE organization_id = $1 AND id = $2
-		RETURNING id, organization_id, workflow_id, version, name, description, definition,
-		          enabled, created_at, updated_at, created_by, tags`
+		WITH updated AS (
+			UPDATE workflows
+			SET name = COALESCE($3, name),
+			    description = COALESCE($4, description),
+			    definition = COALESCE($5, definition),
+			    tags = COALESCE($6, tags),
+			    definition_version = definition_version + CASE WHEN $5 IS NULL THEN 0 ELSE 1 END,
+			    updated_at = NOW()
+			WHERE organization_id = $1 AND id = $2
+			RETURNING id, organization_id, workflow_id, version, name, description, definition,
+			          enabled, created_at, updated_at, created_by, tags, definition_version
+		), version AS (
+			INSERT INTO workflow_versions (organization_id, workflow_id, version_number, definition, created_by, created_at)
+			SELECT organization_id, id, definition_version, definition, $7, updated_at
+			FROM updated
+			WHERE $5 IS NOT NULL
+		)
+		SELECT id, organization_id, workflow_id, version, name, description, definition,
+		       enabled, created_at, updated_at, created_by, tags, definition_version
+		FROM updated`
 
 	workflow := &models.Workflow{}
 	var tags pq.StringArray
 
 	err := r.db.QueryRowContext(
 		ctx, query,
-		organizationID, id, req.Name, req.Description, req.Definition, pq.Array(req.Tags),
+		organizationID, id, req.Name, req.Description, req.Definition, pq.Array(req.Tags), updatedBy,
 	).Scan(
 		&workflow.ID, &workflow.OrganizationID, &workflow.WorkflowID, &workflow.Version, &workflow.Name,
 		&workflow.Description, &workflow.Definition, &workflow.Enabled,
-		&workflow.CreatedAt, &workflow.UpdatedAt, &workflow.CreatedBy, &tags,
+		&workflow.CreatedAt, &workflow.UpdatedAt, &workflow.CreatedBy, &tags, &workflow.DefinitionVersion,
 	)
 
 	if err == sql.ErrNoRows {
@@ -196,6 +221,63 @@ func (r *WorkflowRepository) Update(ctx context.Context, organizationID, id uuid
 	return workflow, nil
 }
 
+// ListVersions retrieves a workflow's definition history, newest first
+func (r *WorkflowRepository) ListVersions(ctx context.Context, organizationID, workflowID uuid.UUID) ([]*models.WorkflowVersion, error) {
+	query := `
+		SELECT id, organization_id, workflow_id, version_number, definition, created_by, created_at
+		FROM workflow_versions
+		WHERE organization_id = $1 AND workflow_id = $2
+		ORDER BY version_number DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, organizationID, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []*models.WorkflowVersion{}
+	for rows.Next() {
+		version := &models.WorkflowVersion{}
+		err := rows.Scan(
+			&version.ID, &version.OrganizationID, &version.WorkflowID, &version.Version,
+			&version.Definition, &version.CreatedBy, &version.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workflow version: %w", err)
+		}
+		versions = append(versions, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list workflow versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+// GetVersion retrieves one version of a workflow's definition
+func (r *WorkflowRepository) GetVersion(ctx context.Context, organizationID, workflowID uuid.UUID, version int) (*models.WorkflowVersion, error) {
+	query := `
+		SELECT id, organization_id, workflow_id, version_number, definition, created_by, created_at
+		FROM workflow_versions
+		WHERE organization_id = $1 AND workflow_id = $2 AND version_number = $3`
+
+	v := &models.WorkflowVersion{}
+	err := r.db.QueryRowContext(ctx, query, organizationID, workflowID, version).Scan(
+		&v.ID, &v.OrganizationID, &v.WorkflowID, &v.Version,
+		&v.Definition, &v.CreatedBy, &v.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrWorkflowVersionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow version: %w", err)
+	}
+
+	return v, nil
+}
+
 // Delete deletes a workflow within an organization
 func (r *WorkflowRepository) Delete(ctx context.Context, organizationID, id uuid.UUID) error {
 	query := `DELETE FROM workflows WHERE organization_id = $1 AND id = $2`
@@ -261,7 +343,7 @@ func (r *WorkflowRepository) ListWorkflows(ctx context.Context, organizationID u
 func (r *WorkflowRepository) ListByEventType(ctx context.Context, organizationID uuid.UUID, eventType string) ([]models.Workflow, error) {
 	query := `
 		SELECT id, organization_id, workflow_id, version, name, description, definition,
-		       enabled, created_at, updated_at, created_by, tags
+		       enabled, created_at, updated_at, created_by, tags, definition_version
 		FROM workflows
 		WHERE organization_id = $1 AND enabled = true
 		  AND definition->'trigger'->>'type' = 'event'
@@ -281,7 +363,7 @@ func (r *WorkflowRepository) ListByEventType(ctx context.Context, organizationID
 		err := rows.Scan(
 			&workflow.ID, &workflow.OrganizationID, &workflow.WorkflowID, &workflow.Version, &workflow.Name,
 			&workflow.Description, &workflow.Definition, &workflow.Enabled,
-			&workflow.CreatedAt, &workflow.UpdatedAt, &workflow.CreatedBy, &tags,
+			&workflow.CreatedAt, &workflow.UpdatedAt, &workflow.CreatedBy, &tags, &workflow.DefinitionVersion,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan workflow: %w", err)
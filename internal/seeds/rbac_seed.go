@@ -7,12 +7,22 @@ import (
 	"log"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // RBACSeeder handles seeding of roles, permissions, and default users
 type RBACSeeder struct {
 	db *sql.DB
+
+	// grantChanges are the grants the last successful seeding changed, by role name
+	grantChanges map[string]grantChanges
+}
+
+// grantChanges counts the permission grants seeding added to and removed from a role
+type grantChanges struct {
+	added   int64
+	removed int64
 }
 
 // NewRBACSeeder creates a new RBAC seeder
@@ -40,19 +50,32 @@ type RolePermission struct {
 	PermissionNames []string
 }
 
-// DefaultUser represents a default user to create
+// DefaultUser represents a default user to create. The user joins Organization, identified by
+// slug, with Roles assigned within it; GlobalRoles apply in every organization
 type DefaultUser struct {
-	Username  string
-	Email     string
-	Password  string
-	FirstName string
-	LastName  string
-	Roles     []string
+	Username     string
+	Email        string
+	Password     string
+	FirstName    string
+	LastName     string
+	Organization string
+	Roles        []string
+	GlobalRoles  []string
 }
 
+// SuperAdminRole is the only role assigned globally rather than within an organization
+const SuperAdminRole = "super_admin"
+
+// allPermissionRoles are granted every permission
+var allPermissionRoles = []string{"admin", SuperAdminRole}
+
 // GetDefaultRoles returns the default roles to seed
 func GetDefaultRoles() []Role {
 	return []Role{
+		{
+			Name:        SuperAdminRole,
+			Description: "Global administrator with full access to every organization",
+		},
 		{
 			Name:        "admin",
 			Description: "Administrator with full system access",
@@ -113,12 +136,27 @@ func GetDefaultPermissions() []Permission {
 		{"role:update", "role", "update", "Update roles"},
 		{"role:delete", "role", "delete", "Delete roles"},
 		{"role:assign", "role", "assign", "Assign roles to users"},
+
+		// Organization permissions
+		{"organization:create", "organization", "create", "Create organizations"},
+		{"organization:read", "organization", "read", "View organizations"},
+		{"organization:update", "organization", "update", "Update organizations"},
+		{"organization:delete", "organization", "delete", "Delete organizations"},
+		{"organization:manage_users", "organization", "manage_users", "Manage organization members"},
+
+		// Audit permissions
+		{"audit:read", "audit", "read", "View the audit log"},
 	}
 }
 
 // GetDefaultRolePermissions returns the default role-permission mappings
 func GetDefaultRolePermissions() []RolePermission {
 	return []RolePermission{
+		{
+			RoleName: SuperAdminRole,
+			// Super admin gets all permissions (will be dynamically assigned)
+			PermissionNames: []string{},
+		},
 		{
 			RoleName: "admin",
 			// Admin gets all permissions (will be dynamically assigned)
@@ -153,22 +191,34 @@ func GetDefaultRolePermissions() []RolePermission {
 	}
 }
 
-// GetDefaultUsers returns default users to create (optional, only admin)
-func GetDefaultUsers() []DefaultUser {
+// AdminCredentials are the login details of the default admin user
+type AdminCredentials struct {
+	Username string
+	Email    string
+	Password string
+}
+
+// GetDefaultUsers returns default users to create (optional, only admin), with the admin
+// logging in with the given credentials
+func GetDefaultUsers(admin AdminCredentials) []DefaultUser {
 	return []DefaultUser{
 		{
-			Username:  "admin",
-			Email:     "admin@example.com",
-			Password:  "admin123", // Should be changed on first login
-			FirstName: "System",
-			LastName:  "Administrator",
-			Roles:     []string{"admin"},
+			Username:     admin.Username,
+			Email:        admin.Email,
+			Password:     admin.Password,
+			FirstName:    "System",
+			LastName:     "Administrator",
+			Organization: "default",
+			Roles:        []string{"admin"},
+			GlobalRoles:  []string{SuperAdminRole},
 		},
 	}
 }
 
-// SeedAll seeds all RBAC data (roles, permissions, mappings, and optionally users)
-func (s *RBACSeeder) SeedAll(ctx context.Context, seedUsers bool) error {
+// SeedAll seeds all RBAC data (roles, permissions, mappings, and optionally users). No users
+// are created when users is empty. With force, permissions the default roles are granted but
+// should not be are revoked, so their grants match the default mappings
+func (s *RBACSeeder) SeedAll(ctx context.Context, users []DefaultUser, force bool) error {
 	log.Println("Starting RBAC seeding...")
 
 	// Start transaction
@@ -189,13 +239,14 @@ func (s *RBACSeeder) SeedAll(ctx context.Context, seedUsers bool) error {
 	}
 
 	// Seed role-permission mappings
-	if err := s.seedRolePermissions(ctx, tx); err != nil {
+	changes, err := s.seedRolePermissions(ctx, tx, force)
+	if err != nil {
 		return fmt.Errorf("failed to seed role-permissions: %w", err)
 	}
 
 	// Optionally seed default users
-	if seedUsers {
-		if err := s.seedDefaultUsers(ctx, tx); err != nil {
+	if len(users) > 0 {
+		if err := s.seedDefaultUsers(ctx, tx, users); err != nil {
 			return fmt.Errorf("failed to seed default users: %w", err)
 		}
 	}
@@ -204,6 +255,7 @@ func (s *RBACSeeder) SeedAll(ctx context.Context, seedUsers bool) error {
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	s.grantChanges = changes
 
 	log.Println("RBAC seeding completed successfully!")
 	return nil
@@ -256,63 +308,58 @@ func (s *RBACSeeder) seedPermissions(ctx context.Context, tx *sql.Tx) error {
 	return nil
 }
 
-// seedRolePermissions seeds role-permission mappings
-func (s *RBACSeeder) seedRolePermissions(ctx context.Context, tx *sql.Tx) error {
+// seedRolePermissions seeds role-permission mappings, adding the grants each role lacks and,
+// with force, revoking those it should not have. It returns the changes by role name
+func (s *RBACSeeder) seedRolePermissions(ctx context.Context, tx *sql.Tx, force bool) (map[string]grantChanges, error) {
 	log.Println("Seeding role-permission mappings...")
 
-	// First, handle admin role - give all permissions
-	_, err := tx.ExecContext(ctx, `
-		INSERT INTO role_permissions (role_id, permission_id, granted_at)
-		SELECT r.id, p.id, NOW()
-		FROM roles r
-		CROSS JOIN permissions p
-		WHERE r.name = 'admin'
-		ON CONFLICT (role_id, permission_id) DO NOTHING
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to assign permissions to admin role: %w", err)
-	}
-	log.Printf("  ✓ Admin: all permissions")
-
-	// Handle other roles
-	roleMappings := GetDefaultRolePermissions()
-	for _, mapping := range roleMappings {
-		if mapping.RoleName == "admin" || len(mapping.PermissionNames) == 0 {
-			continue // Already handled admin
+	changes := make(map[string]grantChanges)
+	for _, mapping := range GetDefaultRolePermissions() {
+		allPermissions := grantsAllPermissions(mapping.RoleName)
+
+		var change grantChanges
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO role_permissions (role_id, permission_id, granted_at)
+			SELECT r.id, p.id, NOW()
+			FROM roles r
+			CROSS JOIN permissions p
+			WHERE r.name = $1 AND ($2 OR p.name = ANY($3))
+			ON CONFLICT (role_id, permission_id) DO NOTHING
+		`, mapping.RoleName, allPermissions, pq.Array(mapping.PermissionNames))
+		if err != nil {
+			return nil, fmt.Errorf("failed to assign permissions to %s role: %w", mapping.RoleName, err)
 		}
+		change.added, _ = result.RowsAffected()
 
-		// Build the permissions list for SQL IN clause
-		permCount := 0
-		for _, permName := range mapping.PermissionNames {
+		// Roles granted every permission have nothing to revoke
+		if force && !allPermissions {
 			result, err := tx.ExecContext(ctx, `
-				INSERT INTO role_permissions (role_id, permission_id, granted_at)
-				SELECT r.id, p.id, NOW()
-				FROM roles r
-				CROSS JOIN permissions p
-				WHERE r.name = $1 AND p.name = $2
-				ON CONFLICT (role_id, permission_id) DO NOTHING
-			`, mapping.RoleName, permName)
-
+				DELETE FROM role_permissions rp
+				USING roles r, permissions p
+				WHERE rp.role_id = r.id AND rp.permission_id = p.id
+				  AND r.name = $1 AND NOT (p.name = ANY($2))
+			`, mapping.RoleName, pq.Array(mapping.PermissionNames))
 			if err != nil {
-				return fmt.Errorf("failed to assign permission %s to role %s: %w", permName, mapping.RoleName, err)
+				return nil, fmt.Errorf("failed to revoke stale permissions from %s role: %w", mapping.RoleName, err)
 			}
+			change.removed, _ = result.RowsAffected()
+		}
+		changes[mapping.RoleName] = change
 
-			rows, _ := result.RowsAffected()
-			if rows > 0 {
-				permCount++
-			}
+		if allPermissions {
+			log.Printf("  ✓ %s: all permissions (+%d, -%d)", mapping.RoleName, change.added, change.removed)
+		} else {
+			log.Printf("  ✓ %s: %d permissions (+%d, -%d)", mapping.RoleName, len(mapping.PermissionNames), change.added, change.removed)
 		}
-		log.Printf("  ✓ %s: %d permissions", mapping.RoleName, len(mapping.PermissionNames))
 	}
 
-	return nil
+	return changes, nil
 }
 
 // seedDefaultUsers seeds default users (optional)
-func (s *RBACSeeder) seedDefaultUsers(ctx context.Context, tx *sql.Tx) error {
+func (s *RBACSeeder) seedDefaultUsers(ctx context.Context, tx *sql.Tx, users []DefaultUser) error {
 	log.Println("Seeding default users...")
 
-	users := GetDefaultUsers()
 	for _, user := range users {
 		// Check if user already exists
 		var exists bool
@@ -346,22 +393,64 @@ func (s *RBACSeeder) seedDefaultUsers(ctx context.Context, tx *sql.Tx) error {
 			return fmt.Errorf("failed to create user %s: %w", user.Username, err)
 		}
 
-		// Assign roles to user
+		// Add user to their organization, with their first role as the membership role
+		var orgID uuid.UUID
+		err = tx.QueryRowContext(ctx, `
+			SELECT id FROM organizations WHERE slug = $1
+		`, user.Organization).Scan(&orgID)
+
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("organization %s for user %s does not exist", user.Organization, user.Username)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get organization %s: %w", user.Organization, err)
+		}
+
+		if len(user.Roles) > 0 {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO organization_users (organization_id, user_id, role_id, joined_at)
+				SELECT $1, $2, r.id, NOW()
+				FROM roles r
+				WHERE r.name = $3
+				ON CONFLICT (organization_id, user_id) DO NOTHING
+			`, orgID, userID, user.Roles[0])
+
+			if err != nil {
+				return fmt.Errorf("failed to add user %s to organization %s: %w", user.Username, user.Organization, err)
+			}
+		}
+
+		// Assign roles to user within the organization
 		for _, roleName := range user.Roles {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO user_roles (user_id, role_id, organization_id, assigned_at)
+				SELECT $1, r.id, $3, NOW()
+				FROM roles r
+				WHERE r.name = $2
+				ON CONFLICT DO NOTHING
+			`, userID, roleName, orgID)
+
+			if err != nil {
+				return fmt.Errorf("failed to assign role %s to user %s: %w", roleName, user.Username, err)
+			}
+		}
+
+		// Assign global roles to user
+		for _, roleName := range user.GlobalRoles {
 			_, err := tx.ExecContext(ctx, `
 				INSERT INTO user_roles (user_id, role_id, assigned_at)
 				SELECT $1, r.id, NOW()
 				FROM roles r
 				WHERE r.name = $2
-				ON CONFLICT (user_id, role_id) DO NOTHING
+				ON CONFLICT DO NOTHING
 			`, userID, roleName)
 
 			if err != nil {
-				return fmt.Errorf("failed to assign role %s to user %s: %w", roleName, user.Username, err)
+				return fmt.Errorf("failed to assign global role %s to user %s: %w", roleName, user.Username, err)
 			}
 		}
 
-		log.Printf("  ✓ User: %s (email: %s, password: %s)", user.Username, user.Email, user.Password)
+		log.Printf("  ✓ User: %s (email: %s)", user.Username, user.Email)
 	}
 
 	return nil
@@ -415,25 +504,25 @@ func (s *RBACSeeder) Verify(ctx context.Context) error {
 	for _, mapping := range roleMappings {
 		var count int
 
-		if mapping.RoleName == "admin" {
-			// Admin should have all permissions
+		if grantsAllPermissions(mapping.RoleName) {
+			// Admin roles should have all permissions
 			err := s.db.QueryRowContext(ctx, `
 				SELECT COUNT(*)
 				FROM role_permissions rp
 				JOIN roles r ON r.id = rp.role_id
-				WHERE r.name = 'admin'
-			`).Scan(&count)
+				WHERE r.name = $1
+			`, mapping.RoleName).Scan(&count)
 
 			if err != nil {
-				return fmt.Errorf("failed to check admin permissions: %w", err)
+				return fmt.Errorf("failed to check %s permissions: %w", mapping.RoleName, err)
 			}
 
 			expectedCount := len(expectedPermissions)
 			if count < expectedCount {
-				log.Printf("  ✗ Admin role has %d permissions, expected %d", count, expectedCount)
-				return fmt.Errorf("admin role is missing permissions")
+				log.Printf("  ✗ %s role has %d permissions, expected %d", mapping.RoleName, count, expectedCount)
+				return fmt.Errorf("%s role is missing permissions", mapping.RoleName)
 			}
-			log.Printf("  ✓ Admin: %d permissions", count)
+			log.Printf("  ✓ %s: %d permissions", mapping.RoleName, count)
 		} else if len(mapping.PermissionNames) > 0 {
 			err := s.db.QueryRowContext(ctx, `
 				SELECT COUNT(*)
@@ -454,10 +543,61 @@ func (s *RBACSeeder) Verify(ctx context.Context) error {
 		}
 	}
 
+	// Verify role assignments are scoped to organizations
+	log.Println("Verifying role assignments...")
+	var globalCount int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.organization_id IS NULL AND r.name <> $1
+	`, SuperAdminRole).Scan(&globalCount)
+
+	if err != nil {
+		return fmt.Errorf("failed to check global role assignments: %w", err)
+	}
+
+	if globalCount > 0 {
+		log.Printf("  ✗ %d global role assignments other than %s, which apply in no organization", globalCount, SuperAdminRole)
+	} else {
+		log.Printf("  ✓ Only %s is assigned globally", SuperAdminRole)
+	}
+
+	var nonMemberCount int
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM user_roles ur
+		WHERE ur.organization_id IS NOT NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM organization_users ou
+			WHERE ou.organization_id = ur.organization_id AND ou.user_id = ur.user_id
+		  )
+	`).Scan(&nonMemberCount)
+
+	if err != nil {
+		return fmt.Errorf("failed to check organization role assignments: %w", err)
+	}
+
+	if nonMemberCount > 0 {
+		log.Printf("  ✗ %d organization role assignments to users who are not members", nonMemberCount)
+	} else {
+		log.Printf("  ✓ Organization roles are assigned to members")
+	}
+
 	log.Println("✓ RBAC verification completed successfully!")
 	return nil
 }
 
+// grantsAllPermissions reports whether roleName is granted every permission
+func grantsAllPermissions(roleName string) bool {
+	for _, name := range allPermissionRoles {
+		if name == roleName {
+			return true
+		}
+	}
+	return false
+}
+
 // Stats returns statistics about RBAC data
 func (s *RBACSeeder) Stats(ctx context.Context) error {
 	log.Println("\n=== RBAC Statistics ===")
@@ -495,12 +635,14 @@ func (s *RBACSeeder) Stats(ctx context.Context) error {
 	log.Printf("Users: %d", userCount)
 
 	// Count user-role assignments
-	var userRoleCount int
-	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_roles").Scan(&userRoleCount)
+	var userRoleCount, globalRoleCount int
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE organization_id IS NULL) FROM user_roles
+	`).Scan(&userRoleCount, &globalRoleCount)
 	if err != nil {
 		return fmt.Errorf("failed to count user-role assignments: %w", err)
 	}
-	log.Printf("User-Role Assignments: %d", userRoleCount)
+	log.Printf("User-Role Assignments: %d (%d global, %d in organizations)", userRoleCount, globalRoleCount, userRoleCount-globalRoleCount)
 
 	// Show breakdown by role
 	log.Println("\n=== Permissions by Role ===")
@@ -522,9 +664,14 @@ func (s *RBACSeeder) Stats(ctx context.Context) error {
 		if err := rows.Scan(&roleName, &permCount); err != nil {
 			return fmt.Errorf("failed to scan role permission count: %w", err)
 		}
-		log.Printf("%s: %d permissions", roleName, permCount)
+		change, changed := s.grantChanges[roleName]
+		if !changed {
+			log.Printf("%s: %d permissions", roleName, permCount)
+			continue
+		}
+		log.Printf("%s: %d permissions (%d added, %d removed by seeding)", roleName, permCount, change.added, change.removed)
 	}
 
-	log.Println("========================\n")
+	log.Print("========================\n\n")
 	return nil
 }
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/database"
+	"github.com/davidmoltin/intelligent-workflows/pkg/llm"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// responseCacheKeyPrefix namespaces cached LLM responses. The version changes whenever the key
+// derivation does, so old entries are never read with a different meaning
+const responseCacheKeyPrefix = "llm:response:v1:"
+
+// responseCache caches LLM responses in Redis, keyed by a hash of the request
+type responseCache struct {
+	redis                 *database.RedisClient
+	ttl                   time.Duration
+	cacheNonDeterministic bool
+	defaultModel          string
+}
+
+// cacheKeyRequest is everything that determines a response. Its fields are marshalled in
+// declaration order and map keys are sorted, so the key is stable across process restarts
+type cacheKeyRequest struct {
+	Provider       llm.Provider           `json:"provider"`
+	Model          string                 `json:"model"`
+	SystemPrompt   string                 `json:"system_prompt"`
+	Messages       []llm.Message          `json:"messages"`
+	MaxTokens      int                    `json:"max_tokens"`
+	Temperature    float64                `json:"temperature"`
+	TopP           float64                `json:"top_p"`
+	StopSequences  []string               `json:"stop_sequences"`
+	ResponseSchema map[string]interface{} `json:"response_schema"`
+}
+
+// SetResponseCache caches responses to idempotent requests in Redis for cfg.CacheTTL (optional
+// dependency). Requests with a temperature above 0 are only cached if cfg.CacheNonDeterministic
+// is set
+func (s *AIService) SetResponseCache(redisClient *database.RedisClient, cfg *config.LLMConfig) {
+	if redisClient == nil || cfg == nil || cfg.CacheTTL <= 0 {
+		s.cache = nil
+		return
+	}
+	s.cache = &responseCache{
+		redis:                 redisClient,
+		ttl:                   cfg.CacheTTL,
+		cacheNonDeterministic: cfg.CacheNonDeterministic,
+		defaultModel:          cfg.DefaultModel,
+	}
+}
+
+// responseCacheKey returns the cache key of the request, or false if its response is not cached
+func (s *AIService) responseCacheKey(req *llm.ChatRequest) (string, bool) {
+	if s.cache == nil || (req.Temperature > 0 && !s.cache.cacheNonDeterministic) {
+		return "", false
+	}
+
+	model := req.Model
+	if model == "" {
+		model = s.cache.defaultModel
+	}
+	data, err := json.Marshal(cacheKeyRequest{
+		Provider:       s.llmClient.GetProvider(),
+		Model:          model,
+		SystemPrompt:   req.SystemPrompt,
+		Messages:       req.Messages,
+		MaxTokens:      req.MaxTokens,
+		Temperature:    req.Temperature,
+		TopP:           req.TopP,
+		StopSequences:  req.StopSequences,
+		ResponseSchema: req.ResponseSchema,
+	})
+	if err != nil {
+		return "", false
+	}
+
+	sum := sha256.Sum256(data)
+	return responseCacheKeyPrefix + hex.EncodeToString(sum[:]), true
+}
+
+// cachedResponse returns the response cached under key, or nil on a miss. Cached responses used
+// no tokens, so they report zero usage
+func (s *AIService) cachedResponse(ctx context.Context, key string) *llm.ChatResponse {
+	data, err := s.cache.redis.Get(ctx, key)
+	if err != nil {
+		if err == redis.Nil {
+			s.recordCacheRequest("miss")
+		} else {
+			s.recordCacheRequest("error")
+			s.logger.Warn("failed to read cached llm response", zap.Error(err))
+		}
+		return nil
+	}
+
+	var resp llm.ChatResponse
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		s.recordCacheRequest("error")
+		s.logger.Warn("failed to decode cached llm response", zap.Error(err))
+		return nil
+	}
+	s.recordCacheRequest("hit")
+
+	resp.Usage = &llm.TokenUsage{}
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]interface{})
+	}
+	resp.Metadata["cached"] = true
+	return &resp
+}
+
+// cacheResponse caches the response under key. Failures are logged, since the response has
+// already been paid for and can still be returned
+func (s *AIService) cacheResponse(ctx context.Context, key string, resp *llm.ChatResponse) {
+	data, err := json.Marshal(resp)
+	if err == nil {
+		err = s.cache.redis.Set(ctx, key, data, s.cache.ttl)
+	}
+	if err != nil {
+		s.logger.Warn("failed to cache llm response", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// recordCacheRequest counts a response cache lookup by result
+func (s *AIService) recordCacheRequest(result string) {
+	if s.metrics != nil {
+		s.metrics.LLMCacheRequests.WithLabelValues(string(s.llmClient.GetProvider()), result).Inc()
+	}
+}
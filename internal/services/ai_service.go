@@ -2,17 +2,39 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/circuitbreaker"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
 	"github.com/davidmoltin/intelligent-workflows/pkg/llm"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/metrics"
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrInvalidClassification is returned when the LLM answers a classification with something
+	// other than one of the candidate labels
+	ErrInvalidClassification = errors.New("classification is not one of the labels")
+
+	// ErrInvalidExtraction is returned when the LLM answers an extraction with something other than
+	// an object matching the schema
+	ErrInvalidExtraction = errors.New("extraction does not match the schema")
+)
+
 // AIService handles AI-related operations
 type AIService struct {
 	llmClient       llm.Client
 	templateManager *llm.TemplateManager
 	logger          *zap.Logger
+	metrics         *metrics.Metrics
+	cache           *responseCache
+	breaker         *circuitbreaker.Breaker
 }
 
 // NewAIService creates a new AI service
@@ -33,7 +55,36 @@ func NewAIService(llmClient llm.Client, logger *zap.Logger) (*AIService, error)
 	}, nil
 }
 
-// Chat sends a chat completion request
+// SetMetrics sets the metrics token usage and cost are recorded in (optional dependency)
+func (s *AIService) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
+// SetCircuitBreaker sets the breaker provider calls are made through, so they fail fast while
+// the provider is degraded (optional dependency). Create it with NewLLMCircuitBreaker
+func (s *AIService) SetCircuitBreaker(breaker *circuitbreaker.Breaker) {
+	s.breaker = breaker
+}
+
+// NewLLMCircuitBreaker creates a breaker for the LLM provider. Only errors showing the provider is
+// degraded (rate limits, timeouts and unavailability) count as failures, not rejected requests
+func NewLLMCircuitBreaker(cfg *config.CircuitBreakerConfig, log *logger.Logger, m *metrics.Metrics) *circuitbreaker.Breaker {
+	return circuitbreaker.New("llm", cfg, llm.IsRetryable, log, m)
+}
+
+// callProvider runs a provider call through the circuit breaker. A short-circuited call returns
+// a retryable service unavailable error wrapping circuitbreaker.ErrOpen
+func (s *AIService) callProvider(call func() error) error {
+	err := s.breaker.Execute(call)
+	if errors.Is(err, circuitbreaker.ErrOpen) {
+		return llm.NewError(s.llmClient.GetProvider(), llm.ErrorTypeServiceUnavailable,
+			"circuit open after repeated provider failures", err)
+	}
+	return err
+}
+
+// Chat sends a chat completion request. If a response cache is set, an identical idempotent
+// request is answered from it
 func (s *AIService) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
 	s.logger.Info("processing chat request",
 		zap.String("provider", string(s.llmClient.GetProvider())),
@@ -41,7 +92,23 @@ func (s *AIService) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRe
 		zap.Int("message_count", len(req.Messages)),
 	)
 
-	resp, err := s.llmClient.Chat(ctx, req)
+	cacheKey, cacheable := s.responseCacheKey(req)
+	if cacheable {
+		if cached := s.cachedResponse(ctx, cacheKey); cached != nil {
+			s.logger.Info("chat request answered from cache",
+				zap.String("id", cached.ID),
+				zap.String("model", cached.Model),
+			)
+			return cached, nil
+		}
+	}
+
+	var resp *llm.ChatResponse
+	err := s.callProvider(func() error {
+		var err error
+		resp, err = s.llmClient.Chat(ctx, req)
+		return err
+	})
 	if err != nil {
 		s.logger.Error("chat request failed",
 			zap.Error(err),
@@ -58,6 +125,11 @@ func (s *AIService) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRe
 		zap.Int("completion_tokens", resp.Usage.CompletionTokens),
 		zap.Int("total_tokens", resp.Usage.TotalTokens),
 	)
+	s.recordUsage(ctx, resp.Provider, resp.Model, resp.Usage)
+
+	if cacheable {
+		s.cacheResponse(ctx, cacheKey, resp)
+	}
 
 	return resp, nil
 }
@@ -70,7 +142,18 @@ func (s *AIService) StreamChat(ctx context.Context, req *llm.ChatRequest, handle
 		zap.Int("message_count", len(req.Messages)),
 	)
 
-	err := s.llmClient.StreamChat(ctx, req, handler)
+	err := s.callProvider(func() error {
+		return s.llmClient.StreamChat(ctx, req, func(chunk *llm.StreamChunk) error {
+			if chunk.IsComplete {
+				model := chunk.Model
+				if model == "" {
+					model = req.Model
+				}
+				s.recordUsage(ctx, s.llmClient.GetProvider(), model, chunk.Usage)
+			}
+			return handler(chunk)
+		})
+	})
 	if err != nil {
 		s.logger.Error("streaming chat request failed",
 			zap.Error(err),
@@ -86,6 +169,99 @@ func (s *AIService) StreamChat(ctx context.Context, req *llm.ChatRequest, handle
 	return nil
 }
 
+// complete sends a chat request for the AI actions. If ctx carries a stream handler and the
+// provider supports streaming, the response is streamed to it and assembled, and a cached
+// response is passed to it as a single chunk; otherwise the request is sent with Chat
+func (s *AIService) complete(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	handler := llm.StreamHandlerFromContext(ctx)
+	if handler == nil || !s.llmClient.GetCapabilities().SupportsStreaming {
+		return s.Chat(ctx, req)
+	}
+
+	cacheKey, cacheable := s.responseCacheKey(req)
+	if cacheable {
+		if cached := s.cachedResponse(ctx, cacheKey); cached != nil {
+			if err := handler(&llm.StreamChunk{Delta: cached.Content}); err != nil {
+				return nil, err
+			}
+			err := handler(&llm.StreamChunk{IsComplete: true, FinishReason: cached.FinishReason, Usage: cached.Usage, Model: cached.Model})
+			if err != nil {
+				return nil, err
+			}
+			return cached, nil
+		}
+	}
+
+	resp := &llm.ChatResponse{
+		Model:     req.Model,
+		Provider:  s.llmClient.GetProvider(),
+		CreatedAt: time.Now(),
+	}
+	var content strings.Builder
+	err := s.StreamChat(ctx, req, func(chunk *llm.StreamChunk) error {
+		content.WriteString(chunk.Delta)
+		if chunk.IsComplete {
+			resp.Usage = chunk.Usage
+			resp.FinishReason = chunk.FinishReason
+			if chunk.Model != "" {
+				resp.Model = chunk.Model
+			}
+		}
+		return handler(chunk)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Content = content.String()
+	if cacheable {
+		s.cacheResponse(ctx, cacheKey, resp)
+	}
+	return resp, nil
+}
+
+// recordUsage records the token usage and estimated cost of a completion in metrics, attributed
+// to the context's usage scope, and passes it to the scope's OnUsage
+func (s *AIService) recordUsage(ctx context.Context, provider llm.Provider, model string, usage *llm.TokenUsage) {
+	if usage == nil {
+		return
+	}
+
+	record := &llm.Usage{
+		Provider:         provider,
+		Model:            model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CostUSD:          s.llmClient.GetCapabilities().EstimateCost(model, usage),
+	}
+
+	scope := llm.UsageScopeFromContext(ctx)
+	if scope == nil {
+		scope = &llm.UsageScope{}
+	}
+
+	if s.metrics != nil {
+		orgLabel := s.metrics.OrganizationLabel(scope.OrganizationID)
+		s.metrics.LLMTokensTotal.WithLabelValues(orgLabel, string(provider), model, "prompt").Add(float64(usage.PromptTokens))
+		s.metrics.LLMTokensTotal.WithLabelValues(orgLabel, string(provider), model, "completion").Add(float64(usage.CompletionTokens))
+		s.metrics.LLMCostTotal.WithLabelValues(orgLabel, string(provider), model).Add(record.CostUSD)
+	}
+
+	s.logger.Debug("recorded llm usage",
+		zap.String("organization_id", scope.OrganizationID),
+		zap.String("workflow_id", scope.WorkflowID),
+		zap.String("execution_id", scope.ExecutionID),
+		zap.String("model", model),
+		zap.Int("prompt_tokens", usage.PromptTokens),
+		zap.Int("completion_tokens", usage.CompletionTokens),
+		zap.Float64("cost_usd", record.CostUSD),
+	)
+
+	if scope.OnUsage != nil {
+		scope.OnUsage(record)
+	}
+}
+
 // GetCapabilities returns the capabilities of the LLM provider
 func (s *AIService) GetCapabilities() *llm.Capabilities {
 	return s.llmClient.GetCapabilities()
@@ -117,6 +293,122 @@ func (s *AIService) InterpretWorkflow(ctx context.Context, description string) (
 	return resp.Content, nil
 }
 
+// Classify classifies the input into exactly one of the labels. Provider errors are returned as
+// is, so callers can tell retryable ones apart with llm.IsRetryable
+func (s *AIService) Classify(ctx context.Context, input string, labels []string, instructions string) (string, error) {
+	if len(labels) == 0 {
+		return "", fmt.Errorf("classification requires at least one label")
+	}
+
+	prompt, err := s.templateManager.Execute("classification", map[string]interface{}{
+		"Labels":       labels,
+		"Instructions": instructions,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to build prompt: %w", err)
+	}
+
+	req := llm.NewPromptBuilder().
+		SetSystemPrompt(prompt).
+		AddUserMessage(input).
+		BuildWithOptions(
+			llm.WithMaxTokens(256),
+			llm.WithTemperature(0),
+		)
+
+	resp, err := s.complete(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to classify: %w", err)
+	}
+
+	return parseClassification(resp.Content, labels)
+}
+
+// parseClassification reads the label from a classification response, accepting a bare label as
+// well as the requested JSON object, and returns it as written in labels
+func parseClassification(content string, labels []string) (string, error) {
+	answer := trimCodeFence(content)
+
+	var parsed struct {
+		Label string `json:"label"`
+	}
+	if err := json.Unmarshal([]byte(answer), &parsed); err == nil && parsed.Label != "" {
+		answer = parsed.Label
+	}
+	answer = strings.Trim(strings.TrimSpace(answer), `"'.`)
+
+	for _, label := range labels {
+		if answer == label {
+			return label, nil
+		}
+	}
+	for _, label := range labels {
+		if strings.EqualFold(answer, label) {
+			return label, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidClassification, answer)
+}
+
+// Extract extracts an object matching the action's JSON schema from the input. The request asks
+// the provider for structured output, and the response is still checked against the schema
+func (s *AIService) Extract(ctx context.Context, input string, extract *models.ExtractAction) (map[string]interface{}, error) {
+	if len(extract.Schema) == 0 {
+		return nil, fmt.Errorf("extraction requires a schema")
+	}
+
+	schema, err := json.MarshalIndent(extract.Schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("invalid extraction schema: %w", err)
+	}
+	prompt, err := s.templateManager.Execute("extraction", map[string]interface{}{
+		"Schema":       string(schema),
+		"Instructions": extract.Instructions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build prompt: %w", err)
+	}
+
+	options := []llm.RequestOption{llm.WithMaxTokens(4096)}
+	if extract.Model != "" {
+		options = append(options, llm.WithModel(extract.Model))
+	}
+	if extract.Temperature != nil {
+		options = append(options, llm.WithTemperature(*extract.Temperature))
+	}
+	req := llm.NewPromptBuilder().
+		SetSystemPrompt(prompt).
+		AddUserMessage(input).
+		BuildWithOptions(options...)
+	req.ResponseSchema = extract.Schema
+
+	resp, err := s.complete(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract: %w", err)
+	}
+
+	return parseExtraction(resp.Content, extract.Schema)
+}
+
+// parseExtraction reads the object from an extraction response and checks it against the schema
+func parseExtraction(content string, schema map[string]interface{}) (map[string]interface{}, error) {
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(trimCodeFence(content)), &object); err != nil || object == nil {
+		return nil, fmt.Errorf("%w: response is not a JSON object", ErrInvalidExtraction)
+	}
+	if problems := llm.ValidateJSONSchema(schema, object); len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidExtraction, strings.Join(problems, "; "))
+	}
+	return object, nil
+}
+
+// trimCodeFence removes the markdown code fence a model may wrap JSON in
+func trimCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	return strings.Trim(content, "`\n ")
+}
+
 // RegisterTemplate registers a custom template
 func (s *AIService) RegisterTemplate(name, content string) error {
 	return s.templateManager.Register(name, content)
@@ -0,0 +1,554 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/circuitbreaker"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/database"
+	"github.com/davidmoltin/intelligent-workflows/pkg/llm"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/metrics"
+)
+
+// fakeLLMClient answers every chat with a fixed response or error, recording the requests. If
+// chunks are set, the client supports streaming and streams them. Responses use fakeModel and
+// report usage, if set
+type fakeLLMClient struct {
+	content  string
+	chunks   []string
+	usage    *llm.TokenUsage
+	err      error
+	requests []*llm.ChatRequest
+}
+
+// fakeModel is priced at $1 per million prompt tokens and $2 per million completion tokens
+const fakeModel = "fake-model"
+
+func (c *fakeLLMClient) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	c.requests = append(c.requests, req)
+	if c.err != nil {
+		return nil, c.err
+	}
+	usage := c.usage
+	if usage == nil {
+		usage = &llm.TokenUsage{}
+	}
+	return &llm.ChatResponse{Content: c.content, Model: fakeModel, Provider: llm.ProviderAnthropic, Usage: usage}, nil
+}
+
+func (c *fakeLLMClient) StreamChat(ctx context.Context, req *llm.ChatRequest, handler llm.StreamHandler) error {
+	if c.chunks == nil {
+		return errors.New("not implemented")
+	}
+	c.requests = append(c.requests, req)
+	for _, chunk := range c.chunks {
+		if err := handler(&llm.StreamChunk{Delta: chunk}); err != nil {
+			return err
+		}
+	}
+	return handler(&llm.StreamChunk{IsComplete: true, Usage: c.usage, FinishReason: "stop", Model: fakeModel})
+}
+
+func (c *fakeLLMClient) GetCapabilities() *llm.Capabilities {
+	return &llm.Capabilities{
+		SupportsStreaming: c.chunks != nil,
+		Models:            []llm.ModelInfo{{ID: fakeModel, InputPricePerMillion: 1, OutputPricePerMillion: 2}},
+	}
+}
+
+func (c *fakeLLMClient) GetProvider() llm.Provider { return llm.ProviderAnthropic }
+
+func (c *fakeLLMClient) Close() error { return nil }
+
+func TestAIService_Classify(t *testing.T) {
+	labels := []string{"billing", "bug", "Feature Request"}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"json object", `{"label": "billing"}`, "billing"},
+		{"json in a code fence", "```json\n{\"label\": \"bug\"}\n```", "bug"},
+		{"bare label", "Feature Request", "Feature Request"},
+		{"differently cased label", `{"label": "feature request"}`, "Feature Request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeLLMClient{content: tt.content}
+			service, err := NewAIService(client, zap.NewNop())
+			require.NoError(t, err)
+
+			label, err := service.Classify(context.Background(), "I was charged twice", labels, "")
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, label)
+			require.Len(t, client.requests, 1)
+			assert.Contains(t, client.requests[0].SystemPrompt, "- Feature Request")
+			assert.Equal(t, "I was charged twice", client.requests[0].Messages[len(client.requests[0].Messages)-1].Content)
+		})
+	}
+
+	t.Run("label outside the candidates", func(t *testing.T) {
+		service, err := NewAIService(&fakeLLMClient{content: `{"label": "shipping"}`}, zap.NewNop())
+		require.NoError(t, err)
+
+		_, err = service.Classify(context.Background(), "Where is my parcel?", labels, "")
+
+		assert.ErrorIs(t, err, ErrInvalidClassification)
+	})
+
+	t.Run("provider errors keep their type", func(t *testing.T) {
+		providerErr := llm.NewError(llm.ProviderAnthropic, llm.ErrorTypeRateLimit, "slow down", nil)
+		service, err := NewAIService(&fakeLLMClient{err: providerErr}, zap.NewNop())
+		require.NoError(t, err)
+
+		_, err = service.Classify(context.Background(), "I was charged twice", labels, "")
+
+		require.Error(t, err)
+		assert.True(t, llm.IsRetryable(err))
+	})
+}
+
+func TestAIService_ClassifyStreaming(t *testing.T) {
+	labels := []string{"billing", "bug"}
+
+	t.Run("streams the response to the context's handler", func(t *testing.T) {
+		client := &fakeLLMClient{chunks: []string{`{"label": `, `"bil`, `ling"}`}}
+		service, err := NewAIService(client, zap.NewNop())
+		require.NoError(t, err)
+
+		var deltas []string
+		var completed bool
+		ctx := llm.WithStreamHandler(context.Background(), func(chunk *llm.StreamChunk) error {
+			if chunk.IsComplete {
+				completed = true
+			} else {
+				deltas = append(deltas, chunk.Delta)
+			}
+			return nil
+		})
+
+		label, err := service.Classify(ctx, "I was charged twice", labels, "")
+
+		require.NoError(t, err)
+		assert.Equal(t, "billing", label)
+		assert.Equal(t, client.chunks, deltas)
+		assert.True(t, completed)
+	})
+
+	t.Run("falls back to a single response without streaming support", func(t *testing.T) {
+		client := &fakeLLMClient{content: `{"label": "bug"}`}
+		service, err := NewAIService(client, zap.NewNop())
+		require.NoError(t, err)
+
+		streamed := false
+		ctx := llm.WithStreamHandler(context.Background(), func(chunk *llm.StreamChunk) error {
+			streamed = true
+			return nil
+		})
+
+		label, err := service.Classify(ctx, "The page crashes", labels, "")
+
+		require.NoError(t, err)
+		assert.Equal(t, "bug", label)
+		assert.False(t, streamed)
+	})
+}
+
+func TestAIService_Extract(t *testing.T) {
+	extract := &models.ExtractAction{
+		Schema: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"order_id"},
+			"properties": map[string]interface{}{
+				"order_id": map[string]interface{}{"type": "string"},
+				"quantity": map[string]interface{}{"type": "integer"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string]interface{}
+	}{
+		{"json object", `{"order_id": "ORD-42", "quantity": 2}`, map[string]interface{}{"order_id": "ORD-42", "quantity": float64(2)}},
+		{"json in a code fence", "```json\n{\"order_id\": \"ORD-42\"}\n```", map[string]interface{}{"order_id": "ORD-42"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeLLMClient{content: tt.content}
+			service, err := NewAIService(client, zap.NewNop())
+			require.NoError(t, err)
+
+			extracted, err := service.Extract(context.Background(), "Two more of ORD-42 please", extract)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, extracted)
+			require.Len(t, client.requests, 1)
+			assert.Equal(t, extract.Schema, client.requests[0].ResponseSchema)
+			assert.Contains(t, client.requests[0].SystemPrompt, `"order_id"`)
+		})
+	}
+
+	invalid := []struct {
+		name    string
+		content string
+	}{
+		{"not json", "The order is ORD-42"},
+		{"missing required property", `{"quantity": 2}`},
+		{"wrong property type", `{"order_id": "ORD-42", "quantity": "two"}`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := NewAIService(&fakeLLMClient{content: tt.content}, zap.NewNop())
+			require.NoError(t, err)
+
+			_, err = service.Extract(context.Background(), "Two more of ORD-42 please", extract)
+
+			assert.ErrorIs(t, err, ErrInvalidExtraction)
+		})
+	}
+
+	t.Run("per-step model and temperature", func(t *testing.T) {
+		temperature := 0.2
+		client := &fakeLLMClient{content: `{"order_id": "ORD-42"}`}
+		service, err := NewAIService(client, zap.NewNop())
+		require.NoError(t, err)
+
+		_, err = service.Extract(context.Background(), "ORD-42", &models.ExtractAction{
+			Schema:      extract.Schema,
+			Model:       "claude-3-haiku-20240307",
+			Temperature: &temperature,
+		})
+
+		require.NoError(t, err)
+		require.Len(t, client.requests, 1)
+		assert.Equal(t, "claude-3-haiku-20240307", client.requests[0].Model)
+		assert.Equal(t, 0.2, client.requests[0].Temperature)
+	})
+}
+
+func TestAIService_RecordsUsage(t *testing.T) {
+	newMetrics := func() *metrics.Metrics {
+		return &metrics.Metrics{
+			LLMTokensTotal: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "llm_tokens"}, []string{"organization_id", "provider", "model", "direction"}),
+			LLMCostTotal:   prometheus.NewCounterVec(prometheus.CounterOpts{Name: "llm_cost"}, []string{"organization_id", "provider", "model"}),
+		}
+	}
+	usage := &llm.TokenUsage{PromptTokens: 300000, CompletionTokens: 100000, TotalTokens: 400000}
+
+	tests := []struct {
+		name   string
+		client *fakeLLMClient
+		stream bool
+	}{
+		{"chat", &fakeLLMClient{content: `{"label": "billing"}`, usage: usage}, false},
+		{"streamed chat", &fakeLLMClient{chunks: []string{`{"label": "billing"}`}, usage: usage}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := NewAIService(tt.client, zap.NewNop())
+			require.NoError(t, err)
+			m := newMetrics()
+			service.SetMetrics(m)
+
+			var recorded []*llm.Usage
+			ctx := llm.WithUsageScope(context.Background(), &llm.UsageScope{
+				OrganizationID: "org-1",
+				ExecutionID:    "exec-1",
+				OnUsage:        func(usage *llm.Usage) { recorded = append(recorded, usage) },
+			})
+			if tt.stream {
+				ctx = llm.WithStreamHandler(ctx, func(chunk *llm.StreamChunk) error { return nil })
+			}
+
+			_, err = service.Classify(ctx, "I was charged twice", []string{"billing", "bug"}, "")
+			require.NoError(t, err)
+
+			require.Len(t, recorded, 1)
+			assert.Equal(t, fakeModel, recorded[0].Model)
+			assert.Equal(t, 300000, recorded[0].PromptTokens)
+			assert.Equal(t, 100000, recorded[0].CompletionTokens)
+			assert.InDelta(t, 0.5, recorded[0].CostUSD, 1e-9)
+
+			// The organization label is left empty unless enabled in the metrics options
+			provider := string(llm.ProviderAnthropic)
+			assert.Equal(t, 300000.0, counterValue(t, m.LLMTokensTotal, "", provider, fakeModel, "prompt"))
+			assert.Equal(t, 100000.0, counterValue(t, m.LLMTokensTotal, "", provider, fakeModel, "completion"))
+			assert.InDelta(t, 0.5, counterValue(t, m.LLMCostTotal, "", provider, fakeModel), 1e-9)
+		})
+	}
+}
+
+// counterValue returns the value of the counter whose labels have the given values, in any order
+func counterValue(t *testing.T, vec *prometheus.CounterVec, labelValues ...string) float64 {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(vec))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			values := make(map[string]bool)
+			for _, label := range metric.GetLabel() {
+				values[label.GetValue()] = true
+			}
+			matched := len(metric.GetLabel()) == len(labelValues)
+			for _, value := range labelValues {
+				matched = matched && values[value]
+			}
+			if matched {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestAIService_ResponseCache(t *testing.T) {
+	newService := func(t *testing.T, client *fakeLLMClient, cacheNonDeterministic bool) (*AIService, *metrics.Metrics, *fakeRedis) {
+		service, err := NewAIService(client, zap.NewNop())
+		require.NoError(t, err)
+		m := &metrics.Metrics{
+			LLMTokensTotal:   prometheus.NewCounterVec(prometheus.CounterOpts{Name: "llm_tokens"}, []string{"organization_id", "provider", "model", "direction"}),
+			LLMCostTotal:     prometheus.NewCounterVec(prometheus.CounterOpts{Name: "llm_cost"}, []string{"organization_id", "provider", "model"}),
+			LLMCacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "llm_cache_requests"}, []string{"provider", "result"}),
+		}
+		service.SetMetrics(m)
+		redisClient, fake := newFakeRedisClient()
+		service.SetResponseCache(redisClient, &config.LLMConfig{CacheTTL: time.Hour, CacheNonDeterministic: cacheNonDeterministic})
+		return service, m, fake
+	}
+	provider := string(llm.ProviderAnthropic)
+
+	t.Run("deterministic requests are answered from the cache", func(t *testing.T) {
+		client := &fakeLLMClient{content: `{"label": "billing"}`, usage: &llm.TokenUsage{PromptTokens: 10, CompletionTokens: 2}}
+		service, m, fake := newService(t, client, false)
+
+		var recorded []*llm.Usage
+		ctx := llm.WithUsageScope(context.Background(), &llm.UsageScope{
+			OnUsage: func(usage *llm.Usage) { recorded = append(recorded, usage) },
+		})
+		for i := 0; i < 2; i++ {
+			label, err := service.Classify(ctx, "I was charged twice", []string{"billing", "bug"}, "")
+			require.NoError(t, err)
+			assert.Equal(t, "billing", label)
+		}
+
+		assert.Len(t, client.requests, 1)
+		assert.Len(t, fake.data, 1)
+		assert.Len(t, recorded, 1, "cache hits use no tokens")
+		assert.Equal(t, 1.0, counterValue(t, m.LLMCacheRequests, provider, "miss"))
+		assert.Equal(t, 1.0, counterValue(t, m.LLMCacheRequests, provider, "hit"))
+
+		resp, err := service.Chat(context.Background(), client.requests[0])
+		require.NoError(t, err)
+		assert.Equal(t, true, resp.Metadata["cached"])
+		assert.Equal(t, 0, resp.Usage.TotalTokens)
+	})
+
+	t.Run("cached responses are streamed as one chunk", func(t *testing.T) {
+		client := &fakeLLMClient{chunks: []string{`{"label": `, `"bug"}`}}
+		service, _, _ := newService(t, client, false)
+
+		var deltas []string
+		ctx := llm.WithStreamHandler(context.Background(), func(chunk *llm.StreamChunk) error {
+			if !chunk.IsComplete {
+				deltas = append(deltas, chunk.Delta)
+			}
+			return nil
+		})
+		for i := 0; i < 2; i++ {
+			label, err := service.Classify(ctx, "The app crashes", []string{"billing", "bug"}, "")
+			require.NoError(t, err)
+			assert.Equal(t, "bug", label)
+		}
+
+		assert.Len(t, client.requests, 1)
+		assert.Equal(t, []string{`{"label": `, `"bug"}`, `{"label": "bug"}`}, deltas)
+	})
+
+	t.Run("non-deterministic requests bypass the cache", func(t *testing.T) {
+		client := &fakeLLMClient{content: "name: workflow"}
+		service, m, fake := newService(t, client, false)
+
+		for i := 0; i < 2; i++ {
+			_, err := service.InterpretWorkflow(context.Background(), "Approve large orders")
+			require.NoError(t, err)
+		}
+
+		assert.Len(t, client.requests, 2)
+		assert.Empty(t, fake.data)
+		assert.Equal(t, 0.0, counterValue(t, m.LLMCacheRequests, provider, "miss"))
+	})
+
+	t.Run("non-deterministic requests are cached when enabled", func(t *testing.T) {
+		client := &fakeLLMClient{content: "name: workflow"}
+		service, _, _ := newService(t, client, true)
+
+		for i := 0; i < 2; i++ {
+			_, err := service.InterpretWorkflow(context.Background(), "Approve large orders")
+			require.NoError(t, err)
+		}
+
+		assert.Len(t, client.requests, 1)
+	})
+}
+
+func TestAIService_ResponseCacheKey(t *testing.T) {
+	service, err := NewAIService(&fakeLLMClient{}, zap.NewNop())
+	require.NoError(t, err)
+	redisClient, _ := newFakeRedisClient()
+	service.SetResponseCache(redisClient, &config.LLMConfig{CacheTTL: time.Hour, DefaultModel: fakeModel})
+
+	newRequest := func(options ...llm.RequestOption) *llm.ChatRequest {
+		req := llm.NewPromptBuilder().
+			SetSystemPrompt("Extract the order").
+			AddUserMessage("Order 42 for $10").
+			BuildWithOptions(append([]llm.RequestOption{llm.WithMaxTokens(256)}, options...)...)
+		req.ResponseSchema = map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"id": map[string]interface{}{"type": "string"}, "total": map[string]interface{}{"type": "number"}},
+		}
+		return req
+	}
+
+	key, ok := service.responseCacheKey(newRequest())
+	require.True(t, ok)
+
+	// The key is a hash of the request's canonical JSON, so it must not change between releases
+	// unless the prefix version is bumped
+	assert.Equal(t, "llm:response:v1:"+sha256Hex(`{"provider":"anthropic","model":"fake-model","system_prompt":"Extract the order",`+
+		`"messages":[{"role":"user","content":"Order 42 for $10"}],"max_tokens":256,"temperature":0,"top_p":0,"stop_sequences":null,`+
+		`"response_schema":{"properties":{"id":{"type":"string"},"total":{"type":"number"}},"type":"object"}}`), key)
+
+	same, _ := service.responseCacheKey(newRequest(llm.WithModel(fakeModel)))
+	assert.Equal(t, key, same, "the default model is used for requests without one")
+
+	other, _ := service.responseCacheKey(newRequest(llm.WithMaxTokens(512)))
+	assert.NotEqual(t, key, other)
+
+	_, ok = service.responseCacheKey(newRequest(llm.WithTemperature(0.5)))
+	assert.False(t, ok)
+}
+
+func TestAIService_CircuitBreaker(t *testing.T) {
+	newService := func(t *testing.T, client *fakeLLMClient) *AIService {
+		service, err := NewAIService(client, zap.NewNop())
+		require.NoError(t, err)
+		service.SetCircuitBreaker(NewLLMCircuitBreaker(&config.CircuitBreakerConfig{
+			Enabled:      true,
+			FailureRatio: 0.5,
+			MinRequests:  2,
+			Interval:     time.Minute,
+			OpenTimeout:  time.Minute,
+		}, logger.NewForTesting(), nil))
+		return service
+	}
+	req := llm.NewPromptBuilder().AddUserMessage("Hi").Build()
+
+	t.Run("provider failures open the breaker", func(t *testing.T) {
+		client := &fakeLLMClient{err: llm.NewError(llm.ProviderAnthropic, llm.ErrorTypeServiceUnavailable, "overloaded", nil)}
+		service := newService(t, client)
+
+		for i := 0; i < 2; i++ {
+			_, err := service.Chat(context.Background(), req)
+			require.ErrorIs(t, err, llm.ErrServiceUnavailable)
+		}
+		_, err := service.Chat(context.Background(), req)
+
+		assert.ErrorIs(t, err, circuitbreaker.ErrOpen)
+		assert.True(t, llm.IsRetryable(err), "an open breaker should be retried later")
+		assert.Len(t, client.requests, 2, "no request is sent while the breaker is open")
+
+		err = service.StreamChat(context.Background(), req, func(chunk *llm.StreamChunk) error { return nil })
+		assert.ErrorIs(t, err, circuitbreaker.ErrOpen)
+	})
+
+	t.Run("rejected requests do not open the breaker", func(t *testing.T) {
+		client := &fakeLLMClient{err: llm.NewError(llm.ProviderAnthropic, llm.ErrorTypeInvalidRequest, "bad request", nil)}
+		service := newService(t, client)
+
+		for i := 0; i < 4; i++ {
+			_, err := service.Chat(context.Background(), req)
+			require.ErrorIs(t, err, llm.ErrInvalidRequest)
+		}
+
+		assert.Len(t, client.requests, 4)
+	})
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// fakeRedis serves the GET and SET commands of a client from memory instead of a server
+type fakeRedis struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+// newFakeRedisClient returns a client whose commands are served by a fakeRedis
+func newFakeRedisClient() (*database.RedisClient, *fakeRedis) {
+	fake := &fakeRedis{data: make(map[string]string)}
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	client.AddHook(fake)
+	return &database.RedisClient{Client: client}, fake
+}
+
+func (f *fakeRedis) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (f *fakeRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (f *fakeRedis) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		args := cmd.Args()
+		switch c := cmd.(type) {
+		case *redis.StringCmd: // GET key
+			value, ok := f.data[args[1].(string)]
+			if !ok {
+				c.SetErr(redis.Nil)
+				return redis.Nil
+			}
+			c.SetVal(value)
+		case *redis.StatusCmd: // SET key value [PX|EX ttl]
+			value := args[2]
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			f.data[args[1].(string)] = fmt.Sprint(value)
+			c.SetVal("OK")
+		default:
+			err := fmt.Errorf("fake redis: unsupported command %s", cmd.Name())
+			c.SetErr(err)
+			return err
+		}
+		return nil
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -16,10 +17,39 @@ type ApprovalRepository interface {
 	UpdateApprovalStatus(ctx context.Context, organizationID, id uuid.UUID, status models.ApprovalStatus, approverID *uuid.UUID, decision *string, decidedAt *time.Time) error
 	GetApprovalByID(ctx context.Context, organizationID, id uuid.UUID) (*models.ApprovalRequest, error)
 	GetApprovalByRequestID(ctx context.Context, organizationID uuid.UUID, requestID string) (*models.ApprovalRequest, error)
-	ListApprovals(ctx context.Context, organizationID uuid.UUID, status *models.ApprovalStatus, approverID *uuid.UUID, limit, offset int) ([]models.ApprovalRequest, int64, error)
+	ListApprovals(ctx context.Context, organizationID uuid.UUID, status *models.ApprovalStatus, approverIDs []uuid.UUID, limit, offset int) ([]models.ApprovalRequest, int64, error)
 	GetExpiredApprovals(ctx context.Context, organizationID uuid.UUID, limit int) ([]models.ApprovalRequest, error)
+	GetEscalationDueApprovals(ctx context.Context, limit int) ([]models.ApprovalRequest, error)
+	EscalateApproval(ctx context.Context, approval *models.ApprovalRequest, fromLevel int) (bool, error)
+	DecideApproval(ctx context.Context, organizationID, id uuid.UUID, status models.ApprovalStatus, approverID *uuid.UUID, decision *string, decidedAt *time.Time) (bool, error)
+	RecordApprovalDecision(ctx context.Context, decision *models.ApprovalDecision) (bool, error)
+	ListApprovalDecisions(ctx context.Context, organizationID, approvalID uuid.UUID) ([]models.ApprovalDecision, error)
+	CreateDelegation(ctx context.Context, delegation *models.ApprovalDelegation) error
+	ListDelegations(ctx context.Context, organizationID, userID uuid.UUID) ([]models.ApprovalDelegation, error)
+	GetActiveDelegationsTo(ctx context.Context, organizationID, delegateID uuid.UUID) ([]models.ApprovalDelegation, error)
+	GetActiveDelegationsFrom(ctx context.Context, organizationID uuid.UUID, delegatorIDs []uuid.UUID) ([]models.ApprovalDelegation, error)
+	DeleteDelegation(ctx context.Context, organizationID, id, delegatorID uuid.UUID) (bool, error)
 }
 
+// UserDirectory looks up users to notify
+type UserDirectory interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+var (
+	// ErrDuplicateApprovalDecision is returned when an approver decides an approval they have already decided
+	ErrDuplicateApprovalDecision = errors.New("approver has already decided this approval")
+
+	// ErrNotDesignatedApprover is returned when a user who is not one of an approval's designated approvers decides it
+	ErrNotDesignatedApprover = errors.New("user is not a designated approver of this approval")
+
+	// ErrInvalidDelegation is returned when a delegation is to the delegator or has no time left to apply
+	ErrInvalidDelegation = errors.New("invalid delegation")
+
+	// ErrDelegationNotFound is returned when a delegation does not exist or was made by another user
+	ErrDelegationNotFound = errors.New("delegation not found")
+)
+
 // WorkflowResumer defines interface for resuming workflows
 type WorkflowResumer interface {
 	ResumeWorkflow(ctx context.Context, executionID uuid.UUID, approved bool) error
@@ -32,6 +62,7 @@ type ApprovalService struct {
 	notificationSvc      *NotificationService
 	workflowResumer      WorkflowResumer
 	auditService         *AuditService
+	userDirectory        UserDirectory
 	defaultApproverEmail string
 }
 
@@ -54,6 +85,12 @@ func NewApprovalService(
 	}
 }
 
+// SetUserDirectory sets where designated approvers' and delegates' emails are looked up for
+// notifications (optional dependency)
+func (s *ApprovalService) SetUserDirectory(userDirectory UserDirectory) {
+	s.userDirectory = userDirectory
+}
+
 // CreateApprovalRequest creates a new approval request
 func (s *ApprovalService) CreateApprovalRequest(
 	ctx context.Context,
@@ -64,9 +101,22 @@ func (s *ApprovalService) CreateApprovalRequest(
 	approverRole string,
 	reason string,
 	expiresIn *time.Duration,
+	escalation models.ApprovalEscalationChain,
+	requiredApprovers []uuid.UUID,
+	quorum int,
 ) (*models.ApprovalRequest, error) {
 	s.logger.Infof("Creating approval request for %s/%s", entityType, entityID)
 
+	if err := validateEscalationChain(escalation); err != nil {
+		return nil, err
+	}
+	if err := validateQuorum(requiredApprovers, quorum); err != nil {
+		return nil, err
+	}
+	if quorum == 0 {
+		quorum = 1
+	}
+
 	approval := &models.ApprovalRequest{
 		ID:           uuid.New(),
 		RequestID:    fmt.Sprintf("appr_%s", uuid.New().String()[:8]),
@@ -78,6 +128,9 @@ func (s *ApprovalService) CreateApprovalRequest(
 		Status:       models.ApprovalStatusPending,
 		Reason:       &reason,
 		RequestedAt:  time.Now(),
+
+		RequiredApprovers: requiredApprovers,
+		Quorum:            quorum,
 	}
 
 	// Set expiration if provided
@@ -86,6 +139,14 @@ func (s *ApprovalService) CreateApprovalRequest(
 		approval.ExpiresAt = &expiresAt
 	}
 
+	// Schedule the first escalation
+	if len(escalation) > 0 {
+		approval.EscalationChain = escalation
+		after, _ := time.ParseDuration(escalation[0].After)
+		nextEscalationAt := approval.RequestedAt.Add(after)
+		approval.NextEscalationAt = &nextEscalationAt
+	}
+
 	if err := s.approvalRepo.CreateApproval(ctx, approval); err != nil {
 		return nil, fmt.Errorf("failed to create approval: %w", err)
 	}
@@ -94,18 +155,58 @@ func (s *ApprovalService) CreateApprovalRequest(
 
 	// Send notification to approver(s)
 	if s.notificationSvc != nil {
-		// In a real implementation, we would look up the approver email based on the role
-		// For now, we'll use a default email or configured approver
-		if err := s.notificationSvc.SendApprovalRequestNotification(ctx, approval, s.defaultApproverEmail); err != nil {
-			// Log error but don't fail the approval creation
-			s.logger.Errorf("Failed to send approval notification: %v", err)
+		for _, email := range s.approverEmails(ctx, approval) {
+			if err := s.notificationSvc.SendApprovalRequestNotification(ctx, approval, email); err != nil {
+				// Log error but don't fail the approval creation
+				s.logger.Errorf("Failed to send approval notification: %v", err)
+			}
 		}
 	}
 
 	return approval, nil
 }
 
-// ApproveRequest approves an approval request
+// approverEmails returns who to notify of a new approval: each designated approver, or the
+// active delegates of those who delegated. Role-based approvals, and approvers whose email
+// cannot be looked up, fall back to the default approver email
+func (s *ApprovalService) approverEmails(ctx context.Context, approval *models.ApprovalRequest) []string {
+	if len(approval.RequiredApprovers) == 0 || s.userDirectory == nil {
+		return []string{s.defaultApproverEmail}
+	}
+
+	delegations, err := s.approvalRepo.GetActiveDelegationsFrom(ctx, approval.OrganizationID, approval.RequiredApprovers)
+	if err != nil {
+		s.logger.Errorf("Failed to get delegations for approval %s: %v", approval.RequestID, err)
+	}
+	delegates := make(map[uuid.UUID][]uuid.UUID)
+	for _, delegation := range delegations {
+		delegates[delegation.DelegatorID] = append(delegates[delegation.DelegatorID], delegation.DelegateID)
+	}
+
+	var emails []string
+	seen := make(map[string]bool)
+	for _, approverID := range approval.RequiredApprovers {
+		recipients := delegates[approverID]
+		if len(recipients) == 0 {
+			recipients = []uuid.UUID{approverID}
+		}
+		for _, userID := range recipients {
+			email := s.defaultApproverEmail
+			if user, err := s.userDirectory.GetByID(ctx, userID); err != nil {
+				s.logger.Errorf("Failed to look up approver %s: %v", userID, err)
+			} else {
+				email = user.Email
+			}
+			if !seen[email] {
+				seen[email] = true
+				emails = append(emails, email)
+			}
+		}
+	}
+	return emails
+}
+
+// ApproveRequest records an approver's approval, approving the request once its quorum is reached
 func (s *ApprovalService) ApproveRequest(
 	ctx context.Context,
 	organizationID uuid.UUID,
@@ -114,7 +215,32 @@ func (s *ApprovalService) ApproveRequest(
 	reason *string,
 ) (*models.ApprovalRequest, error) {
 	s.logger.Infof("Approving request: %s by approver: %s", approvalID, approverID)
+	return s.decide(ctx, organizationID, approvalID, approverID, models.ApprovalDecisionApprove, reason)
+}
+
+// RejectRequest records an approver's rejection, rejecting the request once its quorum is reached
+// or can no longer be reached
+func (s *ApprovalService) RejectRequest(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	approvalID uuid.UUID,
+	approverID uuid.UUID,
+	reason *string,
+) (*models.ApprovalRequest, error) {
+	s.logger.Infof("Rejecting request: %s by approver: %s", approvalID, approverID)
+	return s.decide(ctx, organizationID, approvalID, approverID, models.ApprovalDecisionReject, reason)
+}
 
+// decide records an approver's decision on an approval. Once the decisions reach the approval's
+// quorum the approval is approved or rejected and its workflow resumed
+func (s *ApprovalService) decide(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	approvalID uuid.UUID,
+	approverID uuid.UUID,
+	decision string,
+	reason *string,
+) (*models.ApprovalRequest, error) {
 	// Get approval
 	approval, err := s.approvalRepo.GetApprovalByID(ctx, organizationID, approvalID)
 	if err != nil {
@@ -131,122 +257,192 @@ func (s *ApprovalService) ApproveRequest(
 		return nil, fmt.Errorf("approval has expired")
 	}
 
-	// Update approval
-	now := time.Now()
-	newStatus := models.ApprovalStatusApproved
-
-	if err := s.approvalRepo.UpdateApprovalStatus(ctx, organizationID, approvalID, newStatus, &approverID, reason, &now); err != nil {
-		return nil, fmt.Errorf("failed to update approval: %w", err)
+	// A user who is not a designated approver can only decide on behalf of one who delegated to them
+	actingID := approverID
+	var delegateID *uuid.UUID
+	if len(approval.RequiredApprovers) > 0 && !containsUUID(approval.RequiredApprovers, approverID) {
+		delegatorID, err := s.delegatorFor(ctx, approval, approverID)
+		if err != nil {
+			return nil, err
+		}
+		approverID = delegatorID
+		delegateID = &actingID
 	}
 
-	// Get updated approval
-	approval, err = s.approvalRepo.GetApprovalByID(ctx, organizationID, approvalID)
+	// Record the approver's decision
+	now := time.Now()
+	recorded, err := s.approvalRepo.RecordApprovalDecision(ctx, &models.ApprovalDecision{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+		ApprovalID:     approvalID,
+		ApproverID:     approverID,
+		DelegateID:     delegateID,
+		Decision:       decision,
+		Reason:         reason,
+		DecidedAt:      now,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get updated approval: %w", err)
+		return nil, fmt.Errorf("failed to record decision: %w", err)
+	}
+	if !recorded {
+		return nil, ErrDuplicateApprovalDecision
 	}
-
-	s.logger.Infof("Approval request approved: %s", approval.RequestID)
 
 	// Log audit event
 	if s.auditService != nil {
-		if err := s.auditService.LogApprovalApproved(ctx, approval.ID, approverID, reason); err != nil {
-			s.logger.Errorf("Failed to log audit event for approval: %v", err)
+		if decision == models.ApprovalDecisionApprove {
+			if err := s.auditService.LogApprovalApproved(ctx, approval.ID, actingID, reason); err != nil {
+				s.logger.Errorf("Failed to log audit event for approval: %v", err)
+			}
+		} else if err := s.auditService.LogApprovalRejected(ctx, approval.ID, actingID, reason); err != nil {
+			s.logger.Errorf("Failed to log audit event for rejection: %v", err)
 		}
 	}
 
-	// Resume workflow execution
-	if s.workflowResumer != nil {
-		if err := s.workflowResumer.ResumeWorkflow(ctx, approval.ExecutionID, true); err != nil {
-			s.logger.Errorf("Failed to resume workflow after approval: %v", err)
-			// Note: Approval is already saved, so we return the approval but log the error
+	decisions, err := s.approvalRepo.ListApprovalDecisions(ctx, organizationID, approvalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list decisions: %w", err)
+	}
+
+	status, decided := tallyDecisions(approval, decisions)
+	if !decided {
+		s.logger.Infof("Recorded %s decision on %s, quorum of %d not yet reached", decision, approval.RequestID, approvalQuorum(approval))
+	} else {
+		finalized, err := s.approvalRepo.DecideApproval(ctx, organizationID, approvalID, status, &actingID, reason, &now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update approval: %w", err)
 		}
+		decided = finalized
 	}
 
-	// Send notification about approval decision
-	if s.notificationSvc != nil {
-		requesterEmail := s.defaultApproverEmail // In real implementation, look up requester email
-		if err := s.notificationSvc.SendApprovalDecisionNotification(ctx, approval, requesterEmail); err != nil {
-			s.logger.Errorf("Failed to send approval decision notification: %v", err)
+	// Get updated approval
+	approval, err = s.approvalRepo.GetApprovalByID(ctx, organizationID, approvalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get updated approval: %w", err)
+	}
+	approval.Decisions = decisions
+
+	if decided {
+		s.logger.Infof("Approval request %s: %s", status, approval.RequestID)
+
+		// Resume workflow execution with the approval's outcome
+		if s.workflowResumer != nil {
+			if err := s.workflowResumer.ResumeWorkflow(ctx, approval.ExecutionID, status == models.ApprovalStatusApproved); err != nil {
+				s.logger.Errorf("Failed to resume workflow after %s approval: %v", status, err)
+				// Note: Approval is already saved, so we return the approval but log the error
+			}
+		}
+
+		// Send notification about the decision
+		if s.notificationSvc != nil {
+			requesterEmail := s.defaultApproverEmail // In real implementation, look up requester email
+			if err := s.notificationSvc.SendApprovalDecisionNotification(ctx, approval, requesterEmail); err != nil {
+				s.logger.Errorf("Failed to send approval decision notification: %v", err)
+			}
 		}
 	}
 
 	return approval, nil
 }
 
-// RejectRequest rejects an approval request
-func (s *ApprovalService) RejectRequest(
-	ctx context.Context,
-	organizationID uuid.UUID,
-	approvalID uuid.UUID,
-	approverID uuid.UUID,
-	reason *string,
-) (*models.ApprovalRequest, error) {
-	s.logger.Infof("Rejecting request: %s by approver: %s", approvalID, approverID)
-
-	// Get approval
-	approval, err := s.approvalRepo.GetApprovalByID(ctx, organizationID, approvalID)
+// delegatorFor returns the designated approver of an approval a delegate decides for: one with an
+// active delegation to the delegate who has not decided yet
+func (s *ApprovalService) delegatorFor(ctx context.Context, approval *models.ApprovalRequest, delegateID uuid.UUID) (uuid.UUID, error) {
+	delegations, err := s.approvalRepo.GetActiveDelegationsTo(ctx, approval.OrganizationID, delegateID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get approval: %w", err)
+		return uuid.Nil, fmt.Errorf("failed to get delegations: %w", err)
 	}
 
-	// Validate status
-	if approval.Status != models.ApprovalStatusPending {
-		return nil, fmt.Errorf("approval is not pending (status: %s)", approval.Status)
+	var delegators []uuid.UUID
+	for _, delegation := range delegations {
+		if containsUUID(approval.RequiredApprovers, delegation.DelegatorID) && !containsUUID(delegators, delegation.DelegatorID) {
+			delegators = append(delegators, delegation.DelegatorID)
+		}
 	}
-
-	// Check if expired
-	if approval.ExpiresAt != nil && time.Now().After(*approval.ExpiresAt) {
-		return nil, fmt.Errorf("approval has expired")
+	if len(delegators) == 0 {
+		return uuid.Nil, ErrNotDesignatedApprover
 	}
 
-	// Update approval
-	now := time.Now()
-	newStatus := models.ApprovalStatusRejected
+	decisions, err := s.approvalRepo.ListApprovalDecisions(ctx, approval.OrganizationID, approval.ID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to list decisions: %w", err)
+	}
+	for _, delegatorID := range delegators {
+		decided := false
+		for _, decision := range decisions {
+			if decision.ApproverID == delegatorID {
+				decided = true
+				break
+			}
+		}
+		if !decided {
+			return delegatorID, nil
+		}
+	}
+	return uuid.Nil, ErrDuplicateApprovalDecision
+}
 
-	if err := s.approvalRepo.UpdateApprovalStatus(ctx, organizationID, approvalID, newStatus, &approverID, reason, &now); err != nil {
-		return nil, fmt.Errorf("failed to update approval: %w", err)
+// tallyDecisions reports whether an approval's decisions have decided it, and which way. It is
+// approved once its quorum approve, and rejected once its quorum reject or, with designated
+// approvers, once too few of them are left undecided to reach the quorum
+func tallyDecisions(approval *models.ApprovalRequest, decisions []models.ApprovalDecision) (models.ApprovalStatus, bool) {
+	quorum := approvalQuorum(approval)
+
+	approvals, rejections := 0, 0
+	for _, decision := range decisions {
+		switch decision.Decision {
+		case models.ApprovalDecisionApprove:
+			approvals++
+		case models.ApprovalDecisionReject:
+			rejections++
+		}
 	}
 
-	// Get updated approval
-	approval, err = s.approvalRepo.GetApprovalByID(ctx, organizationID, approvalID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get updated approval: %w", err)
+	switch {
+	case approvals >= quorum:
+		return models.ApprovalStatusApproved, true
+	case rejections >= quorum:
+		return models.ApprovalStatusRejected, true
+	case len(approval.RequiredApprovers) > 0 && len(approval.RequiredApprovers)-rejections < quorum:
+		return models.ApprovalStatusRejected, true
 	}
+	return models.ApprovalStatusPending, false
+}
 
-	s.logger.Infof("Approval request rejected: %s", approval.RequestID)
+// approvalQuorum returns the number of approvals an approval needs, one unless set
+func approvalQuorum(approval *models.ApprovalRequest) int {
+	if approval.Quorum < 1 {
+		return 1
+	}
+	return approval.Quorum
+}
 
-	// Log audit event
-	if s.auditService != nil {
-		if err := s.auditService.LogApprovalRejected(ctx, approval.ID, approverID, reason); err != nil {
-			s.logger.Errorf("Failed to log audit event for rejection: %v", err)
+func containsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
 		}
 	}
+	return false
+}
 
-	// Resume workflow execution with rejection status
-	if s.workflowResumer != nil {
-		if err := s.workflowResumer.ResumeWorkflow(ctx, approval.ExecutionID, false); err != nil {
-			s.logger.Errorf("Failed to resume workflow after rejection: %v", err)
-			// Note: Approval is already saved, so we return the approval but log the error
-		}
+// GetApproval retrieves an approval by ID with its approvers' decisions
+func (s *ApprovalService) GetApproval(ctx context.Context, organizationID, approvalID uuid.UUID) (*models.ApprovalRequest, error) {
+	approval, err := s.approvalRepo.GetApprovalByID(ctx, organizationID, approvalID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Send notification about rejection decision
-	if s.notificationSvc != nil {
-		requesterEmail := s.defaultApproverEmail // In real implementation, look up requester email
-		if err := s.notificationSvc.SendApprovalDecisionNotification(ctx, approval, requesterEmail); err != nil {
-			s.logger.Errorf("Failed to send rejection notification: %v", err)
-		}
+	decisions, err := s.approvalRepo.ListApprovalDecisions(ctx, organizationID, approvalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list decisions: %w", err)
 	}
+	approval.Decisions = decisions
 
 	return approval, nil
 }
 
-// GetApproval retrieves an approval by ID
-func (s *ApprovalService) GetApproval(ctx context.Context, organizationID, approvalID uuid.UUID) (*models.ApprovalRequest, error) {
-	return s.approvalRepo.GetApprovalByID(ctx, organizationID, approvalID)
-}
-
-// ListPendingApprovals retrieves pending approvals for an approver
+// ListPendingApprovals retrieves pending approvals for an approver, including those delegated to them
 func (s *ApprovalService) ListPendingApprovals(
 	ctx context.Context,
 	organizationID uuid.UUID,
@@ -254,10 +450,11 @@ func (s *ApprovalService) ListPendingApprovals(
 	limit, offset int,
 ) ([]models.ApprovalRequest, int64, error) {
 	status := models.ApprovalStatusPending
-	return s.approvalRepo.ListApprovals(ctx, organizationID, &status, approverID, limit, offset)
+	return s.ListApprovals(ctx, organizationID, &status, approverID, limit, offset)
 }
 
-// ListApprovals retrieves approvals with filters
+// ListApprovals retrieves approvals with filters. Pending approvals listed for an approver include
+// those of users with an active delegation to them
 func (s *ApprovalService) ListApprovals(
 	ctx context.Context,
 	organizationID uuid.UUID,
@@ -265,7 +462,83 @@ func (s *ApprovalService) ListApprovals(
 	approverID *uuid.UUID,
 	limit, offset int,
 ) ([]models.ApprovalRequest, int64, error) {
-	return s.approvalRepo.ListApprovals(ctx, organizationID, status, approverID, limit, offset)
+	if approverID == nil {
+		return s.approvalRepo.ListApprovals(ctx, organizationID, status, nil, limit, offset)
+	}
+
+	approverIDs := []uuid.UUID{*approverID}
+	if status != nil && *status == models.ApprovalStatusPending {
+		delegations, err := s.approvalRepo.GetActiveDelegationsTo(ctx, organizationID, *approverID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get delegations: %w", err)
+		}
+		for _, delegation := range delegations {
+			if !containsUUID(approverIDs, delegation.DelegatorID) {
+				approverIDs = append(approverIDs, delegation.DelegatorID)
+			}
+		}
+	}
+	return s.approvalRepo.ListApprovals(ctx, organizationID, status, approverIDs, limit, offset)
+}
+
+// CreateDelegation delegates a user's approvals to another user for a time window, starting now
+// unless a start is given. Once the window ends the delegation stops applying
+func (s *ApprovalService) CreateDelegation(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	delegatorID uuid.UUID,
+	req *models.CreateApprovalDelegationRequest,
+) (*models.ApprovalDelegation, error) {
+	now := time.Now()
+	startsAt := now
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+
+	switch {
+	case req.DelegateID == uuid.Nil:
+		return nil, fmt.Errorf("%w: delegate_id is required", ErrInvalidDelegation)
+	case req.DelegateID == delegatorID:
+		return nil, fmt.Errorf("%w: cannot delegate to yourself", ErrInvalidDelegation)
+	case !req.EndsAt.After(startsAt):
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidDelegation)
+	case !req.EndsAt.After(now):
+		return nil, fmt.Errorf("%w: ends_at must be in the future", ErrInvalidDelegation)
+	}
+
+	delegation := &models.ApprovalDelegation{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+		DelegatorID:    delegatorID,
+		DelegateID:     req.DelegateID,
+		StartsAt:       startsAt,
+		EndsAt:         req.EndsAt,
+		Reason:         req.Reason,
+	}
+	if err := s.approvalRepo.CreateDelegation(ctx, delegation); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Approvals of %s delegated to %s until %s", delegatorID, req.DelegateID, req.EndsAt.Format(time.RFC3339))
+
+	return delegation, nil
+}
+
+// ListDelegations retrieves the delegations a user made or received that have not ended
+func (s *ApprovalService) ListDelegations(ctx context.Context, organizationID, userID uuid.UUID) ([]models.ApprovalDelegation, error) {
+	return s.approvalRepo.ListDelegations(ctx, organizationID, userID)
+}
+
+// RevokeDelegation deletes a delegation the user made
+func (s *ApprovalService) RevokeDelegation(ctx context.Context, organizationID, delegationID, delegatorID uuid.UUID) error {
+	deleted, err := s.approvalRepo.DeleteDelegation(ctx, organizationID, delegationID, delegatorID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrDelegationNotFound
+	}
+	return nil
 }
 
 // ExpireOldApprovals marks expired approvals as expired
@@ -309,3 +582,148 @@ func (s *ApprovalService) ExpireOldApprovals(ctx context.Context) error {
 
 	return nil
 }
+
+// EscalateDueApprovals escalates pending approvals that have gone undecided for their current
+// escalation level's duration to the next level of their chain
+func (s *ApprovalService) EscalateDueApprovals(ctx context.Context) error {
+	approvals, err := s.approvalRepo.GetEscalationDueApprovals(ctx, 1000)
+	if err != nil {
+		return fmt.Errorf("failed to list approvals due to escalate: %w", err)
+	}
+
+	escalatedCount := 0
+	for i := range approvals {
+		escalated, err := s.escalateApproval(ctx, &approvals[i], time.Now())
+		if err != nil {
+			s.logger.Errorf("Failed to escalate approval %s: %v", approvals[i].RequestID, err)
+			continue
+		}
+		if escalated {
+			escalatedCount++
+		}
+	}
+
+	if escalatedCount > 0 {
+		s.logger.Infof("Escalated %d approvals", escalatedCount)
+	}
+
+	return nil
+}
+
+// escalateApproval moves an approval to the next level of its escalation chain: the level's
+// approver is asked to decide, the deadline is extended and the escalation is recorded. It
+// reports false if the approval was decided or escalated elsewhere first
+func (s *ApprovalService) escalateApproval(ctx context.Context, approval *models.ApprovalRequest, now time.Time) (bool, error) {
+	fromLevel := approval.EscalationLevel
+	if fromLevel >= len(approval.EscalationChain) {
+		// Nothing left to escalate to, stop checking the approval
+		approval.NextEscalationAt = nil
+		_, err := s.approvalRepo.EscalateApproval(ctx, approval, fromLevel)
+		return false, err
+	}
+
+	level := approval.EscalationChain[fromLevel]
+	after, err := time.ParseDuration(level.After)
+	if err != nil {
+		return false, fmt.Errorf("invalid escalation level %d: %w", fromLevel+1, err)
+	}
+	extendBy := after
+	if level.ExtendBy != "" {
+		if extendBy, err = time.ParseDuration(level.ExtendBy); err != nil {
+			return false, fmt.Errorf("invalid escalation level %d: %w", fromLevel+1, err)
+		}
+	}
+
+	escalation := models.ApprovalEscalation{
+		Level:         fromLevel + 1,
+		FromRole:      approval.ApproverRole,
+		ToRole:        approval.ApproverRole,
+		ApproverEmail: level.ApproverEmail,
+		EscalatedAt:   now,
+	}
+	if level.ApproverRole != "" {
+		escalation.ToRole = level.ApproverRole
+	}
+	if approval.ExpiresAt != nil {
+		expiresAt := approval.ExpiresAt.Add(extendBy)
+		approval.ExpiresAt = &expiresAt
+		escalation.ExpiresAt = &expiresAt
+	}
+
+	approval.ApproverRole = escalation.ToRole
+	approval.EscalationLevel = fromLevel + 1
+	approval.Escalations = append(approval.Escalations, escalation)
+	approval.NextEscalationAt = nil
+	if approval.EscalationLevel < len(approval.EscalationChain) {
+		next, _ := time.ParseDuration(approval.EscalationChain[approval.EscalationLevel].After)
+		nextEscalationAt := now.Add(next)
+		approval.NextEscalationAt = &nextEscalationAt
+	}
+
+	escalated, err := s.approvalRepo.EscalateApproval(ctx, approval, fromLevel)
+	if err != nil || !escalated {
+		return false, err
+	}
+
+	s.logger.Infof("Approval %s escalated to level %d (%s)", approval.RequestID, escalation.Level, escalation.ToRole)
+
+	// Log audit event
+	if s.auditService != nil {
+		if err := s.auditService.LogApprovalEscalated(ctx, approval.ID, escalation); err != nil {
+			s.logger.Errorf("Failed to log audit event for escalation: %v", err)
+		}
+	}
+
+	// Notify the approver the request was escalated to
+	if s.notificationSvc != nil {
+		approverEmail := level.ApproverEmail
+		if approverEmail == "" {
+			approverEmail = s.defaultApproverEmail // In real implementation, look up the role's approvers
+		}
+		if err := s.notificationSvc.SendApprovalEscalationNotification(ctx, approval, approverEmail); err != nil {
+			s.logger.Errorf("Failed to send escalation notification for %s: %v", approval.RequestID, err)
+		}
+	}
+
+	return true, nil
+}
+
+// validateEscalationChain checks that every level of an escalation chain names who to escalate
+// to and has valid durations
+func validateEscalationChain(chain models.ApprovalEscalationChain) error {
+	for i, level := range chain {
+		if level.ApproverRole == "" && level.ApproverEmail == "" {
+			return fmt.Errorf("invalid escalation level %d: approver_role or approver_email is required", i+1)
+		}
+		after, err := time.ParseDuration(level.After)
+		if err != nil || after <= 0 {
+			return fmt.Errorf("invalid escalation level %d: after must be a positive duration", i+1)
+		}
+		if level.ExtendBy != "" {
+			if extendBy, err := time.ParseDuration(level.ExtendBy); err != nil || extendBy < 0 {
+				return fmt.Errorf("invalid escalation level %d: extend_by must be a non-negative duration", i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// validateQuorum checks that designated approvers are listed once and can reach the quorum
+func validateQuorum(requiredApprovers []uuid.UUID, quorum int) error {
+	if quorum < 0 {
+		return fmt.Errorf("invalid quorum: must be at least 1")
+	}
+
+	seen := make(map[uuid.UUID]bool, len(requiredApprovers))
+	for _, approverID := range requiredApprovers {
+		if seen[approverID] {
+			return fmt.Errorf("invalid approvers: %s is listed more than once", approverID)
+		}
+		seen[approverID] = true
+	}
+
+	if len(requiredApprovers) > 0 && quorum > len(requiredApprovers) {
+		return fmt.Errorf("invalid quorum: %d approvals required from %d approvers", quorum, len(requiredApprovers))
+	}
+	return nil
+}
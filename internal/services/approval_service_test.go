@@ -0,0 +1,654 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// mockApprovalRepo keeps approvals in memory
+type mockApprovalRepo struct {
+	ApprovalRepository
+
+	mu          sync.Mutex
+	approvals   map[uuid.UUID]models.ApprovalRequest
+	decisions   []models.ApprovalDecision
+	delegations []models.ApprovalDelegation
+}
+
+func newMockApprovalRepo() *mockApprovalRepo {
+	return &mockApprovalRepo{approvals: make(map[uuid.UUID]models.ApprovalRequest)}
+}
+
+func (m *mockApprovalRepo) CreateApproval(ctx context.Context, approval *models.ApprovalRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.approvals[approval.ID] = *approval
+	return nil
+}
+
+func (m *mockApprovalRepo) GetApprovalByID(ctx context.Context, organizationID, id uuid.UUID) (*models.ApprovalRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	approval, ok := m.approvals[id]
+	if !ok {
+		return nil, fmt.Errorf("approval not found")
+	}
+	return &approval, nil
+}
+
+func (m *mockApprovalRepo) GetEscalationDueApprovals(ctx context.Context, limit int) ([]models.ApprovalRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []models.ApprovalRequest
+	for _, approval := range m.approvals {
+		if approval.Status == models.ApprovalStatusPending && approval.NextEscalationAt != nil && !approval.NextEscalationAt.After(time.Now()) {
+			approval.Escalations = append(models.ApprovalEscalations(nil), approval.Escalations...)
+			due = append(due, approval)
+		}
+	}
+	return due, nil
+}
+
+func (m *mockApprovalRepo) EscalateApproval(ctx context.Context, approval *models.ApprovalRequest, fromLevel int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.approvals[approval.ID]
+	if !ok || stored.Status != models.ApprovalStatusPending || stored.EscalationLevel != fromLevel {
+		return false, nil
+	}
+	stored.ApproverRole = approval.ApproverRole
+	stored.EscalationLevel = approval.EscalationLevel
+	stored.NextEscalationAt = approval.NextEscalationAt
+	stored.ExpiresAt = approval.ExpiresAt
+	stored.Escalations = approval.Escalations
+	m.approvals[approval.ID] = stored
+	return true, nil
+}
+
+func (m *mockApprovalRepo) DecideApproval(ctx context.Context, organizationID, id uuid.UUID, status models.ApprovalStatus, approverID *uuid.UUID, decision *string, decidedAt *time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	approval, ok := m.approvals[id]
+	if !ok || approval.Status != models.ApprovalStatusPending {
+		return false, nil
+	}
+	approval.Status = status
+	approval.ApproverID = approverID
+	approval.DecisionReason = decision
+	approval.DecidedAt = decidedAt
+	m.approvals[id] = approval
+	return true, nil
+}
+
+func (m *mockApprovalRepo) RecordApprovalDecision(ctx context.Context, decision *models.ApprovalDecision) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.decisions {
+		if existing.ApprovalID == decision.ApprovalID && existing.ApproverID == decision.ApproverID {
+			return false, nil
+		}
+	}
+	m.decisions = append(m.decisions, *decision)
+	return true, nil
+}
+
+func (m *mockApprovalRepo) ListApprovalDecisions(ctx context.Context, organizationID, approvalID uuid.UUID) ([]models.ApprovalDecision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var decisions []models.ApprovalDecision
+	for _, decision := range m.decisions {
+		if decision.ApprovalID == approvalID {
+			decisions = append(decisions, decision)
+		}
+	}
+	return decisions, nil
+}
+
+func (m *mockApprovalRepo) ListApprovals(ctx context.Context, organizationID uuid.UUID, status *models.ApprovalStatus, approverIDs []uuid.UUID, limit, offset int) ([]models.ApprovalRequest, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var approvals []models.ApprovalRequest
+	for _, approval := range m.approvals {
+		if status != nil && approval.Status != *status {
+			continue
+		}
+		if approverIDs != nil {
+			listed := approval.ApproverID != nil && containsUUID(approverIDs, *approval.ApproverID)
+			for _, approverID := range approval.RequiredApprovers {
+				listed = listed || containsUUID(approverIDs, approverID)
+			}
+			if !listed {
+				continue
+			}
+		}
+		approvals = append(approvals, approval)
+	}
+	return approvals, int64(len(approvals)), nil
+}
+
+func (m *mockApprovalRepo) CreateDelegation(ctx context.Context, delegation *models.ApprovalDelegation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delegation.CreatedAt = time.Now()
+	m.delegations = append(m.delegations, *delegation)
+	return nil
+}
+
+func (m *mockApprovalRepo) GetActiveDelegationsTo(ctx context.Context, organizationID, delegateID uuid.UUID) ([]models.ApprovalDelegation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var active []models.ApprovalDelegation
+	for _, delegation := range m.delegations {
+		if delegation.DelegateID == delegateID && delegation.IsActive(time.Now()) {
+			active = append(active, delegation)
+		}
+	}
+	return active, nil
+}
+
+func (m *mockApprovalRepo) DeleteDelegation(ctx context.Context, organizationID, id, delegatorID uuid.UUID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, delegation := range m.delegations {
+		if delegation.ID == id && delegation.DelegatorID == delegatorID {
+			m.delegations = append(m.delegations[:i], m.delegations[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// mockResumer records the outcomes workflows are resumed with
+type mockResumer struct {
+	mu       sync.Mutex
+	outcomes []bool
+}
+
+func (m *mockResumer) ResumeWorkflow(ctx context.Context, executionID uuid.UUID, approved bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outcomes = append(m.outcomes, approved)
+	return nil
+}
+
+// makeEscalationDue moves an approval's next escalation into the past
+func (m *mockApprovalRepo) makeEscalationDue(id uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	approval := m.approvals[id]
+	due := time.Now().Add(-time.Second)
+	approval.NextEscalationAt = &due
+	m.approvals[id] = approval
+}
+
+func TestApprovalEscalation(t *testing.T) {
+	ctx := context.Background()
+	expiresIn := 2 * time.Hour
+	chain := models.ApprovalEscalationChain{
+		{ApproverRole: "director", After: "1h", ExtendBy: "30m"},
+		{ApproverEmail: "cfo@example.com", After: "2h"},
+	}
+
+	t.Run("rejects an invalid escalation chain", func(t *testing.T) {
+		service := NewApprovalService(newMockApprovalRepo(), logger.NewForTesting(), nil, nil, nil, "")
+
+		invalid := []models.ApprovalEscalationChain{
+			{{After: "1h"}},
+			{{ApproverRole: "director"}},
+			{{ApproverRole: "director", After: "-1h"}},
+			{{ApproverRole: "director", After: "1h", ExtendBy: "soon"}},
+		}
+		for _, escalation := range invalid {
+			if _, err := service.CreateApprovalRequest(ctx, uuid.New(), "order", "ord-1", nil, "manager", "review", nil, escalation, nil, 0); err == nil {
+				t.Errorf("Expected an error for escalation chain %+v", escalation)
+			}
+		}
+	})
+
+	t.Run("escalates through the chain and extends the deadline", func(t *testing.T) {
+		repo := newMockApprovalRepo()
+		service := NewApprovalService(repo, logger.NewForTesting(), nil, nil, nil, "")
+
+		approval, err := service.CreateApprovalRequest(ctx, uuid.New(), "order", "ord-1", nil, "manager", "review", &expiresIn, chain, nil, 0)
+		if err != nil {
+			t.Fatalf("CreateApprovalRequest failed: %v", err)
+		}
+		if approval.NextEscalationAt == nil || approval.NextEscalationAt.Sub(approval.RequestedAt) != time.Hour {
+			t.Fatalf("Expected the first escalation an hour after the request, got %v", approval.NextEscalationAt)
+		}
+		deadline := *approval.ExpiresAt
+
+		// Not yet due
+		if err := service.EscalateDueApprovals(ctx); err != nil {
+			t.Fatalf("EscalateDueApprovals failed: %v", err)
+		}
+		if stored, _ := repo.GetApprovalByID(ctx, uuid.Nil, approval.ID); stored.EscalationLevel != 0 {
+			t.Fatalf("Expected no escalation before it is due, got level %d", stored.EscalationLevel)
+		}
+
+		repo.makeEscalationDue(approval.ID)
+		if err := service.EscalateDueApprovals(ctx); err != nil {
+			t.Fatalf("EscalateDueApprovals failed: %v", err)
+		}
+
+		stored, _ := repo.GetApprovalByID(ctx, uuid.Nil, approval.ID)
+		if stored.EscalationLevel != 1 || stored.ApproverRole != "director" {
+			t.Errorf("Expected escalation to the director, got level %d role %s", stored.EscalationLevel, stored.ApproverRole)
+		}
+		if !stored.ExpiresAt.Equal(deadline.Add(30 * time.Minute)) {
+			t.Errorf("Expected the deadline extended by 30m to %s, got %s", deadline.Add(30*time.Minute), stored.ExpiresAt)
+		}
+		if stored.NextEscalationAt == nil || time.Until(*stored.NextEscalationAt) < time.Hour {
+			t.Errorf("Expected the next escalation about 2h away, got %v", stored.NextEscalationAt)
+		}
+		if len(stored.Escalations) != 1 {
+			t.Fatalf("Expected one escalation recorded, got %d", len(stored.Escalations))
+		}
+		first := stored.Escalations[0]
+		if first.Level != 1 || first.FromRole != "manager" || first.ToRole != "director" || first.ExpiresAt == nil {
+			t.Errorf("Unexpected escalation record: %+v", first)
+		}
+
+		// The last level keeps the role, is sent to its email, and defaults extend_by to after
+		deadline = *stored.ExpiresAt
+		repo.makeEscalationDue(approval.ID)
+		if err := service.EscalateDueApprovals(ctx); err != nil {
+			t.Fatalf("EscalateDueApprovals failed: %v", err)
+		}
+
+		stored, _ = repo.GetApprovalByID(ctx, uuid.Nil, approval.ID)
+		if stored.EscalationLevel != 2 || stored.ApproverRole != "director" || stored.NextEscalationAt != nil {
+			t.Errorf("Expected the chain to end at level 2, got level %d role %s next %v", stored.EscalationLevel, stored.ApproverRole, stored.NextEscalationAt)
+		}
+		if !stored.ExpiresAt.Equal(deadline.Add(2 * time.Hour)) {
+			t.Errorf("Expected the deadline extended by 2h, got %s", stored.ExpiresAt)
+		}
+		if len(stored.Escalations) != 2 || stored.Escalations[1].ApproverEmail != "cfo@example.com" {
+			t.Errorf("Expected the second escalation to the CFO, got %+v", stored.Escalations)
+		}
+	})
+
+	t.Run("does not escalate a decided approval", func(t *testing.T) {
+		repo := newMockApprovalRepo()
+		service := NewApprovalService(repo, logger.NewForTesting(), nil, nil, nil, "")
+
+		approval, err := service.CreateApprovalRequest(ctx, uuid.New(), "order", "ord-1", nil, "manager", "review", nil, chain, nil, 0)
+		if err != nil {
+			t.Fatalf("CreateApprovalRequest failed: %v", err)
+		}
+		due, _ := repo.GetApprovalByID(ctx, uuid.Nil, approval.ID)
+		past := time.Now().Add(-time.Second)
+		due.NextEscalationAt = &past
+
+		// Approved after the worker found it due
+		decided := *due
+		decided.Status = models.ApprovalStatusApproved
+		repo.CreateApproval(ctx, &decided)
+
+		escalated, err := service.escalateApproval(ctx, due, time.Now())
+		if err != nil {
+			t.Fatalf("escalateApproval failed: %v", err)
+		}
+		if escalated {
+			t.Error("Expected a decided approval not to be escalated")
+		}
+		if stored, _ := repo.GetApprovalByID(ctx, uuid.Nil, approval.ID); stored.EscalationLevel != 0 || len(stored.Escalations) != 0 {
+			t.Errorf("Expected no escalation recorded, got %+v", stored.Escalations)
+		}
+	})
+}
+
+func TestApprovalQuorum(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	approvers := []uuid.UUID{alice, bob, carol}
+
+	newService := func() (*ApprovalService, *mockResumer) {
+		resumer := &mockResumer{}
+		return NewApprovalService(newMockApprovalRepo(), logger.NewForTesting(), nil, resumer, nil, ""), resumer
+	}
+
+	t.Run("rejects an invalid quorum", func(t *testing.T) {
+		service, _ := newService()
+
+		invalid := []struct {
+			approvers []uuid.UUID
+			quorum    int
+		}{
+			{approvers, 4},
+			{[]uuid.UUID{alice, alice}, 1},
+			{nil, -1},
+		}
+		for _, tt := range invalid {
+			if _, err := service.CreateApprovalRequest(ctx, uuid.New(), "order", "ord-1", nil, "manager", "review", nil, nil, tt.approvers, tt.quorum); err == nil {
+				t.Errorf("Expected an error for %d of %v", tt.quorum, tt.approvers)
+			}
+		}
+	})
+
+	t.Run("approves once the quorum approves", func(t *testing.T) {
+		service, resumer := newService()
+		approval, err := service.CreateApprovalRequest(ctx, uuid.New(), "order", "ord-1", nil, "manager", "review", nil, nil, approvers, 2)
+		if err != nil {
+			t.Fatalf("CreateApprovalRequest failed: %v", err)
+		}
+
+		approval, err = service.ApproveRequest(ctx, orgID, approval.ID, alice, nil)
+		if err != nil {
+			t.Fatalf("ApproveRequest failed: %v", err)
+		}
+		if approval.Status != models.ApprovalStatusPending || len(approval.Decisions) != 1 || len(resumer.outcomes) != 0 {
+			t.Fatalf("Expected the approval to stay pending after one of two approvals, got %s with %d decisions", approval.Status, len(approval.Decisions))
+		}
+
+		if _, err := service.ApproveRequest(ctx, orgID, approval.ID, alice, nil); !errors.Is(err, ErrDuplicateApprovalDecision) {
+			t.Errorf("Expected a duplicate vote to be rejected, got %v", err)
+		}
+		if _, err := service.RejectRequest(ctx, orgID, approval.ID, alice, nil); !errors.Is(err, ErrDuplicateApprovalDecision) {
+			t.Errorf("Expected a changed vote to be rejected, got %v", err)
+		}
+		if _, err := service.ApproveRequest(ctx, orgID, approval.ID, uuid.New(), nil); !errors.Is(err, ErrNotDesignatedApprover) {
+			t.Errorf("Expected a vote from outside the approvers to be rejected, got %v", err)
+		}
+
+		approval, err = service.ApproveRequest(ctx, orgID, approval.ID, bob, nil)
+		if err != nil {
+			t.Fatalf("ApproveRequest failed: %v", err)
+		}
+		if approval.Status != models.ApprovalStatusApproved || approval.ApproverID == nil || *approval.ApproverID != bob {
+			t.Errorf("Expected the approval to be approved by the second approval, got %s", approval.Status)
+		}
+		if len(resumer.outcomes) != 1 || !resumer.outcomes[0] {
+			t.Errorf("Expected the workflow to resume approved once, got %v", resumer.outcomes)
+		}
+
+		if _, err := service.ApproveRequest(ctx, orgID, approval.ID, carol, nil); err == nil {
+			t.Error("Expected a vote on a decided approval to fail")
+		}
+	})
+
+	t.Run("rejects once the quorum can no longer be reached", func(t *testing.T) {
+		service, resumer := newService()
+		approval, err := service.CreateApprovalRequest(ctx, uuid.New(), "order", "ord-1", nil, "manager", "review", nil, nil, approvers, 2)
+		if err != nil {
+			t.Fatalf("CreateApprovalRequest failed: %v", err)
+		}
+
+		if approval, err = service.RejectRequest(ctx, orgID, approval.ID, alice, nil); err != nil || approval.Status != models.ApprovalStatusPending {
+			t.Fatalf("Expected one rejection to leave the approval pending, got %v (%v)", approval, err)
+		}
+		if approval, err = service.ApproveRequest(ctx, orgID, approval.ID, bob, nil); err != nil || approval.Status != models.ApprovalStatusPending {
+			t.Fatalf("Expected one approval to leave the approval pending, got %v (%v)", approval, err)
+		}
+
+		approval, err = service.RejectRequest(ctx, orgID, approval.ID, carol, nil)
+		if err != nil {
+			t.Fatalf("RejectRequest failed: %v", err)
+		}
+		if approval.Status != models.ApprovalStatusRejected {
+			t.Errorf("Expected the approval to be rejected, got %s", approval.Status)
+		}
+		if len(resumer.outcomes) != 1 || resumer.outcomes[0] {
+			t.Errorf("Expected the workflow to resume rejected once, got %v", resumer.outcomes)
+		}
+	})
+
+	t.Run("a single approver decides an approval without a quorum", func(t *testing.T) {
+		service, resumer := newService()
+		approval, err := service.CreateApprovalRequest(ctx, uuid.New(), "order", "ord-1", nil, "manager", "review", nil, nil, nil, 0)
+		if err != nil {
+			t.Fatalf("CreateApprovalRequest failed: %v", err)
+		}
+		if approval.Quorum != 1 {
+			t.Errorf("Expected quorum 1 by default, got %d", approval.Quorum)
+		}
+
+		approval, err = service.RejectRequest(ctx, orgID, approval.ID, uuid.New(), nil)
+		if err != nil {
+			t.Fatalf("RejectRequest failed: %v", err)
+		}
+		if approval.Status != models.ApprovalStatusRejected || len(resumer.outcomes) != 1 {
+			t.Errorf("Expected the first rejection to reject, got %s", approval.Status)
+		}
+	})
+}
+
+func TestApprovalDelegation(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	alice, bob, dave, erin := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	repo := newMockApprovalRepo()
+	resumer := &mockResumer{}
+	service := NewApprovalService(repo, logger.NewForTesting(), nil, resumer, nil, "")
+
+	approval, err := service.CreateApprovalRequest(ctx, uuid.New(), "order", "ord-1", nil, "manager", "review", nil, nil, []uuid.UUID{alice, bob}, 2)
+	if err != nil {
+		t.Fatalf("CreateApprovalRequest failed: %v", err)
+	}
+
+	t.Run("rejects an invalid delegation", func(t *testing.T) {
+		invalid := []*models.CreateApprovalDelegationRequest{
+			{DelegateID: alice, EndsAt: time.Now().Add(time.Hour)},
+			{DelegateID: dave, EndsAt: time.Now().Add(-time.Minute)},
+		}
+		for _, req := range invalid {
+			if _, err := service.CreateDelegation(ctx, orgID, alice, req); !errors.Is(err, ErrInvalidDelegation) {
+				t.Errorf("Expected ErrInvalidDelegation for %+v, got %v", req, err)
+			}
+		}
+	})
+
+	// Alice is away, and Bob's delegation to Erin has ended
+	delegation, err := service.CreateDelegation(ctx, orgID, alice, &models.CreateApprovalDelegationRequest{
+		DelegateID: dave,
+		EndsAt:     time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("CreateDelegation failed: %v", err)
+	}
+	repo.CreateDelegation(ctx, &models.ApprovalDelegation{
+		ID:          uuid.New(),
+		DelegatorID: bob,
+		DelegateID:  erin,
+		StartsAt:    time.Now().Add(-2 * time.Hour),
+		EndsAt:      time.Now().Add(-time.Hour),
+	})
+
+	t.Run("delegated approvals are listed for the delegate", func(t *testing.T) {
+		pending, _, err := service.ListPendingApprovals(ctx, orgID, &dave, 50, 0)
+		if err != nil {
+			t.Fatalf("ListPendingApprovals failed: %v", err)
+		}
+		if len(pending) != 1 || pending[0].ID != approval.ID {
+			t.Errorf("Expected the delegated approval to be pending for the delegate, got %d approvals", len(pending))
+		}
+
+		if pending, _, _ := service.ListPendingApprovals(ctx, orgID, &erin, 50, 0); len(pending) != 0 {
+			t.Errorf("Expected no approvals through an ended delegation, got %d", len(pending))
+		}
+	})
+
+	t.Run("an ended delegation does not allow deciding", func(t *testing.T) {
+		if _, err := service.ApproveRequest(ctx, orgID, approval.ID, erin, nil); !errors.Is(err, ErrNotDesignatedApprover) {
+			t.Errorf("Expected ErrNotDesignatedApprover, got %v", err)
+		}
+	})
+
+	t.Run("an active delegate decides for the delegator", func(t *testing.T) {
+		decided, err := service.ApproveRequest(ctx, orgID, approval.ID, dave, nil)
+		if err != nil {
+			t.Fatalf("ApproveRequest failed: %v", err)
+		}
+		if decided.Status != models.ApprovalStatusPending || len(decided.Decisions) != 1 {
+			t.Fatalf("Expected one of two approvals, got %s with %d decisions", decided.Status, len(decided.Decisions))
+		}
+		decision := decided.Decisions[0]
+		if decision.ApproverID != alice || decision.DelegateID == nil || *decision.DelegateID != dave {
+			t.Errorf("Expected the decision to be Alice's, made by Dave, got approver %s and delegate %v", decision.ApproverID, decision.DelegateID)
+		}
+
+		if _, err := service.ApproveRequest(ctx, orgID, approval.ID, dave, nil); !errors.Is(err, ErrDuplicateApprovalDecision) {
+			t.Errorf("Expected the delegate not to decide twice for the delegator, got %v", err)
+		}
+		if _, err := service.ApproveRequest(ctx, orgID, approval.ID, alice, nil); !errors.Is(err, ErrDuplicateApprovalDecision) {
+			t.Errorf("Expected the delegator not to decide again, got %v", err)
+		}
+
+		decided, err = service.ApproveRequest(ctx, orgID, approval.ID, bob, nil)
+		if err != nil {
+			t.Fatalf("ApproveRequest failed: %v", err)
+		}
+		if decided.Status != models.ApprovalStatusApproved || len(resumer.outcomes) != 1 {
+			t.Errorf("Expected the approval to be approved, got %s", decided.Status)
+		}
+	})
+
+	t.Run("a revoked delegation stops applying", func(t *testing.T) {
+		if err := service.RevokeDelegation(ctx, orgID, delegation.ID, bob); !errors.Is(err, ErrDelegationNotFound) {
+			t.Errorf("Expected only the delegator to revoke, got %v", err)
+		}
+		if err := service.RevokeDelegation(ctx, orgID, delegation.ID, alice); err != nil {
+			t.Fatalf("RevokeDelegation failed: %v", err)
+		}
+
+		other, err := service.CreateApprovalRequest(ctx, uuid.New(), "order", "ord-2", nil, "manager", "review", nil, nil, []uuid.UUID{alice, bob}, 1)
+		if err != nil {
+			t.Fatalf("CreateApprovalRequest failed: %v", err)
+		}
+		if _, err := service.ApproveRequest(ctx, orgID, other.ID, dave, nil); !errors.Is(err, ErrNotDesignatedApprover) {
+			t.Errorf("Expected ErrNotDesignatedApprover after revocation, got %v", err)
+		}
+	})
+}
+
+// fakeExecutionRepo keeps executions in memory
+type fakeExecutionRepo struct {
+	ExecutionRepository
+
+	mu         sync.Mutex
+	executions map[uuid.UUID]models.WorkflowExecution
+}
+
+func (f *fakeExecutionRepo) GetExecutionByID(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowExecution, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	execution, ok := f.executions[id]
+	if !ok {
+		return nil, fmt.Errorf("execution not found")
+	}
+	return &execution, nil
+}
+
+func (f *fakeExecutionRepo) UpdateExecution(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.executions[execution.ID] = *execution
+	return nil
+}
+
+// fakeEngine records the executions it resumes
+type fakeEngine struct {
+	resumed []*models.WorkflowExecution
+}
+
+func (f *fakeEngine) ResumePausedExecution(ctx context.Context, execution *models.WorkflowExecution) error {
+	f.resumed = append(f.resumed, execution)
+	return nil
+}
+
+func TestApprovalResume(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	approver := uuid.New()
+
+	repo := newMockApprovalRepo()
+	executions := &fakeExecutionRepo{executions: make(map[uuid.UUID]models.WorkflowExecution)}
+	engine := &fakeEngine{}
+	resumer := NewWorkflowResumer(logger.NewForTesting(), executions, engine, nil)
+	resumer.SetApprovalLookup(repo)
+	service := NewApprovalService(repo, logger.NewForTesting(), nil, resumer, nil, "")
+
+	newRunning := func() uuid.UUID {
+		id := uuid.New()
+		executions.executions[id] = models.WorkflowExecution{ID: id, OrganizationID: orgID, Status: models.ExecutionStatusRunning}
+		return id
+	}
+
+	executionID := newRunning()
+	approval, err := service.CreateApprovalRequest(ctx, executionID, "order", "ord-1", nil, "manager", "review", nil, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("CreateApprovalRequest failed: %v", err)
+	}
+
+	t.Run("rejects an approval of another execution", func(t *testing.T) {
+		if err := resumer.PauseForApproval(ctx, newRunning(), approval.ID, "waiting for approval", nil); !errors.Is(err, ErrApprovalMismatch) {
+			t.Errorf("Expected ErrApprovalMismatch, got %v", err)
+		}
+	})
+
+	if err := resumer.PauseForApproval(ctx, executionID, approval.ID, "waiting for approval", nil); err != nil {
+		t.Fatalf("PauseForApproval failed: %v", err)
+	}
+
+	t.Run("does not resume before the approval is decided", func(t *testing.T) {
+		paused, _ := executions.GetExecutionByID(ctx, orgID, executionID)
+		if paused.ApprovalID == nil || *paused.ApprovalID != approval.ID {
+			t.Fatalf("Expected the execution to be linked to approval %s, got %v", approval.ID, paused.ApprovalID)
+		}
+		if err := resumer.ResumeFromApproval(ctx, paused); !errors.Is(err, ErrApprovalNotDecided) {
+			t.Errorf("Expected ErrApprovalNotDecided, got %v", err)
+		}
+		if err := resumer.ResumeWorkflow(ctx, executionID, true); !errors.Is(err, ErrApprovalNotDecided) {
+			t.Errorf("Expected ErrApprovalNotDecided, got %v", err)
+		}
+		if len(engine.resumed) != 0 {
+			t.Errorf("Expected no resumes, got %d", len(engine.resumed))
+		}
+	})
+
+	t.Run("does not resume with a decision the approval does not have", func(t *testing.T) {
+		repo.DecideApproval(ctx, orgID, approval.ID, models.ApprovalStatusRejected, &approver, nil, nil)
+		defer func() {
+			repo.mu.Lock()
+			stored := repo.approvals[approval.ID]
+			stored.Status = models.ApprovalStatusPending
+			repo.approvals[approval.ID] = stored
+			repo.mu.Unlock()
+		}()
+
+		if err := resumer.ResumeWorkflow(ctx, executionID, true); !errors.Is(err, ErrApprovalMismatch) {
+			t.Errorf("Expected ErrApprovalMismatch, got %v", err)
+		}
+	})
+
+	t.Run("resumes with the approval's decision and approver", func(t *testing.T) {
+		reason := "looks good"
+		if _, err := service.ApproveRequest(ctx, orgID, approval.ID, approver, &reason); err != nil {
+			t.Fatalf("ApproveRequest failed: %v", err)
+		}
+
+		if len(engine.resumed) != 1 {
+			t.Fatalf("Expected the execution to be resumed once, got %d", len(engine.resumed))
+		}
+		data := engine.resumed[0].ResumeData
+		if data["approved"] != true || data["approval_status"] != "approved" {
+			t.Errorf("Expected an approved decision, got %v", data)
+		}
+		if data["approval_id"] != approval.ID.String() || data["approver_id"] != approver.String() || data["decision_reason"] != reason {
+			t.Errorf("Expected the approval and approver in resume data, got %v", data)
+		}
+		if engine.resumed[0].ApprovalID != nil {
+			t.Errorf("Expected the approval link to be cleared on resume, got %v", engine.resumed[0].ApprovalID)
+		}
+	})
+}
@@ -128,6 +128,24 @@ func (s *AuditService) LogApprovalRejected(
 	return s.LogAction(ctx, "approval", approvalID, "rejected", actorID, "user", changes)
 }
 
+// LogApprovalEscalated logs an approval escalating to the next level of its chain
+func (s *AuditService) LogApprovalEscalated(
+	ctx context.Context,
+	approvalID uuid.UUID,
+	escalation models.ApprovalEscalation,
+) error {
+	changes := map[string]interface{}{
+		"level":     escalation.Level,
+		"from_role": escalation.FromRole,
+		"to_role":   escalation.ToRole,
+	}
+	if escalation.ExpiresAt != nil {
+		changes["expires_at"] = escalation.ExpiresAt.Format(time.RFC3339)
+	}
+	systemActorID := uuid.Nil
+	return s.LogAction(ctx, "approval", approvalID, "escalated", systemActorID, "system", changes)
+}
+
 // LogExecutionPaused logs execution pause
 func (s *AuditService) LogExecutionPaused(
 	ctx context.Context,
@@ -152,6 +170,20 @@ func (s *AuditService) LogExecutionResumed(
 	return s.LogAction(ctx, "execution", executionID, "resumed", actorID, actorType, map[string]interface{}{})
 }
 
+// LogExecutionExpired logs a paused execution failing after being paused too long
+func (s *AuditService) LogExecutionExpired(
+	ctx context.Context,
+	executionID uuid.UUID,
+	actorID uuid.UUID,
+	actorType string,
+	reason string,
+) error {
+	changes := map[string]interface{}{
+		"reason": reason,
+	}
+	return s.LogAction(ctx, "execution", executionID, "expired", actorID, actorType, changes)
+}
+
 // LogExecutionCancelled logs execution cancellation
 func (s *AuditService) LogExecutionCancelled(
 	ctx context.Context,
@@ -2,33 +2,95 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
-	"github.com/davidmoltin/intelligent-workflows/internal/repository/postgres"
 	"github.com/davidmoltin/intelligent-workflows/pkg/auth"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+// UserRepository defines the interface for user persistence
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	UpdateLastLogin(ctx context.Context, userID uuid.UUID) error
+	GetUserRoles(ctx context.Context, userID, organizationID uuid.UUID) ([]string, error)
+	GetUserPermissions(ctx context.Context, userID, organizationID uuid.UUID) ([]string, error)
+}
+
+// APIKeyRepository defines the interface for API key persistence
+type APIKeyRepository interface {
+	Create(ctx context.Context, apiKey *models.APIKey) error
+	GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	UpdateLastUsed(ctx context.Context, organizationID, id uuid.UUID) error
+	Revoke(ctx context.Context, organizationID, id uuid.UUID) error
+}
+
+// RefreshTokenRepository defines the interface for refresh token persistence
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	Rotate(ctx context.Context, tokenID uuid.UUID, next *models.RefreshToken) (bool, error)
+	Revoke(ctx context.Context, tokenHash string) error
+	RevokeFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// OrganizationLookup looks up the organizations a user belongs to
+type OrganizationLookup interface {
+	GetUserOrganizations(ctx context.Context, userID uuid.UUID) ([]models.Organization, error)
+	CheckUserAccess(ctx context.Context, orgID, userID uuid.UUID) (bool, error)
+}
+
+// SuperAdminRole is the role that, assigned globally, grants access to every organization
+const SuperAdminRole = "super_admin"
+
+// ErrNotOrganizationMember is returned when a user's access is resolved in an organization
+// they do not belong to
+var ErrNotOrganizationMember = errors.New("user is not a member of the organization")
+
+// ErrRefreshTokenReused is returned when a refresh token that was already rotated is presented
+// again. Only one of the parties holding it can be the user, so its whole family is revoked
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// PasswordPolicyError is returned when a new password does not meet the password policy
+type PasswordPolicyError struct {
+	Fields []models.FieldError
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+	return "password does not meet the password policy: " + strings.Join(messages, "; ")
+}
+
 // AuthService handles authentication business logic
 type AuthService struct {
-	userRepo         *postgres.UserRepository
-	apiKeyRepo       *postgres.APIKeyRepository
-	refreshTokenRepo *postgres.RefreshTokenRepository
-	orgRepo          *postgres.OrganizationRepository
+	userRepo         UserRepository
+	apiKeyRepo       APIKeyRepository
+	refreshTokenRepo RefreshTokenRepository
+	orgRepo          OrganizationLookup
 	jwtManager       *auth.JWTManager
+	passwordPolicy   *auth.PasswordPolicy
 	logger           *logger.Logger
 }
 
 // NewAuthService creates a new auth service
 func NewAuthService(
-	userRepo *postgres.UserRepository,
-	apiKeyRepo *postgres.APIKeyRepository,
-	refreshTokenRepo *postgres.RefreshTokenRepository,
-	orgRepo *postgres.OrganizationRepository,
+	userRepo UserRepository,
+	apiKeyRepo APIKeyRepository,
+	refreshTokenRepo RefreshTokenRepository,
+	orgRepo OrganizationLookup,
 	jwtManager *auth.JWTManager,
 	log *logger.Logger,
 ) *AuthService {
@@ -38,12 +100,37 @@ func NewAuthService(
 		refreshTokenRepo: refreshTokenRepo,
 		orgRepo:          orgRepo,
 		jwtManager:       jwtManager,
+		passwordPolicy:   auth.DefaultPasswordPolicy(),
 		logger:           log,
 	}
 }
 
+// SetPasswordPolicy replaces the default password policy enforced on registration and password
+// change
+func (s *AuthService) SetPasswordPolicy(policy *auth.PasswordPolicy) {
+	s.passwordPolicy = policy
+}
+
+// checkPassword returns a PasswordPolicyError if password, sent in field, breaks the policy
+func (s *AuthService) checkPassword(field, password string) error {
+	violations := s.passwordPolicy.Check(password)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	fields := make([]models.FieldError, len(violations))
+	for i, violation := range violations {
+		fields[i] = models.FieldError{Field: field, Message: violation}
+	}
+	return &PasswordPolicyError{Fields: fields}
+}
+
 // Register registers a new user
 func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest) (*models.User, error) {
+	if err := s.checkPassword("password", req.Password); err != nil {
+		return nil, err
+	}
+
 	// Check if username already exists
 	existingUser, err := s.userRepo.GetByUsername(ctx, req.Username)
 	if err == nil && existingUser != nil {
@@ -114,15 +201,10 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*mod
 	// Use the first organization as default (in future, we can add user preference for default org)
 	organizationID := orgs[0].ID
 
-	// Get user roles and permissions
-	roles, err := s.userRepo.GetUserRoles(ctx, user.ID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user roles: %w", err)
-	}
-
-	permissions, err := s.userRepo.GetUserPermissions(ctx, user.ID)
+	// Get user roles and permissions in the organization
+	roles, permissions, err := s.GetUserAccess(ctx, user.ID, organizationID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user permissions: %w", err)
+		return nil, err
 	}
 
 	// Generate access token with organization ID
@@ -174,7 +256,9 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*mod
 	}, nil
 }
 
-// RefreshToken refreshes an access token using a refresh token
+// RefreshToken refreshes an access token using a refresh token. The refresh token is rotated:
+// it is revoked and a new one issued in its family. Presenting a rotated token again revokes
+// the family and returns ErrRefreshTokenReused
 func (s *AuthService) RefreshToken(ctx context.Context, refreshTokenString string) (*models.TokenPair, error) {
 	// Hash the refresh token
 	tokenHash := auth.HashRefreshToken(refreshTokenString)
@@ -185,9 +269,10 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshTokenString strin
 		return nil, fmt.Errorf("invalid refresh token")
 	}
 
-	// Check if token is expired
-	if time.Now().After(refreshToken.ExpiresAt) {
-		return nil, fmt.Errorf("refresh token expired")
+	// A rotated token is only presented again if it was copied, so check it even once expired
+	if refreshToken.ReplacedBy != nil {
+		s.revokeReusedFamily(ctx, refreshToken)
+		return nil, ErrRefreshTokenReused
 	}
 
 	// Check if token is revoked
@@ -195,6 +280,11 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshTokenString strin
 		return nil, fmt.Errorf("refresh token revoked")
 	}
 
+	// Check if token is expired
+	if time.Now().After(refreshToken.ExpiresAt) {
+		return nil, fmt.Errorf("refresh token expired")
+	}
+
 	// Get user
 	user, err := s.userRepo.GetByID(ctx, refreshToken.UserID)
 	if err != nil {
@@ -220,15 +310,10 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshTokenString strin
 	// Use the first organization as default
 	organizationID := orgs[0].ID
 
-	// Get user roles and permissions
-	roles, err := s.userRepo.GetUserRoles(ctx, user.ID)
+	// Get user roles and permissions in the organization
+	roles, permissions, err := s.GetUserAccess(ctx, user.ID, organizationID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user roles: %w", err)
-	}
-
-	permissions, err := s.userRepo.GetUserPermissions(ctx, user.ID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user permissions: %w", err)
+		return nil, err
 	}
 
 	// Generate new access token with organization ID
@@ -250,21 +335,22 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshTokenString strin
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
-	// Revoke old refresh token
-	if err := s.refreshTokenRepo.Revoke(ctx, tokenHash); err != nil {
-		s.logger.Warn("Failed to revoke old refresh token", zap.Error(err))
-	}
-
-	// Store new refresh token
+	// Revoke the old refresh token and store the new one in its family. Losing a race with
+	// another use of the same token means it was reused
 	newRefreshToken := &models.RefreshToken{
 		TokenHash: auth.HashRefreshToken(newRefreshTokenString),
 		UserID:    user.ID,
 		ExpiresAt: time.Now().Add(s.jwtManager.GetRefreshTokenTTL()),
 	}
 
-	if err := s.refreshTokenRepo.Create(ctx, newRefreshToken); err != nil {
+	rotated, err := s.refreshTokenRepo.Rotate(ctx, refreshToken.ID, newRefreshToken)
+	if err != nil {
 		return nil, fmt.Errorf("failed to store refresh token: %w", err)
 	}
+	if !rotated {
+		s.revokeReusedFamily(ctx, refreshToken)
+		return nil, ErrRefreshTokenReused
+	}
 
 	return &models.TokenPair{
 		AccessToken:  accessToken,
@@ -273,6 +359,20 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshTokenString strin
 	}, nil
 }
 
+// revokeReusedFamily revokes the family of a reused refresh token and logs the security event
+func (s *AuthService) revokeReusedFamily(ctx context.Context, token *models.RefreshToken) {
+	s.logger.Warn("Security event: refresh token reuse detected, revoking token family",
+		zap.String("event", "refresh_token_reuse"),
+		zap.String("user_id", token.UserID.String()),
+		zap.String("family_id", token.FamilyID.String()),
+		zap.String("token_id", token.ID.String()),
+	)
+
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, token.FamilyID); err != nil {
+		s.logger.Error("Failed to revoke refresh token family", zap.Error(err), zap.String("family_id", token.FamilyID.String()))
+	}
+}
+
 // Logout logs out a user by revoking their refresh token
 func (s *AuthService) Logout(ctx context.Context, refreshTokenString string) error {
 	tokenHash := auth.HashRefreshToken(refreshTokenString)
@@ -322,36 +422,36 @@ func (s *AuthService) CreateAPIKey(ctx context.Context, organizationID, userID u
 	}, nil
 }
 
-// ValidateAPIKey validates an API key and returns the associated user
-func (s *AuthService) ValidateAPIKey(ctx context.Context, apiKeyString string) (*models.User, uuid.UUID, []string, error) {
+// ValidateAPIKey validates an API key and returns the associated user and the key
+func (s *AuthService) ValidateAPIKey(ctx context.Context, apiKeyString string) (*models.User, *models.APIKey, error) {
 	// Hash the API key
 	keyHash := auth.HashAPIKey(apiKeyString)
 
 	// Get API key from database
 	apiKey, err := s.apiKeyRepo.GetByHash(ctx, keyHash)
 	if err != nil {
-		return nil, uuid.Nil, nil, fmt.Errorf("invalid API key")
+		return nil, nil, fmt.Errorf("invalid API key")
 	}
 
 	// Check if API key is active
 	if !apiKey.IsActive {
-		return nil, uuid.Nil, nil, fmt.Errorf("API key is disabled")
+		return nil, nil, fmt.Errorf("API key is disabled")
 	}
 
 	// Check if API key is expired
 	if apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt) {
-		return nil, uuid.Nil, nil, fmt.Errorf("API key expired")
+		return nil, nil, fmt.Errorf("API key expired")
 	}
 
 	// Get user
 	user, err := s.userRepo.GetByID(ctx, apiKey.UserID)
 	if err != nil {
-		return nil, uuid.Nil, nil, fmt.Errorf("user not found")
+		return nil, nil, fmt.Errorf("user not found")
 	}
 
 	// Check if user is active
 	if !user.IsActive {
-		return nil, uuid.Nil, nil, fmt.Errorf("user account is disabled")
+		return nil, nil, fmt.Errorf("user account is disabled")
 	}
 
 	// Update last used timestamp
@@ -359,7 +459,45 @@ func (s *AuthService) ValidateAPIKey(ctx context.Context, apiKeyString string) (
 		s.logger.Warn("Failed to update API key last used", zap.Error(err))
 	}
 
-	return user, apiKey.OrganizationID, apiKey.Scopes, nil
+	return user, apiKey, nil
+}
+
+// GetUserAccess returns the roles and permissions granted to a user through RBAC in an
+// organization: the roles assigned within it and the user's global roles. It returns
+// ErrNotOrganizationMember if the user does not belong to the organization, unless they are a
+// super admin
+func (s *AuthService) GetUserAccess(ctx context.Context, userID, organizationID uuid.UUID) ([]string, []string, error) {
+	roles, err := s.userRepo.GetUserRoles(ctx, userID, organizationID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	if !hasRole(roles, SuperAdminRole) {
+		isMember, err := s.orgRepo.CheckUserAccess(ctx, organizationID, userID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check organization membership: %w", err)
+		}
+		if !isMember {
+			return nil, nil, ErrNotOrganizationMember
+		}
+	}
+
+	permissions, err := s.userRepo.GetUserPermissions(ctx, userID, organizationID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user permissions: %w", err)
+	}
+
+	return roles, permissions, nil
+}
+
+// hasRole reports whether roles contains role
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
 
 // RevokeAPIKey revokes an API key
@@ -369,6 +507,10 @@ func (s *AuthService) RevokeAPIKey(ctx context.Context, organizationID, keyID uu
 
 // ChangePassword changes a user's password
 func (s *AuthService) ChangePassword(ctx context.Context, userID uuid.UUID, req *models.ChangePasswordRequest) error {
+	if err := s.checkPassword("new_password", req.NewPassword); err != nil {
+		return err
+	}
+
 	// Get user
 	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
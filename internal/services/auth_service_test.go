@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/auth"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockUserRepo serves a single user. roles holds the roles assigned in each organization, with
+// global roles under uuid.Nil; without any, the user is an admin everywhere
+type mockUserRepo struct {
+	UserRepository
+
+	user    *models.User
+	roles   map[uuid.UUID][]string
+	created []*models.User
+	updated int
+}
+
+func (m *mockUserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return nil, fmt.Errorf("user not found")
+}
+
+func (m *mockUserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return nil, fmt.Errorf("user not found")
+}
+
+func (m *mockUserRepo) Create(ctx context.Context, user *models.User) error {
+	user.ID = uuid.New()
+	m.created = append(m.created, user)
+	return nil
+}
+
+func (m *mockUserRepo) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	m.updated++
+	return nil
+}
+
+func (m *mockUserRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	if m.user == nil || m.user.ID != id {
+		return nil, fmt.Errorf("user not found")
+	}
+	user := *m.user
+	return &user, nil
+}
+
+func (m *mockUserRepo) GetUserRoles(ctx context.Context, userID, organizationID uuid.UUID) ([]string, error) {
+	if m.roles == nil {
+		return []string{"admin"}, nil
+	}
+	return append(append([]string{}, m.roles[organizationID]...), m.roles[uuid.Nil]...), nil
+}
+
+func (m *mockUserRepo) GetUserPermissions(ctx context.Context, userID, organizationID uuid.UUID) ([]string, error) {
+	return []string{"workflow:read"}, nil
+}
+
+// mockOrganizationLookup puts every user in one organization
+type mockOrganizationLookup struct {
+	organizationID uuid.UUID
+}
+
+func (m *mockOrganizationLookup) GetUserOrganizations(ctx context.Context, userID uuid.UUID) ([]models.Organization, error) {
+	return []models.Organization{{ID: m.organizationID}}, nil
+}
+
+func (m *mockOrganizationLookup) CheckUserAccess(ctx context.Context, orgID, userID uuid.UUID) (bool, error) {
+	return orgID == m.organizationID, nil
+}
+
+// mockRefreshTokenRepo keeps refresh tokens in memory, keyed by hash
+type mockRefreshTokenRepo struct {
+	mu     sync.Mutex
+	tokens map[string]*models.RefreshToken
+}
+
+func newMockRefreshTokenRepo() *mockRefreshTokenRepo {
+	return &mockRefreshTokenRepo{tokens: make(map[string]*models.RefreshToken)}
+}
+
+func (m *mockRefreshTokenRepo) Create(ctx context.Context, token *models.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token.ID = uuid.New()
+	token.CreatedAt = time.Now()
+	if token.FamilyID == uuid.Nil {
+		token.FamilyID = token.ID
+	}
+	stored := *token
+	m.tokens[token.TokenHash] = &stored
+	return nil
+}
+
+func (m *mockRefreshTokenRepo) GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, fmt.Errorf("refresh token not found")
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (m *mockRefreshTokenRepo) Rotate(ctx context.Context, tokenID uuid.UUID, next *models.RefreshToken) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, token := range m.tokens {
+		if token.ID != tokenID || token.RevokedAt != nil {
+			continue
+		}
+		now := time.Now()
+		next.ID = uuid.New()
+		next.CreatedAt = now
+		next.UserID = token.UserID
+		next.FamilyID = token.FamilyID
+		token.RevokedAt = &now
+		token.ReplacedBy = &next.ID
+		stored := *next
+		m.tokens[next.TokenHash] = &stored
+		return true, nil
+	}
+	return false, nil
+}
+
+func (m *mockRefreshTokenRepo) Revoke(ctx context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.tokens[tokenHash]
+	if !ok {
+		return fmt.Errorf("refresh token not found")
+	}
+	now := time.Now()
+	token.RevokedAt = &now
+	return nil
+}
+
+func (m *mockRefreshTokenRepo) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *mockRefreshTokenRepo) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	return errors.New("not implemented")
+}
+
+// active returns the tokens that are not revoked
+func (m *mockRefreshTokenRepo) active() []models.RefreshToken {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var active []models.RefreshToken
+	for _, token := range m.tokens {
+		if token.RevokedAt == nil {
+			active = append(active, *token)
+		}
+	}
+	return active
+}
+
+// newTestAuthService returns an auth service for one active user and a refresh token issued to
+// them at login
+func newTestAuthService(t *testing.T) (*AuthService, *mockRefreshTokenRepo, string) {
+	user := &models.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com", IsActive: true}
+	tokens := newMockRefreshTokenRepo()
+	jwtManager := auth.NewJWTManager("test-secret")
+	service := NewAuthService(&mockUserRepo{user: user}, nil, tokens, &mockOrganizationLookup{organizationID: uuid.New()}, jwtManager, logger.NewForTesting())
+
+	loginToken, err := jwtManager.GenerateRefreshToken()
+	require.NoError(t, err)
+	require.NoError(t, tokens.Create(context.Background(), &models.RefreshToken{
+		TokenHash: auth.HashRefreshToken(loginToken),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+	return service, tokens, loginToken
+}
+
+func TestAuthService_RefreshTokenRotation(t *testing.T) {
+	ctx := context.Background()
+	service, tokens, loginToken := newTestAuthService(t)
+
+	first, err := service.RefreshToken(ctx, loginToken)
+	require.NoError(t, err)
+	assert.NotEmpty(t, first.AccessToken)
+	assert.NotEqual(t, loginToken, first.RefreshToken)
+
+	second, err := service.RefreshToken(ctx, first.RefreshToken)
+	require.NoError(t, err)
+
+	login, err := tokens.GetByHash(ctx, auth.HashRefreshToken(loginToken))
+	require.NoError(t, err)
+	rotated, err := tokens.GetByHash(ctx, auth.HashRefreshToken(first.RefreshToken))
+	require.NoError(t, err)
+	latest, err := tokens.GetByHash(ctx, auth.HashRefreshToken(second.RefreshToken))
+	require.NoError(t, err)
+
+	assert.NotNil(t, login.RevokedAt, "a used token is revoked")
+	assert.Equal(t, rotated.ID, *login.ReplacedBy)
+	assert.Equal(t, latest.ID, *rotated.ReplacedBy)
+	assert.Equal(t, login.FamilyID, rotated.FamilyID)
+	assert.Equal(t, login.FamilyID, latest.FamilyID)
+
+	active := tokens.active()
+	require.Len(t, active, 1, "only the latest token of a family is active")
+	assert.Equal(t, latest.ID, active[0].ID)
+}
+
+func TestAuthService_RefreshTokenReuse(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reusing a rotated token revokes its family", func(t *testing.T) {
+		service, tokens, loginToken := newTestAuthService(t)
+
+		first, err := service.RefreshToken(ctx, loginToken)
+		require.NoError(t, err)
+		second, err := service.RefreshToken(ctx, first.RefreshToken)
+		require.NoError(t, err)
+
+		_, err = service.RefreshToken(ctx, loginToken)
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		assert.Empty(t, tokens.active(), "the whole family is revoked")
+
+		// The latest token, possibly held by the attacker, no longer works either
+		_, err = service.RefreshToken(ctx, second.RefreshToken)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrRefreshTokenReused)
+	})
+
+	t.Run("only the reused family is revoked", func(t *testing.T) {
+		service, tokens, loginToken := newTestAuthService(t)
+		otherLogin, err := service.jwtManager.GenerateRefreshToken()
+		require.NoError(t, err)
+		require.NoError(t, tokens.Create(ctx, &models.RefreshToken{
+			TokenHash: auth.HashRefreshToken(otherLogin),
+			UserID:    uuid.New(),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}))
+
+		_, err = service.RefreshToken(ctx, loginToken)
+		require.NoError(t, err)
+		_, err = service.RefreshToken(ctx, loginToken)
+		require.ErrorIs(t, err, ErrRefreshTokenReused)
+
+		active := tokens.active()
+		require.Len(t, active, 1)
+		assert.Equal(t, auth.HashRefreshToken(otherLogin), active[0].TokenHash)
+	})
+
+	t.Run("concurrent use rotates the token once", func(t *testing.T) {
+		service, tokens, loginToken := newTestAuthService(t)
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = service.RefreshToken(ctx, loginToken)
+			}(i)
+		}
+		wg.Wait()
+
+		// Either both see the same active token and one loses the rotation, or the second
+		// sees the rotated token; either way it is reuse and the family is revoked
+		require.Len(t, errs, 2)
+		assert.True(t, (errs[0] == nil) != (errs[1] == nil), "exactly one use succeeds, got %v", errs)
+		assert.True(t, errors.Is(errs[0], ErrRefreshTokenReused) || errors.Is(errs[1], ErrRefreshTokenReused))
+		assert.Empty(t, tokens.active())
+	})
+
+	t.Run("a logged out token is revoked, not reused", func(t *testing.T) {
+		service, _, loginToken := newTestAuthService(t)
+		require.NoError(t, service.Logout(ctx, loginToken))
+
+		_, err := service.RefreshToken(ctx, loginToken)
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrRefreshTokenReused)
+	})
+}
+
+func TestAuthService_PasswordPolicy(t *testing.T) {
+	ctx := context.Background()
+	users := &mockUserRepo{user: &models.User{ID: uuid.New(), IsActive: true}}
+	service := NewAuthService(users, nil, newMockRefreshTokenRepo(), &mockOrganizationLookup{}, auth.NewJWTManager("test-secret"), logger.NewForTesting())
+
+	t.Run("registration rejects a weak password", func(t *testing.T) {
+		_, err := service.Register(ctx, &models.RegisterRequest{Username: "bob", Email: "bob@example.com", Password: "password"})
+
+		var policyErr *PasswordPolicyError
+		require.ErrorAs(t, err, &policyErr)
+		assert.Equal(t, []models.FieldError{
+			{Field: "password", Message: "must be at least 10 characters"},
+			{Field: "password", Message: "must contain an uppercase letter"},
+			{Field: "password", Message: "must contain a digit"},
+			{Field: "password", Message: "is too common"},
+		}, policyErr.Fields)
+		assert.Empty(t, users.created)
+	})
+
+	t.Run("registration accepts a strong password", func(t *testing.T) {
+		user, err := service.Register(ctx, &models.RegisterRequest{Username: "bob", Email: "bob@example.com", Password: "SecurePass123!"})
+
+		require.NoError(t, err)
+		assert.Len(t, users.created, 1)
+		assert.NoError(t, auth.VerifyPassword("SecurePass123!", user.PasswordHash))
+	})
+
+	t.Run("password change rejects a weak new password", func(t *testing.T) {
+		err := service.ChangePassword(ctx, users.user.ID, &models.ChangePasswordRequest{OldPassword: "SecurePass123!", NewPassword: "Welcome123"})
+
+		var policyErr *PasswordPolicyError
+		require.ErrorAs(t, err, &policyErr)
+		assert.Equal(t, []models.FieldError{{Field: "new_password", Message: "is too common"}}, policyErr.Fields)
+		assert.Zero(t, users.updated)
+	})
+
+	t.Run("a configured policy replaces the default", func(t *testing.T) {
+		policy, err := auth.NewPasswordPolicy(&config.PasswordPolicyConfig{MinLength: 16})
+		require.NoError(t, err)
+		service.SetPasswordPolicy(policy)
+
+		_, err = service.Register(ctx, &models.RegisterRequest{Username: "carol", Email: "carol@example.com", Password: "SecurePass123!"})
+
+		var policyErr *PasswordPolicyError
+		require.ErrorAs(t, err, &policyErr)
+		assert.Equal(t, []models.FieldError{{Field: "password", Message: "must be at least 16 characters"}}, policyErr.Fields)
+	})
+}
+
+func TestAuthService_GetUserAccess(t *testing.T) {
+	ctx := context.Background()
+	memberOf, other := uuid.New(), uuid.New()
+	user := &models.User{ID: uuid.New(), Username: "alice", IsActive: true}
+	newService := func(roles map[uuid.UUID][]string) *AuthService {
+		return NewAuthService(&mockUserRepo{user: user, roles: roles}, nil, nil,
+			&mockOrganizationLookup{organizationID: memberOf}, auth.NewJWTManager("test-secret"), logger.NewForTesting())
+	}
+
+	t.Run("roles in the member's organization", func(t *testing.T) {
+		service := newService(map[uuid.UUID][]string{memberOf: {"approver"}, other: {"admin"}})
+
+		roles, permissions, err := service.GetUserAccess(ctx, user.ID, memberOf)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"approver"}, roles)
+		assert.Equal(t, []string{"workflow:read"}, permissions)
+	})
+
+	t.Run("no access to another organization", func(t *testing.T) {
+		service := newService(map[uuid.UUID][]string{memberOf: {"approver"}, other: {"admin"}})
+
+		_, _, err := service.GetUserAccess(ctx, user.ID, other)
+		assert.ErrorIs(t, err, ErrNotOrganizationMember)
+	})
+
+	t.Run("global roles other than super admin grant no membership", func(t *testing.T) {
+		service := newService(map[uuid.UUID][]string{uuid.Nil: {"admin"}})
+
+		_, _, err := service.GetUserAccess(ctx, user.ID, other)
+		assert.ErrorIs(t, err, ErrNotOrganizationMember)
+	})
+
+	t.Run("super admin has access to every organization", func(t *testing.T) {
+		service := newService(map[uuid.UUID][]string{memberOf: {"approver"}, uuid.Nil: {SuperAdminRole}})
+
+		roles, _, err := service.GetUserAccess(ctx, user.ID, other)
+		require.NoError(t, err)
+		assert.Equal(t, []string{SuperAdminRole}, roles)
+
+		roles, _, err = service.GetUserAccess(ctx, user.ID, memberOf)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"approver", SuperAdminRole}, roles)
+	})
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronDescriptors maps the cron descriptors to the equivalent six-field expressions
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+var monthNames = []string{"", "January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+
+var dayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+
+// cronField describes how one field of a cron expression is written and named
+type cronField struct {
+	expr  string
+	unit  string
+	names []string // Names of the field's values, if any
+}
+
+// describeCron returns a human-readable description of a cron expression that has already been
+// validated by the schedule parser, e.g. "Every day at 9:00 AM UTC"
+func describeCron(expression, timezone string) string {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "TZ=") || strings.HasPrefix(expression, "CRON_TZ=") {
+		i := strings.IndexByte(expression, ' ')
+		if i < 0 {
+			return expression
+		}
+		timezone = expression[strings.IndexByte(expression, '=')+1 : i]
+		expression = strings.TrimSpace(expression[i:])
+	}
+
+	if strings.HasPrefix(expression, "@every ") {
+		d, err := time.ParseDuration(strings.TrimPrefix(expression, "@every "))
+		if err != nil {
+			return expression
+		}
+		return "Every " + d.String()
+	}
+	if spec, ok := cronDescriptors[expression]; ok {
+		expression = spec
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 6 {
+		return expression
+	}
+	second := cronField{expr: fields[0], unit: "second"}
+	minute := cronField{expr: fields[1], unit: "minute"}
+	hour := cronField{expr: fields[2], unit: "hour"}
+	dom := cronField{expr: fields[3], unit: "day"}
+	month := cronField{expr: fields[4], unit: "month", names: monthNames}
+	dow := cronField{expr: fields[5], unit: "day", names: dayNames}
+
+	days := describeDays(dom, month, dow)
+
+	// A run at fixed times of day reads as a clock time
+	if second.single() && minute.single() && hour.list() {
+		var clocks []string
+		for _, h := range hour.values() {
+			clocks = append(clocks, formatClock(h, minute.values()[0], second.values()[0]))
+		}
+		at := "at " + joinWords(clocks) + " " + timezone
+		switch {
+		case len(days) == 0:
+			return "Every day " + at
+		case len(days) == 1 && strings.HasPrefix(days[0], "every "):
+			return capitalize(days[0]) + " " + at
+		default:
+			return capitalize(at) + ", " + strings.Join(days, ", ")
+		}
+	}
+
+	var parts []string
+	if second.expr != "0" {
+		parts = append(parts, second.describe())
+	}
+	switch {
+	case second.expr == "0" && minute.expr == "0" && hour.isStar():
+		parts = append(parts, "every hour")
+	case second.expr == "0" && minute.expr == "0" && hour.isStep():
+		parts = append(parts, hour.describe())
+	case minute.single():
+		parts = append(parts, fmt.Sprintf("at %d minutes past the hour", minute.values()[0]))
+		if !hour.isStar() {
+			parts = append(parts, hour.describeHours(timezone))
+		}
+	default:
+		if !(minute.isStar() && second.expr != "0") {
+			parts = append(parts, minute.describe())
+		}
+		if !hour.isStar() {
+			parts = append(parts, hour.describeHours(timezone))
+		}
+	}
+
+	return capitalize(strings.Join(append(parts, days...), ", "))
+}
+
+// describeDays describes the days a cron expression runs on, or returns nothing if it runs
+// every day
+func describeDays(dom, month, dow cronField) []string {
+	var days []string
+	switch {
+	case !dom.isStar() && !dow.isStar():
+		// Cron runs on days matching either field
+		days = append(days, dom.describeDaysOfMonth()+" or "+dow.describeWeekdays())
+	case !dom.isStar():
+		days = append(days, dom.describeDaysOfMonth())
+	case !dow.isStar():
+		days = append(days, dow.describeWeekdays())
+	}
+	if !month.isStar() {
+		if month.isStep() {
+			days = append(days, month.describe())
+		} else {
+			days = append(days, "in "+month.describeValues())
+		}
+	}
+	return days
+}
+
+func (f cronField) isStar() bool {
+	return f.expr == "*" || f.expr == "?"
+}
+
+func (f cronField) isStep() bool {
+	return strings.Contains(f.expr, "/")
+}
+
+// list reports whether the field is one or more single values
+func (f cronField) list() bool {
+	if f.isStar() || f.isStep() || strings.Contains(f.expr, "-") {
+		return false
+	}
+	return f.values() != nil
+}
+
+func (f cronField) single() bool {
+	return f.list() && !strings.Contains(f.expr, ",")
+}
+
+// values returns the field's values if it is a list of single values
+func (f cronField) values() []int {
+	var values []int
+	for _, part := range strings.Split(f.expr, ",") {
+		v, ok := f.value(part)
+		if !ok {
+			return nil
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// value parses a number or, for fields with names, a three-letter name
+func (f cronField) value(s string) (int, bool) {
+	if v, err := strconv.Atoi(s); err == nil {
+		return v, true
+	}
+	for i, name := range f.names {
+		if name != "" && strings.EqualFold(s, name[:3]) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// name returns how a value of the field is written
+func (f cronField) name(s string) string {
+	v, ok := f.value(s)
+	if !ok {
+		return s
+	}
+	if f.names != nil && v < len(f.names) {
+		return f.names[v]
+	}
+	return strconv.Itoa(v)
+}
+
+// describe describes the field on its own, e.g. "every 15 minutes"
+func (f cronField) describe() string {
+	if f.isStar() {
+		return "every " + f.unit
+	}
+	if f.isStep() {
+		parts := strings.SplitN(f.expr, "/", 2)
+		step := "every " + parts[1] + " " + f.unit + "s"
+		if parts[1] == "1" {
+			step = "every " + f.unit
+		}
+		if parts[0] == "*" {
+			return step
+		}
+		return step + " from " + f.unit + " " + f.rangeWords(parts[0])
+	}
+	return f.unit + plural(f.expr) + " " + f.describeValues()
+}
+
+// describeValues joins a field's values and ranges, e.g. "Monday through Friday"
+func (f cronField) describeValues() string {
+	var words []string
+	for _, part := range strings.Split(f.expr, ",") {
+		words = append(words, f.rangeWords(part))
+	}
+	return joinWords(words)
+}
+
+func (f cronField) rangeWords(part string) string {
+	if from, to, ok := strings.Cut(part, "-"); ok {
+		return f.name(from) + " through " + f.name(to)
+	}
+	return f.name(part)
+}
+
+func (f cronField) describeHours(timezone string) string {
+	if f.isStep() {
+		return f.describe()
+	}
+	var words []string
+	for _, part := range strings.Split(f.expr, ",") {
+		if from, to, ok := strings.Cut(part, "-"); ok {
+			start, _ := f.value(from)
+			end, _ := f.value(to)
+			words = append(words, "between "+formatClock(start, 0, 0)+" and "+formatClock(end, 59, 0))
+			continue
+		}
+		h, _ := f.value(part)
+		words = append(words, "during the "+formatHour(h)+" hour")
+	}
+	return joinWords(words) + " " + timezone
+}
+
+func (f cronField) describeDaysOfMonth() string {
+	if f.isStep() {
+		return f.describe() + " of the month"
+	}
+	return "on day" + plural(f.expr) + " " + f.describeValues() + " of the month"
+}
+
+func (f cronField) describeWeekdays() string {
+	if f.isStep() {
+		return f.describe() + " of the week"
+	}
+	if f.single() {
+		return "every " + f.describeValues()
+	}
+	if strings.Contains(f.expr, "-") {
+		return f.describeValues()
+	}
+	return "on " + f.describeValues()
+}
+
+func plural(expr string) string {
+	if strings.ContainsAny(expr, ",-") {
+		return "s"
+	}
+	return ""
+}
+
+// formatClock formats a time of day on a 12-hour clock, e.g. "9:00 AM"
+func formatClock(hour, minute, second int) string {
+	layout := "3:04 PM"
+	if second != 0 {
+		layout = "3:04:05 PM"
+	}
+	return time.Date(2000, 1, 1, hour, minute, second, 0, time.UTC).Format(layout)
+}
+
+func formatHour(hour int) string {
+	return time.Date(2000, 1, 1, hour, 0, 0, 0, time.UTC).Format("3 PM")
+}
+
+// joinWords joins words into a list, e.g. "a, b and c"
+func joinWords(words []string) string {
+	if len(words) <= 1 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeCron(t *testing.T) {
+	tests := []struct {
+		expression string
+		timezone   string
+		expected   string
+	}{
+		{"0 0 9 * * *", "UTC", "Every day at 9:00 AM UTC"},
+		{"0 30 17 * * 1", "UTC", "Every Monday at 5:30 PM UTC"},
+		{"0 0 9 * * MON-FRI", "America/New_York", "At 9:00 AM America/New_York, Monday through Friday"},
+		{"0 0 9,17 * * *", "UTC", "Every day at 9:00 AM and 5:00 PM UTC"},
+		{"30 0 0 1 * *", "UTC", "At 12:00:30 AM UTC, on day 1 of the month"},
+		{"0 0 8 1,15 jan *", "UTC", "At 8:00 AM UTC, on days 1 and 15 of the month, in January"},
+		{"0 */15 * * * *", "UTC", "Every 15 minutes"},
+		{"0 */5 9-17 * * 1-5", "UTC", "Every 5 minutes, between 9:00 AM and 5:59 PM UTC, Monday through Friday"},
+		{"0 0 * * * *", "UTC", "Every hour"},
+		{"0 0 */2 * * *", "UTC", "Every 2 hours"},
+		{"0 30 * * * *", "UTC", "At 30 minutes past the hour"},
+		{"*/10 * * * * *", "UTC", "Every 10 seconds"},
+		{"@daily", "Europe/London", "Every day at 12:00 AM Europe/London"},
+		{"@weekly", "UTC", "Every Sunday at 12:00 AM UTC"},
+		{"@every 1h30m", "UTC", "Every 1h30m0s"},
+		{"CRON_TZ=Asia/Tokyo 0 0 6 * * *", "UTC", "Every day at 6:00 AM Asia/Tokyo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert.Equal(t, tt.expected, describeCron(tt.expression, tt.timezone))
+		})
+	}
+}
@@ -6,15 +6,33 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
 	"net/http"
+	"net/mail"
 	"net/smtp"
+	"net/textproto"
+	"strings"
 	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
 	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/egress"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/slack"
+	"github.com/google/uuid"
 )
 
+// slackTimeout bounds each request to Slack
+const slackTimeout = 10 * time.Second
+
+// OrganizationGetter loads organizations, whose settings may hold their own Slack credentials
+type OrganizationGetter interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Organization, error)
+}
+
 // NotificationChannel represents different notification channels
 type NotificationChannel string
 
@@ -29,6 +47,8 @@ type NotificationService struct {
 	logger      *logger.Logger
 	emailClient *EmailClient
 	slackClient *SlackClient
+	slackAPI    *slack.Client // Posts slack action messages
+	orgs        OrganizationGetter
 	templates   *NotificationTemplates
 }
 
@@ -39,6 +59,18 @@ type EmailClient struct {
 	username string
 	password string
 	from     string
+	sendMail func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// send delivers a message to recipients through the configured SMTP server
+func (c *EmailClient) send(recipients []string, message []byte) error {
+	auth := smtp.PlainAuth("", c.username, c.password, c.smtpHost)
+	addr := fmt.Sprintf("%s:%d", c.smtpHost, c.smtpPort)
+
+	if err := c.sendMail(addr, auth, c.from, recipients, message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
 }
 
 // SlackClient handles Slack notifications
@@ -80,6 +112,7 @@ func NewNotificationService(cfg *config.NotificationConfig, log *logger.Logger)
 			username: cfg.Email.SMTPUser,
 			password: cfg.Email.SMTPPassword,
 			from:     cfg.Email.FromAddress,
+			sendMail: smtp.SendMail,
 		}
 	}
 
@@ -103,10 +136,72 @@ func NewNotificationService(cfg *config.NotificationConfig, log *logger.Logger)
 		logger:      log,
 		emailClient: emailClient,
 		slackClient: slackClient,
+		slackAPI:    slack.NewClient(&http.Client{Timeout: slackTimeout}),
 		templates:   templates,
 	}, nil
 }
 
+// SetOrganizationRepository sets where organizations' own Slack credentials are read from
+// (optional dependency)
+func (s *NotificationService) SetOrganizationRepository(orgs OrganizationGetter) {
+	s.orgs = orgs
+}
+
+// SetEgressPolicy sets the policy Slack message destinations are checked against, since
+// organizations configure their own webhook URLs (optional dependency)
+func (s *NotificationService) SetEgressPolicy(policy *egress.Policy) {
+	s.slackAPI = slack.NewClient(policy.Client("slack", slackTimeout))
+}
+
+// PostSlackMessage posts a message with the organization's Slack credentials, set under
+// "slack" in its settings, or the configured ones if it has none
+func (s *NotificationService) PostSlackMessage(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	message slack.Message,
+) (*slack.PostResult, error) {
+	credentials, err := s.slackCredentials(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.slackAPI.Post(ctx, credentials, message)
+}
+
+// slackCredentials returns the credentials an organization's messages are posted with
+func (s *NotificationService) slackCredentials(ctx context.Context, organizationID uuid.UUID) (slack.Credentials, error) {
+	if s.orgs != nil && organizationID != uuid.Nil {
+		org, err := s.orgs.GetByID(ctx, organizationID)
+		if err != nil {
+			return slack.Credentials{}, fmt.Errorf("failed to load organization Slack settings: %w", err)
+		}
+		if credentials := organizationSlackCredentials(org.Settings); credentials.Configured() {
+			return credentials, nil
+		}
+	}
+
+	return slack.Credentials{
+		WebhookURL:     s.config.Slack.WebhookURL,
+		BotToken:       s.config.Slack.BotToken,
+		DefaultChannel: s.config.Slack.DefaultChannel,
+	}, nil
+}
+
+// organizationSlackCredentials reads the "slack" setting of an organization:
+// {"webhook_url": ..., "bot_token": ..., "default_channel": ...}
+func organizationSlackCredentials(settings models.JSONB) slack.Credentials {
+	setting, _ := settings["slack"].(map[string]interface{})
+	webhookURL, _ := setting["webhook_url"].(string)
+	botToken, _ := setting["bot_token"].(string)
+	defaultChannel, _ := setting["default_channel"].(string)
+
+	return slack.Credentials{
+		WebhookURL:     webhookURL,
+		BotToken:       botToken,
+		DefaultChannel: defaultChannel,
+	}
+}
+
 // SendApprovalRequestNotification sends notification for a new approval request
 func (s *NotificationService) SendApprovalRequestNotification(
 	ctx context.Context,
@@ -143,6 +238,39 @@ func (s *NotificationService) SendApprovalRequestNotification(
 	return nil
 }
 
+// SendApprovalEscalationNotification sends the approval request to the approver it was escalated to
+func (s *NotificationService) SendApprovalEscalationNotification(
+	ctx context.Context,
+	approval *models.ApprovalRequest,
+	approverEmail string,
+) error {
+	s.logger.Infof("Sending approval escalation notification for %s", approval.RequestID)
+
+	data := s.prepareApprovalData(approval)
+
+	var errors []error
+
+	if s.config.Email.Enabled && approverEmail != "" {
+		if err := s.sendApprovalEmail(ctx, approverEmail, data, s.templates.ApprovalRequest); err != nil {
+			s.logger.Errorf("Failed to send email notification: %v", err)
+			errors = append(errors, err)
+		}
+	}
+
+	if s.config.Slack.Enabled {
+		if err := s.sendApprovalSlackMessage(ctx, approval, "escalated"); err != nil {
+			s.logger.Errorf("Failed to send Slack notification: %v", err)
+			errors = append(errors, err)
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("notification errors: %v", errors)
+	}
+
+	return nil
+}
+
 // SendApprovalDecisionNotification sends notification for approval decision
 func (s *NotificationService) SendApprovalDecisionNotification(
 	ctx context.Context,
@@ -256,17 +384,167 @@ func (s *NotificationService) sendApprovalEmail(
 	message += body.String()
 
 	// Send email
-	auth := smtp.PlainAuth("", s.emailClient.username, s.emailClient.password, s.emailClient.smtpHost)
-	addr := fmt.Sprintf("%s:%d", s.emailClient.smtpHost, s.emailClient.smtpPort)
-
-	if err := smtp.SendMail(addr, auth, s.emailClient.from, []string{to}, []byte(message)); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+	if err := s.emailClient.send([]string{to}, []byte(message)); err != nil {
+		return err
 	}
 
 	s.logger.Infof("Email sent successfully to %s", to)
 	return nil
 }
 
+// SendEmail sends a workflow's email through the configured SMTP transport, returning the
+// Message-ID it was sent with. Every recipient address is validated before anything is sent
+func (s *NotificationService) SendEmail(ctx context.Context, email models.EmailMessage) (string, error) {
+	if s.emailClient == nil {
+		return "", fmt.Errorf("email client not configured")
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	to, err := parseAddresses(email.To)
+	if err != nil {
+		return "", fmt.Errorf("invalid to address: %w", err)
+	}
+	if len(to) == 0 {
+		return "", fmt.Errorf("email requires at least one to address")
+	}
+	cc, err := parseAddresses(email.CC)
+	if err != nil {
+		return "", fmt.Errorf("invalid cc address: %w", err)
+	}
+	bcc, err := parseAddresses(email.BCC)
+	if err != nil {
+		return "", fmt.Errorf("invalid bcc address: %w", err)
+	}
+	if email.Text == "" && email.HTML == "" {
+		return "", fmt.Errorf("email requires a text or HTML body")
+	}
+
+	messageID := newMessageID(s.emailClient.from)
+	message, err := buildEmail(s.emailClient.from, to, cc, email, messageID, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to build email: %w", err)
+	}
+
+	// Bcc recipients only appear in the envelope
+	var recipients []string
+	for _, list := range [][]*mail.Address{to, cc, bcc} {
+		for _, addr := range list {
+			recipients = append(recipients, addr.Address)
+		}
+	}
+
+	if err := s.emailClient.send(recipients, message); err != nil {
+		return "", err
+	}
+
+	s.logger.Infof("Email %s sent to %d recipients", messageID, len(recipients))
+	return messageID, nil
+}
+
+// parseAddresses parses recipients, each an address or a comma-separated address list
+func parseAddresses(values []string) ([]*mail.Address, error) {
+	var addresses []*mail.Address
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		parsed, err := mail.ParseAddressList(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", value, err)
+		}
+		addresses = append(addresses, parsed...)
+	}
+	return addresses, nil
+}
+
+// newMessageID returns a unique Message-ID in the domain of the from address
+func newMessageID(from string) string {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if _, host, ok := strings.Cut(addr.Address, "@"); ok && host != "" {
+			domain = host
+		}
+	}
+	return fmt.Sprintf("<%s@%s>", uuid.New().String(), domain)
+}
+
+// buildEmail renders a MIME message. With both bodies, the text and HTML are sent as
+// alternatives; header values are encoded, so rendered subjects can't inject headers
+func buildEmail(from string, to, cc []*mail.Address, email models.EmailMessage, messageID string, date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+
+	header("From", from)
+	header("To", formatAddresses(to))
+	if len(cc) > 0 {
+		header("Cc", formatAddresses(cc))
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	header("MIME-Version", "1.0")
+
+	if email.Text == "" || email.HTML == "" {
+		contentType, body := "text/plain; charset=UTF-8", email.Text
+		if email.HTML != "" {
+			contentType, body = "text/html; charset=UTF-8", email.HTML
+		}
+		header("Content-Type", contentType)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", email.Text},
+		{"text/html; charset=UTF-8", email.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// formatAddresses formats addresses for a header, encoding display names as needed
+func formatAddresses(addresses []*mail.Address) string {
+	formatted := make([]string, len(addresses))
+	for i, addr := range addresses {
+		formatted[i] = addr.String()
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// writeQuotedPrintable writes body to w in quoted-printable encoding
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, body); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
 // sendApprovalSlackMessage sends a Slack notification
 func (s *NotificationService) sendApprovalSlackMessage(
 	ctx context.Context,
@@ -285,6 +563,10 @@ func (s *NotificationService) sendApprovalSlackMessage(
 		color = "#FFEB3B" // Yellow
 		title = fmt.Sprintf("🔔 New Approval Request: %s", approval.RequestID)
 		text = fmt.Sprintf("*Entity:* %s/%s\n*Approver Role:* %s", approval.EntityType, approval.EntityID, approval.ApproverRole)
+	case "escalated":
+		color = "#FF9800" // Orange
+		title = fmt.Sprintf("⏫ Approval Escalated: %s", approval.RequestID)
+		text = fmt.Sprintf("*Entity:* %s/%s\n*Approver Role:* %s", approval.EntityType, approval.EntityID, approval.ApproverRole)
 	case "approved":
 		color = "#4CAF50" // Green
 		title = fmt.Sprintf("✅ Approval Approved: %s", approval.RequestID)
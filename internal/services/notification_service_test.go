@@ -1,6 +1,12 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
 	"testing"
 	"time"
 
@@ -11,6 +17,7 @@ import (
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
 	"github.com/davidmoltin/intelligent-workflows/pkg/config"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/slack"
 )
 
 func TestNewNotificationService(t *testing.T) {
@@ -213,3 +220,149 @@ func (w *testWriter) Write(p []byte) (n int, err error) {
 	*w.output += string(p)
 	return len(p), nil
 }
+
+// stubOrganizations returns organizations by ID
+type stubOrganizations map[uuid.UUID]*models.Organization
+
+func (s stubOrganizations) GetByID(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	if org, ok := s[id]; ok {
+		return org, nil
+	}
+	return nil, errors.New("organization not found")
+}
+
+func TestPostSlackMessage(t *testing.T) {
+	log, err := logger.New("info", "json")
+	require.NoError(t, err)
+
+	// Each webhook path stands for a different Slack channel
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	withSettings := uuid.New()
+	withoutSettings := uuid.New()
+
+	svc, err := NewNotificationService(&config.NotificationConfig{
+		Slack: config.SlackConfig{WebhookURL: server.URL + "/global"},
+	}, log)
+	require.NoError(t, err)
+	svc.SetOrganizationRepository(stubOrganizations{
+		withSettings: {ID: withSettings, Settings: models.JSONB{
+			"slack": map[string]interface{}{"webhook_url": server.URL + "/org"},
+		}},
+		withoutSettings: {ID: withoutSettings, Settings: models.JSONB{}},
+	})
+
+	ctx := context.Background()
+	message := slack.Message{Text: "Order ord_1 needs review"}
+
+	_, err = svc.PostSlackMessage(ctx, withSettings, message)
+	require.NoError(t, err)
+	_, err = svc.PostSlackMessage(ctx, withoutSettings, message)
+	require.NoError(t, err)
+	_, err = svc.PostSlackMessage(ctx, uuid.Nil, message)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"/org", "/global", "/global"}, paths, "organizations without their own credentials use the configured ones")
+
+	_, err = svc.PostSlackMessage(ctx, uuid.New(), message)
+	assert.Error(t, err, "an organization that can't be loaded must not fall back to the configured credentials")
+}
+
+func TestSendEmail(t *testing.T) {
+	log, err := logger.New("info", "json")
+	require.NoError(t, err)
+
+	svc, err := NewNotificationService(&config.NotificationConfig{
+		Email: config.EmailConfig{
+			Enabled:     true,
+			SMTPHost:    "smtp.example.com",
+			SMTPPort:    587,
+			FromAddress: "Workflows <noreply@example.com>",
+		},
+	}, log)
+	require.NoError(t, err)
+
+	var envelope []string
+	var sent string
+	svc.emailClient.sendMail = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		envelope = to
+		sent = string(msg)
+		return nil
+	}
+
+	ctx := context.Background()
+
+	t.Run("sends text and HTML alternatives to every recipient", func(t *testing.T) {
+		messageID, err := svc.SendEmail(ctx, models.EmailMessage{
+			To:      []string{"Ada <ada@example.com>, bob@example.com"},
+			CC:      []string{"ops@example.com"},
+			BCC:     []string{"audit@example.com"},
+			Subject: "Order ord_1 shipped",
+			Text:    "Your order shipped",
+			HTML:    "<p>Your order shipped</p>",
+		})
+		require.NoError(t, err)
+
+		assert.Regexp(t, `^<[0-9a-f-]+@example\.com>$`, messageID)
+		assert.Equal(t, []string{"ada@example.com", "bob@example.com", "ops@example.com", "audit@example.com"}, envelope)
+		assert.Contains(t, sent, "To: \"Ada\" <ada@example.com>, <bob@example.com>\r\n")
+		assert.Contains(t, sent, "Cc: <ops@example.com>\r\n")
+		assert.Contains(t, sent, "Message-ID: "+messageID+"\r\n")
+		assert.Contains(t, sent, "Content-Type: multipart/alternative;")
+		assert.Contains(t, sent, "<p>Your order shipped</p>")
+		assert.NotContains(t, sent, "audit@example.com", "bcc recipients must not appear in the message")
+	})
+
+	t.Run("encodes subjects so they can't add headers", func(t *testing.T) {
+		_, err := svc.SendEmail(ctx, models.EmailMessage{
+			To:      []string{"ada@example.com"},
+			Subject: "Hello\r\nBcc: attacker@example.com",
+			Text:    "hi",
+		})
+		require.NoError(t, err)
+
+		assert.NotContains(t, sent, "\r\nBcc:")
+		assert.Equal(t, []string{"ada@example.com"}, envelope)
+	})
+
+	t.Run("rejects invalid addresses before sending", func(t *testing.T) {
+		envelope = nil
+		_, err := svc.SendEmail(ctx, models.EmailMessage{
+			To:      []string{"ada@example.com"},
+			CC:      []string{"not an address"},
+			Subject: "Hello",
+			Text:    "hi",
+		})
+		assert.ErrorContains(t, err, "invalid cc address")
+		assert.Nil(t, envelope)
+
+		_, err = svc.SendEmail(ctx, models.EmailMessage{Subject: "Hello", Text: "hi"})
+		assert.Error(t, err)
+	})
+
+	t.Run("reports transport failures", func(t *testing.T) {
+		svc.emailClient.sendMail = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+			return errors.New("connection refused")
+		}
+		_, err := svc.SendEmail(ctx, models.EmailMessage{To: []string{"ada@example.com"}, Subject: "Hello", Text: "hi"})
+		assert.ErrorContains(t, err, "connection refused")
+	})
+}
+
+func TestSendEmail_NotConfigured(t *testing.T) {
+	log, err := logger.New("info", "json")
+	require.NoError(t, err)
+
+	svc, err := NewNotificationService(&config.NotificationConfig{}, log)
+	require.NoError(t, err)
+
+	_, err = svc.SendEmail(context.Background(), models.EmailMessage{To: []string{"ada@example.com"}, Subject: "Hello", Text: "hi"})
+	assert.ErrorContains(t, err, "email client not configured")
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/internal/repository/postgres"
+	"github.com/davidmoltin/intelligent-workflows/internal/seeds"
+	"github.com/davidmoltin/intelligent-workflows/pkg/auth"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RoleRepository defines the interface for role persistence
+type RoleRepository interface {
+	Create(ctx context.Context, role *models.Role, permissionIDs []uuid.UUID) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Role, error)
+	GetByName(ctx context.Context, name string) (*models.Role, error)
+	ListForOrganization(ctx context.Context, organizationID uuid.UUID) ([]*models.Role, error)
+	GetRolePermissions(ctx context.Context, roleID uuid.UUID) ([]*models.Permission, error)
+	GrantPermission(ctx context.Context, roleID, permissionID uuid.UUID) error
+	RevokePermission(ctx context.Context, roleID, permissionID uuid.UUID) error
+}
+
+// PermissionRepository defines the interface for permission persistence
+type PermissionRepository interface {
+	List(ctx context.Context) ([]*models.Permission, error)
+}
+
+// RoleAssignmentRepository defines the interface for persisting the roles assigned to users
+type RoleAssignmentRepository interface {
+	AssignRole(ctx context.Context, userID, roleID uuid.UUID, organizationID *uuid.UUID, assignedBy uuid.UUID) error
+	RemoveRole(ctx context.Context, userID, roleID uuid.UUID, organizationID *uuid.UUID) error
+}
+
+// OrganizationMembership looks up the members of organizations
+type OrganizationMembership interface {
+	CheckUserAccess(ctx context.Context, orgID, userID uuid.UUID) (bool, error)
+	GetUserRoleInOrganization(ctx context.Context, orgID, userID uuid.UUID) (*models.Role, error)
+}
+
+var (
+	// ErrInvalidRole is returned when a role or its permissions fail validation
+	ErrInvalidRole = errors.New("invalid role")
+	// ErrRoleExists is returned when creating a role with a name already in use
+	ErrRoleExists = errors.New("role already exists")
+	// ErrBuiltInRole is returned when modifying the permissions of a built-in role
+	ErrBuiltInRole = errors.New("built-in roles cannot be modified")
+	// ErrPermissionEscalation is returned when granting permissions the caller does not hold
+	ErrPermissionEscalation = errors.New("cannot grant permissions you do not hold")
+	// ErrMembershipRole is returned when removing the role a user holds through their membership
+	ErrMembershipRole = errors.New("membership role cannot be removed")
+)
+
+// RoleService manages custom roles and the roles assigned to users within organizations.
+// Permissions are validated against the catalog seeded by the RBAC seeder
+type RoleService struct {
+	roleRepo       RoleRepository
+	permissionRepo PermissionRepository
+	assignmentRepo RoleAssignmentRepository
+	membership     OrganizationMembership
+	catalog        map[string]bool
+	logger         *logger.Logger
+}
+
+// NewRoleService creates a new role service
+func NewRoleService(
+	roleRepo RoleRepository,
+	permissionRepo PermissionRepository,
+	assignmentRepo RoleAssignmentRepository,
+	membership OrganizationMembership,
+	log *logger.Logger,
+) *RoleService {
+	catalog := make(map[string]bool)
+	for _, permission := range seeds.GetDefaultPermissions() {
+		catalog[permission.Name] = true
+	}
+
+	return &RoleService{
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+		assignmentRepo: assignmentRepo,
+		membership:     membership,
+		catalog:        catalog,
+		logger:         log,
+	}
+}
+
+// ListPermissions returns every permission that can be granted to a role
+func (s *RoleService) ListPermissions(ctx context.Context) ([]*models.Permission, error) {
+	permissions, err := s.permissionRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := make([]*models.Permission, 0, len(permissions))
+	for _, permission := range permissions {
+		if s.catalog[permission.Name] {
+			granted = append(granted, permission)
+		}
+	}
+	return granted, nil
+}
+
+// ListRoles returns the built-in roles and the custom roles of an organization
+func (s *RoleService) ListRoles(ctx context.Context, organizationID uuid.UUID) ([]*models.Role, error) {
+	return s.roleRepo.ListForOrganization(ctx, organizationID)
+}
+
+// GetRole returns a role visible to an organization with its permissions. Custom roles of other
+// organizations are reported as postgres.ErrRoleNotFound
+func (s *RoleService) GetRole(ctx context.Context, organizationID, roleID uuid.UUID) (*models.Role, error) {
+	role, err := s.visibleRole(ctx, organizationID, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.roleRepo.GetRolePermissions(ctx, role.ID)
+	if err != nil {
+		return nil, err
+	}
+	role.Permissions = make([]models.Permission, len(permissions))
+	for i, permission := range permissions {
+		role.Permissions[i] = *permission
+	}
+
+	return role, nil
+}
+
+// CreateRole creates a custom role in the caller's organization, granted the requested
+// permissions. The caller must hold every permission they grant
+func (s *RoleService) CreateRole(ctx context.Context, caller *auth.JWTClaims, req *models.CreateRoleRequest) (*models.Role, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidRole)
+	}
+
+	permissionIDs, err := s.grantablePermissions(ctx, caller, req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.roleRepo.GetByName(ctx, name); err == nil {
+		return nil, ErrRoleExists
+	} else if !errors.Is(err, postgres.ErrRoleNotFound) {
+		return nil, err
+	}
+
+	organizationID := caller.OrganizationID
+	role := &models.Role{
+		Name:           name,
+		Description:    req.Description,
+		OrganizationID: &organizationID,
+	}
+	if err := s.roleRepo.Create(ctx, role, permissionIDs); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Role created",
+		zap.String("role_id", role.ID.String()),
+		zap.String("name", role.Name),
+		zap.String("organization_id", organizationID.String()),
+		zap.String("created_by", caller.UserID.String()),
+	)
+
+	return s.GetRole(ctx, organizationID, role.ID)
+}
+
+// AttachPermissions grants permissions to a custom role of the caller's organization. The
+// caller must hold every permission they grant
+func (s *RoleService) AttachPermissions(ctx context.Context, caller *auth.JWTClaims, roleID uuid.UUID, names []string) (*models.Role, error) {
+	role, err := s.editableRole(ctx, caller.OrganizationID, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: no permissions given", ErrInvalidRole)
+	}
+	permissionIDs, err := s.grantablePermissions(ctx, caller, names)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, permissionID := range permissionIDs {
+		if err := s.roleRepo.GrantPermission(ctx, role.ID, permissionID); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.Info("Permissions attached to role",
+		zap.String("role_id", role.ID.String()),
+		zap.Strings("permissions", names),
+		zap.String("updated_by", caller.UserID.String()),
+	)
+
+	return s.GetRole(ctx, caller.OrganizationID, role.ID)
+}
+
+// DetachPermission revokes a permission from a custom role of the caller's organization. It
+// returns postgres.ErrPermissionNotGranted if the role was not granted the permission
+func (s *RoleService) DetachPermission(ctx context.Context, caller *auth.JWTClaims, roleID uuid.UUID, name string) (*models.Role, error) {
+	role, err := s.editableRole(ctx, caller.OrganizationID, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissionIDs, err := s.permissionIDs(ctx, []string{name})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.roleRepo.RevokePermission(ctx, role.ID, permissionIDs[0]); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Permission detached from role",
+		zap.String("role_id", role.ID.String()),
+		zap.String("permission", name),
+		zap.String("updated_by", caller.UserID.String()),
+	)
+
+	return s.GetRole(ctx, caller.OrganizationID, role.ID)
+}
+
+// AssignRole assigns a role to a member of the caller's organization, within the organization.
+// The caller must hold every permission the role grants. The super admin role is only assigned
+// globally and cannot be assigned through an organization
+func (s *RoleService) AssignRole(ctx context.Context, caller *auth.JWTClaims, userID, roleID uuid.UUID) error {
+	role, err := s.GetRole(ctx, caller.OrganizationID, roleID)
+	if err != nil {
+		return err
+	}
+	if role.Name == SuperAdminRole {
+		return fmt.Errorf("%w: %s can only be assigned globally", ErrInvalidRole, SuperAdminRole)
+	}
+
+	names := make([]string, len(role.Permissions))
+	for i, permission := range role.Permissions {
+		names[i] = permission.Name
+	}
+	if missing := missingPermissions(caller.Permissions, names); len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrPermissionEscalation, strings.Join(missing, ", "))
+	}
+
+	isMember, err := s.membership.CheckUserAccess(ctx, caller.OrganizationID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotOrganizationMember
+	}
+
+	organizationID := caller.OrganizationID
+	if err := s.assignmentRepo.AssignRole(ctx, userID, role.ID, &organizationID, caller.UserID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Role assigned",
+		zap.String("user_id", userID.String()),
+		zap.String("role", role.Name),
+		zap.String("organization_id", organizationID.String()),
+		zap.String("assigned_by", caller.UserID.String()),
+	)
+
+	return nil
+}
+
+// UnassignRole removes a role from a user within the caller's organization. The role a user
+// holds through their membership is changed on the membership instead. It returns
+// postgres.ErrRoleAssignmentNotFound if the user was not assigned the role
+func (s *RoleService) UnassignRole(ctx context.Context, caller *auth.JWTClaims, userID, roleID uuid.UUID) error {
+	membershipRole, err := s.membership.GetUserRoleInOrganization(ctx, caller.OrganizationID, userID)
+	if err == nil && membershipRole.ID == roleID {
+		return ErrMembershipRole
+	}
+
+	organizationID := caller.OrganizationID
+	if err := s.assignmentRepo.RemoveRole(ctx, userID, roleID, &organizationID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Role unassigned",
+		zap.String("user_id", userID.String()),
+		zap.String("role_id", roleID.String()),
+		zap.String("organization_id", organizationID.String()),
+		zap.String("removed_by", caller.UserID.String()),
+	)
+
+	return nil
+}
+
+// visibleRole returns a role if it is built-in or belongs to organizationID
+func (s *RoleService) visibleRole(ctx context.Context, organizationID, roleID uuid.UUID) (*models.Role, error) {
+	role, err := s.roleRepo.GetByID(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.IsBuiltIn() && *role.OrganizationID != organizationID {
+		return nil, postgres.ErrRoleNotFound
+	}
+	return role, nil
+}
+
+// editableRole returns a custom role of organizationID
+func (s *RoleService) editableRole(ctx context.Context, organizationID, roleID uuid.UUID) (*models.Role, error) {
+	role, err := s.visibleRole(ctx, organizationID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role.IsBuiltIn() {
+		return nil, ErrBuiltInRole
+	}
+	return role, nil
+}
+
+// grantablePermissions returns the IDs of the permissions names, checking the caller holds them
+func (s *RoleService) grantablePermissions(ctx context.Context, caller *auth.JWTClaims, names []string) ([]uuid.UUID, error) {
+	if missing := missingPermissions(caller.Permissions, names); len(missing) > 0 {
+		// Unknown permissions are reported as such rather than as an escalation
+		if _, err := s.permissionIDs(ctx, missing); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s", ErrPermissionEscalation, strings.Join(missing, ", "))
+	}
+	return s.permissionIDs(ctx, names)
+}
+
+// permissionIDs returns the IDs of the permissions names, rejecting names not in the catalog
+func (s *RoleService) permissionIDs(ctx context.Context, names []string) ([]uuid.UUID, error) {
+	var unknown []string
+	for _, name := range names {
+		if !s.catalog[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: unknown permissions: %s", ErrInvalidRole, strings.Join(unknown, ", "))
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	permissions, err := s.permissionRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]uuid.UUID, len(permissions))
+	for _, permission := range permissions {
+		byName[permission.Name] = permission.ID
+	}
+
+	ids := make([]uuid.UUID, len(names))
+	for i, name := range names {
+		id, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("permission %s has not been seeded", name)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// missingPermissions returns the permissions in required that held does not contain
+func missingPermissions(held, required []string) []string {
+	holds := make(map[string]bool, len(held))
+	for _, permission := range held {
+		holds[permission] = true
+	}
+
+	var missing []string
+	for _, permission := range required {
+		if !holds[permission] {
+			missing = append(missing, permission)
+		}
+	}
+	return missing
+}
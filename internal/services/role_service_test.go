@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/internal/repository/postgres"
+	"github.com/davidmoltin/intelligent-workflows/internal/seeds"
+	"github.com/davidmoltin/intelligent-workflows/pkg/auth"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roleAssignment is a role assigned to a user in an organization
+type roleAssignment struct {
+	userID, roleID, organizationID uuid.UUID
+}
+
+// mockRBACStore keeps roles, permissions, role assignments and organization memberships in
+// memory. Permissions are those of the seeder catalog
+type mockRBACStore struct {
+	roles       map[uuid.UUID]*models.Role
+	permissions []*models.Permission
+	grants      map[uuid.UUID]map[uuid.UUID]bool
+	assignments map[roleAssignment]bool
+	members     map[uuid.UUID]map[uuid.UUID]uuid.UUID // organization -> user -> membership role
+}
+
+func newMockRBACStore() *mockRBACStore {
+	store := &mockRBACStore{
+		roles:       make(map[uuid.UUID]*models.Role),
+		grants:      make(map[uuid.UUID]map[uuid.UUID]bool),
+		assignments: make(map[roleAssignment]bool),
+		members:     make(map[uuid.UUID]map[uuid.UUID]uuid.UUID),
+	}
+	for _, permission := range seeds.GetDefaultPermissions() {
+		store.permissions = append(store.permissions, &models.Permission{ID: uuid.New(), Name: permission.Name})
+	}
+	return store
+}
+
+// addRole adds a role to organizationID, or a built-in role if it is nil, granted permissions
+func (m *mockRBACStore) addRole(name string, organizationID *uuid.UUID, permissions ...string) *models.Role {
+	role := &models.Role{ID: uuid.New(), Name: name, OrganizationID: organizationID}
+	m.roles[role.ID] = role
+	m.grants[role.ID] = make(map[uuid.UUID]bool)
+	for _, permission := range m.permissions {
+		for _, name := range permissions {
+			if permission.Name == name {
+				m.grants[role.ID][permission.ID] = true
+			}
+		}
+	}
+	return role
+}
+
+func (m *mockRBACStore) addMember(organizationID, userID, roleID uuid.UUID) {
+	if m.members[organizationID] == nil {
+		m.members[organizationID] = make(map[uuid.UUID]uuid.UUID)
+	}
+	m.members[organizationID][userID] = roleID
+	m.assignments[roleAssignment{userID, roleID, organizationID}] = true
+}
+
+func (m *mockRBACStore) Create(ctx context.Context, role *models.Role, permissionIDs []uuid.UUID) error {
+	role.ID = uuid.New()
+	stored := *role
+	m.roles[role.ID] = &stored
+	m.grants[role.ID] = make(map[uuid.UUID]bool)
+	for _, id := range permissionIDs {
+		m.grants[role.ID][id] = true
+	}
+	return nil
+}
+
+func (m *mockRBACStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Role, error) {
+	role, ok := m.roles[id]
+	if !ok {
+		return nil, postgres.ErrRoleNotFound
+	}
+	copied := *role
+	return &copied, nil
+}
+
+func (m *mockRBACStore) GetByName(ctx context.Context, name string) (*models.Role, error) {
+	for _, role := range m.roles {
+		if role.Name == name {
+			copied := *role
+			return &copied, nil
+		}
+	}
+	return nil, postgres.ErrRoleNotFound
+}
+
+func (m *mockRBACStore) ListForOrganization(ctx context.Context, organizationID uuid.UUID) ([]*models.Role, error) {
+	var roles []*models.Role
+	for _, role := range m.roles {
+		if role.OrganizationID == nil || *role.OrganizationID == organizationID {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+func (m *mockRBACStore) GetRolePermissions(ctx context.Context, roleID uuid.UUID) ([]*models.Permission, error) {
+	var permissions []*models.Permission
+	for _, permission := range m.permissions {
+		if m.grants[roleID][permission.ID] {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions, nil
+}
+
+func (m *mockRBACStore) GrantPermission(ctx context.Context, roleID, permissionID uuid.UUID) error {
+	m.grants[roleID][permissionID] = true
+	return nil
+}
+
+func (m *mockRBACStore) RevokePermission(ctx context.Context, roleID, permissionID uuid.UUID) error {
+	if !m.grants[roleID][permissionID] {
+		return postgres.ErrPermissionNotGranted
+	}
+	delete(m.grants[roleID], permissionID)
+	return nil
+}
+
+func (m *mockRBACStore) List(ctx context.Context) ([]*models.Permission, error) {
+	return m.permissions, nil
+}
+
+func (m *mockRBACStore) AssignRole(ctx context.Context, userID, roleID uuid.UUID, organizationID *uuid.UUID, assignedBy uuid.UUID) error {
+	m.assignments[roleAssignment{userID, roleID, *organizationID}] = true
+	return nil
+}
+
+func (m *mockRBACStore) RemoveRole(ctx context.Context, userID, roleID uuid.UUID, organizationID *uuid.UUID) error {
+	key := roleAssignment{userID, roleID, *organizationID}
+	if !m.assignments[key] {
+		return postgres.ErrRoleAssignmentNotFound
+	}
+	delete(m.assignments, key)
+	return nil
+}
+
+func (m *mockRBACStore) CheckUserAccess(ctx context.Context, orgID, userID uuid.UUID) (bool, error) {
+	_, ok := m.members[orgID][userID]
+	return ok, nil
+}
+
+func (m *mockRBACStore) GetUserRoleInOrganization(ctx context.Context, orgID, userID uuid.UUID) (*models.Role, error) {
+	roleID, ok := m.members[orgID][userID]
+	if !ok {
+		return nil, fmt.Errorf("user not found in organization")
+	}
+	return m.GetByID(ctx, roleID)
+}
+
+// permissionNames returns the names of a role's permissions
+func permissionNames(role *models.Role) []string {
+	names := make([]string, len(role.Permissions))
+	for i, permission := range role.Permissions {
+		names[i] = permission.Name
+	}
+	return names
+}
+
+func newTestRoleService() (*RoleService, *mockRBACStore) {
+	store := newMockRBACStore()
+	return NewRoleService(store, store, store, store, logger.NewForTesting()), store
+}
+
+func TestRoleService_CreateRole(t *testing.T) {
+	ctx := context.Background()
+	organizationID := uuid.New()
+	caller := &auth.JWTClaims{
+		UserID:         uuid.New(),
+		OrganizationID: organizationID,
+		Permissions:    []string{"role:create", "workflow:read", "execution:read"},
+	}
+
+	t.Run("creates a role in the caller's organization", func(t *testing.T) {
+		service, _ := newTestRoleService()
+
+		role, err := service.CreateRole(ctx, caller, &models.CreateRoleRequest{
+			Name:        "auditor",
+			Permissions: []string{"workflow:read", "execution:read"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "auditor", role.Name)
+		assert.Equal(t, &organizationID, role.OrganizationID)
+		assert.ElementsMatch(t, []string{"workflow:read", "execution:read"}, permissionNames(role))
+	})
+
+	t.Run("rejects unknown permissions", func(t *testing.T) {
+		service, _ := newTestRoleService()
+
+		_, err := service.CreateRole(ctx, caller, &models.CreateRoleRequest{
+			Name:        "auditor",
+			Permissions: []string{"workflow:read", "workflows:read"},
+		})
+		assert.ErrorIs(t, err, ErrInvalidRole)
+		assert.Contains(t, err.Error(), "workflows:read")
+	})
+
+	t.Run("rejects permissions the caller does not hold", func(t *testing.T) {
+		service, _ := newTestRoleService()
+
+		_, err := service.CreateRole(ctx, caller, &models.CreateRoleRequest{
+			Name:        "deployer",
+			Permissions: []string{"workflow:read", "workflow:delete"},
+		})
+		assert.ErrorIs(t, err, ErrPermissionEscalation)
+		assert.Contains(t, err.Error(), "workflow:delete")
+	})
+
+	t.Run("rejects names in use", func(t *testing.T) {
+		service, store := newTestRoleService()
+		store.addRole("admin", nil)
+
+		_, err := service.CreateRole(ctx, caller, &models.CreateRoleRequest{Name: "admin"})
+		assert.ErrorIs(t, err, ErrRoleExists)
+	})
+}
+
+func TestRoleService_RolePermissions(t *testing.T) {
+	ctx := context.Background()
+	organizationID := uuid.New()
+	caller := &auth.JWTClaims{
+		UserID:         uuid.New(),
+		OrganizationID: organizationID,
+		Permissions:    []string{"role:update", "workflow:read", "execution:read"},
+	}
+
+	t.Run("attaches and detaches permissions of a custom role", func(t *testing.T) {
+		service, store := newTestRoleService()
+		role := store.addRole("auditor", &organizationID, "workflow:read")
+
+		updated, err := service.AttachPermissions(ctx, caller, role.ID, []string{"execution:read"})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"workflow:read", "execution:read"}, permissionNames(updated))
+
+		updated, err = service.DetachPermission(ctx, caller, role.ID, "workflow:read")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"execution:read"}, permissionNames(updated))
+
+		_, err = service.DetachPermission(ctx, caller, role.ID, "workflow:read")
+		assert.ErrorIs(t, err, postgres.ErrPermissionNotGranted)
+	})
+
+	t.Run("built-in roles cannot be modified", func(t *testing.T) {
+		service, store := newTestRoleService()
+		role := store.addRole("workflow_viewer", nil, "workflow:read")
+
+		_, err := service.AttachPermissions(ctx, caller, role.ID, []string{"execution:read"})
+		assert.ErrorIs(t, err, ErrBuiltInRole)
+		_, err = service.DetachPermission(ctx, caller, role.ID, "workflow:read")
+		assert.ErrorIs(t, err, ErrBuiltInRole)
+	})
+
+	t.Run("roles of other organizations are not found", func(t *testing.T) {
+		service, store := newTestRoleService()
+		otherOrganization := uuid.New()
+		role := store.addRole("auditor", &otherOrganization, "workflow:read")
+
+		_, err := service.GetRole(ctx, organizationID, role.ID)
+		assert.ErrorIs(t, err, postgres.ErrRoleNotFound)
+		_, err = service.AttachPermissions(ctx, caller, role.ID, []string{"execution:read"})
+		assert.ErrorIs(t, err, postgres.ErrRoleNotFound)
+	})
+
+	t.Run("rejects unknown and unheld permissions", func(t *testing.T) {
+		service, store := newTestRoleService()
+		role := store.addRole("auditor", &organizationID)
+
+		_, err := service.AttachPermissions(ctx, caller, role.ID, []string{"audit:write"})
+		assert.ErrorIs(t, err, ErrInvalidRole)
+		_, err = service.AttachPermissions(ctx, caller, role.ID, []string{"audit:read"})
+		assert.ErrorIs(t, err, ErrPermissionEscalation)
+		_, err = service.DetachPermission(ctx, caller, role.ID, "audit:write")
+		assert.ErrorIs(t, err, ErrInvalidRole)
+	})
+}
+
+func TestRoleService_AssignRole(t *testing.T) {
+	ctx := context.Background()
+	organizationID := uuid.New()
+	caller := &auth.JWTClaims{
+		UserID:         uuid.New(),
+		OrganizationID: organizationID,
+		Permissions:    []string{"role:assign", "approval:read", "approval:approve"},
+	}
+
+	service, store := newTestRoleService()
+	viewer := store.addRole("workflow_viewer", nil, "workflow:read")
+	approver := store.addRole("approver", nil, "approval:read", "approval:approve")
+	superAdmin := store.addRole(SuperAdminRole, nil, "approval:read")
+	member, outsider := uuid.New(), uuid.New()
+	store.addMember(organizationID, member, viewer.ID)
+
+	require.NoError(t, service.AssignRole(ctx, caller, member, approver.ID))
+	assert.True(t, store.assignments[roleAssignment{member, approver.ID, organizationID}])
+
+	assert.ErrorIs(t, service.AssignRole(ctx, caller, outsider, approver.ID), ErrNotOrganizationMember)
+	assert.ErrorIs(t, service.AssignRole(ctx, caller, member, viewer.ID), ErrPermissionEscalation,
+		"the caller does not hold workflow:read")
+	assert.ErrorIs(t, service.AssignRole(ctx, caller, member, superAdmin.ID), ErrInvalidRole)
+
+	require.NoError(t, service.UnassignRole(ctx, caller, member, approver.ID))
+	assert.ErrorIs(t, service.UnassignRole(ctx, caller, member, approver.ID), postgres.ErrRoleAssignmentNotFound)
+	assert.ErrorIs(t, service.UnassignRole(ctx, caller, member, viewer.ID), ErrMembershipRole)
+}
+
+func TestRoleService_ListPermissions(t *testing.T) {
+	service, store := newTestRoleService()
+	store.permissions = append(store.permissions, &models.Permission{ID: uuid.New(), Name: "legacy:permission"})
+
+	permissions, err := service.ListPermissions(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, permissions, len(seeds.GetDefaultPermissions()), "only catalog permissions are listed")
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,18 +12,27 @@ import (
 	"github.com/robfig/cron/v3"
 )
 
+// ErrInvalidSchedule is returned when a schedule is created or updated with invalid settings
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
 // ScheduleRepository defines the interface for schedule data access
 type ScheduleRepository interface {
 	Create(ctx context.Context, schedule *models.WorkflowSchedule) error
 	GetByID(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowSchedule, error)
 	GetByWorkflowID(ctx context.Context, organizationID, workflowID uuid.UUID) ([]*models.WorkflowSchedule, error)
 	GetDueSchedules(ctx context.Context, organizationID uuid.UUID) ([]*models.WorkflowSchedule, error)
+	ClaimDueSchedules(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]*models.WorkflowSchedule, error)
+	ReleaseClaim(ctx context.Context, organizationID, id uuid.UUID) error
 	Update(ctx context.Context, organizationID uuid.UUID, schedule *models.WorkflowSchedule) error
 	UpdateNextTrigger(ctx context.Context, organizationID, id uuid.UUID, lastTriggered, nextTrigger time.Time) error
+	Complete(ctx context.Context, organizationID, id uuid.UUID, lastTriggered time.Time) error
 	Delete(ctx context.Context, organizationID, id uuid.UUID) error
 	List(ctx context.Context, organizationID uuid.UUID, limit, offset int) ([]*models.WorkflowSchedule, int64, error)
 }
 
+// MaxNextRuns is the most upcoming runs listed for a schedule
+const MaxNextRuns = 100
+
 // ScheduleService handles workflow scheduling logic
 type ScheduleService struct {
 	scheduleRepo ScheduleRepository
@@ -42,12 +52,11 @@ func NewScheduleService(scheduleRepo ScheduleRepository, log *logger.Logger) *Sc
 	}
 }
 
-// CreateSchedule creates a new workflow schedule
+// CreateSchedule creates a new workflow schedule, recurring on a cron expression or running
+// once at a future time
 func (s *ScheduleService) CreateSchedule(ctx context.Context, organizationID, workflowID uuid.UUID, req *models.CreateScheduleRequest) (*models.WorkflowSchedule, error) {
-	// Validate cron expression
-	schedule, err := s.parser.Parse(req.CronExpression)
-	if err != nil {
-		return nil, fmt.Errorf("invalid cron expression: %w", err)
+	if (req.CronExpression == "") == (req.RunAt == nil) {
+		return nil, fmt.Errorf("%w: exactly one of cron_expression and run_at is required", ErrInvalidSchedule)
 	}
 
 	// Set default timezone if not provided
@@ -59,11 +68,23 @@ func (s *ScheduleService) CreateSchedule(ctx context.Context, organizationID, wo
 	// Load timezone
 	loc, err := time.LoadLocation(timezone)
 	if err != nil {
-		return nil, fmt.Errorf("invalid timezone: %w", err)
+		return nil, fmt.Errorf("%w: invalid timezone: %v", ErrInvalidSchedule, err)
 	}
 
 	// Calculate next trigger time
-	nextTrigger := schedule.Next(time.Now().In(loc))
+	var nextTrigger time.Time
+	if req.RunAt != nil {
+		if !req.RunAt.After(time.Now()) {
+			return nil, fmt.Errorf("%w: run_at must be in the future", ErrInvalidSchedule)
+		}
+		nextTrigger = *req.RunAt
+	} else {
+		schedule, err := s.parser.Parse(req.CronExpression)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid cron expression: %v", ErrInvalidSchedule, err)
+		}
+		nextTrigger = nextRun(schedule, time.Now(), loc)
+	}
 
 	// Set default enabled if not provided
 	enabled := true
@@ -71,24 +92,38 @@ func (s *ScheduleService) CreateSchedule(ctx context.Context, organizationID, wo
 		enabled = *req.Enabled
 	}
 
+	missedRunPolicy := req.MissedRunPolicy
+	if missedRunPolicy == "" {
+		missedRunPolicy = models.MissedRunPolicySkip
+	}
+	if !missedRunPolicy.IsValid() {
+		return nil, fmt.Errorf("%w: invalid missed run policy: %s", ErrInvalidSchedule, missedRunPolicy)
+	}
+
 	// Create schedule
 	newSchedule := &models.WorkflowSchedule{
-		ID:             uuid.New(),
-		OrganizationID: organizationID,
-		WorkflowID:     workflowID,
-		CronExpression: req.CronExpression,
-		Timezone:       timezone,
-		Enabled:        enabled,
-		NextTriggerAt:  &nextTrigger,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+		ID:              uuid.New(),
+		OrganizationID:  organizationID,
+		WorkflowID:      workflowID,
+		CronExpression:  req.CronExpression,
+		Timezone:        timezone,
+		Enabled:         enabled,
+		NextTriggerAt:   &nextTrigger,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+		MissedRunPolicy: missedRunPolicy,
+		RunAt:           req.RunAt,
 	}
 
 	if err := s.scheduleRepo.Create(ctx, newSchedule); err != nil {
 		return nil, fmt.Errorf("failed to create schedule: %w", err)
 	}
 
-	s.logger.Infof("Created schedule %s for workflow %s with cron %s", newSchedule.ID, workflowID, req.CronExpression)
+	if newSchedule.IsOneShot() {
+		s.logger.Infof("Created schedule %s for workflow %s to run once at %s", newSchedule.ID, workflowID, req.RunAt)
+	} else {
+		s.logger.Infof("Created schedule %s for workflow %s with cron %s", newSchedule.ID, workflowID, req.CronExpression)
+	}
 
 	return newSchedule, nil
 }
@@ -113,19 +148,33 @@ func (s *ScheduleService) UpdateSchedule(ctx context.Context, organizationID, id
 
 	// Update fields if provided
 	if req.CronExpression != nil {
+		if existing.IsOneShot() {
+			return nil, fmt.Errorf("%w: a one-shot schedule has no cron expression", ErrInvalidSchedule)
+		}
 		// Validate new cron expression
 		_, err := s.parser.Parse(*req.CronExpression)
 		if err != nil {
-			return nil, fmt.Errorf("invalid cron expression: %w", err)
+			return nil, fmt.Errorf("%w: invalid cron expression: %v", ErrInvalidSchedule, err)
 		}
 		existing.CronExpression = *req.CronExpression
 	}
 
+	if req.RunAt != nil {
+		if !existing.IsOneShot() {
+			return nil, fmt.Errorf("%w: a recurring schedule has no run_at", ErrInvalidSchedule)
+		}
+		if !req.RunAt.After(time.Now()) {
+			return nil, fmt.Errorf("%w: run_at must be in the future", ErrInvalidSchedule)
+		}
+		existing.RunAt = req.RunAt
+		existing.NextTriggerAt = req.RunAt
+	}
+
 	if req.Timezone != nil {
 		// Validate new timezone
 		_, err := time.LoadLocation(*req.Timezone)
 		if err != nil {
-			return nil, fmt.Errorf("invalid timezone: %w", err)
+			return nil, fmt.Errorf("%w: invalid timezone: %v", ErrInvalidSchedule, err)
 		}
 		existing.Timezone = *req.Timezone
 	}
@@ -134,8 +183,15 @@ func (s *ScheduleService) UpdateSchedule(ctx context.Context, organizationID, id
 		existing.Enabled = *req.Enabled
 	}
 
+	if req.MissedRunPolicy != nil {
+		if !req.MissedRunPolicy.IsValid() {
+			return nil, fmt.Errorf("%w: invalid missed run policy: %s", ErrInvalidSchedule, *req.MissedRunPolicy)
+		}
+		existing.MissedRunPolicy = *req.MissedRunPolicy
+	}
+
 	// Recalculate next trigger time if cron or timezone changed
-	if req.CronExpression != nil || req.Timezone != nil {
+	if !existing.IsOneShot() && (req.CronExpression != nil || req.Timezone != nil) {
 		schedule, err := s.parser.Parse(existing.CronExpression)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse cron expression: %w", err)
@@ -146,7 +202,7 @@ func (s *ScheduleService) UpdateSchedule(ctx context.Context, organizationID, id
 			return nil, fmt.Errorf("failed to load timezone: %w", err)
 		}
 
-		nextTrigger := schedule.Next(time.Now().In(loc))
+		nextTrigger := nextRun(schedule, time.Now(), loc)
 		existing.NextTriggerAt = &nextTrigger
 	}
 
@@ -176,13 +232,70 @@ func (s *ScheduleService) GetDueSchedules(ctx context.Context, organizationID uu
 	return s.scheduleRepo.GetDueSchedules(ctx, organizationID)
 }
 
-// MarkTriggered marks a schedule as triggered and calculates the next run time
-func (s *ScheduleService) MarkTriggered(ctx context.Context, organizationID, id uuid.UUID) error {
+// ClaimDueSchedules claims up to limit due schedules across all organizations for the caller
+// to trigger. A claimed schedule is not returned again until it is released or its claim
+// expires after claimTTL
+func (s *ScheduleService) ClaimDueSchedules(ctx context.Context, claimTTL time.Duration, limit int) ([]*models.WorkflowSchedule, error) {
+	return s.scheduleRepo.ClaimDueSchedules(ctx, time.Now(), claimTTL, limit)
+}
+
+// ReleaseSchedule gives up a claim on a schedule, leaving its next run time as it is
+func (s *ScheduleService) ReleaseSchedule(ctx context.Context, organizationID, id uuid.UUID) error {
+	return s.scheduleRepo.ReleaseClaim(ctx, organizationID, id)
+}
+
+// DueRuns lists the scheduled times, from the schedule's next trigger time up to now, that have
+// not fired yet, keeping the latest limit of them. missed counts the earlier runs left out
+func (s *ScheduleService) DueRuns(schedule *models.WorkflowSchedule, now time.Time, limit int) (runs []time.Time, missed int, err error) {
+	if schedule.NextTriggerAt == nil || schedule.NextTriggerAt.After(now) {
+		return nil, 0, nil
+	}
+	if limit <= 0 {
+		limit = 1
+	}
+
+	// A one-shot schedule has a single run
+	if schedule.IsOneShot() {
+		return []time.Time{*schedule.NextTriggerAt}, 0, nil
+	}
+
+	cronSchedule, err := s.parser.Parse(schedule.CronExpression)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse cron expression: %w", err)
+	}
+
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load timezone: %w", err)
+	}
+
+	for run := schedule.NextTriggerAt.In(loc); !run.After(now); run = nextRun(cronSchedule, run, loc) {
+		runs = append(runs, run)
+		if len(runs) > limit {
+			runs = runs[1:]
+			missed++
+		}
+	}
+
+	return runs, missed, nil
+}
+
+// MarkTriggered records that the run scheduled at scheduledAt fired and advances the schedule to
+// the run after it. A one-shot schedule is completed instead: it is disabled and has no next run
+func (s *ScheduleService) MarkTriggered(ctx context.Context, organizationID, id uuid.UUID, scheduledAt time.Time) error {
 	schedule, err := s.scheduleRepo.GetByID(ctx, organizationID, id)
 	if err != nil {
 		return err
 	}
 
+	if schedule.IsOneShot() {
+		if err := s.scheduleRepo.Complete(ctx, organizationID, id, time.Now()); err != nil {
+			return fmt.Errorf("failed to complete schedule: %w", err)
+		}
+		s.logger.Debugf("One-shot schedule %s completed", id)
+		return nil
+	}
+
 	// Parse cron expression
 	cronSchedule, err := s.parser.Parse(schedule.CronExpression)
 	if err != nil {
@@ -197,7 +310,7 @@ func (s *ScheduleService) MarkTriggered(ctx context.Context, organizationID, id
 
 	// Calculate next trigger time
 	now := time.Now().In(loc)
-	nextTrigger := cronSchedule.Next(now)
+	nextTrigger := nextRun(cronSchedule, scheduledAt, loc)
 
 	// Update trigger times
 	if err := s.scheduleRepo.UpdateNextTrigger(ctx, organizationID, id, now, nextTrigger); err != nil {
@@ -209,9 +322,10 @@ func (s *ScheduleService) MarkTriggered(ctx context.Context, organizationID, id
 	return nil
 }
 
-// GetNextRuns calculates the next N run times for a schedule
+// GetNextRuns calculates the next N run times for a schedule. A one-shot schedule has at most
+// one, its pending run
 func (s *ScheduleService) GetNextRuns(ctx context.Context, organizationID, id uuid.UUID, count int) ([]time.Time, error) {
-	if count <= 0 || count > 100 {
+	if count <= 0 || count > MaxNextRuns {
 		count = 10 // Default to 10, max 100
 	}
 
@@ -220,6 +334,13 @@ func (s *ScheduleService) GetNextRuns(ctx context.Context, organizationID, id uu
 		return nil, err
 	}
 
+	if schedule.IsOneShot() {
+		if schedule.NextTriggerAt == nil {
+			return []time.Time{}, nil
+		}
+		return []time.Time{*schedule.NextTriggerAt}, nil
+	}
+
 	// Parse cron expression
 	cronSchedule, err := s.parser.Parse(schedule.CronExpression)
 	if err != nil {
@@ -234,15 +355,102 @@ func (s *ScheduleService) GetNextRuns(ctx context.Context, organizationID, id uu
 
 	// Calculate next N runs
 	runs := make([]time.Time, count)
-	current := time.Now().In(loc)
+	current := time.Now()
 	for i := 0; i < count; i++ {
-		current = cronSchedule.Next(current)
+		current = nextRun(cronSchedule, current, loc)
 		runs[i] = current
 	}
 
 	return runs, nil
 }
 
+// PreviewSchedule describes a schedule and lists its next count runs in the schedule's timezone.
+// Disabled schedules are previewed as if they were enabled
+func (s *ScheduleService) PreviewSchedule(schedule *models.WorkflowSchedule, count int) (*models.SchedulePreviewResponse, error) {
+	if count <= 0 {
+		count = 10
+	}
+	if count > MaxNextRuns {
+		count = MaxNextRuns
+	}
+
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone: %w", err)
+	}
+
+	preview := &models.SchedulePreviewResponse{
+		ScheduleID: schedule.ID,
+		WorkflowID: schedule.WorkflowID,
+		Timezone:   schedule.Timezone,
+		Enabled:    schedule.Enabled,
+		NextRuns:   []time.Time{},
+	}
+
+	if schedule.IsOneShot() {
+		runAt := schedule.RunAt.In(loc)
+		preview.Description = "Once on " + runAt.Format("January 2, 2006 at 3:04 PM") + " " + schedule.Timezone
+		if schedule.LastTriggeredAt == nil {
+			preview.NextRuns = append(preview.NextRuns, runAt)
+		}
+		return preview, nil
+	}
+
+	cronSchedule, err := s.parser.Parse(schedule.CronExpression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cron expression: %w", err)
+	}
+	preview.Description = describeCron(schedule.CronExpression, schedule.Timezone)
+
+	current := time.Now()
+	for i := 0; i < count; i++ {
+		current = nextRun(cronSchedule, current, loc)
+		if current.IsZero() {
+			break
+		}
+		preview.NextRuns = append(preview.NextRuns, current)
+	}
+
+	return preview, nil
+}
+
+// cronStarBit marks a cron field written as "*"; it mirrors the cron package's unexported starBit
+const cronStarBit = 1 << 63
+
+// nextRun returns the schedule's first run after t. Cron fields are matched against wall-clock
+// time in loc, so around daylight saving transitions:
+//   - a run at a wall-clock time skipped when clocks go forward does not fire that day
+//   - a run at a fixed hour that repeats when clocks go back fires once, at the hour's first
+//     occurrence. Schedules with a "*" hour fire in both occurrences, which are an hour apart
+func nextRun(schedule cron.Schedule, t time.Time, loc *time.Location) time.Time {
+	next := schedule.Next(t.In(loc))
+
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok || spec.Hour&cronStarBit != 0 {
+		return next
+	}
+	for !next.IsZero() && isRepeatedWallClock(next) {
+		next = schedule.Next(next)
+	}
+	return next
+}
+
+// isRepeatedWallClock reports whether t is the second occurrence of its wall-clock time, in a
+// period that repeats because clocks went back shortly before
+func isRepeatedWallClock(t time.Time) bool {
+	_, offset := t.Zone()
+	// Offsets change by at most two hours, so the offset before a recent change is in effect
+	// three hours earlier
+	_, earlierOffset := t.Add(-3 * time.Hour).Zone()
+	if earlierOffset <= offset {
+		return false
+	}
+
+	first := t.Add(-time.Duration(earlierOffset-offset) * time.Second)
+	_, firstOffset := first.Zone()
+	return firstOffset == earlierOffset && first.Hour() == t.Hour() && first.Minute() == t.Minute() && first.Second() == t.Second()
+}
+
 // ValidateCronExpression validates a cron expression
 func (s *ScheduleService) ValidateCronExpression(expression string) error {
 	_, err := s.parser.Parse(expression)
@@ -15,13 +15,16 @@ import (
 // Mock ScheduleRepository for testing
 type mockScheduleRepo struct {
 	createFunc        func(ctx context.Context, schedule *models.WorkflowSchedule) error
-	getByIDFunc       func(ctx context.Context, id uuid.UUID) (*models.WorkflowSchedule, error)
-	getByWorkflowFunc func(ctx context.Context, workflowID uuid.UUID) ([]*models.WorkflowSchedule, error)
-	getDueFunc        func(ctx context.Context) ([]*models.WorkflowSchedule, error)
-	updateFunc        func(ctx context.Context, schedule *models.WorkflowSchedule) error
-	updateNextFunc    func(ctx context.Context, id uuid.UUID, lastTriggered, nextTrigger time.Time) error
-	deleteFunc        func(ctx context.Context, id uuid.UUID) error
-	listFunc          func(ctx context.Context, limit, offset int) ([]*models.WorkflowSchedule, int64, error)
+	getByIDFunc       func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowSchedule, error)
+	getByWorkflowFunc func(ctx context.Context, organizationID, workflowID uuid.UUID) ([]*models.WorkflowSchedule, error)
+	getDueFunc        func(ctx context.Context, organizationID uuid.UUID) ([]*models.WorkflowSchedule, error)
+	claimDueFunc      func(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]*models.WorkflowSchedule, error)
+	releaseClaimFunc  func(ctx context.Context, organizationID, id uuid.UUID) error
+	updateFunc        func(ctx context.Context, organizationID uuid.UUID, schedule *models.WorkflowSchedule) error
+	updateNextFunc    func(ctx context.Context, organizationID, id uuid.UUID, lastTriggered, nextTrigger time.Time) error
+	completeFunc      func(ctx context.Context, organizationID, id uuid.UUID, lastTriggered time.Time) error
+	deleteFunc        func(ctx context.Context, organizationID, id uuid.UUID) error
+	listFunc          func(ctx context.Context, organizationID uuid.UUID, limit, offset int) ([]*models.WorkflowSchedule, int64, error)
 }
 
 func (m *mockScheduleRepo) Create(ctx context.Context, schedule *models.WorkflowSchedule) error {
@@ -31,51 +34,72 @@ func (m *mockScheduleRepo) Create(ctx context.Context, schedule *models.Workflow
 	return nil
 }
 
-func (m *mockScheduleRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.WorkflowSchedule, error) {
+func (m *mockScheduleRepo) GetByID(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowSchedule, error) {
 	if m.getByIDFunc != nil {
-		return m.getByIDFunc(ctx, id)
+		return m.getByIDFunc(ctx, organizationID, id)
 	}
 	return nil, errors.New("not found")
 }
 
-func (m *mockScheduleRepo) GetByWorkflowID(ctx context.Context, workflowID uuid.UUID) ([]*models.WorkflowSchedule, error) {
+func (m *mockScheduleRepo) GetByWorkflowID(ctx context.Context, organizationID, workflowID uuid.UUID) ([]*models.WorkflowSchedule, error) {
 	if m.getByWorkflowFunc != nil {
-		return m.getByWorkflowFunc(ctx, workflowID)
+		return m.getByWorkflowFunc(ctx, organizationID, workflowID)
 	}
 	return []*models.WorkflowSchedule{}, nil
 }
 
-func (m *mockScheduleRepo) GetDueSchedules(ctx context.Context) ([]*models.WorkflowSchedule, error) {
+func (m *mockScheduleRepo) GetDueSchedules(ctx context.Context, organizationID uuid.UUID) ([]*models.WorkflowSchedule, error) {
 	if m.getDueFunc != nil {
-		return m.getDueFunc(ctx)
+		return m.getDueFunc(ctx, organizationID)
 	}
 	return []*models.WorkflowSchedule{}, nil
 }
 
-func (m *mockScheduleRepo) Update(ctx context.Context, schedule *models.WorkflowSchedule) error {
+func (m *mockScheduleRepo) ClaimDueSchedules(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]*models.WorkflowSchedule, error) {
+	if m.claimDueFunc != nil {
+		return m.claimDueFunc(ctx, now, claimTTL, limit)
+	}
+	return []*models.WorkflowSchedule{}, nil
+}
+
+func (m *mockScheduleRepo) ReleaseClaim(ctx context.Context, organizationID, id uuid.UUID) error {
+	if m.releaseClaimFunc != nil {
+		return m.releaseClaimFunc(ctx, organizationID, id)
+	}
+	return nil
+}
+
+func (m *mockScheduleRepo) Update(ctx context.Context, organizationID uuid.UUID, schedule *models.WorkflowSchedule) error {
 	if m.updateFunc != nil {
-		return m.updateFunc(ctx, schedule)
+		return m.updateFunc(ctx, organizationID, schedule)
 	}
 	return nil
 }
 
-func (m *mockScheduleRepo) UpdateNextTrigger(ctx context.Context, id uuid.UUID, lastTriggered, nextTrigger time.Time) error {
+func (m *mockScheduleRepo) UpdateNextTrigger(ctx context.Context, organizationID, id uuid.UUID, lastTriggered, nextTrigger time.Time) error {
 	if m.updateNextFunc != nil {
-		return m.updateNextFunc(ctx, id, lastTriggered, nextTrigger)
+		return m.updateNextFunc(ctx, organizationID, id, lastTriggered, nextTrigger)
 	}
 	return nil
 }
 
-func (m *mockScheduleRepo) Delete(ctx context.Context, id uuid.UUID) error {
+func (m *mockScheduleRepo) Complete(ctx context.Context, organizationID, id uuid.UUID, lastTriggered time.Time) error {
+	if m.completeFunc != nil {
+		return m.completeFunc(ctx, organizationID, id, lastTriggered)
+	}
+	return nil
+}
+
+func (m *mockScheduleRepo) Delete(ctx context.Context, organizationID, id uuid.UUID) error {
 	if m.deleteFunc != nil {
-		return m.deleteFunc(ctx, id)
+		return m.deleteFunc(ctx, organizationID, id)
 	}
 	return nil
 }
 
-func (m *mockScheduleRepo) List(ctx context.Context, limit, offset int) ([]*models.WorkflowSchedule, int64, error) {
+func (m *mockScheduleRepo) List(ctx context.Context, organizationID uuid.UUID, limit, offset int) ([]*models.WorkflowSchedule, int64, error) {
 	if m.listFunc != nil {
-		return m.listFunc(ctx, limit, offset)
+		return m.listFunc(ctx, organizationID, limit, offset)
 	}
 	return []*models.WorkflowSchedule{}, 0, nil
 }
@@ -102,7 +126,7 @@ func TestCreateSchedule(t *testing.T) {
 			Timezone:       "UTC",
 		}
 
-		schedule, err := service.CreateSchedule(context.Background(), workflowID, req)
+		schedule, err := service.CreateSchedule(context.Background(), uuid.New(), workflowID, req)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, schedule)
@@ -125,7 +149,7 @@ func TestCreateSchedule(t *testing.T) {
 			Timezone:       "UTC",
 		}
 
-		schedule, err := service.CreateSchedule(context.Background(), workflowID, req)
+		schedule, err := service.CreateSchedule(context.Background(), uuid.New(), workflowID, req)
 
 		assert.Error(t, err)
 		assert.Nil(t, schedule)
@@ -143,7 +167,7 @@ func TestCreateSchedule(t *testing.T) {
 			Timezone:       "InvalidTimezone",
 		}
 
-		schedule, err := service.CreateSchedule(context.Background(), workflowID, req)
+		schedule, err := service.CreateSchedule(context.Background(), uuid.New(), workflowID, req)
 
 		assert.Error(t, err)
 		assert.Nil(t, schedule)
@@ -165,7 +189,7 @@ func TestCreateSchedule(t *testing.T) {
 			CronExpression: "0 0 9 * * *",
 		}
 
-		schedule, err := service.CreateSchedule(context.Background(), workflowID, req)
+		schedule, err := service.CreateSchedule(context.Background(), uuid.New(), workflowID, req)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, schedule)
@@ -190,7 +214,7 @@ func TestCreateSchedule(t *testing.T) {
 			Enabled:        &enabled,
 		}
 
-		schedule, err := service.CreateSchedule(context.Background(), workflowID, req)
+		schedule, err := service.CreateSchedule(context.Background(), uuid.New(), workflowID, req)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, schedule)
@@ -213,10 +237,10 @@ func TestUpdateSchedule(t *testing.T) {
 		}
 
 		repo := &mockScheduleRepo{
-			getByIDFunc: func(ctx context.Context, id uuid.UUID) (*models.WorkflowSchedule, error) {
+			getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowSchedule, error) {
 				return existingSchedule, nil
 			},
-			updateFunc: func(ctx context.Context, schedule *models.WorkflowSchedule) error {
+			updateFunc: func(ctx context.Context, organizationID uuid.UUID, schedule *models.WorkflowSchedule) error {
 				return nil
 			},
 		}
@@ -228,7 +252,7 @@ func TestUpdateSchedule(t *testing.T) {
 			CronExpression: &newCron,
 		}
 
-		schedule, err := service.UpdateSchedule(context.Background(), scheduleID, req)
+		schedule, err := service.UpdateSchedule(context.Background(), uuid.New(), scheduleID, req)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, schedule)
@@ -247,7 +271,7 @@ func TestUpdateSchedule(t *testing.T) {
 		}
 
 		repo := &mockScheduleRepo{
-			getByIDFunc: func(ctx context.Context, id uuid.UUID) (*models.WorkflowSchedule, error) {
+			getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowSchedule, error) {
 				return existingSchedule, nil
 			},
 		}
@@ -259,7 +283,7 @@ func TestUpdateSchedule(t *testing.T) {
 			CronExpression: &invalidCron,
 		}
 
-		schedule, err := service.UpdateSchedule(context.Background(), scheduleID, req)
+		schedule, err := service.UpdateSchedule(context.Background(), uuid.New(), scheduleID, req)
 
 		assert.Error(t, err)
 		assert.Nil(t, schedule)
@@ -277,10 +301,10 @@ func TestUpdateSchedule(t *testing.T) {
 		}
 
 		repo := &mockScheduleRepo{
-			getByIDFunc: func(ctx context.Context, id uuid.UUID) (*models.WorkflowSchedule, error) {
+			getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowSchedule, error) {
 				return existingSchedule, nil
 			},
-			updateFunc: func(ctx context.Context, schedule *models.WorkflowSchedule) error {
+			updateFunc: func(ctx context.Context, organizationID uuid.UUID, schedule *models.WorkflowSchedule) error {
 				return nil
 			},
 		}
@@ -292,7 +316,7 @@ func TestUpdateSchedule(t *testing.T) {
 			Enabled: &enabled,
 		}
 
-		schedule, err := service.UpdateSchedule(context.Background(), scheduleID, req)
+		schedule, err := service.UpdateSchedule(context.Background(), uuid.New(), scheduleID, req)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, schedule)
@@ -317,10 +341,10 @@ func TestMarkTriggered(t *testing.T) {
 		var capturedLastTriggered, capturedNextTrigger time.Time
 
 		repo := &mockScheduleRepo{
-			getByIDFunc: func(ctx context.Context, id uuid.UUID) (*models.WorkflowSchedule, error) {
+			getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowSchedule, error) {
 				return schedule, nil
 			},
-			updateNextFunc: func(ctx context.Context, id uuid.UUID, lastTriggered, nextTrigger time.Time) error {
+			updateNextFunc: func(ctx context.Context, organizationID, id uuid.UUID, lastTriggered, nextTrigger time.Time) error {
 				capturedLastTriggered = lastTriggered
 				capturedNextTrigger = nextTrigger
 				return nil
@@ -329,15 +353,291 @@ func TestMarkTriggered(t *testing.T) {
 
 		service := NewScheduleService(repo, log)
 
-		err := service.MarkTriggered(context.Background(), scheduleID)
+		scheduledAt := time.Now().Truncate(time.Hour)
+		err := service.MarkTriggered(context.Background(), uuid.New(), scheduleID, scheduledAt)
 
 		assert.NoError(t, err)
 		assert.NotZero(t, capturedLastTriggered)
-		assert.NotZero(t, capturedNextTrigger)
+		assert.True(t, capturedNextTrigger.Equal(scheduledAt.Add(time.Hour)))
 		assert.True(t, capturedNextTrigger.After(capturedLastTriggered))
 	})
 }
 
+// TestOneShotSchedule tests schedules that run once at a fixed time
+func TestOneShotSchedule(t *testing.T) {
+	log := logger.NewForTesting()
+
+	t.Run("creates a schedule that runs at run_at", func(t *testing.T) {
+		var captured *models.WorkflowSchedule
+		repo := &mockScheduleRepo{
+			createFunc: func(ctx context.Context, schedule *models.WorkflowSchedule) error {
+				captured = schedule
+				return nil
+			},
+		}
+		service := NewScheduleService(repo, log)
+
+		runAt := time.Now().Add(72 * time.Hour)
+		schedule, err := service.CreateSchedule(context.Background(), uuid.New(), uuid.New(), &models.CreateScheduleRequest{RunAt: &runAt})
+
+		assert.NoError(t, err)
+		assert.Equal(t, captured, schedule)
+		assert.True(t, schedule.IsOneShot())
+		assert.Empty(t, schedule.CronExpression)
+		assert.True(t, schedule.NextTriggerAt.Equal(runAt))
+		assert.True(t, schedule.Enabled)
+	})
+
+	invalid := []struct {
+		name string
+		req  *models.CreateScheduleRequest
+	}{
+		{name: "run_at in the past", req: &models.CreateScheduleRequest{RunAt: timePtr(time.Now().Add(-time.Minute))}},
+		{name: "both cron and run_at", req: &models.CreateScheduleRequest{CronExpression: "0 0 9 * * *", RunAt: timePtr(time.Now().Add(time.Hour))}},
+		{name: "neither cron nor run_at", req: &models.CreateScheduleRequest{}},
+	}
+	for _, tt := range invalid {
+		t.Run("rejects "+tt.name, func(t *testing.T) {
+			service := NewScheduleService(&mockScheduleRepo{}, log)
+
+			_, err := service.CreateSchedule(context.Background(), uuid.New(), uuid.New(), tt.req)
+
+			assert.ErrorIs(t, err, ErrInvalidSchedule)
+		})
+	}
+
+	t.Run("has a single due run", func(t *testing.T) {
+		service := NewScheduleService(&mockScheduleRepo{}, log)
+		runAt := time.Now().Add(-2 * time.Hour)
+		schedule := &models.WorkflowSchedule{Timezone: "UTC", RunAt: &runAt, NextTriggerAt: &runAt, MissedRunPolicy: models.MissedRunPolicyCatchUp}
+
+		runs, missed, err := service.DueRuns(schedule, time.Now(), 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []time.Time{runAt}, runs)
+		assert.Zero(t, missed)
+	})
+
+	t.Run("is completed once triggered", func(t *testing.T) {
+		runAt := time.Now().Add(-time.Minute)
+		completed := false
+		repo := &mockScheduleRepo{
+			getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowSchedule, error) {
+				return &models.WorkflowSchedule{ID: id, Timezone: "UTC", Enabled: true, RunAt: &runAt, NextTriggerAt: &runAt}, nil
+			},
+			updateNextFunc: func(ctx context.Context, organizationID, id uuid.UUID, lastTriggered, nextTrigger time.Time) error {
+				t.Error("Expected a one-shot schedule not to be rescheduled")
+				return nil
+			},
+			completeFunc: func(ctx context.Context, organizationID, id uuid.UUID, lastTriggered time.Time) error {
+				completed = true
+				return nil
+			},
+		}
+		service := NewScheduleService(repo, log)
+
+		err := service.MarkTriggered(context.Background(), uuid.New(), uuid.New(), runAt)
+
+		assert.NoError(t, err)
+		assert.True(t, completed)
+	})
+}
+
+// TestPreviewSchedule tests previewing a schedule's upcoming runs
+func TestPreviewSchedule(t *testing.T) {
+	service := NewScheduleService(&mockScheduleRepo{}, logger.NewForTesting())
+
+	t.Run("lists runs in the schedule's timezone", func(t *testing.T) {
+		schedule := &models.WorkflowSchedule{ID: uuid.New(), CronExpression: "0 0 9 * * *", Timezone: "America/New_York"}
+
+		preview, err := service.PreviewSchedule(schedule, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Every day at 9:00 AM America/New_York", preview.Description)
+		assert.Len(t, preview.NextRuns, 3)
+		for _, run := range preview.NextRuns {
+			assert.Equal(t, "America/New_York", run.Location().String())
+			assert.Equal(t, 9, run.Hour())
+		}
+	})
+
+	t.Run("caps the number of runs", func(t *testing.T) {
+		schedule := &models.WorkflowSchedule{ID: uuid.New(), CronExpression: "0 * * * * *", Timezone: "UTC"}
+
+		preview, err := service.PreviewSchedule(schedule, 500)
+
+		assert.NoError(t, err)
+		assert.Len(t, preview.NextRuns, MaxNextRuns)
+	})
+
+	t.Run("lists the pending run of a one-shot schedule", func(t *testing.T) {
+		runAt := time.Date(2030, 3, 1, 14, 0, 0, 0, time.UTC)
+		schedule := &models.WorkflowSchedule{ID: uuid.New(), RunAt: &runAt, Timezone: "Europe/Paris"}
+
+		preview, err := service.PreviewSchedule(schedule, 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Once on March 1, 2030 at 3:00 PM Europe/Paris", preview.Description)
+		assert.Len(t, preview.NextRuns, 1)
+		assert.True(t, preview.NextRuns[0].Equal(runAt))
+	})
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// TestDueRuns tests listing the runs a schedule missed
+func TestDueRuns(t *testing.T) {
+	log := logger.NewForTesting()
+	service := NewScheduleService(&mockScheduleRepo{}, log)
+
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	newSchedule := func(next time.Time) *models.WorkflowSchedule {
+		return &models.WorkflowSchedule{
+			ID:             uuid.New(),
+			CronExpression: "0 0 * * * *", // Every hour
+			Timezone:       "UTC",
+			Enabled:        true,
+			NextTriggerAt:  &next,
+		}
+	}
+
+	t.Run("on time", func(t *testing.T) {
+		runs, missed, err := service.DueRuns(newSchedule(now.Add(-30*time.Minute)), now, 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []time.Time{now.Add(-30 * time.Minute)}, runs)
+		assert.Zero(t, missed)
+	})
+
+	t.Run("lists every missed run up to the limit", func(t *testing.T) {
+		runs, missed, err := service.DueRuns(newSchedule(now.Add(-3*time.Hour-30*time.Minute)), now, 10)
+
+		assert.NoError(t, err)
+		assert.Len(t, runs, 4)
+		assert.True(t, runs[0].Equal(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)))
+		assert.True(t, runs[3].Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)))
+		assert.Zero(t, missed)
+	})
+
+	t.Run("keeps the latest runs beyond the limit", func(t *testing.T) {
+		runs, missed, err := service.DueRuns(newSchedule(now.Add(-3*time.Hour-30*time.Minute)), now, 1)
+
+		assert.NoError(t, err)
+		assert.Len(t, runs, 1)
+		assert.True(t, runs[0].Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)))
+		assert.Equal(t, 3, missed)
+	})
+
+	t.Run("not due", func(t *testing.T) {
+		runs, missed, err := service.DueRuns(newSchedule(now.Add(time.Minute)), now, 10)
+
+		assert.NoError(t, err)
+		assert.Empty(t, runs)
+		assert.Zero(t, missed)
+	})
+}
+
+// TestNextRun_DaylightSaving tests next run calculation across America/New_York's 2024
+// transitions: clocks went forward from 2:00 to 3:00 on March 10 and back from 2:00 to 1:00
+// on November 3
+func TestNextRun_DaylightSaving(t *testing.T) {
+	service := NewScheduleService(&mockScheduleRepo{}, logger.NewForTesting())
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		cron     string
+		from     time.Time
+		expected []time.Time
+	}{
+		{
+			name: "daily run in the spring-forward gap is skipped",
+			cron: "0 0 2 * * *",
+			from: time.Date(2024, 3, 9, 12, 0, 0, 0, ny),
+			expected: []time.Time{
+				time.Date(2024, 3, 11, 6, 0, 0, 0, time.UTC), // 2:00 EDT
+				time.Date(2024, 3, 12, 6, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "daily run in the repeated hour fires once",
+			cron: "0 30 1 * * *",
+			from: time.Date(2024, 11, 2, 12, 0, 0, 0, ny),
+			expected: []time.Time{
+				time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), // 1:30 EDT
+				time.Date(2024, 11, 4, 6, 30, 0, 0, time.UTC), // 1:30 EST
+			},
+		},
+		{
+			name: "runs within a fixed repeated hour fire once",
+			cron: "0 */30 1 * * *",
+			from: time.Date(2024, 11, 3, 0, 0, 0, 0, ny),
+			expected: []time.Time{
+				time.Date(2024, 11, 3, 5, 0, 0, 0, time.UTC),  // 1:00 EDT
+				time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), // 1:30 EDT
+				time.Date(2024, 11, 4, 6, 0, 0, 0, time.UTC),  // 1:00 EST
+			},
+		},
+		{
+			name: "hourly runs fire in both occurrences of the repeated hour",
+			cron: "0 0 * * * *",
+			from: time.Date(2024, 11, 3, 0, 30, 0, 0, ny),
+			expected: []time.Time{
+				time.Date(2024, 11, 3, 5, 0, 0, 0, time.UTC), // 1:00 EDT
+				time.Date(2024, 11, 3, 6, 0, 0, 0, time.UTC), // 1:00 EST
+				time.Date(2024, 11, 3, 7, 0, 0, 0, time.UTC), // 2:00 EST
+			},
+		},
+		{
+			name: "hourly runs skip the missing hour",
+			cron: "0 0 * * * *",
+			from: time.Date(2024, 3, 10, 0, 30, 0, 0, ny),
+			expected: []time.Time{
+				time.Date(2024, 3, 10, 6, 0, 0, 0, time.UTC), // 1:00 EST
+				time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC), // 3:00 EDT
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cronSchedule, err := service.parser.Parse(tt.cron)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %v", tt.cron, err)
+			}
+
+			current := tt.from
+			for i, expected := range tt.expected {
+				current = nextRun(cronSchedule, current, ny)
+				if !current.Equal(expected) {
+					t.Fatalf("Run %d: expected %s, got %s", i, expected.In(ny), current)
+				}
+				if current.Location() != ny {
+					t.Errorf("Run %d: expected a time in %s, got %s", i, ny, current.Location())
+				}
+			}
+		})
+	}
+
+	t.Run("due runs across the repeated hour", func(t *testing.T) {
+		next := time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC) // 1:30 EDT, as read back from storage
+		schedule := &models.WorkflowSchedule{CronExpression: "0 30 1 * * *", Timezone: "America/New_York", NextTriggerAt: &next}
+
+		runs, missed, err := service.DueRuns(schedule, time.Date(2024, 11, 4, 12, 0, 0, 0, time.UTC), 10)
+
+		assert.NoError(t, err)
+		assert.Zero(t, missed)
+		assert.Len(t, runs, 2)
+		assert.True(t, runs[1].Equal(time.Date(2024, 11, 4, 6, 30, 0, 0, time.UTC)))
+	})
+}
+
 // TestGetNextRuns tests calculating next run times
 func TestGetNextRuns(t *testing.T) {
 	log := logger.NewForTesting()
@@ -353,14 +653,14 @@ func TestGetNextRuns(t *testing.T) {
 		}
 
 		repo := &mockScheduleRepo{
-			getByIDFunc: func(ctx context.Context, id uuid.UUID) (*models.WorkflowSchedule, error) {
+			getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowSchedule, error) {
 				return schedule, nil
 			},
 		}
 
 		service := NewScheduleService(repo, log)
 
-		runs, err := service.GetNextRuns(context.Background(), scheduleID, 5)
+		runs, err := service.GetNextRuns(context.Background(), uuid.New(), scheduleID, 5)
 
 		assert.NoError(t, err)
 		assert.Len(t, runs, 5)
@@ -382,14 +682,14 @@ func TestGetNextRuns(t *testing.T) {
 		}
 
 		repo := &mockScheduleRepo{
-			getByIDFunc: func(ctx context.Context, id uuid.UUID) (*models.WorkflowSchedule, error) {
+			getByIDFunc: func(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowSchedule, error) {
 				return schedule, nil
 			},
 		}
 
 		service := NewScheduleService(repo, log)
 
-		runs, err := service.GetNextRuns(context.Background(), scheduleID, 150) // Over max
+		runs, err := service.GetNextRuns(context.Background(), uuid.New(), scheduleID, 150) // Over max
 
 		assert.NoError(t, err)
 		assert.Len(t, runs, 10) // Should default to 10
@@ -403,12 +703,12 @@ func TestValidateCronExpression(t *testing.T) {
 
 	t.Run("validates correct cron expressions", func(t *testing.T) {
 		validExpressions := []string{
-			"0 0 9 * * *",         // Daily at 9 AM (with seconds)
-			"0 */15 * * * *",      // Every 15 minutes (with seconds)
-			"0 0 0 * * MON",       // Every Monday at midnight
-			"0 0 0,12 * * *",      // Twice daily
-			"@hourly",             // Descriptor
-			"0 0 0 * * *",         // With seconds
+			"0 0 9 * * *",    // Daily at 9 AM (with seconds)
+			"0 */15 * * * *", // Every 15 minutes (with seconds)
+			"0 0 0 * * MON",  // Every Monday at midnight
+			"0 0 0,12 * * *", // Twice daily
+			"@hourly",        // Descriptor
+			"0 0 0 * * *",    // With seconds
 		}
 
 		for _, expr := range validExpressions {
@@ -420,9 +720,9 @@ func TestValidateCronExpression(t *testing.T) {
 	t.Run("rejects invalid cron expressions", func(t *testing.T) {
 		invalidExpressions := []string{
 			"invalid",
-			"0 60 * * * *",       // Invalid minute
-			"* * * * * * *",      // Too many fields
-			"",                   // Empty
+			"0 60 * * * *",  // Invalid minute
+			"* * * * * * *", // Too many fields
+			"",              // Empty
 		}
 
 		for _, expr := range invalidExpressions {
@@ -441,7 +741,7 @@ func TestDeleteSchedule(t *testing.T) {
 		deleted := false
 
 		repo := &mockScheduleRepo{
-			deleteFunc: func(ctx context.Context, id uuid.UUID) error {
+			deleteFunc: func(ctx context.Context, organizationID, id uuid.UUID) error {
 				if id == scheduleID {
 					deleted = true
 					return nil
@@ -452,7 +752,7 @@ func TestDeleteSchedule(t *testing.T) {
 
 		service := NewScheduleService(repo, log)
 
-		err := service.DeleteSchedule(context.Background(), scheduleID)
+		err := service.DeleteSchedule(context.Background(), uuid.New(), scheduleID)
 
 		assert.NoError(t, err)
 		assert.True(t, deleted)
@@ -462,14 +762,14 @@ func TestDeleteSchedule(t *testing.T) {
 		scheduleID := uuid.New()
 
 		repo := &mockScheduleRepo{
-			deleteFunc: func(ctx context.Context, id uuid.UUID) error {
+			deleteFunc: func(ctx context.Context, organizationID, id uuid.UUID) error {
 				return errors.New("not found")
 			},
 		}
 
 		service := NewScheduleService(repo, log)
 
-		err := service.DeleteSchedule(context.Background(), scheduleID)
+		err := service.DeleteSchedule(context.Background(), uuid.New(), scheduleID)
 
 		assert.Error(t, err)
 	})
@@ -481,7 +781,7 @@ func TestListSchedules(t *testing.T) {
 
 	t.Run("lists schedules with pagination", func(t *testing.T) {
 		repo := &mockScheduleRepo{
-			listFunc: func(ctx context.Context, limit, offset int) ([]*models.WorkflowSchedule, int64, error) {
+			listFunc: func(ctx context.Context, organizationID uuid.UUID, limit, offset int) ([]*models.WorkflowSchedule, int64, error) {
 				return []*models.WorkflowSchedule{
 					{ID: uuid.New()},
 					{ID: uuid.New()},
@@ -491,7 +791,7 @@ func TestListSchedules(t *testing.T) {
 
 		service := NewScheduleService(repo, log)
 
-		schedules, total, err := service.ListSchedules(context.Background(), 10, 0)
+		schedules, total, err := service.ListSchedules(context.Background(), uuid.New(), 10, 0)
 
 		assert.NoError(t, err)
 		assert.Len(t, schedules, 2)
@@ -502,7 +802,7 @@ func TestListSchedules(t *testing.T) {
 		var capturedLimit int
 
 		repo := &mockScheduleRepo{
-			listFunc: func(ctx context.Context, limit, offset int) ([]*models.WorkflowSchedule, int64, error) {
+			listFunc: func(ctx context.Context, organizationID uuid.UUID, limit, offset int) ([]*models.WorkflowSchedule, int64, error) {
 				capturedLimit = limit
 				return []*models.WorkflowSchedule{}, 0, nil
 			},
@@ -510,7 +810,7 @@ func TestListSchedules(t *testing.T) {
 
 		service := NewScheduleService(repo, log)
 
-		_, _, err := service.ListSchedules(context.Background(), 150, 0) // Over max
+		_, _, err := service.ListSchedules(context.Background(), uuid.New(), 150, 0) // Over max
 
 		assert.NoError(t, err)
 		assert.Equal(t, 50, capturedLimit) // Should default to 50
@@ -2,9 +2,12 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/davidmoltin/intelligent-workflows/internal/engine"
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
 	"github.com/google/uuid"
@@ -23,12 +26,61 @@ type WorkflowEngine interface {
 	ResumePausedExecution(ctx context.Context, execution *models.WorkflowExecution) error
 }
 
+// DefaultMaxPausedDuration is how long a paused execution can still be resumed unless configured
+const DefaultMaxPausedDuration = 7 * 24 * time.Hour
+
+// WorkflowLookup looks up the workflow of a paused execution
+type WorkflowLookup interface {
+	GetWorkflowByID(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error)
+}
+
+// ExecutionBroadcaster broadcasts execution state changes to WebSocket clients
+type ExecutionBroadcaster interface {
+	BroadcastExecution(execution *models.WorkflowExecution)
+}
+
+// ApprovalLookup looks up the approval a paused execution is waiting for
+type ApprovalLookup interface {
+	GetApprovalByID(ctx context.Context, organizationID, id uuid.UUID) (*models.ApprovalRequest, error)
+}
+
+var (
+	// ErrNoLinkedApproval is returned when an execution is resumed from an approval it is not paused for
+	ErrNoLinkedApproval = errors.New("execution is not paused for an approval")
+
+	// ErrApprovalNotDecided is returned when an execution is resumed before its approval is approved or rejected
+	ErrApprovalNotDecided = errors.New("approval has not been decided")
+
+	// ErrApprovalMismatch is returned when an approval's decision or execution does not match the resume
+	ErrApprovalMismatch = errors.New("approval does not match execution")
+)
+
+// ResumeDataError is returned when resume data does not match the resume schema of the wait step
+// an execution is paused at
+type ResumeDataError struct {
+	StepID string
+	Fields []models.ResumeFieldError
+}
+
+func (e *ResumeDataError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+	return fmt.Sprintf("resume data does not match the schema of step %s: %s", e.StepID, strings.Join(messages, "; "))
+}
+
 // WorkflowResumerImpl implements WorkflowResumer interface
 type WorkflowResumerImpl struct {
 	logger        *logger.Logger
 	executionRepo ExecutionRepository
 	engine        WorkflowEngine
 	auditService  *AuditService
+	approvals     ApprovalLookup
+	workflows     WorkflowLookup
+	broadcaster   ExecutionBroadcaster
+
+	maxPausedDuration time.Duration
 }
 
 // NewWorkflowResumer creates a new workflow resumer
@@ -38,11 +90,48 @@ func NewWorkflowResumer(log *logger.Logger, executionRepo ExecutionRepository, e
 		executionRepo: executionRepo,
 		engine:        engine,
 		auditService:  auditService,
+
+		maxPausedDuration: DefaultMaxPausedDuration,
+	}
+}
+
+// SetMaxPausedDuration sets how long a paused execution can still be resumed when its workflow
+// does not set its own limit
+func (w *WorkflowResumerImpl) SetMaxPausedDuration(maxPausedDuration time.Duration) {
+	if maxPausedDuration > 0 {
+		w.maxPausedDuration = maxPausedDuration
 	}
 }
 
+// SetWorkflowLookup sets where executions' workflows are looked up for their own max paused
+// duration (optional dependency)
+func (w *WorkflowResumerImpl) SetWorkflowLookup(workflows WorkflowLookup) {
+	w.workflows = workflows
+}
+
+// SetApprovalLookup sets where the approvals executions are paused for are looked up. Without
+// it, executions paused for an approval are not resumed (optional dependency)
+func (w *WorkflowResumerImpl) SetApprovalLookup(approvals ApprovalLookup) {
+	w.approvals = approvals
+}
+
+// SetExecutionBroadcaster sets where expired executions are broadcast (optional dependency)
+func (w *WorkflowResumerImpl) SetExecutionBroadcaster(broadcaster ExecutionBroadcaster) {
+	w.broadcaster = broadcaster
+}
+
 // PauseExecution pauses a running workflow execution
 func (w *WorkflowResumerImpl) PauseExecution(ctx context.Context, executionID uuid.UUID, reason string, stepID *uuid.UUID) error {
+	return w.pauseExecution(ctx, executionID, reason, stepID, nil)
+}
+
+// PauseForApproval pauses a running workflow execution until the given approval is approved or
+// rejected
+func (w *WorkflowResumerImpl) PauseForApproval(ctx context.Context, executionID, approvalID uuid.UUID, reason string, stepID *uuid.UUID) error {
+	return w.pauseExecution(ctx, executionID, reason, stepID, &approvalID)
+}
+
+func (w *WorkflowResumerImpl) pauseExecution(ctx context.Context, executionID uuid.UUID, reason string, stepID *uuid.UUID, approvalID *uuid.UUID) error {
 	w.logger.Infof("Pausing workflow execution %s: %s", executionID, reason)
 
 	if w.executionRepo == nil {
@@ -61,6 +150,17 @@ func (w *WorkflowResumerImpl) PauseExecution(ctx context.Context, executionID uu
 		return fmt.Errorf("execution %s is not running (status: %s)", executionID, execution.Status)
 	}
 
+	// Validate the approval belongs to the execution
+	if approvalID != nil && w.approvals != nil {
+		approval, err := w.approvals.GetApprovalByID(ctx, execution.OrganizationID, *approvalID)
+		if err != nil {
+			return fmt.Errorf("failed to get approval %s: %w", *approvalID, err)
+		}
+		if approval.ExecutionID != executionID {
+			return fmt.Errorf("%w: approval %s belongs to execution %s", ErrApprovalMismatch, approval.ID, approval.ExecutionID)
+		}
+	}
+
 	// Update execution to paused state
 	now := time.Now()
 	execution.Status = models.ExecutionStatusPaused
@@ -69,6 +169,7 @@ func (w *WorkflowResumerImpl) PauseExecution(ctx context.Context, executionID uu
 	if stepID != nil {
 		execution.PausedStepID = stepID
 	}
+	execution.ApprovalID = approvalID
 
 	// Save updated execution
 	if err := w.executionRepo.UpdateExecution(ctx, execution.OrganizationID, execution); err != nil {
@@ -90,7 +191,8 @@ func (w *WorkflowResumerImpl) PauseExecution(ctx context.Context, executionID uu
 }
 
 // ResumeWorkflow resumes a paused workflow execution
-// This method maintains backward compatibility with the WorkflowResumer interface
+// This method maintains backward compatibility with the WorkflowResumer interface. An execution
+// paused for an approval is only resumed once that approval is decided the same way
 func (w *WorkflowResumerImpl) ResumeWorkflow(ctx context.Context, executionID uuid.UUID, approved bool) error {
 	w.logger.Infof("Resuming workflow execution %s with approval status: %v", executionID, approved)
 
@@ -107,21 +209,93 @@ func (w *WorkflowResumerImpl) ResumeWorkflow(ctx context.Context, executionID uu
 	}
 
 	// Validate execution can be resumed
-	if err := w.CanResume(execution); err != nil {
+	if err := w.CanResume(ctx, execution); err != nil {
 		return err
 	}
 
 	// Add approval decision to resume data
-	if execution.ResumeData == nil {
-		execution.ResumeData = make(models.JSONB)
+	if execution.ApprovalID != nil {
+		approval, err := w.decidedApproval(ctx, execution)
+		if err != nil {
+			return err
+		}
+		if (approval.Status == models.ApprovalStatusApproved) != approved {
+			return fmt.Errorf("%w: approval %s is %s", ErrApprovalMismatch, approval.ID, approval.Status)
+		}
+		setApprovalResumeData(execution, approval)
+	} else {
+		if execution.ResumeData == nil {
+			execution.ResumeData = make(models.JSONB)
+		}
+		execution.ResumeData["approved"] = approved
+		execution.ResumeData["resumed_at"] = time.Now()
 	}
-	execution.ResumeData["approved"] = approved
-	execution.ResumeData["resumed_at"] = time.Now()
 
 	// Resume the execution
 	return w.resumeExecution(ctx, execution)
 }
 
+// ResumeFromApproval resumes an execution paused for an approval with the approval's decision,
+// returning ErrApprovalNotDecided while the approval is not yet approved or rejected
+func (w *WorkflowResumerImpl) ResumeFromApproval(ctx context.Context, execution *models.WorkflowExecution) error {
+	if err := w.CanResume(ctx, execution); err != nil {
+		return err
+	}
+	if execution.ApprovalID == nil {
+		return ErrNoLinkedApproval
+	}
+
+	approval, err := w.decidedApproval(ctx, execution)
+	if err != nil {
+		return err
+	}
+	setApprovalResumeData(execution, approval)
+
+	w.logger.Infof("Resuming execution %s after approval %s was %s", execution.ID, approval.ID, approval.Status)
+	return w.resumeExecution(ctx, execution)
+}
+
+// decidedApproval returns the approval an execution is paused for, verifying it belongs to the
+// execution and has been approved or rejected
+func (w *WorkflowResumerImpl) decidedApproval(ctx context.Context, execution *models.WorkflowExecution) (*models.ApprovalRequest, error) {
+	if w.approvals == nil {
+		return nil, fmt.Errorf("approval lookup not configured")
+	}
+
+	approval, err := w.approvals.GetApprovalByID(ctx, execution.OrganizationID, *execution.ApprovalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get approval %s: %w", *execution.ApprovalID, err)
+	}
+	if approval.ExecutionID != execution.ID {
+		return nil, fmt.Errorf("%w: approval %s belongs to execution %s", ErrApprovalMismatch, approval.ID, approval.ExecutionID)
+	}
+	if approval.Status != models.ApprovalStatusApproved && approval.Status != models.ApprovalStatusRejected {
+		return nil, fmt.Errorf("%w: approval %s is %s", ErrApprovalNotDecided, approval.ID, approval.Status)
+	}
+
+	return approval, nil
+}
+
+// setApprovalResumeData copies an approval's decision and who made it into an execution's resume data
+func setApprovalResumeData(execution *models.WorkflowExecution, approval *models.ApprovalRequest) {
+	if execution.ResumeData == nil {
+		execution.ResumeData = make(models.JSONB)
+	}
+	execution.ResumeData["approved"] = approval.Status == models.ApprovalStatusApproved
+	execution.ResumeData["approval_id"] = approval.ID.String()
+	execution.ResumeData["approval_status"] = string(approval.Status)
+	if approval.ApproverID != nil {
+		execution.ResumeData["approver_id"] = approval.ApproverID.String()
+	}
+	if approval.DecisionReason != nil {
+		execution.ResumeData["decision_reason"] = *approval.DecisionReason
+	}
+	if approval.DecidedAt != nil {
+		execution.ResumeData["decided_at"] = *approval.DecidedAt
+	}
+	execution.ResumeData["resumed_at"] = time.Now()
+}
+
 // ResumeExecution resumes a paused workflow execution with custom resume data
 func (w *WorkflowResumerImpl) ResumeExecution(ctx context.Context, executionID uuid.UUID, resumeData models.JSONB) error {
 	w.logger.Infof("Resuming workflow execution %s", executionID)
@@ -138,7 +312,12 @@ func (w *WorkflowResumerImpl) ResumeExecution(ctx context.Context, executionID u
 	}
 
 	// Validate execution can be resumed
-	if err := w.CanResume(execution); err != nil {
+	if err := w.CanResume(ctx, execution); err != nil {
+		return err
+	}
+
+	// Validate resume data against the paused step's resume schema
+	if err := w.validateResumeData(ctx, execution, resumeData); err != nil {
 		return err
 	}
 
@@ -154,8 +333,44 @@ func (w *WorkflowResumerImpl) ResumeExecution(ctx context.Context, executionID u
 	return w.resumeExecution(ctx, execution)
 }
 
+// validateResumeData checks resume data against the resume schema of the wait step an execution
+// is paused at, returning a ResumeDataError listing the mismatched fields. Executions whose step
+// declares no schema accept any data
+func (w *WorkflowResumerImpl) validateResumeData(ctx context.Context, execution *models.WorkflowExecution, resumeData models.JSONB) error {
+	if w.workflows == nil {
+		return nil
+	}
+
+	var stepID string
+	switch {
+	case execution.PausedStepID != nil:
+		stepID = execution.PausedStepID.String()
+	case execution.CurrentStepID != nil:
+		stepID = *execution.CurrentStepID
+	default:
+		return nil
+	}
+
+	workflow, err := w.workflows.GetWorkflowByID(ctx, execution.OrganizationID, execution.WorkflowID)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow %s: %w", execution.WorkflowID, err)
+	}
+	for _, step := range workflow.Definition.Steps {
+		if step.ID != stepID || step.Wait == nil {
+			continue
+		}
+		if fieldErrors := step.Wait.ValidateResumeData(resumeData); len(fieldErrors) > 0 {
+			return &ResumeDataError{StepID: step.ID, Fields: fieldErrors}
+		}
+	}
+	return nil
+}
+
 // resumeExecution is the internal method that performs the actual resumption
 func (w *WorkflowResumerImpl) resumeExecution(ctx context.Context, execution *models.WorkflowExecution) error {
+	// Keep the pause, so it can be restored if the engine cannot run the execution yet
+	paused := *execution
+
 	// Update execution state
 	now := time.Now()
 	execution.Status = models.ExecutionStatusRunning
@@ -165,6 +380,7 @@ func (w *WorkflowResumerImpl) resumeExecution(ctx context.Context, execution *mo
 	// Clear pause information
 	execution.PausedAt = nil
 	execution.PausedReason = nil
+	execution.ApprovalID = nil
 
 	// Save updated execution
 	if err := w.executionRepo.UpdateExecution(ctx, execution.OrganizationID, execution); err != nil {
@@ -175,6 +391,10 @@ func (w *WorkflowResumerImpl) resumeExecution(ctx context.Context, execution *mo
 	// Trigger workflow engine to continue execution
 	if w.engine != nil {
 		if err := w.engine.ResumePausedExecution(ctx, execution); err != nil {
+			if errors.Is(err, engine.ErrConcurrencyLimitReached) || errors.Is(err, engine.ErrRateLimited) {
+				w.pauseAgain(ctx, execution, &paused)
+				return fmt.Errorf("execution %s not resumed: %w", execution.ID, err)
+			}
 			w.logger.Errorf("Failed to resume execution in engine %s: %v", execution.ID, err)
 			return fmt.Errorf("failed to resume execution in engine: %w", err)
 		}
@@ -195,6 +415,23 @@ func (w *WorkflowResumerImpl) resumeExecution(ctx context.Context, execution *mo
 	return nil
 }
 
+// pauseAgain restores the pause of an execution the engine did not run, so it is resumed on a
+// later attempt
+func (w *WorkflowResumerImpl) pauseAgain(ctx context.Context, execution, paused *models.WorkflowExecution) {
+	execution.Status = paused.Status
+	execution.LastResumedAt = paused.LastResumedAt
+	execution.ResumeCount = paused.ResumeCount
+	execution.PausedAt = paused.PausedAt
+	execution.PausedReason = paused.PausedReason
+	execution.ApprovalID = paused.ApprovalID
+
+	if err := w.executionRepo.UpdateExecution(ctx, execution.OrganizationID, execution); err != nil {
+		w.logger.Errorf("Failed to pause execution %s again: %v", execution.ID, err)
+		return
+	}
+	w.logger.Infof("Execution %s left paused until its workflow's limits allow it to run", execution.ID)
+}
+
 // GetPausedExecutions retrieves paused executions pending resume
 func (w *WorkflowResumerImpl) GetPausedExecutions(ctx context.Context, limit int) ([]*models.WorkflowExecution, error) {
 	if w.executionRepo == nil {
@@ -213,7 +450,7 @@ func (w *WorkflowResumerImpl) GetPausedExecutions(ctx context.Context, limit int
 }
 
 // CanResume checks if an execution can be resumed
-func (w *WorkflowResumerImpl) CanResume(execution *models.WorkflowExecution) error {
+func (w *WorkflowResumerImpl) CanResume(ctx context.Context, execution *models.WorkflowExecution) error {
 	if execution == nil {
 		return fmt.Errorf("execution is nil")
 	}
@@ -226,11 +463,87 @@ func (w *WorkflowResumerImpl) CanResume(execution *models.WorkflowExecution) err
 		return fmt.Errorf("execution %s has no pause timestamp", execution.ID)
 	}
 
-	// Check if execution has been paused for too long (e.g., 7 days)
-	maxPauseDuration := 7 * 24 * time.Hour
-	if time.Since(*execution.PausedAt) > maxPauseDuration {
-		return fmt.Errorf("execution %s has been paused for too long (paused at: %v)", execution.ID, execution.PausedAt)
+	// Check if execution has been paused for too long
+	maxPausedDuration, err := w.getMaxPausedDuration(ctx, execution)
+	if err != nil {
+		return err
+	}
+	if time.Since(*execution.PausedAt) > maxPausedDuration {
+		return fmt.Errorf("execution %s has been paused for too long (paused at: %v, max: %s)", execution.ID, execution.PausedAt, maxPausedDuration)
 	}
 
 	return nil
 }
+
+// ExpireExecution fails a paused execution that has been paused longer than its max paused
+// duration, so it is not left paused forever. It reports whether the execution was expired
+func (w *WorkflowResumerImpl) ExpireExecution(ctx context.Context, execution *models.WorkflowExecution) (bool, error) {
+	if w.executionRepo == nil {
+		return false, fmt.Errorf("execution repository not configured")
+	}
+	if execution.Status != models.ExecutionStatusPaused || execution.PausedAt == nil {
+		return false, nil
+	}
+
+	maxPausedDuration, err := w.getMaxPausedDuration(ctx, execution)
+	if err != nil {
+		return false, err
+	}
+	if time.Since(*execution.PausedAt) <= maxPausedDuration {
+		return false, nil
+	}
+
+	// Update execution to failed status, keeping why it was paused
+	now := time.Now()
+	execution.Status = models.ExecutionStatusFailed
+	failedResult := models.ExecutionResultFailed
+	execution.Result = &failedResult
+	execution.CompletedAt = &now
+	duration := int(now.Sub(execution.StartedAt).Milliseconds())
+	execution.DurationMs = &duration
+	errorMsg := fmt.Sprintf("Execution expired after being paused for more than %s", maxPausedDuration)
+	if execution.PausedReason != nil && *execution.PausedReason != "" {
+		errorMsg += fmt.Sprintf(" (paused: %s)", *execution.PausedReason)
+	}
+	execution.ErrorMessage = &errorMsg
+
+	if err := w.executionRepo.UpdateExecution(ctx, execution.OrganizationID, execution); err != nil {
+		w.logger.Errorf("Failed to update execution %s: %v", execution.ID, err)
+		return false, fmt.Errorf("failed to update execution: %w", err)
+	}
+
+	if w.broadcaster != nil {
+		w.broadcaster.BroadcastExecution(execution)
+	}
+
+	if w.auditService != nil {
+		systemActorID := uuid.MustParse("00000000-0000-0000-0000-000000000000")
+		if err := w.auditService.LogExecutionExpired(ctx, execution.ID, systemActorID, "system", errorMsg); err != nil {
+			w.logger.Errorf("Failed to log audit event for execution expiry: %v", err)
+		}
+	}
+
+	w.logger.Infof("Expired execution %s paused since %s (max: %s)", execution.ID, execution.PausedAt.Format(time.RFC3339), maxPausedDuration)
+	return true, nil
+}
+
+// getMaxPausedDuration returns how long an execution can stay paused, preferring its workflow's
+// own setting
+func (w *WorkflowResumerImpl) getMaxPausedDuration(ctx context.Context, execution *models.WorkflowExecution) (time.Duration, error) {
+	if w.workflows == nil {
+		return w.maxPausedDuration, nil
+	}
+
+	workflow, err := w.workflows.GetWorkflowByID(ctx, execution.OrganizationID, execution.WorkflowID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get workflow %s: %w", execution.WorkflowID, err)
+	}
+	if workflow.Definition.MaxPausedDuration != "" {
+		maxPausedDuration, err := time.ParseDuration(workflow.Definition.MaxPausedDuration)
+		if err == nil && maxPausedDuration > 0 {
+			return maxPausedDuration, nil
+		}
+		w.logger.Warnf("Invalid max_paused_duration %q on workflow %s, using %s", workflow.Definition.MaxPausedDuration, workflow.ID, w.maxPausedDuration)
+	}
+	return w.maxPausedDuration, nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/davidmoltin/intelligent-workflows/internal/engine"
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
 )
@@ -25,12 +26,12 @@ func (m *MockExecutionRepository) CreateExecution(ctx context.Context, execution
 	return args.Error(0)
 }
 
-func (m *MockExecutionRepository) UpdateExecution(ctx context.Context, execution *models.WorkflowExecution) error {
+func (m *MockExecutionRepository) UpdateExecution(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
 	args := m.Called(ctx, execution)
 	return args.Error(0)
 }
 
-func (m *MockExecutionRepository) GetExecutionByID(ctx context.Context, id uuid.UUID) (*models.WorkflowExecution, error) {
+func (m *MockExecutionRepository) GetExecutionByID(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowExecution, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -38,7 +39,7 @@ func (m *MockExecutionRepository) GetExecutionByID(ctx context.Context, id uuid.
 	return args.Get(0).(*models.WorkflowExecution), args.Error(1)
 }
 
-func (m *MockExecutionRepository) GetPausedExecutions(ctx context.Context, limit int) ([]*models.WorkflowExecution, error) {
+func (m *MockExecutionRepository) GetPausedExecutions(ctx context.Context, organizationID uuid.UUID, limit int) ([]*models.WorkflowExecution, error) {
 	args := m.Called(ctx, limit)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -63,7 +64,7 @@ func TestNewWorkflowResumer(t *testing.T) {
 	mockRepo := new(MockExecutionRepository)
 	mockEngine := new(MockWorkflowEngine)
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	assert.NotNil(t, resumer)
 	assert.Equal(t, log, resumer.logger)
@@ -103,7 +104,7 @@ func TestPauseExecution_Success(t *testing.T) {
 			*exec.PausedStepID == stepID
 	})).Return(nil)
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test pause execution
 	err = resumer.PauseExecution(ctx, executionID, reason, &stepID)
@@ -126,7 +127,7 @@ func TestPauseExecution_ExecutionNotFound(t *testing.T) {
 	// Setup expectations
 	mockRepo.On("GetExecutionByID", ctx, executionID).Return(nil, fmt.Errorf("execution not found"))
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test pause execution
 	err = resumer.PauseExecution(ctx, executionID, reason, nil)
@@ -158,7 +159,7 @@ func TestPauseExecution_NotRunning(t *testing.T) {
 	// Setup expectations
 	mockRepo.On("GetExecutionByID", ctx, executionID).Return(execution, nil)
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test pause execution
 	err = resumer.PauseExecution(ctx, executionID, reason, nil)
@@ -176,7 +177,7 @@ func TestPauseExecution_NilRepository(t *testing.T) {
 	executionID := uuid.New()
 	reason := "waiting for approval"
 
-	resumer := NewWorkflowResumer(log, nil, nil)
+	resumer := NewWorkflowResumer(log, nil, nil, nil)
 
 	// Test pause execution with nil repository
 	err = resumer.PauseExecution(ctx, executionID, reason, nil)
@@ -223,7 +224,7 @@ func TestResumeWorkflow_Success(t *testing.T) {
 	})).Return(nil)
 	mockEngine.On("ResumePausedExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test resume workflow
 	err = resumer.ResumeWorkflow(ctx, executionID, approved)
@@ -233,6 +234,60 @@ func TestResumeWorkflow_Success(t *testing.T) {
 	mockEngine.AssertExpectations(t)
 }
 
+func TestResumeWorkflow_ConcurrencyLimitReached(t *testing.T) {
+	log, err := logger.New("info", "json")
+	require.NoError(t, err)
+
+	mockRepo := new(MockExecutionRepository)
+	mockEngine := new(MockWorkflowEngine)
+
+	ctx := context.Background()
+	executionID := uuid.New()
+	approvalID := uuid.New()
+
+	// Create a paused execution
+	pausedAt := time.Now().Add(-1 * time.Hour)
+	pausedReason := "waiting for approval"
+	execution := &models.WorkflowExecution{
+		ID:           executionID,
+		WorkflowID:   uuid.New(),
+		Status:       models.ExecutionStatusPaused,
+		StartedAt:    time.Now().Add(-2 * time.Hour),
+		PausedAt:     &pausedAt,
+		PausedReason: &pausedReason,
+		ApprovalID:   &approvalID,
+		ResumeData:   make(models.JSONB),
+		ResumeCount:  0,
+	}
+
+	// Setup expectations - the engine has no free slot for the workflow
+	mockRepo.On("GetExecutionByID", ctx, executionID).Return(execution, nil)
+	mockRepo.On("UpdateExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil).Twice()
+	mockEngine.On("ResumePausedExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(engine.ErrConcurrencyLimitReached)
+
+	approvals := newMockApprovalRepo()
+	approvals.approvals[approvalID] = models.ApprovalRequest{ID: approvalID, ExecutionID: executionID, Status: models.ApprovalStatusApproved}
+
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
+	resumer.SetApprovalLookup(approvals)
+
+	// Test resume workflow
+	err = resumer.ResumeWorkflow(ctx, executionID, true)
+
+	assert.ErrorIs(t, err, engine.ErrConcurrencyLimitReached)
+	mockRepo.AssertExpectations(t)
+	mockEngine.AssertExpectations(t)
+
+	// The execution is saved paused again, so it is resumed on a later attempt
+	assert.Equal(t, models.ExecutionStatusPaused, execution.Status)
+	assert.Equal(t, &pausedAt, execution.PausedAt)
+	assert.Equal(t, &pausedReason, execution.PausedReason)
+	assert.Equal(t, &approvalID, execution.ApprovalID)
+	assert.Equal(t, 0, execution.ResumeCount)
+	assert.Nil(t, execution.LastResumedAt)
+	assert.NoError(t, resumer.CanResume(ctx, execution))
+}
+
 func TestResumeWorkflow_NotPaused(t *testing.T) {
 	log, err := logger.New("info", "json")
 	require.NoError(t, err)
@@ -255,7 +310,7 @@ func TestResumeWorkflow_NotPaused(t *testing.T) {
 	// Setup expectations
 	mockRepo.On("GetExecutionByID", ctx, executionID).Return(execution, nil)
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test resume workflow
 	err = resumer.ResumeWorkflow(ctx, executionID, approved)
@@ -291,7 +346,7 @@ func TestResumeWorkflow_PausedTooLong(t *testing.T) {
 	// Setup expectations
 	mockRepo.On("GetExecutionByID", ctx, executionID).Return(execution, nil)
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test resume workflow
 	err = resumer.ResumeWorkflow(ctx, executionID, approved)
@@ -345,7 +400,7 @@ func TestResumeExecution_WithResumeData(t *testing.T) {
 	})).Return(nil)
 	mockEngine.On("ResumePausedExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test resume execution with custom data
 	err = resumer.ResumeExecution(ctx, executionID, resumeData)
@@ -355,6 +410,82 @@ func TestResumeExecution_WithResumeData(t *testing.T) {
 	mockEngine.AssertExpectations(t)
 }
 
+func TestResumeExecution_ResumeSchema(t *testing.T) {
+	log, err := logger.New("info", "json")
+	require.NoError(t, err)
+
+	stepID := uuid.New()
+	workflow := &models.Workflow{
+		ID: uuid.New(),
+		Definition: models.WorkflowDefinition{Steps: []models.Step{{
+			ID:   stepID.String(),
+			Type: "wait",
+			Wait: &models.WaitConfig{Event: "review.completed", ResumeSchema: []models.ResumeField{
+				{Name: "approved", Type: "boolean", Required: true},
+				{Name: "score", Type: "number"},
+			}},
+		}}},
+	}
+
+	tests := []struct {
+		name       string
+		resumeData models.JSONB
+		fields     []string // Fields expected to be rejected, if any
+	}{
+		{"matching data resumes", models.JSONB{"approved": true, "score": 0.9}, nil},
+		{"missing required field", models.JSONB{"score": 0.9}, []string{"approved"}},
+		{"wrong types", models.JSONB{"approved": "yes", "score": "high"}, []string{"approved", "score"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			pausedAt := time.Now().Add(-time.Hour)
+			execution := &models.WorkflowExecution{
+				ID:           uuid.New(),
+				WorkflowID:   workflow.ID,
+				Status:       models.ExecutionStatusPaused,
+				StartedAt:    pausedAt.Add(-time.Minute),
+				PausedAt:     &pausedAt,
+				PausedStepID: &stepID,
+			}
+
+			mockRepo := new(MockExecutionRepository)
+			mockEngine := new(MockWorkflowEngine)
+			workflows := new(MockWorkflowLookup)
+			mockRepo.On("GetExecutionByID", ctx, execution.ID).Return(execution, nil)
+			workflows.On("GetWorkflowByID", ctx, execution.OrganizationID, workflow.ID).Return(workflow, nil)
+			if tt.fields == nil {
+				mockRepo.On("UpdateExecution", ctx, execution).Return(nil)
+				mockEngine.On("ResumePausedExecution", ctx, execution).Return(nil)
+			}
+
+			resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
+			resumer.SetWorkflowLookup(workflows)
+
+			err := resumer.ResumeExecution(ctx, execution.ID, tt.resumeData)
+
+			if tt.fields == nil {
+				require.NoError(t, err)
+				assert.Equal(t, true, execution.ResumeData["approved"])
+			} else {
+				var dataErr *ResumeDataError
+				require.ErrorAs(t, err, &dataErr)
+				assert.Equal(t, stepID.String(), dataErr.StepID)
+				var fields []string
+				for _, field := range dataErr.Fields {
+					fields = append(fields, field.Field)
+				}
+				assert.Equal(t, tt.fields, fields)
+				assert.Equal(t, models.ExecutionStatusPaused, execution.Status)
+				assert.Nil(t, execution.ResumeData)
+			}
+			mockRepo.AssertExpectations(t)
+			mockEngine.AssertExpectations(t)
+		})
+	}
+}
+
 func TestResumeExecution_WithoutEngine(t *testing.T) {
 	log, err := logger.New("info", "json")
 	require.NoError(t, err)
@@ -384,7 +515,7 @@ func TestResumeExecution_WithoutEngine(t *testing.T) {
 	mockRepo.On("UpdateExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
 
 	// Create resumer without engine
-	resumer := NewWorkflowResumer(log, mockRepo, nil)
+	resumer := NewWorkflowResumer(log, mockRepo, nil, nil)
 
 	// Test resume execution without engine
 	err = resumer.ResumeExecution(ctx, executionID, resumeData)
@@ -430,7 +561,7 @@ func TestGetPausedExecutions_Success(t *testing.T) {
 	// Setup expectations
 	mockRepo.On("GetPausedExecutions", ctx, limit).Return(executions, nil)
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test get paused executions
 	result, err := resumer.GetPausedExecutions(ctx, limit)
@@ -449,7 +580,7 @@ func TestGetPausedExecutions_NilRepository(t *testing.T) {
 	ctx := context.Background()
 	limit := 50
 
-	resumer := NewWorkflowResumer(log, nil, nil)
+	resumer := NewWorkflowResumer(log, nil, nil, nil)
 
 	// Test get paused executions with nil repository
 	result, err := resumer.GetPausedExecutions(ctx, limit)
@@ -478,10 +609,10 @@ func TestCanResume_Success(t *testing.T) {
 		PausedReason: &pausedReason,
 	}
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test can resume
-	err = resumer.CanResume(execution)
+	err = resumer.CanResume(context.Background(), execution)
 
 	assert.NoError(t, err)
 }
@@ -493,10 +624,10 @@ func TestCanResume_NilExecution(t *testing.T) {
 	mockRepo := new(MockExecutionRepository)
 	mockEngine := new(MockWorkflowEngine)
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test can resume with nil execution
-	err = resumer.CanResume(nil)
+	err = resumer.CanResume(context.Background(), nil)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "execution is nil")
@@ -516,10 +647,10 @@ func TestCanResume_NotPaused(t *testing.T) {
 		StartedAt:  time.Now(),
 	}
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test can resume
-	err = resumer.CanResume(execution)
+	err = resumer.CanResume(context.Background(), execution)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "is not paused")
@@ -540,10 +671,10 @@ func TestCanResume_NoPauseTimestamp(t *testing.T) {
 		PausedAt:   nil, // Missing pause timestamp
 	}
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test can resume
-	err = resumer.CanResume(execution)
+	err = resumer.CanResume(context.Background(), execution)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "has no pause timestamp")
@@ -566,15 +697,210 @@ func TestCanResume_PausedTooLong(t *testing.T) {
 		PausedAt:   &pausedAt,
 	}
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test can resume
-	err = resumer.CanResume(execution)
+	err = resumer.CanResume(context.Background(), execution)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "has been paused for too long")
 }
 
+// MockWorkflowLookup is a mock implementation of WorkflowLookup
+type MockWorkflowLookup struct {
+	mock.Mock
+}
+
+func (m *MockWorkflowLookup) GetWorkflowByID(ctx context.Context, organizationID, id uuid.UUID) (*models.Workflow, error) {
+	args := m.Called(ctx, organizationID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Workflow), args.Error(1)
+}
+
+func TestCanResume_MaxPausedDuration(t *testing.T) {
+	log, err := logger.New("info", "json")
+	require.NoError(t, err)
+
+	day := 24 * time.Hour
+	tests := []struct {
+		name              string
+		maxPausedDuration time.Duration // Global setting, if any
+		workflowMax       string        // Workflow's max_paused_duration, if any
+		pausedFor         time.Duration
+		canResume         bool
+	}{
+		{"default allows under 7 days", 0, "", 6 * day, true},
+		{"default rejects over 7 days", 0, "", 8 * day, false},
+		{"global setting applies", 2 * day, "", 3 * day, false},
+		{"shorter workflow override rejects within the default", 0, "24h", 2 * day, false},
+		{"shorter workflow override allows within itself", 0, "24h", 12 * time.Hour, true},
+		{"longer workflow override allows past the default", 0, "720h", 20 * day, true},
+		{"longer workflow override rejects past itself", 0, "720h", 31 * day, false},
+		{"workflow override replaces the global setting", 2 * day, "336h", 10 * day, true},
+		{"invalid workflow override falls back to the default", 0, "two weeks", 8 * day, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			pausedAt := time.Now().Add(-tt.pausedFor)
+			execution := &models.WorkflowExecution{
+				ID:             uuid.New(),
+				OrganizationID: uuid.New(),
+				WorkflowID:     uuid.New(),
+				Status:         models.ExecutionStatusPaused,
+				PausedAt:       &pausedAt,
+			}
+
+			workflows := new(MockWorkflowLookup)
+			workflows.On("GetWorkflowByID", ctx, execution.OrganizationID, execution.WorkflowID).Return(&models.Workflow{
+				ID:         execution.WorkflowID,
+				Definition: models.WorkflowDefinition{MaxPausedDuration: tt.workflowMax},
+			}, nil)
+
+			resumer := NewWorkflowResumer(log, new(MockExecutionRepository), new(MockWorkflowEngine), nil)
+			resumer.SetMaxPausedDuration(tt.maxPausedDuration)
+			resumer.SetWorkflowLookup(workflows)
+
+			err := resumer.CanResume(ctx, execution)
+			if tt.canResume {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "has been paused for too long")
+			}
+			workflows.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCanResume_WorkflowLookupError(t *testing.T) {
+	log, err := logger.New("info", "json")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	pausedAt := time.Now().Add(-time.Hour)
+	execution := &models.WorkflowExecution{
+		ID:         uuid.New(),
+		WorkflowID: uuid.New(),
+		Status:     models.ExecutionStatusPaused,
+		PausedAt:   &pausedAt,
+	}
+
+	workflows := new(MockWorkflowLookup)
+	workflows.On("GetWorkflowByID", ctx, execution.OrganizationID, execution.WorkflowID).Return(nil, fmt.Errorf("workflow not found"))
+
+	resumer := NewWorkflowResumer(log, new(MockExecutionRepository), new(MockWorkflowEngine), nil)
+	resumer.SetWorkflowLookup(workflows)
+
+	err = resumer.CanResume(ctx, execution)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to get workflow")
+}
+
+// MockExecutionBroadcaster is a mock implementation of ExecutionBroadcaster
+type MockExecutionBroadcaster struct {
+	mock.Mock
+}
+
+func (m *MockExecutionBroadcaster) BroadcastExecution(execution *models.WorkflowExecution) {
+	m.Called(execution)
+}
+
+func TestExpireExecution(t *testing.T) {
+	log, err := logger.New("info", "json")
+	require.NoError(t, err)
+
+	day := 24 * time.Hour
+	tests := []struct {
+		name        string
+		workflowMax string
+		pausedFor   time.Duration
+		expired     bool
+	}{
+		{"within the default is kept", "", 6 * day, false},
+		{"past the default expires", "", 8 * day, true},
+		{"past a shorter workflow override expires", "24h", 2 * day, true},
+		{"within a longer workflow override is kept", "720h", 20 * day, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			pausedAt := time.Now().Add(-tt.pausedFor)
+			reason := "Waiting for approval"
+			execution := &models.WorkflowExecution{
+				ID:             uuid.New(),
+				OrganizationID: uuid.New(),
+				WorkflowID:     uuid.New(),
+				Status:         models.ExecutionStatusPaused,
+				StartedAt:      pausedAt.Add(-time.Minute),
+				PausedAt:       &pausedAt,
+				PausedReason:   &reason,
+			}
+
+			workflows := new(MockWorkflowLookup)
+			workflows.On("GetWorkflowByID", ctx, execution.OrganizationID, execution.WorkflowID).Return(&models.Workflow{
+				ID:         execution.WorkflowID,
+				Definition: models.WorkflowDefinition{MaxPausedDuration: tt.workflowMax},
+			}, nil)
+			mockRepo := new(MockExecutionRepository)
+			broadcaster := new(MockExecutionBroadcaster)
+			if tt.expired {
+				mockRepo.On("UpdateExecution", ctx, execution).Return(nil)
+				broadcaster.On("BroadcastExecution", execution).Return()
+			}
+
+			resumer := NewWorkflowResumer(log, mockRepo, new(MockWorkflowEngine), nil)
+			resumer.SetWorkflowLookup(workflows)
+			resumer.SetExecutionBroadcaster(broadcaster)
+
+			expired, err := resumer.ExpireExecution(ctx, execution)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expired, expired)
+			if tt.expired {
+				assert.Equal(t, models.ExecutionStatusFailed, execution.Status)
+				require.NotNil(t, execution.Result)
+				assert.Equal(t, models.ExecutionResultFailed, *execution.Result)
+				assert.NotNil(t, execution.CompletedAt)
+				require.NotNil(t, execution.ErrorMessage)
+				assert.Contains(t, *execution.ErrorMessage, "expired after being paused")
+				assert.Contains(t, *execution.ErrorMessage, reason)
+			} else {
+				assert.Equal(t, models.ExecutionStatusPaused, execution.Status)
+			}
+			mockRepo.AssertExpectations(t)
+			broadcaster.AssertExpectations(t)
+		})
+	}
+}
+
+func TestExpireExecution_NotPaused(t *testing.T) {
+	log, err := logger.New("info", "json")
+	require.NoError(t, err)
+
+	pausedAt := time.Now().Add(-30 * 24 * time.Hour)
+	execution := &models.WorkflowExecution{
+		ID:       uuid.New(),
+		Status:   models.ExecutionStatusRunning,
+		PausedAt: &pausedAt,
+	}
+
+	mockRepo := new(MockExecutionRepository)
+	resumer := NewWorkflowResumer(log, mockRepo, new(MockWorkflowEngine), nil)
+
+	expired, err := resumer.ExpireExecution(context.Background(), execution)
+
+	assert.NoError(t, err)
+	assert.False(t, expired)
+	assert.Equal(t, models.ExecutionStatusRunning, execution.Status)
+	mockRepo.AssertNotCalled(t, "UpdateExecution", mock.Anything, mock.Anything)
+}
+
 func TestResumeWorkflow_NilRepository(t *testing.T) {
 	log, err := logger.New("info", "json")
 	require.NoError(t, err)
@@ -582,7 +908,7 @@ func TestResumeWorkflow_NilRepository(t *testing.T) {
 	ctx := context.Background()
 	executionID := uuid.New()
 
-	resumer := NewWorkflowResumer(log, nil, nil)
+	resumer := NewWorkflowResumer(log, nil, nil, nil)
 
 	// Test resume workflow with nil repository - should be a no-op
 	err = resumer.ResumeWorkflow(ctx, executionID, true)
@@ -625,7 +951,7 @@ func TestResumeExecution_MultipleResumes(t *testing.T) {
 	})).Return(nil)
 	mockEngine.On("ResumePausedExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
 
-	resumer := NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 
 	// Test resume execution
 	resumeData := models.JSONB{"approved": true}
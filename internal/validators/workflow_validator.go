@@ -2,11 +2,28 @@ package validators
 
 import (
 	"fmt"
+	"net/mail"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/database"
+	"github.com/google/cel-go/cel"
 )
 
+// maxWebhookAttempts caps the delivery attempts of a webhook action
+const maxWebhookAttempts = 10
+
+// identifierPattern matches names CEL expressions can refer to as identifiers
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// celEnv is the CEL environment expressions are compiled in, created on first use
+var celEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv()
+})
+
 // WorkflowValidator validates workflow definitions
 type WorkflowValidator struct{}
 
@@ -15,6 +32,47 @@ func NewWorkflowValidator() *WorkflowValidator {
 	return &WorkflowValidator{}
 }
 
+// ValidationIssue is a single problem found in a workflow definition
+type ValidationIssue struct {
+	StepID  string `json:"step_id,omitempty"` // Empty for problems not tied to one step, e.g. the trigger
+	Message string `json:"message"`
+}
+
+// ValidationResult lists the problems found in a workflow definition. Warnings, such as steps
+// that can never run, don't make a definition invalid
+type ValidationResult struct {
+	Valid    bool              `json:"valid"`
+	Errors   []ValidationIssue `json:"errors"`
+	Warnings []ValidationIssue `json:"warnings"`
+}
+
+// Err returns the errors as a single error, or nil when the definition is valid
+func (r *ValidationResult) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(r.Errors))
+	for i, issue := range r.Errors {
+		messages[i] = issue.Message
+	}
+	return fmt.Errorf("%s", strings.Join(messages, "; "))
+}
+
+func (r *ValidationResult) addError(stepID, format string, args ...interface{}) {
+	r.Errors = append(r.Errors, ValidationIssue{StepID: stepID, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *ValidationResult) addWarning(stepID, format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, ValidationIssue{StepID: stepID, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateDefinition statically checks a workflow definition so problems surface before it is
+// saved rather than when it runs
+func ValidateDefinition(def *models.WorkflowDefinition) *ValidationResult {
+	return NewWorkflowValidator().ValidateDefinition(def)
+}
+
 // Validate validates a complete workflow definition
 func (v *WorkflowValidator) Validate(workflow *models.Workflow) error {
 	var errors []string
@@ -32,20 +90,10 @@ func (v *WorkflowValidator) Validate(workflow *models.Workflow) error {
 		errors = append(errors, "version is required")
 	}
 
-	// Validate trigger
-	if err := v.validateTrigger(&workflow.Definition.Trigger); err != nil {
+	if err := v.ValidateDefinition(&workflow.Definition).Err(); err != nil {
 		errors = append(errors, err.Error())
 	}
 
-	// Validate steps
-	if len(workflow.Definition.Steps) == 0 {
-		errors = append(errors, "workflow must have at least one step")
-	} else {
-		if err := v.validateSteps(workflow.Definition.Steps); err != nil {
-			errors = append(errors, err.Error())
-		}
-	}
-
 	if len(errors) > 0 {
 		return fmt.Errorf("workflow validation failed: %s", strings.Join(errors, "; "))
 	}
@@ -53,6 +101,40 @@ func (v *WorkflowValidator) Validate(workflow *models.Workflow) error {
 	return nil
 }
 
+// ValidateDefinition checks the trigger and the step graph: every step reference exists, the
+// first step is the only entry point, step IDs are unique, step types are known and each step
+// has the configuration its type requires. Steps the entry step can never reach are warnings
+func (v *WorkflowValidator) ValidateDefinition(def *models.WorkflowDefinition) *ValidationResult {
+	result := &ValidationResult{Errors: []ValidationIssue{}, Warnings: []ValidationIssue{}}
+
+	if err := v.validateTrigger(&def.Trigger); err != nil {
+		result.addError("", "%v", err)
+	}
+
+	if err := validateConcurrency(def.Concurrency); err != nil {
+		result.addError("", "%v", err)
+	}
+
+	if err := validateRateLimit(def.RateLimit); err != nil {
+		result.addError("", "%v", err)
+	}
+
+	if def.Priority != "" {
+		if err := compileExpression(def.Priority); err != nil {
+			result.addError("", "invalid priority: %v", err)
+		}
+	}
+
+	if len(def.Steps) == 0 {
+		result.addError("", "workflow must have at least one step")
+	} else {
+		v.validateSteps(def.Steps, result)
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
 // validateTrigger validates the trigger definition
 func (v *WorkflowValidator) validateTrigger(trigger *models.TriggerDefinition) error {
 	if trigger.Type == "" {
@@ -69,9 +151,32 @@ func (v *WorkflowValidator) validateTrigger(trigger *models.TriggerDefinition) e
 		return fmt.Errorf("invalid trigger type '%s', must be one of: event, schedule, manual", trigger.Type)
 	}
 
-	// Event triggers require an event name
-	if trigger.Type == "event" && trigger.Event == "" {
-		return fmt.Errorf("event trigger requires event name")
+	// Event triggers require an event name or pattern
+	if trigger.Type == "event" {
+		patterns := trigger.EventPatterns()
+		if len(patterns) == 0 {
+			return fmt.Errorf("event trigger requires event name")
+		}
+		for _, pattern := range patterns {
+			if err := validateEventPattern(pattern); err != nil {
+				return err
+			}
+		}
+	}
+
+	if trigger.Filter != nil {
+		if trigger.Type != "event" {
+			return fmt.Errorf("trigger filter requires an event trigger")
+		}
+		if err := v.validateCondition(trigger.Filter); err != nil {
+			return fmt.Errorf("invalid trigger filter: %w", err)
+		}
+	}
+
+	if trigger.Priority != "" {
+		if err := compileExpression(trigger.Priority); err != nil {
+			return fmt.Errorf("invalid trigger priority: %w", err)
+		}
 	}
 
 	// Schedule triggers require a cron expression
@@ -82,54 +187,204 @@ func (v *WorkflowValidator) validateTrigger(trigger *models.TriggerDefinition) e
 	return nil
 }
 
-// validateSteps validates all steps in the workflow
-func (v *WorkflowValidator) validateSteps(steps []models.Step) error {
-	var errors []string
+// validateConcurrency checks the limit, policy and queue timeout of a concurrency limit
+func validateConcurrency(concurrency *models.ConcurrencyDefinition) error {
+	if concurrency == nil {
+		return nil
+	}
+
+	if concurrency.Max < 0 {
+		return fmt.Errorf("invalid concurrency max: %d", concurrency.Max)
+	}
+
+	switch concurrency.Policy {
+	case "", models.ConcurrencyPolicyQueue:
+	case models.ConcurrencyPolicyReject:
+		if concurrency.QueueTimeout != "" {
+			return fmt.Errorf("concurrency queue_timeout requires the queue policy")
+		}
+	default:
+		return fmt.Errorf("invalid concurrency policy '%s', must be one of: queue, reject", concurrency.Policy)
+	}
 
+	return validatePositiveDuration("concurrency queue_timeout", concurrency.QueueTimeout)
+}
+
+// validateRateLimit checks the rate, burst, policy and durations of a rate limit
+func validateRateLimit(rateLimit *models.RateLimitDefinition) error {
+	if rateLimit == nil {
+		return nil
+	}
+
+	if rateLimit.Rate <= 0 {
+		return fmt.Errorf("rate_limit rate must be positive, got %d", rateLimit.Rate)
+	}
+	if rateLimit.Burst < 0 {
+		return fmt.Errorf("invalid rate_limit burst: %d", rateLimit.Burst)
+	}
+	if err := validatePositiveDuration("rate_limit per", rateLimit.Per); err != nil {
+		return err
+	}
+
+	switch rateLimit.Policy {
+	case "", models.RateLimitPolicyQueue:
+	case models.RateLimitPolicyDrop:
+		if rateLimit.QueueTimeout != "" {
+			return fmt.Errorf("rate_limit queue_timeout requires the queue policy")
+		}
+	default:
+		return fmt.Errorf("invalid rate_limit policy '%s', must be one of: queue, drop", rateLimit.Policy)
+	}
+
+	return validatePositiveDuration("rate_limit queue_timeout", rateLimit.QueueTimeout)
+}
+
+// validateEventPattern checks that an event pattern has no empty segments and uses wildcards
+// only as whole segments
+func validateEventPattern(pattern string) error {
+	for _, segment := range strings.Split(pattern, ".") {
+		if segment == "" {
+			return fmt.Errorf("invalid event pattern '%s': empty segment", pattern)
+		}
+		if strings.Contains(segment, "*") && segment != "*" && segment != "**" {
+			return fmt.Errorf("invalid event pattern '%s': wildcards must be a whole segment, '*' or '**'", pattern)
+		}
+	}
+	return nil
+}
+
+// validateSteps validates the top-level steps of the workflow and the references between them
+func (v *WorkflowValidator) validateSteps(steps []models.Step, result *ValidationResult) {
 	// Build step ID map for reference validation
 	stepIDs := make(map[string]bool)
 	for _, step := range steps {
 		if step.ID == "" {
-			errors = append(errors, "all steps must have an ID")
+			result.addError("", "all steps must have an ID")
 			continue
 		}
 
 		if stepIDs[step.ID] {
-			errors = append(errors, fmt.Sprintf("duplicate step ID: %s", step.ID))
+			result.addError(step.ID, "duplicate step ID: %s", step.ID)
 		}
 		stepIDs[step.ID] = true
 	}
 
+	// Execution always starts at the first step, so no other step may lead back to it
+	entryID := steps[0].ID
+
 	// Validate each step
-	for i, step := range steps {
-		if err := v.validateStep(&step, stepIDs, i); err != nil {
-			errors = append(errors, err.Error())
+	for i := range steps {
+		step := &steps[i]
+		for _, msg := range v.validateStep(step) {
+			result.addError(step.ID, "%s", msg)
+		}
+		for _, target := range stepTargets(step) {
+			if !stepIDs[target.id] {
+				result.addError(step.ID, "step %s references non-existent %s step: %s", step.ID, target.field, target.id)
+			} else if entryID != "" && target.id == entryID {
+				result.addError(step.ID, "step %s references entry step %s as its %s step; only the first step may start the workflow", step.ID, entryID, target.field)
+			}
 		}
 	}
 
 	// Check for circular dependencies
 	if err := v.validateNoCycles(steps); err != nil {
-		errors = append(errors, err.Error())
+		result.addError("", "%v", err)
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("step validation errors: %s", strings.Join(errors, "; "))
+	for _, id := range unreachableSteps(steps) {
+		result.addWarning(id, "step %s is unreachable from entry step %s", id, entryID)
 	}
+}
 
-	return nil
+// unreachableSteps returns, in definition order, the IDs of top-level steps that no path from
+// the first step leads to. Sub-steps of parallel and foreach steps run as part of their parent
+func unreachableSteps(steps []models.Step) []string {
+	byID := make(map[string]*models.Step, len(steps))
+	for i := range steps {
+		if _, ok := byID[steps[i].ID]; !ok {
+			byID[steps[i].ID] = &steps[i]
+		}
+	}
+
+	visited := map[string]bool{steps[0].ID: true}
+	queue := []string{steps[0].ID}
+	for len(queue) > 0 {
+		step := byID[queue[0]]
+		queue = queue[1:]
+		for _, target := range stepTargets(step) {
+			// Dangling references are reported as errors by validateSteps
+			if _, ok := byID[target.id]; ok && !visited[target.id] {
+				visited[target.id] = true
+				queue = append(queue, target.id)
+			}
+		}
+	}
+
+	var unreachable []string
+	for _, step := range steps {
+		if step.ID != "" && !visited[step.ID] {
+			unreachable = append(unreachable, step.ID)
+			visited[step.ID] = true // report duplicated IDs once
+		}
+	}
+	return unreachable
+}
+
+// stepTarget is a reference from one step to the step that runs after it
+type stepTarget struct {
+	field string // e.g. "next", "on_true"
+	id    string
+}
+
+// stepTargets returns every step a top-level step can continue to
+func stepTargets(step *models.Step) []stepTarget {
+	var targets []stepTarget
+	add := func(field, id string) {
+		if id != "" {
+			targets = append(targets, stepTarget{field: field, id: id})
+		}
+	}
+
+	add("next", step.Next)
+	add("on_true", step.OnTrue)
+	add("on_false", step.OnFalse)
+	add("on_error", step.OnError)
+	if step.Switch != nil {
+		for _, c := range step.Switch.Cases {
+			add("switch case", c.Next)
+		}
+		add("switch default", step.Switch.Default)
+	}
+	if step.Parallel != nil {
+		add("parallel on_success", step.Parallel.OnSuccess)
+		add("parallel on_failure", step.Parallel.OnFailure)
+	}
+	if step.Wait != nil {
+		add("on_timeout", step.Wait.OnTimeout)
+	}
+	return targets
 }
 
-// validateStep validates a single step
-func (v *WorkflowValidator) validateStep(step *models.Step, stepIDs map[string]bool, index int) error {
+// validateStep checks a step's type and the configuration that type requires. Sub-steps of
+// parallel and foreach steps are checked the same way; references between steps are checked
+// by validateSteps
+func (v *WorkflowValidator) validateStep(step *models.Step) []string {
 	var errors []string
 
 	// Validate step type
 	validTypes := map[string]bool{
 		"condition": true,
+		"switch":    true,
 		"action":    true,
 		"parallel":  true,
+		"foreach":   true,
 		"execute":   true,
 		"wait":      true,
+		"delay":     true,
+		"call":      true,
+		"query":     true,
+		"set":       true,
 	}
 
 	if !validTypes[step.Type] {
@@ -139,25 +394,73 @@ func (v *WorkflowValidator) validateStep(step *models.Step, stepIDs map[string]b
 	// Type-specific validation
 	switch step.Type {
 	case "condition":
-		if step.Condition == nil {
-			errors = append(errors, fmt.Sprintf("step %s (condition) must have a condition", step.ID))
-		} else {
+		// A condition step evaluates either its own condition or a named rule
+		if step.Condition == nil && step.RuleID == "" {
+			errors = append(errors, fmt.Sprintf("step %s (condition) must have a condition or rule_id", step.ID))
+		} else if step.Condition != nil {
 			if err := v.validateCondition(step.Condition); err != nil {
 				errors = append(errors, fmt.Sprintf("step %s: %v", step.ID, err))
 			}
 		}
 
-		// Validate on_true and on_false references
-		if step.OnTrue != "" && !stepIDs[step.OnTrue] {
-			errors = append(errors, fmt.Sprintf("step %s references non-existent on_true step: %s", step.ID, step.OnTrue))
-		}
-		if step.OnFalse != "" && !stepIDs[step.OnFalse] {
-			errors = append(errors, fmt.Sprintf("step %s references non-existent on_false step: %s", step.ID, step.OnFalse))
+	case "switch":
+		if step.Switch == nil {
+			errors = append(errors, fmt.Sprintf("step %s (switch) must have switch configuration", step.ID))
+		} else {
+			if step.Switch.Field == "" {
+				errors = append(errors, fmt.Sprintf("step %s (switch) must have a field", step.ID))
+			}
+			if len(step.Switch.Cases) == 0 {
+				errors = append(errors, fmt.Sprintf("step %s (switch) must have at least one case", step.ID))
+			}
+
+			// Case values are compared by their string form, so uniqueness is too
+			seenValues := make(map[string]bool)
+			for j, c := range step.Switch.Cases {
+				key := fmt.Sprintf("%v", c.Value)
+				if seenValues[key] {
+					errors = append(errors, fmt.Sprintf("step %s has duplicate switch case value: %s", step.ID, key))
+				}
+				seenValues[key] = true
+
+				if c.Next == "" {
+					errors = append(errors, fmt.Sprintf("step %s switch case %d must have a next step", step.ID, j))
+				}
+			}
 		}
 
 	case "action":
 		if step.Action == nil {
 			errors = append(errors, fmt.Sprintf("step %s (action) must have an action", step.ID))
+		} else if step.Action.Type == "ai_classify" {
+			classify := step.Action.Classify
+			switch {
+			case classify == nil:
+				errors = append(errors, fmt.Sprintf("step %s (ai_classify) must have classify configuration", step.ID))
+			case classify.Input == "":
+				errors = append(errors, fmt.Sprintf("step %s (ai_classify) must have an input", step.ID))
+			case len(classify.Labels) < 2:
+				errors = append(errors, fmt.Sprintf("step %s (ai_classify) must have at least two labels", step.ID))
+			default:
+				seen := make(map[string]bool)
+				for _, label := range classify.Labels {
+					if strings.TrimSpace(label) == "" || seen[strings.ToLower(label)] {
+						errors = append(errors, fmt.Sprintf("step %s (ai_classify) labels must be non-empty and distinct", step.ID))
+						break
+					}
+					seen[strings.ToLower(label)] = true
+				}
+			}
+		} else if step.Action.Type == "ai_extract" {
+			extract := step.Action.Extract
+			switch {
+			case extract == nil:
+				errors = append(errors, fmt.Sprintf("step %s (ai_extract) must have extract configuration", step.ID))
+			case extract.Input == "":
+				errors = append(errors, fmt.Sprintf("step %s (ai_extract) must have an input", step.ID))
+			case extract.Schema["type"] != "object":
+				errors = append(errors, fmt.Sprintf("step %s (ai_extract) schema must be of type object", step.ID))
+			}
 		}
 
 	case "parallel":
@@ -176,6 +479,29 @@ func (v *WorkflowValidator) validateStep(step *models.Step, stepIDs map[string]b
 			if step.Parallel.Strategy != "" && !validStrategies[step.Parallel.Strategy] {
 				errors = append(errors, fmt.Sprintf("step %s has invalid parallel strategy: %s", step.ID, step.Parallel.Strategy))
 			}
+
+			for i := range step.Parallel.Steps {
+				errors = append(errors, v.validateStep(&step.Parallel.Steps[i])...)
+			}
+		}
+
+	case "foreach":
+		if step.ForEach == nil {
+			errors = append(errors, fmt.Sprintf("step %s (foreach) must have foreach configuration", step.ID))
+		} else {
+			if step.ForEach.Items == "" {
+				errors = append(errors, fmt.Sprintf("step %s (foreach) must have items", step.ID))
+			}
+			if step.ForEach.ItemVar == "" {
+				errors = append(errors, fmt.Sprintf("step %s (foreach) must have an item_var", step.ID))
+			}
+			if len(step.ForEach.Steps) == 0 {
+				errors = append(errors, fmt.Sprintf("step %s (foreach) must have at least one sub-step", step.ID))
+			}
+
+			for i := range step.ForEach.Steps {
+				errors = append(errors, v.validateStep(&step.ForEach.Steps[i])...)
+			}
 		}
 
 	case "execute":
@@ -189,53 +515,131 @@ func (v *WorkflowValidator) validateStep(step *models.Step, stepIDs map[string]b
 			}
 		}
 
+	case "call":
+		if step.Call == nil || step.Call.WorkflowID == "" {
+			errors = append(errors, fmt.Sprintf("step %s (call) must have a workflow_id", step.ID))
+		}
+
+	case "query":
+		if err := validateQuery(step.Query); err != nil {
+			errors = append(errors, fmt.Sprintf("step %s (query): %v", step.ID, err))
+		}
+
+	case "set":
+		if len(step.Set) == 0 {
+			errors = append(errors, fmt.Sprintf("step %s (set) must have at least one variable", step.ID))
+		}
+		for _, variable := range step.Set {
+			if err := validateSetVariable(variable); err != nil {
+				errors = append(errors, fmt.Sprintf("step %s (set): %v", step.ID, err))
+			}
+		}
+
+	case "delay":
+		if step.Delay == nil || step.Delay.Duration == "" {
+			errors = append(errors, fmt.Sprintf("step %s (delay) must have a duration", step.ID))
+		} else if !strings.HasPrefix(strings.TrimSpace(step.Delay.Duration), "{{") {
+			// Templated durations are resolved when the step runs
+			if duration, err := time.ParseDuration(step.Delay.Duration); err != nil || duration <= 0 {
+				errors = append(errors, fmt.Sprintf("step %s (delay) has invalid duration: %s", step.ID, step.Delay.Duration))
+			}
+		}
+
 	case "wait":
 		if step.Wait == nil {
 			errors = append(errors, fmt.Sprintf("step %s (wait) must have wait configuration", step.ID))
-		} else {
-			if step.Wait.Event == "" {
-				errors = append(errors, fmt.Sprintf("step %s (wait) must have an event to wait for", step.ID))
-			}
-			if step.Wait.OnTimeout != "" && !stepIDs[step.Wait.OnTimeout] {
-				errors = append(errors, fmt.Sprintf("step %s references non-existent on_timeout step: %s", step.ID, step.Wait.OnTimeout))
+		} else if len(step.Wait.EventNames()) == 0 {
+			errors = append(errors, fmt.Sprintf("step %s (wait) must have an event to wait for", step.ID))
+		}
+		if step.Wait != nil {
+			seen := make(map[string]bool)
+			for _, field := range step.Wait.ResumeSchema {
+				switch {
+				case field.Name == "":
+					errors = append(errors, fmt.Sprintf("step %s (wait) has a resume_schema field without a name", step.ID))
+				case seen[field.Name]:
+					errors = append(errors, fmt.Sprintf("step %s (wait) has duplicate resume_schema field: %s", step.ID, field.Name))
+				case !models.ResumeFieldTypes[field.Type]:
+					errors = append(errors, fmt.Sprintf("step %s (wait) resume_schema field %s has invalid type '%s'", step.ID, field.Name, field.Type))
+				}
+				seen[field.Name] = true
 			}
 		}
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	// Without a step to continue to, a recovered failure would silently end the workflow
+	if step.ContinueOnError && step.OnError == "" && step.Next == "" {
+		errors = append(errors, fmt.Sprintf("step %s sets continue_on_error but has no next or on_error step to continue to", step.ID))
 	}
 
-	return nil
+	if step.Compensate != nil {
+		if len(step.Compensate.Execute) == 0 {
+			errors = append(errors, fmt.Sprintf("step %s compensate must have at least one execute action", step.ID))
+		}
+		for j, action := range step.Compensate.Execute {
+			if err := v.validateExecuteAction(&action); err != nil {
+				errors = append(errors, fmt.Sprintf("step %s compensate action %d: %v", step.ID, j, err))
+			}
+		}
+	}
+
+	return errors
 }
 
 // validateCondition validates a condition
 func (v *WorkflowValidator) validateCondition(cond *models.Condition) error {
+	if cond.Expression != "" {
+		if cond.Field != "" || len(cond.And) > 0 || len(cond.Or) > 0 {
+			return fmt.Errorf("condition cannot combine an expression with field or and/or clauses")
+		}
+		return nil
+	}
+
 	if cond.Field == "" && len(cond.And) == 0 && len(cond.Or) == 0 {
-		return fmt.Errorf("condition must have field or and/or clauses")
+		return fmt.Errorf("condition must have field, expression or and/or clauses")
+	}
+
+	// A condition is either a leaf (field/operator/value) or a single and/or group
+	if len(cond.And) > 0 && len(cond.Or) > 0 {
+		return fmt.Errorf("condition cannot combine and and or clauses; nest one group inside the other")
+	}
+	if cond.Field != "" && (len(cond.And) > 0 || len(cond.Or) > 0) {
+		return fmt.Errorf("condition cannot combine field '%s' with and/or clauses", cond.Field)
 	}
 
 	if cond.Field != "" {
 		// Validate operator
 		validOperators := map[string]bool{
-			"eq":       true,
-			"neq":      true,
-			"gt":       true,
-			"gte":      true,
-			"lt":       true,
-			"lte":      true,
-			"in":       true,
-			"contains": true,
-			"regex":    true,
+			"eq":          true,
+			"neq":         true,
+			"gt":          true,
+			"gte":         true,
+			"lt":          true,
+			"lte":         true,
+			"in":          true,
+			"not_in":      true,
+			"contains":    true,
+			"starts_with": true,
+			"ends_with":   true,
+			"regex":       true,
+			"exists":      true,
+			"is_null":     true,
 		}
 
 		if !validOperators[cond.Operator] {
 			return fmt.Errorf("invalid operator '%s'", cond.Operator)
 		}
 
-		if cond.Value == nil {
+		// exists and is_null only inspect the field
+		if cond.Value == nil && cond.Operator != "exists" && cond.Operator != "is_null" {
 			return fmt.Errorf("condition value is required")
 		}
+
+		switch cond.OnMissing {
+		case "", models.OnMissingError, models.OnMissingFalse, models.OnMissingSkip:
+		default:
+			return fmt.Errorf("invalid on_missing policy '%s', must be one of: error, false, skip", cond.OnMissing)
+		}
 	}
 
 	// Recursively validate nested conditions
@@ -270,6 +674,39 @@ func (v *WorkflowValidator) validateExecuteAction(action *models.ExecuteAction)
 			return fmt.Errorf("notify action requires message")
 		}
 
+	case "email":
+		if len(action.To) == 0 {
+			return fmt.Errorf("email action requires to")
+		}
+		if action.Subject == "" {
+			return fmt.Errorf("email action requires subject")
+		}
+		if action.Message == "" && action.HTML == "" {
+			return fmt.Errorf("email action requires message or html")
+		}
+		if err := validateRecipients(action.To); err != nil {
+			return fmt.Errorf("email action to: %w", err)
+		}
+		if err := validateRecipients(action.CC); err != nil {
+			return fmt.Errorf("email action cc: %w", err)
+		}
+		if err := validateRecipients(action.BCC); err != nil {
+			return fmt.Errorf("email action bcc: %w", err)
+		}
+
+	case "slack":
+		if action.Message == "" && len(action.Blocks) == 0 {
+			return fmt.Errorf("slack action requires message or blocks")
+		}
+		if err := validatePositiveDuration("timeout", action.Timeout); err != nil {
+			return fmt.Errorf("slack action: %w", err)
+		}
+		if action.Retry != nil {
+			if err := validateWebhookRetry(action.Retry); err != nil {
+				return fmt.Errorf("slack retry: %w", err)
+			}
+		}
+
 	case "webhook", "http_request":
 		if action.URL == "" {
 			return fmt.Errorf("%s action requires URL", action.Type)
@@ -278,10 +715,18 @@ func (v *WorkflowValidator) validateExecuteAction(action *models.ExecuteAction)
 			validMethods := map[string]bool{
 				"GET": true, "POST": true, "PUT": true, "DELETE": true, "PATCH": true,
 			}
-			if !validMethods[action.Method] {
+			if !validMethods[strings.ToUpper(action.Method)] {
 				return fmt.Errorf("invalid HTTP method: %s", action.Method)
 			}
 		}
+		if err := validatePositiveDuration("timeout", action.Timeout); err != nil {
+			return fmt.Errorf("%s action: %w", action.Type, err)
+		}
+		if action.Retry != nil {
+			if err := validateWebhookRetry(action.Retry); err != nil {
+				return fmt.Errorf("%s retry: %w", action.Type, err)
+			}
+		}
 
 	case "create_record", "update_record", "delete_record":
 		if action.Entity == "" {
@@ -295,6 +740,97 @@ func (v *WorkflowValidator) validateExecuteAction(action *models.ExecuteAction)
 	return nil
 }
 
+// validateRecipients checks literal email addresses; templated ones are checked once rendered
+func validateRecipients(recipients []string) error {
+	for _, recipient := range recipients {
+		if strings.Contains(recipient, "{{") || strings.Contains(recipient, "${") {
+			continue
+		}
+		if _, err := mail.ParseAddressList(recipient); err != nil {
+			return fmt.Errorf("invalid address %q", recipient)
+		}
+	}
+	return nil
+}
+
+// validateWebhookRetry validates the redelivery configuration of a webhook action
+func validateWebhookRetry(retry *models.WebhookRetryConfig) error {
+	if retry.MaxAttempts < 1 || retry.MaxAttempts > maxWebhookAttempts {
+		return fmt.Errorf("max_attempts must be between 1 and %d", maxWebhookAttempts)
+	}
+	if retry.Backoff != "" && retry.Backoff != "linear" && retry.Backoff != "exponential" {
+		return fmt.Errorf("invalid backoff: %s", retry.Backoff)
+	}
+	if err := validatePositiveDuration("initial_delay", retry.InitialDelay); err != nil {
+		return err
+	}
+	return validatePositiveDuration("max_backoff", retry.MaxBackoff)
+}
+
+// validateQuery checks a query step's datasource, SQL and limits. The SQL must be a single
+// SELECT whose placeholders are all bound by params
+func validateQuery(query *models.QueryStep) error {
+	if query == nil {
+		return fmt.Errorf("must have query configuration")
+	}
+	if query.Datasource == "" {
+		return fmt.Errorf("must have a datasource")
+	}
+	if strings.TrimSpace(query.SQL) == "" {
+		return fmt.Errorf("must have sql")
+	}
+	if err := database.CheckReadOnlyQuery(query.SQL, len(query.Params)); err != nil {
+		return fmt.Errorf("invalid sql: %w", err)
+	}
+	if query.MaxRows < 0 {
+		return fmt.Errorf("invalid max_rows: %d", query.MaxRows)
+	}
+	return validatePositiveDuration("timeout", query.Timeout)
+}
+
+// validateSetVariable checks that a set step variable has a name later expressions can refer
+// to and an expression that compiles
+func validateSetVariable(variable models.SetVariable) error {
+	if !identifierPattern.MatchString(variable.Name) {
+		return fmt.Errorf("invalid variable name '%s'", variable.Name)
+	}
+	if strings.TrimSpace(variable.Expression) == "" {
+		return fmt.Errorf("variable %s must have an expression", variable.Name)
+	}
+	if err := compileExpression(variable.Expression); err != nil {
+		return fmt.Errorf("variable %s: %w", variable.Name, err)
+	}
+	return nil
+}
+
+// compileExpression checks that a CEL expression compiles the way the engine compiles it: parsed
+// without type checking, since identifiers resolve against the context at evaluation time
+func compileExpression(expression string) error {
+	env, err := celEnv()
+	if err != nil {
+		return fmt.Errorf("failed to create expression environment: %w", err)
+	}
+	ast, issues := env.Parse(expression)
+	if issues != nil && issues.Err() != nil {
+		return fmt.Errorf("invalid expression %q: %w", expression, issues.Err())
+	}
+	if _, err := env.Program(ast); err != nil {
+		return fmt.Errorf("invalid expression %q: %w", expression, err)
+	}
+	return nil
+}
+
+// validatePositiveDuration checks that an optional duration field is positive
+func validatePositiveDuration(name, value string) error {
+	if value == "" {
+		return nil
+	}
+	if duration, err := time.ParseDuration(value); err != nil || duration <= 0 {
+		return fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return nil
+}
+
 // validateNoCycles checks for circular dependencies in the workflow
 func (v *WorkflowValidator) validateNoCycles(steps []models.Step) error {
 	// Build adjacency list
@@ -311,6 +847,27 @@ func (v *WorkflowValidator) validateNoCycles(steps []models.Step) error {
 		if step.Wait != nil && step.Wait.OnTimeout != "" {
 			graph[step.ID] = append(graph[step.ID], step.Wait.OnTimeout)
 		}
+		if step.OnError != "" {
+			graph[step.ID] = append(graph[step.ID], step.OnError)
+		}
+		if step.Parallel != nil {
+			if step.Parallel.OnSuccess != "" {
+				graph[step.ID] = append(graph[step.ID], step.Parallel.OnSuccess)
+			}
+			if step.Parallel.OnFailure != "" {
+				graph[step.ID] = append(graph[step.ID], step.Parallel.OnFailure)
+			}
+		}
+		if step.Switch != nil {
+			for _, c := range step.Switch.Cases {
+				if c.Next != "" {
+					graph[step.ID] = append(graph[step.ID], c.Next)
+				}
+			}
+			if step.Switch.Default != "" {
+				graph[step.ID] = append(graph[step.ID], step.Switch.Default)
+			}
+		}
 	}
 
 	// DFS to detect cycles
@@ -0,0 +1,544 @@
+package validators
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+)
+
+func validDefinition() models.WorkflowDefinition {
+	return models.WorkflowDefinition{
+		Trigger: models.TriggerDefinition{Type: "event", Event: "order.created"},
+		Steps: []models.Step{
+			{
+				ID:        "check_total",
+				Type:      "condition",
+				Condition: &models.Condition{Field: "order.total", Operator: "gt", Value: 1000},
+				OnTrue:    "block",
+				OnFalse:   "allow",
+			},
+			{ID: "block", Type: "action", Action: &models.Action{Type: "block"}},
+			{ID: "allow", Type: "action", Action: &models.Action{Type: "allow"}},
+		},
+	}
+}
+
+func TestValidateDefinition(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(def *models.WorkflowDefinition)
+		stepID string // expected step of the single error; "" when the definition is valid
+		errMsg string
+	}{
+		{
+			name:   "valid",
+			modify: func(def *models.WorkflowDefinition) {},
+		},
+		{
+			name: "event pattern list with wildcards",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Trigger = models.TriggerDefinition{Type: "event", Events: []string{"order.*", "payment.**"}}
+			},
+		},
+		{
+			name:   "event trigger without events",
+			modify: func(def *models.WorkflowDefinition) { def.Trigger.Event = "" },
+			errMsg: "event trigger requires event name",
+		},
+		{
+			name:   "wildcard within an event segment",
+			modify: func(def *models.WorkflowDefinition) { def.Trigger.Events = []string{"order.ship*"} },
+			errMsg: "wildcards must be a whole segment",
+		},
+		{
+			name:   "empty event segment",
+			modify: func(def *models.WorkflowDefinition) { def.Trigger.Event = "order..created" },
+			errMsg: "empty segment",
+		},
+		{
+			name: "trigger filter",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Trigger.Filter = &models.Condition{Field: "order.total", Operator: "gt", Value: 1000}
+			},
+		},
+		{
+			name: "trigger filter with an unknown operator",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Trigger.Filter = &models.Condition{Field: "order.total", Operator: "bigger", Value: 1000}
+			},
+			errMsg: "invalid trigger filter: invalid operator 'bigger'",
+		},
+		{
+			name: "trigger filter on a manual trigger",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Trigger = models.TriggerDefinition{Type: "manual", Filter: &models.Condition{Field: "order.total", Operator: "gt", Value: 1000}}
+			},
+			errMsg: "trigger filter requires an event trigger",
+		},
+		{
+			name: "concurrency limit per entity",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Concurrency = &models.ConcurrencyDefinition{Key: "{{order.id}}", Max: 1, QueueTimeout: "10m"}
+			},
+		},
+		{
+			name: "unknown concurrency policy",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Concurrency = &models.ConcurrencyDefinition{Policy: "drop"}
+			},
+			errMsg: "invalid concurrency policy 'drop'",
+		},
+		{
+			name: "concurrency queue timeout with the reject policy",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Concurrency = &models.ConcurrencyDefinition{Policy: models.ConcurrencyPolicyReject, QueueTimeout: "1m"}
+			},
+			errMsg: "queue_timeout requires the queue policy",
+		},
+		{
+			name: "rate limit",
+			modify: func(def *models.WorkflowDefinition) {
+				def.RateLimit = &models.RateLimitDefinition{Rate: 10, Per: "1s", Burst: 20}
+			},
+		},
+		{
+			name: "rate limit without a rate",
+			modify: func(def *models.WorkflowDefinition) {
+				def.RateLimit = &models.RateLimitDefinition{Per: "1s"}
+			},
+			errMsg: "rate_limit rate must be positive",
+		},
+		{
+			name: "rate limit with an invalid interval",
+			modify: func(def *models.WorkflowDefinition) {
+				def.RateLimit = &models.RateLimitDefinition{Rate: 10, Per: "a second"}
+			},
+			errMsg: "invalid rate_limit per",
+		},
+		{
+			name: "priority by order value",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Priority = "1"
+				def.Trigger.Priority = "order.total >= 1000 ? 10 : 0"
+			},
+		},
+		{
+			name: "trigger priority that does not compile",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Trigger.Priority = "order.total >="
+			},
+			errMsg: "invalid trigger priority",
+		},
+		{
+			name:   "next references missing step",
+			modify: func(def *models.WorkflowDefinition) { def.Steps[1].Next = "missing" },
+			stepID: "block",
+			errMsg: "non-existent next step: missing",
+		},
+		{
+			name:   "on_true references missing step",
+			modify: func(def *models.WorkflowDefinition) { def.Steps[0].OnTrue = "missing" },
+			stepID: "check_total",
+			errMsg: "non-existent on_true step: missing",
+		},
+		{
+			name: "step leads back to the entry step",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps = append(def.Steps, models.Step{ID: "retry", Type: "action", Action: &models.Action{Type: "allow"}, Next: "check_total"})
+			},
+			stepID: "retry",
+			errMsg: "references entry step check_total",
+		},
+		{
+			name: "duplicate step ID",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2].ID = "block"
+				def.Steps[0].OnFalse = "block"
+			},
+			stepID: "block",
+			errMsg: "duplicate step ID: block",
+		},
+		{
+			name:   "unknown step type",
+			modify: func(def *models.WorkflowDefinition) { def.Steps[1].Type = "teleport" },
+			stepID: "block",
+			errMsg: "invalid type 'teleport'",
+		},
+		{
+			name:   "condition step without condition",
+			modify: func(def *models.WorkflowDefinition) { def.Steps[0].Condition = nil },
+			stepID: "check_total",
+			errMsg: "must have a condition or rule_id",
+		},
+		{
+			name: "condition step using a rule",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[0].Condition = nil
+				def.Steps[0].RuleID = "high_value_order"
+			},
+		},
+		{
+			name: "foreach without items",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{
+					ID:   "allow",
+					Type: "foreach",
+					ForEach: &models.ForEachStep{
+						ItemVar: "item",
+						Steps:   []models.Step{{ID: "notify", Type: "execute", Execute: []models.ExecuteAction{{Type: "log"}}}},
+					},
+				}
+			},
+			stepID: "allow",
+			errMsg: "(foreach) must have items",
+		},
+		{
+			name: "invalid foreach sub-step",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{
+					ID:      "allow",
+					Type:    "foreach",
+					ForEach: &models.ForEachStep{Items: "{{order.items}}", ItemVar: "item", Steps: []models.Step{{ID: "notify", Type: "execute"}}},
+				}
+			},
+			stepID: "allow",
+			errMsg: "step notify (execute) must have at least one execute action",
+		},
+		{
+			name: "ai_classify action",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2].Action = &models.Action{
+					Type:     "ai_classify",
+					Classify: &models.ClassifyAction{Input: "{{order.notes}}", Labels: []string{"gift", "business"}, OutputVar: "purpose"},
+				}
+			},
+		},
+		{
+			name: "ai_classify action with one label",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2].Action = &models.Action{
+					Type:     "ai_classify",
+					Classify: &models.ClassifyAction{Input: "{{order.notes}}", Labels: []string{"gift"}},
+				}
+			},
+			stepID: "allow",
+			errMsg: "(ai_classify) must have at least two labels",
+		},
+		{
+			name: "ai_classify action with duplicate labels",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2].Action = &models.Action{
+					Type:     "ai_classify",
+					Classify: &models.ClassifyAction{Input: "{{order.notes}}", Labels: []string{"gift", "Gift"}},
+				}
+			},
+			stepID: "allow",
+			errMsg: "labels must be non-empty and distinct",
+		},
+		{
+			name: "ai_extract action",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2].Action = &models.Action{
+					Type: "ai_extract",
+					Extract: &models.ExtractAction{
+						Input:  "{{email.body}}",
+						Schema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+					},
+				}
+			},
+		},
+		{
+			name: "ai_extract action without an object schema",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2].Action = &models.Action{
+					Type:    "ai_extract",
+					Extract: &models.ExtractAction{Input: "{{email.body}}", Schema: map[string]interface{}{"type": "string"}},
+				}
+			},
+			stepID: "allow",
+			errMsg: "(ai_extract) schema must be of type object",
+		},
+		{
+			name: "wait step with a resume schema",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{
+					ID:   "allow",
+					Type: "wait",
+					Wait: &models.WaitConfig{Event: "order.reviewed", ResumeSchema: []models.ResumeField{
+						{Name: "approved", Type: "boolean", Required: true},
+						{Name: "notes", Type: "string"},
+					}},
+				}
+			},
+		},
+		{
+			name: "delay step",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[1].Next = "cool_off"
+				def.Steps = append(def.Steps, models.Step{ID: "cool_off", Type: "delay", Delay: &models.DelayStep{Duration: "{{order.review_after}}"}, Next: "allow"})
+			},
+		},
+		{
+			name: "delay step with a negative duration",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{ID: "allow", Type: "delay", Delay: &models.DelayStep{Duration: "-5m"}}
+			},
+			stepID: "allow",
+			errMsg: "(delay) has invalid duration: -5m",
+		},
+		{
+			name: "resume schema field with an unknown type",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{
+					ID:   "allow",
+					Type: "wait",
+					Wait: &models.WaitConfig{Event: "order.reviewed", ResumeSchema: []models.ResumeField{{Name: "amount", Type: "money"}}},
+				}
+			},
+			stepID: "allow",
+			errMsg: "resume_schema field amount has invalid type 'money'",
+		},
+		{
+			name: "webhook with delivery retries and signing",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{ID: "allow", Type: "execute", Execute: []models.ExecuteAction{{
+					Type:          "webhook",
+					URL:           "https://example.com/hooks/orders",
+					Method:        "put",
+					Timeout:       "5s",
+					Retry:         &models.WebhookRetryConfig{MaxAttempts: 3, InitialDelay: "500ms", MaxBackoff: "10s"},
+					SigningSecret: "billing",
+				}}}
+			},
+		},
+		{
+			name: "webhook retry without attempts",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{ID: "allow", Type: "execute", Execute: []models.ExecuteAction{{
+					Type:  "webhook",
+					URL:   "https://example.com/hooks/orders",
+					Retry: &models.WebhookRetryConfig{Backoff: "exponential"},
+				}}}
+			},
+			stepID: "allow",
+			errMsg: "webhook retry: max_attempts must be between 1 and 10",
+		},
+		{
+			name: "webhook with an invalid timeout",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{ID: "allow", Type: "execute", Execute: []models.ExecuteAction{{
+					Type:    "webhook",
+					URL:     "https://example.com/hooks/orders",
+					Timeout: "soon",
+				}}}
+			},
+			stepID: "allow",
+			errMsg: "webhook action: invalid timeout: soon",
+		},
+		{
+			name: "slack message with blocks and retries",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{ID: "allow", Type: "execute", Execute: []models.ExecuteAction{{
+					Type:    "slack",
+					Channel: "#orders",
+					Blocks:  []interface{}{map[string]interface{}{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": "Order {{order.id}}"}}},
+					Retry:   &models.WebhookRetryConfig{MaxAttempts: 3},
+				}}}
+			},
+		},
+		{
+			name: "slack without message or blocks",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{ID: "allow", Type: "execute", Execute: []models.ExecuteAction{{
+					Type:    "slack",
+					Channel: "#orders",
+				}}}
+			},
+			stepID: "allow",
+			errMsg: "slack action requires message or blocks",
+		},
+		{
+			name: "email with templated and literal recipients",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{ID: "allow", Type: "execute", Execute: []models.ExecuteAction{{
+					Type:    "email",
+					To:      []string{"{{customer.email}}"},
+					CC:      []string{"Ops <ops@example.com>, billing@example.com"},
+					Subject: "Order {{order.id}}",
+					HTML:    "<p>Thanks, {{customer.name}}</p>",
+				}}}
+			},
+		},
+		{
+			name: "email with an invalid address",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{ID: "allow", Type: "execute", Execute: []models.ExecuteAction{{
+					Type:    "email",
+					To:      []string{"{{customer.email}}"},
+					BCC:     []string{"not an address"},
+					Subject: "Order {{order.id}}",
+					Message: "Thanks",
+				}}}
+			},
+			stepID: "allow",
+			errMsg: `email action bcc: invalid address "not an address"`,
+		},
+		{
+			name: "query step",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[0].OnFalse = "history"
+				def.Steps = append(def.Steps, models.Step{
+					ID:   "history",
+					Type: "query",
+					Query: &models.QueryStep{
+						Datasource: "analytics",
+						SQL:        "SELECT count(*) AS orders FROM orders WHERE customer_id = $1",
+						Params:     []string{"{{customer.id}}"},
+						MaxRows:    1,
+						Timeout:    "2s",
+					},
+					Next: "allow",
+				})
+			},
+		},
+		{
+			name: "query step with a second statement",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{ID: "allow", Type: "query", Query: &models.QueryStep{
+					Datasource: "analytics",
+					SQL:        "SELECT 1; DELETE FROM orders",
+				}}
+			},
+			stepID: "allow",
+			errMsg: "(query): invalid sql: multiple statements are not allowed",
+		},
+		{
+			name: "query step with an unbound placeholder",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{ID: "allow", Type: "query", Query: &models.QueryStep{
+					Datasource: "analytics",
+					SQL:        "SELECT * FROM orders WHERE id = $1",
+				}}
+			},
+			stepID: "allow",
+			errMsg: "placeholder $1 has no parameter (0 given)",
+		},
+		{
+			name: "set step",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{ID: "allow", Type: "set", Set: []models.SetVariable{
+					{Name: "total_with_tax", Expression: "order.total * 1.2"},
+					{Name: "needs_review", Expression: "total_with_tax > 1000.0"},
+				}}
+			},
+		},
+		{
+			name: "set step with an invalid expression",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{ID: "allow", Type: "set", Set: []models.SetVariable{
+					{Name: "total_with_tax", Expression: "order.total * "},
+				}}
+			},
+			stepID: "allow",
+			errMsg: "(set): variable total_with_tax: invalid expression",
+		},
+		{
+			name: "set step with a dotted variable name",
+			modify: func(def *models.WorkflowDefinition) {
+				def.Steps[2] = models.Step{ID: "allow", Type: "set", Set: []models.SetVariable{
+					{Name: "order.total_with_tax", Expression: "order.total * 1.2"},
+				}}
+			},
+			stepID: "allow",
+			errMsg: "invalid variable name 'order.total_with_tax'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := validDefinition()
+			tt.modify(&def)
+
+			result := ValidateDefinition(&def)
+
+			if tt.errMsg == "" {
+				if !result.Valid || len(result.Errors) != 0 || len(result.Warnings) != 0 {
+					t.Fatalf("Expected a valid definition, got errors %v and warnings %v", result.Errors, result.Warnings)
+				}
+				return
+			}
+
+			if result.Valid {
+				t.Fatal("Expected an invalid definition")
+			}
+			if len(result.Errors) != 1 {
+				t.Fatalf("Expected one error, got %v", result.Errors)
+			}
+			if result.Errors[0].StepID != tt.stepID {
+				t.Errorf("Expected error for step %q, got %q", tt.stepID, result.Errors[0].StepID)
+			}
+			if !strings.Contains(result.Errors[0].Message, tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %q", tt.errMsg, result.Errors[0].Message)
+			}
+		})
+	}
+
+	t.Run("no steps", func(t *testing.T) {
+		def := validDefinition()
+		def.Steps = nil
+
+		result := ValidateDefinition(&def)
+		if result.Valid || result.Err() == nil {
+			t.Fatal("Expected a definition without steps to be invalid")
+		}
+	})
+
+	t.Run("unreachable steps are warnings", func(t *testing.T) {
+		def := validDefinition()
+		def.Steps[0].OnFalse = ""
+		def.Steps = append(def.Steps,
+			models.Step{ID: "notify", Type: "execute", Execute: []models.ExecuteAction{{Type: "log"}}, Next: "audit"},
+			models.Step{ID: "audit", Type: "action", Action: &models.Action{Type: "allow"}},
+		)
+
+		result := ValidateDefinition(&def)
+
+		if !result.Valid {
+			t.Fatalf("Expected unreachable steps not to invalidate the definition, got %v", result.Errors)
+		}
+
+		var unreachable []string
+		for _, warning := range result.Warnings {
+			unreachable = append(unreachable, warning.StepID)
+		}
+		if strings.Join(unreachable, ",") != "allow,notify,audit" {
+			t.Errorf("Expected warnings for allow, notify and audit, got %v", result.Warnings)
+		}
+	})
+
+	t.Run("branches reach their targets", func(t *testing.T) {
+		def := validDefinition()
+		def.Steps[0] = models.Step{
+			ID:   "route",
+			Type: "switch",
+			Switch: &models.SwitchStep{
+				Field:   "order.status",
+				Cases:   []models.SwitchCase{{Value: "fraud", Next: "block"}},
+				Default: "wait",
+			},
+		}
+		def.Steps = append(def.Steps, models.Step{
+			ID:   "wait",
+			Type: "wait",
+			Wait: &models.WaitConfig{Event: "order.approved", Timeout: "1h", OnTimeout: "block"},
+			Next: "allow",
+		})
+
+		result := ValidateDefinition(&def)
+
+		if !result.Valid || len(result.Warnings) != 0 {
+			t.Errorf("Expected no errors or warnings, got errors %v and warnings %v", result.Errors, result.Warnings)
+		}
+	})
+}
@@ -321,6 +321,19 @@ func (h *Hub) BroadcastStepEvent(msgType MessageType, data *StepEventData) {
 	h.Broadcast(channel, message, "", data.ExecutionID, data.Status, "")
 }
 
+// BroadcastStepProgress broadcasts partial output of a running step
+func (h *Hub) BroadcastStepProgress(data *StepProgressData) {
+	message, err := NewMessage(MessageTypeStepProgress, data)
+	if err != nil {
+		h.logger.Error("failed to create step progress message", zap.Error(err))
+		return
+	}
+
+	// Broadcast to execution-specific channel
+	channel := fmt.Sprintf("executions:%s", data.ExecutionID)
+	h.Broadcast(channel, message, "", data.ExecutionID, "running", "")
+}
+
 // BroadcastApprovalEvent broadcasts an approval event
 func (h *Hub) BroadcastApprovalEvent(msgType MessageType, data *ApprovalEventData) {
 	message, err := NewMessage(msgType, data)
@@ -24,6 +24,7 @@ const (
 	MessageTypeStepCompleted MessageType = "step.completed"
 	MessageTypeStepFailed    MessageType = "step.failed"
 	MessageTypeStepSkipped   MessageType = "step.skipped"
+	MessageTypeStepProgress  MessageType = "step.progress"
 
 	// Approval event types
 	MessageTypeApprovalRequired MessageType = "approval.required"
@@ -76,6 +77,15 @@ type StepEventData struct {
 	Output       map[string]interface{} `json:"output,omitempty"`
 }
 
+// StepProgressData contains partial output of a running step, e.g. the tokens of an AI step
+type StepProgressData struct {
+	ExecutionID string `json:"execution_id"`
+	StepID      string `json:"step_id"`
+	Delta       string `json:"delta,omitempty"` // Output since the previous event
+	Sequence    int    `json:"sequence"`        // Orders the events of one step attempt, starting at 1
+	Done        bool   `json:"done,omitempty"`  // Set on the last event of the attempt
+}
+
 // ApprovalEventData contains approval event details
 type ApprovalEventData struct {
 	ApprovalID   string     `json:"approval_id"`
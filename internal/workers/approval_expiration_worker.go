@@ -75,8 +75,14 @@ func (w *ApprovalExpirationWorker) run(ctx context.Context) {
 	}
 }
 
-// checkExpiredApprovals checks and expires old approvals
+// checkExpiredApprovals escalates approvals that are due to escalate and expires old approvals
 func (w *ApprovalExpirationWorker) checkExpiredApprovals(ctx context.Context) {
+	w.logger.Debug("Checking for approvals due to escalate")
+
+	if err := w.approvalService.EscalateDueApprovals(ctx); err != nil {
+		w.logger.Errorf("Failed to escalate approvals: %v", err)
+	}
+
 	w.logger.Debug("Checking for expired approvals")
 
 	if err := w.approvalService.ExpireOldApprovals(ctx); err != nil {
@@ -0,0 +1,151 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/engine"
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+)
+
+// DelayedExecutionRepository claims executions whose delay step is due
+type DelayedExecutionRepository interface {
+	ClaimDueDelays(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]*models.WorkflowExecution, error)
+}
+
+// DelayResumer continues executions whose delay has passed
+type DelayResumer interface {
+	ResumeDelayedExecution(ctx context.Context, execution *models.WorkflowExecution) error
+}
+
+// DelayWorker continues executions waiting at a delay step once their resume time has passed.
+// Due executions are claimed before they are continued, so several instances can run the
+// worker without continuing an execution twice. Claims expire, so executions claimed by an
+// instance that dies are continued by another
+type DelayWorker struct {
+	executionRepo DelayedExecutionRepository
+	resumer       DelayResumer
+	logger        *logger.Logger
+	checkInterval time.Duration
+	batchSize     int
+	claimTTL      time.Duration
+	stopCh        chan struct{}
+	doneCh        chan struct{}
+}
+
+// NewDelayWorker creates a new delay worker
+func NewDelayWorker(
+	executionRepo DelayedExecutionRepository,
+	resumer DelayResumer,
+	logger *logger.Logger,
+	checkInterval time.Duration,
+) *DelayWorker {
+	if checkInterval == 0 {
+		checkInterval = 10 * time.Second // Default to 10 seconds
+	}
+
+	return &DelayWorker{
+		executionRepo: executionRepo,
+		resumer:       resumer,
+		logger:        logger,
+		checkInterval: checkInterval,
+		batchSize:     50,              // Continue up to 50 executions per check
+		claimTTL:      5 * time.Minute, // Claims outlive a batch of slow synchronous runs, then expire if an instance dies
+		stopCh:        make(chan struct{}),
+		doneCh:        make(chan struct{}),
+	}
+}
+
+// Start starts the worker in the background
+func (w *DelayWorker) Start(ctx context.Context) {
+	w.logger.Info("Starting delay worker",
+		logger.String("interval", w.checkInterval.String()),
+		logger.Int("batch_size", w.batchSize),
+	)
+
+	go w.run(ctx)
+}
+
+// Stop stops the worker gracefully
+func (w *DelayWorker) Stop() {
+	w.logger.Info("Stopping delay worker")
+	close(w.stopCh)
+	<-w.doneCh
+	w.logger.Info("Delay worker stopped")
+}
+
+// run is the main worker loop
+func (w *DelayWorker) run(ctx context.Context) {
+	defer close(w.doneCh)
+
+	ticker := time.NewTicker(w.checkInterval)
+	defer ticker.Stop()
+
+	// Run immediately on start, continuing delays that passed while no instance was running
+	w.processDueDelays(ctx)
+
+	for {
+		select {
+		case <-ticker.C:
+			w.processDueDelays(ctx)
+		case <-w.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// processDueDelays claims executions whose delay has passed and continues them
+func (w *DelayWorker) processDueDelays(ctx context.Context) {
+	w.logger.Debug("Checking for due delays")
+
+	claimedAt := time.Now()
+	executions, err := w.executionRepo.ClaimDueDelays(ctx, claimedAt, w.claimTTL, w.batchSize)
+	if err != nil {
+		w.logger.Errorf("Failed to claim due delays: %v", err)
+		return
+	}
+
+	if len(executions) == 0 {
+		w.logger.Debug("No due delays found")
+		return
+	}
+
+	w.logger.Infof("Found %d delayed executions to continue", len(executions))
+
+	resumedCount := 0
+	skippedCount := 0
+	errorCount := 0
+
+	for _, execution := range executions {
+		// Another instance may have claimed the execution again once this claim expired
+		if time.Since(claimedAt) >= w.claimTTL {
+			w.logger.Warnf("Claim of delayed execution %s expired before it was continued, leaving it to be claimed again", execution.ID)
+			skippedCount++
+			continue
+		}
+
+		if err := w.resumer.ResumeDelayedExecution(ctx, execution); err != nil {
+			if errors.Is(err, engine.ErrConcurrencyLimitReached) || errors.Is(err, engine.ErrRateLimited) {
+				// Back to waiting, and claimed again on the next check
+				w.logger.Infof("Delayed execution %s left waiting by its workflow's limits: %v", execution.ID, err)
+				skippedCount++
+				continue
+			}
+			w.logger.Errorf("Failed to continue delayed execution %s: %v", execution.ID, err)
+			errorCount++
+			continue
+		}
+		resumedCount++
+	}
+
+	w.logger.Infof(
+		"Delayed executions processed: resumed=%d, skipped=%d, errors=%d",
+		resumedCount,
+		skippedCount,
+		errorCount,
+	)
+}
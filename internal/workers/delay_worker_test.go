@@ -0,0 +1,154 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// fakeDelayedExecutionRepo hands each due delay to a single claimant until its claim expires,
+// like the claiming query. Executions stay delayed until the resumer continues them
+type fakeDelayedExecutionRepo struct {
+	mu           sync.Mutex
+	executions   []*models.WorkflowExecution
+	claimedUntil map[uuid.UUID]time.Time
+	continued    map[uuid.UUID]bool
+}
+
+func newFakeDelayedExecutionRepo(executions ...*models.WorkflowExecution) *fakeDelayedExecutionRepo {
+	return &fakeDelayedExecutionRepo{
+		executions:   executions,
+		claimedUntil: make(map[uuid.UUID]time.Time),
+		continued:    make(map[uuid.UUID]bool),
+	}
+}
+
+func (f *fakeDelayedExecutionRepo) ClaimDueDelays(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]*models.WorkflowExecution, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var claimed []*models.WorkflowExecution
+	for _, execution := range f.executions {
+		if f.continued[execution.ID] || execution.WaitState.ResumeAt.After(now) || f.claimedUntil[execution.ID].After(now) || len(claimed) >= limit {
+			continue
+		}
+		f.claimedUntil[execution.ID] = now.Add(claimTTL)
+		copied := *execution
+		copied.Status = models.ExecutionStatusRunning
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+// fakeDelayResumer continues delayed executions, failing for those in failFor
+type fakeDelayResumer struct {
+	mu      sync.Mutex
+	repo    *fakeDelayedExecutionRepo
+	failFor map[uuid.UUID]bool
+	delay   time.Duration // how long each continued run takes
+	resumed []uuid.UUID
+}
+
+func (f *fakeDelayResumer) ResumeDelayedExecution(ctx context.Context, execution *models.WorkflowExecution) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if execution.Status != models.ExecutionStatusRunning {
+		return errors.New("execution must be in running state to resume")
+	}
+	if f.failFor[execution.ID] {
+		return errors.New("failed to update execution status")
+	}
+	time.Sleep(f.delay)
+
+	f.resumed = append(f.resumed, execution.ID)
+	f.repo.mu.Lock()
+	f.repo.continued[execution.ID] = true
+	f.repo.mu.Unlock()
+	return nil
+}
+
+// newDelayedExecution returns an execution waiting at a delay step that fell due the given time ago
+func newDelayedExecution(overdue time.Duration) *models.WorkflowExecution {
+	resumeAt := time.Now().Add(-overdue)
+	stepID := "cool_off"
+	return &models.WorkflowExecution{
+		ID:             uuid.New(),
+		OrganizationID: uuid.New(),
+		WorkflowID:     uuid.New(),
+		Status:         models.ExecutionStatusWaiting,
+		CurrentStepID:  &stepID,
+		WaitState:      &models.WaitState{ResumeAt: &resumeAt, WaitingSince: resumeAt.Add(-10 * time.Minute)},
+	}
+}
+
+func TestDelayWorker_ProcessDueDelays(t *testing.T) {
+	log := logger.NewForTesting()
+
+	t.Run("continues due delays once", func(t *testing.T) {
+		due := newDelayedExecution(time.Minute)
+		notDue := newDelayedExecution(-time.Hour)
+		repo := newFakeDelayedExecutionRepo(due, notDue)
+		resumer := &fakeDelayResumer{repo: repo}
+		worker := NewDelayWorker(repo, resumer, log, time.Minute)
+
+		worker.processDueDelays(context.Background())
+		worker.processDueDelays(context.Background())
+
+		if len(resumer.resumed) != 1 || resumer.resumed[0] != due.ID {
+			t.Errorf("Expected only the due delay to be continued once, got %v", resumer.resumed)
+		}
+	})
+
+	t.Run("claims a delay that failed to continue again once its claim expires", func(t *testing.T) {
+		execution := newDelayedExecution(time.Minute)
+		repo := newFakeDelayedExecutionRepo(execution)
+		resumer := &fakeDelayResumer{repo: repo, failFor: map[uuid.UUID]bool{execution.ID: true}}
+		worker := NewDelayWorker(repo, resumer, log, time.Minute)
+		worker.claimTTL = 20 * time.Millisecond
+
+		worker.processDueDelays(context.Background())
+		if len(resumer.resumed) != 0 {
+			t.Fatalf("Expected the delay not to be continued, got %v", resumer.resumed)
+		}
+
+		// Still claimed, so no other check takes it
+		resumer.failFor = nil
+		worker.processDueDelays(context.Background())
+		if len(resumer.resumed) != 0 {
+			t.Fatalf("Expected the claimed delay not to be continued before its claim expires, got %v", resumer.resumed)
+		}
+
+		time.Sleep(2 * worker.claimTTL)
+		worker.processDueDelays(context.Background())
+		if len(resumer.resumed) != 1 || resumer.resumed[0] != execution.ID {
+			t.Errorf("Expected the delay to be continued once its claim expired, got %v", resumer.resumed)
+		}
+	})
+
+	t.Run("leaves delays whose claim expired during the batch", func(t *testing.T) {
+		first := newDelayedExecution(2 * time.Minute)
+		second := newDelayedExecution(time.Minute)
+		repo := newFakeDelayedExecutionRepo(first, second)
+		resumer := &fakeDelayResumer{repo: repo, delay: 30 * time.Millisecond}
+		worker := NewDelayWorker(repo, resumer, log, time.Minute)
+		worker.claimTTL = 20 * time.Millisecond
+
+		worker.processDueDelays(context.Background())
+		if len(resumer.resumed) != 1 || resumer.resumed[0] != first.ID {
+			t.Fatalf("Expected only the first delay to be continued, got %v", resumer.resumed)
+		}
+
+		// The skipped delay is claimed again on the next check
+		worker.processDueDelays(context.Background())
+		if len(resumer.resumed) != 2 || resumer.resumed[1] != second.ID {
+			t.Errorf("Expected the skipped delay to be continued on the next check, got %v", resumer.resumed)
+		}
+	})
+}
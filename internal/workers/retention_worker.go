@@ -0,0 +1,206 @@
+package workers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/archive"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// RetentionRepository soft-deletes expired executions and purges soft-deleted ones
+type RetentionRepository interface {
+	SoftDeleteExpiredExecutions(ctx context.Context, defaultRetentionDays, limit int) (int, error)
+	PurgeDeletedExecutions(
+		ctx context.Context,
+		purgeAfter time.Duration,
+		limit int,
+		export func(ctx context.Context, traces []models.ExecutionTraceResponse) error,
+	) (int, error)
+}
+
+// RetentionWorker enforces the execution retention policy. Completed and failed executions past
+// their organization's retention window are soft-deleted in batches; once PurgeAfter has passed
+// they are exported to the archive, when one is configured, and permanently deleted with their
+// step executions. Retention windows are read from the database on every check, so changes to an
+// organization's policy apply without a restart
+type RetentionWorker struct {
+	executionRepo RetentionRepository
+	archive       archive.Store
+	logger        *logger.Logger
+	cfg           *config.RetentionConfig
+	stopCh        chan struct{}
+	doneCh        chan struct{}
+}
+
+// NewRetentionWorker creates a new retention worker. archiveStore may be nil to purge executions
+// without exporting them
+func NewRetentionWorker(
+	executionRepo RetentionRepository,
+	archiveStore archive.Store,
+	logger *logger.Logger,
+	cfg *config.RetentionConfig,
+) *RetentionWorker {
+	return &RetentionWorker{
+		executionRepo: executionRepo,
+		archive:       archiveStore,
+		logger:        logger,
+		cfg:           cfg,
+		stopCh:        make(chan struct{}),
+		doneCh:        make(chan struct{}),
+	}
+}
+
+// Start starts the worker in the background
+func (w *RetentionWorker) Start(ctx context.Context) {
+	w.logger.Info("Starting retention worker",
+		logger.String("interval", w.cfg.CheckInterval.String()),
+		logger.Int("default_retention_days", w.cfg.Days),
+		logger.String("purge_after", w.cfg.PurgeAfter.String()),
+		logger.Bool("archive", w.archive != nil),
+	)
+
+	go w.run(ctx)
+}
+
+// Stop stops the worker gracefully
+func (w *RetentionWorker) Stop() {
+	w.logger.Info("Stopping retention worker")
+	close(w.stopCh)
+	<-w.doneCh
+	w.logger.Info("Retention worker stopped")
+}
+
+// run is the main worker loop
+func (w *RetentionWorker) run(ctx context.Context) {
+	defer close(w.doneCh)
+
+	ticker := time.NewTicker(w.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	w.enforceRetention(ctx)
+
+	for {
+		select {
+		case <-ticker.C:
+			w.enforceRetention(ctx)
+		case <-w.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stopping reports whether the worker has been asked to stop between batches
+func (w *RetentionWorker) stopping(ctx context.Context) bool {
+	select {
+	case <-w.stopCh:
+		return true
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// enforceRetention soft-deletes expired executions, then purges those whose purge delay has
+// passed, one batch at a time until none are left
+func (w *RetentionWorker) enforceRetention(ctx context.Context) {
+	w.logger.Debug("Checking for expired executions")
+
+	deleted := 0
+	for !w.stopping(ctx) {
+		count, err := w.executionRepo.SoftDeleteExpiredExecutions(ctx, w.cfg.Days, w.cfg.BatchSize)
+		if err != nil {
+			w.logger.Errorf("Failed to soft-delete expired executions: %v", err)
+			break
+		}
+		deleted += count
+		if count < w.cfg.BatchSize {
+			break
+		}
+	}
+
+	var export func(context.Context, []models.ExecutionTraceResponse) error
+	if w.archive != nil {
+		export = w.exportExecutions
+	}
+
+	purged := 0
+	for !w.stopping(ctx) {
+		count, err := w.executionRepo.PurgeDeletedExecutions(ctx, w.cfg.PurgeAfter, w.cfg.BatchSize, export)
+		if err != nil {
+			w.logger.Errorf("Failed to purge deleted executions: %v", err)
+			break
+		}
+		purged += count
+		if count < w.cfg.BatchSize {
+			break
+		}
+	}
+
+	if deleted > 0 || purged > 0 {
+		w.logger.Infof("Execution retention enforced: soft_deleted=%d, purged=%d", deleted, purged)
+	}
+}
+
+// exportExecutions writes a batch of executions to the archive as gzipped JSON lines, one object
+// per organization, each line holding an execution and its steps
+func (w *RetentionWorker) exportExecutions(ctx context.Context, traces []models.ExecutionTraceResponse) error {
+	byOrganization := make(map[uuid.UUID][]models.ExecutionTraceResponse)
+	var organizations []uuid.UUID
+	for _, trace := range traces {
+		organizationID := trace.Execution.OrganizationID
+		if _, ok := byOrganization[organizationID]; !ok {
+			organizations = append(organizations, organizationID)
+		}
+		byOrganization[organizationID] = append(byOrganization[organizationID], trace)
+	}
+
+	now := time.Now().UTC()
+	for _, organizationID := range organizations {
+		batch := byOrganization[organizationID]
+		data, err := encodeArchive(batch)
+		if err != nil {
+			return err
+		}
+
+		key := archiveKey(organizationID, now, batch[0].Execution.ID)
+		if err := w.archive.Put(ctx, key, data); err != nil {
+			return fmt.Errorf("failed to archive executions of organization %s: %w", organizationID, err)
+		}
+		w.logger.Debugf("Archived %d executions to %s", len(batch), key)
+	}
+
+	return nil
+}
+
+// archiveKey names the archive object of a batch of an organization's executions, partitioned by
+// the day they were purged
+func archiveKey(organizationID uuid.UUID, purgedAt time.Time, firstExecutionID uuid.UUID) string {
+	return fmt.Sprintf("executions/%s/%s/%s.jsonl.gz", organizationID, purgedAt.Format("2006/01/02"), firstExecutionID)
+}
+
+// encodeArchive encodes executions as gzipped JSON lines
+func encodeArchive(traces []models.ExecutionTraceResponse) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+	for _, trace := range traces {
+		if err := encoder.Encode(trace); err != nil {
+			return nil, fmt.Errorf("failed to encode execution %s: %w", trace.Execution.ID, err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,172 @@
+package workers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// fakeRetentionRepo holds expired executions, soft-deleting and purging them a batch at a time
+type fakeRetentionRepo struct {
+	expired      int
+	deleted      []models.ExecutionTraceResponse
+	purged       int
+	softDeletes  int
+	retentionArg int
+	purgeAfter   time.Duration
+}
+
+func (f *fakeRetentionRepo) SoftDeleteExpiredExecutions(ctx context.Context, defaultRetentionDays, limit int) (int, error) {
+	f.softDeletes++
+	f.retentionArg = defaultRetentionDays
+	count := min(f.expired, limit)
+	f.expired -= count
+	return count, nil
+}
+
+func (f *fakeRetentionRepo) PurgeDeletedExecutions(
+	ctx context.Context,
+	purgeAfter time.Duration,
+	limit int,
+	export func(ctx context.Context, traces []models.ExecutionTraceResponse) error,
+) (int, error) {
+	f.purgeAfter = purgeAfter
+	batch := f.deleted[:min(len(f.deleted), limit)]
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if export != nil {
+		if err := export(ctx, batch); err != nil {
+			return 0, err
+		}
+	}
+	f.deleted = f.deleted[len(batch):]
+	f.purged += len(batch)
+	return len(batch), nil
+}
+
+// fakeArchive records the objects put into it, failing when err is set
+type fakeArchive struct {
+	objects map[string][]byte
+	err     error
+}
+
+func (a *fakeArchive) Put(ctx context.Context, key string, data []byte) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.objects[key] = data
+	return nil
+}
+
+func newDeletedExecution(organizationID uuid.UUID, stepIDs ...string) models.ExecutionTraceResponse {
+	execution := &models.WorkflowExecution{ID: uuid.New(), OrganizationID: organizationID, Status: models.ExecutionStatusCompleted}
+	trace := models.ExecutionTraceResponse{Execution: execution}
+	for _, stepID := range stepIDs {
+		trace.Steps = append(trace.Steps, models.StepExecution{ExecutionID: execution.ID, StepID: stepID, Status: models.StepStatusCompleted})
+	}
+	return trace
+}
+
+// readArchive decodes an archive object's gzipped JSON lines
+func readArchive(t *testing.T, data []byte) []models.ExecutionTraceResponse {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Archive is not gzipped: %v", err)
+	}
+	var traces []models.ExecutionTraceResponse
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		var trace models.ExecutionTraceResponse
+		if err := json.Unmarshal(scanner.Bytes(), &trace); err != nil {
+			t.Fatalf("Archive line is not an execution: %v", err)
+		}
+		traces = append(traces, trace)
+	}
+	return traces
+}
+
+func TestRetentionWorker_EnforceRetention(t *testing.T) {
+	log := logger.NewForTesting()
+	cfg := &config.RetentionConfig{Days: 30, PurgeAfter: 48 * time.Hour, CheckInterval: time.Hour, BatchSize: 2}
+
+	t.Run("soft-deletes and purges in batches, archiving each organization separately", func(t *testing.T) {
+		orgA, orgB := uuid.New(), uuid.New()
+		repo := &fakeRetentionRepo{
+			expired: 5,
+			deleted: []models.ExecutionTraceResponse{
+				newDeletedExecution(orgA, "check", "notify"),
+				newDeletedExecution(orgB, "check"),
+				newDeletedExecution(orgA),
+			},
+		}
+		withSteps := repo.deleted[0].Execution.ID
+		store := &fakeArchive{objects: map[string][]byte{}}
+
+		NewRetentionWorker(repo, store, log, cfg).enforceRetention(context.Background())
+
+		if repo.expired != 0 || repo.softDeletes != 3 || repo.retentionArg != 30 {
+			t.Errorf("Expected 3 soft-delete batches with the default window, got %d batches, %d left, window %d",
+				repo.softDeletes, repo.expired, repo.retentionArg)
+		}
+		if repo.purged != 3 || repo.purgeAfter != 48*time.Hour {
+			t.Errorf("Expected every deleted execution to be purged after 48h, got %d purged after %s", repo.purged, repo.purgeAfter)
+		}
+
+		// The first batch holds one execution of each organization, the second the last one
+		if len(store.objects) != 3 {
+			t.Fatalf("Expected 3 archive objects, got %d", len(store.objects))
+		}
+		archived := map[uuid.UUID]int{}
+		for key, data := range store.objects {
+			if !strings.HasPrefix(key, "executions/") || !strings.HasSuffix(key, ".jsonl.gz") {
+				t.Errorf("Unexpected archive key %s", key)
+			}
+			for _, trace := range readArchive(t, data) {
+				if !strings.Contains(key, trace.Execution.OrganizationID.String()) {
+					t.Errorf("Execution of %s archived under %s", trace.Execution.OrganizationID, key)
+				}
+				if trace.Execution.ID == withSteps && len(trace.Steps) != 2 {
+					t.Errorf("Expected the steps to be archived with their execution, got %v", trace.Steps)
+				}
+				archived[trace.Execution.OrganizationID]++
+			}
+		}
+		if archived[orgA] != 2 || archived[orgB] != 1 {
+			t.Errorf("Expected 2 executions of A and 1 of B archived, got %v", archived)
+		}
+	})
+
+	t.Run("keeps executions the archive fails to store", func(t *testing.T) {
+		repo := &fakeRetentionRepo{deleted: []models.ExecutionTraceResponse{newDeletedExecution(uuid.New())}}
+		store := &fakeArchive{objects: map[string][]byte{}, err: errors.New("bucket unavailable")}
+
+		NewRetentionWorker(repo, store, log, cfg).enforceRetention(context.Background())
+
+		if repo.purged != 0 || len(repo.deleted) != 1 {
+			t.Errorf("Expected the execution to be kept, got %d purged", repo.purged)
+		}
+	})
+
+	t.Run("purges without exporting when no archive is configured", func(t *testing.T) {
+		repo := &fakeRetentionRepo{deleted: []models.ExecutionTraceResponse{newDeletedExecution(uuid.New())}}
+
+		NewRetentionWorker(repo, nil, log, cfg).enforceRetention(context.Background())
+
+		if repo.purged != 1 {
+			t.Errorf("Expected the execution to be purged, got %d", repo.purged)
+		}
+	})
+}
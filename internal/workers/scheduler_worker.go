@@ -2,6 +2,7 @@ package workers
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
@@ -11,21 +12,28 @@ import (
 
 // ScheduleService defines the interface for schedule operations
 type ScheduleService interface {
-	GetDueSchedules(ctx context.Context, organizationID uuid.UUID) ([]*models.WorkflowSchedule, error)
-	MarkTriggered(ctx context.Context, organizationID, id uuid.UUID) error
+	ClaimDueSchedules(ctx context.Context, claimTTL time.Duration, limit int) ([]*models.WorkflowSchedule, error)
+	DueRuns(schedule *models.WorkflowSchedule, now time.Time, limit int) ([]time.Time, int, error)
+	MarkTriggered(ctx context.Context, organizationID, id uuid.UUID, scheduledAt time.Time) error
+	ReleaseSchedule(ctx context.Context, organizationID, id uuid.UUID) error
 }
 
 // WorkflowTrigger defines the interface for triggering workflows
 type WorkflowTrigger interface {
-	TriggerWorkflowManually(ctx context.Context, organizationID, workflowID uuid.UUID, payload map[string]interface{}) (*models.WorkflowExecution, error)
+	TriggerScheduledWorkflow(ctx context.Context, organizationID, workflowID uuid.UUID, payload map[string]interface{}, catchUpFor *time.Time) (*models.WorkflowExecution, error)
 }
 
-// SchedulerWorker handles periodic checking and triggering of scheduled workflows
+// SchedulerWorker handles periodic checking and triggering of scheduled workflows. Due schedules
+// are claimed before they are triggered, so several instances can run the worker without firing
+// the same run twice
 type SchedulerWorker struct {
 	scheduleService ScheduleService
 	workflowTrigger WorkflowTrigger
 	logger          *logger.Logger
 	checkInterval   time.Duration
+	batchSize       int
+	claimTTL        time.Duration
+	maxCatchUpRuns  int
 	stopCh          chan struct{}
 	doneCh          chan struct{}
 }
@@ -36,16 +44,23 @@ func NewSchedulerWorker(
 	workflowTrigger WorkflowTrigger,
 	logger *logger.Logger,
 	checkInterval time.Duration,
+	maxCatchUpRuns int,
 ) *SchedulerWorker {
 	if checkInterval == 0 {
 		checkInterval = 1 * time.Minute // Default to 1 minute
 	}
+	if maxCatchUpRuns <= 0 {
+		maxCatchUpRuns = 10 // Default to 10 missed runs per check
+	}
 
 	return &SchedulerWorker{
 		scheduleService: scheduleService,
 		workflowTrigger: workflowTrigger,
 		logger:          logger,
 		checkInterval:   checkInterval,
+		batchSize:       50,              // Trigger up to 50 due schedules per check
+		claimTTL:        5 * time.Minute, // Claims outlive a slow synchronous run, then expire if an instance dies
+		maxCatchUpRuns:  maxCatchUpRuns,
 		stopCh:          make(chan struct{}),
 		doneCh:          make(chan struct{}),
 	}
@@ -55,6 +70,8 @@ func NewSchedulerWorker(
 func (w *SchedulerWorker) Start(ctx context.Context) {
 	w.logger.Info("Starting scheduler worker",
 		logger.String("interval", w.checkInterval.String()),
+		logger.Int("batch_size", w.batchSize),
+		logger.Int("max_catch_up_runs", w.maxCatchUpRuns),
 	)
 
 	go w.run(ctx)
@@ -90,14 +107,15 @@ func (w *SchedulerWorker) run(ctx context.Context) {
 	}
 }
 
-// processDueSchedules checks for due schedules and triggers their workflows
+// processDueSchedules claims due schedules and triggers their workflows. A schedule is only
+// advanced past a run once its workflow was triggered for it, so a run whose workflow could
+// not be triggered is retried on the next check
 func (w *SchedulerWorker) processDueSchedules(ctx context.Context) {
 	w.logger.Debug("Checking for due schedules")
 
-	// Get due schedules across all organizations (worker context)
-	schedules, err := w.scheduleService.GetDueSchedules(ctx, uuid.Nil)
+	schedules, err := w.scheduleService.ClaimDueSchedules(ctx, w.claimTTL, w.batchSize)
 	if err != nil {
-		w.logger.Errorf("Failed to get due schedules: %v", err)
+		w.logger.Errorf("Failed to claim due schedules: %v", err)
 		return
 	}
 
@@ -112,58 +130,92 @@ func (w *SchedulerWorker) processDueSchedules(ctx context.Context) {
 	errorCount := 0
 
 	for _, schedule := range schedules {
+		if err := w.triggerSchedule(ctx, schedule); err != nil {
+			w.logger.Errorf("Failed to process schedule %s: %v", schedule.ID, err)
+			errorCount++
+			continue
+		}
+		triggeredCount++
+	}
+
+	w.logger.Infof(
+		"Scheduled workflows processed: triggered=%d, errors=%d",
+		triggeredCount,
+		errorCount,
+	)
+}
+
+// triggerSchedule triggers a claimed schedule's workflow for each of its due runs, advancing the
+// schedule past every run that fired, then releases the claim. A skip schedule fires only its
+// latest due run; a catch_up schedule fires its latest maxCatchUpRuns due runs, and each run
+// but the latest is recorded as a catch-up for the time it was missed
+func (w *SchedulerWorker) triggerSchedule(ctx context.Context, schedule *models.WorkflowSchedule) error {
+	defer func() {
+		if err := w.scheduleService.ReleaseSchedule(ctx, schedule.OrganizationID, schedule.ID); err != nil {
+			w.logger.Errorf("Failed to release schedule %s: %v", schedule.ID, err)
+		}
+	}()
+
+	limit := 1
+	if schedule.MissedRunPolicy == models.MissedRunPolicyCatchUp {
+		limit = w.maxCatchUpRuns
+	}
+
+	runs, missed, err := w.scheduleService.DueRuns(schedule, time.Now(), limit)
+	if err != nil {
+		return fmt.Errorf("failed to compute due runs: %w", err)
+	}
+	if missed > 0 {
+		w.logger.Warnf("Schedule %s (policy %s) is skipping %d missed runs", schedule.ID, schedule.MissedRunPolicy, missed)
+	}
+
+	for i, run := range runs {
+		var catchUpFor *time.Time
+		if i < len(runs)-1 {
+			catchUpFor = &runs[i]
+		}
+
 		// Create payload with schedule context
 		payload := map[string]interface{}{
-			"schedule_id": schedule.ID.String(),
-			"trigger_type": "schedule",
+			"schedule_id":     schedule.ID.String(),
+			"trigger_type":    "schedule",
 			"cron_expression": schedule.CronExpression,
-			"timezone": schedule.Timezone,
+			"timezone":        schedule.Timezone,
+			"scheduled_at":    run.Format(time.RFC3339),
 		}
 
 		w.logger.Infof(
-			"Triggering scheduled workflow: org_id=%s, workflow_id=%s, schedule_id=%s, cron=%s",
+			"Triggering scheduled workflow: org_id=%s, workflow_id=%s, schedule_id=%s, cron=%s, scheduled_at=%s, catch_up=%t",
 			schedule.OrganizationID,
 			schedule.WorkflowID,
 			schedule.ID,
 			schedule.CronExpression,
+			run.Format(time.RFC3339),
+			catchUpFor != nil,
 		)
 
-		// Trigger the workflow with organization context
-		execution, err := w.workflowTrigger.TriggerWorkflowManually(ctx, schedule.OrganizationID, schedule.WorkflowID, payload)
+		// Trigger the workflow with organization context. A run that starts but fails still
+		// counts as triggered; only a workflow that could not be started is retried
+		execution, err := w.workflowTrigger.TriggerScheduledWorkflow(ctx, schedule.OrganizationID, schedule.WorkflowID, payload, catchUpFor)
+		if execution == nil {
+			return fmt.Errorf("failed to trigger workflow %s: %w", schedule.WorkflowID, err)
+		}
+
 		if err != nil {
-			w.logger.Errorf(
-				"Failed to trigger scheduled workflow %s (schedule %s): %v",
+			w.logger.Warnf("Scheduled execution %s of workflow %s failed: %v", execution.ID, schedule.WorkflowID, err)
+		} else {
+			w.logger.Infof(
+				"Successfully triggered scheduled workflow: workflow_id=%s, execution_id=%s",
 				schedule.WorkflowID,
-				schedule.ID,
-				err,
+				execution.ID,
 			)
-			errorCount++
-			continue
 		}
 
-		w.logger.Infof(
-			"Successfully triggered scheduled workflow: workflow_id=%s, execution_id=%s",
-			schedule.WorkflowID,
-			execution.ID,
-		)
-
-		// Mark schedule as triggered (this also calculates next run time)
-		if err := w.scheduleService.MarkTriggered(ctx, schedule.OrganizationID, schedule.ID); err != nil {
-			w.logger.Errorf(
-				"Failed to mark schedule %s as triggered: %v",
-				schedule.ID,
-				err,
-			)
-			errorCount++
-			continue
+		// Mark the run as triggered (this also calculates the next run time)
+		if err := w.scheduleService.MarkTriggered(ctx, schedule.OrganizationID, schedule.ID, run); err != nil {
+			return fmt.Errorf("failed to mark schedule as triggered: %w", err)
 		}
-
-		triggeredCount++
 	}
 
-	w.logger.Infof(
-		"Scheduled workflows processed: triggered=%d, errors=%d",
-		triggeredCount,
-		errorCount,
-	)
+	return nil
 }
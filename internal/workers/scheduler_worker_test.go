@@ -0,0 +1,263 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// fakeScheduleService hands each due schedule to a single claimant, like the claiming query.
+// Schedules run hourly
+type fakeScheduleService struct {
+	mu        sync.Mutex
+	schedules []*models.WorkflowSchedule
+	claimed   map[uuid.UUID]bool
+	triggered []time.Time
+	released  []uuid.UUID
+}
+
+func newFakeScheduleService(schedules ...*models.WorkflowSchedule) *fakeScheduleService {
+	return &fakeScheduleService{schedules: schedules, claimed: make(map[uuid.UUID]bool)}
+}
+
+func (f *fakeScheduleService) ClaimDueSchedules(ctx context.Context, claimTTL time.Duration, limit int) ([]*models.WorkflowSchedule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var claimed []*models.WorkflowSchedule
+	for _, schedule := range f.schedules {
+		if !f.claimed[schedule.ID] && !schedule.NextTriggerAt.After(time.Now()) && len(claimed) < limit {
+			f.claimed[schedule.ID] = true
+			copied := *schedule
+			claimed = append(claimed, &copied)
+		}
+	}
+	return claimed, nil
+}
+
+func (f *fakeScheduleService) DueRuns(schedule *models.WorkflowSchedule, now time.Time, limit int) ([]time.Time, int, error) {
+	var runs []time.Time
+	missed := 0
+	for run := *schedule.NextTriggerAt; !run.After(now); run = run.Add(time.Hour) {
+		runs = append(runs, run)
+		if len(runs) > limit {
+			runs = runs[1:]
+			missed++
+		}
+	}
+	return runs, missed, nil
+}
+
+func (f *fakeScheduleService) MarkTriggered(ctx context.Context, organizationID, id uuid.UUID, scheduledAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.triggered = append(f.triggered, scheduledAt)
+	for _, schedule := range f.schedules {
+		if schedule.ID == id {
+			next := scheduledAt.Add(time.Hour)
+			schedule.NextTriggerAt = &next
+		}
+	}
+	return nil
+}
+
+func (f *fakeScheduleService) ReleaseSchedule(ctx context.Context, organizationID, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.released = append(f.released, id)
+	delete(f.claimed, id)
+	return nil
+}
+
+type fakeWorkflowTrigger struct {
+	mu         sync.Mutex
+	calls      []map[string]interface{}
+	catchUpFor []*time.Time
+	failFor    uuid.UUID // workflow that cannot be started
+	runFails   bool      // executions start but fail
+}
+
+func (f *fakeWorkflowTrigger) TriggerScheduledWorkflow(ctx context.Context, organizationID, workflowID uuid.UUID, payload map[string]interface{}, catchUpFor *time.Time) (*models.WorkflowExecution, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if workflowID == f.failFor {
+		return nil, errors.New("workflow is disabled")
+	}
+	f.calls = append(f.calls, payload)
+	f.catchUpFor = append(f.catchUpFor, catchUpFor)
+
+	execution := &models.WorkflowExecution{ID: uuid.New(), WorkflowID: workflowID}
+	if f.runFails {
+		return execution, errors.New("workflow execution failed")
+	}
+	return execution, nil
+}
+
+// newDueSchedule returns an hourly schedule whose next run fell due the given time ago
+func newDueSchedule(overdue time.Duration, policy models.MissedRunPolicy) *models.WorkflowSchedule {
+	due := time.Now().Add(-overdue).Truncate(time.Second)
+	return &models.WorkflowSchedule{
+		ID:              uuid.New(),
+		OrganizationID:  uuid.New(),
+		WorkflowID:      uuid.New(),
+		CronExpression:  "0 * * * *",
+		Timezone:        "UTC",
+		Enabled:         true,
+		NextTriggerAt:   &due,
+		MissedRunPolicy: policy,
+	}
+}
+
+func TestSchedulerWorker_ProcessDueSchedules(t *testing.T) {
+	log := logger.NewForTesting()
+
+	t.Run("triggers and advances due schedules", func(t *testing.T) {
+		schedule := newDueSchedule(time.Minute, models.MissedRunPolicySkip)
+		due := *schedule.NextTriggerAt
+		service := newFakeScheduleService(schedule)
+		trigger := &fakeWorkflowTrigger{}
+		worker := NewSchedulerWorker(service, trigger, log, time.Minute, 10)
+
+		worker.processDueSchedules(context.Background())
+
+		if len(trigger.calls) != 1 {
+			t.Fatalf("Expected one trigger, got %d", len(trigger.calls))
+		}
+		payload := trigger.calls[0]
+		if payload["trigger_type"] != "schedule" || payload["schedule_id"] != schedule.ID.String() {
+			t.Errorf("Expected schedule trigger payload, got %v", payload)
+		}
+		if payload["scheduled_at"] != due.Format(time.RFC3339) {
+			t.Errorf("Expected scheduled_at %s, got %v", due.Format(time.RFC3339), payload["scheduled_at"])
+		}
+		if len(service.triggered) != 1 || !service.triggered[0].Equal(due) {
+			t.Errorf("Expected the run at %s to be marked triggered, got %v", due, service.triggered)
+		}
+		if len(service.released) != 1 {
+			t.Errorf("Expected the claim to be released, got %v", service.released)
+		}
+		if trigger.catchUpFor[0] != nil {
+			t.Errorf("Expected an on-time run not to be a catch-up, got %v", trigger.catchUpFor[0])
+		}
+	})
+
+	t.Run("does not advance a schedule whose workflow could not be triggered", func(t *testing.T) {
+		schedule := newDueSchedule(time.Minute, models.MissedRunPolicySkip)
+		service := newFakeScheduleService(schedule)
+		trigger := &fakeWorkflowTrigger{failFor: schedule.WorkflowID}
+		worker := NewSchedulerWorker(service, trigger, log, time.Minute, 10)
+
+		worker.processDueSchedules(context.Background())
+
+		if len(service.triggered) != 0 {
+			t.Errorf("Expected the schedule not to be advanced, got %v", service.triggered)
+		}
+		if len(service.released) != 1 || service.released[0] != schedule.ID {
+			t.Errorf("Expected the claim to be released, got %v", service.released)
+		}
+
+		// The released schedule is retried on the next check
+		trigger.failFor = uuid.Nil
+		worker.processDueSchedules(context.Background())
+
+		if len(service.triggered) != 1 {
+			t.Errorf("Expected the schedule to be triggered on retry, got %v", service.triggered)
+		}
+	})
+
+	t.Run("advances a schedule whose run failed", func(t *testing.T) {
+		service := newFakeScheduleService(newDueSchedule(time.Minute, models.MissedRunPolicySkip))
+		worker := NewSchedulerWorker(service, &fakeWorkflowTrigger{runFails: true}, log, time.Minute, 10)
+
+		worker.processDueSchedules(context.Background())
+
+		if len(service.triggered) != 1 {
+			t.Errorf("Expected the failed run not to be retried, got triggered=%v", service.triggered)
+		}
+	})
+
+	t.Run("skip policy fires the latest missed run once", func(t *testing.T) {
+		schedule := newDueSchedule(3*time.Hour+time.Minute, models.MissedRunPolicySkip)
+		latest := schedule.NextTriggerAt.Add(3 * time.Hour).Format(time.RFC3339)
+		service := newFakeScheduleService(schedule)
+		trigger := &fakeWorkflowTrigger{}
+		worker := NewSchedulerWorker(service, trigger, log, time.Minute, 10)
+
+		worker.processDueSchedules(context.Background())
+
+		if len(trigger.calls) != 1 || trigger.calls[0]["scheduled_at"] != latest {
+			t.Fatalf("Expected one run scheduled at %s, got %v", latest, trigger.calls)
+		}
+		if trigger.catchUpFor[0] != nil {
+			t.Errorf("Expected the latest run not to be a catch-up, got %v", trigger.catchUpFor[0])
+		}
+	})
+
+	t.Run("catch_up policy fires every missed run", func(t *testing.T) {
+		schedule := newDueSchedule(3*time.Hour+time.Minute, models.MissedRunPolicyCatchUp)
+		first := *schedule.NextTriggerAt
+		service := newFakeScheduleService(schedule)
+		trigger := &fakeWorkflowTrigger{}
+		worker := NewSchedulerWorker(service, trigger, log, time.Minute, 10)
+
+		worker.processDueSchedules(context.Background())
+
+		if len(trigger.calls) != 4 || len(service.triggered) != 4 {
+			t.Fatalf("Expected 4 runs, got %d triggered and %d marked", len(trigger.calls), len(service.triggered))
+		}
+		for i, catchUpFor := range trigger.catchUpFor[:3] {
+			missed := first.Add(time.Duration(i) * time.Hour)
+			if catchUpFor == nil || !catchUpFor.Equal(missed) {
+				t.Errorf("Expected run %d to catch up the run missed at %s, got %v", i, missed, catchUpFor)
+			}
+		}
+		if trigger.catchUpFor[3] != nil {
+			t.Errorf("Expected the latest run not to be a catch-up, got %v", trigger.catchUpFor[3])
+		}
+	})
+
+	t.Run("catch_up runs are capped", func(t *testing.T) {
+		schedule := newDueSchedule(5*time.Hour+time.Minute, models.MissedRunPolicyCatchUp)
+		service := newFakeScheduleService(schedule)
+		trigger := &fakeWorkflowTrigger{}
+		worker := NewSchedulerWorker(service, trigger, log, time.Minute, 2)
+
+		worker.processDueSchedules(context.Background())
+
+		if len(trigger.calls) != 2 {
+			t.Fatalf("Expected 2 runs, got %d", len(trigger.calls))
+		}
+		if schedule.NextTriggerAt.Before(time.Now()) {
+			t.Errorf("Expected the schedule to be advanced past the skipped runs, next run is %s", schedule.NextTriggerAt)
+		}
+	})
+
+	t.Run("concurrent workers fire each run once", func(t *testing.T) {
+		service := newFakeScheduleService(newDueSchedule(time.Minute, models.MissedRunPolicySkip), newDueSchedule(time.Minute, models.MissedRunPolicySkip), newDueSchedule(time.Minute, models.MissedRunPolicySkip))
+		trigger := &fakeWorkflowTrigger{}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			worker := NewSchedulerWorker(service, trigger, log, time.Minute, 10)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				worker.processDueSchedules(context.Background())
+			}()
+		}
+		wg.Wait()
+
+		if len(trigger.calls) != 3 {
+			t.Errorf("Expected 3 triggers, got %d", len(trigger.calls))
+		}
+	})
+}
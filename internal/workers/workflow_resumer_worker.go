@@ -2,8 +2,10 @@ package workers
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/davidmoltin/intelligent-workflows/internal/engine"
 	"github.com/davidmoltin/intelligent-workflows/internal/services"
 	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
 )
@@ -78,7 +80,8 @@ func (w *WorkflowResumerWorker) run(ctx context.Context) {
 	}
 }
 
-// processPausedExecutions checks for paused executions and resumes those ready
+// processPausedExecutions checks for paused executions, expiring those paused too long and
+// resuming those whose approval has been approved or rejected
 func (w *WorkflowResumerWorker) processPausedExecutions(ctx context.Context) {
 	w.logger.Debug("Checking for paused executions ready to resume")
 
@@ -97,53 +100,54 @@ func (w *WorkflowResumerWorker) processPausedExecutions(ctx context.Context) {
 	w.logger.Infof("Found %d paused executions to process", len(executions))
 
 	resumedCount := 0
+	expiredCount := 0
 	skippedCount := 0
 	errorCount := 0
 
 	for _, execution := range executions {
-		// Check if execution has approval decision in resume_data
-		if execution.ResumeData != nil {
-			if approved, exists := execution.ResumeData["approved"]; exists {
-				// This execution has an approval decision, try to resume it
-				approvedBool, ok := approved.(bool)
-				if !ok {
-					w.logger.Warnf("Invalid approval decision type for execution %s", execution.ID)
-					errorCount++
-					continue
-				}
-
-				w.logger.Infof("Auto-resuming execution %s (approved: %v)", execution.ID, approvedBool)
-
-				if err := w.workflowResumer.ResumeWorkflow(ctx, execution.ID, approvedBool); err != nil {
-					w.logger.Errorf("Failed to resume execution %s: %v", execution.ID, err)
-					errorCount++
-					continue
-				}
+		// Fail executions paused longer than their max paused duration instead of leaving them paused
+		expired, err := w.workflowResumer.ExpireExecution(ctx, execution)
+		if err != nil {
+			w.logger.Errorf("Failed to expire execution %s: %v", execution.ID, err)
+			errorCount++
+			continue
+		}
+		if expired {
+			expiredCount++
+			continue
+		}
 
+		// Resume executions paused for an approval once the approval is decided
+		if execution.ApprovalID != nil {
+			// Resuming clears the execution's approval, so keep it for logging
+			approvalID := *execution.ApprovalID
+			err := w.workflowResumer.ResumeFromApproval(ctx, execution)
+			switch {
+			case err == nil:
 				resumedCount++
 				continue
+			case errors.Is(err, services.ErrApprovalNotDecided):
+				// Still waiting for the approval
+			case errors.Is(err, engine.ErrConcurrencyLimitReached), errors.Is(err, engine.ErrRateLimited):
+				// Left paused until its workflow's limits allow it to run
+				w.logger.Infof("Execution %s left paused by its workflow's limits: %v", execution.ID, err)
+			default:
+				w.logger.Errorf("Failed to resume execution %s from approval %s: %v", execution.ID, approvalID, err)
+				errorCount++
+				continue
 			}
-		}
-
-		// Check if execution has been paused for too long (warn but don't auto-resume)
-		if execution.PausedAt != nil {
-			pauseDuration := time.Since(*execution.PausedAt)
-			if pauseDuration > 24*time.Hour {
-				w.logger.Warnf(
-					"Execution %s has been paused for %v (reason: %s) - may need manual intervention",
-					execution.ID,
-					pauseDuration.Round(time.Hour),
-					stringOrEmpty(execution.PausedReason),
-				)
-			}
+		} else if _, exists := execution.ResumeData["approved"]; exists {
+			// A decision that is not tied to an approval could belong to another execution
+			w.logger.Warnf("Execution %s has an approval decision but no linked approval, not auto-resuming", execution.ID)
 		}
 
 		skippedCount++
 	}
 
 	w.logger.Infof(
-		"Paused executions processed: resumed=%d, skipped=%d, errors=%d",
+		"Paused executions processed: resumed=%d, expired=%d, skipped=%d, errors=%d",
 		resumedCount,
+		expiredCount,
 		skippedCount,
 		errorCount,
 	)
@@ -2,6 +2,7 @@ package workers
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -25,12 +26,12 @@ func (m *MockExecutionRepository) CreateExecution(ctx context.Context, execution
 	return args.Error(0)
 }
 
-func (m *MockExecutionRepository) UpdateExecution(ctx context.Context, execution *models.WorkflowExecution) error {
+func (m *MockExecutionRepository) UpdateExecution(ctx context.Context, organizationID uuid.UUID, execution *models.WorkflowExecution) error {
 	args := m.Called(ctx, execution)
 	return args.Error(0)
 }
 
-func (m *MockExecutionRepository) GetExecutionByID(ctx context.Context, id uuid.UUID) (*models.WorkflowExecution, error) {
+func (m *MockExecutionRepository) GetExecutionByID(ctx context.Context, organizationID, id uuid.UUID) (*models.WorkflowExecution, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -38,7 +39,7 @@ func (m *MockExecutionRepository) GetExecutionByID(ctx context.Context, id uuid.
 	return args.Get(0).(*models.WorkflowExecution), args.Error(1)
 }
 
-func (m *MockExecutionRepository) GetPausedExecutions(ctx context.Context, limit int) ([]*models.WorkflowExecution, error) {
+func (m *MockExecutionRepository) GetPausedExecutions(ctx context.Context, organizationID uuid.UUID, limit int) ([]*models.WorkflowExecution, error) {
 	args := m.Called(ctx, limit)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -56,6 +57,24 @@ func (m *MockWorkflowEngine) ResumePausedExecution(ctx context.Context, executio
 	return args.Error(0)
 }
 
+// fakeApprovalLookup serves approvals executions are paused for from memory
+type fakeApprovalLookup map[uuid.UUID]*models.ApprovalRequest
+
+func (f fakeApprovalLookup) GetApprovalByID(ctx context.Context, organizationID, id uuid.UUID) (*models.ApprovalRequest, error) {
+	approval, ok := f[id]
+	if !ok {
+		return nil, errors.New("approval not found")
+	}
+	return approval, nil
+}
+
+// link pauses an execution for a new approval with the given status
+func (f fakeApprovalLookup) link(execution *models.WorkflowExecution, status models.ApprovalStatus) {
+	approval := &models.ApprovalRequest{ID: uuid.New(), ExecutionID: execution.ID, Status: status}
+	f[approval.ID] = approval
+	execution.ApprovalID = &approval.ID
+}
+
 func TestNewWorkflowResumerWorker(t *testing.T) {
 	log, err := logger.New("info", "json")
 	require.NoError(t, err)
@@ -98,7 +117,7 @@ func TestWorkflowResumerWorker_StartStop(t *testing.T) {
 	// Setup mock to return no paused executions
 	mockRepo.On("GetPausedExecutions", mock.Anything, 50).Return([]*models.WorkflowExecution{}, nil)
 
-	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 	checkInterval := 100 * time.Millisecond // Short interval for testing
 
 	worker := NewWorkflowResumerWorker(resumer, log, checkInterval)
@@ -138,7 +157,7 @@ func TestWorkflowResumerWorker_ProcessPausedExecutions_NoExecutions(t *testing.T
 	// Setup mock to return no paused executions
 	mockRepo.On("GetPausedExecutions", ctx, 50).Return([]*models.WorkflowExecution{}, nil)
 
-	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 	worker := NewWorkflowResumerWorker(resumer, log, 1*time.Minute)
 
 	// Process paused executions
@@ -169,19 +188,19 @@ func TestWorkflowResumerWorker_ProcessPausedExecutions_WithApproval(t *testing.T
 		StartedAt:    time.Now().Add(-2 * time.Hour),
 		PausedAt:     &pausedAt,
 		PausedReason: &pausedReason,
-		ResumeData: models.JSONB{
-			"approved": true,
-		},
-		ResumeCount: 0,
+		ResumeCount:  0,
 	}
 
 	// Setup mock expectations
 	mockRepo.On("GetPausedExecutions", ctx, 50).Return([]*models.WorkflowExecution{execution}, nil)
-	mockRepo.On("GetExecutionByID", ctx, executionID).Return(execution, nil)
 	mockRepo.On("UpdateExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
 	mockEngine.On("ResumePausedExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
 
-	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine)
+	approvals := fakeApprovalLookup{}
+	approvals.link(execution, models.ApprovalStatusApproved)
+
+	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine, nil)
+	resumer.SetApprovalLookup(approvals)
 	worker := NewWorkflowResumerWorker(resumer, log, 1*time.Minute)
 
 	// Process paused executions
@@ -213,19 +232,19 @@ func TestWorkflowResumerWorker_ProcessPausedExecutions_WithRejection(t *testing.
 		StartedAt:    time.Now().Add(-2 * time.Hour),
 		PausedAt:     &pausedAt,
 		PausedReason: &pausedReason,
-		ResumeData: models.JSONB{
-			"approved": false,
-		},
-		ResumeCount: 0,
+		ResumeCount:  0,
 	}
 
 	// Setup mock expectations
 	mockRepo.On("GetPausedExecutions", ctx, 50).Return([]*models.WorkflowExecution{execution}, nil)
-	mockRepo.On("GetExecutionByID", ctx, executionID).Return(execution, nil)
 	mockRepo.On("UpdateExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
 	mockEngine.On("ResumePausedExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
 
-	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine)
+	approvals := fakeApprovalLookup{}
+	approvals.link(execution, models.ApprovalStatusRejected)
+
+	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine, nil)
+	resumer.SetApprovalLookup(approvals)
 	worker := NewWorkflowResumerWorker(resumer, log, 1*time.Minute)
 
 	// Process paused executions
@@ -264,7 +283,7 @@ func TestWorkflowResumerWorker_ProcessPausedExecutions_NoApprovalDecision(t *tes
 	// Setup mock expectations - should NOT call ResumeWorkflow
 	mockRepo.On("GetPausedExecutions", ctx, 50).Return([]*models.WorkflowExecution{execution}, nil)
 
-	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 	worker := NewWorkflowResumerWorker(resumer, log, 1*time.Minute)
 
 	// Process paused executions - should skip this execution
@@ -304,7 +323,7 @@ func TestWorkflowResumerWorker_ProcessPausedExecutions_LongPause(t *testing.T) {
 	// Setup mock expectations
 	mockRepo.On("GetPausedExecutions", ctx, 50).Return([]*models.WorkflowExecution{execution}, nil)
 
-	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 	worker := NewWorkflowResumerWorker(resumer, log, 1*time.Minute)
 
 	// Process paused executions - should warn about long pause
@@ -335,7 +354,6 @@ func TestWorkflowResumerWorker_ProcessPausedExecutions_MultipleExecutions(t *tes
 			StartedAt:    time.Now().Add(-2 * time.Hour),
 			PausedAt:     &pausedAt,
 			PausedReason: &pausedReason,
-			ResumeData:   models.JSONB{"approved": true},
 			ResumeCount:  0,
 		},
 		{
@@ -345,7 +363,6 @@ func TestWorkflowResumerWorker_ProcessPausedExecutions_MultipleExecutions(t *tes
 			StartedAt:    time.Now().Add(-2 * time.Hour),
 			PausedAt:     &pausedAt,
 			PausedReason: &pausedReason,
-			ResumeData:   models.JSONB{"approved": false},
 			ResumeCount:  0,
 		},
 		{
@@ -365,12 +382,16 @@ func TestWorkflowResumerWorker_ProcessPausedExecutions_MultipleExecutions(t *tes
 
 	// First two should be resumed
 	for i := 0; i < 2; i++ {
-		mockRepo.On("GetExecutionByID", ctx, executions[i].ID).Return(executions[i], nil)
 		mockRepo.On("UpdateExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
 		mockEngine.On("ResumePausedExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
 	}
 
-	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine)
+	approvals := fakeApprovalLookup{}
+	approvals.link(executions[0], models.ApprovalStatusApproved)
+	approvals.link(executions[1], models.ApprovalStatusRejected)
+
+	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine, nil)
+	resumer.SetApprovalLookup(approvals)
 	worker := NewWorkflowResumerWorker(resumer, log, 1*time.Minute)
 
 	// Process paused executions
@@ -401,22 +422,69 @@ func TestWorkflowResumerWorker_ProcessPausedExecutions_ResumeError(t *testing.T)
 		StartedAt:    time.Now().Add(-2 * time.Hour),
 		PausedAt:     &pausedAt,
 		PausedReason: &pausedReason,
-		ResumeData:   models.JSONB{"approved": true},
 		ResumeCount:  0,
 	}
 
 	// Setup mock expectations - resume will fail
 	mockRepo.On("GetPausedExecutions", ctx, 50).Return([]*models.WorkflowExecution{execution}, nil)
-	mockRepo.On("GetExecutionByID", ctx, executionID).Return(execution, nil)
 	mockRepo.On("UpdateExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(assert.AnError)
 
-	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine)
+	approvals := fakeApprovalLookup{}
+	approvals.link(execution, models.ApprovalStatusApproved)
+
+	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine, nil)
+	resumer.SetApprovalLookup(approvals)
 	worker := NewWorkflowResumerWorker(resumer, log, 1*time.Minute)
 
-	// Process paused executions - should handle error gracefully
-	worker.processPausedExecutions(ctx)
+	// Process paused executions - should handle error gracefully, even though the failed resume
+	// cleared the execution's approval
+	assert.NotPanics(t, func() { worker.processPausedExecutions(ctx) })
+
+	mockRepo.AssertExpectations(t)
+	mockEngine.AssertNotCalled(t, "ResumePausedExecution", mock.Anything, mock.Anything)
+}
+
+func TestWorkflowResumerWorker_ProcessPausedExecutions_EngineResumeError(t *testing.T) {
+	log, err := logger.New("info", "json")
+	require.NoError(t, err)
+
+	// Create mock execution repository and engine
+	mockRepo := new(MockExecutionRepository)
+	mockEngine := new(MockWorkflowEngine)
+
+	ctx := context.Background()
+
+	pausedAt := time.Now().Add(-1 * time.Hour)
+	pausedReason := "waiting for approval"
+
+	execution := &models.WorkflowExecution{
+		ID:           uuid.New(),
+		WorkflowID:   uuid.New(),
+		Status:       models.ExecutionStatusPaused,
+		StartedAt:    time.Now().Add(-2 * time.Hour),
+		PausedAt:     &pausedAt,
+		PausedReason: &pausedReason,
+		ResumeCount:  0,
+	}
+
+	// Setup mock expectations - the execution is saved as running, then the engine fails
+	mockRepo.On("GetPausedExecutions", ctx, 50).Return([]*models.WorkflowExecution{execution}, nil)
+	mockRepo.On("UpdateExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
+	mockEngine.On("ResumePausedExecution", ctx, mock.AnythingOfType("*models.WorkflowExecution")).Return(assert.AnError)
+
+	approvals := fakeApprovalLookup{}
+	approvals.link(execution, models.ApprovalStatusApproved)
+
+	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine, nil)
+	resumer.SetApprovalLookup(approvals)
+	worker := NewWorkflowResumerWorker(resumer, log, 1*time.Minute)
+
+	// Process paused executions - should log the failure without panicking
+	assert.NotPanics(t, func() { worker.processPausedExecutions(ctx) })
 
 	mockRepo.AssertExpectations(t)
+	mockEngine.AssertExpectations(t)
+	assert.Nil(t, execution.ApprovalID, "resuming should clear the execution's approval")
 }
 
 func TestWorkflowResumerWorker_ProcessPausedExecutions_GetError(t *testing.T) {
@@ -432,7 +500,7 @@ func TestWorkflowResumerWorker_ProcessPausedExecutions_GetError(t *testing.T) {
 	// Setup mock expectations - get will fail
 	mockRepo.On("GetPausedExecutions", ctx, 50).Return(nil, assert.AnError)
 
-	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 	worker := NewWorkflowResumerWorker(resumer, log, 1*time.Minute)
 
 	// Process paused executions - should handle error gracefully
@@ -452,7 +520,7 @@ func TestWorkflowResumerWorker_ContextCancellation(t *testing.T) {
 	// Setup mock to return no paused executions
 	mockRepo.On("GetPausedExecutions", mock.Anything, 50).Return([]*models.WorkflowExecution{}, nil)
 
-	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 	checkInterval := 100 * time.Millisecond
 
 	worker := NewWorkflowResumerWorker(resumer, log, checkInterval)
@@ -509,7 +577,7 @@ func TestWorkflowResumerWorker_InvalidApprovalType(t *testing.T) {
 	// Setup mock expectations - should NOT call ResumeWorkflow
 	mockRepo.On("GetPausedExecutions", ctx, 50).Return([]*models.WorkflowExecution{execution}, nil)
 
-	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine)
+	resumer := services.NewWorkflowResumer(log, mockRepo, mockEngine, nil)
 	worker := NewWorkflowResumerWorker(resumer, log, 1*time.Minute)
 
 	// Process paused executions - should skip this execution due to invalid type
@@ -0,0 +1,6 @@
+-- Remove idempotency index
+DROP INDEX IF EXISTS idx_executions_idempotency;
+
+-- Remove idempotency column
+ALTER TABLE workflow_executions
+DROP COLUMN IF EXISTS idempotency_key;
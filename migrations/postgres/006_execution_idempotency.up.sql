@@ -0,0 +1,10 @@
+-- Add idempotency key to workflow_executions so duplicate deliveries reuse the original execution
+ALTER TABLE workflow_executions
+ADD COLUMN idempotency_key VARCHAR(255);
+
+-- Enforce one execution per key for each workflow within an organization
+CREATE UNIQUE INDEX idx_executions_idempotency ON workflow_executions(organization_id, workflow_id, idempotency_key)
+WHERE idempotency_key IS NOT NULL;
+
+-- Add comment for documentation
+COMMENT ON COLUMN workflow_executions.idempotency_key IS 'Caller- or trigger-supplied key that deduplicates executions of a workflow';
@@ -0,0 +1,2 @@
+-- Remove keyset pagination index
+DROP INDEX IF EXISTS idx_executions_org_started_id;
@@ -0,0 +1,2 @@
+-- Support keyset pagination of executions ordered by (started_at, id) within an organization
+CREATE INDEX idx_executions_org_started_id ON workflow_executions(organization_id, started_at DESC, id DESC);
@@ -0,0 +1,2 @@
+-- Remove trigger event listing index
+DROP INDEX IF EXISTS idx_executions_org_trigger_started;
@@ -0,0 +1,2 @@
+-- Support listing an organization's executions for a trigger event within a time window
+CREATE INDEX idx_executions_org_trigger_started ON workflow_executions(organization_id, trigger_event, started_at DESC);
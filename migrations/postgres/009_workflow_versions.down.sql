@@ -0,0 +1,10 @@
+-- Remove execution definition version
+ALTER TABLE workflow_executions
+DROP COLUMN IF EXISTS workflow_version;
+
+-- Remove current definition version
+ALTER TABLE workflows
+DROP COLUMN IF EXISTS definition_version;
+
+-- Remove definition history
+DROP TABLE IF EXISTS workflow_versions;
@@ -0,0 +1,30 @@
+-- Immutable history of workflow definitions, one row per create or definition update
+CREATE TABLE workflow_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    workflow_id UUID NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    version_number INTEGER NOT NULL,
+    definition JSONB NOT NULL,
+    created_by UUID,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(workflow_id, version_number)
+);
+
+CREATE INDEX idx_workflow_versions_org_workflow ON workflow_versions(organization_id, workflow_id, version_number DESC);
+
+-- Number of the workflow's current definition in workflow_versions
+ALTER TABLE workflows
+ADD COLUMN definition_version INTEGER NOT NULL DEFAULT 1;
+
+-- Record existing definitions as their workflow's first version
+INSERT INTO workflow_versions (organization_id, workflow_id, version_number, definition, created_by, created_at)
+SELECT organization_id, id, 1, definition, created_by, updated_at
+FROM workflows;
+
+-- Definition version each execution ran against
+ALTER TABLE workflow_executions
+ADD COLUMN workflow_version INTEGER;
+
+-- Add comments for documentation
+COMMENT ON COLUMN workflows.definition_version IS 'Version number of the current definition in workflow_versions';
+COMMENT ON COLUMN workflow_executions.workflow_version IS 'Definition version (workflow_versions.version_number) the execution ran against';
@@ -0,0 +1,3 @@
+-- Remove schedule claim lease
+ALTER TABLE workflow_schedules
+DROP COLUMN IF EXISTS claimed_until;
@@ -0,0 +1,5 @@
+-- Lease taken by a scheduler instance while it triggers a due schedule, so that
+-- concurrent instances do not fire the same run twice
+ALTER TABLE workflow_schedules ADD COLUMN claimed_until TIMESTAMP;
+
+COMMENT ON COLUMN workflow_schedules.claimed_until IS 'Time until which a scheduler instance holds the schedule while triggering it';
@@ -0,0 +1,3 @@
+-- Remove missed run policy
+ALTER TABLE workflow_schedules
+DROP COLUMN IF EXISTS missed_run_policy;
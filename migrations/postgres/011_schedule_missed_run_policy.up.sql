@@ -0,0 +1,5 @@
+-- How a schedule handles runs missed while no scheduler was running
+ALTER TABLE workflow_schedules ADD COLUMN missed_run_policy VARCHAR(20) NOT NULL DEFAULT 'skip'
+    CONSTRAINT valid_missed_run_policy CHECK (missed_run_policy IN ('skip', 'catch_up'));
+
+COMMENT ON COLUMN workflow_schedules.missed_run_policy IS 'skip fires once for the latest missed run; catch_up fires for every missed run, up to a configured maximum';
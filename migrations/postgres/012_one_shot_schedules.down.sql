@@ -0,0 +1,8 @@
+-- Remove one-shot schedules
+DELETE FROM workflow_schedules WHERE run_at IS NOT NULL;
+
+ALTER TABLE workflow_schedules DROP CONSTRAINT IF EXISTS cron_or_run_at;
+ALTER TABLE workflow_schedules ALTER COLUMN cron_expression SET NOT NULL;
+
+ALTER TABLE workflow_schedules
+DROP COLUMN IF EXISTS run_at;
@@ -0,0 +1,13 @@
+-- One-shot schedules run once at run_at instead of on a cron expression
+ALTER TABLE workflow_schedules ADD COLUMN run_at TIMESTAMP;
+
+ALTER TABLE workflow_schedules ALTER COLUMN cron_expression DROP NOT NULL;
+ALTER TABLE workflow_schedules DROP CONSTRAINT valid_cron;
+ALTER TABLE workflow_schedules ADD CONSTRAINT valid_cron
+    CHECK (cron_expression IS NULL OR cron_expression ~ '^[@0-9*/,-]+(\s+[@0-9*/,-]+){4,5}$');
+
+-- Every schedule is either recurring or one-shot
+ALTER TABLE workflow_schedules ADD CONSTRAINT cron_or_run_at
+    CHECK ((cron_expression IS NULL) <> (run_at IS NULL));
+
+COMMENT ON COLUMN workflow_schedules.run_at IS 'Time a one-shot schedule runs at; the schedule is disabled once it has run';
@@ -0,0 +1,17 @@
+-- Shared secrets used to verify the HMAC signature of inbound events, per
+-- organization and event source. The source '*' applies to every source of the
+-- organization without a secret of its own
+CREATE TABLE webhook_secrets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    source VARCHAR(100) NOT NULL,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_webhook_secret_source UNIQUE (organization_id, source)
+);
+
+CREATE TRIGGER update_webhook_secrets_updated_at BEFORE UPDATE ON webhook_secrets
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE webhook_secrets IS 'Secrets for verifying the X-Signature header of inbound events';
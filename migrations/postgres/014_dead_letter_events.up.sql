@@ -0,0 +1,22 @@
+-- Events that could not be processed, kept with their payload so they can be
+-- replayed. workflow_id is NULL when the event failed before it was routed to a
+-- workflow, in which case a replay routes it again
+CREATE TABLE dead_letter_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    event_id UUID REFERENCES events(id) ON DELETE SET NULL,
+    event_type VARCHAR(255) NOT NULL,
+    source VARCHAR(255),
+    payload JSONB NOT NULL,
+    workflow_id UUID REFERENCES workflows(id) ON DELETE SET NULL,
+    reason VARCHAR(20) NOT NULL,
+    error TEXT NOT NULL,
+    failed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    replayed_at TIMESTAMP,
+    CONSTRAINT valid_dead_letter_reason CHECK (reason IN ('panic', 'execution_error', 'routing_error'))
+);
+
+CREATE INDEX idx_dead_letter_events_org ON dead_letter_events(organization_id, failed_at DESC);
+CREATE INDEX idx_dead_letter_events_pending ON dead_letter_events(organization_id) WHERE replayed_at IS NULL;
+
+COMMENT ON TABLE dead_letter_events IS 'Events whose routing or workflow execution failed before an execution was recorded';
@@ -0,0 +1,9 @@
+-- Remove escalation index
+DROP INDEX IF EXISTS idx_approvals_next_escalation;
+
+-- Remove escalation columns
+ALTER TABLE approval_requests
+DROP COLUMN IF EXISTS escalation_chain,
+DROP COLUMN IF EXISTS escalation_level,
+DROP COLUMN IF EXISTS next_escalation_at,
+DROP COLUMN IF EXISTS escalations;
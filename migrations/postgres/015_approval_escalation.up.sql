@@ -0,0 +1,16 @@
+-- Add escalation columns to approval_requests table
+ALTER TABLE approval_requests
+ADD COLUMN escalation_chain JSONB NOT NULL DEFAULT '[]',
+ADD COLUMN escalation_level INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN next_escalation_at TIMESTAMP,
+ADD COLUMN escalations JSONB NOT NULL DEFAULT '[]';
+
+-- Add index for finding approvals due to escalate
+CREATE INDEX idx_approvals_next_escalation ON approval_requests(next_escalation_at)
+WHERE next_escalation_at IS NOT NULL AND status = 'pending';
+
+-- Add comments for documentation
+COMMENT ON COLUMN approval_requests.escalation_chain IS 'Ordered levels the approval escalates through while undecided';
+COMMENT ON COLUMN approval_requests.escalation_level IS 'Number of escalation levels reached so far';
+COMMENT ON COLUMN approval_requests.next_escalation_at IS 'When the approval escalates to the next level if still undecided';
+COMMENT ON COLUMN approval_requests.escalations IS 'History of escalations, oldest first';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS approval_decisions;
+
+ALTER TABLE approval_requests
+DROP COLUMN IF EXISTS required_approvers,
+DROP COLUMN IF EXISTS quorum;
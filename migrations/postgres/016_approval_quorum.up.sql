@@ -0,0 +1,24 @@
+-- Add quorum columns to approval_requests table. An approval with designated
+-- approvers only accepts their decisions; quorum is how many must approve
+ALTER TABLE approval_requests
+ADD COLUMN required_approvers UUID[],
+ADD COLUMN quorum INTEGER NOT NULL DEFAULT 1;
+
+-- Individual approvers' decisions, one per approver
+CREATE TABLE approval_decisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    approval_id UUID NOT NULL REFERENCES approval_requests(id) ON DELETE CASCADE,
+    approver_id UUID NOT NULL,
+    decision VARCHAR(20) NOT NULL,
+    reason TEXT,
+    decided_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_approval_decision UNIQUE (approval_id, approver_id),
+    CONSTRAINT valid_approval_decision CHECK (decision IN ('approve', 'reject'))
+);
+
+CREATE INDEX idx_approval_decisions_approval ON approval_decisions(approval_id, decided_at);
+
+COMMENT ON COLUMN approval_requests.required_approvers IS 'Users whose decisions count towards the quorum; any approver if NULL';
+COMMENT ON COLUMN approval_requests.quorum IS 'Number of approvals needed to approve the request';
+COMMENT ON TABLE approval_decisions IS 'Individual approve/reject decisions on approval requests';
@@ -0,0 +1,3 @@
+ALTER TABLE approval_decisions DROP COLUMN IF EXISTS delegate_id;
+
+DROP TABLE IF EXISTS approval_delegations;
@@ -0,0 +1,23 @@
+-- Approvers delegating their approvals to another user for a time window. A
+-- delegation applies from starts_at until ends_at
+CREATE TABLE approval_delegations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    delegator_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    delegate_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    starts_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ends_at TIMESTAMP NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT delegation_not_self CHECK (delegator_id <> delegate_id),
+    CONSTRAINT delegation_window CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX idx_approval_delegations_delegator ON approval_delegations(organization_id, delegator_id, ends_at);
+CREATE INDEX idx_approval_delegations_delegate ON approval_delegations(organization_id, delegate_id, ends_at);
+
+-- Record who decided on a designated approver's behalf
+ALTER TABLE approval_decisions ADD COLUMN delegate_id UUID;
+
+COMMENT ON TABLE approval_delegations IS 'Approvers handing their approvals to a delegate for a time window';
+COMMENT ON COLUMN approval_decisions.delegate_id IS 'Delegate who made the decision on the approver''s behalf';
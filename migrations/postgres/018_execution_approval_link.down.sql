@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_executions_approval;
+
+ALTER TABLE workflow_executions DROP COLUMN IF EXISTS approval_id;
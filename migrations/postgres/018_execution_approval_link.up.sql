@@ -0,0 +1,8 @@
+-- Link a paused execution to the approval it is waiting for
+ALTER TABLE workflow_executions
+    ADD COLUMN approval_id UUID REFERENCES approval_requests(id) ON DELETE SET NULL;
+
+-- Add index for finding the execution an approval pauses
+CREATE INDEX idx_executions_approval ON workflow_executions(approval_id) WHERE approval_id IS NOT NULL;
+
+COMMENT ON COLUMN workflow_executions.approval_id IS 'Approval the execution is paused for; it is only auto-resumed once this approval is approved or rejected';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_family;
+
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS replaced_by,
+    DROP COLUMN IF EXISTS family_id;
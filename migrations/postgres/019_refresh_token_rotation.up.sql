@@ -0,0 +1,16 @@
+-- Track refresh token rotation: every token belongs to the family started by a login, and a
+-- used token points to the token it was exchanged for
+ALTER TABLE refresh_tokens
+    ADD COLUMN family_id UUID,
+    ADD COLUMN replaced_by UUID REFERENCES refresh_tokens(id) ON DELETE SET NULL;
+
+-- Existing tokens each start their own family
+UPDATE refresh_tokens SET family_id = id WHERE family_id IS NULL;
+
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+
+-- Add index for revoking a family when reuse is detected
+CREATE INDEX idx_refresh_tokens_family ON refresh_tokens(family_id);
+
+COMMENT ON COLUMN refresh_tokens.family_id IS 'Login the token descends from; the whole family is revoked when a used token is presented again';
+COMMENT ON COLUMN refresh_tokens.replaced_by IS 'Token this one was rotated into when it was used';
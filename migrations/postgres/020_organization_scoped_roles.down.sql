@@ -0,0 +1,19 @@
+-- Collapse organization role assignments back into global ones, keeping one per user and role
+DELETE FROM user_roles a
+USING user_roles b
+WHERE a.user_id = b.user_id
+  AND a.role_id = b.role_id
+  AND a.ctid > b.ctid;
+
+DROP INDEX IF EXISTS idx_user_roles_user_organization;
+DROP INDEX IF EXISTS idx_user_roles_organization;
+DROP INDEX IF EXISTS idx_user_roles_global;
+ALTER TABLE user_roles DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE user_roles ADD CONSTRAINT user_roles_pkey PRIMARY KEY (user_id, role_id);
+
+DELETE FROM roles WHERE name = 'super_admin';
+
+DELETE FROM permissions WHERE name IN (
+    'organization:create', 'organization:read', 'organization:update',
+    'organization:delete', 'organization:manage_users', 'audit:read'
+);
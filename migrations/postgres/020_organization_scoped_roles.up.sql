@@ -0,0 +1,58 @@
+-- Scope role assignments to organizations. A user's roles now apply within one organization;
+-- assignments without an organization are global and reserved for the super_admin role
+
+-- Global super admin role
+INSERT INTO roles (name, description) VALUES
+    ('super_admin', 'Global administrator with full access to every organization')
+ON CONFLICT (name) DO UPDATE
+SET description = EXCLUDED.description,
+    updated_at = NOW();
+
+-- Organization and audit permissions used by the API
+INSERT INTO permissions (name, resource, action, description) VALUES
+    ('organization:create', 'organization', 'create', 'Create organizations'),
+    ('organization:read', 'organization', 'read', 'View organizations'),
+    ('organization:update', 'organization', 'update', 'Update organizations'),
+    ('organization:delete', 'organization', 'delete', 'Delete organizations'),
+    ('organization:manage_users', 'organization', 'manage_users', 'Manage organization members'),
+    ('audit:read', 'audit', 'read', 'View the audit log')
+ON CONFLICT (name) DO UPDATE
+SET resource = EXCLUDED.resource,
+    action = EXCLUDED.action,
+    description = EXCLUDED.description;
+
+-- Admin and super admin get all permissions
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name IN ('admin', 'super_admin')
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+
+-- Organization of each role assignment, NULL for global assignments
+ALTER TABLE user_roles ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+
+ALTER TABLE user_roles DROP CONSTRAINT user_roles_pkey;
+CREATE UNIQUE INDEX idx_user_roles_global ON user_roles(user_id, role_id) WHERE organization_id IS NULL;
+CREATE UNIQUE INDEX idx_user_roles_organization ON user_roles(user_id, organization_id, role_id) WHERE organization_id IS NOT NULL;
+CREATE INDEX idx_user_roles_user_organization ON user_roles(user_id, organization_id);
+
+-- Each member holds their organization role within the organization
+INSERT INTO user_roles (user_id, role_id, organization_id, assigned_at, assigned_by)
+SELECT ou.user_id, ou.role_id, ou.organization_id, ou.joined_at, ou.invited_by
+FROM organization_users ou
+WHERE ou.role_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+-- Existing global roles carry over into every organization the user belongs to
+INSERT INTO user_roles (user_id, role_id, organization_id, assigned_at, assigned_by)
+SELECT ur.user_id, ur.role_id, ou.organization_id, ur.assigned_at, ur.assigned_by
+FROM user_roles ur
+INNER JOIN organization_users ou ON ou.user_id = ur.user_id
+INNER JOIN roles r ON r.id = ur.role_id
+WHERE ur.organization_id IS NULL AND r.name <> 'super_admin'
+ON CONFLICT DO NOTHING;
+
+DELETE FROM user_roles ur
+USING roles r
+WHERE r.id = ur.role_id AND ur.organization_id IS NULL AND r.name <> 'super_admin';
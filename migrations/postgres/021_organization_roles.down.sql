@@ -0,0 +1,5 @@
+DELETE FROM roles WHERE organization_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_roles_organization;
+
+ALTER TABLE roles DROP COLUMN IF EXISTS organization_id;
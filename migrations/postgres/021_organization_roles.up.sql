@@ -0,0 +1,5 @@
+-- Custom roles belong to the organization that created them. Built-in roles have no
+-- organization and are shared by all
+ALTER TABLE roles ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_roles_organization ON roles(organization_id);
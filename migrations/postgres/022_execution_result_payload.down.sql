@@ -0,0 +1 @@
+ALTER TABLE workflow_executions DROP COLUMN IF EXISTS result_payload;
@@ -0,0 +1,5 @@
+-- Structured output an execution's steps chose to return, separate from its context and step trace
+ALTER TABLE workflow_executions
+    ADD COLUMN result_payload JSONB;
+
+COMMENT ON COLUMN workflow_executions.result_payload IS 'Final output of the execution, populated by response actions and set variables marked as results';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_executions_correlation;
+DROP INDEX IF EXISTS idx_events_correlation;
+
+ALTER TABLE workflow_executions DROP COLUMN IF EXISTS correlation_id;
+ALTER TABLE events DROP COLUMN IF EXISTS correlation_id;
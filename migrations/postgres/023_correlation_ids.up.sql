@@ -0,0 +1,13 @@
+-- Tie the request, event, executions and outbound calls of one business transaction together
+ALTER TABLE events
+    ADD COLUMN correlation_id VARCHAR(128) NOT NULL DEFAULT '';
+
+ALTER TABLE workflow_executions
+    ADD COLUMN correlation_id VARCHAR(128) NOT NULL DEFAULT '';
+
+-- Add indexes for finding everything recorded under a correlation ID
+CREATE INDEX idx_events_correlation ON events(organization_id, correlation_id) WHERE correlation_id <> '';
+CREATE INDEX idx_executions_correlation ON workflow_executions(organization_id, correlation_id) WHERE correlation_id <> '';
+
+COMMENT ON COLUMN events.correlation_id IS 'Correlation ID from the X-Correlation-ID header of the request or message the event arrived in, or generated at ingestion';
+COMMENT ON COLUMN workflow_executions.correlation_id IS 'Correlation ID of the event or request that started the execution; forwarded on its outbound requests';
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_step_executions_started;
@@ -0,0 +1,2 @@
+-- Serve an execution's steps page by page in the order they started
+CREATE INDEX idx_step_executions_started ON step_executions(execution_id, started_at, id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_step_executions_started;
+CREATE INDEX idx_step_executions_started ON step_executions(execution_id, started_at, id);
+
+ALTER TABLE step_executions DROP COLUMN IF EXISTS sequence;
@@ -0,0 +1,9 @@
+-- Steps of parallel and foreach runs can start within the same timestamp; a sequence assigned at
+-- creation breaks those ties so an execution's steps are always listed in the same order
+ALTER TABLE step_executions
+    ADD COLUMN sequence BIGSERIAL;
+
+DROP INDEX IF EXISTS idx_step_executions_started;
+CREATE INDEX idx_step_executions_started ON step_executions(execution_id, started_at, sequence);
+
+COMMENT ON COLUMN step_executions.sequence IS 'Monotonic creation order; orders steps that started at the same time';
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS retention_policies;
+
+DROP INDEX IF EXISTS idx_executions_deleted;
+DROP INDEX IF EXISTS idx_executions_retention;
+
+ALTER TABLE workflow_executions DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,25 @@
+-- Finished executions past their retention window are soft-deleted, hiding them from the API,
+-- and purged by the retention worker once the purge delay has passed
+ALTER TABLE workflow_executions
+    ADD COLUMN deleted_at TIMESTAMP;
+
+-- Add indexes for finding expired and purgeable executions
+CREATE INDEX idx_executions_retention ON workflow_executions(COALESCE(completed_at, started_at))
+    WHERE deleted_at IS NULL AND status IN ('completed', 'failed');
+CREATE INDEX idx_executions_deleted ON workflow_executions(deleted_at) WHERE deleted_at IS NOT NULL;
+
+COMMENT ON COLUMN workflow_executions.deleted_at IS 'When the execution was soft-deleted by the retention worker; NULL while it is retained';
+
+-- Retention windows of organizations that override the configured default
+CREATE TABLE retention_policies (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    retention_days INTEGER NOT NULL CHECK (retention_days >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_retention_policies_updated_at BEFORE UPDATE ON retention_policies
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE retention_policies IS 'Per-organization retention windows for finished executions';
+COMMENT ON COLUMN retention_policies.retention_days IS 'Days completed and failed executions are kept; 0 keeps them forever';
@@ -0,0 +1,5 @@
+DELETE FROM dead_letter_events WHERE reason = 'concurrency_limit';
+
+ALTER TABLE dead_letter_events DROP CONSTRAINT valid_dead_letter_reason;
+ALTER TABLE dead_letter_events ADD CONSTRAINT valid_dead_letter_reason
+    CHECK (reason IN ('panic', 'execution_error', 'routing_error'));
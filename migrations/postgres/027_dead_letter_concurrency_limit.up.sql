@@ -0,0 +1,5 @@
+-- Events whose execution was rejected by its workflow's concurrency limit are dead-lettered so
+-- they can be replayed once the running execution has finished
+ALTER TABLE dead_letter_events DROP CONSTRAINT valid_dead_letter_reason;
+ALTER TABLE dead_letter_events ADD CONSTRAINT valid_dead_letter_reason
+    CHECK (reason IN ('panic', 'execution_error', 'routing_error', 'concurrency_limit'));
@@ -0,0 +1,5 @@
+-- Events whose execution was dropped by an execution rate limit are dead-lettered with the
+-- reason, so they can be replayed once the downstream systems have recovered
+ALTER TABLE dead_letter_events DROP CONSTRAINT valid_dead_letter_reason;
+ALTER TABLE dead_letter_events ADD CONSTRAINT valid_dead_letter_reason
+    CHECK (reason IN ('panic', 'execution_error', 'routing_error', 'concurrency_limit', 'rate_limited'));
@@ -0,0 +1,3 @@
+-- Remove delay claim lease
+ALTER TABLE workflow_executions
+DROP COLUMN IF EXISTS delay_claimed_until;
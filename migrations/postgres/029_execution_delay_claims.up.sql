@@ -0,0 +1,5 @@
+-- Lease taken by a delay worker while it continues a due delay, so that an execution claimed by
+-- an instance that dies before continuing it is claimed again once the lease expires
+ALTER TABLE workflow_executions ADD COLUMN delay_claimed_until TIMESTAMP;
+
+COMMENT ON COLUMN workflow_executions.delay_claimed_until IS 'Time until which a delay worker instance holds the execution while continuing its delay';
@@ -0,0 +1,128 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Store writes objects to cold storage
+type Store interface {
+	// Put stores data under key, a slash-separated relative path, replacing any existing object
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// New returns the store for a URL: a file:// URL writes objects below a directory, for example
+// a mounted bucket, and an http(s):// URL PUTs them below a base URL, sending token as a bearer
+// token when it is set
+func New(rawURL, token string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("archive URL %q has no directory", rawURL)
+		}
+		return &DirStore{dir: u.Path}, nil
+	case "http", "https":
+		return &HTTPStore{
+			baseURL: strings.TrimSuffix(rawURL, "/"),
+			token:   token,
+			client:  &http.Client{Timeout: 5 * time.Minute},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive URL scheme: %q", u.Scheme)
+	}
+}
+
+// DirStore writes objects as files below a directory
+type DirStore struct {
+	dir string
+}
+
+// Put writes the object to a temporary file first, so a partially written object is never left
+// under its key
+func (s *DirStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".archive-*")
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write archive file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync archive file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close archive file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store archive file: %w", err)
+	}
+	return nil
+}
+
+// path resolves a key below the store's directory, rejecting keys that would escape it
+func (s *DirStore) path(key string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid archive key: %q", key)
+	}
+	return filepath.Join(s.dir, cleaned), nil
+}
+
+// HTTPStore uploads objects with a PUT request below a base URL, as accepted by most object
+// stores behind a gateway or with pre-authorized URLs
+type HTTPStore struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// Put uploads the object, failing on any response other than 2xx
+func (s *HTTPStore) Put(ctx context.Context, key string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.baseURL+"/"+strings.TrimPrefix(key, "/"), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create archive request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload archive: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("archive upload returned status %d", resp.StatusCode)
+	}
+	return nil
+}
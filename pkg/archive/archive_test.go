@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirStore_Put(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := New("file://"+dir, "")
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(ctx, "executions/org/2026-10-16/batch.jsonl.gz", []byte("first")))
+	require.NoError(t, store.Put(ctx, "executions/org/2026-10-16/batch.jsonl.gz", []byte("second")))
+
+	data, err := os.ReadFile(filepath.Join(dir, "executions", "org", "2026-10-16", "batch.jsonl.gz"))
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Join(dir, "executions", "org", "2026-10-16"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	for _, key := range []string{"", "../outside", "/etc/passwd"} {
+		assert.Error(t, store.Put(ctx, key, []byte("data")), "key %q", key)
+	}
+}
+
+func TestHTTPStore_Put(t *testing.T) {
+	ctx := context.Background()
+
+	var method, path, authorization, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, authorization = r.Method, r.URL.Path, r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		if r.URL.Path == "/archive/rejected" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	store, err := New(server.URL+"/archive/", "secret-token")
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(ctx, "executions/batch.jsonl.gz", []byte("payload")))
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/archive/executions/batch.jsonl.gz", path)
+	assert.Equal(t, "Bearer secret-token", authorization)
+	assert.Equal(t, "payload", body)
+
+	err = store.Put(ctx, "rejected", []byte("payload"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 403")
+}
+
+func TestNew_InvalidURL(t *testing.T) {
+	for _, rawURL := range []string{"s3://bucket/executions", "file://", "://missing-scheme"} {
+		_, err := New(rawURL, "")
+		assert.Error(t, err, "URL %q", rawURL)
+	}
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+)
+
+// MaxPasswordLength is the longest password in bytes; bcrypt ignores anything past it
+const MaxPasswordLength = 72
+
+// commonPasswords are rejected whatever the policy's other rules, compared case-insensitively.
+// Most would pass the character class rules, which is why they are listed
+var commonPasswords = []string{
+	"123456789", "1234567890", "12345678", "password", "password1", "password12",
+	"password123", "password1234", "passw0rd", "p@ssw0rd", "p@ssword1", "p@ssw0rd123",
+	"qwerty123", "qwertyuiop", "qwerty1234", "1q2w3e4r5t", "1qaz2wsx3edc", "abc12345",
+	"abcd1234", "iloveyou1", "welcome1", "welcome123", "welcome2024", "welcome2025",
+	"letmein1", "letmein123", "admin123", "admin1234", "administrator", "changeme",
+	"changeme1", "changeme123", "trustno1", "sunshine1", "football1", "baseball1",
+	"monkey123", "dragon123", "master123", "superman1", "starwars1", "summer2024",
+	"summer2025", "winter2024", "winter2025", "spring2025", "autumn2025", "secret123",
+}
+
+// PasswordPolicy is the set of strength rules a new password must meet
+type PasswordPolicy struct {
+	minLength     int
+	requireUpper  bool
+	requireLower  bool
+	requireDigit  bool
+	requireSymbol bool
+	denylist      map[string]bool
+}
+
+// NewPasswordPolicy creates a policy from cfg, adding the passwords in cfg.DenylistFile to the
+// built-in denylist
+func NewPasswordPolicy(cfg *config.PasswordPolicyConfig) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{
+		minLength:     cfg.MinLength,
+		requireUpper:  cfg.RequireUpper,
+		requireLower:  cfg.RequireLower,
+		requireDigit:  cfg.RequireDigit,
+		requireSymbol: cfg.RequireSymbol,
+		denylist:      make(map[string]bool, len(commonPasswords)),
+	}
+	for _, password := range commonPasswords {
+		policy.denylist[password] = true
+	}
+
+	if cfg.DenylistFile != "" {
+		if err := policy.loadDenylist(cfg.DenylistFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+// DefaultPasswordPolicy returns the policy used when none is configured: at least 10 characters
+// with upper and lower case letters and a digit, and not a common password
+func DefaultPasswordPolicy() *PasswordPolicy {
+	policy, _ := NewPasswordPolicy(&config.PasswordPolicyConfig{
+		MinLength:    10,
+		RequireUpper: true,
+		RequireLower: true,
+		RequireDigit: true,
+	})
+	return policy
+}
+
+// loadDenylist adds the passwords in path, one per line, to the denylist. Blank lines and
+// lines starting with # are skipped
+func (p *PasswordPolicy) loadDenylist(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open password denylist: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.denylist[strings.ToLower(line)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read password denylist: %w", err)
+	}
+	return nil
+}
+
+// Check returns the rules password breaks, each as a message to follow the field name, e.g.
+// "must contain a digit". It returns nil if the password meets the policy
+func (p *PasswordPolicy) Check(password string) []string {
+	var violations []string
+
+	if p.minLength > 0 && utf8.RuneCountInString(password) < p.minLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", p.minLength))
+	}
+	if len(password) > MaxPasswordLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes", MaxPasswordLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	if p.requireUpper && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.requireLower && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.requireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.requireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if p.denylist[strings.ToLower(password)] {
+		violations = append(violations, "is too common")
+	}
+
+	return violations
+}
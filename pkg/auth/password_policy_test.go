@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy, err := NewPasswordPolicy(&config.PasswordPolicyConfig{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"meets every rule", "Tr0ub4dor&3x", nil},
+		{"too short", "Ab1!efgh", []string{"must be at least 10 characters"}},
+		{"length counts characters, not bytes", "Ünïcödé1!x", nil},
+		{"longer than bcrypt reads", "Aa1!" + strings.Repeat("x", 69), []string{"must be at most 72 bytes"}},
+		{"no uppercase letter", "tr0ub4dor&3x", []string{"must contain an uppercase letter"}},
+		{"no lowercase letter", "TR0UB4DOR&3X", []string{"must contain a lowercase letter"}},
+		{"no digit", "Troubador&xx", []string{"must contain a digit"}},
+		{"no symbol", "Tr0ub4dor33x", []string{"must contain a symbol"}},
+		{"common password", "P@ssw0rd123", []string{"is too common"}},
+		{"common password in any case", "PASSWORD123", []string{"must contain a lowercase letter", "must contain a symbol", "is too common"}},
+		{"breaks several rules", "abc", []string{
+			"must be at least 10 characters",
+			"must contain an uppercase letter",
+			"must contain a digit",
+			"must contain a symbol",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.Check(tt.password))
+		})
+	}
+}
+
+func TestPasswordPolicy_OptionalRules(t *testing.T) {
+	policy, err := NewPasswordPolicy(&config.PasswordPolicyConfig{MinLength: 4})
+	require.NoError(t, err)
+
+	assert.Nil(t, policy.Check("abcd"), "character classes are only checked when required")
+	assert.Equal(t, []string{"is too common"}, policy.Check("password"), "the denylist always applies")
+}
+
+func TestDefaultPasswordPolicy(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+
+	assert.Nil(t, policy.Check("SecurePass123!"))
+	assert.Nil(t, policy.Check("SecurePass123"), "symbols are not required by default")
+	assert.NotNil(t, policy.Check("Short1a"))
+	assert.NotNil(t, policy.Check("Welcome123"))
+}
+
+func TestPasswordPolicy_DenylistFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# company passwords\n\nAcmeCorp2025\n  Intelligent1  \n"), 0o600))
+
+	policy, err := NewPasswordPolicy(&config.PasswordPolicyConfig{MinLength: 8, DenylistFile: path})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"is too common"}, policy.Check("acmecorp2025"))
+	assert.Equal(t, []string{"is too common"}, policy.Check("Intelligent1"))
+	assert.Equal(t, []string{"is too common"}, policy.Check("password123"), "the built-in denylist still applies")
+	assert.Nil(t, policy.Check("# company passwords"), "comments are not passwords")
+
+	_, err = NewPasswordPolicy(&config.PasswordPolicyConfig{DenylistFile: filepath.Join(t.TempDir(), "missing.txt")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/davidmoltin/intelligent-workflows/pkg/metrics"
+	"github.com/sony/gobreaker"
+)
+
+// ErrOpen is returned (wrapped) when a call is failed fast because the breaker is open, or
+// half-open with its probe still in flight. The dependency is degraded, so callers should back
+// off and retry later
+var ErrOpen = errors.New("circuit open")
+
+// Breaker fails calls to a degraded dependency fast. It opens once FailureRatio of the calls in
+// Interval failed, fails every call for OpenTimeout, then lets a single probe through: the
+// breaker closes if it succeeds and opens again if it fails.
+//
+// A nil Breaker is disabled and runs every call.
+type Breaker struct {
+	name string
+	cb   *gobreaker.CircuitBreaker
+}
+
+// New creates a breaker, or returns nil if breakers are disabled. isFailure reports whether an
+// error counts as a failure of the dependency; nil counts every error. State changes are logged
+// and, if m is set, exported in the circuit_breaker_state gauge
+func New(name string, cfg *config.CircuitBreakerConfig, isFailure func(err error) bool, log *logger.Logger, m *metrics.Metrics) *Breaker {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	settings := gobreaker.Settings{
+		Name:        name,
+		MaxRequests: 1, // a single probe while half-open
+		Interval:    cfg.Interval,
+		Timeout:     cfg.OpenTimeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			if counts.Requests < uint32(cfg.MinRequests) {
+				return false
+			}
+			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
+			shouldTrip := failureRatio >= cfg.FailureRatio
+
+			if shouldTrip {
+				log.Errorf(
+					"Circuit breaker %s tripping: requests=%d, failures=%d, ratio=%.2f",
+					name,
+					counts.Requests,
+					counts.TotalFailures,
+					failureRatio,
+				)
+			}
+
+			return shouldTrip
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			log.Warnf("Circuit breaker %s state changed: %s -> %s", name, from.String(), to.String())
+			if m != nil {
+				m.CircuitBreakerState.WithLabelValues(name).Set(float64(to))
+			}
+		},
+	}
+	if isFailure != nil {
+		settings.IsSuccessful = func(err error) bool {
+			return err == nil || !isFailure(err)
+		}
+	}
+
+	if m != nil {
+		m.CircuitBreakerState.WithLabelValues(name).Set(float64(gobreaker.StateClosed))
+	}
+
+	return &Breaker{name: name, cb: gobreaker.NewCircuitBreaker(settings)}
+}
+
+// Execute runs fn unless the breaker is open, in which case it returns an error wrapping ErrOpen
+// without running it. fn's error is returned as is
+func (b *Breaker) Execute(fn func() error) error {
+	if b == nil {
+		return fn()
+	}
+
+	_, err := b.cb.Execute(func() (interface{}, error) {
+		return nil, fn()
+	})
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return fmt.Errorf("%w: %s", ErrOpen, b.name)
+	}
+	return err
+}
+
+// State returns the current state of the breaker; a disabled breaker is always closed
+func (b *Breaker) State() gobreaker.State {
+	if b == nil {
+		return gobreaker.StateClosed
+	}
+	return b.cb.State()
+}
@@ -0,0 +1,80 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBreaker(isFailure func(error) bool) *Breaker {
+	return New("test", &config.CircuitBreakerConfig{
+		Enabled:      true,
+		FailureRatio: 0.5,
+		MinRequests:  4,
+		Interval:     time.Minute,
+		OpenTimeout:  20 * time.Millisecond,
+	}, isFailure, logger.NewForTesting(), nil)
+}
+
+func TestBreaker(t *testing.T) {
+	errFailed := errors.New("failed")
+	succeed := func() error { return nil }
+	fail := func() error { return errFailed }
+
+	t.Run("opens at the failure ratio once enough calls were made", func(t *testing.T) {
+		breaker := newTestBreaker(nil)
+
+		assert.NoError(t, breaker.Execute(succeed))
+		assert.NoError(t, breaker.Execute(succeed))
+		assert.ErrorIs(t, breaker.Execute(fail), errFailed)
+		assert.Equal(t, gobreaker.StateClosed, breaker.State())
+		assert.ErrorIs(t, breaker.Execute(fail), errFailed)
+		assert.Equal(t, gobreaker.StateOpen, breaker.State())
+
+		calls := 0
+		err := breaker.Execute(func() error { calls++; return nil })
+		assert.ErrorIs(t, err, ErrOpen)
+		assert.Zero(t, calls)
+	})
+
+	t.Run("a failed probe opens the breaker again", func(t *testing.T) {
+		breaker := newTestBreaker(nil)
+		for i := 0; i < 4; i++ {
+			_ = breaker.Execute(fail)
+		}
+		require.Equal(t, gobreaker.StateOpen, breaker.State())
+
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, gobreaker.StateHalfOpen, breaker.State())
+		assert.ErrorIs(t, breaker.Execute(fail), errFailed)
+		assert.Equal(t, gobreaker.StateOpen, breaker.State())
+
+		time.Sleep(30 * time.Millisecond)
+		assert.NoError(t, breaker.Execute(succeed))
+		assert.Equal(t, gobreaker.StateClosed, breaker.State())
+	})
+
+	t.Run("errors that are not failures keep the breaker closed", func(t *testing.T) {
+		breaker := newTestBreaker(func(err error) bool { return !errors.Is(err, errFailed) })
+		for i := 0; i < 8; i++ {
+			assert.ErrorIs(t, breaker.Execute(fail), errFailed)
+		}
+		assert.Equal(t, gobreaker.StateClosed, breaker.State())
+	})
+
+	t.Run("a disabled breaker runs every call", func(t *testing.T) {
+		breaker := New("test", &config.CircuitBreakerConfig{Enabled: false}, nil, logger.NewForTesting(), nil)
+		require.Nil(t, breaker)
+
+		for i := 0; i < 8; i++ {
+			assert.ErrorIs(t, breaker.Execute(fail), errFailed)
+		}
+		assert.Equal(t, gobreaker.StateClosed, breaker.State())
+	})
+}
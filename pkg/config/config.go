@@ -1,9 +1,11 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,7 +19,20 @@ type Config struct {
 	Notification      NotificationConfig
 	LLM               LLMConfig
 	Workers           WorkersConfig
+	Concurrency       ConcurrencyConfig
+	WorkflowRateLimit WorkflowRateLimitConfig
+	Retention         RetentionConfig
 	ContextEnrichment ContextEnrichmentConfig
+	Webhook           WebhookConfig
+	Egress            EgressConfig
+	Query             QueryConfig
+	Metrics           MetricsConfig
+	Tracing           TracingConfig
+	Health            HealthConfig
+	Ingestion         IngestionConfig
+	CircuitBreaker    CircuitBreakerConfig
+	PasswordPolicy    PasswordPolicyConfig
+	RateLimit         RateLimitConfig
 }
 
 // ServerConfig holds HTTP server configuration
@@ -27,6 +42,7 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
 	ShutdownTimeout time.Duration
+	DrainTimeout    time.Duration // How long shutdown waits for running workflow executions
 }
 
 // DatabaseConfig holds PostgreSQL configuration
@@ -85,6 +101,10 @@ type EmailConfig struct {
 type SlackConfig struct {
 	Enabled    bool
 	WebhookURL string
+	// BotToken posts slack actions through the Web API, which reports where messages were
+	// posted. Organizations can configure their own in their settings
+	BotToken       string
+	DefaultChannel string // Channel of slack actions that name none, when posting with BotToken
 }
 
 // LLMConfig holds LLM provider configuration
@@ -96,6 +116,11 @@ type LLMConfig struct {
 	MaxRetries   int
 	RetryDelay   time.Duration
 	BaseURL      string
+	// CacheTTL caches responses to deterministic (temperature 0) requests in Redis for this
+	// long; 0 disables the cache
+	CacheTTL time.Duration
+	// CacheNonDeterministic also caches responses to requests with a temperature above 0
+	CacheNonDeterministic bool
 }
 
 // WorkersConfig holds background worker configuration
@@ -104,6 +129,73 @@ type WorkersConfig struct {
 	WorkflowResumerCheckInterval    time.Duration
 	TimeoutEnforcerCheckInterval    time.Duration
 	SchedulerCheckInterval          time.Duration
+	// DelayCheckInterval is how often executions waiting at a delay step are checked for
+	// delays that have passed, and so how late they may continue
+	DelayCheckInterval time.Duration
+	// SchedulerMaxCatchUpRuns caps the missed runs a catch_up schedule fires in one check
+	SchedulerMaxCatchUpRuns int
+	// WorkflowIndexRefreshInterval is how often the in-memory index events are routed from is
+	// reloaded; zero or less routes every event from the database
+	WorkflowIndexRefreshInterval time.Duration
+	// MaxPausedDuration is how long a paused execution can still be resumed, unless its
+	// workflow sets its own max_paused_duration
+	MaxPausedDuration time.Duration
+}
+
+// ConcurrencyConfig holds the defaults of workflow concurrency limits, which are enforced with
+// leases held in Redis
+type ConcurrencyConfig struct {
+	// LeaseTTL is how long a slot outlives the process holding it, e.g. after a crash. Running
+	// executions renew their lease every third of it. Zero uses the default of 30s
+	LeaseTTL time.Duration
+	// QueueTimeout is how long a queued execution waits for a slot, unless its workflow sets its
+	// own queue_timeout
+	QueueTimeout time.Duration
+	// PollInterval is how often a queued execution checks for a free slot. Zero uses the
+	// default of 1s
+	PollInterval time.Duration
+}
+
+// WorkflowRateLimitConfig holds the organization-wide execution rate limit and the defaults of
+// workflow rate limits, which are token buckets held in Redis. Executions take a token when they
+// start and again when they resume
+type WorkflowRateLimitConfig struct {
+	// OrganizationRate caps the executions each organization starts or resumes per
+	// OrganizationInterval, across all its workflows; zero leaves organizations unlimited
+	OrganizationRate     int
+	OrganizationInterval time.Duration
+	// OrganizationBurst is how many executions an organization may start at once after a quiet
+	// period; zero uses OrganizationRate
+	OrganizationBurst int
+	// OrganizationPolicy is queue or drop, for executions of workflows without a rate limit of
+	// their own that exceed the organization's limit
+	OrganizationPolicy string
+	// QueueTimeout is how long a queued execution waits for a token, unless its workflow sets its
+	// own queue_timeout
+	QueueTimeout time.Duration
+	// PollInterval is how often a queued execution ranked behind others checks whether it is next
+	PollInterval time.Duration
+}
+
+// RetentionConfig holds execution retention configuration. Finished executions past their
+// organization's retention window are soft-deleted, then purged once PurgeAfter has passed
+type RetentionConfig struct {
+	Enabled bool
+	// Days is how long completed and failed executions are kept in organizations without a
+	// retention window of their own; zero keeps them forever
+	Days int
+	// PurgeAfter is how long soft-deleted executions stay in the database, hidden from the API,
+	// before they are exported and permanently deleted
+	PurgeAfter    time.Duration
+	CheckInterval time.Duration
+	// BatchSize caps the executions soft-deleted or purged per transaction, keeping locks short
+	BatchSize int
+	// ArchiveURL is where executions are exported before they are purged: a file:// directory,
+	// e.g. a mounted bucket, or an http(s):// base URL objects are PUT under. Empty purges
+	// without exporting
+	ArchiveURL string
+	// ArchiveToken is sent as a bearer token with archive uploads over HTTP
+	ArchiveToken string
 }
 
 // ContextEnrichmentConfig holds context enrichment service configuration
@@ -114,7 +206,193 @@ type ContextEnrichmentConfig struct {
 	MaxRetries      int
 	RetryDelay      time.Duration
 	CacheTTL        time.Duration
-	EndpointMapping map[string]string
+	EndpointMapping map[string]EnrichmentEndpoint
+	// RetryMaxBackoff caps a single retry delay; 0 leaves it uncapped
+	RetryMaxBackoff time.Duration
+	// RetryJitter spreads retry delays: full, equal, or a percentage such as "20%"
+	RetryJitter string
+	// MaxConcurrentEnrichment caps how many resources a single context build loads at once
+	MaxConcurrentEnrichment int
+	// Headers are sent with every enrichment request, e.g. a static API key
+	Headers map[string]string
+	// AuthTokenEnv names an environment variable holding a bearer token. It is read on every
+	// request, so the token can be rotated without a restart
+	AuthTokenEnv string
+	// NegativeCacheTTL backs off fetching a resource for this long after a failure; 0 disables it
+	NegativeCacheTTL time.Duration
+	// StaleWhileRevalidate serves the last good value, kept for StaleTTL, when a fetch fails
+	StaleWhileRevalidate bool
+	StaleTTL             time.Duration
+	// InvalidateOnResume drops the cached entries of a workflow's resources before a resumed
+	// execution reloads them, so it sees fresh data
+	InvalidateOnResume bool
+	// ComputedFields maps field names to CEL expressions evaluated against every execution
+	// context and stored under _computed, e.g. {"order_is_high_value": "order.total >= 10000"}
+	ComputedFields map[string]string
+}
+
+// WebhookConfig holds outbound webhook delivery configuration
+type WebhookConfig struct {
+	// Timeout bounds each delivery attempt of webhook actions without a timeout of their own
+	Timeout time.Duration
+	// SigningSecrets holds the secrets webhook actions sign request bodies with, by the name the
+	// action refers to them by, e.g. {"billing": "..."}
+	SigningSecrets map[string]string
+}
+
+// EgressConfig holds the policy for outbound requests made on behalf of workflows, by webhook
+// actions and context enrichment. Only http(s) destinations are allowed, and hosts resolving to
+// private, loopback, link-local or other internal addresses are blocked unless allowed here
+type EgressConfig struct {
+	// AllowPrivate allows every internal address, for self-hosted deployments whose integrations
+	// all run on the internal network
+	AllowPrivate bool
+	// AllowedHosts may resolve to internal addresses, e.g. billing.internal
+	AllowedHosts []string
+	// AllowedCIDRs are internal ranges requests may reach, e.g. 10.20.0.0/16
+	AllowedCIDRs []string
+	// DeniedHosts are always blocked; "*.example.com" matches every subdomain
+	DeniedHosts []string
+	// DeniedCIDRs are always blocked, even for allowed hosts
+	DeniedCIDRs []string
+}
+
+// QueryConfig holds the datasources query steps run read-only SQL against
+type QueryConfig struct {
+	// Datasources holds PostgreSQL connection URLs by the name query steps refer to them by.
+	// Every organization's workflows can query them, so connect as a role that can only read
+	// what all of them may see
+	Datasources map[string]string
+	// MaxRows caps the rows a query step reads, and is the default for steps without max_rows
+	MaxRows int
+	// Timeout caps how long a query step's query runs, and is the default for steps without one
+	Timeout time.Duration
+	// MaxOpenConns limits the connections opened to each datasource
+	MaxOpenConns int
+}
+
+// CircuitBreakerConfig configures the circuit breakers around calls to the LLM provider and
+// the context enrichment microservice
+type CircuitBreakerConfig struct {
+	Enabled bool
+	// FailureRatio opens a breaker once this share of the calls in Interval failed
+	FailureRatio float64
+	// MinRequests is how many calls Interval must have before the failure ratio is checked
+	MinRequests int
+	// Interval is the window calls are counted in while the breaker is closed
+	Interval time.Duration
+	// OpenTimeout is how long an open breaker fails calls fast before letting a probe through
+	OpenTimeout time.Duration
+}
+
+// PasswordPolicyConfig configures the strength rules passwords must meet on registration and
+// password change
+type PasswordPolicyConfig struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// DenylistFile adds the common passwords listed in it, one per line, to the built-in denylist
+	DenylistFile string
+}
+
+// RateLimitConfig configures the API rate limits, counted in Redis per authenticated user or
+// API key, or per IP for unauthenticated requests
+type RateLimitConfig struct {
+	// Window is the period requests are counted over
+	Window time.Duration
+	// DefaultLimit is the requests per window of an authenticated user or API key
+	DefaultLimit int
+	// AnonymousLimit is the requests per window of an unauthenticated client IP
+	AnonymousLimit int
+	// RoleLimits replaces DefaultLimit for principals with a role, e.g. {"agent": 1000}; a
+	// principal with several roles gets the highest of their limits
+	RoleLimits map[string]int
+}
+
+// MetricsConfig holds Prometheus metrics configuration
+type MetricsConfig struct {
+	// OrganizationLabel adds the organization ID to execution and LLM usage metrics. Every organization
+	// multiplies the number of series, so deployments with many organizations may disable it.
+	OrganizationLabel bool
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	// Endpoint is the OTLP/HTTP collector URL spans are exported to; tracing is a no-op without one
+	Endpoint    string
+	ServiceName string
+	// SampleRatio is the fraction of executions traced when the caller's trace context doesn't decide
+	SampleRatio float64
+}
+
+// HealthConfig holds dependency health polling configuration
+type HealthConfig struct {
+	// PollInterval is how often dependencies are checked for the health endpoint
+	PollInterval time.Duration
+	// CheckTimeout bounds each dependency ping
+	CheckTimeout time.Duration
+}
+
+// IngestionConfig holds broker event ingestion configuration. Events are only consumed from a
+// broker when Broker is set; otherwise they arrive over HTTP alone
+type IngestionConfig struct {
+	// Broker selects the broker events are consumed from: "nats", or empty to disable ingestion.
+	// Kafka is not supported
+	Broker string
+	// OrganizationID receives events whose message names no organization
+	OrganizationID string
+	// BatchSize is how many messages are fetched at a time
+	BatchSize int
+	// FetchWait is how long a fetch waits for messages to arrive
+	FetchWait time.Duration
+	NATS      NATSConfig
+}
+
+// NATSConfig holds NATS JetStream consumer configuration
+type NATSConfig struct {
+	// URL is the server address, e.g. nats://localhost:4222, tls://nats.internal:4222
+	URL      string
+	User     string
+	Password string
+	Token    string
+	// Stream is the JetStream stream events are published to
+	Stream string
+	// Consumer is the durable consumer name; instances sharing it split the stream's messages
+	Consumer string
+	// Subject limits a consumer the service creates to matching subjects
+	Subject string
+	// AckWait is how long a message may go uncommitted before it is delivered again
+	AckWait time.Duration
+}
+
+// EnrichmentEndpoint describes how a context resource is fetched. Path may contain {id},
+// replaced by the resource's identifier from the context
+type EnrichmentEndpoint struct {
+	Path string `json:"path"`
+	// Method defaults to GET
+	Method string `json:"method,omitempty"`
+	// Body is a JSON request body template whose {{path}} tokens are rendered from the
+	// execution context, e.g. a GraphQL query with its variables
+	Body interface{} `json:"body,omitempty"`
+}
+
+// UnmarshalJSON accepts either a bare path string, fetched with GET, or an endpoint object
+func (e *EnrichmentEndpoint) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		*e = EnrichmentEndpoint{Path: path}
+		return nil
+	}
+
+	type endpoint EnrichmentEndpoint
+	var decoded endpoint
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*e = EnrichmentEndpoint(decoded)
+	return nil
 }
 
 // Load loads configuration from environment variables
@@ -126,6 +404,7 @@ func Load() (*Config, error) {
 			ReadTimeout:     getEnvAsDuration("SERVER_READ_TIMEOUT", 15*time.Second),
 			WriteTimeout:    getEnvAsDuration("SERVER_WRITE_TIMEOUT", 15*time.Second),
 			ShutdownTimeout: getEnvAsDuration("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
+			DrainTimeout:    getEnvAsDuration("SERVER_DRAIN_TIMEOUT", 30*time.Second),
 		},
 		Database: DatabaseConfig{
 			Host:            getEnv("DB_HOST", "localhost"),
@@ -165,8 +444,10 @@ func Load() (*Config, error) {
 				FromAddress:  getEnv("NOTIFICATION_FROM_ADDRESS", "noreply@example.com"),
 			},
 			Slack: SlackConfig{
-				Enabled:    getEnvAsBool("NOTIFICATION_SLACK_ENABLED", false),
-				WebhookURL: getEnv("NOTIFICATION_SLACK_WEBHOOK_URL", ""),
+				Enabled:        getEnvAsBool("NOTIFICATION_SLACK_ENABLED", false),
+				WebhookURL:     getEnv("NOTIFICATION_SLACK_WEBHOOK_URL", ""),
+				BotToken:       getEnv("NOTIFICATION_SLACK_BOT_TOKEN", ""),
+				DefaultChannel: getEnv("NOTIFICATION_SLACK_DEFAULT_CHANNEL", ""),
 			},
 		},
 		LLM: LLMConfig{
@@ -177,31 +458,163 @@ func Load() (*Config, error) {
 			MaxRetries:   getEnvAsInt("LLM_MAX_RETRIES", 3),
 			RetryDelay:   getEnvAsDuration("LLM_RETRY_DELAY", 1*time.Second),
 			BaseURL:      getEnv("LLM_BASE_URL", ""),
+
+			CacheTTL:              getEnvAsDuration("LLM_CACHE_TTL", 0),
+			CacheNonDeterministic: getEnvAsBool("LLM_CACHE_NON_DETERMINISTIC", false),
 		},
 		Workers: WorkersConfig{
 			ApprovalExpirationCheckInterval: getEnvAsDuration("WORKER_APPROVAL_EXPIRATION_INTERVAL", 5*time.Minute),
 			WorkflowResumerCheckInterval:    getEnvAsDuration("WORKER_WORKFLOW_RESUMER_INTERVAL", 1*time.Minute),
 			TimeoutEnforcerCheckInterval:    getEnvAsDuration("WORKER_TIMEOUT_ENFORCER_INTERVAL", 1*time.Minute),
 			SchedulerCheckInterval:          getEnvAsDuration("WORKER_SCHEDULER_INTERVAL", 1*time.Minute),
+			SchedulerMaxCatchUpRuns:         getEnvAsInt("WORKER_SCHEDULER_MAX_CATCH_UP_RUNS", 10),
+			DelayCheckInterval:              getEnvAsDuration("WORKER_DELAY_INTERVAL", 10*time.Second),
+			WorkflowIndexRefreshInterval:    getEnvAsDuration("WORKER_WORKFLOW_INDEX_REFRESH_INTERVAL", 30*time.Second),
+			MaxPausedDuration:               getEnvAsDuration("WORKER_MAX_PAUSED_DURATION", 7*24*time.Hour),
+		},
+		Concurrency: ConcurrencyConfig{
+			LeaseTTL:     getEnvAsDuration("CONCURRENCY_LEASE_TTL", 30*time.Second),
+			QueueTimeout: getEnvAsDuration("CONCURRENCY_QUEUE_TIMEOUT", 5*time.Minute),
+			PollInterval: getEnvAsDuration("CONCURRENCY_POLL_INTERVAL", time.Second),
+		},
+		WorkflowRateLimit: WorkflowRateLimitConfig{
+			OrganizationRate:     getEnvAsInt("WORKFLOW_RATE_LIMIT_ORG_RATE", 0),
+			OrganizationInterval: getEnvAsDuration("WORKFLOW_RATE_LIMIT_ORG_INTERVAL", time.Minute),
+			OrganizationBurst:    getEnvAsInt("WORKFLOW_RATE_LIMIT_ORG_BURST", 0),
+			OrganizationPolicy:   getEnv("WORKFLOW_RATE_LIMIT_ORG_POLICY", "queue"),
+			QueueTimeout:         getEnvAsDuration("WORKFLOW_RATE_LIMIT_QUEUE_TIMEOUT", 5*time.Minute),
+			PollInterval:         getEnvAsDuration("WORKFLOW_RATE_LIMIT_POLL_INTERVAL", 250*time.Millisecond),
+		},
+		Retention: RetentionConfig{
+			Enabled:       getEnvAsBool("RETENTION_ENABLED", false),
+			Days:          getEnvAsInt("RETENTION_DAYS", 90),
+			PurgeAfter:    getEnvAsDuration("RETENTION_PURGE_AFTER", 7*24*time.Hour),
+			CheckInterval: getEnvAsDuration("RETENTION_CHECK_INTERVAL", time.Hour),
+			BatchSize:     getEnvAsInt("RETENTION_BATCH_SIZE", 500),
+			ArchiveURL:    getEnv("RETENTION_ARCHIVE_URL", ""),
+			ArchiveToken:  getEnv("RETENTION_ARCHIVE_TOKEN", ""),
 		},
 		ContextEnrichment: ContextEnrichmentConfig{
-			Enabled:    getEnvAsBool("CONTEXT_ENRICHMENT_ENABLED", true),
-			BaseURL:    getEnv("CONTEXT_ENRICHMENT_BASE_URL", "http://localhost:8081"),
-			Timeout:    getEnvAsDuration("CONTEXT_ENRICHMENT_TIMEOUT", 10*time.Second),
-			MaxRetries: getEnvAsInt("CONTEXT_ENRICHMENT_MAX_RETRIES", 3),
-			RetryDelay: getEnvAsDuration("CONTEXT_ENRICHMENT_RETRY_DELAY", 500*time.Millisecond),
-			CacheTTL:   getEnvAsDuration("CONTEXT_ENRICHMENT_CACHE_TTL", 5*time.Minute),
-			EndpointMapping: map[string]string{
-				"order.details":      "/api/v1/orders/{id}/details",
-				"customer.history":   "/api/v1/customers/{id}/history",
-				"product.inventory":  "/api/v1/products/{id}/inventory",
-				"payment.status":     "/api/v1/payments/{id}/status",
-				"shipment.tracking":  "/api/v1/shipments/{id}/tracking",
-				"user.preferences":   "/api/v1/users/{id}/preferences",
-				"subscription.info":  "/api/v1/subscriptions/{id}/info",
-				"invoice.details":    "/api/v1/invoices/{id}/details",
+			Enabled:         getEnvAsBool("CONTEXT_ENRICHMENT_ENABLED", true),
+			BaseURL:         getEnv("CONTEXT_ENRICHMENT_BASE_URL", "http://localhost:8081"),
+			Timeout:         getEnvAsDuration("CONTEXT_ENRICHMENT_TIMEOUT", 10*time.Second),
+			MaxRetries:      getEnvAsInt("CONTEXT_ENRICHMENT_MAX_RETRIES", 3),
+			RetryDelay:      getEnvAsDuration("CONTEXT_ENRICHMENT_RETRY_DELAY", 500*time.Millisecond),
+			RetryMaxBackoff: getEnvAsDuration("CONTEXT_ENRICHMENT_RETRY_MAX_BACKOFF", 10*time.Second),
+			RetryJitter:     getEnv("CONTEXT_ENRICHMENT_RETRY_JITTER", ""),
+			CacheTTL:        getEnvAsDuration("CONTEXT_ENRICHMENT_CACHE_TTL", 5*time.Minute),
+			EndpointMapping: map[string]EnrichmentEndpoint{
+				"order.details":     {Path: "/api/v1/orders/{id}/details"},
+				"customer.history":  {Path: "/api/v1/customers/{id}/history"},
+				"product.inventory": {Path: "/api/v1/products/{id}/inventory"},
+				"payment.status":    {Path: "/api/v1/payments/{id}/status"},
+				"shipment.tracking": {Path: "/api/v1/shipments/{id}/tracking"},
+				"user.preferences":  {Path: "/api/v1/users/{id}/preferences"},
+				"subscription.info": {Path: "/api/v1/subscriptions/{id}/info"},
+				"invoice.details":   {Path: "/api/v1/invoices/{id}/details"},
+			},
+			MaxConcurrentEnrichment: getEnvAsInt("CONTEXT_ENRICHMENT_MAX_CONCURRENCY", 5),
+			Headers:                 getEnvAsMap("CONTEXT_ENRICHMENT_HEADERS"),
+			AuthTokenEnv:            getEnv("CONTEXT_ENRICHMENT_AUTH_TOKEN_ENV", ""),
+			NegativeCacheTTL:        getEnvAsDuration("CONTEXT_ENRICHMENT_NEGATIVE_CACHE_TTL", 0),
+			StaleWhileRevalidate:    getEnvAsBool("CONTEXT_ENRICHMENT_STALE_WHILE_REVALIDATE", false),
+			StaleTTL:                getEnvAsDuration("CONTEXT_ENRICHMENT_STALE_TTL", 24*time.Hour),
+			InvalidateOnResume:      getEnvAsBool("CONTEXT_ENRICHMENT_INVALIDATE_ON_RESUME", false),
+		},
+		Webhook: WebhookConfig{
+			Timeout:        getEnvAsDuration("WEBHOOK_TIMEOUT", 30*time.Second),
+			SigningSecrets: getEnvAsMap("WEBHOOK_SIGNING_SECRETS"),
+		},
+		Egress: EgressConfig{
+			AllowPrivate: getEnvAsBool("EGRESS_ALLOW_PRIVATE", false),
+			AllowedHosts: getEnvAsList("EGRESS_ALLOWED_HOSTS"),
+			AllowedCIDRs: getEnvAsList("EGRESS_ALLOWED_CIDRS"),
+			DeniedHosts:  getEnvAsList("EGRESS_DENIED_HOSTS"),
+			DeniedCIDRs:  getEnvAsList("EGRESS_DENIED_CIDRS"),
+		},
+		Query: QueryConfig{
+			Datasources:  getEnvAsMap("QUERY_DATASOURCES"),
+			MaxRows:      getEnvAsInt("QUERY_MAX_ROWS", 1000),
+			Timeout:      getEnvAsDuration("QUERY_TIMEOUT", 5*time.Second),
+			MaxOpenConns: getEnvAsInt("QUERY_MAX_OPEN_CONNS", 5),
+		},
+		Metrics: MetricsConfig{
+			OrganizationLabel: getEnvAsBool("METRICS_ORGANIZATION_LABEL", true),
+		},
+		Tracing: TracingConfig{
+			Endpoint:    getEnv("TRACING_ENDPOINT", ""),
+			ServiceName: getEnv("TRACING_SERVICE_NAME", "intelligent-workflows"),
+			SampleRatio: getEnvAsFloat("TRACING_SAMPLE_RATIO", 1.0),
+		},
+		Health: HealthConfig{
+			PollInterval: getEnvAsDuration("HEALTH_POLL_INTERVAL", 15*time.Second),
+			CheckTimeout: getEnvAsDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		},
+		Ingestion: IngestionConfig{
+			Broker:         getEnv("INGESTION_BROKER", ""),
+			OrganizationID: getEnv("INGESTION_ORGANIZATION_ID", ""),
+			BatchSize:      getEnvAsInt("INGESTION_BATCH_SIZE", 10),
+			FetchWait:      getEnvAsDuration("INGESTION_FETCH_WAIT", 5*time.Second),
+			NATS: NATSConfig{
+				URL:      getEnv("NATS_URL", "nats://localhost:4222"),
+				User:     getEnv("NATS_USER", ""),
+				Password: getEnv("NATS_PASSWORD", ""),
+				Token:    getEnv("NATS_TOKEN", ""),
+				Stream:   getEnv("NATS_STREAM", ""),
+				Consumer: getEnv("NATS_CONSUMER", "intelligent-workflows"),
+				Subject:  getEnv("NATS_SUBJECT", ""),
+				AckWait:  getEnvAsDuration("NATS_ACK_WAIT", 30*time.Second),
 			},
 		},
+		CircuitBreaker: CircuitBreakerConfig{
+			Enabled:      getEnvAsBool("CIRCUIT_BREAKER_ENABLED", true),
+			FailureRatio: getEnvAsFloat("CIRCUIT_BREAKER_FAILURE_RATIO", 0.5),
+			MinRequests:  getEnvAsInt("CIRCUIT_BREAKER_MIN_REQUESTS", 10),
+			Interval:     getEnvAsDuration("CIRCUIT_BREAKER_INTERVAL", 60*time.Second),
+			OpenTimeout:  getEnvAsDuration("CIRCUIT_BREAKER_OPEN_TIMEOUT", 30*time.Second),
+		},
+		PasswordPolicy: PasswordPolicyConfig{
+			MinLength:     getEnvAsInt("PASSWORD_MIN_LENGTH", 10),
+			RequireUpper:  getEnvAsBool("PASSWORD_REQUIRE_UPPER", true),
+			RequireLower:  getEnvAsBool("PASSWORD_REQUIRE_LOWER", true),
+			RequireDigit:  getEnvAsBool("PASSWORD_REQUIRE_DIGIT", true),
+			RequireSymbol: getEnvAsBool("PASSWORD_REQUIRE_SYMBOL", false),
+			DenylistFile:  getEnv("PASSWORD_DENYLIST_FILE", ""),
+		},
+		RateLimit: RateLimitConfig{
+			Window:         getEnvAsDuration("RATE_LIMIT_WINDOW", time.Minute),
+			DefaultLimit:   getEnvAsInt("RATE_LIMIT_DEFAULT", 100),
+			AnonymousLimit: getEnvAsInt("RATE_LIMIT_ANONYMOUS", 30),
+			RoleLimits:     map[string]int{"agent": 1000},
+		},
+	}
+
+	// Endpoints from the environment add to or replace the built-in mapping
+	if endpoints := os.Getenv("CONTEXT_ENRICHMENT_ENDPOINTS"); endpoints != "" {
+		var overrides map[string]EnrichmentEndpoint
+		if err := json.Unmarshal([]byte(endpoints), &overrides); err != nil {
+			return nil, fmt.Errorf("invalid CONTEXT_ENRICHMENT_ENDPOINTS: %w", err)
+		}
+		for resource, endpoint := range overrides {
+			cfg.ContextEnrichment.EndpointMapping[resource] = endpoint
+		}
+	}
+
+	if os.Getenv("RATE_LIMIT_ROLE_LIMITS") != "" {
+		cfg.RateLimit.RoleLimits = make(map[string]int)
+		for role, value := range getEnvAsMap("RATE_LIMIT_ROLE_LIMITS") {
+			limit, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RATE_LIMIT_ROLE_LIMITS limit for role %s: %q", role, value)
+			}
+			cfg.RateLimit.RoleLimits[role] = limit
+		}
+	}
+
+	if fields := os.Getenv("CONTEXT_ENRICHMENT_COMPUTED_FIELDS"); fields != "" {
+		if err := json.Unmarshal([]byte(fields), &cfg.ContextEnrichment.ComputedFields); err != nil {
+			return nil, fmt.Errorf("invalid CONTEXT_ENRICHMENT_COMPUTED_FIELDS: %w", err)
+		}
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -229,6 +642,67 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("redis host is required")
 	}
 
+	if c.CircuitBreaker.Enabled && (c.CircuitBreaker.FailureRatio <= 0 || c.CircuitBreaker.FailureRatio > 1) {
+		return fmt.Errorf("invalid circuit breaker failure ratio: %v", c.CircuitBreaker.FailureRatio)
+	}
+
+	if c.RateLimit.Window < 0 || c.RateLimit.DefaultLimit < 0 || c.RateLimit.AnonymousLimit < 0 {
+		return fmt.Errorf("invalid rate limit: window %s, default %d, anonymous %d",
+			c.RateLimit.Window, c.RateLimit.DefaultLimit, c.RateLimit.AnonymousLimit)
+	}
+
+	switch c.Ingestion.Broker {
+	case "":
+	case "nats":
+		if c.Ingestion.NATS.Stream == "" || c.Ingestion.NATS.Consumer == "" {
+			return fmt.Errorf("NATS stream and consumer are required for NATS ingestion")
+		}
+		if c.Ingestion.BatchSize <= 0 || c.Ingestion.FetchWait <= 0 {
+			return fmt.Errorf("invalid ingestion fetch: batch size %d, wait %s", c.Ingestion.BatchSize, c.Ingestion.FetchWait)
+		}
+	case "kafka":
+		// Kafka commits a partition's offset, not single messages, so a message that failed to route
+		// could not be left for redelivery while later messages are committed
+		return fmt.Errorf("unsupported ingestion broker: %q; publish Kafka events to a NATS JetStream stream or over HTTP", c.Ingestion.Broker)
+	default:
+		return fmt.Errorf("unsupported ingestion broker: %q", c.Ingestion.Broker)
+	}
+
+	if len(c.Query.Datasources) > 0 && (c.Query.MaxRows <= 0 || c.Query.Timeout <= 0) {
+		return fmt.Errorf("invalid query limits: max rows %d, timeout %s", c.Query.MaxRows, c.Query.Timeout)
+	}
+
+	if c.Concurrency.LeaseTTL < 0 || c.Concurrency.QueueTimeout < 0 || c.Concurrency.PollInterval < 0 {
+		return fmt.Errorf("invalid concurrency: lease TTL %s, queue timeout %s, poll interval %s",
+			c.Concurrency.LeaseTTL, c.Concurrency.QueueTimeout, c.Concurrency.PollInterval)
+	}
+
+	if c.WorkflowRateLimit.OrganizationRate < 0 || c.WorkflowRateLimit.OrganizationBurst < 0 || c.WorkflowRateLimit.QueueTimeout < 0 ||
+		c.WorkflowRateLimit.PollInterval < 0 || (c.WorkflowRateLimit.OrganizationRate > 0 && c.WorkflowRateLimit.OrganizationInterval <= 0) {
+		return fmt.Errorf("invalid workflow rate limit: rate %d per %s, burst %d, queue timeout %s, poll interval %s",
+			c.WorkflowRateLimit.OrganizationRate, c.WorkflowRateLimit.OrganizationInterval,
+			c.WorkflowRateLimit.OrganizationBurst, c.WorkflowRateLimit.QueueTimeout, c.WorkflowRateLimit.PollInterval)
+	}
+
+	switch c.WorkflowRateLimit.OrganizationPolicy {
+	case "", "queue", "drop":
+	default:
+		return fmt.Errorf("unsupported workflow rate limit policy: %q", c.WorkflowRateLimit.OrganizationPolicy)
+	}
+
+	if c.Retention.Enabled && (c.Retention.Days < 0 || c.Retention.PurgeAfter < 0 || c.Retention.CheckInterval <= 0 || c.Retention.BatchSize <= 0) {
+		return fmt.Errorf("invalid retention: days %d, purge after %s, interval %s, batch size %d",
+			c.Retention.Days, c.Retention.PurgeAfter, c.Retention.CheckInterval, c.Retention.BatchSize)
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("invalid tracing sample ratio: %g", c.Tracing.SampleRatio)
+	}
+
+	if c.PasswordPolicy.MinLength < 0 || c.PasswordPolicy.MinLength > 72 {
+		return fmt.Errorf("invalid password minimum length: %d", c.PasswordPolicy.MinLength)
+	}
+
 	return nil
 }
 
@@ -268,6 +742,15 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -285,3 +768,28 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getEnvAsList parses a comma-separated list; empty items are skipped
+func getEnvAsList(key string) []string {
+	var result []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// getEnvAsMap parses a comma-separated list of key=value pairs; malformed pairs are skipped
+func getEnvAsMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			continue
+		}
+		result[name] = strings.TrimSpace(value)
+	}
+	return result
+}
@@ -43,19 +43,34 @@ func TestLoad(t *testing.T) {
 				assert.Equal(t, 5432, cfg.Database.Port)
 				assert.Equal(t, "postgres", cfg.Database.User)
 				assert.Equal(t, "workflows", cfg.Database.Database)
+				assert.True(t, cfg.Metrics.OrganizationLabel)
+				assert.Empty(t, cfg.Tracing.Endpoint)
+				assert.Equal(t, 1.0, cfg.Tracing.SampleRatio)
+				assert.False(t, cfg.Retention.Enabled)
+				assert.Equal(t, 90, cfg.Retention.Days)
+				assert.Equal(t, 7*24*time.Hour, cfg.Retention.PurgeAfter)
+				assert.Equal(t, 30*time.Second, cfg.Concurrency.LeaseTTL)
+				assert.Equal(t, 5*time.Minute, cfg.Concurrency.QueueTimeout)
+				assert.Zero(t, cfg.WorkflowRateLimit.OrganizationRate)
+				assert.Equal(t, "queue", cfg.WorkflowRateLimit.OrganizationPolicy)
+				assert.Equal(t, 250*time.Millisecond, cfg.WorkflowRateLimit.PollInterval)
+				assert.Equal(t, 10, cfg.PasswordPolicy.MinLength)
+				assert.True(t, cfg.PasswordPolicy.RequireDigit)
+				assert.False(t, cfg.PasswordPolicy.RequireSymbol)
 			},
 		},
 		{
 			name: "custom values",
 			env: map[string]string{
-				"SERVER_PORT": "9000",
-				"DB_HOST":     "db.example.com",
-				"DB_PORT":     "5433",
-				"DB_NAME":     "custom_db",
-				"REDIS_HOST":  "redis.example.com",
-				"REDIS_PORT":  "6380",
-				"LOG_LEVEL":   "debug",
-				"APP_ENV":     "production",
+				"SERVER_PORT":                "9000",
+				"DB_HOST":                    "db.example.com",
+				"DB_PORT":                    "5433",
+				"DB_NAME":                    "custom_db",
+				"REDIS_HOST":                 "redis.example.com",
+				"REDIS_PORT":                 "6380",
+				"LOG_LEVEL":                  "debug",
+				"APP_ENV":                    "production",
+				"METRICS_ORGANIZATION_LABEL": "false",
 			},
 			check: func(t *testing.T, cfg *Config) {
 				assert.Equal(t, 9000, cfg.Server.Port)
@@ -66,8 +81,65 @@ func TestLoad(t *testing.T) {
 				assert.Equal(t, 6380, cfg.Redis.Port)
 				assert.Equal(t, "debug", cfg.Logger.Level)
 				assert.Equal(t, "production", cfg.App.Environment)
+				assert.False(t, cfg.Metrics.OrganizationLabel)
 			},
 		},
+		{
+			name: "endpoint overrides",
+			env: map[string]string{
+				"CONTEXT_ENRICHMENT_ENDPOINTS": `{"order.details": "/orders/{id}", "order.risk": {"path": "/graphql", "method": "POST", "body": {"query": "{ risk }"}}}`,
+			},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, EnrichmentEndpoint{Path: "/orders/{id}"}, cfg.ContextEnrichment.EndpointMapping["order.details"])
+				assert.Equal(t, EnrichmentEndpoint{
+					Path:   "/graphql",
+					Method: "POST",
+					Body:   map[string]interface{}{"query": "{ risk }"},
+				}, cfg.ContextEnrichment.EndpointMapping["order.risk"])
+				// Built-in endpoints that aren't overridden are kept
+				assert.Equal(t, "/api/v1/customers/{id}/history", cfg.ContextEnrichment.EndpointMapping["customer.history"].Path)
+			},
+		},
+		{
+			name: "invalid endpoint overrides",
+			env: map[string]string{
+				"CONTEXT_ENRICHMENT_ENDPOINTS": `{"order.details": 42}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "rate limit role limits",
+			env: map[string]string{
+				"RATE_LIMIT_ROLE_LIMITS": "agent=5000, admin=300",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, map[string]int{"agent": 5000, "admin": 300}, cfg.RateLimit.RoleLimits)
+				assert.Equal(t, 100, cfg.RateLimit.DefaultLimit)
+			},
+		},
+		{
+			name: "invalid rate limit role limits",
+			env: map[string]string{
+				"RATE_LIMIT_ROLE_LIMITS": "agent=lots",
+			},
+			wantErr: true,
+		},
+		{
+			name: "computed fields",
+			env: map[string]string{
+				"CONTEXT_ENRICHMENT_COMPUTED_FIELDS": `{"order_is_high_value": "order.total >= 10000.0"}`,
+			},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, map[string]string{"order_is_high_value": "order.total >= 10000.0"}, cfg.ContextEnrichment.ComputedFields)
+			},
+		},
+		{
+			name: "invalid computed fields",
+			env: map[string]string{
+				"CONTEXT_ENRICHMENT_COMPUTED_FIELDS": `["order.total"]`,
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid port",
 			env: map[string]string{
@@ -179,6 +251,154 @@ func TestConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "redis host is required",
 		},
+		{
+			name: "invalid circuit breaker failure ratio",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Database: DatabaseConfig{
+					Host:     "localhost",
+					Database: "workflows",
+				},
+				Redis:          RedisConfig{Host: "localhost"},
+				CircuitBreaker: CircuitBreakerConfig{Enabled: true, FailureRatio: 1.5},
+			},
+			wantErr: true,
+			errMsg:  "invalid circuit breaker failure ratio",
+		},
+		{
+			name: "password minimum length above bcrypt limit",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Database: DatabaseConfig{
+					Host:     "localhost",
+					Database: "workflows",
+				},
+				Redis:          RedisConfig{Host: "localhost"},
+				PasswordPolicy: PasswordPolicyConfig{MinLength: 100},
+			},
+			wantErr: true,
+			errMsg:  "invalid password minimum length",
+		},
+		{
+			name: "kafka ingestion broker",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Database: DatabaseConfig{
+					Host:     "localhost",
+					Database: "workflows",
+				},
+				Redis:     RedisConfig{Host: "localhost"},
+				Ingestion: IngestionConfig{Broker: "kafka"},
+			},
+			wantErr: true,
+			errMsg:  "publish Kafka events to a NATS JetStream stream",
+		},
+		{
+			name: "unknown ingestion broker",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Database: DatabaseConfig{
+					Host:     "localhost",
+					Database: "workflows",
+				},
+				Redis:     RedisConfig{Host: "localhost"},
+				Ingestion: IngestionConfig{Broker: "rabbitmq"},
+			},
+			wantErr: true,
+			errMsg:  "unsupported ingestion broker",
+		},
+		{
+			name: "NATS ingestion without a stream",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Database: DatabaseConfig{
+					Host:     "localhost",
+					Database: "workflows",
+				},
+				Redis: RedisConfig{Host: "localhost"},
+				Ingestion: IngestionConfig{
+					Broker:    "nats",
+					BatchSize: 10,
+					FetchWait: 5 * time.Second,
+					NATS:      NATSConfig{Consumer: "intelligent-workflows"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "NATS stream and consumer are required",
+		},
+		{
+			name: "query datasources without a row limit",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Database: DatabaseConfig{
+					Host:     "localhost",
+					Database: "workflows",
+				},
+				Redis: RedisConfig{Host: "localhost"},
+				Query: QueryConfig{
+					Datasources: map[string]string{"analytics": "postgres://reader@replica/analytics"},
+					Timeout:     5 * time.Second,
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid query limits",
+		},
+		{
+			name: "retention without a check interval",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Database: DatabaseConfig{
+					Host:     "localhost",
+					Database: "workflows",
+				},
+				Redis:     RedisConfig{Host: "localhost"},
+				Retention: RetentionConfig{Enabled: true, Days: 90, BatchSize: 500},
+			},
+			wantErr: true,
+			errMsg:  "invalid retention",
+		},
+		{
+			name: "negative concurrency queue timeout",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Database: DatabaseConfig{
+					Host:     "localhost",
+					Database: "workflows",
+				},
+				Redis:       RedisConfig{Host: "localhost"},
+				Concurrency: ConcurrencyConfig{QueueTimeout: -time.Minute},
+			},
+			wantErr: true,
+			errMsg:  "invalid concurrency",
+		},
+		{
+			name: "organization rate limit without an interval",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Database: DatabaseConfig{
+					Host:     "localhost",
+					Database: "workflows",
+				},
+				Redis:             RedisConfig{Host: "localhost"},
+				WorkflowRateLimit: WorkflowRateLimitConfig{OrganizationRate: 100},
+			},
+			wantErr: true,
+			errMsg:  "invalid workflow rate limit",
+		},
+		{
+			name: "tracing sample ratio above 1",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Database: DatabaseConfig{
+					Host:     "localhost",
+					Database: "workflows",
+				},
+				Redis:   RedisConfig{Host: "localhost"},
+				Tracing: TracingConfig{SampleRatio: 1.5},
+			},
+			wantErr: true,
+			errMsg:  "invalid tracing sample ratio",
+		},
 	}
 
 	for _, tt := range tests {
@@ -318,3 +538,22 @@ func TestGetEnvAsDuration(t *testing.T) {
 		})
 	}
 }
+
+func TestGetEnvAsMap(t *testing.T) {
+	t.Setenv("TEST_MAP", "X-Api-Key=abc, X-Tenant = acme,malformed,=empty-name,X-Empty=")
+
+	assert.Equal(t, map[string]string{
+		"X-Api-Key": "abc",
+		"X-Tenant":  "acme",
+		"X-Empty":   "",
+	}, getEnvAsMap("TEST_MAP"))
+
+	assert.Empty(t, getEnvAsMap("TEST_MAP_UNSET"))
+}
+
+func TestGetEnvAsList(t *testing.T) {
+	t.Setenv("TEST_LIST", "10.0.0.0/8, 192.168.1.1 ,,")
+
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1"}, getEnvAsList("TEST_LIST"))
+	assert.Empty(t, getEnvAsList("TEST_LIST_UNSET"))
+}
@@ -0,0 +1,46 @@
+package correlation
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Header is the HTTP header a correlation ID is read from and forwarded in
+const Header = "X-Correlation-ID"
+
+// maxLength bounds correlation IDs taken from callers, which are stored and logged
+const maxLength = 128
+
+// contextKey is the context key for the current correlation ID
+type contextKey struct{}
+
+// NewID generates a correlation ID
+func NewID() string {
+	return uuid.New().String()
+}
+
+// WithID returns a copy of ctx carrying the correlation ID id
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the correlation ID carried by ctx, or "" if there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Valid reports whether a correlation ID taken from a caller can be used as is: it must be
+// non-empty, at most 128 characters and printable ASCII, so it is safe to log and forward
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+)
+
+// OpenDatasources opens a connection pool for each datasource query steps can run against.
+// Connections are made lazily, so a datasource that is down only fails the steps using it
+func OpenDatasources(cfg *config.QueryConfig, log *logger.Logger) (map[string]*sql.DB, error) {
+	datasources := make(map[string]*sql.DB, len(cfg.Datasources))
+	for name, dsn := range cfg.Datasources {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			CloseDatasources(datasources)
+			return nil, fmt.Errorf("failed to open datasource %s: %w", name, err)
+		}
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+		db.SetMaxIdleConns(cfg.MaxOpenConns)
+		datasources[name] = db
+
+		log.Info("Query datasource configured", logger.String("datasource", name))
+	}
+	return datasources, nil
+}
+
+// CloseDatasources closes datasources opened by OpenDatasources
+func CloseDatasources(datasources map[string]*sql.DB) {
+	for _, db := range datasources {
+		db.Close()
+	}
+}
@@ -0,0 +1,223 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrNotSelect is returned for queries that aren't a single SELECT statement
+	ErrNotSelect = errors.New("only SELECT statements are allowed")
+	// ErrMultipleStatements is returned for queries with more than one statement
+	ErrMultipleStatements = errors.New("multiple statements are not allowed")
+)
+
+// CheckReadOnlyQuery checks that query is a single PostgreSQL SELECT statement whose $n
+// placeholders are all bound by one of params parameters. Quoted strings, quoted identifiers,
+// dollar-quoted strings and comments are skipped, so a semicolon or keyword inside them is
+// harmless.
+//
+// This only rejects what can be recognised statically: queries must still run in a read-only
+// transaction, which catches side effects such as functions that write.
+func CheckReadOnlyQuery(query string, params int) error {
+	s := &sqlScanner{src: query}
+
+	first, err := s.next()
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(first, "select") {
+		return ErrNotSelect
+	}
+
+	for {
+		word, err := s.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case word == "":
+			return nil
+		case word == ";":
+			// Only whitespace and comments may follow the statement's terminator
+			rest, err := s.next()
+			if err != nil {
+				return err
+			}
+			if rest != "" {
+				return ErrMultipleStatements
+			}
+			return nil
+		case strings.EqualFold(word, "into"):
+			// SELECT ... INTO creates a table
+			return fmt.Errorf("%w: SELECT INTO is not allowed", ErrNotSelect)
+		case strings.HasPrefix(word, "$"):
+			n, _ := strconv.Atoi(word[1:])
+			if n < 1 || n > params {
+				return fmt.Errorf("placeholder %s has no parameter (%d given)", word, params)
+			}
+		}
+	}
+}
+
+// sqlScanner splits SQL into the words, placeholders and semicolons that matter to
+// CheckReadOnlyQuery, skipping everything else
+type sqlScanner struct {
+	src    string
+	pos    int
+	prefix string // Word written right before the current string, such as E in E'...'
+}
+
+// next returns the next identifier or keyword, $n placeholder or semicolon, or "" at the end
+func (s *sqlScanner) next() (string, error) {
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == ';':
+			s.pos++
+			return ";", nil
+
+		case c == '-' && s.peek(1) == '-':
+			end := strings.IndexByte(s.src[s.pos:], '\n')
+			if end < 0 {
+				s.pos = len(s.src)
+			} else {
+				s.pos += end + 1
+			}
+
+		case c == '/' && s.peek(1) == '*':
+			if err := s.skipBlockComment(); err != nil {
+				return "", err
+			}
+
+		case c == '\'':
+			// E'...' strings allow backslash escapes
+			escapes := strings.EqualFold(s.prefix, "e")
+			s.prefix = ""
+			if err := s.skipQuoted('\'', escapes); err != nil {
+				return "", err
+			}
+
+		case c == '"':
+			if err := s.skipQuoted('"', false); err != nil {
+				return "", err
+			}
+
+		case c == '$':
+			if word, ok := s.placeholder(); ok {
+				return word, nil
+			}
+			if err := s.skipDollarQuoted(); err != nil {
+				return "", err
+			}
+
+		case isIdentStart(c):
+			start := s.pos
+			for s.pos < len(s.src) && isIdentPart(s.src[s.pos]) {
+				s.pos++
+			}
+			word := s.src[start:s.pos]
+			// A prefix such as E, B or X belongs to the string that follows
+			if s.pos < len(s.src) && s.src[s.pos] == '\'' {
+				s.prefix = word
+				continue
+			}
+			return word, nil
+
+		default:
+			s.pos++
+		}
+	}
+	return "", nil
+}
+
+// peek returns the byte offset bytes ahead, or 0 past the end
+func (s *sqlScanner) peek(offset int) byte {
+	if s.pos+offset < len(s.src) {
+		return s.src[s.pos+offset]
+	}
+	return 0
+}
+
+// skipQuoted skips a string or quoted identifier, where a doubled quote is an escaped quote
+func (s *sqlScanner) skipQuoted(quote byte, escapes bool) error {
+	for i := s.pos + 1; i < len(s.src); i++ {
+		switch {
+		case escapes && s.src[i] == '\\':
+			i++
+		case s.src[i] == quote && i+1 < len(s.src) && s.src[i+1] == quote:
+			i++
+		case s.src[i] == quote:
+			s.pos = i + 1
+			return nil
+		}
+	}
+	return errors.New("unterminated quoted string")
+}
+
+// skipBlockComment skips a /* comment */, which may be nested
+func (s *sqlScanner) skipBlockComment() error {
+	depth := 0
+	for i := s.pos; i+1 < len(s.src); i++ {
+		switch {
+		case s.src[i] == '/' && s.src[i+1] == '*':
+			depth++
+			i++
+		case s.src[i] == '*' && s.src[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				s.pos = i + 1
+				return nil
+			}
+		}
+	}
+	return errors.New("unterminated comment")
+}
+
+// placeholder consumes a $n placeholder at the current position
+func (s *sqlScanner) placeholder() (string, bool) {
+	end := s.pos + 1
+	for end < len(s.src) && s.src[end] >= '0' && s.src[end] <= '9' {
+		end++
+	}
+	if end == s.pos+1 {
+		return "", false
+	}
+	word := s.src[s.pos:end]
+	s.pos = end
+	return word, true
+}
+
+// skipDollarQuoted skips a $tag$ ... $tag$ string. A lone $ is skipped
+func (s *sqlScanner) skipDollarQuoted() error {
+	end := s.pos + 1
+	for end < len(s.src) && isIdentPart(s.src[end]) && s.src[end] != '$' {
+		end++
+	}
+	if end >= len(s.src) || s.src[end] != '$' {
+		s.pos++
+		return nil
+	}
+
+	tag := s.src[s.pos : end+1]
+	closing := strings.Index(s.src[end+1:], tag)
+	if closing < 0 {
+		return errors.New("unterminated dollar-quoted string")
+	}
+	s.pos = end + 1 + closing + len(tag)
+	return nil
+}
+
+// isIdentStart reports whether c can start an identifier or keyword
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 0x80 || unicode.IsLetter(rune(c))
+}
+
+// isIdentPart reports whether c can continue an identifier or keyword
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9') || c == '$'
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckReadOnlyQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		params  int
+		wantErr error
+		errMsg  string
+	}{
+		{
+			name:   "select with parameters",
+			query:  "SELECT id, total FROM orders WHERE customer_id = $1 AND status = $2",
+			params: 2,
+		},
+		{
+			name:  "lowercase with a trailing semicolon and comment",
+			query: "select count(*) from orders; -- daily total",
+		},
+		{
+			name:  "semicolons and keywords inside strings and comments",
+			query: "SELECT 'a;b', \"into\", $$; DROP TABLE x$$, E'\\'; DELETE' /* ; /* nested */ into */ FROM t",
+		},
+		{
+			name:   "leading comment",
+			query:  "/* enrichment */ -- lookup\nSELECT * FROM customers WHERE id = $1",
+			params: 1,
+		},
+		{
+			name:    "update",
+			query:   "UPDATE orders SET status = 'paid'",
+			wantErr: ErrNotSelect,
+		},
+		{
+			name:    "writable CTE",
+			query:   "WITH deleted AS (DELETE FROM orders RETURNING *) SELECT * FROM deleted",
+			wantErr: ErrNotSelect,
+		},
+		{
+			name:    "select into",
+			query:   "SELECT * INTO copy FROM orders",
+			wantErr: ErrNotSelect,
+		},
+		{
+			name:    "stacked statement",
+			query:   "SELECT 1; DROP TABLE orders",
+			wantErr: ErrMultipleStatements,
+		},
+		{
+			name:    "statement after a standard string that looks escaped",
+			query:   "SELECT 'a\\'; DROP TABLE orders; --'",
+			wantErr: ErrMultipleStatements,
+		},
+		{
+			name:   "unbound placeholder",
+			query:  "SELECT * FROM orders WHERE id = $2",
+			params: 1,
+			errMsg: "placeholder $2 has no parameter (1 given)",
+		},
+		{
+			name:   "unterminated string",
+			query:  "SELECT 'open",
+			errMsg: "unterminated quoted string",
+		},
+		{
+			name:   "unterminated dollar quote",
+			query:  "SELECT $tag$ open",
+			errMsg: "unterminated dollar-quoted string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckReadOnlyQuery(tt.query, tt.params)
+
+			switch {
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			case tt.errMsg != "":
+				assert.EqualError(t, err, tt.errMsg)
+			default:
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
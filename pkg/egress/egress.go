@@ -0,0 +1,294 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/metrics"
+)
+
+// ErrBlockedDestination is returned (wrapped) when the policy forbids a request's destination
+var ErrBlockedDestination = errors.New("blocked destination")
+
+// Reasons a destination is blocked, as counted in the egress_blocked_total metric
+const (
+	ReasonScheme  = "scheme"           // Not http or https
+	ReasonHost    = "denied_host"      // Host on the denylist
+	ReasonAddress = "denied_address"   // Address in a denied range
+	ReasonPrivate = "internal_address" // Private, loopback, link-local or otherwise internal address
+)
+
+// internalPrefixes are ranges that are never publicly routable beyond those netip reports as
+// private, loopback, link-local, multicast or unspecified
+var internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // Reserved, including broadcast
+}
+
+// Policy decides which destinations outbound requests made on behalf of workflows may reach.
+// Destinations must be http(s); hosts resolving to internal addresses are blocked unless the
+// host or address is allowed, and denied hosts and ranges are blocked regardless.
+//
+// A nil Policy allows every destination.
+type Policy struct {
+	allowPrivate bool
+	allowedHosts map[string]bool
+	deniedHosts  []string
+	allowedNets  []netip.Prefix
+	deniedNets   []netip.Prefix
+	resolver     *net.Resolver
+	metrics      *metrics.Metrics
+}
+
+// NewPolicy creates a policy from configuration
+func NewPolicy(cfg *config.EgressConfig) (*Policy, error) {
+	p := &Policy{
+		allowPrivate: cfg.AllowPrivate,
+		allowedHosts: make(map[string]bool, len(cfg.AllowedHosts)),
+		resolver:     net.DefaultResolver,
+	}
+
+	for _, host := range cfg.AllowedHosts {
+		p.allowedHosts[normalizeHost(host)] = true
+	}
+	for _, host := range cfg.DeniedHosts {
+		p.deniedHosts = append(p.deniedHosts, normalizeHost(host))
+	}
+
+	var err error
+	if p.allowedNets, err = parsePrefixes(cfg.AllowedCIDRs); err != nil {
+		return nil, fmt.Errorf("invalid allowed egress range: %w", err)
+	}
+	if p.deniedNets, err = parsePrefixes(cfg.DeniedCIDRs); err != nil {
+		return nil, fmt.Errorf("invalid denied egress range: %w", err)
+	}
+
+	return p, nil
+}
+
+// SetMetrics sets the metrics blocked requests are counted in (optional dependency)
+func (p *Policy) SetMetrics(m *metrics.Metrics) {
+	p.metrics = m
+}
+
+// WithAllowedHosts returns a copy of the policy that also allows hosts, for clients whose
+// destination is configured by the operator rather than by workflows
+func (p *Policy) WithAllowedHosts(hosts ...string) *Policy {
+	if p == nil {
+		return nil
+	}
+
+	copied := *p
+	copied.allowedHosts = make(map[string]bool, len(p.allowedHosts)+len(hosts))
+	for host := range p.allowedHosts {
+		copied.allowedHosts[host] = true
+	}
+	for _, host := range hosts {
+		copied.allowedHosts[normalizeHost(host)] = true
+	}
+	return &copied
+}
+
+// CheckURL checks a request's destination before it is made, resolving its host. client names
+// the caller in the blocked request count
+func (p *Policy) CheckURL(ctx context.Context, client, rawURL string) error {
+	if p == nil {
+		return nil
+	}
+
+	destination, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if destination.Scheme != "http" && destination.Scheme != "https" {
+		return p.block(client, ReasonScheme, fmt.Errorf("%w: scheme %q is not allowed", ErrBlockedDestination, destination.Scheme))
+	}
+
+	_, err = p.resolve(ctx, client, destination.Hostname())
+	return err
+}
+
+// Client returns an HTTP client that only connects to addresses the policy allows. Addresses
+// are checked as they are dialed, so redirects and DNS answers that change after CheckURL are
+// covered too. Requests are not sent through a proxy, which would hide their destination
+func (p *Policy) Client(client string, timeout time.Duration) *http.Client {
+	if p == nil {
+		return &http.Client{Timeout: timeout}
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		addresses, err := p.resolve(ctx, client, host)
+		if err != nil {
+			return nil, err
+		}
+
+		// Dial the checked addresses themselves, so the host can't resolve elsewhere in between
+		var dialErr error
+		for _, addr := range addresses {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+		}
+		return nil, dialErr
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return p.block(client, ReasonScheme, fmt.Errorf("%w: redirect to scheme %q is not allowed", ErrBlockedDestination, req.URL.Scheme))
+			}
+			return nil
+		},
+	}
+}
+
+// resolve resolves host, returning its addresses if the policy allows them all
+func (p *Policy) resolve(ctx context.Context, client, host string) ([]netip.Addr, error) {
+	host = normalizeHost(host)
+	if host == "" {
+		return nil, p.block(client, ReasonHost, fmt.Errorf("%w: no host", ErrBlockedDestination))
+	}
+
+	for _, denied := range p.deniedHosts {
+		if matchHost(denied, host) {
+			return nil, p.block(client, ReasonHost, fmt.Errorf("%w: host %s is denied", ErrBlockedDestination, host))
+		}
+	}
+
+	var addresses []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addresses = []netip.Addr{addr}
+	} else {
+		addresses, err = p.resolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+	}
+
+	hostAllowed := p.allowedHosts[host]
+	for i, addr := range addresses {
+		addr = addr.Unmap().WithZone("")
+		addresses[i] = addr
+		if reason := p.checkAddress(addr, hostAllowed); reason != "" {
+			return nil, p.block(client, reason, fmt.Errorf("%w: %s resolves to %s", ErrBlockedDestination, host, describeAddress(addr, reason)))
+		}
+	}
+
+	return addresses, nil
+}
+
+// checkAddress returns why an address is blocked, or "" if it is allowed. Denied ranges apply
+// even to allowed hosts
+func (p *Policy) checkAddress(addr netip.Addr, hostAllowed bool) string {
+	for _, prefix := range p.deniedNets {
+		if prefix.Contains(addr) {
+			return ReasonAddress
+		}
+	}
+	if hostAllowed || p.allowPrivate || !isInternal(addr) {
+		return ""
+	}
+	for _, prefix := range p.allowedNets {
+		if prefix.Contains(addr) {
+			return ""
+		}
+	}
+	return ReasonPrivate
+}
+
+// block counts a blocked request and returns err
+func (p *Policy) block(client, reason string, err error) error {
+	if p.metrics != nil {
+		p.metrics.EgressBlocked.WithLabelValues(client, reason).Inc()
+	}
+	return err
+}
+
+// isInternal reports whether addr is not publicly routable
+func isInternal(addr netip.Addr) bool {
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range internalPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeAddress names the kind of address that was blocked
+func describeAddress(addr netip.Addr, reason string) string {
+	switch {
+	case reason == ReasonAddress:
+		return fmt.Sprintf("%s, in a denied range", addr)
+	case addr.IsLoopback():
+		return fmt.Sprintf("loopback address %s", addr)
+	case addr.IsLinkLocalUnicast():
+		return fmt.Sprintf("link-local address %s", addr)
+	case addr.IsPrivate():
+		return fmt.Sprintf("private address %s", addr)
+	default:
+		return fmt.Sprintf("internal address %s", addr)
+	}
+}
+
+// matchHost reports whether host matches pattern, an exact host or "*.example.com" for any
+// subdomain of example.com
+func matchHost(pattern, host string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return pattern == host
+}
+
+// normalizeHost lowercases a host and strips IPv6 brackets and a trailing dot
+func normalizeHost(host string) string {
+	host = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(host), "["), "]")
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// parsePrefixes parses CIDR ranges; a bare address is a range of one
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if addr, err := netip.ParseAddr(value); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
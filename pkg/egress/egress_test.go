@@ -0,0 +1,124 @@
+package egress
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPolicy(t *testing.T, cfg config.EgressConfig) *Policy {
+	t.Helper()
+	policy, err := NewPolicy(&cfg)
+	require.NoError(t, err)
+	return policy
+}
+
+func TestPolicy_CheckURL(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		cfg     config.EgressConfig
+		url     string
+		blocked bool
+	}{
+		{name: "public address", url: "https://93.184.216.34/hook"},
+		{name: "non-http scheme", url: "ftp://93.184.216.34/file", blocked: true},
+		{name: "file scheme", url: "file:///etc/passwd", blocked: true},
+		{name: "loopback", url: "http://127.0.0.1:8080/admin", blocked: true},
+		{name: "IPv6 loopback", url: "http://[::1]/admin", blocked: true},
+		{name: "IPv4-mapped IPv6 loopback", url: "http://[::ffff:127.0.0.1]/admin", blocked: true},
+		{name: "cloud metadata", url: "http://169.254.169.254/latest/meta-data", blocked: true},
+		{name: "private network", url: "http://10.0.0.5/hook", blocked: true},
+		{name: "carrier-grade NAT", url: "http://100.64.0.1/hook", blocked: true},
+		{name: "unspecified", url: "http://0.0.0.0/hook", blocked: true},
+		{name: "private addresses allowed", cfg: config.EgressConfig{AllowPrivate: true}, url: "http://10.0.0.5/hook"},
+		{name: "allowed range", cfg: config.EgressConfig{AllowedCIDRs: []string{"10.1.0.0/16"}}, url: "http://10.1.2.3/hook"},
+		{name: "outside allowed range", cfg: config.EgressConfig{AllowedCIDRs: []string{"10.1.0.0/16"}}, url: "http://10.2.0.1/hook", blocked: true},
+		{name: "allowed host", cfg: config.EgressConfig{AllowedHosts: []string{"127.0.0.1"}}, url: "http://127.0.0.1/hook"},
+		{
+			name:    "denied range beats private addresses allowed",
+			cfg:     config.EgressConfig{AllowPrivate: true, DeniedCIDRs: []string{"169.254.169.254"}},
+			url:     "http://169.254.169.254/latest/meta-data",
+			blocked: true,
+		},
+		{name: "denied public range", cfg: config.EgressConfig{DeniedCIDRs: []string{"93.184.216.0/24"}}, url: "https://93.184.216.34/hook", blocked: true},
+		{name: "denied host", cfg: config.EgressConfig{DeniedHosts: []string{"metadata.google.internal"}}, url: "http://Metadata.Google.Internal./", blocked: true},
+		{name: "denied subdomain", cfg: config.EgressConfig{DeniedHosts: []string{"*.internal.example.com"}}, url: "https://api.internal.example.com/", blocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestPolicy(t, tt.cfg).CheckURL(ctx, "test", tt.url)
+			if tt.blocked {
+				assert.ErrorIs(t, err, ErrBlockedDestination)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("nil policy allows everything", func(t *testing.T) {
+		var policy *Policy
+		assert.NoError(t, policy.CheckURL(ctx, "test", "http://127.0.0.1/admin"))
+	})
+}
+
+func TestPolicy_WithAllowedHosts(t *testing.T) {
+	ctx := context.Background()
+	policy := newTestPolicy(t, config.EgressConfig{})
+
+	allowed := policy.WithAllowedHosts("127.0.0.1")
+	assert.NoError(t, allowed.CheckURL(ctx, "test", "http://127.0.0.1/"))
+	assert.ErrorIs(t, policy.CheckURL(ctx, "test", "http://127.0.0.1/"), ErrBlockedDestination, "the original policy must be unchanged")
+
+	var nilPolicy *Policy
+	assert.Nil(t, nilPolicy.WithAllowedHosts("127.0.0.1"))
+}
+
+func TestPolicy_Client(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "ftp://example.com/file", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Run("refuses to connect to internal addresses", func(t *testing.T) {
+		client := newTestPolicy(t, config.EgressConfig{}).Client("test", 0)
+		_, err := client.Get(server.URL)
+		assert.ErrorIs(t, err, ErrBlockedDestination)
+		assert.Zero(t, requests)
+	})
+
+	t.Run("connects when private addresses are allowed", func(t *testing.T) {
+		client := newTestPolicy(t, config.EgressConfig{AllowPrivate: true}).Client("test", 0)
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("refuses redirects to other schemes", func(t *testing.T) {
+		client := newTestPolicy(t, config.EgressConfig{AllowPrivate: true}).Client("test", 0)
+		_, err := client.Get(server.URL + "/redirect")
+		assert.ErrorIs(t, err, ErrBlockedDestination)
+	})
+}
+
+func TestNewPolicy_InvalidRange(t *testing.T) {
+	_, err := NewPolicy(&config.EgressConfig{AllowedCIDRs: []string{"10.0.0.0/33"}})
+	assert.Error(t, err)
+
+	_, err = NewPolicy(&config.EgressConfig{DeniedCIDRs: []string{"not-a-range"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,122 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Dependency statuses
+const (
+	StatusUp      = "up"
+	StatusDown    = "down"
+	StatusUnknown = "unknown" // Not checked yet
+)
+
+// Checker is implemented by dependencies that can be pinged
+type Checker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// Dependency is a service the API depends on
+type Dependency struct {
+	Name    string
+	Checker Checker
+
+	// Critical dependencies must be reachable for the API to take traffic
+	Critical bool
+}
+
+// DependencyHealth is the outcome of a dependency's latest health check
+type DependencyHealth struct {
+	Status      string     `json:"status"`
+	Critical    bool       `json:"critical"`
+	LatencyMs   float64    `json:"latency_ms"`
+	LastChecked *time.Time `json:"last_checked,omitempty"`
+
+	// Err is why the dependency is down; it is logged rather than exposed
+	Err error `json:"-"`
+}
+
+// Check pings the dependencies concurrently, each within timeout, and returns their health by name
+func Check(ctx context.Context, dependencies []Dependency, timeout time.Duration) map[string]DependencyHealth {
+	results := make(map[string]DependencyHealth, len(dependencies))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, dependency := range dependencies {
+		wg.Add(1)
+		go func(dependency Dependency) {
+			defer wg.Done()
+
+			result := checkOne(ctx, dependency, timeout)
+
+			mu.Lock()
+			results[dependency.Name] = result
+			mu.Unlock()
+		}(dependency)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// checkOne pings a dependency within timeout, measuring how long it took to answer
+func checkOne(ctx context.Context, dependency Dependency, timeout time.Duration) DependencyHealth {
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := dependency.Checker.HealthCheck(pingCtx)
+	latency := time.Since(start)
+
+	result := DependencyHealth{
+		Status:      StatusUp,
+		Critical:    dependency.Critical,
+		LatencyMs:   float64(latency.Microseconds()) / 1000,
+		LastChecked: &start,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Err = err
+	}
+	return result
+}
+
+// HTTPChecker checks that an HTTP service is reachable. Any response below 500 counts, since the
+// request carries no credentials and the service may well reject it
+type HTTPChecker struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPChecker creates a checker requesting url
+func NewHTTPChecker(url string) *HTTPChecker {
+	return &HTTPChecker{
+		url:    url,
+		client: &http.Client{},
+	}
+}
+
+// HealthCheck requests the service's URL, bounded by ctx
+func (c *HTTPChecker) HealthCheck(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create health check request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChecker fails its health check with err after delay, or waits for the check to time out
+// when block is set
+type fakeChecker struct {
+	err   error
+	delay time.Duration
+	block bool
+}
+
+func (f *fakeChecker) HealthCheck(ctx context.Context) error {
+	if f.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	time.Sleep(f.delay)
+	return f.err
+}
+
+func TestCheck(t *testing.T) {
+	before := time.Now()
+	results := Check(context.Background(), []Dependency{
+		{Name: "database", Checker: &fakeChecker{delay: 20 * time.Millisecond}, Critical: true},
+		{Name: "redis", Checker: &fakeChecker{err: errors.New("connection refused")}, Critical: true},
+		{Name: "llm", Checker: &fakeChecker{block: true}},
+	}, 50*time.Millisecond)
+
+	require.Len(t, results, 3)
+
+	database := results["database"]
+	assert.Equal(t, StatusUp, database.Status)
+	assert.True(t, database.Critical)
+	assert.GreaterOrEqual(t, database.LatencyMs, 20.0, "latency is measured")
+	require.NotNil(t, database.LastChecked)
+	assert.False(t, database.LastChecked.Before(before))
+	assert.NoError(t, database.Err)
+
+	assert.Equal(t, StatusDown, results["redis"].Status)
+	assert.EqualError(t, results["redis"].Err, "connection refused")
+
+	llm := results["llm"]
+	assert.Equal(t, StatusDown, llm.Status, "a ping past the timeout is down")
+	assert.False(t, llm.Critical)
+	assert.ErrorIs(t, llm.Err, context.DeadlineExceeded)
+}
+
+func TestHTTPChecker(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	checker := NewHTTPChecker(server.URL)
+
+	assert.NoError(t, checker.HealthCheck(context.Background()))
+
+	status = http.StatusUnauthorized
+	assert.NoError(t, checker.HealthCheck(context.Background()), "a rejected request still reached the service")
+
+	status = http.StatusBadGateway
+	assert.Error(t, checker.HealthCheck(context.Background()))
+
+	server.Close()
+	assert.Error(t, checker.HealthCheck(context.Background()), "an unreachable service is down")
+}
@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Poller checks dependencies in the background and caches the results, so health endpoints
+// answer without pinging anything
+type Poller struct {
+	dependencies []Dependency
+	interval     time.Duration
+	timeout      time.Duration
+	logger       *logger.Logger
+
+	mu      sync.RWMutex
+	results map[string]DependencyHealth
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewPoller creates a poller checking dependencies every interval, each ping bounded by timeout.
+// Until the first check, every dependency is reported as StatusUnknown
+func NewPoller(dependencies []Dependency, interval, timeout time.Duration, log *logger.Logger) *Poller {
+	if interval <= 0 {
+		interval = 15 * time.Second // Default to 15 seconds
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	results := make(map[string]DependencyHealth, len(dependencies))
+	for _, dependency := range dependencies {
+		results[dependency.Name] = DependencyHealth{Status: StatusUnknown, Critical: dependency.Critical}
+	}
+
+	return &Poller{
+		dependencies: dependencies,
+		interval:     interval,
+		timeout:      timeout,
+		logger:       log,
+		results:      results,
+		stopCh:       make(chan struct{}),
+		doneCh:       make(chan struct{}),
+	}
+}
+
+// Start starts polling in the background
+func (p *Poller) Start(ctx context.Context) {
+	p.logger.Info("Starting health poller",
+		logger.String("interval", p.interval.String()),
+	)
+
+	go p.run(ctx)
+}
+
+// Stop stops polling gracefully
+func (p *Poller) Stop() {
+	p.logger.Info("Stopping health poller")
+	close(p.stopCh)
+	<-p.doneCh
+	p.logger.Info("Health poller stopped")
+}
+
+// run is the main polling loop
+func (p *Poller) run(ctx context.Context) {
+	defer close(p.doneCh)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	// Poll immediately on start
+	p.Poll(ctx)
+
+	for {
+		select {
+		case <-ticker.C:
+			p.Poll(ctx)
+		case <-p.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Poll checks every dependency once and caches the results. Dependencies going down or coming
+// back up are logged
+func (p *Poller) Poll(ctx context.Context) {
+	results := Check(ctx, p.dependencies, p.timeout)
+
+	p.mu.Lock()
+	previous := p.results
+	p.results = results
+	p.mu.Unlock()
+
+	for name, result := range results {
+		if result.Status == previous[name].Status {
+			continue
+		}
+		if result.Status == StatusDown {
+			p.logger.Error("Dependency health check failed",
+				logger.String("dependency", name),
+				zap.Error(result.Err),
+			)
+		} else if previous[name].Status == StatusDown {
+			p.logger.Info("Dependency recovered", logger.String("dependency", name))
+		}
+	}
+}
+
+// Results returns the latest health of each dependency by name
+func (p *Poller) Results() map[string]DependencyHealth {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	results := make(map[string]DependencyHealth, len(p.results))
+	for name, result := range p.results {
+		results[name] = result
+	}
+	return results
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingChecker counts its health checks, failing while down is set
+type countingChecker struct {
+	checks atomic.Int32
+	down   atomic.Bool
+}
+
+func (c *countingChecker) HealthCheck(ctx context.Context) error {
+	c.checks.Add(1)
+	if c.down.Load() {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestPoller_Results(t *testing.T) {
+	checker := &countingChecker{}
+	poller := NewPoller([]Dependency{{Name: "database", Checker: checker, Critical: true}}, time.Minute, time.Second, logger.NewForTesting())
+
+	results := poller.Results()
+	assert.Equal(t, StatusUnknown, results["database"].Status, "nothing is checked before the first poll")
+	assert.True(t, results["database"].Critical)
+	assert.Nil(t, results["database"].LastChecked)
+
+	poller.Poll(context.Background())
+	assert.Equal(t, StatusUp, poller.Results()["database"].Status)
+
+	checker.down.Store(true)
+	poller.Poll(context.Background())
+	assert.Equal(t, StatusDown, poller.Results()["database"].Status)
+
+	checks := checker.checks.Load()
+	poller.Results()
+	poller.Results()
+	assert.Equal(t, checks, checker.checks.Load(), "results are served from cache")
+}
+
+func TestPoller_StartStop(t *testing.T) {
+	checker := &countingChecker{}
+	poller := NewPoller([]Dependency{{Name: "redis", Checker: checker}}, 10*time.Millisecond, time.Second, logger.NewForTesting())
+
+	poller.Start(context.Background())
+	require.Eventually(t, func() bool { return checker.checks.Load() >= 3 }, time.Second, 5*time.Millisecond, "dependencies are polled every interval")
+	poller.Stop()
+
+	checks := checker.checks.Load()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, checks, checker.checks.Load(), "polling stops")
+	assert.Equal(t, StatusUp, poller.Results()["redis"].Status)
+}
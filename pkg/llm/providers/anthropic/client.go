@@ -11,6 +11,9 @@ import (
 	"github.com/liushuangls/go-anthropic/v2"
 )
 
+// responseToolName is the tool Claude is made to call with a structured response
+const responseToolName = "structured_response"
+
 // Client implements the LLM Client interface for Anthropic
 type Client struct {
 	client       *anthropic.Client
@@ -112,6 +115,7 @@ func (c *Client) StreamChat(ctx context.Context, req *llm.ChatRequest, handler l
 		IsComplete:   true,
 		Usage:        resp.Usage,
 		FinishReason: resp.FinishReason,
+		Model:        resp.Model,
 	})
 }
 
@@ -191,6 +195,16 @@ func (c *Client) buildAnthropicRequest(req *llm.ChatRequest) anthropic.MessagesR
 		anthropicReq.StopSequences = req.StopSequences
 	}
 
+	// Force a tool call whose input is the structured response
+	if req.ResponseSchema != nil {
+		anthropicReq.Tools = []anthropic.ToolDefinition{{
+			Name:        responseToolName,
+			Description: "Record the response in the required structure",
+			InputSchema: req.ResponseSchema,
+		}}
+		anthropicReq.ToolChoice = &anthropic.ToolChoice{Type: "tool", Name: responseToolName}
+	}
+
 	return anthropicReq
 }
 
@@ -204,6 +218,14 @@ func (c *Client) mapResponse(resp *anthropic.MessagesResponse, model string) *ll
 		}
 	}
 
+	// A structured response replaces any text around the tool call
+	for _, block := range resp.Content {
+		if block.Type == anthropic.MessagesContentTypeToolUse && block.MessageContentToolUse != nil &&
+			block.MessageContentToolUse.Name == responseToolName {
+			content = string(block.MessageContentToolUse.Input)
+		}
+	}
+
 	return &llm.ChatResponse{
 		ID:       resp.ID,
 		Content:  content,
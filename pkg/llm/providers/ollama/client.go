@@ -0,0 +1,416 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/llm"
+)
+
+const (
+	// defaultBaseURL is where a local Ollama server listens
+	defaultBaseURL = "http://localhost:11434"
+
+	// defaultModel is used when neither the request nor the config names a model
+	defaultModel = "llama3.2"
+)
+
+// Client implements the LLM Client interface for a local or self-hosted Ollama server
+type Client struct {
+	httpClient   *http.Client
+	baseURL      string
+	config       *llm.Config
+	capabilities *llm.Capabilities
+}
+
+// NewClient creates a new Ollama client. The API key is optional, and is sent as a bearer token
+// for servers behind an authenticating proxy
+func NewClient(config *llm.Config) (*Client, error) {
+	baseURL := strings.TrimSuffix(config.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
+	httpClient := &http.Client{}
+	if config.Timeout > 0 {
+		httpClient.Timeout = config.Timeout
+	}
+
+	c := &Client{
+		httpClient:   httpClient,
+		baseURL:      baseURL,
+		config:       config,
+		capabilities: buildCapabilities(),
+	}
+
+	return c, nil
+}
+
+// chatRequest is the body of a request to Ollama's chat endpoint
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Format   interface{}   `json:"format,omitempty"` // JSON schema the response must match
+	Options  *chatOptions  `json:"options,omitempty"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatOptions struct {
+	Temperature float64  `json:"temperature,omitempty"`
+	TopP        float64  `json:"top_p,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"` // Maximum tokens to generate
+	Stop        []string `json:"stop,omitempty"`
+}
+
+// chatResponse is a response from Ollama's chat endpoint, or one line of a streamed response
+type chatResponse struct {
+	Model           string      `json:"model"`
+	CreatedAt       time.Time   `json:"created_at"`
+	Message         chatMessage `json:"message"`
+	Done            bool        `json:"done"`
+	DoneReason      string      `json:"done_reason,omitempty"`
+	PromptEvalCount int         `json:"prompt_eval_count,omitempty"`
+	EvalCount       int         `json:"eval_count,omitempty"`
+	Error           string      `json:"error,omitempty"`
+}
+
+// Chat sends a chat completion request
+func (c *Client) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	if err := c.validateRequest(req); err != nil {
+		return nil, err
+	}
+
+	// Build the Ollama request
+	ollamaReq := c.buildOllamaRequest(req, false)
+
+	// Execute the request with retries
+	var resp chatResponse
+	var err error
+
+	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			// Wait before retry
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(c.config.RetryDelay * time.Duration(attempt)):
+			}
+		}
+
+		resp, err = c.chat(ctx, ollamaReq)
+		if err == nil {
+			break
+		}
+
+		// Check if error is retryable
+		if !llm.IsRetryable(err) {
+			break
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c.mapResponse(&resp), nil
+}
+
+// chat sends a non-streaming request and decodes the response
+func (c *Client) chat(ctx context.Context, ollamaReq *chatRequest) (chatResponse, error) {
+	var resp chatResponse
+
+	httpResp, err := c.post(ctx, ollamaReq)
+	if err != nil {
+		return resp, err
+	}
+	defer httpResp.Body.Close()
+
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return resp, c.mapError(err)
+	}
+	if resp.Error != "" {
+		return resp, llm.NewError(llm.ProviderOllama, llm.ErrorTypeUnknown, resp.Error, nil)
+	}
+
+	return resp, nil
+}
+
+// StreamChat sends a streaming chat completion request
+func (c *Client) StreamChat(ctx context.Context, req *llm.ChatRequest, handler llm.StreamHandler) error {
+	if err := c.validateRequest(req); err != nil {
+		return err
+	}
+
+	httpResp, err := c.post(ctx, c.buildOllamaRequest(req, true))
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	// Ollama streams one JSON object per line, the last with done set
+	decoder := json.NewDecoder(httpResp.Body)
+	for {
+		var chunk chatResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				return llm.NewError(llm.ProviderOllama, llm.ErrorTypeServiceUnavailable, "stream ended before the response was complete", nil)
+			}
+			return c.mapError(err)
+		}
+		if chunk.Error != "" {
+			return llm.NewError(llm.ProviderOllama, llm.ErrorTypeUnknown, chunk.Error, nil)
+		}
+
+		if chunk.Message.Content != "" {
+			if err := handler(&llm.StreamChunk{
+				Delta:      chunk.Message.Content,
+				IsComplete: false,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if chunk.Done {
+			// Send final chunk
+			return handler(&llm.StreamChunk{
+				Delta:        "",
+				IsComplete:   true,
+				Usage:        mapUsage(&chunk),
+				FinishReason: chunk.DoneReason,
+				Model:        chunk.Model,
+			})
+		}
+	}
+}
+
+// GetCapabilities returns the capabilities of the Ollama provider
+func (c *Client) GetCapabilities() *llm.Capabilities {
+	return c.capabilities
+}
+
+// GetProvider returns the provider type
+func (c *Client) GetProvider() llm.Provider {
+	return llm.ProviderOllama
+}
+
+// Close closes the client
+func (c *Client) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// post sends a request to the chat endpoint, returning an error for any non-200 response
+func (c *Client) post(ctx context.Context, ollamaReq *chatRequest) (*http.Response, error) {
+	body, err := json.Marshal(ollamaReq)
+	if err != nil {
+		return nil, llm.NewError(llm.ProviderOllama, llm.ErrorTypeInvalidRequest, "failed to encode request", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, llm.NewError(llm.ProviderOllama, llm.ErrorTypeInvalidRequest, "failed to create request", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.config.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, c.mapError(err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		defer httpResp.Body.Close()
+		return nil, c.mapStatusError(httpResp)
+	}
+
+	return httpResp, nil
+}
+
+// buildOllamaRequest converts our request to Ollama format
+func (c *Client) buildOllamaRequest(req *llm.ChatRequest, stream bool) *chatRequest {
+	model := req.Model
+	if model == "" {
+		model = c.config.DefaultModel
+	}
+	if model == "" {
+		model = defaultModel
+	}
+
+	// Convert messages
+	messages := make([]chatMessage, 0, len(req.Messages)+1)
+
+	// Add system prompt first if provided
+	if req.SystemPrompt != "" {
+		messages = append(messages, chatMessage{
+			Role:    string(llm.RoleSystem),
+			Content: req.SystemPrompt,
+		})
+	}
+
+	// Add conversation messages
+	for _, msg := range req.Messages {
+		messages = append(messages, chatMessage{
+			Role:    string(msg.Role),
+			Content: msg.Content,
+		})
+	}
+
+	ollamaReq := &chatRequest{
+		Model:    model,
+		Messages: messages,
+		Stream:   stream,
+		Options: &chatOptions{
+			NumPredict:  req.MaxTokens,
+			Temperature: req.Temperature,
+			TopP:        req.TopP,
+			Stop:        req.StopSequences,
+		},
+	}
+
+	// Ask for a JSON response matching the schema
+	if req.ResponseSchema != nil {
+		ollamaReq.Format = req.ResponseSchema
+	}
+
+	return ollamaReq
+}
+
+// mapResponse converts Ollama response to our format
+func (c *Client) mapResponse(resp *chatResponse) *llm.ChatResponse {
+	return &llm.ChatResponse{
+		Content:      resp.Message.Content,
+		Model:        resp.Model,
+		Provider:     llm.ProviderOllama,
+		Usage:        mapUsage(resp),
+		FinishReason: resp.DoneReason,
+		CreatedAt:    resp.CreatedAt,
+	}
+}
+
+func mapUsage(resp *chatResponse) *llm.TokenUsage {
+	return &llm.TokenUsage{
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+		TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+	}
+}
+
+// mapStatusError converts an error response from Ollama to our error format
+func (c *Client) mapStatusError(resp *http.Response) error {
+	message := http.StatusText(resp.StatusCode)
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil && body.Error != "" {
+		message = body.Error
+	}
+
+	var errType llm.ErrorType
+	switch resp.StatusCode {
+	case http.StatusBadRequest:
+		errType = llm.ErrorTypeInvalidRequest
+	case http.StatusUnauthorized, http.StatusForbidden:
+		errType = llm.ErrorTypeAuthentication
+	case http.StatusNotFound:
+		// Ollama answers 404 for models that have not been pulled
+		errType = llm.ErrorTypeModelNotFound
+	case http.StatusTooManyRequests:
+		errType = llm.ErrorTypeRateLimit
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		errType = llm.ErrorTypeServiceUnavailable
+	default:
+		errType = llm.ErrorTypeUnknown
+	}
+
+	llmErr := llm.NewError(llm.ProviderOllama, errType, message, nil)
+	llmErr.StatusCode = resp.StatusCode
+	return llmErr
+}
+
+// mapError converts transport errors to our error format
+func (c *Client) mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// Check for timeouts, including the HTTP client's
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return llm.NewError(llm.ProviderOllama, llm.ErrorTypeTimeout, "request timeout", err)
+	}
+
+	// A server that cannot be reached, e.g. one that is still starting, may come up on a retry
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return llm.NewError(llm.ProviderOllama, llm.ErrorTypeServiceUnavailable, "ollama server unreachable", err)
+	}
+
+	// Default unknown error
+	return llm.NewError(llm.ProviderOllama, llm.ErrorTypeUnknown, err.Error(), err)
+}
+
+// validateRequest validates the request
+func (c *Client) validateRequest(req *llm.ChatRequest) error {
+	if len(req.Messages) == 0 {
+		return llm.NewError(llm.ProviderOllama, llm.ErrorTypeInvalidRequest, "messages cannot be empty", nil)
+	}
+
+	return nil
+}
+
+// buildCapabilities returns the capabilities of Ollama. Token limits depend on the model the
+// server runs, so they are left unset
+func buildCapabilities() *llm.Capabilities {
+	return &llm.Capabilities{
+		Provider:                llm.ProviderOllama,
+		SupportsStreaming:       true,
+		SupportsSystemPrompt:    true,
+		SupportsFunctionCalling: true,
+		SupportsVision:          false,
+		Models: []llm.ModelInfo{
+			{
+				ID:                defaultModel,
+				Name:              "Llama 3.2",
+				Description:       "Small Meta model suited to local development",
+				ContextWindow:     128000,
+				SupportsFunctions: true,
+			},
+			{
+				ID:                "llama3.1",
+				Name:              "Llama 3.1",
+				Description:       "Larger Meta model for higher quality on capable hardware",
+				ContextWindow:     128000,
+				SupportsFunctions: true,
+			},
+			{
+				ID:                "qwen2.5",
+				Name:              "Qwen 2.5",
+				Description:       "Alibaba model with strong structured output",
+				ContextWindow:     32768,
+				SupportsFunctions: true,
+			},
+			{
+				ID:                "mistral",
+				Name:              "Mistral",
+				Description:       "Mistral AI 7B model",
+				ContextWindow:     32768,
+				SupportsFunctions: true,
+			},
+		},
+	}
+}
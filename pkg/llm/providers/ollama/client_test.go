@@ -0,0 +1,149 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient returns a client for a server answering chat requests with handler, and the
+// requests the server received
+func newTestClient(t *testing.T, handler func(w http.ResponseWriter, req *chatRequest)) (*Client, *[]chatRequest) {
+	var requests []chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, req)
+		handler(w, &req)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(&llm.Config{Provider: llm.ProviderOllama, BaseURL: server.URL + "/"})
+	require.NoError(t, err)
+	return client, &requests
+}
+
+func TestClient_Chat(t *testing.T) {
+	client, requests := newTestClient(t, func(w http.ResponseWriter, req *chatRequest) {
+		fmt.Fprint(w, `{"model":"llama3.2","message":{"role":"assistant","content":"{\"label\":\"billing\"}"},"done":true,"done_reason":"stop","prompt_eval_count":20,"eval_count":5}`)
+	})
+
+	req := llm.NewPromptBuilder().
+		SetSystemPrompt("Classify the ticket").
+		AddUserMessage("I was charged twice").
+		BuildWithOptions(llm.WithMaxTokens(256), llm.WithTemperature(0.2))
+	req.ResponseSchema = map[string]interface{}{"type": "object"}
+
+	resp, err := client.Chat(context.Background(), req)
+
+	require.NoError(t, err)
+	assert.Equal(t, `{"label":"billing"}`, resp.Content)
+	assert.Equal(t, llm.ProviderOllama, resp.Provider)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, 25, resp.Usage.TotalTokens)
+
+	require.Len(t, *requests, 1)
+	sent := (*requests)[0]
+	assert.Equal(t, defaultModel, sent.Model)
+	assert.False(t, sent.Stream)
+	require.Len(t, sent.Messages, 2)
+	assert.Equal(t, "system", sent.Messages[0].Role)
+	assert.Equal(t, "Classify the ticket", sent.Messages[0].Content)
+	assert.Equal(t, 256, sent.Options.NumPredict)
+	assert.Equal(t, 0.2, sent.Options.Temperature)
+	assert.Equal(t, map[string]interface{}{"type": "object"}, sent.Format)
+}
+
+func TestClient_StreamChat(t *testing.T) {
+	client, requests := newTestClient(t, func(w http.ResponseWriter, req *chatRequest) {
+		for _, token := range []string{"Hel", "lo"} {
+			fmt.Fprintf(w, `{"message":{"role":"assistant","content":%q},"done":false}`+"\n", token)
+		}
+		fmt.Fprint(w, `{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":3,"eval_count":2}`+"\n")
+	})
+
+	var deltas []string
+	var final *llm.StreamChunk
+	err := client.StreamChat(context.Background(), llm.NewPromptBuilder().AddUserMessage("Hi").Build(), func(chunk *llm.StreamChunk) error {
+		if chunk.IsComplete {
+			final = chunk
+		} else {
+			deltas = append(deltas, chunk.Delta)
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hel", "lo"}, deltas)
+	require.NotNil(t, final)
+	assert.Equal(t, "stop", final.FinishReason)
+	assert.Equal(t, 5, final.Usage.TotalTokens)
+	require.Len(t, *requests, 1)
+	assert.True(t, (*requests)[0].Stream)
+}
+
+func TestClient_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		errType   llm.ErrorType
+		retryable bool
+	}{
+		{"model not pulled", http.StatusNotFound, `{"error":"model \"llama3.2\" not found, try pulling it first"}`, llm.ErrorTypeModelNotFound, false},
+		{"bad request", http.StatusBadRequest, `{"error":"invalid format"}`, llm.ErrorTypeInvalidRequest, false},
+		{"server overloaded", http.StatusServiceUnavailable, `{"error":"server busy"}`, llm.ErrorTypeServiceUnavailable, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t, func(w http.ResponseWriter, req *chatRequest) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			_, err := client.Chat(context.Background(), llm.NewPromptBuilder().AddUserMessage("Hi").Build())
+
+			var llmErr *llm.Error
+			require.True(t, errors.As(err, &llmErr), "expected an llm error, got %v", err)
+			assert.Equal(t, tt.errType, llmErr.Type)
+			assert.Equal(t, tt.status, llmErr.StatusCode)
+			assert.Equal(t, tt.retryable, llm.IsRetryable(err))
+		})
+	}
+
+	t.Run("unreachable server", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+		client, err := NewClient(&llm.Config{Provider: llm.ProviderOllama, BaseURL: server.URL})
+		require.NoError(t, err)
+
+		_, err = client.Chat(context.Background(), llm.NewPromptBuilder().AddUserMessage("Hi").Build())
+
+		assert.True(t, llm.IsRetryable(err), "expected a retryable error, got %v", err)
+	})
+
+	t.Run("empty messages", func(t *testing.T) {
+		client, requests := newTestClient(t, func(w http.ResponseWriter, req *chatRequest) {})
+
+		_, err := client.Chat(context.Background(), &llm.ChatRequest{})
+
+		assert.ErrorIs(t, err, llm.ErrInvalidRequest)
+		assert.Empty(t, *requests)
+	})
+}
@@ -0,0 +1,33 @@
+package openai
+
+import (
+	"fmt"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/llm"
+	"github.com/sashabaranov/go-openai"
+)
+
+// azureAPIVersion is the Azure OpenAI API version requests are sent with. It is the first
+// generally available version with structured outputs, which extraction steps rely on
+const azureAPIVersion = "2024-10-21"
+
+// NewAzureClient creates a client for an Azure OpenAI resource. BaseURL is the resource endpoint,
+// e.g. https://my-resource.openai.azure.com, and models are addressed by deployment name, so
+// DefaultModel and per-request models must name deployments of the resource
+func NewAzureClient(config *llm.Config) (*Client, error) {
+	if config.APIKey == "" {
+		return nil, llm.ErrInvalidAPIKey
+	}
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("azure_openai requires the resource endpoint as the base URL")
+	}
+
+	clientConfig := openai.DefaultAzureConfig(config.APIKey, config.BaseURL)
+	clientConfig.APIVersion = azureAPIVersion
+	// Deployment names are used as written; the default mapper strips dots from them
+	clientConfig.AzureModelMapperFunc = func(model string) string {
+		return model
+	}
+
+	return newClient(config, clientConfig, llm.ProviderAzureOpenAI), nil
+}
@@ -2,6 +2,7 @@ package openai
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -12,10 +13,11 @@ import (
 	"github.com/sashabaranov/go-openai"
 )
 
-// Client implements the LLM Client interface for OpenAI
+// Client implements the LLM Client interface for OpenAI and the OpenAI-compatible Azure OpenAI
 type Client struct {
 	client       *openai.Client
 	config       *llm.Config
+	provider     llm.Provider
 	capabilities *llm.Capabilities
 }
 
@@ -31,21 +33,23 @@ func NewClient(config *llm.Config) (*Client, error) {
 		clientConfig.BaseURL = config.BaseURL
 	}
 
+	return newClient(config, clientConfig, llm.ProviderOpenAI), nil
+}
+
+// newClient creates a client sending requests as configured by clientConfig
+func newClient(config *llm.Config, clientConfig openai.ClientConfig, provider llm.Provider) *Client {
 	if config.Timeout > 0 {
 		clientConfig.HTTPClient = &http.Client{
 			Timeout: config.Timeout,
 		}
 	}
 
-	client := openai.NewClientWithConfig(clientConfig)
-
-	c := &Client{
-		client:       client,
+	return &Client{
+		client:       openai.NewClientWithConfig(clientConfig),
 		config:       config,
-		capabilities: buildCapabilities(),
+		provider:     provider,
+		capabilities: buildCapabilities(provider),
 	}
-
-	return c, nil
 }
 
 // Chat sends a chat completion request
@@ -95,8 +99,9 @@ func (c *Client) StreamChat(ctx context.Context, req *llm.ChatRequest, handler l
 		return err
 	}
 
-	// Build the OpenAI request
+	// Build the OpenAI request, asking for usage in the last chunk
 	openaiReq := c.buildOpenAIRequest(req)
+	openaiReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
 
 	// Create streaming request
 	stream, err := c.client.CreateChatCompletionStream(ctx, openaiReq)
@@ -107,6 +112,7 @@ func (c *Client) StreamChat(ctx context.Context, req *llm.ChatRequest, handler l
 
 	var totalUsage *llm.TokenUsage
 	var finishReason string
+	var model string
 
 	// Process stream
 	for {
@@ -119,6 +125,10 @@ func (c *Client) StreamChat(ctx context.Context, req *llm.ChatRequest, handler l
 			return c.mapError(err)
 		}
 
+		if response.Model != "" {
+			model = response.Model
+		}
+
 		// Extract delta content
 		if len(response.Choices) > 0 {
 			choice := response.Choices[0]
@@ -140,8 +150,8 @@ func (c *Client) StreamChat(ctx context.Context, req *llm.ChatRequest, handler l
 			}
 		}
 
-		// Extract usage (typically in the last chunk)
-		if response.Usage.TotalTokens > 0 {
+		// Extract usage (only sent in the last chunk)
+		if response.Usage != nil {
 			totalUsage = &llm.TokenUsage{
 				PromptTokens:     response.Usage.PromptTokens,
 				CompletionTokens: response.Usage.CompletionTokens,
@@ -156,6 +166,7 @@ func (c *Client) StreamChat(ctx context.Context, req *llm.ChatRequest, handler l
 		IsComplete:   true,
 		Usage:        totalUsage,
 		FinishReason: finishReason,
+		Model:        model,
 	}
 	if err := handler(finalChunk); err != nil {
 		return err
@@ -164,14 +175,14 @@ func (c *Client) StreamChat(ctx context.Context, req *llm.ChatRequest, handler l
 	return nil
 }
 
-// GetCapabilities returns the capabilities of the OpenAI provider
+// GetCapabilities returns the capabilities of the provider
 func (c *Client) GetCapabilities() *llm.Capabilities {
 	return c.capabilities
 }
 
 // GetProvider returns the provider type
 func (c *Client) GetProvider() llm.Provider {
-	return llm.ProviderOpenAI
+	return c.provider
 }
 
 // Close closes the client
@@ -236,6 +247,20 @@ func (c *Client) buildOpenAIRequest(req *llm.ChatRequest) openai.ChatCompletionR
 		openaiReq.Stop = req.StopSequences
 	}
 
+	// Ask for a JSON response matching the schema
+	if req.ResponseSchema != nil {
+		schema, err := json.Marshal(req.ResponseSchema)
+		if err == nil {
+			openaiReq.ResponseFormat = &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+				JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+					Name:   "structured_response",
+					Schema: json.RawMessage(schema),
+				},
+			}
+		}
+	}
+
 	// Add user identifier if present in metadata
 	if userID, ok := req.Metadata["user_id"]; ok {
 		openaiReq.User = userID
@@ -259,7 +284,7 @@ func (c *Client) mapResponse(resp *openai.ChatCompletionResponse) *llm.ChatRespo
 		ID:       resp.ID,
 		Content:  content,
 		Model:    resp.Model,
-		Provider: llm.ProviderOpenAI,
+		Provider: c.provider,
 		Usage: &llm.TokenUsage{
 			PromptTokens:     resp.Usage.PromptTokens,
 			CompletionTokens: resp.Usage.CompletionTokens,
@@ -281,50 +306,50 @@ func (c *Client) mapError(err error) error {
 	if errors.As(err, &apiErr) {
 		switch apiErr.HTTPStatusCode {
 		case http.StatusUnauthorized:
-			return llm.NewError(llm.ProviderOpenAI, llm.ErrorTypeAuthentication, apiErr.Message, err)
+			return llm.NewError(c.provider, llm.ErrorTypeAuthentication, apiErr.Message, err)
 		case http.StatusTooManyRequests:
-			return llm.NewError(llm.ProviderOpenAI, llm.ErrorTypeRateLimit, apiErr.Message, err)
+			return llm.NewError(c.provider, llm.ErrorTypeRateLimit, apiErr.Message, err)
 		case http.StatusBadRequest:
 			if apiErr.Code == "context_length_exceeded" {
-				return llm.NewError(llm.ProviderOpenAI, llm.ErrorTypeContextLengthExceeded, apiErr.Message, err)
+				return llm.NewError(c.provider, llm.ErrorTypeContextLengthExceeded, apiErr.Message, err)
 			}
-			return llm.NewError(llm.ProviderOpenAI, llm.ErrorTypeInvalidRequest, apiErr.Message, err)
+			return llm.NewError(c.provider, llm.ErrorTypeInvalidRequest, apiErr.Message, err)
 		case http.StatusNotFound:
-			return llm.NewError(llm.ProviderOpenAI, llm.ErrorTypeModelNotFound, apiErr.Message, err)
+			return llm.NewError(c.provider, llm.ErrorTypeModelNotFound, apiErr.Message, err)
 		case http.StatusServiceUnavailable, http.StatusBadGateway:
-			return llm.NewError(llm.ProviderOpenAI, llm.ErrorTypeServiceUnavailable, apiErr.Message, err)
+			return llm.NewError(c.provider, llm.ErrorTypeServiceUnavailable, apiErr.Message, err)
 		default:
-			return llm.NewError(llm.ProviderOpenAI, llm.ErrorTypeUnknown, apiErr.Message, err)
+			return llm.NewError(c.provider, llm.ErrorTypeUnknown, apiErr.Message, err)
 		}
 	}
 
 	// Check for context errors
 	if err == context.DeadlineExceeded || err == context.Canceled {
-		return llm.NewError(llm.ProviderOpenAI, llm.ErrorTypeTimeout, "request timeout", err)
+		return llm.NewError(c.provider, llm.ErrorTypeTimeout, "request timeout", err)
 	}
 
 	// Default unknown error
-	return llm.NewError(llm.ProviderOpenAI, llm.ErrorTypeUnknown, err.Error(), err)
+	return llm.NewError(c.provider, llm.ErrorTypeUnknown, err.Error(), err)
 }
 
 // validateRequest validates the request
 func (c *Client) validateRequest(req *llm.ChatRequest) error {
 	if len(req.Messages) == 0 {
-		return llm.NewError(llm.ProviderOpenAI, llm.ErrorTypeInvalidRequest, "messages cannot be empty", nil)
+		return llm.NewError(c.provider, llm.ErrorTypeInvalidRequest, "messages cannot be empty", nil)
 	}
 
 	if req.MaxTokens > 16384 {
-		return llm.NewError(llm.ProviderOpenAI, llm.ErrorTypeInvalidRequest,
+		return llm.NewError(c.provider, llm.ErrorTypeInvalidRequest,
 			fmt.Sprintf("max_tokens %d exceeds limit of 16384", req.MaxTokens), nil)
 	}
 
 	return nil
 }
 
-// buildCapabilities returns the capabilities of OpenAI
-func buildCapabilities() *llm.Capabilities {
+// buildCapabilities returns the capabilities of OpenAI, as served by the provider
+func buildCapabilities(provider llm.Provider) *llm.Capabilities {
 	return &llm.Capabilities{
-		Provider:                llm.ProviderOpenAI,
+		Provider:                provider,
 		SupportsStreaming:       true,
 		SupportsSystemPrompt:    true,
 		MaxTokensLimit:          16384,
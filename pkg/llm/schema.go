@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ValidateJSONSchema checks a value decoded from JSON against a JSON schema. It supports the
+// type, enum, properties, required and items keywords and ignores the rest. It returns one
+// problem per mismatch, prefixed with the path of the mismatched value, e.g. "$.address.zip"
+func ValidateJSONSchema(schema map[string]interface{}, value interface{}) []string {
+	var problems []string
+	validateSchema("$", schema, value, &problems)
+	return problems
+}
+
+func validateSchema(path string, schema map[string]interface{}, value interface{}, problems *[]string) {
+	if types := schemaStrings(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if matchesSchemaType(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(types, " or "), schemaType(value)))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		matched := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			*problems = append(*problems, fmt.Sprintf("%s: must be one of %v", path, enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schemaStrings(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: missing required property %s", path, name))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			propertySchema, ok := properties[name].(map[string]interface{})
+			if property, exists := v[name]; ok && exists {
+				validateSchema(path+"."+name, propertySchema, property, problems)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateSchema(fmt.Sprintf("%s[%d]", path, i), items, item, problems)
+			}
+		}
+	}
+}
+
+// schemaStrings reads a keyword holding a string or a list of strings
+func schemaStrings(keyword interface{}) []string {
+	switch v := keyword.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func matchesSchemaType(t string, value interface{}) bool {
+	if t == "integer" {
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	}
+	return schemaType(value) == t
+}
+
+// schemaType returns the JSON schema type of a value decoded from JSON
+func schemaType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
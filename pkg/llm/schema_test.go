@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateJSONSchema(t *testing.T) {
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["name", "address"],
+		"properties": {
+			"name": {"type": "string"},
+			"quantity": {"type": "integer"},
+			"priority": {"enum": ["low", "high"]},
+			"notes": {"type": ["string", "null"]},
+			"address": {
+				"type": "object",
+				"required": ["zip"],
+				"properties": {"zip": {"type": "string"}}
+			},
+			"tags": {"type": "array", "items": {"type": "string"}}
+		}
+	}`), &schema))
+
+	tests := []struct {
+		name     string
+		value    string
+		problems []string
+	}{
+		{
+			name:  "valid",
+			value: `{"name": "Ada", "quantity": 2, "priority": "high", "notes": null, "address": {"zip": "94103"}, "tags": ["a"], "extra": true}`,
+		},
+		{
+			name:     "missing required properties",
+			value:    `{"address": {}}`,
+			problems: []string{"$: missing required property name", "$.address: missing required property zip"},
+		},
+		{
+			name:  "wrong types",
+			value: `{"name": 1, "quantity": 1.5, "priority": "medium", "address": {"zip": 94103}, "tags": ["a", 2]}`,
+			problems: []string{
+				"$.address.zip: expected string, got number",
+				"$.name: expected string, got number",
+				"$.priority: must be one of [low high]",
+				"$.quantity: expected integer, got number",
+				"$.tags[1]: expected string, got number",
+			},
+		},
+		{
+			name:     "not an object",
+			value:    `["Ada"]`,
+			problems: []string{"$: expected object, got array"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.value), &value))
+
+			assert.Equal(t, tt.problems, ValidateJSONSchema(schema, value))
+		})
+	}
+}
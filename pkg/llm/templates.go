@@ -232,6 +232,25 @@ Focus on:
 
 Provide clear, actionable insights.`
 
+	// ClassificationTemplate constrains a response to one of a set of labels
+	ClassificationTemplate = `You are a classifier. Classify the text in the user's message into exactly one of these labels:
+{{range .Labels}}
+- {{.}}
+{{end}}
+{{if .Instructions}}
+{{.Instructions}}
+{{end}}
+Respond with only a JSON object of the form {"label": "<label>"}, where <label> is one of the labels above written exactly as listed. Do not include any other text.`
+
+	// ExtractionTemplate asks for the fields of a JSON schema found in a text
+	ExtractionTemplate = `You extract structured data. Extract the object described by this JSON schema from the text in the user's message:
+
+{{.Schema}}
+{{if .Instructions}}
+{{.Instructions}}
+{{end}}
+Respond with only a JSON object matching the schema. Leave out properties the text does not mention unless the schema requires them. Do not include any other text.`
+
 	// SummarizationTemplate helps summarize content
 	SummarizationTemplate = `Summarize the following content in {{.MaxWords}} words or less:
 
@@ -248,6 +267,8 @@ func GetDefaultTemplates() (*TemplateManager, error) {
 		"workflow_interpretation": WorkflowInterpretationTemplate,
 		"code_generation":         CodeGenerationTemplate,
 		"data_analysis":           DataAnalysisTemplate,
+		"classification":          ClassificationTemplate,
+		"extraction":              ExtractionTemplate,
 		"summarization":           SummarizationTemplate,
 	}
 
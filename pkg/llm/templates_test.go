@@ -105,6 +105,8 @@ func TestGetDefaultTemplates(t *testing.T) {
 	assert.True(t, tm.Exists("code_generation"))
 	assert.True(t, tm.Exists("data_analysis"))
 	assert.True(t, tm.Exists("summarization"))
+	assert.True(t, tm.Exists("classification"))
+	assert.True(t, tm.Exists("extraction"))
 }
 
 func TestRequestOptions(t *testing.T) {
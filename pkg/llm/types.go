@@ -9,10 +9,27 @@ import (
 type Provider string
 
 const (
-	ProviderAnthropic Provider = "anthropic"
-	ProviderOpenAI    Provider = "openai"
+	ProviderAnthropic   Provider = "anthropic"
+	ProviderOpenAI      Provider = "openai"
+	ProviderAzureOpenAI Provider = "azure_openai"
+	ProviderOllama      Provider = "ollama"
 )
 
+// DefaultBaseURL returns the API endpoint a provider is reached at when Config.BaseURL is not set,
+// or "" for Azure OpenAI, whose endpoint is per resource
+func DefaultBaseURL(provider Provider) string {
+	switch provider {
+	case ProviderAnthropic:
+		return "https://api.anthropic.com"
+	case ProviderOpenAI:
+		return "https://api.openai.com/v1"
+	case ProviderOllama:
+		return "http://localhost:11434"
+	default:
+		return ""
+	}
+}
+
 // Client defines the interface for LLM providers
 type Client interface {
 	// Chat sends a chat completion request and returns the response
@@ -54,6 +71,11 @@ type ChatRequest struct {
 	// SystemPrompt is the system message (for providers that support it)
 	SystemPrompt string `json:"system_prompt,omitempty"`
 
+	// ResponseSchema is a JSON schema the response must match, using the provider's structured
+	// output support (JSON schema response format or a forced tool call). The JSON object is
+	// returned as the response content
+	ResponseSchema map[string]interface{} `json:"response_schema,omitempty"`
+
 	// Metadata for tracking and debugging
 	Metadata map[string]string `json:"metadata,omitempty"`
 }
@@ -132,10 +154,28 @@ type StreamChunk struct {
 	// Usage is only present in the final chunk
 	Usage *TokenUsage `json:"usage,omitempty"`
 
+	// Model is the model that was used, only present in the final chunk
+	Model string `json:"model,omitempty"`
+
 	// FinishReason is only present in the final chunk
 	FinishReason string `json:"finish_reason,omitempty"`
 }
 
+// streamHandlerKey is the context key for the handler set by WithStreamHandler
+type streamHandlerKey struct{}
+
+// WithStreamHandler returns a context asking the code serving a request to stream the response
+// to handler, for callers that want partial output but not the streaming API
+func WithStreamHandler(ctx context.Context, handler StreamHandler) context.Context {
+	return context.WithValue(ctx, streamHandlerKey{}, handler)
+}
+
+// StreamHandlerFromContext returns the handler set by WithStreamHandler, or nil
+func StreamHandlerFromContext(ctx context.Context) StreamHandler {
+	handler, _ := ctx.Value(streamHandlerKey{}).(StreamHandler)
+	return handler
+}
+
 // Capabilities describes what a provider supports
 type Capabilities struct {
 	// Provider is the provider type
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+// Usage is the token usage of one completion, with its cost estimated from list prices
+type Usage struct {
+	Provider         Provider `json:"provider"`
+	Model            string   `json:"model"`
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	CostUSD          float64  `json:"cost_usd"`
+}
+
+// UsageScope attributes the usage of the completions made on a context, e.g. to the
+// organization making an API request or to a workflow execution
+type UsageScope struct {
+	OrganizationID string
+	WorkflowID     string
+	ExecutionID    string
+
+	// OnUsage is called with the usage of each completion, if set
+	OnUsage func(usage *Usage)
+}
+
+// usageScopeKey is the context key for the scope set by WithUsageScope
+type usageScopeKey struct{}
+
+// WithUsageScope returns a context whose completions are attributed to scope
+func WithUsageScope(ctx context.Context, scope *UsageScope) context.Context {
+	return context.WithValue(ctx, usageScopeKey{}, scope)
+}
+
+// UsageScopeFromContext returns the scope set by WithUsageScope, or nil
+func UsageScopeFromContext(ctx context.Context) *UsageScope {
+	scope, _ := ctx.Value(usageScopeKey{}).(*UsageScope)
+	return scope
+}
+
+// EstimateCost estimates the cost in USD of a completion from the model's list prices. Dated
+// model versions, e.g. gpt-4o-2024-08-06, are priced as the longest listed model ID they start
+// with; models without a listed price cost nothing
+func (c *Capabilities) EstimateCost(model string, usage *TokenUsage) float64 {
+	if usage == nil {
+		return 0
+	}
+
+	var priced *ModelInfo
+	for i := range c.Models {
+		info := &c.Models[i]
+		if info.ID == model {
+			priced = info
+			break
+		}
+		if strings.HasPrefix(model, info.ID+"-") && (priced == nil || len(info.ID) > len(priced.ID)) {
+			priced = info
+		}
+	}
+	if priced == nil {
+		return 0
+	}
+
+	return (float64(usage.PromptTokens)*priced.InputPricePerMillion +
+		float64(usage.CompletionTokens)*priced.OutputPricePerMillion) / 1e6
+}
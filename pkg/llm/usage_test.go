@@ -0,0 +1,44 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilitiesEstimateCost(t *testing.T) {
+	caps := &Capabilities{
+		Models: []ModelInfo{
+			{ID: "gpt-4o", InputPricePerMillion: 2.5, OutputPricePerMillion: 10},
+			{ID: "gpt-4o-mini", InputPricePerMillion: 0.15, OutputPricePerMillion: 0.6},
+		},
+	}
+	usage := &TokenUsage{PromptTokens: 1000000, CompletionTokens: 500000}
+
+	tests := []struct {
+		model    string
+		expected float64
+	}{
+		{"gpt-4o", 7.5},
+		{"gpt-4o-2024-08-06", 7.5},
+		{"gpt-4o-mini-2024-07-18", 0.45},
+		{"gpt-4", 0},
+		{"llama3.2", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, caps.EstimateCost(tt.model, usage), 1e-9)
+		})
+	}
+
+	assert.Zero(t, caps.EstimateCost("gpt-4o", nil))
+}
+
+func TestUsageScopeFromContext(t *testing.T) {
+	assert.Nil(t, UsageScopeFromContext(context.Background()))
+
+	scope := &UsageScope{OrganizationID: "org-1", ExecutionID: "exec-1"}
+	assert.Same(t, scope, UsageScopeFromContext(WithUsageScope(context.Background(), scope)))
+}
@@ -1,8 +1,10 @@
 package logger
 
 import (
+	"context"
 	"os"
 
+	"github.com/davidmoltin/intelligent-workflows/pkg/correlation"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -65,6 +67,14 @@ func (l *Logger) WithField(key string, value interface{}) *Logger {
 	return &Logger{l.Logger.With(zap.Any(key, value))}
 }
 
+// WithContext adds the correlation ID carried by ctx to the logger, if there is one
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	if id := correlation.FromContext(ctx); id != "" {
+		return &Logger{l.Logger.With(zap.String("correlation_id", id))}
+	}
+	return l
+}
+
 // WithFields adds multiple fields to the logger
 func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
 	zapFields := make([]zap.Field, 0, len(fields))
@@ -19,6 +19,18 @@ type Metrics struct {
 	WorkflowStepDuration    *prometheus.HistogramVec
 	WorkflowErrors          *prometheus.CounterVec
 	ActiveWorkflows         *prometheus.GaugeVec
+	StepDuration            *prometheus.HistogramVec
+	StepExecutionsTotal     *prometheus.CounterVec
+	RateLimitTokens         *prometheus.GaugeVec
+	RateLimitDecisions      *prometheus.CounterVec
+	RateLimitQueueDepth     *prometheus.GaugeVec
+	RateLimitQueueWait      *prometheus.HistogramVec
+
+	// Context Enrichment Metrics
+	ContextCacheRequests *prometheus.CounterVec
+	ContextFetchDuration *prometheus.HistogramVec
+	ContextFetchErrors   *prometheus.CounterVec
+	CircuitBreakerState  *prometheus.GaugeVec
 
 	// Database Metrics
 	DBConnectionsActive      prometheus.Gauge
@@ -38,6 +50,9 @@ type Metrics struct {
 	NotificationsSent       *prometheus.CounterVec
 	AIRequestsTotal         *prometheus.CounterVec
 	AIRequestDuration       *prometheus.HistogramVec
+	LLMTokensTotal          *prometheus.CounterVec
+	LLMCostTotal            *prometheus.CounterVec
+	LLMCacheRequests        *prometheus.CounterVec
 
 	// Worker Metrics
 	WorkerJobsProcessed     *prometheus.CounterVec
@@ -48,11 +63,31 @@ type Metrics struct {
 	AuthRequestsTotal       *prometheus.CounterVec
 	AuthFailuresTotal       *prometheus.CounterVec
 	AuthTokenValidations    *prometheus.CounterVec
+
+	// Webhook Metrics
+	WebhookSignatureFailures *prometheus.CounterVec
+
+	// Egress Metrics
+	EgressBlocked *prometheus.CounterVec
+
+	organizationLabel bool
 }
 
+// Options configures optional metric labels
+type Options struct {
+	// OrganizationLabel fills the organization_id label on workflow execution and LLM usage metrics.
+	// When false the label is left empty, so all organizations share one series per workflow.
+	OrganizationLabel bool
+}
+
+// organizationLabelHelp is appended to the help text of metrics carrying the organization_id label
+const organizationLabelHelp = " (organization_id adds a series per organization; set METRICS_ORGANIZATION_LABEL=false to leave it empty when there are many organizations)"
+
 // New creates and registers all Prometheus metrics
-func New() *Metrics {
+func New(opts Options) *Metrics {
 	m := &Metrics{
+		organizationLabel: opts.OrganizationLabel,
+
 		// HTTP Metrics
 		HTTPRequestsTotal: promauto.NewCounterVec(
 			prometheus.CounterOpts{
@@ -90,17 +125,17 @@ func New() *Metrics {
 		WorkflowExecutionsTotal: promauto.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "workflow_executions_total",
-				Help: "Total number of workflow executions",
+				Help: "Total number of workflow executions" + organizationLabelHelp,
 			},
-			[]string{"workflow_id", "status"},
+			[]string{"organization_id", "workflow_id", "status"},
 		),
 		WorkflowDuration: promauto.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Name:    "workflow_execution_duration_seconds",
-				Help:    "Workflow execution duration in seconds",
+				Help:    "Workflow execution duration in seconds" + organizationLabelHelp,
 				Buckets: prometheus.ExponentialBuckets(0.1, 2, 10), // 0.1s to ~102s
 			},
-			[]string{"workflow_id"},
+			[]string{"organization_id", "workflow_id"},
 		),
 		WorkflowStepDuration: promauto.NewHistogramVec(
 			prometheus.HistogramOpts{
@@ -113,16 +148,91 @@ func New() *Metrics {
 		WorkflowErrors: promauto.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "workflow_execution_errors_total",
-				Help: "Total number of workflow execution errors",
+				Help: "Total number of workflow execution errors" + organizationLabelHelp,
 			},
-			[]string{"workflow_id", "error_type"},
+			[]string{"organization_id", "workflow_id", "error_type"},
 		),
 		ActiveWorkflows: promauto.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "active_workflow_executions",
-				Help: "Number of currently running workflow executions",
+				Help: "Number of currently running workflow executions" + organizationLabelHelp,
 			},
-			[]string{"workflow_id"},
+			[]string{"organization_id", "workflow_id"},
+		),
+		RateLimitTokens: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "workflow_rate_limit_tokens",
+				Help: "Executions that can still start at once under a rate limit, as of its last check; workflow_id is empty for the organization's limit" + organizationLabelHelp,
+			},
+			[]string{"organization_id", "workflow_id"},
+		),
+		RateLimitDecisions: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "workflow_rate_limit_decisions_total",
+				Help: "Total number of executions checked against execution rate limits, by result" + organizationLabelHelp,
+			},
+			[]string{"organization_id", "workflow_id", "result"},
+		),
+		RateLimitQueueDepth: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "workflow_rate_limit_queue_depth",
+				Help: "Executions waiting for a rate limit token, by priority" + organizationLabelHelp,
+			},
+			[]string{"organization_id", "priority"},
+		),
+		RateLimitQueueWait: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "workflow_rate_limit_queue_wait_seconds",
+				Help:    "How long executions queued by a rate limit waited before starting, by priority" + organizationLabelHelp,
+				Buckets: prometheus.ExponentialBuckets(0.1, 2, 14), // 0.1s to ~27m
+			},
+			[]string{"organization_id", "priority"},
+		),
+		StepDuration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "workflow_step_type_duration_seconds",
+				Help:    "Workflow step execution duration in seconds by step type",
+				Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms to ~16s
+			},
+			[]string{"step_type", "status"},
+		),
+		StepExecutionsTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "workflow_step_executions_total",
+				Help: "Total number of workflow step executions by step type",
+			},
+			[]string{"step_type", "status"},
+		),
+
+		// Context Enrichment Metrics
+		ContextCacheRequests: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "context_cache_requests_total",
+				Help: "Total number of context enrichment cache lookups by result (hit, miss, error)",
+			},
+			[]string{"resource", "result"},
+		),
+		ContextFetchDuration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "context_fetch_duration_seconds",
+				Help:    "Context enrichment microservice fetch duration in seconds, including retries",
+				Buckets: prometheus.ExponentialBuckets(0.01, 2, 10), // 0.01s to ~10s
+			},
+			[]string{"resource"},
+		),
+		ContextFetchErrors: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "context_fetch_errors_total",
+				Help: "Total number of failed context enrichment fetches by reason (unauthorized, circuit_open, error)",
+			},
+			[]string{"resource", "reason"},
+		),
+		CircuitBreakerState: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "circuit_breaker_state",
+				Help: "State of each circuit breaker (0 = closed, 1 = half-open, 2 = open)",
+			},
+			[]string{"name"},
 		),
 
 		// Database Metrics
@@ -223,6 +333,27 @@ func New() *Metrics {
 			},
 			[]string{"model"},
 		),
+		LLMTokensTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "llm_tokens_total",
+				Help: "Total number of LLM tokens used, by direction (prompt or completion)" + organizationLabelHelp,
+			},
+			[]string{"organization_id", "provider", "model", "direction"},
+		),
+		LLMCostTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "llm_cost_usd_total",
+				Help: "Estimated LLM cost in USD at list prices; models without a listed price count as free" + organizationLabelHelp,
+			},
+			[]string{"organization_id", "provider", "model"},
+		),
+		LLMCacheRequests: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "llm_cache_requests_total",
+				Help: "Total number of LLM response cache lookups by result (hit, miss, error)",
+			},
+			[]string{"provider", "result"},
+		),
 
 		// Worker Metrics
 		WorkerJobsProcessed: promauto.NewCounterVec(
@@ -270,7 +401,33 @@ func New() *Metrics {
 			},
 			[]string{"valid"},
 		),
+
+		// Webhook Metrics
+		WebhookSignatureFailures: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "webhook_signature_failures_total",
+				Help: "Total number of inbound events rejected for a missing or invalid signature",
+			},
+			[]string{"reason"},
+		),
+
+		// Egress Metrics
+		EgressBlocked: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "egress_blocked_total",
+				Help: "Total number of outbound requests blocked by the egress policy",
+			},
+			[]string{"client", "reason"},
+		),
 	}
 
 	return m
 }
+
+// OrganizationLabel returns the organization_id label value, or "" when the label is disabled
+func (m *Metrics) OrganizationLabel(organizationID string) string {
+	if !m.organizationLabel {
+		return ""
+	}
+	return organizationID
+}
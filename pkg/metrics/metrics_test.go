@@ -0,0 +1,15 @@
+package metrics
+
+import "testing"
+
+func TestOrganizationLabel(t *testing.T) {
+	enabled := &Metrics{organizationLabel: true}
+	if got := enabled.OrganizationLabel("org-1"); got != "org-1" {
+		t.Errorf("Expected org-1, got %q", got)
+	}
+
+	disabled := &Metrics{}
+	if got := disabled.OrganizationLabel("org-1"); got != "" {
+		t.Errorf("Expected empty label when disabled, got %q", got)
+	}
+}
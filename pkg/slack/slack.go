@@ -0,0 +1,226 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/egress"
+)
+
+// DefaultAPIURL is the Slack Web API messages are posted to with a bot token
+const DefaultAPIURL = "https://slack.com/api"
+
+// maxResponseBytes limits how much of a Slack response is read
+const maxResponseBytes = 1 << 20
+
+var (
+	// ErrNotConfigured is returned when neither a webhook URL nor a bot token is available
+	ErrNotConfigured = errors.New("slack is not configured")
+	// ErrMissingChannel is returned when a message posted with a bot token names no channel
+	ErrMissingChannel = errors.New("slack channel is required when posting with a bot token")
+	// ErrEmptyMessage is returned for messages with neither text nor blocks
+	ErrEmptyMessage = errors.New("slack message requires text or blocks")
+)
+
+// retryableCodes are Slack error codes that may succeed when the message is posted again
+var retryableCodes = map[string]bool{
+	"ratelimited":         true,
+	"rate_limited":        true,
+	"internal_error":      true,
+	"fatal_error":         true,
+	"service_unavailable": true,
+	"request_timeout":     true,
+}
+
+// Credentials are what a message is posted with. A bot token takes precedence, since only the
+// Web API reports where the message was posted
+type Credentials struct {
+	WebhookURL     string
+	BotToken       string
+	DefaultChannel string // Channel of messages that name none, when posting with a bot token
+}
+
+// Configured reports whether the credentials can post messages
+func (c Credentials) Configured() bool {
+	return c.WebhookURL != "" || c.BotToken != ""
+}
+
+// Message is a message to post
+type Message struct {
+	Channel  string        `json:"channel,omitempty"`
+	Text     string        `json:"text,omitempty"`
+	Blocks   []interface{} `json:"blocks,omitempty"`
+	ThreadTS string        `json:"thread_ts,omitempty"`
+}
+
+// PostResult identifies a posted message. Incoming webhooks don't report the message, so both
+// fields are empty for messages posted through one
+type PostResult struct {
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+// Error is a failed post: a transport error, an error status, or an error code from Slack
+type Error struct {
+	StatusCode int           // HTTP status, 0 if no response was received
+	Code       string        // Slack error code, e.g. channel_not_found
+	RetryAfter time.Duration // Delay Slack asked for before retrying, if any
+	Err        error         // Transport error, if no response was received
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("slack request failed: %v", e.Err)
+	case e.Code != "":
+		return fmt.Sprintf("slack returned error %s (status %d)", e.Code, e.StatusCode)
+	default:
+		return fmt.Sprintf("slack returned error status: %d", e.StatusCode)
+	}
+}
+
+// Unwrap returns the transport error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether posting the message again may succeed: after transport errors,
+// 429 and 5xx responses, and transient Slack errors
+func (e *Error) Retryable() bool {
+	if e.Err != nil {
+		return !errors.Is(e.Err, egress.ErrBlockedDestination) && !errors.Is(e.Err, context.Canceled)
+	}
+	if retryableCodes[e.Code] {
+		return true
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// IsRetryable reports whether err is a failed post worth retrying
+func IsRetryable(err error) bool {
+	var slackErr *Error
+	return errors.As(err, &slackErr) && slackErr.Retryable()
+}
+
+// Client posts messages to Slack
+type Client struct {
+	httpClient *http.Client
+	apiURL     string
+}
+
+// NewClient creates a client posting through httpClient
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{httpClient: httpClient, apiURL: DefaultAPIURL}
+}
+
+// SetAPIURL sets the Web API base URL, e.g. for a proxy or a test server
+func (c *Client) SetAPIURL(apiURL string) {
+	c.apiURL = strings.TrimSuffix(apiURL, "/")
+}
+
+// Post posts a message with the bot token if there is one, or through the incoming webhook
+func (c *Client) Post(ctx context.Context, credentials Credentials, message Message) (*PostResult, error) {
+	if message.Text == "" && len(message.Blocks) == 0 {
+		return nil, ErrEmptyMessage
+	}
+
+	switch {
+	case credentials.BotToken != "":
+		if message.Channel == "" {
+			message.Channel = credentials.DefaultChannel
+		}
+		if message.Channel == "" {
+			return nil, ErrMissingChannel
+		}
+		return c.postMessage(ctx, credentials.BotToken, message)
+	case credentials.WebhookURL != "":
+		// The webhook posts to its own channel
+		message.Channel = ""
+		return c.postWebhook(ctx, credentials.WebhookURL, message)
+	default:
+		return nil, ErrNotConfigured
+	}
+}
+
+// postMessage posts with chat.postMessage, which reports the message's channel and timestamp
+func (c *Client) postMessage(ctx context.Context, token string, message Message) (*PostResult, error) {
+	resp, body, err := c.send(ctx, c.apiURL+"/chat.postMessage", "Bearer "+token, message)
+	if err != nil {
+		return nil, err
+	}
+
+	// The Web API reports errors in the body, usually with a 200 status
+	var response struct {
+		OK      bool   `json:"ok"`
+		Error   string `json:"error"`
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+	if jsonErr := json.Unmarshal(body, &response); jsonErr != nil || !response.OK {
+		return nil, responseError(resp, response.Error)
+	}
+
+	return &PostResult{Channel: response.Channel, TS: response.TS}, nil
+}
+
+// postWebhook posts through an incoming webhook, which answers "ok" or an error code
+func (c *Client) postWebhook(ctx context.Context, webhookURL string, message Message) (*PostResult, error) {
+	resp, body, err := c.send(ctx, webhookURL, "", message)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp, strings.TrimSpace(string(body)))
+	}
+
+	return &PostResult{}, nil
+}
+
+// send posts message as JSON, returning the response and its body
+func (c *Client) send(ctx context.Context, url, authorization string, message Message) (*http.Response, []byte, error) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal Slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create Slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, &Error{Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, nil, &Error{StatusCode: resp.StatusCode, Err: err}
+	}
+
+	return resp, body, nil
+}
+
+// responseError describes a failed response, with the delay Slack asked for before retrying
+func responseError(resp *http.Response, code string) *Error {
+	slackErr := &Error{StatusCode: resp.StatusCode, Code: code}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		slackErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return slackErr
+}
@@ -0,0 +1,134 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_PostWithBotToken(t *testing.T) {
+	var authorization string
+	var posted Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/chat.postMessage", r.URL.Path)
+		authorization = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&posted))
+
+		switch posted.Channel {
+		case "#missing":
+			w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+		case "#busy":
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"ok":false,"error":"ratelimited"}`))
+		default:
+			w.Write([]byte(`{"ok":true,"channel":"C024BE91L","ts":"1503435956.000247"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client())
+	client.SetAPIURL(server.URL + "/")
+	credentials := Credentials{BotToken: "xoxb-test", DefaultChannel: "#orders"}
+	ctx := context.Background()
+
+	t.Run("reports where the message was posted", func(t *testing.T) {
+		result, err := client.Post(ctx, credentials, Message{
+			Text:     "Order ord_1 needs review",
+			Blocks:   []interface{}{map[string]interface{}{"type": "divider"}},
+			ThreadTS: "1503435900.000100",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, &PostResult{Channel: "C024BE91L", TS: "1503435956.000247"}, result)
+		assert.Equal(t, "Bearer xoxb-test", authorization)
+		assert.Equal(t, "#orders", posted.Channel, "the default channel is used for messages naming none")
+		assert.Equal(t, "1503435900.000100", posted.ThreadTS)
+		assert.Len(t, posted.Blocks, 1)
+	})
+
+	t.Run("API errors are not retried", func(t *testing.T) {
+		_, err := client.Post(ctx, credentials, Message{Channel: "#missing", Text: "hello"})
+
+		var slackErr *Error
+		require.ErrorAs(t, err, &slackErr)
+		assert.Equal(t, "channel_not_found", slackErr.Code)
+		assert.False(t, IsRetryable(err))
+	})
+
+	t.Run("rate limits are retried after the delay Slack asks for", func(t *testing.T) {
+		_, err := client.Post(ctx, credentials, Message{Channel: "#busy", Text: "hello"})
+
+		var slackErr *Error
+		require.ErrorAs(t, err, &slackErr)
+		assert.True(t, IsRetryable(err))
+		assert.Equal(t, 3*time.Second, slackErr.RetryAfter)
+	})
+
+	t.Run("requires a channel", func(t *testing.T) {
+		_, err := client.Post(ctx, Credentials{BotToken: "xoxb-test"}, Message{Text: "hello"})
+		assert.ErrorIs(t, err, ErrMissingChannel)
+	})
+}
+
+func TestClient_PostWithWebhook(t *testing.T) {
+	var posted map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&posted))
+		switch r.URL.Path {
+		case "/gone":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("no_service"))
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client())
+	ctx := context.Background()
+
+	result, err := client.Post(ctx, Credentials{WebhookURL: server.URL + "/hook"}, Message{Channel: "#ignored", Text: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, &PostResult{}, result)
+	assert.Equal(t, map[string]interface{}{"text": "hello"}, posted, "webhooks post to their own channel")
+
+	_, err = client.Post(ctx, Credentials{WebhookURL: server.URL + "/gone"}, Message{Text: "hello"})
+	var slackErr *Error
+	require.ErrorAs(t, err, &slackErr)
+	assert.Equal(t, "no_service", slackErr.Code)
+	assert.False(t, IsRetryable(err))
+
+	_, err = client.Post(ctx, Credentials{WebhookURL: server.URL + "/down"}, Message{Text: "hello"})
+	assert.True(t, IsRetryable(err))
+}
+
+func TestClient_PostInvalid(t *testing.T) {
+	client := NewClient(http.DefaultClient)
+	ctx := context.Background()
+
+	_, err := client.Post(ctx, Credentials{}, Message{Text: "hello"})
+	assert.ErrorIs(t, err, ErrNotConfigured)
+	assert.False(t, IsRetryable(err))
+
+	_, err = client.Post(ctx, Credentials{WebhookURL: "https://hooks.slack.com/services/T/B/X"}, Message{})
+	assert.ErrorIs(t, err, ErrEmptyMessage)
+}
+
+func TestError_Retryable(t *testing.T) {
+	assert.True(t, (&Error{Err: errors.New("connection reset")}).Retryable())
+	assert.False(t, (&Error{Err: context.Canceled}).Retryable())
+	assert.True(t, (&Error{StatusCode: http.StatusOK, Code: "internal_error"}).Retryable())
+	assert.True(t, (&Error{StatusCode: http.StatusBadGateway}).Retryable())
+	assert.False(t, (&Error{StatusCode: http.StatusOK, Code: "invalid_auth"}).Retryable())
+}
@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidmoltin/intelligent-workflows/pkg/config"
+	"github.com/davidmoltin/intelligent-workflows/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+// Setup installs the global OpenTelemetry tracer provider and W3C trace context propagator.
+// Without an endpoint configured, the default no-op provider is kept, so spans cost nothing and
+// nothing is exported. The returned function flushes and stops the exporter
+func Setup(ctx context.Context, cfg *config.TracingConfig, log *logger.Logger) (func(context.Context) error, error) {
+	// Propagate incoming trace context even when nothing is exported, so IDs reach downstream calls
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if cfg.Endpoint == "" {
+		log.Info("Tracing disabled: no exporter endpoint configured")
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// Keep the caller's sampling decision so a distributed trace isn't cut in the middle
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	log.Info("Tracing enabled",
+		logger.String("endpoint", cfg.Endpoint),
+		logger.String("service_name", cfg.ServiceName),
+	)
+
+	return provider.Shutdown, nil
+}
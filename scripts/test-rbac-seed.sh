@@ -132,7 +132,7 @@ fi
 # Test 7: Seed with users
 echo ""
 echo -e "${YELLOW}Test 7: Seeding with default admin user${NC}"
-if ./bin/seed --users; then
+if ./bin/seed --users --allow-insecure-default; then
     echo -e "${GREEN}✓ Seeding with users successful${NC}"
 else
     echo -e "${RED}✗ Seeding with users failed${NC}"
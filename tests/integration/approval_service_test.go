@@ -43,9 +43,9 @@ func TestApprovalService_CreateApprovalRequest(t *testing.T) {
 	notificationSvc, err := services.NewNotificationService(notificationCfg, log)
 	require.NoError(t, err)
 
-	workflowResumer := services.NewWorkflowResumer(log, nil, nil)
+	workflowResumer := services.NewWorkflowResumer(log, nil, nil, nil)
 
-	approvalService := services.NewApprovalService(approvalRepo, log, notificationSvc, workflowResumer, "test-approver@example.com")
+	approvalService := services.NewApprovalService(approvalRepo, log, notificationSvc, workflowResumer, nil, "test-approver@example.com")
 
 	ctx := context.Background()
 	executionID := uuid.New()
@@ -61,6 +61,9 @@ func TestApprovalService_CreateApprovalRequest(t *testing.T) {
 		"admin",
 		"Production deployment requires approval",
 		&expiresIn,
+		nil,
+		nil,
+		0,
 	)
 
 	require.NoError(t, err)
@@ -100,9 +103,9 @@ func TestApprovalService_ApproveRequest(t *testing.T) {
 	notificationSvc, err := services.NewNotificationService(notificationCfg, log)
 	require.NoError(t, err)
 
-	workflowResumer := services.NewWorkflowResumer(log, nil, nil)
+	workflowResumer := services.NewWorkflowResumer(log, nil, nil, nil)
 
-	approvalService := services.NewApprovalService(approvalRepo, log, notificationSvc, workflowResumer, "test-approver@example.com")
+	approvalService := services.NewApprovalService(approvalRepo, log, notificationSvc, workflowResumer, nil, "test-approver@example.com")
 
 	ctx := context.Background()
 
@@ -120,6 +123,9 @@ func TestApprovalService_ApproveRequest(t *testing.T) {
 		"admin",
 		"Production deployment requires approval",
 		&expiresIn,
+		nil,
+		nil,
+		0,
 	)
 	require.NoError(t, err)
 
@@ -127,7 +133,7 @@ func TestApprovalService_ApproveRequest(t *testing.T) {
 	approverID := uuid.New()
 	reason := "Looks good to me"
 
-	approvedApproval, err := approvalService.ApproveRequest(ctx, approval.ID, approverID, &reason)
+	approvedApproval, err := approvalService.ApproveRequest(ctx, approval.OrganizationID, approval.ID, approverID, &reason)
 
 	require.NoError(t, err)
 	assert.NotNil(t, approvedApproval)
@@ -163,9 +169,9 @@ func TestApprovalService_RejectRequest(t *testing.T) {
 	notificationSvc, err := services.NewNotificationService(notificationCfg, log)
 	require.NoError(t, err)
 
-	workflowResumer := services.NewWorkflowResumer(log, nil, nil)
+	workflowResumer := services.NewWorkflowResumer(log, nil, nil, nil)
 
-	approvalService := services.NewApprovalService(approvalRepo, log, notificationSvc, workflowResumer, "test-approver@example.com")
+	approvalService := services.NewApprovalService(approvalRepo, log, notificationSvc, workflowResumer, nil, "test-approver@example.com")
 
 	ctx := context.Background()
 
@@ -183,6 +189,9 @@ func TestApprovalService_RejectRequest(t *testing.T) {
 		"admin",
 		"Production deployment requires approval",
 		&expiresIn,
+		nil,
+		nil,
+		0,
 	)
 	require.NoError(t, err)
 
@@ -190,7 +199,7 @@ func TestApprovalService_RejectRequest(t *testing.T) {
 	approverID := uuid.New()
 	reason := "Not ready for production"
 
-	rejectedApproval, err := approvalService.RejectRequest(ctx, approval.ID, approverID, &reason)
+	rejectedApproval, err := approvalService.RejectRequest(ctx, approval.OrganizationID, approval.ID, approverID, &reason)
 
 	require.NoError(t, err)
 	assert.NotNil(t, rejectedApproval)
@@ -226,9 +235,9 @@ func TestApprovalService_ExpireOldApprovals(t *testing.T) {
 	notificationSvc, err := services.NewNotificationService(notificationCfg, log)
 	require.NoError(t, err)
 
-	workflowResumer := services.NewWorkflowResumer(log, nil, nil)
+	workflowResumer := services.NewWorkflowResumer(log, nil, nil, nil)
 
-	approvalService := services.NewApprovalService(approvalRepo, log, notificationSvc, workflowResumer, "test-approver@example.com")
+	approvalService := services.NewApprovalService(approvalRepo, log, notificationSvc, workflowResumer, nil, "test-approver@example.com")
 
 	ctx := context.Background()
 
@@ -246,6 +255,9 @@ func TestApprovalService_ExpireOldApprovals(t *testing.T) {
 		"admin",
 		"Production deployment requires approval",
 		&expiresIn,
+		nil,
+		nil,
+		0,
 	)
 	require.NoError(t, err)
 
@@ -254,7 +266,7 @@ func TestApprovalService_ExpireOldApprovals(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify the approval was marked as expired
-	expiredApproval, err := approvalService.GetApproval(ctx, approval.ID)
+	expiredApproval, err := approvalService.GetApproval(ctx, approval.OrganizationID, approval.ID)
 	require.NoError(t, err)
 	assert.Equal(t, models.ApprovalStatusExpired, expiredApproval.Status)
 	assert.NotNil(t, expiredApproval.DecidedAt)
@@ -286,19 +298,20 @@ func TestApprovalService_ListPendingApprovals(t *testing.T) {
 	notificationSvc, err := services.NewNotificationService(notificationCfg, log)
 	require.NoError(t, err)
 
-	workflowResumer := services.NewWorkflowResumer(log, nil, nil)
+	workflowResumer := services.NewWorkflowResumer(log, nil, nil, nil)
 
-	approvalService := services.NewApprovalService(approvalRepo, log, notificationSvc, workflowResumer, "test-approver@example.com")
+	approvalService := services.NewApprovalService(approvalRepo, log, notificationSvc, workflowResumer, nil, "test-approver@example.com")
 
 	ctx := context.Background()
 
 	// Create multiple approval requests
+	var organizationID uuid.UUID
 	for i := 0; i < 3; i++ {
 		executionID := uuid.New()
 		requesterID := uuid.New()
 		expiresIn := 24 * time.Hour
 
-		_, err := approvalService.CreateApprovalRequest(
+		approval, err := approvalService.CreateApprovalRequest(
 			ctx,
 			executionID,
 			"deployment",
@@ -307,12 +320,16 @@ func TestApprovalService_ListPendingApprovals(t *testing.T) {
 			"admin",
 			"Production deployment requires approval",
 			&expiresIn,
+			nil,
+			nil,
+			0,
 		)
 		require.NoError(t, err)
+		organizationID = approval.OrganizationID
 	}
 
 	// List pending approvals
-	approvals, total, err := approvalService.ListPendingApprovals(ctx, nil, 10, 0)
+	approvals, total, err := approvalService.ListPendingApprovals(ctx, organizationID, nil, 10, 0)
 
 	require.NoError(t, err)
 	assert.GreaterOrEqual(t, len(approvals), 3)
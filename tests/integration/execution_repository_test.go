@@ -0,0 +1,310 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/davidmoltin/intelligent-workflows/internal/models"
+	"github.com/davidmoltin/intelligent-workflows/internal/repository/postgres"
+	"github.com/davidmoltin/intelligent-workflows/pkg/testutil"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createExecutionFixtures inserts an organization, a workflow and one execution per entry, returning the organization
+func createExecutionFixtures(t *testing.T, ctx context.Context, s *IntegrationSuite, executions []*models.WorkflowExecution) uuid.UUID {
+	t.Helper()
+
+	organizationID := uuid.New()
+	_, err := s.DB.DB.ExecContext(ctx,
+		`INSERT INTO organizations (id, name, slug) VALUES ($1, $2, $3)`,
+		organizationID, "Test Org", "test-org-"+organizationID.String()[:8])
+	require.NoError(t, err)
+
+	workflowFixture := testutil.NewFixtureBuilder().Workflow()
+	workflow, err := postgres.NewWorkflowRepository(s.DB.DB).Create(ctx, organizationID, &models.CreateWorkflowRequest{
+		WorkflowID: workflowFixture.WorkflowID,
+		Version:    workflowFixture.Version,
+		Name:       workflowFixture.Name,
+		Definition: workflowFixture.Definition,
+	}, nil)
+	require.NoError(t, err)
+
+	repo := postgres.NewExecutionRepository(s.DB.DB)
+	for _, execution := range executions {
+		execution.ID = uuid.New()
+		execution.OrganizationID = organizationID
+		execution.WorkflowID = workflow.ID
+		execution.TriggerPayload = models.JSONB{}
+		execution.Context = models.JSONB{}
+		execution.Metadata = models.JSONB{}
+		require.NoError(t, repo.CreateExecution(ctx, execution))
+	}
+
+	return organizationID
+}
+
+func TestExecutionRepository_ListExecutions_Filters(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	suite := SetupSuite(t)
+	defer TeardownSuite(t)
+	suite.ResetDatabase(t)
+
+	ctx := suite.GetContext(t)
+	repo := postgres.NewExecutionRepository(suite.DB.DB)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	newExecution := func(executionID, event string, status models.ExecutionStatus, age time.Duration) *models.WorkflowExecution {
+		return &models.WorkflowExecution{
+			ExecutionID:  executionID,
+			TriggerEvent: event,
+			Status:       status,
+			StartedAt:    now.Add(-age),
+		}
+	}
+
+	organizationID := createExecutionFixtures(t, ctx, suite, []*models.WorkflowExecution{
+		newExecution("exec-match-1", "order.created", models.ExecutionStatusFailed, time.Hour),
+		newExecution("exec-match-2", "order.created", models.ExecutionStatusFailed, 23*time.Hour),
+		newExecution("exec-too-old", "order.created", models.ExecutionStatusFailed, 25*time.Hour),
+		newExecution("exec-other-event", "order.updated", models.ExecutionStatusFailed, time.Hour),
+		newExecution("exec-other-status", "order.created", models.ExecutionStatusCompleted, time.Hour),
+	})
+
+	// An identical execution in another organization must never be listed
+	createExecutionFixtures(t, ctx, suite, []*models.WorkflowExecution{
+		newExecution("exec-other-org", "order.created", models.ExecutionStatusFailed, time.Hour),
+	})
+
+	status := models.ExecutionStatusFailed
+	event := "order.created"
+	startedAfter := now.Add(-24 * time.Hour)
+	filters := &postgres.ExecutionListFilters{
+		Status:       &status,
+		TriggerEvent: &event,
+		StartedAfter: &startedAfter,
+	}
+
+	t.Run("status, event and time window combine", func(t *testing.T) {
+		executions, total, err := repo.ListExecutions(ctx, organizationID, filters, 50, 0)
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(2), total)
+		require.Len(t, executions, 2)
+		assert.Equal(t, "exec-match-1", executions[0].ExecutionID)
+		assert.Equal(t, "exec-match-2", executions[1].ExecutionID)
+	})
+
+	t.Run("started_before is exclusive", func(t *testing.T) {
+		startedBefore := now.Add(-time.Hour)
+		windowed := *filters
+		windowed.StartedBefore = &startedBefore
+
+		executions, total, err := repo.ListExecutions(ctx, organizationID, &windowed, 50, 0)
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(1), total)
+		require.Len(t, executions, 1)
+		assert.Equal(t, "exec-match-2", executions[0].ExecutionID)
+	})
+
+	t.Run("cursor pages share the filters", func(t *testing.T) {
+		first, total, cursor, err := repo.ListExecutionsByCursor(ctx, organizationID, filters, "", 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		require.Len(t, first, 1)
+		assert.Equal(t, "exec-match-1", first[0].ExecutionID)
+		require.NotEmpty(t, cursor)
+
+		second, total, cursor, err := repo.ListExecutionsByCursor(ctx, organizationID, filters, cursor, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		require.Len(t, second, 1)
+		assert.Equal(t, "exec-match-2", second[0].ExecutionID)
+		assert.Empty(t, cursor)
+	})
+}
+
+func TestExecutionRepository_GetStepExecutions_StableOrder(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	suite := SetupSuite(t)
+	defer TeardownSuite(t)
+	suite.ResetDatabase(t)
+
+	ctx := suite.GetContext(t)
+	repo := postgres.NewExecutionRepository(suite.DB.DB)
+
+	execution := &models.WorkflowExecution{
+		ExecutionID:  "exec-foreach",
+		TriggerEvent: "order.created",
+		Status:       models.ExecutionStatusCompleted,
+		StartedAt:    time.Now().UTC(),
+	}
+	organizationID := createExecutionFixtures(t, ctx, suite, []*models.WorkflowExecution{execution})
+
+	// Foreach iterations recorded within the same timestamp, after a step that started earlier
+	startedAt := time.Now().UTC().Truncate(time.Millisecond)
+	expected := []string{"load_items"}
+	require.NoError(t, repo.CreateStepExecution(ctx, &models.StepExecution{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+		ExecutionID:    execution.ID,
+		StepID:         "load_items",
+		StepType:       "set",
+		Status:         models.StepStatusCompleted,
+		Input:          models.JSONB{},
+		Output:         models.JSONB{},
+		StartedAt:      startedAt.Add(-time.Second),
+	}))
+	for i := 0; i < 8; i++ {
+		stepID := "notify_" + string(rune('a'+i))
+		expected = append(expected, stepID)
+		require.NoError(t, repo.CreateStepExecution(ctx, &models.StepExecution{
+			ID:             uuid.New(),
+			OrganizationID: organizationID,
+			ExecutionID:    execution.ID,
+			StepID:         stepID,
+			StepType:       "execute",
+			Status:         models.StepStatusCompleted,
+			Input:          models.JSONB{},
+			Output:         models.JSONB{},
+			StartedAt:      startedAt,
+		}))
+	}
+
+	stepIDs := func(steps []models.StepExecution) []string {
+		ids := make([]string, len(steps))
+		for i, step := range steps {
+			ids[i] = step.StepID
+		}
+		return ids
+	}
+
+	for read := 0; read < 5; read++ {
+		steps, err := repo.GetStepExecutions(ctx, organizationID, execution.ID)
+		require.NoError(t, err)
+		assert.Equal(t, expected, stepIDs(steps), "read %d", read)
+		for i := 1; i < len(steps); i++ {
+			assert.Less(t, steps[i-1].Sequence, steps[i].Sequence)
+		}
+	}
+
+	// Pages of the same list neither repeat nor skip tied steps
+	var paged []models.StepExecution
+	for offset := 0; offset < len(expected); offset += 3 {
+		page, total, err := repo.ListStepExecutions(ctx, organizationID, execution.ID, nil, 3, offset)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(expected)), total)
+		paged = append(paged, page...)
+	}
+	assert.Equal(t, expected, stepIDs(paged))
+}
+
+func TestExecutionRepository_Retention(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	suite := SetupSuite(t)
+	defer TeardownSuite(t)
+	suite.ResetDatabase(t)
+
+	ctx := suite.GetContext(t)
+	repo := postgres.NewExecutionRepository(suite.DB.DB)
+
+	now := time.Now().UTC()
+	newExecution := func(executionID string, status models.ExecutionStatus, age time.Duration) *models.WorkflowExecution {
+		startedAt := now.Add(-age)
+		return &models.WorkflowExecution{
+			ExecutionID:  executionID,
+			TriggerEvent: "order.created",
+			Status:       status,
+			StartedAt:    startedAt,
+			CompletedAt:  &startedAt,
+		}
+	}
+
+	expiredCompleted := newExecution("exec-expired-completed", models.ExecutionStatusCompleted, 40*24*time.Hour)
+	expiredFailed := newExecution("exec-expired-failed", models.ExecutionStatusFailed, 40*24*time.Hour)
+	organizationID := createExecutionFixtures(t, ctx, suite, []*models.WorkflowExecution{
+		expiredCompleted,
+		expiredFailed,
+		newExecution("exec-old-running", models.ExecutionStatusRunning, 40*24*time.Hour),
+		newExecution("exec-recent", models.ExecutionStatusCompleted, 24*time.Hour),
+	})
+	require.NoError(t, repo.CreateStepExecution(ctx, &models.StepExecution{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+		ExecutionID:    expiredCompleted.ID,
+		StepID:         "notify",
+		StepType:       "execute",
+		Status:         models.StepStatusCompleted,
+		Input:          models.JSONB{},
+		Output:         models.JSONB{},
+		StartedAt:      expiredCompleted.StartedAt,
+	}))
+
+	// An organization keeping its executions forever
+	keptID := createExecutionFixtures(t, ctx, suite, []*models.WorkflowExecution{
+		newExecution("exec-kept", models.ExecutionStatusCompleted, 400*24*time.Hour),
+	})
+	_, err := postgres.NewRetentionRepository(suite.DB.DB).SetPolicy(ctx, keptID, 0)
+	require.NoError(t, err)
+
+	deleted, err := repo.SoftDeleteExpiredExecutions(ctx, 30, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	// Soft-deleted executions are hidden from the API
+	executions, total, err := repo.ListExecutions(ctx, organizationID, nil, 50, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	for _, execution := range executions {
+		assert.NotContains(t, []string{"exec-expired-completed", "exec-expired-failed"}, execution.ExecutionID)
+	}
+	_, err = repo.GetExecutionByID(ctx, organizationID, expiredCompleted.ID)
+	assert.ErrorIs(t, err, postgres.ErrExecutionNotFound)
+
+	// Nothing is purged before the purge delay has passed
+	purged, err := repo.PurgeDeletedExecutions(ctx, time.Hour, 100, nil)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	// A failed export keeps the batch
+	_, err = repo.PurgeDeletedExecutions(ctx, 0, 100, func(ctx context.Context, traces []models.ExecutionTraceResponse) error {
+		return assert.AnError
+	})
+	require.Error(t, err)
+
+	var exported []models.ExecutionTraceResponse
+	purged, err = repo.PurgeDeletedExecutions(ctx, 0, 100, func(ctx context.Context, traces []models.ExecutionTraceResponse) error {
+		exported = traces
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	require.Len(t, exported, 2)
+	for _, trace := range exported {
+		if trace.Execution.ID == expiredCompleted.ID {
+			require.Len(t, trace.Steps, 1)
+			assert.Equal(t, "notify", trace.Steps[0].StepID)
+		}
+	}
+
+	var remaining int
+	require.NoError(t, suite.DB.DB.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM step_executions WHERE execution_id = $1`, expiredCompleted.ID).Scan(&remaining))
+	assert.Zero(t, remaining)
+
+	_, total, err = repo.ListExecutions(ctx, keptID, nil, 50, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"testing"
 
 	"github.com/davidmoltin/intelligent-workflows/internal/models"
@@ -11,6 +12,19 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// createOrganizationFixture inserts an organization and returns its ID
+func createOrganizationFixture(t *testing.T, ctx context.Context, s *IntegrationSuite) uuid.UUID {
+	t.Helper()
+
+	organizationID := uuid.New()
+	_, err := s.DB.DB.ExecContext(ctx,
+		`INSERT INTO organizations (id, name, slug) VALUES ($1, $2, $3)`,
+		organizationID, "Test Org", "test-org-"+organizationID.String()[:8])
+	require.NoError(t, err)
+
+	return organizationID
+}
+
 func TestWorkflowRepository_Create(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
@@ -22,6 +36,7 @@ func TestWorkflowRepository_Create(t *testing.T) {
 
 	ctx := suite.GetContext(t)
 	repo := postgres.NewWorkflowRepository(suite.DB.DB)
+	organizationID := createOrganizationFixture(t, ctx, suite)
 
 	// Create fixture
 	fixtures := testutil.NewFixtureBuilder()
@@ -39,7 +54,7 @@ func TestWorkflowRepository_Create(t *testing.T) {
 
 	// Test Create
 	createdBy := uuid.New()
-	workflow, err := repo.Create(ctx, req, &createdBy)
+	workflow, err := repo.Create(ctx, organizationID, req, &createdBy)
 	require.NoError(t, err)
 	assert.NotEqual(t, uuid.Nil, workflow.ID)
 	assert.Equal(t, req.WorkflowID, workflow.WorkflowID)
@@ -47,7 +62,7 @@ func TestWorkflowRepository_Create(t *testing.T) {
 	assert.Equal(t, req.Version, workflow.Version)
 
 	// Verify it was created
-	retrieved, err := repo.GetByID(ctx, workflow.ID)
+	retrieved, err := repo.GetByID(ctx, organizationID, workflow.ID)
 	require.NoError(t, err)
 	assert.Equal(t, workflow.WorkflowID, retrieved.WorkflowID)
 	assert.Equal(t, workflow.Name, retrieved.Name)
@@ -65,6 +80,7 @@ func TestWorkflowRepository_GetByID(t *testing.T) {
 
 	ctx := suite.GetContext(t)
 	repo := postgres.NewWorkflowRepository(suite.DB.DB)
+	organizationID := createOrganizationFixture(t, ctx, suite)
 
 	// Create workflow
 	fixtures := testutil.NewFixtureBuilder()
@@ -80,11 +96,11 @@ func TestWorkflowRepository_GetByID(t *testing.T) {
 	}
 
 	createdBy := uuid.New()
-	workflow, err := repo.Create(ctx, req, &createdBy)
+	workflow, err := repo.Create(ctx, organizationID, req, &createdBy)
 	require.NoError(t, err)
 
 	// Test GetByID
-	retrieved, err := repo.GetByID(ctx, workflow.ID)
+	retrieved, err := repo.GetByID(ctx, organizationID, workflow.ID)
 	require.NoError(t, err)
 	assert.Equal(t, workflow.ID, retrieved.ID)
 	assert.Equal(t, workflow.WorkflowID, retrieved.WorkflowID)
@@ -101,6 +117,7 @@ func TestWorkflowRepository_GetByWorkflowID(t *testing.T) {
 
 	ctx := suite.GetContext(t)
 	repo := postgres.NewWorkflowRepository(suite.DB.DB)
+	organizationID := createOrganizationFixture(t, ctx, suite)
 
 	// Create workflow
 	fixtures := testutil.NewFixtureBuilder()
@@ -116,11 +133,11 @@ func TestWorkflowRepository_GetByWorkflowID(t *testing.T) {
 	}
 
 	createdBy := uuid.New()
-	workflow, err := repo.Create(ctx, req, &createdBy)
+	workflow, err := repo.Create(ctx, organizationID, req, &createdBy)
 	require.NoError(t, err)
 
 	// Test GetByWorkflowID
-	retrieved, err := repo.GetByWorkflowID(ctx, "test-workflow-123")
+	retrieved, err := repo.GetByWorkflowID(ctx, organizationID, "test-workflow-123")
 	require.NoError(t, err)
 	assert.Equal(t, workflow.ID, retrieved.ID)
 	assert.Equal(t, "test-workflow-123", retrieved.WorkflowID)
@@ -138,6 +155,7 @@ func TestWorkflowRepository_List(t *testing.T) {
 
 	ctx := suite.GetContext(t)
 	repo := postgres.NewWorkflowRepository(suite.DB.DB)
+	organizationID := createOrganizationFixture(t, ctx, suite)
 
 	// Create multiple workflows
 	fixtures := testutil.NewFixtureBuilder()
@@ -153,12 +171,12 @@ func TestWorkflowRepository_List(t *testing.T) {
 			Definition:  workflowFixture.Definition,
 			Tags:        workflowFixture.Tags,
 		}
-		_, err := repo.Create(ctx, req, &createdBy)
+		_, err := repo.Create(ctx, organizationID, req, &createdBy)
 		require.NoError(t, err)
 	}
 
 	// Test List
-	workflows, total, err := repo.List(ctx, nil, 10, 0)
+	workflows, total, err := repo.List(ctx, organizationID, nil, 10, 0)
 	require.NoError(t, err)
 	assert.GreaterOrEqual(t, len(workflows), 5)
 	assert.GreaterOrEqual(t, total, int64(5))
@@ -175,6 +193,7 @@ func TestWorkflowRepository_Update(t *testing.T) {
 
 	ctx := suite.GetContext(t)
 	repo := postgres.NewWorkflowRepository(suite.DB.DB)
+	organizationID := createOrganizationFixture(t, ctx, suite)
 
 	// Create workflow
 	fixtures := testutil.NewFixtureBuilder()
@@ -190,7 +209,7 @@ func TestWorkflowRepository_Update(t *testing.T) {
 	}
 
 	createdBy := uuid.New()
-	workflow, err := repo.Create(ctx, createReq, &createdBy)
+	workflow, err := repo.Create(ctx, organizationID, createReq, &createdBy)
 	require.NoError(t, err)
 
 	// Update workflow
@@ -201,12 +220,12 @@ func TestWorkflowRepository_Update(t *testing.T) {
 		Description: &updatedDescription,
 	}
 
-	updated, err := repo.Update(ctx, workflow.ID, updateReq)
+	updated, err := repo.Update(ctx, organizationID, workflow.ID, updateReq, &createdBy)
 	require.NoError(t, err)
 	assert.Equal(t, "Updated Workflow Name", updated.Name)
 
 	// Verify update
-	retrieved, err := repo.GetByID(ctx, workflow.ID)
+	retrieved, err := repo.GetByID(ctx, organizationID, workflow.ID)
 	require.NoError(t, err)
 	assert.Equal(t, "Updated Workflow Name", retrieved.Name)
 	assert.Equal(t, "Updated description", *retrieved.Description)
@@ -223,6 +242,7 @@ func TestWorkflowRepository_Delete(t *testing.T) {
 
 	ctx := suite.GetContext(t)
 	repo := postgres.NewWorkflowRepository(suite.DB.DB)
+	organizationID := createOrganizationFixture(t, ctx, suite)
 
 	// Create workflow
 	fixtures := testutil.NewFixtureBuilder()
@@ -238,14 +258,14 @@ func TestWorkflowRepository_Delete(t *testing.T) {
 	}
 
 	createdBy := uuid.New()
-	workflow, err := repo.Create(ctx, req, &createdBy)
+	workflow, err := repo.Create(ctx, organizationID, req, &createdBy)
 	require.NoError(t, err)
 
 	// Delete workflow
-	err = repo.Delete(ctx, workflow.ID)
+	err = repo.Delete(ctx, organizationID, workflow.ID)
 	require.NoError(t, err)
 
 	// Verify deletion
-	_, err = repo.GetByID(ctx, workflow.ID)
+	_, err = repo.GetByID(ctx, organizationID, workflow.ID)
 	assert.Error(t, err)
 }
@@ -46,7 +46,7 @@ func TestWorkflowResumer_PauseAndResume_Integration(t *testing.T) {
 	mockEngine := new(MockWorkflowEngine)
 
 	// Create workflow resumer
-	resumer := services.NewWorkflowResumer(log, executionRepo, mockEngine)
+	resumer := services.NewWorkflowResumer(log, executionRepo, mockEngine, nil)
 
 	ctx := context.Background()
 
@@ -73,7 +73,7 @@ func TestWorkflowResumer_PauseAndResume_Integration(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify execution is paused
-	pausedExec, err := executionRepo.GetExecutionByID(ctx, execution.ID)
+	pausedExec, err := executionRepo.GetExecutionByID(ctx, execution.OrganizationID, execution.ID)
 	require.NoError(t, err)
 	assert.Equal(t, models.ExecutionStatusPaused, pausedExec.Status)
 	assert.NotNil(t, pausedExec.PausedAt)
@@ -90,7 +90,7 @@ func TestWorkflowResumer_PauseAndResume_Integration(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify execution is running again
-	resumedExec, err := executionRepo.GetExecutionByID(ctx, execution.ID)
+	resumedExec, err := executionRepo.GetExecutionByID(ctx, execution.OrganizationID, execution.ID)
 	require.NoError(t, err)
 	assert.Equal(t, models.ExecutionStatusRunning, resumedExec.Status)
 	assert.Nil(t, resumedExec.PausedAt)
@@ -119,7 +119,7 @@ func TestWorkflowResumer_GetPausedExecutions_Integration(t *testing.T) {
 	executionRepo := postgres.NewExecutionRepository(suite.DB.DB)
 
 	// Create workflow resumer
-	resumer := services.NewWorkflowResumer(log, executionRepo, nil)
+	resumer := services.NewWorkflowResumer(log, executionRepo, nil, nil)
 
 	ctx := context.Background()
 	workflowID := uuid.New()
@@ -220,7 +220,7 @@ func TestWorkflowResumer_MultipleResumesCycle_Integration(t *testing.T) {
 	mockEngine.On("ResumePausedExecution", mock.Anything, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
 
 	// Create workflow resumer
-	resumer := services.NewWorkflowResumer(log, executionRepo, mockEngine)
+	resumer := services.NewWorkflowResumer(log, executionRepo, mockEngine, nil)
 
 	ctx := context.Background()
 	workflowID := uuid.New()
@@ -248,7 +248,7 @@ func TestWorkflowResumer_MultipleResumesCycle_Integration(t *testing.T) {
 		require.NoError(t, err, "pause iteration %d failed", i)
 
 		// Verify paused
-		pausedExec, err := executionRepo.GetExecutionByID(ctx, execution.ID)
+		pausedExec, err := executionRepo.GetExecutionByID(ctx, execution.OrganizationID, execution.ID)
 		require.NoError(t, err, "get execution iteration %d failed", i)
 		assert.Equal(t, models.ExecutionStatusPaused, pausedExec.Status)
 
@@ -261,7 +261,7 @@ func TestWorkflowResumer_MultipleResumesCycle_Integration(t *testing.T) {
 		require.NoError(t, err, "resume iteration %d failed", i)
 
 		// Verify resumed
-		resumedExec, err := executionRepo.GetExecutionByID(ctx, execution.ID)
+		resumedExec, err := executionRepo.GetExecutionByID(ctx, execution.OrganizationID, execution.ID)
 		require.NoError(t, err, "get resumed execution iteration %d failed", i)
 		assert.Equal(t, models.ExecutionStatusRunning, resumedExec.Status)
 		assert.Equal(t, i, resumedExec.ResumeCount, "resume count should be %d", i)
@@ -273,7 +273,7 @@ func TestWorkflowResumer_MultipleResumesCycle_Integration(t *testing.T) {
 	}
 
 	// Final verification
-	finalExec, err := executionRepo.GetExecutionByID(ctx, execution.ID)
+	finalExec, err := executionRepo.GetExecutionByID(ctx, execution.OrganizationID, execution.ID)
 	require.NoError(t, err)
 	assert.Equal(t, 3, finalExec.ResumeCount)
 	assert.NotNil(t, finalExec.LastResumedAt)
@@ -297,7 +297,7 @@ func TestWorkflowResumer_CanResume_Integration(t *testing.T) {
 	executionRepo := postgres.NewExecutionRepository(suite.DB.DB)
 
 	// Create workflow resumer
-	resumer := services.NewWorkflowResumer(log, executionRepo, nil)
+	resumer := services.NewWorkflowResumer(log, executionRepo, nil, nil)
 
 	ctx := context.Background()
 	workflowID := uuid.New()
@@ -368,7 +368,7 @@ func TestWorkflowResumer_CanResume_Integration(t *testing.T) {
 			require.NoError(t, err)
 
 			// Test can resume
-			err = resumer.CanResume(tt.execution)
+			err = resumer.CanResume(ctx, tt.execution)
 
 			if tt.shouldSucceed {
 				assert.NoError(t, err)
@@ -402,7 +402,7 @@ func TestWorkflowResumerWorker_ProcessPausedExecutions_Integration(t *testing.T)
 	mockEngine.On("ResumePausedExecution", mock.Anything, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
 
 	// Create workflow resumer and worker
-	resumer := services.NewWorkflowResumer(log, executionRepo, mockEngine)
+	resumer := services.NewWorkflowResumer(log, executionRepo, mockEngine, nil)
 	worker := workers.NewWorkflowResumerWorker(resumer, log, 1*time.Minute)
 
 	ctx := context.Background()
@@ -450,7 +450,7 @@ func TestWorkflowResumerWorker_ProcessPausedExecutions_Integration(t *testing.T)
 
 	// Verify executions were resumed
 	for _, exec := range executions {
-		execution, err := executionRepo.GetExecutionByExecutionID(ctx, exec.executionID)
+		execution, err := executionRepo.GetExecutionByExecutionID(ctx, uuid.Nil, exec.executionID)
 		require.NoError(t, err)
 		assert.Equal(t, models.ExecutionStatusRunning, execution.Status, "execution %s should be running", exec.executionID)
 		assert.Equal(t, 1, execution.ResumeCount, "execution %s should have resume count 1", exec.executionID)
@@ -480,7 +480,7 @@ func TestWorkflowResumer_ResumeData_MergeIntegration(t *testing.T) {
 	mockEngine.On("ResumePausedExecution", mock.Anything, mock.AnythingOfType("*models.WorkflowExecution")).Return(nil)
 
 	// Create workflow resumer
-	resumer := services.NewWorkflowResumer(log, executionRepo, mockEngine)
+	resumer := services.NewWorkflowResumer(log, executionRepo, mockEngine, nil)
 
 	ctx := context.Background()
 	workflowID := uuid.New()
@@ -519,7 +519,7 @@ func TestWorkflowResumer_ResumeData_MergeIntegration(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify resume data was merged correctly
-	resumedExec, err := executionRepo.GetExecutionByID(ctx, execution.ID)
+	resumedExec, err := executionRepo.GetExecutionByID(ctx, execution.OrganizationID, execution.ID)
 	require.NoError(t, err)
 	assert.Equal(t, models.ExecutionStatusRunning, resumedExec.Status)
 	assert.Equal(t, "initial_value", resumedExec.ResumeData["initial_key"])